@@ -40,7 +40,8 @@ const (
 	USER     BfdSessionOwner = 2
 	BGP      BfdSessionOwner = 3
 	OSPF     BfdSessionOwner = 4
-	MAX_APPS BfdSessionOwner = 5
+	RIBD     BfdSessionOwner = 5
+	MAX_APPS BfdSessionOwner = 6
 )
 
 type BfdSessionOperation int32
@@ -64,6 +65,8 @@ func ConvertBfdSessionOwnerStrToVal(owner string) BfdSessionOwner {
 		ownerVal = BGP
 	case "ospf":
 		ownerVal = OSPF
+	case "ribd":
+		ownerVal = RIBD
 	}
 	return ownerVal
 }
@@ -79,6 +82,8 @@ func ConvertBfdSessionOwnerValToStr(owner BfdSessionOwner) string {
 		ownerStr = "bgp"
 	case OSPF:
 		ownerStr = "ospf"
+	case RIBD:
+		ownerStr = "ribd"
 	}
 	return ownerStr
 }
@@ -34,6 +34,13 @@ type SourcePolicyMap struct {
 	Policy  string
 }
 
+type LabelAllocPolicy int
+
+const (
+	LabelAllocPerPrefix LabelAllocPolicy = iota
+	LabelAllocPerNextHop
+)
+
 type GlobalConfig struct {
 	AS                  uint32
 	RouterId            net.IP
@@ -42,6 +49,9 @@ type GlobalConfig struct {
 	EBGPAllowMultipleAS bool
 	IBGPMaxPaths        uint32
 	Redistribution      []SourcePolicyMap
+	LabelAllocation     LabelAllocPolicy
+	LabelRangeStart     uint32
+	LabelRangeEnd       uint32
 }
 
 type GlobalState struct {
@@ -284,4 +294,26 @@ type RouteConfig struct {
 	OutgoingInterface string
 	IsIPv6            bool
 	NullRoute         bool
+	Labels            []uint32
+}
+
+type LabelOp int
+
+const (
+	LabelOpSwap LabelOp = iota
+	LabelOpPop
+)
+
+/*  Incoming label map entry for a locally allocated label. Swap entries replace the label with
+ *  OutLabels and forward to the next hop, pop entries remove the label and do an IP lookup.
+ */
+type LabelRouteConfig struct {
+	InLabel           uint32
+	Op                LabelOp
+	OutLabels         []uint32
+	NextHopIp         string
+	OutgoingInterface string
+	DestinationNw     string
+	NetworkMask       string
+	IsIPv6            bool
 }
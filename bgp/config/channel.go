@@ -102,6 +102,7 @@ type RouteInfo struct {
 	NetworkStatement bool
 	RouteOrigin      string
 	AddressType      ribdCommonDefs.IPType
+	RouteTag         uint32 // ribd route tag, carried as metadata of the bgp path
 }
 
 type RouteCh struct {
@@ -24,4 +24,6 @@
 // conn.go
 package config
 
-const BGPPort string = "179"
+// BGPPort is the TCP port used for BGP sessions. It is a variable so that test harnesses can run the
+// server on an unprivileged port.
+var BGPPort string = "179"
@@ -52,6 +52,7 @@ type RouteMgrIntf interface {
 	CreateRoute(*RouteConfig)
 	DeleteRoute(*RouteConfig)
 	UpdateRoute(cfg *RouteConfig, op string)
+	UpdateLabelRoute(cfg *LabelRouteConfig, op string)
 	ApplyPolicy(applyList []*ApplyPolicyInfo, undoList []*ApplyPolicyInfo)
 	GetRoutes() ([]*RouteInfo, []*RouteInfo)
 }
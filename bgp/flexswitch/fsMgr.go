@@ -27,6 +27,7 @@ import (
 	"asicdServices"
 	"bfdd"
 	nanomsg "github.com/op/go-nanomsg"
+	"l3/bgp/utils"
 	"ndpd"
 	"ribd"
 	"utils/logging"
@@ -40,6 +41,7 @@ type FSRouteMgr struct {
 	ribdClient      *ribd.RIBDServicesClient
 	ribSubSocket    *nanomsg.SubSocket
 	ribSubBGPSocket *nanomsg.SubSocket
+	labelTable      *utils.FileLabelTable
 }
 
 /*  Interface manager will handle all the communication with asicd
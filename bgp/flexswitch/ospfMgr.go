@@ -0,0 +1,55 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package FSMgr
+
+import (
+	"l3/bgp/rpc"
+	"ospfd"
+	"ospfdInt"
+	"utils/logging"
+)
+
+/*  Waits for the initial BGP convergence and notifies ospfd, which ends the
+ *  startup max-metric period of routers configured to wait for BGP. The ospfd
+ *  client is only connected then, bgpd does not depend on ospfd running.
+ */
+func NotifyOspfBgpConverged(logger *logging.Writer, fileName string, convergedCh chan bool) {
+	<-convergedCh
+	var ospfdClient *ospfd.OSPFDServicesClient = nil
+	ospfdClientChan := make(chan *ospfd.OSPFDServicesClient)
+
+	logger.Info("Connecting to OSPFd")
+	go rpc.StartOspfdClient(logger, fileName, ospfdClientChan)
+	ospfdClient = <-ospfdClientChan
+	if ospfdClient == nil {
+		logger.Err("Failed to connect to OSPFd, BGP convergence not notified")
+		return
+	}
+	_, err := ospfdClient.ExecuteOspfBgpConverged(&ospfdInt.OspfBgpConverged{Source: "bgpd"})
+	if err != nil {
+		logger.Err("Failed to notify OSPFd of BGP convergence, error:", err)
+		return
+	}
+	logger.Info("Notified OSPFd of BGP convergence")
+}
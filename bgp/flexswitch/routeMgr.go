@@ -31,6 +31,7 @@ import (
 	"l3/bgp/api"
 	"l3/bgp/config"
 	"l3/bgp/rpc"
+	"l3/bgp/utils"
 	"l3/rib/ribdCommonDefs"
 	"ribd"
 	"ribdInt"
@@ -39,6 +40,9 @@ import (
 	nanomsg "github.com/op/go-nanomsg"
 )
 
+// The forwarding plane does not have an MPLS label table yet, labelled routes are kept in a file.
+const labelTableFileName = "bgpdLabelTable.json"
+
 /*  Init route manager with ribd client as its core
  */
 func NewFSRouteMgr(logger *logging.Writer, fileName string) (*FSRouteMgr, error) {
@@ -55,10 +59,17 @@ func NewFSRouteMgr(logger *logging.Writer, fileName string) (*FSRouteMgr, error)
 		logger.Info("Connected to RIBd")
 	}
 
+	labelTable, err := utils.NewFileLabelTable(logger, fileName+labelTableFileName)
+	if err != nil {
+		logger.Err("Failed to load the label table, error:", err)
+		return nil, err
+	}
+
 	mgr := &FSRouteMgr{
 		plugin:     "ovsdb",
 		ribdClient: ribdClient,
 		logger:     logger,
+		labelTable: labelTable,
 	}
 
 	return mgr, nil
@@ -121,6 +132,7 @@ func (mgr *FSRouteMgr) populateConfigRoute(route *ribdInt.Routes) *config.RouteI
 		NetworkStatement: route.NetworkStatement,
 		RouteOrigin:      route.RouteOrigin,
 		AddressType:      ribdCommonDefs.IPType(route.IPAddrType),
+		RouteTag:         uint32(route.RouteTag),
 	}
 	return rv
 }
@@ -278,6 +290,12 @@ func (mgr *FSRouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
 	}
 }
 
+func (mgr *FSRouteMgr) UpdateLabelRoute(cfg *config.LabelRouteConfig, op string) {
+	if err := mgr.labelTable.UpdateLabelRoute(cfg, op); err != nil {
+		mgr.logger.Err("Label route", op, "failed for label", cfg.InLabel, "error:", err)
+	}
+}
+
 func (mgr *FSRouteMgr) ApplyPolicy(applyList []*config.ApplyPolicyInfo, undoList []*config.ApplyPolicyInfo) {
 
 	mgr.logger.Info("RouteMgr:ApplyPolicy, applyList:", applyList)
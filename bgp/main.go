@@ -178,6 +178,7 @@ func main() {
 
 		up := <-bgpServer.ServerUpCh
 		logger.Info(" Serverup:", up)
+		go FSMgr.NotifyOspfBgpConverged(logger, fileName, bgpServer.ConvergedCh)
 
 		api.InitPolicy(bgpPolicyMgr)
 		api.Init(bgpServer)
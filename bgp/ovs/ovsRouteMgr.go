@@ -54,6 +54,10 @@ func (mgr *OvsRouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
 
 }
 
+func (mgr *OvsRouteMgr) UpdateLabelRoute(cfg *config.LabelRouteConfig, op string) {
+
+}
+
 func (mgr *OvsRouteMgr) GetNextHopInfo(ipAddr string, ifIndex int32) (*config.NextHopInfo, error) {
 	return nil, nil
 }
@@ -45,9 +45,13 @@ const (
 	SafiMulticast
 )
 
+const SafiLabelledUnicast SAFI = 4
+
 var ProtocolFamilyMap = map[string]uint32{
-	"ipv4-unicast": GetProtocolFamily(AfiIP, SafiUnicast),
-	"ipv6-unicast": GetProtocolFamily(AfiIP6, SafiUnicast),
+	"ipv4-unicast":          GetProtocolFamily(AfiIP, SafiUnicast),
+	"ipv6-unicast":          GetProtocolFamily(AfiIP6, SafiUnicast),
+	"ipv4-labelled-unicast": GetProtocolFamily(AfiIP, SafiLabelledUnicast),
+	"ipv6-labelled-unicast": GetProtocolFamily(AfiIP6, SafiLabelledUnicast),
 	//"ipv4-multicast": GetProtocolFamily(AfiIP, SafiMulticast),
 	//"ipv6-multicast": GetProtocolFamily(AfiIP6, SafiMulticast),
 }
@@ -102,6 +106,11 @@ func GetAfiSafi(protocolFamily uint32) (AFI, SAFI) {
 	return AFI(protocolFamily >> 8), SAFI(protocolFamily & 0xFF)
 }
 
+func IsLabelledFamily(protoFamily uint32) bool {
+	_, safi := GetAfiSafi(protoFamily)
+	return safi == SafiLabelledUnicast
+}
+
 func GetAddressLengthForFamily(protoFamily uint32) int {
 	afi, _ := GetAfiSafi(protoFamily)
 	if addrLen, ok := AFINextHopLenMap[afi]; ok {
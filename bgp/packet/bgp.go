@@ -2313,7 +2313,13 @@ func decodeNLRI(pkt []byte, ipPrefix *[]NLRI, length uint32, afi AFI, safi SAFI,
 	peerAttrs := data.(BGPPeerAttrs)
 
 	for ptr < length {
-		if peerAttrs.AddPathsRxActual {
+		if safi == SafiLabelledUnicast {
+			if peerAttrs.AddPathsRxActual {
+				ip = &ExtLabelledNLRI{}
+			} else {
+				ip = &LabelledNLRI{}
+			}
+		} else if peerAttrs.AddPathsRxActual {
 			ip = &ExtNLRI{}
 		} else {
 			ip = &IPPrefix{}
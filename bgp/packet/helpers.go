@@ -528,6 +528,8 @@ func ConstructNLRIFromPathIdAndNLRI(nlri NLRI, pathId uint32) NLRI {
 	newNLRI := nlri.Clone()
 	if extNLRI, ok := newNLRI.(*ExtNLRI); ok {
 		extNLRI.PathId = pathId
+	} else if extNLRI, ok := newNLRI.(*ExtLabelledNLRI); ok {
+		extNLRI.PathId = pathId
 	}
 
 	return newNLRI
@@ -0,0 +1,261 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// label.go
+package packet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	BGPLabelLen           = 3
+	BGPLabelBits          = BGPLabelLen * 8
+	BGPLabelBottomOfStack = 0x1
+)
+
+const (
+	MPLSLabelIPv4ExplicitNull uint32 = 0
+	MPLSLabelIPv6ExplicitNull uint32 = 2
+	MPLSLabelImplicitNull     uint32 = 3
+	MPLSLabelMinUnreserved    uint32 = 16
+	MPLSLabelMax              uint32 = 0xFFFFF
+)
+
+// Label value carried in withdrawn labelled NLRIs (RFC 8277 section 2.4). The 3 octet label field
+// is encoded as 0x800000 which is label 0x80000 without the bottom of stack bit.
+const BGPLabelWithdraw uint32 = 0x80000
+
+func encodeLabelStack(pkt []byte, labels []uint32) {
+	for i, label := range labels {
+		value := (label & MPLSLabelMax) << 4
+		if i == len(labels)-1 && label != BGPLabelWithdraw {
+			value |= BGPLabelBottomOfStack
+		}
+		pkt[i*BGPLabelLen] = uint8(value >> 16)
+		pkt[i*BGPLabelLen+1] = uint8(value >> 8)
+		pkt[i*BGPLabelLen+2] = uint8(value)
+	}
+}
+
+func decodeLabelStack(pkt []byte, bits uint8) ([]uint32, error) {
+	labels := make([]uint32, 0)
+	idx := 0
+	for {
+		if int(bits) < (len(labels)+1)*BGPLabelBits || len(pkt) < idx+BGPLabelLen {
+			return labels, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+				"Labelled NLRI does not contain bottom of stack label"}
+		}
+
+		value := uint32(pkt[idx])<<16 | uint32(pkt[idx+1])<<8 | uint32(pkt[idx+2])
+		idx += BGPLabelLen
+		labels = append(labels, value>>4)
+		if (value&BGPLabelBottomOfStack) != 0 || (value>>4) == BGPLabelWithdraw || value == 0 {
+			break
+		}
+	}
+	return labels, nil
+}
+
+func labelStackString(labels []uint32) string {
+	labelStrs := make([]string, 0, len(labels))
+	for _, label := range labels {
+		labelStrs = append(labelStrs, strconv.Itoa(int(label)))
+	}
+	return strings.Join(labelStrs, ",")
+}
+
+/*  Labelled NLRI as defined by RFC 8277 - a stack of 3 octet labels followed by the prefix. The
+ *  length field covers both the labels and the prefix.
+ */
+type LabelledNLRI struct {
+	*IPPrefix
+	Labels []uint32
+}
+
+func (n *LabelledNLRI) Clone() NLRI {
+	x := *n
+	prefix := n.IPPrefix.Clone()
+	x.IPPrefix = prefix.(*IPPrefix)
+	x.Labels = make([]uint32, len(n.Labels))
+	copy(x.Labels, n.Labels)
+	return &x
+}
+
+func (n *LabelledNLRI) Len() uint32 {
+	return n.IPPrefix.Len() + uint32(len(n.Labels)*BGPLabelLen)
+}
+
+func (n *LabelledNLRI) Encode(afi AFI) ([]byte, error) {
+	if len(n.Labels) == 0 {
+		return nil, BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			fmt.Sprintf("Labelled NLRI %s does not have a label", n.IPPrefix.GetCIDR())}
+	}
+
+	labelsLen := len(n.Labels) * BGPLabelLen
+	pkt := make([]byte, 1+labelsLen)
+	pkt[0] = n.IPPrefix.Length + uint8(labelsLen*8)
+	encodeLabelStack(pkt[1:], n.Labels)
+	ipBytes, err := n.IPPrefix.Encode(afi)
+	if err != nil {
+		return nil, err
+	}
+	pkt = append(pkt, ipBytes[1:]...)
+	return pkt, nil
+}
+
+func (n *LabelledNLRI) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 1+BGPLabelLen {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"Labelled NLRI does not contain label or prefix length"}
+	}
+
+	labels, err := decodeLabelStack(pkt[1:], pkt[0])
+	if err != nil {
+		return err
+	}
+	n.Labels = labels
+
+	labelsLen := len(labels) * BGPLabelLen
+	prefixPkt := make([]byte, len(pkt)-labelsLen)
+	prefixPkt[0] = pkt[0] - uint8(labelsLen*8)
+	copy(prefixPkt[1:], pkt[1+labelsLen:])
+
+	n.IPPrefix = &IPPrefix{}
+	return n.IPPrefix.Decode(prefixPkt, afi)
+}
+
+func (n *LabelledNLRI) GetLabels() []uint32 {
+	return n.Labels
+}
+
+func (n *LabelledNLRI) String() string {
+	return "{0 " + n.Prefix.String() + "/" + strconv.Itoa(int(n.Length)) + " label " +
+		labelStackString(n.Labels) + "}"
+}
+
+func NewLabelledNLRI(labels []uint32, prefix *IPPrefix) *LabelledNLRI {
+	return &LabelledNLRI{
+		IPPrefix: prefix,
+		Labels:   labels,
+	}
+}
+
+type ExtLabelledNLRI struct {
+	*LabelledNLRI
+	PathId uint32
+}
+
+func (n *ExtLabelledNLRI) Clone() NLRI {
+	x := *n
+	nlri := n.LabelledNLRI.Clone()
+	x.LabelledNLRI = nlri.(*LabelledNLRI)
+	return &x
+}
+
+func (n *ExtLabelledNLRI) Len() uint32 {
+	return n.LabelledNLRI.Len() + 4
+}
+
+func (n *ExtLabelledNLRI) Encode(afi AFI) ([]byte, error) {
+	pkt := make([]byte, 4)
+	binary.BigEndian.PutUint32(pkt, n.PathId)
+	nlriBytes, err := n.LabelledNLRI.Encode(afi)
+	if err != nil {
+		return nil, err
+	}
+	pkt = append(pkt, nlriBytes...)
+	return pkt, nil
+}
+
+func (n *ExtLabelledNLRI) Decode(pkt []byte, afi AFI) error {
+	if len(pkt) < 5 {
+		return BGPMessageError{BGPUpdateMsgError, BGPInvalidNetworkField, nil,
+			"Labelled NLRI does not contain path id or prefix lenght"}
+	}
+	n.PathId = binary.BigEndian.Uint32(pkt[:4])
+
+	n.LabelledNLRI = &LabelledNLRI{}
+	return n.LabelledNLRI.Decode(pkt[4:], afi)
+}
+
+func (n *ExtLabelledNLRI) GetPathId() uint32 {
+	return n.PathId
+}
+
+func (n *ExtLabelledNLRI) String() string {
+	return "{" + strconv.Itoa(int(n.PathId)) + " " + n.Prefix.String() + "/" + strconv.Itoa(int(n.Length)) +
+		" label " + labelStackString(n.Labels) + "}"
+}
+
+func NewExtLabelledNLRI(pathId uint32, nlri *LabelledNLRI) *ExtLabelledNLRI {
+	return &ExtLabelledNLRI{
+		LabelledNLRI: nlri,
+		PathId:       pathId,
+	}
+}
+
+func GetNLRILabels(nlri NLRI) []uint32 {
+	switch labelled := nlri.(type) {
+	case *LabelledNLRI:
+		return labelled.Labels
+	case *ExtLabelledNLRI:
+		return labelled.Labels
+	}
+	return nil
+}
+
+func NewNLRIWithPathId(pathId uint32, nlri NLRI) NLRI {
+	switch n := nlri.(type) {
+	case *LabelledNLRI:
+		return NewExtLabelledNLRI(pathId, n)
+	case *ExtLabelledNLRI:
+		return NewExtLabelledNLRI(pathId, n.LabelledNLRI)
+	case *ExtNLRI:
+		return NewExtNLRI(pathId, n.IPPrefix)
+	}
+	return NewExtNLRI(pathId, nlri.GetIPPrefix())
+}
+
+func ConstructLabelledMPReachNLRI(protoFamily uint32, nextHop net.IP, nlriList []NLRI) *BGPPathAttrMPReachNLRI {
+	afi, safi := GetAfiSafi(protoFamily)
+	if afi == AfiIP6 {
+		return ConstructIPv6MPReachNLRI(protoFamily, nextHop, nil, nlriList)
+	}
+
+	mpReachNLRI := NewBGPPathAttrMPReachNLRI()
+	mpReachNLRI.AFI = afi
+	mpReachNLRI.SAFI = safi
+	mpNextHop := NewMPNextHopIP()
+	if nextHop.To4() != nil {
+		nextHop = nextHop.To4()
+	}
+	mpNextHop.SetNextHop(nextHop)
+	mpReachNLRI.SetNextHop(mpNextHop)
+	mpReachNLRI.SetNLRIList(nlriList)
+	return mpReachNLRI
+}
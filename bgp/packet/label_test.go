@@ -0,0 +1,164 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// label_test.go
+package packet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"l3/bgp/utils"
+	"net"
+	"testing"
+	"utils/logging"
+)
+
+func TestLabelledNLRIEncodeDecode(t *testing.T) {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+	utils.SetLogger(logger)
+
+	prefixes := []string{"20.1.10.0/24", "10.0.0.1/32", "2001:1:2::/48"}
+	afis := []AFI{AfiIP, AfiIP, AfiIP6}
+	labels := [][]uint32{[]uint32{100}, []uint32{16, 1048575}, []uint32{MPLSLabelImplicitNull}}
+	for i, cidr := range prefixes {
+		prefix, err := ConstructIPPrefixFromCIDR(cidr)
+		if err != nil {
+			t.Fatal("ConstructIPPrefixFromCIDR failed for", cidr, "with error:", err)
+		}
+
+		nlri := NewLabelledNLRI(labels[i], prefix)
+		pkt, err := nlri.Encode(afis[i])
+		if err != nil {
+			t.Fatal("LabelledNLRI encode failed for", nlri, "with error:", err)
+		}
+		if uint32(len(pkt)) != nlri.Len() {
+			t.Fatal("LabelledNLRI encode for", nlri, "expected length", nlri.Len(), "got", len(pkt))
+		}
+
+		decoded := &LabelledNLRI{}
+		err = decoded.Decode(pkt, afis[i])
+		if err != nil {
+			t.Fatal("LabelledNLRI decode failed for", nlri, "with error:", err)
+		}
+		if decoded.GetCIDR() != nlri.GetCIDR() {
+			t.Fatal("LabelledNLRI decode expected prefix", nlri.GetCIDR(), "got", decoded.GetCIDR())
+		}
+		if len(decoded.Labels) != len(labels[i]) {
+			t.Fatal("LabelledNLRI decode expected labels", labels[i], "got", decoded.Labels)
+		}
+		for idx, label := range labels[i] {
+			if decoded.Labels[idx] != label {
+				t.Fatal("LabelledNLRI decode expected labels", labels[i], "got", decoded.Labels)
+			}
+		}
+	}
+}
+
+func TestLabelledNLRIEncoding(t *testing.T) {
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		t.Fatal("Failed to start the logger. Exiting!!")
+	}
+	utils.SetLogger(logger)
+
+	prefix := NewIPPrefix(net.ParseIP("20.1.10.0"), 24)
+	expected, _ := hex.DecodeString("3000064114010A")
+	pkt, err := NewLabelledNLRI([]uint32{100}, prefix).Encode(AfiIP)
+	if err != nil {
+		t.Fatal("LabelledNLRI encode failed with error:", err)
+	}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("LabelledNLRI encode expected %x got %x", expected, pkt)
+	}
+
+	expected, _ = hex.DecodeString("0000000530800000" + "14010A")
+	pkt, err = NewExtLabelledNLRI(5, NewLabelledNLRI([]uint32{BGPLabelWithdraw}, prefix)).Encode(AfiIP)
+	if err != nil {
+		t.Fatal("ExtLabelledNLRI encode failed with error:", err)
+	}
+	if !bytes.Equal(pkt, expected) {
+		t.Fatalf("ExtLabelledNLRI withdraw encode expected %x got %x", expected, pkt)
+	}
+
+	_, err = NewLabelledNLRI([]uint32{}, prefix).Encode(AfiIP)
+	if err == nil {
+		t.Fatal("LabelledNLRI encode without labels, expected failure, got NO error")
+	}
+}
+
+func TestLabelledNLRIDecodeErrors(t *testing.T) {
+	packets := []string{
+		"30000640",       // Bottom of stack not set and no more labels
+		"10000641",       // Length does not cover the label
+		"3800064114",     // Prefix shorter than the length
+		"5800064114010A", // Prefix length more than 32 bits
+	}
+
+	for _, strPkt := range packets {
+		pkt, _ := hex.DecodeString(strPkt)
+		nlri := &LabelledNLRI{}
+		err := nlri.Decode(pkt, AfiIP)
+		if err == nil {
+			t.Fatal("LabelledNLRI decode for packet", strPkt, "expected failure, got NO error")
+		} else {
+			t.Log("LabelledNLRI decode for packet", strPkt, "failed with error:", err)
+		}
+	}
+}
+
+func TestMPReachLabelledNLRIDecode(t *testing.T) {
+	hexPkt, _ := hex.DecodeString("800E100001040" + "40A010A0100" + "3000064114010A")
+	mpReach := NewBGPPathAttrMPReachNLRI()
+	peerAttrs := BGPPeerAttrs{
+		ASSize:           4,
+		AddPathsRxActual: false,
+	}
+	err := mpReach.Decode(hexPkt, peerAttrs)
+	if err != nil {
+		t.Fatal("BGP MPReachNLRI decode for labelled unicast failed with error:", err)
+	}
+	if len(mpReach.NLRI) != 1 {
+		t.Fatal("BGP MPReachNLRI decode for labelled unicast expected 1 NLRI, got", len(mpReach.NLRI))
+	}
+	labels := GetNLRILabels(mpReach.NLRI[0])
+	if len(labels) != 1 || labels[0] != 100 {
+		t.Fatal("BGP MPReachNLRI decode for labelled unicast expected label 100, got", labels)
+	}
+	if mpReach.NLRI[0].GetCIDR() != "20.1.10.0/24" {
+		t.Fatal("BGP MPReachNLRI decode for labelled unicast expected 20.1.10.0/24, got",
+			mpReach.NLRI[0].GetCIDR())
+	}
+
+	hexPkt, _ = hex.DecodeString("800F0A00010430800000" + "14010A")
+	mpUnreach := NewBGPPathAttrMPUnreachNLRI()
+	err = mpUnreach.Decode(hexPkt, peerAttrs)
+	if err != nil {
+		t.Fatal("BGP MPUnreachNLRI decode for labelled unicast failed with error:", err)
+	}
+	if len(mpUnreach.NLRI) != 1 || mpUnreach.NLRI[0].GetCIDR() != "20.1.10.0/24" {
+		t.Fatal("BGP MPUnreachNLRI decode for labelled unicast expected 20.1.10.0/24, got", mpUnreach.NLRI)
+	}
+}
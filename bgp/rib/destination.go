@@ -63,6 +63,8 @@ type Destination struct {
 	BGPRouteState     config.ModelRouteIntf
 	PathInfoRouteMap  map[*bgpd.PathInfo]*Route
 	routeListIdx      int
+	localLabel        uint32
+	labelKey          string
 }
 
 func NewDestination(rib *LocRib, nlri packet.NLRI, protoFamily uint32, gConf *config.GlobalConfig) *Destination {
@@ -275,6 +277,19 @@ func (d *Destination) AddOrUpdatePath(peerIp string, pathId uint32, path *Path)
 	return added
 }
 
+func (d *Destination) setPathLabels(path *Path, labels []uint32) {
+	if route, ok := d.pathRouteMap[path]; ok {
+		route.Labels = labels
+	}
+}
+
+func (d *Destination) getPathLabels(path *Path) []uint32 {
+	if route, ok := d.pathRouteMap[path]; ok {
+		return route.Labels
+	}
+	return nil
+}
+
 func (d *Destination) RemovePath(peerIP string, pathId uint32, path *Path) *Path {
 	var pathMap map[uint32]*Path
 	var oldPath *Path
@@ -424,11 +439,107 @@ func (d *Destination) ConstructRouteConfig(path *Path, reachInfo *ReachabilityIn
 		OutgoingInterface: strconv.Itoa(int(reachInfo.NextHopIfIdx)),
 		IsIPv6:            isIPv6,
 		NullRoute:         nullRoute,
+		Labels:            d.getPathLabels(path),
 	}
 
 	return &cfg
 }
 
+func (d *Destination) constructLabelRouteConfig(path *Path, ipLength int) *config.LabelRouteConfig {
+	cfg := &config.LabelRouteConfig{
+		Op:     config.LabelOpPop,
+		IsIPv6: ipLength == 16,
+	}
+
+	if d.rib.labelMgr.GetAllocPolicy() == config.LabelAllocPerPrefix {
+		cfg.DestinationNw = d.NLRI.GetPrefix().String()
+		cfg.NetworkMask = d.constructNetmaskFromLen(int(d.NLRI.GetLength()), ipLength*8).String()
+	}
+
+	if path.IsLocal() {
+		return cfg
+	}
+
+	reachInfo := path.GetReachability(d.protoFamily)
+	if reachInfo != nil {
+		cfg.NextHopIp = reachInfo.NextHop
+		cfg.OutgoingInterface = strconv.Itoa(int(reachInfo.NextHopIfIdx))
+	}
+	labels := d.getPathLabels(path)
+	if len(labels) > 0 && labels[0] != packet.MPLSLabelImplicitNull {
+		cfg.Op = config.LabelOpSwap
+		cfg.OutLabels = labels
+	}
+	return cfg
+}
+
+/*  Binds a local label to the destination for labelled unicast families based on the best path.
+ *  The label is released when the destination no longer has a best path.
+ */
+func (d *Destination) updateLocalLabel(ipLength int) {
+	if !packet.IsLabelledFamily(d.protoFamily) {
+		return
+	}
+
+	labelMgr := d.rib.labelMgr
+	if d.LocRibPath == nil {
+		if d.labelKey != "" {
+			labelMgr.UnbindLabel(d.labelKey)
+			d.labelKey = ""
+			d.localLabel = 0
+		}
+		return
+	}
+
+	cfg := d.constructLabelRouteConfig(d.LocRibPath, ipLength)
+	key := labelMgr.GetLabelKey(d.protoFamily, d.NLRI.GetCIDR(), cfg)
+	if key == d.labelKey {
+		labelMgr.UpdateBinding(key, cfg)
+		return
+	}
+
+	label, err := labelMgr.BindLabel(key, cfg)
+	if d.labelKey != "" {
+		labelMgr.UnbindLabel(d.labelKey)
+		d.labelKey = ""
+		d.localLabel = 0
+	}
+	if err != nil {
+		// The old binding no longer matches the best path, the destination is withdrawn until a label is bound
+		d.logger.Errf("Destination %s failed to bind local label, error %s", d.NLRI.GetCIDR(), err)
+		return
+	}
+	d.labelKey = key
+	d.localLabel = label
+}
+
+func (d *Destination) GetLocalLabel() uint32 {
+	return d.localLabel
+}
+
+/*  Labelled unicast destinations are only advertised while a local label is bound.
+ */
+func (d *Destination) HasLocalLabel() bool {
+	return !packet.IsLabelledFamily(d.protoFamily) || d.labelKey != ""
+}
+
+/*  NLRI to advertise to peers. Labelled unicast destinations are advertised with the local label
+ *  since the local address is always set as the next hop.
+ */
+func (d *Destination) GetAdvertisedNLRI() packet.NLRI {
+	if packet.IsLabelledFamily(d.protoFamily) {
+		return packet.NewLabelledNLRI([]uint32{d.localLabel}, d.NLRI.GetIPPrefix())
+	}
+	return d.NLRI.GetIPPrefix()
+}
+
+func (d *Destination) GetWithdrawnNLRI() packet.NLRI {
+	if packet.IsLabelledFamily(d.protoFamily) {
+		return packet.NewLabelledNLRI([]uint32{packet.BGPLabelWithdraw}, d.NLRI.GetIPPrefix())
+	}
+	return d.NLRI
+}
+
 func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool, []*Route, []*Route, []*Route) {
 	updatedPaths := make([]*Path, 0)
 	removedPaths := make([]*Path, 0)
@@ -625,6 +736,8 @@ func (d *Destination) SelectRouteForLocRib(addPathCount int) (RouteAction, bool,
 			d.rib.routeMgr.UpdateRoute(cfg, "add")
 		}
 	}
+
+	d.updateLocalLabel(ipLength)
 	return locRibAction, addPathsUpdated, addedRoutes, updatedRoutes, deletedRoutes
 }
 
@@ -61,6 +61,10 @@ func (r *RouteMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
 	r.t.Log("RouteMgr:UpdateRoute:", cfg, "operation:", op)
 }
 
+func (r *RouteMgr) UpdateLabelRoute(cfg *config.LabelRouteConfig, op string) {
+	r.t.Log("RouteMgr:UpdateLabelRoute:", cfg, "operation:", op)
+}
+
 func (r *RouteMgr) ApplyPolicy(policy, conditions []*config.ApplyPolicyInfo) {
 	r.t.Log("RouteMgr:ApplyPolicy")
 }
@@ -0,0 +1,229 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// labelMgr.go
+package rib
+
+import (
+	"errors"
+	"fmt"
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	"reflect"
+	"strconv"
+	"utils/logging"
+)
+
+type labelBinding struct {
+	label    uint32
+	refCount int
+	cfg      *config.LabelRouteConfig
+}
+
+/*  Label manager allocates the local labels advertised with labelled unicast routes. With per
+ *  prefix allocation every destination gets its own label. With per next hop allocation all the
+ *  destinations that resolve to the same next hop and remote label stack share a label. The
+ *  incoming label entries are programmed through the route manager when a label is first bound
+ *  and removed when the last destination releases it.
+ */
+type LabelMgr struct {
+	logger      *logging.Writer
+	gConf       *config.GlobalConfig
+	routeMgr    config.RouteMgrIntf
+	rangeStart  uint32
+	rangeEnd    uint32
+	nextLabel   uint32
+	freeLabels  []uint32
+	boundLabels map[uint32]bool
+	bindings    map[string]*labelBinding
+}
+
+func NewLabelMgr(logger *logging.Writer, gConf *config.GlobalConfig, rMgr config.RouteMgrIntf) *LabelMgr {
+	return &LabelMgr{
+		logger:      logger,
+		gConf:       gConf,
+		routeMgr:    rMgr,
+		freeLabels:  make([]uint32, 0),
+		boundLabels: make(map[uint32]bool),
+		bindings:    make(map[string]*labelBinding),
+	}
+}
+
+func (m *LabelMgr) getLabelRange() (uint32, uint32) {
+	start := packet.MPLSLabelMinUnreserved
+	end := packet.MPLSLabelMax
+	if m.gConf != nil {
+		if m.gConf.LabelRangeStart >= packet.MPLSLabelMinUnreserved {
+			start = m.gConf.LabelRangeStart
+		}
+		if m.gConf.LabelRangeEnd != 0 && m.gConf.LabelRangeEnd >= start &&
+			m.gConf.LabelRangeEnd <= packet.MPLSLabelMax {
+			end = m.gConf.LabelRangeEnd
+		}
+	}
+	return start, end
+}
+
+/*  When the label range is reconfigured the free labels may fall outside of the new range, they are
+ *  dropped and the labels of the new range are handed out from its start, skipping the labels still
+ *  bound from the old range.
+ */
+func (m *LabelMgr) allocLabel() (uint32, error) {
+	start, end := m.getLabelRange()
+	if start != m.rangeStart || end != m.rangeEnd {
+		m.rangeStart = start
+		m.rangeEnd = end
+		m.nextLabel = start
+		m.freeLabels = m.freeLabels[:0]
+	}
+
+	if len(m.freeLabels) > 0 {
+		label := m.freeLabels[len(m.freeLabels)-1]
+		m.freeLabels = m.freeLabels[:len(m.freeLabels)-1]
+		return label, nil
+	}
+
+	for ; m.nextLabel <= end; m.nextLabel++ {
+		if !m.boundLabels[m.nextLabel] {
+			label := m.nextLabel
+			m.nextLabel++
+			return label, nil
+		}
+	}
+	return 0, errors.New(fmt.Sprintf("Label range %d-%d exhausted", start, end))
+}
+
+func (m *LabelMgr) freeLabel(label uint32) {
+	delete(m.boundLabels, label)
+	if label < m.rangeStart || label > m.rangeEnd {
+		return
+	}
+	m.freeLabels = append(m.freeLabels, label)
+}
+
+func (m *LabelMgr) GetAllocPolicy() config.LabelAllocPolicy {
+	if m.gConf == nil {
+		return config.LabelAllocPerPrefix
+	}
+	return m.gConf.LabelAllocation
+}
+
+func (m *LabelMgr) GetLabelKey(protoFamily uint32, cidr string, cfg *config.LabelRouteConfig) string {
+	key := strconv.Itoa(int(protoFamily)) + "/"
+	if m.GetAllocPolicy() == config.LabelAllocPerNextHop {
+		if cfg.Op == config.LabelOpPop {
+			return key + "local"
+		}
+		key += cfg.NextHopIp
+		for _, label := range cfg.OutLabels {
+			key += ":" + strconv.Itoa(int(label))
+		}
+		return key
+	}
+	return key + cidr
+}
+
+func (m *LabelMgr) GetLabel(key string) (uint32, bool) {
+	if binding, ok := m.bindings[key]; ok {
+		return binding.label, true
+	}
+	return 0, false
+}
+
+func (m *LabelMgr) GetNumBindings() int {
+	return len(m.bindings)
+}
+
+/*  Returns the label bound to key, allocating one and programming the incoming label entry if this
+ *  is the first reference to key.
+ */
+func (m *LabelMgr) BindLabel(key string, cfg *config.LabelRouteConfig) (uint32, error) {
+	if binding, ok := m.bindings[key]; ok {
+		binding.refCount++
+		return binding.label, nil
+	}
+
+	label, err := m.allocLabel()
+	if err != nil {
+		m.logger.Errf("LabelMgr: Failed to allocate label for %s, error %s", key, err)
+		return 0, err
+	}
+
+	labelCfg := *cfg
+	labelCfg.InLabel = label
+	m.boundLabels[label] = true
+	m.bindings[key] = &labelBinding{
+		label:    label,
+		refCount: 1,
+		cfg:      &labelCfg,
+	}
+	m.logger.Infof("LabelMgr: Bind label %d to %s", label, key)
+	if m.routeMgr != nil {
+		m.routeMgr.UpdateLabelRoute(&labelCfg, "add")
+	}
+	return label, nil
+}
+
+/*  Reprograms the incoming label entry for key if the forwarding information changed. Used with
+ *  per prefix allocation when the best path of a destination changes but its label is retained.
+ */
+func (m *LabelMgr) UpdateBinding(key string, cfg *config.LabelRouteConfig) {
+	binding, ok := m.bindings[key]
+	if !ok {
+		m.logger.Errf("LabelMgr: UpdateBinding - label binding not found for %s", key)
+		return
+	}
+
+	labelCfg := *cfg
+	labelCfg.InLabel = binding.label
+	if reflect.DeepEqual(&labelCfg, binding.cfg) {
+		return
+	}
+
+	m.logger.Infof("LabelMgr: Update label %d binding for %s", binding.label, key)
+	if m.routeMgr != nil {
+		m.routeMgr.UpdateLabelRoute(binding.cfg, "remove")
+		m.routeMgr.UpdateLabelRoute(&labelCfg, "add")
+	}
+	binding.cfg = &labelCfg
+}
+
+func (m *LabelMgr) UnbindLabel(key string) {
+	binding, ok := m.bindings[key]
+	if !ok {
+		m.logger.Errf("LabelMgr: UnbindLabel - label binding not found for %s", key)
+		return
+	}
+
+	binding.refCount--
+	if binding.refCount > 0 {
+		return
+	}
+
+	m.logger.Infof("LabelMgr: Release label %d bound to %s", binding.label, key)
+	if m.routeMgr != nil {
+		m.routeMgr.UpdateLabelRoute(binding.cfg, "remove")
+	}
+	delete(m.bindings, key)
+	m.freeLabel(binding.label)
+}
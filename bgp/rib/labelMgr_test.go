@@ -0,0 +1,164 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// labelMgr_test.go
+package rib
+
+import (
+	"l3/bgp/config"
+	"l3/bgp/packet"
+	"testing"
+)
+
+func TestLabelMgrPerPrefix(t *testing.T) {
+	logger := getLogger(t)
+	gConf, _ := getConfObjects("192.168.0.100", uint32(1234), uint32(4321))
+	gConf.LabelRangeStart = 1000
+	labelMgr := NewLabelMgr(logger, gConf, &RouteMgr{t})
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiLabelledUnicast)
+
+	cfg := &config.LabelRouteConfig{Op: config.LabelOpSwap, NextHopIp: "10.1.1.1", OutLabels: []uint32{200}}
+	key1 := labelMgr.GetLabelKey(protoFamily, "20.1.10.0/24", cfg)
+	key2 := labelMgr.GetLabelKey(protoFamily, "20.1.20.0/24", cfg)
+	if key1 == key2 {
+		t.Fatal("LabelMgr per prefix allocation returned the same key", key1, "for different prefixes")
+	}
+
+	label1, err := labelMgr.BindLabel(key1, cfg)
+	if err != nil || label1 != 1000 {
+		t.Fatal("LabelMgr BindLabel expected label 1000, got", label1, "error", err)
+	}
+	label2, err := labelMgr.BindLabel(key2, cfg)
+	if err != nil || label2 != 1001 {
+		t.Fatal("LabelMgr BindLabel expected label 1001, got", label2, "error", err)
+	}
+
+	labelMgr.UnbindLabel(key1)
+	if _, ok := labelMgr.GetLabel(key1); ok {
+		t.Fatal("LabelMgr label for", key1, "not released after UnbindLabel")
+	}
+
+	key3 := labelMgr.GetLabelKey(protoFamily, "20.1.30.0/24", cfg)
+	label3, _ := labelMgr.BindLabel(key3, cfg)
+	if label3 != label1 {
+		t.Fatal("LabelMgr expected released label", label1, "to be reused, got", label3)
+	}
+}
+
+func TestLabelMgrPerNextHop(t *testing.T) {
+	logger := getLogger(t)
+	gConf, _ := getConfObjects("192.168.0.100", uint32(1234), uint32(4321))
+	gConf.LabelAllocation = config.LabelAllocPerNextHop
+	labelMgr := NewLabelMgr(logger, gConf, &RouteMgr{t})
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiLabelledUnicast)
+
+	cfg := &config.LabelRouteConfig{Op: config.LabelOpSwap, NextHopIp: "10.1.1.1", OutLabels: []uint32{200}}
+	key1 := labelMgr.GetLabelKey(protoFamily, "20.1.10.0/24", cfg)
+	key2 := labelMgr.GetLabelKey(protoFamily, "20.1.20.0/24", cfg)
+	if key1 != key2 {
+		t.Fatal("LabelMgr per next hop allocation expected the same key, got", key1, key2)
+	}
+
+	label1, _ := labelMgr.BindLabel(key1, cfg)
+	label2, _ := labelMgr.BindLabel(key2, cfg)
+	if label1 != label2 || label1 != packet.MPLSLabelMinUnreserved {
+		t.Fatal("LabelMgr per next hop allocation expected shared label", packet.MPLSLabelMinUnreserved, "got",
+			label1, label2)
+	}
+
+	otherCfg := &config.LabelRouteConfig{Op: config.LabelOpSwap, NextHopIp: "10.1.1.1", OutLabels: []uint32{300}}
+	key3 := labelMgr.GetLabelKey(protoFamily, "20.1.30.0/24", otherCfg)
+	if key3 == key1 {
+		t.Fatal("LabelMgr per next hop allocation expected a different key for different remote labels")
+	}
+
+	labelMgr.UnbindLabel(key1)
+	if _, ok := labelMgr.GetLabel(key2); !ok {
+		t.Fatal("LabelMgr shared label released while still in use")
+	}
+	labelMgr.UnbindLabel(key2)
+	if labelMgr.GetNumBindings() != 0 {
+		t.Fatal("LabelMgr expected no bindings, got", labelMgr.GetNumBindings())
+	}
+}
+
+func TestLabelMgrRangeExhausted(t *testing.T) {
+	logger := getLogger(t)
+	gConf, _ := getConfObjects("192.168.0.100", uint32(1234), uint32(4321))
+	gConf.LabelRangeStart = 100
+	gConf.LabelRangeEnd = 101
+	labelMgr := NewLabelMgr(logger, gConf, nil)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiLabelledUnicast)
+	cfg := &config.LabelRouteConfig{Op: config.LabelOpPop}
+
+	for i, cidr := range []string{"20.1.10.0/24", "20.1.20.0/24", "20.1.30.0/24"} {
+		_, err := labelMgr.BindLabel(labelMgr.GetLabelKey(protoFamily, cidr, cfg), cfg)
+		if i < 2 && err != nil {
+			t.Fatal("LabelMgr BindLabel failed for", cidr, "with error:", err)
+		} else if i == 2 && err == nil {
+			t.Fatal("LabelMgr BindLabel expected failure when label range is exhausted, got NO error")
+		}
+	}
+}
+
+func TestLabelMgrRangeChange(t *testing.T) {
+	logger := getLogger(t)
+	gConf, _ := getConfObjects("192.168.0.100", uint32(1234), uint32(4321))
+	gConf.LabelRangeStart = 1000
+	gConf.LabelRangeEnd = 1001
+	labelMgr := NewLabelMgr(logger, gConf, nil)
+	protoFamily := packet.GetProtocolFamily(packet.AfiIP, packet.SafiLabelledUnicast)
+	cfg := &config.LabelRouteConfig{Op: config.LabelOpPop}
+
+	key1 := labelMgr.GetLabelKey(protoFamily, "20.1.10.0/24", cfg)
+	key2 := labelMgr.GetLabelKey(protoFamily, "20.1.20.0/24", cfg)
+	labelMgr.BindLabel(key1, cfg)
+	labelMgr.BindLabel(key2, cfg)
+	labelMgr.UnbindLabel(key1)
+
+	gConf.LabelRangeStart = 2000
+	gConf.LabelRangeEnd = 2001
+	key3 := labelMgr.GetLabelKey(protoFamily, "20.1.30.0/24", cfg)
+	label3, err := labelMgr.BindLabel(key3, cfg)
+	if err != nil || label3 != 2000 {
+		t.Fatal("LabelMgr expected label 2000 from the new range, got", label3, "error", err)
+	}
+
+	labelMgr.UnbindLabel(key2)
+	key4 := labelMgr.GetLabelKey(protoFamily, "20.1.40.0/24", cfg)
+	label4, err := labelMgr.BindLabel(key4, cfg)
+	if err != nil || label4 != 2001 {
+		t.Fatal("LabelMgr expected label 2001 from the new range, got", label4, "error", err)
+	}
+	key5 := labelMgr.GetLabelKey(protoFamily, "20.1.50.0/24", cfg)
+	if label5, err := labelMgr.BindLabel(key5, cfg); err == nil {
+		t.Fatal("LabelMgr expected the new range to be exhausted, got label", label5)
+	}
+
+	gConf.LabelRangeStart = 2000
+	gConf.LabelRangeEnd = 2003
+	label5, err := labelMgr.BindLabel(key5, cfg)
+	if err != nil || label5 != 2002 {
+		t.Fatal("LabelMgr expected label 2002 skipping the bound labels, got", label5, "error", err)
+	}
+}
@@ -83,6 +83,7 @@ type Path struct {
 	MED                uint32
 	LocalPref          uint32
 	AggregatedPaths    map[string]*Path
+	RouteTag           uint32 // route tag of the redistributed ribd route, not sent to the peers
 }
 
 func NewPath(locRib *LocRib, peer *base.NeighborConf, pa []packet.BGPPathAttr,
@@ -114,6 +115,7 @@ func (p *Path) Clone() *Path {
 		routeType:          p.routeType,
 		MED:                p.MED,
 		LocalPref:          p.LocalPref,
+		RouteTag:           p.RouteTag,
 	}
 
 	return path
@@ -63,6 +63,7 @@ type LocRib struct {
 	logger           *logging.Writer
 	gConf            *config.GlobalConfig
 	routeMgr         config.RouteMgrIntf
+	labelMgr         *LabelMgr
 	stateDBMgr       statedbclient.StateDBClient
 	destPathMap      map[uint32]map[string]*Destination
 	reachabilityMap  map[string]*ReachabilityInfo
@@ -81,6 +82,7 @@ func NewLocRib(logger *logging.Writer, rMgr config.RouteMgrIntf, sDBMgr statedbc
 		logger:           logger,
 		gConf:            gConf,
 		routeMgr:         rMgr,
+		labelMgr:         NewLabelMgr(logger, gConf, rMgr),
 		stateDBMgr:       sDBMgr,
 		destPathMap:      make(map[uint32]map[string]*Destination),
 		reachabilityMap:  make(map[string]*ReachabilityInfo),
@@ -110,6 +112,10 @@ func (l *LocRib) GetRoutesCount() map[uint32]uint32 {
 	return l.routesCount
 }
 
+func (l *LocRib) GetLabelMgr() *LabelMgr {
+	return l.labelMgr
+}
+
 func (l *LocRib) GetReachabilityInfo(ipStr string) *ReachabilityInfo {
 	if reachabilityInfo, ok := l.reachabilityMap[ipStr]; ok {
 		return reachabilityInfo
@@ -286,6 +292,7 @@ func (l *LocRib) ProcessRoutes(peerIP string, add, rem []packet.NLRI, addPath, r
 		}
 
 		dest.AddOrUpdatePath(peerIP, nlri.GetPathId(), addPath)
+		dest.setPathLabels(addPath, packet.GetNLRILabels(nlri))
 		if !addPath.IsReachable(protoFamily) {
 			if _, ok := l.unreachablePaths[nextHopStr][addPath][dest]; !ok {
 				l.unreachablePaths[nextHopStr][addPath][dest] = make([]uint32, 0)
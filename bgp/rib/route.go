@@ -49,6 +49,7 @@ type Route struct {
 	OutPathId        uint32
 	PolicyList       []string
 	PolicyHitCounter int
+	Labels           []uint32
 }
 
 func NewRoute(dest *Destination, path *Path, action RouteAction, inPathId, outPathId uint32) *Route {
@@ -79,6 +80,10 @@ func NewRoute(dest *Destination, path *Path, action RouteAction, inPathId, outPa
 	}
 }
 
+func (r *Route) GetRouteTag() uint32 {
+	return r.path.RouteTag
+}
+
 func (r *Route) setAction(action RouteAction) {
 	r.action = action
 }
@@ -32,6 +32,7 @@ import (
 	_ "fmt"
 	"io/ioutil"
 	"ndpd"
+	"ospfd"
 	"ribd"
 	"strconv"
 	"time"
@@ -209,6 +210,36 @@ func StartNdpdClient(logger *logging.Writer, filePath string, ndpdClient chan *n
 	ndpdClient <- client
 }
 
+func StartOspfdClient(logger *logging.Writer, filePath string, ospfdClient chan *ospfd.OSPFDServicesClient) {
+	fileName := filePath + ClientsFileName
+	clientJson, err := getClient(logger, fileName, "ospfd")
+	if err != nil || clientJson == nil {
+		ospfdClient <- nil
+		return
+	}
+
+	clientTransport, protocolFactory, err := ipcutils.CreateIPCHandles("localhost:" + strconv.Itoa(clientJson.Port))
+	if err != nil {
+		logger.Infof("Failed to connect to OSPFd, retrying until connection is successful")
+		count := 0
+		ticker := time.NewTicker(time.Duration(1000) * time.Millisecond)
+		for _ = range ticker.C {
+			clientTransport, protocolFactory, err = ipcutils.CreateIPCHandles("localhost:" + strconv.Itoa(clientJson.Port))
+			if err == nil {
+				ticker.Stop()
+				break
+			}
+			count++
+			if (count % 10) == 0 {
+				logger.Infof("Still can't connect to OSPFd, retrying...")
+			}
+		}
+	}
+
+	client := ospfd.NewOSPFDServicesClientFactory(clientTransport, protocolFactory)
+	ospfdClient <- client
+}
+
 func StartBfddClient(logger *logging.Writer, filePath string, bfddClient chan *bfdd.BFDDServicesClient) {
 	fileName := filePath + ClientsFileName
 	clientJson, err := getClient(logger, fileName, "bfdd")
@@ -926,13 +926,13 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 		}
 	}
 
-	if p.isAdvertisable(path) {
+	if p.isAdvertisable(path) && dest.HasLocalLabel() {
 		route := dest.LocRibPathRoute
 		if path != nil { // Loc-RIB path changed
 			if canAdvertise {
 				pathAdded, protoFamilyAdded, newUpdated = p.addPathFamilyToUpdated(pathAdded, protoFamilyAdded, path,
 					protoFamily, newUpdated)
-				nlri := packet.NewExtNLRI(route.OutPathId, dest.NLRI.GetIPPrefix())
+				nlri := packet.NewNLRIWithPathId(route.OutPathId, dest.GetAdvertisedNLRI())
 				newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily], nlri)
 			}
 		} else {
@@ -943,7 +943,7 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 
 	for i := 0; i < len(dest.AddPaths) && len(pathIdMap) < (addPathsTx-1); i++ {
 		route := dest.GetPathRoute(dest.AddPaths[i])
-		if route != nil && p.isAdvertisable(dest.AddPaths[i]) {
+		if route != nil && p.isAdvertisable(dest.AddPaths[i]) && dest.HasLocalLabel() {
 			pathIdMap[route.OutPathId] = dest.AddPaths[i]
 		}
 	}
@@ -951,7 +951,7 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 	for ribOutPathId, ribOutPath := range ribOutRoute.GetPathMap() {
 		if path, ok := pathIdMap[ribOutPathId]; !ok {
 			if canWithdraw {
-				nlri := packet.NewExtNLRI(ribOutPathId, dest.NLRI.GetIPPrefix())
+				nlri := packet.NewNLRIWithPathId(ribOutPathId, dest.GetWithdrawnNLRI())
 				withdrawList[protoFamily] = append(withdrawList[protoFamily], nlri)
 			}
 			ribOutRoute.RemovePath(ribOutPathId)
@@ -961,7 +961,7 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 			if canAdvertise {
 				pathAdded, protoFamilyAdded, newUpdated = p.addPathFamilyToUpdated(pathAdded, protoFamilyAdded, path,
 					protoFamily, newUpdated)
-				nlri := packet.NewExtNLRI(ribOutPathId, dest.NLRI.GetIPPrefix())
+				nlri := packet.NewNLRIWithPathId(ribOutPathId, dest.GetAdvertisedNLRI())
 				newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily], nlri)
 			}
 			ribOutRoute.AddPath(ribOutPathId, path)
@@ -973,7 +973,7 @@ func (p *Peer) calculateAddPathsAdvertisements(dest *bgprib.Destination, path *b
 		if canAdvertise {
 			pathAdded, protoFamilyAdded, newUpdated = p.addPathFamilyToUpdated(pathAdded, protoFamilyAdded, path,
 				protoFamily, newUpdated)
-			nlri := packet.NewExtNLRI(pathId, dest.NLRI.GetIPPrefix())
+			nlri := packet.NewNLRIWithPathId(pathId, dest.GetAdvertisedNLRI())
 			newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily], nlri)
 		}
 		ribOutRoute.AddPath(pathId, path)
@@ -1037,11 +1037,11 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 
 					if addPathsTx > 0 {
 						for pathId, _ := range route.GetPathMap() {
-							nlri := packet.NewExtNLRI(pathId, dest.NLRI.GetIPPrefix())
+							nlri := packet.NewNLRIWithPathId(pathId, dest.GetWithdrawnNLRI())
 							withdrawList[protoFamily] = append(withdrawList[protoFamily], nlri)
 						}
 					} else {
-						withdrawList[protoFamily] = append(withdrawList[protoFamily], dest.NLRI)
+						withdrawList[protoFamily] = append(withdrawList[protoFamily], dest.GetWithdrawnNLRI())
 					}
 					route.RemoveAllPaths()
 				}
@@ -1069,10 +1069,10 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 					newUpdated, withdrawList = p.calculateAddPathsAdvertisements(dest, path, newUpdated,
 						withdrawList, addPathsTx)
 				} else {
-					if !p.isAdvertisable(path) {
+					if !p.isAdvertisable(path) || !dest.HasLocalLabel() {
 						if ribOutRoute := p.ribOut[protoFamily][ip]; ribOutRoute != nil &&
 							p.checkRIBOutWithdraw(ribOutRoute) {
-							withdrawList[protoFamily] = append(withdrawList[protoFamily], dest.NLRI)
+							withdrawList[protoFamily] = append(withdrawList[protoFamily], dest.GetWithdrawnNLRI())
 							p.ribOut[protoFamily][ip].RemoveAllPaths()
 							delete(p.ribOut[protoFamily], ip)
 						}
@@ -1098,7 +1098,7 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 									newUpdated[path][protoFamily] = make([]packet.NLRI, 0)
 								}
 								newUpdated[path][protoFamily] = append(newUpdated[path][protoFamily],
-									dest.NLRI.GetIPPrefix())
+									dest.GetAdvertisedNLRI())
 							}
 						}
 						ribOutRoute.AddPath(pathId, path)
@@ -1156,7 +1156,12 @@ func (p *Peer) SendUpdate(updated map[uint32]map[*bgprib.Path][]*bgprib.Destinat
 
 		for protoFamily, nlriList := range pfNLRIMap {
 			if len(nlriList) > 0 {
-				mpReachNLRI := packet.ConstructIPv6MPReachNLRI(protoFamily, localAddress, nil, nlriList)
+				var mpReachNLRI *packet.BGPPathAttrMPReachNLRI
+				if packet.IsLabelledFamily(protoFamily) {
+					mpReachNLRI = packet.ConstructLabelledMPReachNLRI(protoFamily, localAddress, nlriList)
+				} else {
+					mpReachNLRI = packet.ConstructIPv6MPReachNLRI(protoFamily, localAddress, nil, nlriList)
+				}
 				pa := packet.CopyPathAttrs(path.PathAttrs)
 				pa = packet.AddMPReachNLRIToPathAttrs(pa, mpReachNLRI)
 				updateMsg = packet.NewBGPUpdateMessage(nil, pa, ipv4List)
@@ -52,6 +52,9 @@ import (
 	"utils/statedbclient"
 )
 
+// Time without updates after which the initial convergence is done
+const ConvergeSettleTime = 10 * time.Second
+
 type GlobalUpdate struct {
 	BGPConfig *bgpd.BGPGlobal
 	OldConfig config.GlobalConfig
@@ -126,7 +129,10 @@ type BGPServer struct {
 	RoutesCh         chan *config.RouteCh
 	acceptCh         chan *net.TCPConn
 	ServerUpCh       chan bool
+	ConvergedCh      chan bool
 	GlobalCfgDone    bool
+	convergeTimer    *time.Timer
+	converged        bool
 
 	NeighborMutex     sync.RWMutex
 	PeerMap           map[string]*Peer
@@ -173,6 +179,9 @@ func NewBGPServer(logger *logging.Writer, policyManager *bgppolicy.BGPPolicyMana
 	bgpServer.IntfMapCh = make(chan config.IntfMapInfo)
 	bgpServer.RoutesCh = make(chan *config.RouteCh)
 	bgpServer.ServerUpCh = make(chan bool)
+	bgpServer.ConvergedCh = make(chan bool, 1)
+	bgpServer.convergeTimer = time.NewTimer(ConvergeSettleTime)
+	bgpServer.convergeTimer.Stop()
 
 	bgpServer.NeighborMutex = sync.RWMutex{}
 	bgpServer.PeerMap = make(map[string]*Peer)
@@ -568,7 +577,8 @@ func (s *BGPServer) ApplyAggregateAction(actionInfo interface{}, conditionInfo [
 		SendSummaryOnly: aggActions.SendSummaryOnly,
 	}
 
-	s.logger.Infof("ApplyAggregateAction: ipPrefix=%+v, aggPrefix=%+v", ipPrefix.Prefix, aggPrefix.Prefix)
+	s.logger.Infof("ApplyAggregateAction: ipPrefix=%+v, aggPrefix=%+v, route tag=%d", ipPrefix.Prefix,
+		aggPrefix.Prefix, policyParams.route.GetRouteTag())
 	var updated map[uint32]map[*bgprib.Path][]*bgprib.Destination
 	var withdrawn []*bgprib.Destination
 	var updatedAddPaths []*bgprib.Destination
@@ -1095,14 +1105,45 @@ func (s *BGPServer) convertDestIPToIPPrefix(routes []*config.RouteInfo) map[uint
 	return pfNLRI
 }
 
+// The redistributed routes of a route tag share a clone of the connected routes path that carries the tag
+func (s *BGPServer) getConnRoutesPath(routeTag uint32) *bgprib.Path {
+	if routeTag == 0 {
+		return s.ConnRoutesPath
+	}
+	path := s.ConnRoutesPath.Clone()
+	path.RouteTag = routeTag
+	return path
+}
+
 func (s *BGPServer) ProcessConnectedRoutes(installedRoutes, withdrawnRoutes []*config.RouteInfo) {
 	s.logger.Info("valid routes:", installedRoutes, "invalid routes:", withdrawnRoutes)
-	valid := s.convertDestIPToIPPrefix(installedRoutes)
+	tagRoutes := make(map[uint32][]*config.RouteInfo)
+	for _, route := range installedRoutes {
+		tagRoutes[route.RouteTag] = append(tagRoutes[route.RouteTag], route)
+	}
 	invalid := s.convertDestIPToIPPrefix(withdrawnRoutes)
-	s.logger.Info("pfNLRI valid:", valid, "invalid:", invalid)
 	routerId := s.BgpConfig.Global.Config.RouterId.String()
+	valid := s.convertDestIPToIPPrefix(tagRoutes[0])
+	delete(tagRoutes, 0)
+	s.logger.Info("pfNLRI valid:", valid, "invalid:", invalid)
 	updated, withdrawn, updatedAddPaths := s.LocRib.ProcessConnectedRoutes(routerId, s.ConnRoutesPath, valid,
 		invalid, s.AddPathCount)
+	for routeTag, routes := range tagRoutes {
+		valid = s.convertDestIPToIPPrefix(routes)
+		s.logger.Info("pfNLRI valid:", valid, "route tag:", routeTag)
+		tagUpdated, tagWithdrawn, tagUpdatedAddPaths := s.LocRib.ProcessConnectedRoutes(routerId,
+			s.getConnRoutesPath(routeTag), valid, make(map[uint32][]packet.NLRI), s.AddPathCount)
+		for protoFamily, pathDestMap := range tagUpdated {
+			if _, ok := updated[protoFamily]; !ok {
+				updated[protoFamily] = make(map[*bgprib.Path][]*bgprib.Destination)
+			}
+			for path, dests := range pathDestMap {
+				updated[protoFamily][path] = append(updated[protoFamily][path], dests...)
+			}
+		}
+		withdrawn = append(withdrawn, tagWithdrawn...)
+		updatedAddPaths = append(updatedAddPaths, tagUpdatedAddPaths...)
+	}
 	updated, withdrawn, updatedAddPaths = s.CheckForAggregation(updated, withdrawn, updatedAddPaths)
 	s.SendUpdate(updated, withdrawn, updatedAddPaths)
 }
@@ -2218,6 +2259,7 @@ func (s *BGPServer) listenChannelUpdates() {
 			}
 
 			s.SendAllRoutesToPeer(peer)
+			s.restartConvergeTimer()
 
 		case peerIP := <-s.PeerConnBrokenCh:
 			s.logger.Infof("Server: Peer %s FSM connection broken", peerIP)
@@ -2247,6 +2289,10 @@ func (s *BGPServer) listenChannelUpdates() {
 		case pktInfo := <-s.BGPPktSrcCh:
 			s.logger.Info("Received BGP message from peer %s", pktInfo.Src)
 			s.ProcessUpdate(pktInfo)
+			s.restartConvergeTimer()
+
+		case <-s.convergeTimer.C:
+			s.setConverged()
 
 		case reachabilityInfo := <-s.ReachabilityCh:
 			s.logger.Info("Server: Get reachability info for ip", reachabilityInfo.IP)
@@ -2300,6 +2346,35 @@ func (s *BGPServer) listenChannelUpdates() {
 
 }
 
+/*  BGP has converged once a peer is established and no update was received for
+ *  ConvergeSettleTime. Only the initial convergence is signalled, it ends the
+ *  startup max-metric period of ospfd (RFC 6987).
+ */
+func (s *BGPServer) restartConvergeTimer() {
+	if s.converged {
+		return
+	}
+	if !s.convergeTimer.Stop() {
+		select {
+		case <-s.convergeTimer.C:
+		default:
+		}
+	}
+	s.convergeTimer.Reset(ConvergeSettleTime)
+}
+
+func (s *BGPServer) setConverged() {
+	if s.converged {
+		return
+	}
+	s.logger.Info("Server: BGP converged")
+	s.converged = true
+	select {
+	case s.ConvergedCh <- true:
+	default:
+	}
+}
+
 func (s *BGPServer) ProcessIntfMapUpdates(cfg []config.IntfMapInfo) {
 	s.logger.Infof("ProcessIntfMapUpdates, cfg = %+v", cfg)
 	if s.IntfIdNameMap == nil {
@@ -2411,11 +2486,11 @@ func (s *BGPServer) GetBGPGlobalState() config.GlobalState {
 	s.BgpConfig.Global.State.Totalv6Prefixes = 0
 	for protoFamily, count := range routesCount {
 		switch protoFamily {
-		case packet.ProtocolFamilyMap["ipv4-unicast"]:
-			s.BgpConfig.Global.State.Totalv4Prefixes = count
+		case packet.ProtocolFamilyMap["ipv4-unicast"], packet.ProtocolFamilyMap["ipv4-labelled-unicast"]:
+			s.BgpConfig.Global.State.Totalv4Prefixes += count
 
-		case packet.ProtocolFamilyMap["ipv6-unicast"]:
-			s.BgpConfig.Global.State.Totalv6Prefixes = count
+		case packet.ProtocolFamilyMap["ipv6-unicast"], packet.ProtocolFamilyMap["ipv6-labelled-unicast"]:
+			s.BgpConfig.Global.State.Totalv6Prefixes += count
 
 		default:
 			s.logger.Err("Unknown protocol family type", protoFamily)
@@ -0,0 +1,447 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// main.go
+
+/*  BGP scale test. Starts a BGP server with stubbed out FlexSwitch clients on the loopback interface
+ *  and brings up eBGP sessions from synthetic speakers that are built on the packet library. The
+ *  speakers inject a table of the configured size, flap part of it and finally withdraw part of it.
+ *  An additional speaker that does not advertise anything tracks the routes the server advertises.
+ *  For every phase the test reports the convergence time, the memory used by the process and the
+ *  rate at which the server sent UPDATE messages to every peer.
+ *
+ *  The speakers use addresses from 127.0.1.1 onwards as their session source address, so the test
+ *  needs to run on a host where the whole 127.0.0.0/8 network is assigned to the loopback
+ *  interface (the default on Linux). Example for a full table from 8 peers:
+ *
+ *      scale -peers 8 -prefixes 800000 -paths 3 -cpuprofile bgp.prof
+ *
+ *  A non zero exit code is returned if a phase does not converge or takes longer than
+ *  -max-convergence, so the test can be used to catch performance regressions.
+ */
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"l3/bgp/config"
+	bgppolicy "l3/bgp/policy"
+	"l3/bgp/server"
+	"l3/bgp/utils"
+	"net"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
+	"utils/logging"
+)
+
+const (
+	convergencePollInterval = 100 * time.Millisecond
+	peerConnectTimeout      = 2 * time.Minute
+)
+
+type phaseResult struct {
+	name          string
+	converged     bool
+	convergence   time.Duration
+	sendTime      time.Duration
+	updatesSent   uint64
+	routes        int
+	routesAdded   uint64
+	routesRemoved uint64
+	memStats      runtime.MemStats
+	numGoroutines int
+	peerStats     []PeerStats
+}
+
+type ScaleTest struct {
+	bgpServer  *server.BGPServer
+	routeMgr   *routeMgr
+	workload   *Workload
+	speakers   []*Speaker
+	observer   *Speaker
+	settleTime time.Duration
+	timeout    time.Duration
+	flapRounds int
+	flapDelay  time.Duration
+	baseMem    runtime.MemStats
+	results    []*phaseResult
+	serverAddr *net.TCPAddr
+	localAS    uint32
+	holdTime   uint16
+}
+
+func getSpeakerAddr(base net.IP, idx int) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(base.To4())+uint32(idx))
+	return ip
+}
+
+func (t *ScaleTest) startServer(logger *logging.Writer, routerId net.IP) {
+	t.routeMgr = &routeMgr{}
+	policyManager := bgppolicy.NewPolicyManager(logger, &policyMgr{})
+	t.bgpServer = server.NewBGPServer(logger, policyManager, &intfMgr{}, t.routeMgr, &bfdMgr{}, &stateDBMgr{})
+	go t.bgpServer.StartServer()
+	<-t.bgpServer.ServerUpCh
+
+	gConf := config.GlobalConfig{
+		AS:       t.localAS,
+		RouterId: routerId,
+	}
+	t.bgpServer.GlobalConfigCh <- server.GlobalUpdate{nil, config.GlobalConfig{}, gConf, make([]bool, 0), nil,
+		"create"}
+}
+
+func (t *ScaleTest) addPeer(speaker *Speaker) {
+	nConf := config.NeighborConfig{
+		BaseConfig: config.BaseConfig{
+			PeerAS:           speaker.GetAS(),
+			LocalAS:          t.localAS,
+			PeerAddressType:  config.PeerAddressV4,
+			HoldTime:         uint32(t.holdTime),
+			KeepaliveTime:    uint32(t.holdTime / 3),
+			ConnectRetryTime: 60,
+		},
+		NeighborAddress: speaker.GetAddress(),
+		IfIndex:         -1,
+	}
+	t.bgpServer.AddPeerCh <- server.PeerUpdate{NewPeer: nConf, Op: "create"}
+}
+
+func (t *ScaleTest) connectSpeakers() error {
+	allSpeakers := append([]*Speaker{t.observer}, t.speakers...)
+	for _, speaker := range allSpeakers {
+		t.addPeer(speaker)
+	}
+
+	errCh := make(chan error, len(allSpeakers))
+	for _, speaker := range allSpeakers {
+		go func(speaker *Speaker) {
+			errCh <- speaker.Connect(t.serverAddr, peerConnectTimeout)
+		}(speaker)
+	}
+	for i := 0; i < len(allSpeakers); i++ {
+		if err := <-errCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ScaleTest) closeSpeakers() {
+	t.observer.Close()
+	for _, speaker := range t.speakers {
+		speaker.Close()
+	}
+}
+
+func (t *ScaleTest) waitForConvergence(start, sendDone time.Time, expected int) (time.Duration, bool) {
+	allSpeakers := append([]*Speaker{t.observer}, t.speakers...)
+	for {
+		time.Sleep(convergencePollInterval)
+		lastRx := sendDone
+		for _, speaker := range allSpeakers {
+			if stats := speaker.GetStats(); stats.LastRx.After(lastRx) {
+				lastRx = stats.LastRx
+			}
+		}
+
+		if t.observer.GetNumRoutes() == expected && time.Since(lastRx) >= t.settleTime {
+			return lastRx.Sub(start), true
+		}
+		if time.Since(start) > t.timeout {
+			return time.Since(start), false
+		}
+	}
+}
+
+/*  Runs one phase of the test. build returns the encoded messages every speaker sends during the
+ *  phase, rounds of the messages are sent flapDelay apart. The messages are built before the phase
+ *  starts so that only the server is measured.
+ */
+func (t *ScaleTest) runPhase(name string, rounds int, build func(speaker int) ([][]byte, error),
+	expected int) (*phaseResult, error) {
+	fmt.Printf("Running phase %s\n", name)
+	pkts := make([][][]byte, len(t.speakers))
+	for idx := range t.speakers {
+		speakerPkts, err := build(idx)
+		if err != nil {
+			return nil, err
+		}
+		pkts[idx] = speakerPkts
+	}
+
+	result := &phaseResult{name: name}
+	t.observer.ResetStats()
+	for _, speaker := range t.speakers {
+		speaker.ResetStats()
+	}
+	addedBefore, removedBefore := t.routeMgr.GetCounters()
+
+	start := time.Now()
+	for round := 0; round < rounds; round++ {
+		if round > 0 && t.flapDelay > 0 {
+			time.Sleep(t.flapDelay)
+		}
+		var wg sync.WaitGroup
+		errCh := make(chan error, len(t.speakers))
+		for idx, speaker := range t.speakers {
+			wg.Add(1)
+			go func(speaker *Speaker, speakerPkts [][]byte) {
+				defer wg.Done()
+				if err := speaker.SendPkts(speakerPkts, true); err != nil {
+					errCh <- err
+				}
+			}(speaker, pkts[idx])
+		}
+		wg.Wait()
+		close(errCh)
+		if err := <-errCh; err != nil {
+			return nil, err
+		}
+	}
+	sendDone := time.Now()
+	result.sendTime = sendDone.Sub(start)
+
+	result.convergence, result.converged = t.waitForConvergence(start, sendDone, expected)
+	result.routes = t.observer.GetNumRoutes()
+	addedAfter, removedAfter := t.routeMgr.GetCounters()
+	result.routesAdded = addedAfter - addedBefore
+	result.routesRemoved = removedAfter - removedBefore
+
+	result.peerStats = append(result.peerStats, t.observer.GetStats())
+	for _, speaker := range t.speakers {
+		stats := speaker.GetStats()
+		result.updatesSent += stats.UpdatesTx
+		result.peerStats = append(result.peerStats, stats)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&result.memStats)
+	result.numGoroutines = runtime.NumGoroutine()
+	t.results = append(t.results, result)
+
+	for _, speaker := range append([]*Speaker{t.observer}, t.speakers...) {
+		if err := speaker.GetError(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (t *ScaleTest) run() error {
+	w := t.workload
+	numPrefixes := w.GetNumPrefixes()
+
+	_, err := t.runPhase("initial-load", 1, func(speaker int) ([][]byte, error) {
+		return w.BuildUpdates(speaker, w.GetPrefixes(speaker, nil))
+	}, numPrefixes)
+	if err != nil {
+		return err
+	}
+
+	if t.flapRounds > 0 && w.conf.FlapPct > 0 {
+		_, err = t.runPhase("flap", t.flapRounds, func(speaker int) ([][]byte, error) {
+			prefixes := w.GetPrefixes(speaker, func(idx int) bool {
+				return w.IsFlapped(idx) && w.IsFlapper(speaker, idx)
+			})
+			withdraws, err := w.BuildWithdraws(prefixes)
+			if err != nil {
+				return nil, err
+			}
+			updates, err := w.BuildUpdates(speaker, prefixes)
+			if err != nil {
+				return nil, err
+			}
+			return append(withdraws, updates...), nil
+		}, numPrefixes)
+		if err != nil {
+			return err
+		}
+	}
+
+	if w.conf.WithdrawPct > 0 {
+		_, err = t.runPhase("withdraw", 1, func(speaker int) ([][]byte, error) {
+			return w.BuildWithdraws(w.GetPrefixes(speaker, w.IsWithdrawn))
+		}, w.GetNumRemainingPrefixes())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toMB(bytes uint64) float64 {
+	return float64(bytes) / (1024 * 1024)
+}
+
+func (t *ScaleTest) printReport() {
+	fmt.Printf("\nBaseline heap in use %.1f MB\n", toMB(t.baseMem.HeapInuse))
+	for _, result := range t.results {
+		fmt.Printf("\nPhase %s\n", result.name)
+		fmt.Printf("  converged            %v\n", result.converged)
+		fmt.Printf("  convergence time     %v\n", result.convergence)
+		fmt.Printf("  injection time       %v\n", result.sendTime)
+		fmt.Printf("  updates injected     %d\n", result.updatesSent)
+		fmt.Printf("  routes advertised    %d\n", result.routes)
+		fmt.Printf("  RIB adds/removes     %d/%d\n", result.routesAdded, result.routesRemoved)
+		fmt.Printf("  heap in use          %.1f MB (+%.1f MB)\n", toMB(result.memStats.HeapInuse),
+			toMB(result.memStats.HeapInuse)-toMB(t.baseMem.HeapInuse))
+		fmt.Printf("  heap objects         %d\n", result.memStats.HeapObjects)
+		fmt.Printf("  goroutines           %d\n", result.numGoroutines)
+		fmt.Printf("  %-16s %10s %12s %12s %12s\n", "peer", "updates", "prefixes", "withdrawn", "updates/s")
+		for idx, stats := range result.peerStats {
+			var speaker *Speaker
+			if idx == 0 {
+				speaker = t.observer
+			} else {
+				speaker = t.speakers[idx-1]
+			}
+			fmt.Printf("  %-16s %10d %12d %12d %12.1f\n", speaker.GetAddress(), stats.UpdatesRx, stats.PrefixesRx,
+				stats.WithdrawnRx, stats.GetUpdateRate())
+		}
+	}
+}
+
+func main() {
+	numPeers := flag.Int("peers", 4, "Number of speakers that inject routes")
+	numPrefixes := flag.Int("prefixes", 100000, "Number of unique prefixes in the table")
+	paths := flag.Int("paths", 2, "Number of speakers that advertise each prefix")
+	attrSets := flag.Int("attr-sets", 2000, "Number of distinct path attribute sets per speaker")
+	maxPathLen := flag.Int("max-path-len", 8, "Maximum AS path length")
+	maxCommunities := flag.Int("max-communities", 4, "Maximum number of communities per path")
+	flapPct := flag.Int("flap-pct", 10, "Percentage of the prefixes that flap during the flap phase")
+	flapRounds := flag.Int("flap-rounds", 3, "Number of times the prefixes flap")
+	flapDelay := flag.Duration("flap-interval", 0, "Time between the flap rounds")
+	withdrawPct := flag.Int("withdraw-pct", 50, "Percentage of the prefixes withdrawn in the withdraw phase")
+	port := flag.Int("port", 1179, "TCP port the BGP server listens on")
+	localAS := flag.Uint("as", 65000, "AS number of the BGP server")
+	firstPeerAS := flag.Uint("peer-as", 65001, "AS number of the first speaker")
+	speakerAddr := flag.String("speaker-addr", "127.0.1.1", "Address of the first speaker")
+	holdTime := flag.Uint("hold-time", 180, "Hold time of the sessions")
+	settleTime := flag.Duration("settle", 3*time.Second, "Time without updates after which a phase is converged")
+	timeout := flag.Duration("timeout", 10*time.Minute, "Maximum time to wait for a phase to converge")
+	maxConvergence := flag.Duration("max-convergence", 0, "Fail if a phase takes longer to converge, 0 disables")
+	seed := flag.Int64("seed", 1, "Seed for the random path attributes")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of the test to this file")
+	memProfile := flag.String("memprofile", "", "Write a heap profile at the end of the test to this file")
+	flag.Parse()
+
+	baseAddr := net.ParseIP(*speakerAddr)
+	if baseAddr == nil || baseAddr.To4() == nil {
+		fmt.Println("Invalid speaker address", *speakerAddr)
+		os.Exit(1)
+	}
+
+	logger, err := logging.NewLogger("bgpd", "BGP", true)
+	if err != nil {
+		fmt.Println("Failed to start the logger. Exiting!!")
+		os.Exit(1)
+	}
+	utils.SetLogger(logger)
+	config.BGPPort = strconv.Itoa(*port)
+
+	t := &ScaleTest{
+		settleTime: *settleTime,
+		timeout:    *timeout,
+		flapRounds: *flapRounds,
+		flapDelay:  *flapDelay,
+		serverAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: *port},
+		localAS:    uint32(*localAS),
+		holdTime:   uint16(*holdTime),
+	}
+
+	speakerASes := make([]uint32, 0, *numPeers)
+	speakerIPs := make([]net.IP, 0, *numPeers)
+	for idx := 0; idx < *numPeers; idx++ {
+		speaker := NewSpeaker(idx, uint32(*firstPeerAS)+uint32(idx), getSpeakerAddr(baseAddr, idx), t.holdTime, false)
+		t.speakers = append(t.speakers, speaker)
+		speakerASes = append(speakerASes, speaker.GetAS())
+		speakerIPs = append(speakerIPs, speaker.GetAddress())
+	}
+	t.observer = NewSpeaker(*numPeers, uint32(*firstPeerAS)+uint32(*numPeers), getSpeakerAddr(baseAddr, *numPeers),
+		t.holdTime, true)
+
+	t.workload, err = NewWorkload(WorkloadConfig{
+		NumSpeakers:    *numPeers,
+		NumPrefixes:    *numPrefixes,
+		PathsPerPrefix: *paths,
+		AttrSets:       *attrSets,
+		MaxPathLen:     *maxPathLen,
+		MaxCommunities: *maxCommunities,
+		FlapPct:        *flapPct,
+		WithdrawPct:    *withdrawPct,
+		Seed:           *seed,
+	}, speakerASes, speakerIPs)
+	if err != nil {
+		fmt.Println("Failed to create the workload, error:", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Starting BGP server on port %d\n", *port)
+	t.startServer(logger, net.ParseIP("127.0.0.1"))
+	fmt.Printf("Connecting %d speakers\n", *numPeers+1)
+	if err = t.connectSpeakers(); err != nil {
+		fmt.Println("Failed to connect the speakers, error:", err)
+		os.Exit(1)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&t.baseMem)
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Println("Failed to create CPU profile, error:", err)
+			os.Exit(1)
+		}
+		pprof.StartCPUProfile(f)
+	}
+	err = t.run()
+	t.closeSpeakers()
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	if *memProfile != "" {
+		if f, err := os.Create(*memProfile); err == nil {
+			pprof.WriteHeapProfile(f)
+			f.Close()
+		}
+	}
+
+	t.printReport()
+	if err != nil {
+		fmt.Println("\nScale test failed, error:", err)
+		os.Exit(1)
+	}
+	for _, result := range t.results {
+		if !result.converged || (*maxConvergence != 0 && result.convergence > *maxConvergence) {
+			fmt.Printf("\nPhase %s did not converge within the limit\n", result.name)
+			os.Exit(1)
+		}
+	}
+}
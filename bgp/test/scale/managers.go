@@ -0,0 +1,174 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// managers.go
+package main
+
+import (
+	"errors"
+	"l3/bgp/config"
+	"models/objects"
+	"sync/atomic"
+	"utils/statedbclient"
+)
+
+/*  The managers below stand in for the FlexSwitch clients so that the BGP server can run without
+ *  asicd, ribd, bfdd or the state DB. Every next hop is treated as reachable and the routes that
+ *  the server programs are only counted.
+ */
+type intfMgr struct {
+}
+
+func (m *intfMgr) Start() {
+}
+
+func (m *intfMgr) PortStateChange() {
+}
+
+func (m *intfMgr) GetIPv4Intfs() []*config.IntfStateInfo {
+	return nil
+}
+
+func (m *intfMgr) GetIPv6Intfs() []*config.IntfStateInfo {
+	return nil
+}
+
+func (m *intfMgr) GetIPv6Neighbors() []*config.IntfStateInfo {
+	return nil
+}
+
+func (m *intfMgr) GetPortInfo() []config.IntfMapInfo {
+	return nil
+}
+
+func (m *intfMgr) GetVlanInfo() []config.IntfMapInfo {
+	return nil
+}
+
+func (m *intfMgr) GetLogicalIntfInfo() []config.IntfMapInfo {
+	return nil
+}
+
+func (m *intfMgr) GetIPv4Information(ifIndex int32) (string, error) {
+	return "", errors.New("Interfaces are not supported by the scale test")
+}
+
+func (m *intfMgr) GetIPv6Information(ifIndex int32) (string, error) {
+	return "", errors.New("Interfaces are not supported by the scale test")
+}
+
+func (m *intfMgr) GetIfIndex(ifIndex, ifType int) int32 {
+	return int32(ifIndex)
+}
+
+type routeMgr struct {
+	routesAdded   uint64
+	routesRemoved uint64
+	labelUpdates  uint64
+}
+
+func (m *routeMgr) Start() {
+}
+
+func (m *routeMgr) GetNextHopInfo(ipAddr string, ifIndex int32) (*config.NextHopInfo, error) {
+	return &config.NextHopInfo{
+		IPAddr:      ipAddr,
+		Mask:        "255.255.255.255",
+		NextHopIp:   ipAddr,
+		IsReachable: true,
+	}, nil
+}
+
+func (m *routeMgr) CreateRoute(cfg *config.RouteConfig) {
+	atomic.AddUint64(&m.routesAdded, 1)
+}
+
+func (m *routeMgr) DeleteRoute(cfg *config.RouteConfig) {
+	atomic.AddUint64(&m.routesRemoved, 1)
+}
+
+func (m *routeMgr) UpdateRoute(cfg *config.RouteConfig, op string) {
+	if op == "add" {
+		atomic.AddUint64(&m.routesAdded, 1)
+	} else {
+		atomic.AddUint64(&m.routesRemoved, 1)
+	}
+}
+
+func (m *routeMgr) UpdateLabelRoute(cfg *config.LabelRouteConfig, op string) {
+	atomic.AddUint64(&m.labelUpdates, 1)
+}
+
+func (m *routeMgr) ApplyPolicy(applyList []*config.ApplyPolicyInfo, undoList []*config.ApplyPolicyInfo) {
+}
+
+func (m *routeMgr) GetRoutes() ([]*config.RouteInfo, []*config.RouteInfo) {
+	return nil, nil
+}
+
+func (m *routeMgr) GetCounters() (uint64, uint64) {
+	return atomic.LoadUint64(&m.routesAdded), atomic.LoadUint64(&m.routesRemoved)
+}
+
+type bfdMgr struct {
+}
+
+func (m *bfdMgr) Start() {
+}
+
+func (m *bfdMgr) CreateBfdSession(ipAddr string, iface string, sessionParam string) (bool, error) {
+	return true, nil
+}
+
+func (m *bfdMgr) DeleteBfdSession(ipAddr string, iface string) (bool, error) {
+	return true, nil
+}
+
+type policyMgr struct {
+}
+
+func (m *policyMgr) Start() {
+}
+
+/*  Route state objects are dropped. The client interface is embedded so that only the calls made
+ *  by the RIB need to be provided here.
+ */
+type stateDBMgr struct {
+	statedbclient.StateDBClient
+}
+
+func (m *stateDBMgr) Init() error {
+	return nil
+}
+
+func (m *stateDBMgr) AddObject(obj objects.ConfigObj) error {
+	return nil
+}
+
+func (m *stateDBMgr) DeleteObject(obj objects.ConfigObj) error {
+	return nil
+}
+
+func (m *stateDBMgr) UpdateObject(obj objects.ConfigObj) error {
+	return nil
+}
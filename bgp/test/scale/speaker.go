@@ -0,0 +1,341 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// speaker.go
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"l3/bgp/packet"
+	"net"
+	"sync"
+	"time"
+)
+
+const speakerWriteBufSize = 64 * 1024
+
+type PeerStats struct {
+	UpdatesTx   uint64
+	UpdatesRx   uint64
+	PrefixesRx  uint64
+	WithdrawnRx uint64
+	FirstRx     time.Time
+	LastRx      time.Time
+}
+
+// Rate at which the server sent UPDATE messages to the speaker.
+func (s PeerStats) GetUpdateRate() float64 {
+	elapsed := s.LastRx.Sub(s.FirstRx).Seconds()
+	if s.UpdatesRx == 0 || elapsed <= 0 {
+		return float64(s.UpdatesRx)
+	}
+	return float64(s.UpdatesRx) / elapsed
+}
+
+/*  Speaker is a minimal BGP speaker built on the packet library. It opens an eBGP session to the
+ *  server, injects pre encoded UPDATE messages and counts the UPDATE messages the server sends
+ *  back. A tracking speaker also keeps the set of prefixes it learnt from the server so that the
+ *  harness can tell when the server has advertised the expected table.
+ */
+type Speaker struct {
+	id        int
+	as        uint32
+	addr      net.IP
+	routerId  net.IP
+	holdTime  uint16
+	track     bool
+	peerAttrs packet.BGPPeerAttrs
+
+	conn        *net.TCPConn
+	writer      *bufio.Writer
+	writeMutex  sync.Mutex
+	established chan bool
+	errCh       chan error
+	stopCh      chan bool
+
+	statsMutex sync.Mutex
+	stats      PeerStats
+	routes     map[uint64]bool
+}
+
+func NewSpeaker(id int, as uint32, addr net.IP, holdTime uint16, track bool) *Speaker {
+	return &Speaker{
+		id:       id,
+		as:       as,
+		addr:     addr,
+		routerId: addr,
+		holdTime: holdTime,
+		track:    track,
+		peerAttrs: packet.BGPPeerAttrs{
+			ASSize:        2,
+			AddPathFamily: make(map[packet.AFI]map[packet.SAFI]uint8),
+		},
+		established: make(chan bool),
+		errCh:       make(chan error, 1),
+		stopCh:      make(chan bool),
+		routes:      make(map[uint64]bool),
+	}
+}
+
+func (s *Speaker) GetAS() uint32 {
+	return s.as
+}
+
+func (s *Speaker) GetAddress() net.IP {
+	return s.addr
+}
+
+func (s *Speaker) Connect(serverAddr *net.TCPAddr, timeout time.Duration) error {
+	conn, err := net.DialTCP("tcp4", &net.TCPAddr{IP: s.addr}, serverAddr)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.writer = bufio.NewWriterSize(conn, speakerWriteBufSize)
+
+	afiSafiMap := map[uint32]bool{packet.GetProtocolFamily(packet.AfiIP, packet.SafiUnicast): true}
+	openMsg := packet.NewBGPOpenMessage(s.as, s.holdTime, s.routerId.String(),
+		packet.ConstructOptParams(s.as, afiSafiMap, false, 0))
+	if err = s.sendMsg(openMsg); err != nil {
+		conn.Close()
+		return err
+	}
+
+	go s.readMessages()
+
+	select {
+	case <-s.established:
+		go s.sendKeepAlives()
+		return nil
+
+	case err = <-s.errCh:
+
+	case <-time.After(timeout):
+		err = errors.New(fmt.Sprintf("Timed out waiting for the session from %s to come up", s.addr))
+	}
+	conn.Close()
+	return err
+}
+
+func (s *Speaker) Close() {
+	if s.conn == nil {
+		return
+	}
+	close(s.stopCh)
+	s.conn.Close()
+}
+
+func (s *Speaker) sendMsg(msg *packet.BGPMessage) error {
+	pkt, err := msg.Encode()
+	if err != nil {
+		return err
+	}
+	return s.SendPkts([][]byte{pkt}, false)
+}
+
+// Writes the encoded messages to the session. isUpdate controls if the messages are counted as UPDATEs.
+func (s *Speaker) SendPkts(pkts [][]byte, isUpdate bool) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	for _, pkt := range pkts {
+		if _, err := s.writer.Write(pkt); err != nil {
+			return err
+		}
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+
+	if isUpdate {
+		s.statsMutex.Lock()
+		s.stats.UpdatesTx += uint64(len(pkts))
+		s.statsMutex.Unlock()
+	}
+	return nil
+}
+
+func (s *Speaker) sendKeepAlives() {
+	interval := time.Duration(s.holdTime/3) * time.Second
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.sendMsg(packet.NewBGPKeepAliveMessage()); err != nil {
+				return
+			}
+
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *Speaker) readMessages() {
+	isEstablished := false
+	headerBuf := make([]byte, packet.BGPMsgHeaderLen)
+	for {
+		if _, err := io.ReadFull(s.conn, headerBuf); err != nil {
+			s.reportError(err)
+			return
+		}
+
+		header := packet.NewBGPHeader()
+		header.Decode(headerBuf)
+		if header.Len() < uint32(packet.BGPMsgHeaderLen) {
+			s.reportError(errors.New(fmt.Sprintf("Speaker %s received message with length %d", s.addr,
+				header.Len())))
+			return
+		}
+
+		body := make([]byte, header.Len()-uint32(packet.BGPMsgHeaderLen))
+		if _, err := io.ReadFull(s.conn, body); err != nil {
+			s.reportError(err)
+			return
+		}
+
+		msg := packet.NewBGPMessage()
+		if err := msg.Decode(header, body, s.peerAttrs); err != nil {
+			s.reportError(err)
+			return
+		}
+
+		switch header.Type {
+		case packet.BGPMsgTypeOpen:
+			openMsg := msg.Body.(*packet.BGPOpen)
+			s.peerAttrs.ASSize = packet.GetASSize(openMsg)
+			if err := s.sendMsg(packet.NewBGPKeepAliveMessage()); err != nil {
+				s.reportError(err)
+				return
+			}
+
+		case packet.BGPMsgTypeKeepAlive:
+			if !isEstablished {
+				isEstablished = true
+				close(s.established)
+			}
+
+		case packet.BGPMsgTypeUpdate:
+			s.processUpdate(msg.Body.(*packet.BGPUpdate))
+
+		case packet.BGPMsgTypeNotification:
+			notification := msg.Body.(*packet.BGPNotification)
+			s.reportError(errors.New(fmt.Sprintf("Speaker %s received notification, code %d subcode %d",
+				s.addr, notification.ErrorCode, notification.ErrorSubcode)))
+			return
+		}
+	}
+}
+
+func (s *Speaker) reportError(err error) {
+	select {
+	case <-s.stopCh:
+		return
+	default:
+	}
+
+	select {
+	case s.errCh <- err:
+	default:
+	}
+}
+
+func getRouteKey(nlri packet.NLRI) uint64 {
+	ip := nlri.GetPrefix().To4()
+	if ip == nil {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(ip))<<8 | uint64(nlri.GetLength())
+}
+
+func (s *Speaker) processUpdate(update *packet.BGPUpdate) {
+	withdrawn := update.WithdrawnRoutes
+	nlriList := update.NLRI
+	for _, pa := range update.PathAttributes {
+		switch attr := pa.(type) {
+		case *packet.BGPPathAttrMPReachNLRI:
+			nlriList = append(nlriList, attr.NLRI...)
+
+		case *packet.BGPPathAttrMPUnreachNLRI:
+			withdrawn = append(withdrawn, attr.NLRI...)
+		}
+	}
+
+	now := time.Now()
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	if s.stats.UpdatesRx == 0 {
+		s.stats.FirstRx = now
+	}
+	s.stats.LastRx = now
+	s.stats.UpdatesRx++
+	s.stats.PrefixesRx += uint64(len(nlriList))
+	s.stats.WithdrawnRx += uint64(len(withdrawn))
+
+	if !s.track {
+		return
+	}
+	for _, nlri := range withdrawn {
+		delete(s.routes, getRouteKey(nlri))
+	}
+	for _, nlri := range nlriList {
+		s.routes[getRouteKey(nlri)] = true
+	}
+}
+
+func (s *Speaker) GetStats() PeerStats {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	return s.stats
+}
+
+func (s *Speaker) ResetStats() {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	s.stats = PeerStats{}
+}
+
+func (s *Speaker) GetNumRoutes() int {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	return len(s.routes)
+}
+
+// Returns the first error seen on the session since the session came up, nil if there was none.
+func (s *Speaker) GetError() error {
+	select {
+	case err := <-s.errCh:
+		return err
+	default:
+	}
+	return nil
+}
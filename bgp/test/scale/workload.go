@@ -0,0 +1,277 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// workload.go
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"l3/bgp/packet"
+	"math/rand"
+	"net"
+)
+
+const (
+	bgpPathAttrTypeCommunities packet.BGPPathAttrType = 8
+	workloadPrefixLen          uint8                  = 24
+	workloadPrefixBase         uint32                 = 11 << 24
+	workloadMaxPrefixes        int                    = 1 << 22
+	transitASBase              uint32                 = 1000
+	transitASPool                                     = 20000
+	// Each /24 NLRI is encoded as one length octet followed by three prefix octets
+	prefixNLRILen = 4
+	// Withdrawn routes length and total path attribute length fields
+	updateFixedLen = 4
+)
+
+type WorkloadConfig struct {
+	NumSpeakers    int
+	NumPrefixes    int
+	PathsPerPrefix int
+	AttrSets       int
+	MaxPathLen     int
+	MaxCommunities int
+	FlapPct        int
+	WithdrawPct    int
+	Seed           int64
+}
+
+type speakerAttrs struct {
+	pathAttrs [][]packet.BGPPathAttr
+	attrsLen  []int
+}
+
+/*  Workload generates the routes injected by the synthetic speakers. Prefix idx is the /24 at
+ *  11.0.0.0 + idx * 256 and is advertised by PathsPerPrefix consecutive speakers starting at
+ *  idx % NumSpeakers, so the server has to run best path selection between several paths for every
+ *  destination. Every speaker owns AttrSets random attribute combinations with varying AS path
+ *  length, MED and communities. The prefixes are spread over the attribute sets and packed into
+ *  as few UPDATE messages as the 4096 byte limit allows.
+ */
+type Workload struct {
+	conf  WorkloadConfig
+	attrs []speakerAttrs
+}
+
+func NewWorkload(conf WorkloadConfig, speakerAS []uint32, speakerIP []net.IP) (*Workload, error) {
+	if conf.NumSpeakers <= 0 || conf.NumSpeakers != len(speakerAS) || conf.NumSpeakers != len(speakerIP) {
+		return nil, errors.New(fmt.Sprintf("Invalid number of speakers %d", conf.NumSpeakers))
+	}
+	if conf.NumPrefixes <= 0 || conf.NumPrefixes > workloadMaxPrefixes {
+		return nil, errors.New(fmt.Sprintf("Number of prefixes %d is not in the range 1-%d", conf.NumPrefixes,
+			workloadMaxPrefixes))
+	}
+	if conf.PathsPerPrefix <= 0 || conf.PathsPerPrefix > conf.NumSpeakers {
+		conf.PathsPerPrefix = conf.NumSpeakers
+	}
+	if conf.AttrSets <= 0 {
+		conf.AttrSets = 1
+	}
+	if conf.MaxPathLen <= 0 {
+		conf.MaxPathLen = 1
+	}
+
+	w := &Workload{
+		conf:  conf,
+		attrs: make([]speakerAttrs, conf.NumSpeakers),
+	}
+	for s := 0; s < conf.NumSpeakers; s++ {
+		r := rand.New(rand.NewSource(conf.Seed + int64(s)))
+		w.attrs[s].pathAttrs = make([][]packet.BGPPathAttr, conf.AttrSets)
+		w.attrs[s].attrsLen = make([]int, conf.AttrSets)
+		for a := 0; a < conf.AttrSets; a++ {
+			pathAttrs := w.constructPathAttrs(r, speakerAS[s], speakerIP[s])
+			attrsLen := 0
+			for _, pa := range pathAttrs {
+				attrsLen += int(pa.TotalLen())
+			}
+			w.attrs[s].pathAttrs[a] = pathAttrs
+			w.attrs[s].attrsLen[a] = attrsLen
+		}
+	}
+	return w, nil
+}
+
+func (w *Workload) constructPathAttrs(r *rand.Rand, as uint32, nextHopIP net.IP) []packet.BGPPathAttr {
+	pathAttrs := make([]packet.BGPPathAttr, 0, 5)
+
+	originType := packet.BGPPathAttrOriginIGP
+	if r.Intn(10) == 0 {
+		originType = packet.BGPPathAttrOriginIncomplete
+	}
+	pathAttrs = append(pathAttrs, packet.NewBGPPathAttrOrigin(originType))
+
+	asPathSeg := packet.NewBGPAS4PathSegmentSeq()
+	asPathSeg.AppendAS(as)
+	pathLen := 1 + r.Intn(w.conf.MaxPathLen)
+	for i := 1; i < pathLen; i++ {
+		asPathSeg.AppendAS(transitASBase + uint32(r.Intn(transitASPool)))
+	}
+	asPath := packet.NewBGPPathAttrASPath()
+	asPath.AppendASPathSegment(asPathSeg)
+	pathAttrs = append(pathAttrs, asPath)
+
+	nextHop := packet.NewBGPPathAttrNextHop()
+	nextHop.Value = nextHopIP.To4()
+	pathAttrs = append(pathAttrs, nextHop)
+
+	if r.Intn(2) == 0 {
+		med := packet.NewBGPPathAttrMultiExitDisc()
+		med.Value = uint32(r.Intn(1000))
+		pathAttrs = append(pathAttrs, med)
+	}
+
+	if numCommunities := r.Intn(w.conf.MaxCommunities + 1); numCommunities > 0 {
+		value := make([]byte, numCommunities*4)
+		for i := 0; i < numCommunities; i++ {
+			community := (as&0xFFFF)<<16 | uint32(r.Intn(1000))
+			binary.BigEndian.PutUint32(value[i*4:], community)
+		}
+		communities := &packet.BGPPathAttrUnknown{
+			BGPPathAttrBase: packet.BGPPathAttrBase{
+				Flags:          packet.BGPPathAttrFlagOptional | packet.BGPPathAttrFlagTransitive,
+				Code:           bgpPathAttrTypeCommunities,
+				Length:         uint16(len(value)),
+				BGPPathAttrLen: 3,
+			},
+			Value: value,
+		}
+		pathAttrs = append(pathAttrs, communities)
+	}
+
+	return pathAttrs
+}
+
+func (w *Workload) GetNumPrefixes() int {
+	return w.conf.NumPrefixes
+}
+
+func (w *Workload) GetPrefix(idx int) *packet.IPPrefix {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, workloadPrefixBase+uint32(idx)<<(32-workloadPrefixLen))
+	return packet.NewIPPrefix(ip, workloadPrefixLen)
+}
+
+func (w *Workload) IsAdvertiser(speaker, idx int) bool {
+	offset := (speaker - idx%w.conf.NumSpeakers + w.conf.NumSpeakers) % w.conf.NumSpeakers
+	return offset < w.conf.PathsPerPrefix
+}
+
+func (w *Workload) IsFlapped(idx int) bool {
+	return idx%100 < w.conf.FlapPct
+}
+
+func (w *Workload) IsWithdrawn(idx int) bool {
+	return idx%100 >= 100-w.conf.WithdrawPct
+}
+
+// Only the first advertiser of a prefix flaps it, the other paths stay in the RIB.
+func (w *Workload) IsFlapper(speaker, idx int) bool {
+	return idx%w.conf.NumSpeakers == speaker
+}
+
+func (w *Workload) getAttrSet(speaker, idx int) int {
+	return int((uint32(idx)*2654435761 + uint32(speaker)*40503) % uint32(w.conf.AttrSets))
+}
+
+// Returns the prefixes advertised by speaker for which filter returns true.
+func (w *Workload) GetPrefixes(speaker int, filter func(int) bool) []int {
+	prefixes := make([]int, 0, w.conf.NumPrefixes*w.conf.PathsPerPrefix/w.conf.NumSpeakers)
+	for idx := 0; idx < w.conf.NumPrefixes; idx++ {
+		if w.IsAdvertiser(speaker, idx) && (filter == nil || filter(idx)) {
+			prefixes = append(prefixes, idx)
+		}
+	}
+	return prefixes
+}
+
+// Returns the number of prefixes that are advertised by at least one speaker after the withdraw phase.
+func (w *Workload) GetNumRemainingPrefixes() int {
+	count := 0
+	for idx := 0; idx < w.conf.NumPrefixes; idx++ {
+		if !w.IsWithdrawn(idx) {
+			count++
+		}
+	}
+	return count
+}
+
+/*  Encodes the UPDATE messages that advertise prefixes from speaker. Prefixes that share an
+ *  attribute set are packed together.
+ */
+func (w *Workload) BuildUpdates(speaker int, prefixes []int) ([][]byte, error) {
+	attrs := w.attrs[speaker]
+	attrSetPrefixes := make(map[int][]packet.NLRI)
+	order := make([]int, 0)
+	for _, idx := range prefixes {
+		attrSet := w.getAttrSet(speaker, idx)
+		if _, ok := attrSetPrefixes[attrSet]; !ok {
+			order = append(order, attrSet)
+		}
+		attrSetPrefixes[attrSet] = append(attrSetPrefixes[attrSet], w.GetPrefix(idx))
+	}
+
+	pkts := make([][]byte, 0)
+	for _, attrSet := range order {
+		nlriList := attrSetPrefixes[attrSet]
+		maxNLRIs := (packet.BGPMsgMaxLen - packet.BGPMsgHeaderLen - updateFixedLen - attrs.attrsLen[attrSet]) /
+			prefixNLRILen
+		for start := 0; start < len(nlriList); start += maxNLRIs {
+			end := start + maxNLRIs
+			if end > len(nlriList) {
+				end = len(nlriList)
+			}
+			msg := packet.NewBGPUpdateMessage(nil, attrs.pathAttrs[attrSet], nlriList[start:end])
+			pkt, err := msg.Encode()
+			if err != nil {
+				return nil, err
+			}
+			pkts = append(pkts, pkt)
+		}
+	}
+	return pkts, nil
+}
+
+func (w *Workload) BuildWithdraws(prefixes []int) ([][]byte, error) {
+	maxNLRIs := (packet.BGPMsgMaxLen - packet.BGPMsgHeaderLen - updateFixedLen) / prefixNLRILen
+	pkts := make([][]byte, 0, len(prefixes)/maxNLRIs+1)
+	for start := 0; start < len(prefixes); start += maxNLRIs {
+		end := start + maxNLRIs
+		if end > len(prefixes) {
+			end = len(prefixes)
+		}
+		wdRoutes := make([]packet.NLRI, 0, end-start)
+		for _, idx := range prefixes[start:end] {
+			wdRoutes = append(wdRoutes, w.GetPrefix(idx))
+		}
+		msg := packet.NewBGPUpdateMessage(wdRoutes, nil, nil)
+		pkt, err := msg.Encode()
+		if err != nil {
+			return nil, err
+		}
+		pkts = append(pkts, pkt)
+	}
+	return pkts, nil
+}
@@ -0,0 +1,150 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// labelTable.go
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"l3/bgp/config"
+	"os"
+	"sort"
+	"sync"
+	"utils/logging"
+)
+
+type labelRoutesByLabel []*config.LabelRouteConfig
+
+func (l labelRoutesByLabel) Len() int           { return len(l) }
+func (l labelRoutesByLabel) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l labelRoutesByLabel) Less(i, j int) bool { return l[i].InLabel < l[j].InLabel }
+
+/*  File backed stand-in for the hardware MPLS label table. Every change rewrites the file with
+ *  the current set of incoming label entries so that tests and lab setups can inspect what would
+ *  have been programmed in the forwarding plane.
+ */
+type FileLabelTable struct {
+	logger   *logging.Writer
+	fileName string
+	rwMutex  *sync.RWMutex
+	entries  map[uint32]*config.LabelRouteConfig
+}
+
+func NewFileLabelTable(logger *logging.Writer, fileName string) (*FileLabelTable, error) {
+	table := &FileLabelTable{
+		logger:   logger,
+		fileName: fileName,
+		rwMutex:  &sync.RWMutex{},
+		entries:  make(map[uint32]*config.LabelRouteConfig),
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return table, nil
+		}
+		return nil, err
+	}
+
+	entries := make([]*config.LabelRouteConfig, 0)
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &entries); err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to read label table %s, error %s", fileName, err))
+		}
+	}
+	/*  The label manager starts with no bindings, so the entries left by the previous instance are
+	 *  not owned by any route. They are removed rather than left to be overwritten or leaked.
+	 */
+	if len(entries) > 0 {
+		if logger != nil {
+			logger.Infof("Removing %d label entries left in label table %s", len(entries), fileName)
+		}
+		if err = table.write(); err != nil {
+			return nil, err
+		}
+	}
+	return table, nil
+}
+
+func (t *FileLabelTable) UpdateLabelRoute(cfg *config.LabelRouteConfig, op string) error {
+	t.rwMutex.Lock()
+	defer t.rwMutex.Unlock()
+
+	switch op {
+	case "add":
+		entry := *cfg
+		t.entries[cfg.InLabel] = &entry
+
+	case "remove":
+		if _, ok := t.entries[cfg.InLabel]; !ok {
+			return errors.New(fmt.Sprintf("Label %d not found in label table", cfg.InLabel))
+		}
+		delete(t.entries, cfg.InLabel)
+
+	default:
+		return errors.New(fmt.Sprintf("Unknown label table operation %s", op))
+	}
+
+	return t.write()
+}
+
+func (t *FileLabelTable) GetLabelRoute(label uint32) (*config.LabelRouteConfig, bool) {
+	t.rwMutex.RLock()
+	defer t.rwMutex.RUnlock()
+	entry, ok := t.entries[label]
+	return entry, ok
+}
+
+func (t *FileLabelTable) GetLabelRoutes() []*config.LabelRouteConfig {
+	t.rwMutex.RLock()
+	defer t.rwMutex.RUnlock()
+	return t.sortedEntries()
+}
+
+func (t *FileLabelTable) sortedEntries() []*config.LabelRouteConfig {
+	entries := make([]*config.LabelRouteConfig, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, entry)
+	}
+	sort.Sort(labelRoutesByLabel(entries))
+	return entries
+}
+
+func (t *FileLabelTable) write() error {
+	data, err := json.MarshalIndent(t.sortedEntries(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFileName := t.fileName + ".tmp"
+	if err = ioutil.WriteFile(tmpFileName, data, 0644); err != nil {
+		if t.logger != nil {
+			t.logger.Errf("Failed to write label table %s, error %s", t.fileName, err)
+		}
+		return err
+	}
+	return os.Rename(tmpFileName, t.fileName)
+}
@@ -23,7 +23,9 @@
 
 package config
 
-import ()
+import (
+	"time"
+)
 
 type AreaId string
 type RouterId string
@@ -73,6 +75,24 @@ const (
 	Reserved       AuthType = 3
 )
 
+type AuthAlgorithm int
+
+const (
+	KeyedMd5   AuthAlgorithm = 1
+	HmacSha1   AuthAlgorithm = 2
+	HmacSha256 AuthAlgorithm = 3
+	HmacSha384 AuthAlgorithm = 4
+	HmacSha512 AuthAlgorithm = 5
+)
+
+var AuthAlgorithmList = []string{
+	"Undefined",
+	"md5",
+	"hmac-sha-1",
+	"hmac-sha-256",
+	"hmac-sha-384",
+	"hmac-sha-512"}
+
 type RestartSupport int
 
 const (
@@ -134,6 +154,7 @@ const (
 	UnnumberedP2P     IfType = 4
 	PointToMultipoint IfType = 5
 	Stub              IfType = 6
+	VirtualLink       IfType = 7
 )
 
 var IfTypeList = []string{
@@ -143,7 +164,8 @@ var IfTypeList = []string{
 	"NumberedP2P",
 	"UnnumberedP2P",
 	"PointToMultipoint",
-	"Stub"}
+	"Stub",
+	"VirtualLink"}
 
 type MulticastForwarding int
 
@@ -254,6 +276,29 @@ const (
 	Helping    NbrRestartHelperStatus = 2
 )
 
+type LfaProtection int
+
+const (
+	LfaDisabled       LfaProtection = 1
+	LfaLinkProtection LfaProtection = 2
+	LfaNodeProtection LfaProtection = 3
+)
+
+type RouteMapAction int
+
+const (
+	RouteMapPermit RouteMapAction = 1
+	RouteMapDeny   RouteMapAction = 2
+)
+
+type ExtMetricType int
+
+const (
+	ExtMetricTypeNone ExtMetricType = 0 // keep the metric type of the route
+	ExtMetricType1    ExtMetricType = 1
+	ExtMetricType2    ExtMetricType = 2
+)
+
 type GlobalConf struct {
 	RouterId           RouterId
 	AdminStat          Status
@@ -281,6 +326,245 @@ type GlobalState struct {
 	StubRouterSupport bool
 	//DiscontinuityTime        string
 	DiscontinuityTime int32 //This should be string
+	SpfThrottleState  string
+	SpfEvents         int32
+	SpfDeferredEvents int32
+	SpfRunsTotal      int32
+	StubRouterStatus  string
+	StubRouterRemain  int32
+}
+
+// SPF back-off timers (RFC 8405), all in milliseconds
+type SpfThrottleConf struct {
+	SpfInitialDelay int32
+	SpfShortDelay   int32
+	SpfLongDelay    int32
+	SpfTimeToLearn  int32
+	SpfHolddown     int32
+}
+
+// Helper side of graceful restart (RFC 3623)
+type GracefulRestartConf struct {
+	HelperSupport     RestartSupport
+	StrictLsaChecking bool
+}
+
+// Loop-free alternates (RFC 5286) and remote LFA (RFC 7490)
+type LfaConf struct {
+	Protection     LfaProtection
+	DownstreamOnly bool
+	RemoteLfa      bool
+}
+
+// Indexed By IfIpAddress, AddressLessIf
+type IfLfaConf struct {
+	IfIpAddress   IpAddress
+	AddressLessIf InterfaceIndexOrZero
+	Exclude       bool
+}
+
+// Indexed By IfIpAddress, AddressLessIf
+type IfBfdConf struct {
+	IfIpAddress   IpAddress
+	AddressLessIf InterfaceIndexOrZero
+	Enable        bool
+	SessionParam  string
+}
+
+// Max-metric router LSAs (RFC 6987), StartupTime in seconds
+type StubRouterConf struct {
+	OnStartup      bool
+	StartupTime    int32
+	WaitForBgp     bool
+	Administrative bool
+	SummaryLsa     bool
+	ExternalLsa    bool
+}
+
+// Opaque LSAs (RFC 5250) and traffic engineering (RFC 3630)
+// TeRouterAddress of 0.0.0.0 uses the router id
+type OpaqueConf struct {
+	OpaqueLsaSupport bool
+	TeEnable         bool
+	TeRouterAddress  IpAddress
+}
+
+// Indexed By IfIpAddress, AddressLessIf
+// Bandwidths in Mbps, 0 uses the port speed / the max bandwidth.
+// TeMetric of 0 uses the interface cost.
+type IfTeConf struct {
+	IfIpAddress     IpAddress
+	AddressLessIf   InterfaceIndexOrZero
+	TeMetric        uint32
+	AdminGroup      uint32
+	MaxBandwidth    uint32
+	MaxRsvBandwidth uint32
+}
+
+// Opaque data originated on behalf of other subsystems.
+// Indexed By LsaType, OpaqueType, OpaqueId, AreaId, IfIpAddress, AddressLessIf
+// AreaId is used by area scope LSAs, the interface by link scope LSAs.
+type OpaqueLsaConf struct {
+	LsaType       LsaType
+	OpaqueType    uint8
+	OpaqueId      uint32
+	AreaId        AreaId
+	IfIpAddress   IpAddress
+	AddressLessIf InterfaceIndexOrZero
+	Data          []byte
+}
+
+// TE database entry, one per TE link LSA (RFC 3630)
+type TeLinkState struct {
+	AreaId          AreaId
+	AdvRouter       RouterId
+	RouterAddress   IpAddress
+	OpaqueId        uint32
+	LinkType        uint8
+	LinkId          IpAddress
+	LocalIpAddress  []IpAddress
+	RemoteIpAddress []IpAddress
+	TeMetric        uint32
+	MaxBandwidth    float32 // bytes per second
+	MaxRsvBandwidth float32
+	UnrsvBandwidth  []float32
+	AdminGroup      uint32
+}
+
+// Packets sent and received per packet type and received packets
+// dropped per reason
+type PktStats struct {
+	TxHello                 uint32
+	TxDbd                   uint32
+	TxLsReq                 uint32
+	TxLsUpd                 uint32
+	TxLsAck                 uint32
+	RxHello                 uint32
+	RxDbd                   uint32
+	RxLsReq                 uint32
+	RxLsUpd                 uint32
+	RxLsAck                 uint32
+	RxBadLength             uint32
+	RxBadVersion            uint32
+	RxBadChecksum           uint32
+	RxAreaMismatch          uint32
+	RxAuthFailure           uint32
+	RxNetmaskMismatch       uint32
+	RxHelloIntervalMismatch uint32
+	RxDeadIntervalMismatch  uint32
+	RxOptionsMismatch       uint32
+	RxMtuMismatch           uint32
+	RxUnknownNbr            uint32
+	RxBadPktType            uint32
+}
+
+// Indexed By IfIpAddress, AddressLessIf
+type IfStatsState struct {
+	IfIpAddress    IpAddress
+	AddressLessIf  InterfaceIndexOrZero
+	Stats          PktStats
+	LastDropReason string
+	LastDropSrc    IpAddress
+	LastDropTime   string
+}
+
+// Indexed By NbrIpAddress, NbrAddressLessIndex
+// NbrUptime is the time in seconds since the adjacency became full
+type NbrStatsState struct {
+	NbrIpAddress        IpAddress
+	NbrAddressLessIndex int
+	NbrRtrId            string
+	NbrState            string
+	NbrStateChanges     uint32
+	NbrUptime           int32
+	NbrLsRetransQLen    int
+	Stats               PktStats
+}
+
+// Indexed By IfIpAddress, AddressLessIf, SrcIpAddress
+// Drops per source address, kept after the neighbor goes down
+type SrcDropStatsState struct {
+	IfIpAddress    IpAddress
+	AddressLessIf  InterfaceIndexOrZero
+	SrcIpAddress   IpAddress
+	Stats          PktStats
+	LastDropReason string
+	LastDropTime   string
+}
+
+// Neighbor FSM transition log, oldest first
+type NbrEventState struct {
+	EventIdx            int32
+	TimeStamp           string
+	NbrIpAddress        IpAddress
+	NbrAddressLessIndex int
+	NbrRtrId            string
+	OldState            string
+	NewState            string
+	Reason              string
+	NbrLsRetransQLen    int
+}
+
+// Route map entry, the entries of a route map are evaluated in
+// Sequence order and the first matching entry applies.
+// Indexed By Name, Sequence
+// Unset match fields match every route. MatchPrefix/MatchMask match
+// the routes within the prefix, only the prefix itself with MatchExact.
+// MatchProtocol is the ribd protocol, "OSPF" for the distribute-list.
+// Set fields are only used by redistribution, zero keeps the value.
+type RouteMapConf struct {
+	Name          string
+	Sequence      int32
+	Action        RouteMapAction
+	MatchPrefix   IpAddress
+	MatchMask     IpAddress
+	MatchExact    bool
+	MatchTag      uint32
+	MatchProtocol string
+	SetMetric     uint32
+	SetMetricType ExtMetricType
+	SetTag        uint32
+}
+
+// Route maps applied by ospfd, an empty name disables the policy.
+// RedistRouteMap filters and modifies the routes redistributed from
+// ribd, routes not permitted by it are not redistributed.
+// DistributeList keeps the OSPF routes it does not permit out of
+// ribd, they stay in the LSDB and the OSPF routing table.
+type PolicyConf struct {
+	RedistRouteMap string
+	DistributeList string
+}
+
+// Indexed By IfIpAddress, AddressLessIf
+// A passive interface neither sends nor accepts hellos, its subnet
+// is still advertised. SuppressPrefix (RFC 6860) keeps the subnet
+// of the interface out of the router and network LSAs.
+type IfPolicyConf struct {
+	IfIpAddress    IpAddress
+	AddressLessIf  InterfaceIndexOrZero
+	Passive        bool
+	SuppressPrefix bool
+}
+
+// Routes received from ribd for redistribution
+type RedistRouteState struct {
+	DestIp     string
+	Mask       string
+	Protocol   string
+	Permitted  bool
+	Metric     uint32
+	MetricType ExtMetricType
+	Tag        uint32
+}
+
+type SpfRunLog struct {
+	StartTime     string
+	Trigger       string
+	Events        int32
+	FullSpf       bool
+	Duration      int32 // microseconds
+	RoutesChanged int32
 }
 
 // Indexed By AreaId
@@ -302,6 +586,13 @@ type AreaState struct {
 	AreaLsaCksumSum          int32
 	AreaNssaTranslatorState  NssaTranslatorState
 	AreaNssaTranslatorEvents int32
+	SpfPartialRuns           int32
+	SpfHistory               []SpfRunLog
+	LfaPrefixes              int32
+	LfaProtected             int32
+	LfaNodeProtected         int32
+	LfaRemoteProtected       int32
+	LfaPQNodes               int32
 }
 
 // Indexed by StubAreaId and StubTOS
@@ -332,11 +623,13 @@ type LsdbState struct {
 }
 
 // Indexed By RangeAreaId, RangeNet
+// AreaRangeCost of 0 advertises the highest cost of the component routes
 type AreaRangeConf struct {
 	RangeAreaId     AreaId
 	AreaRangeNet    IpAddress
 	ArearangeMask   IpAddress
 	AreaRangeEffect AreaRangeEffect
+	AreaRangeCost   int32
 }
 
 type AreaRangeState struct {
@@ -344,6 +637,17 @@ type AreaRangeState struct {
 	AreaRangeNet    IpAddress
 	ArearangeMask   IpAddress
 	AreaRangeEffect AreaRangeEffect
+	AreaRangeCost   int32
+}
+
+// External summary address used by ASBRs to aggregate
+// redistributed routes.
+// Indexed By SummaryAddressNet, SummaryAddressMask
+type SummaryAddressConf struct {
+	SummaryAddressNet    IpAddress
+	SummaryAddressMask   IpAddress
+	SummaryAddressEffect AreaRangeEffect
+	SummaryAddressTag    uint32
 }
 
 // Indexed By HostIpAddress, HostTOS
@@ -390,6 +694,45 @@ type InterfaceState struct {
 	IfLsaCksumSum              int32
 	IfDesignatedRouterId       RouterId
 	IfBackupDesignatedRouterId RouterId
+	IfKeyChain                 string
+	IfAuthTxKeyId              int32
+	IfAuthTxSeqNum             uint32
+	IfAuthTypeMismatch         int32
+	IfAuthKeyNotFound          int32
+	IfAuthDigestFailure        int32
+	IfAuthReplayDrops          int32
+	IfAuthPasswordFailure      int32
+	IfAuthTxNoKey              int32
+}
+
+// Key chain used for cryptographic authentication, indexed by Name.
+// A zero lifetime boundary means the key has no start or end.
+type AuthKeyConf struct {
+	KeyId       uint8
+	Algorithm   AuthAlgorithm
+	Key         string
+	SendStart   time.Time
+	SendEnd     time.Time
+	AcceptStart time.Time
+	AcceptEnd   time.Time
+}
+
+type KeyChainConf struct {
+	Name    string
+	KeyList []AuthKeyConf
+}
+
+// Indexed By IfIpAddress, AddressLessIf
+type IfKeyChainConf struct {
+	IfIpAddress   IpAddress
+	AddressLessIf InterfaceIndexOrZero
+	KeyChain      string
+}
+
+// Indexed By AreaId
+type AreaKeyChainConf struct {
+	AreaId   AreaId
+	KeyChain string
 }
 
 // Indexed By  IfMetricIpAddress, IfMetricAddressLessIf, IfMetricTOS
@@ -432,6 +775,9 @@ type VirtIfState struct {
 	VirtIfAuthType        AuthType
 	VirtIfLsaCount        int
 	VirtIfLsaCksumSum     int
+	VirtIfCost            int
+	VirtIfLocalIpAddress  IpAddress
+	VirtIfNbrIpAddress    IpAddress
 }
 
 // Indexed by NbrIpAddress, NbrAddressLessIndex
@@ -455,6 +801,7 @@ type NeighborState struct {
 	NbrRestartHelperStatus     int
 	NbrRestartHelperAge        uint32
 	NbrRestartHelperExitReason int
+	NbrBfdState                string
 }
 
 // Virtual Neighbor Table (Read Only)
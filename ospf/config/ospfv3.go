@@ -0,0 +1,76 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package config
+
+// OSPFv3 instance, indexed By InstanceId
+type Ospfv3GlobalConf struct {
+	InstanceId uint8
+	RouterId   RouterId
+	AdminStat  Status
+}
+
+// Indexed By IfIndex, InstanceId
+type Ospfv3IntfConf struct {
+	IfIndex           int32
+	InstanceId        uint8
+	IfAreaId          AreaId
+	IfType            IfType
+	IfAdminStat       Status
+	IfRtrPriority     DesignatedRouterPriority
+	IfHelloInterval   HelloRange
+	IfRtrDeadInterval PositiveInteger
+	IfCost            Metric
+}
+
+type Ospfv3IntfState struct {
+	IfIndex            int32
+	InstanceId         uint8
+	IfName             string
+	IfLinkLocalAddress string
+	IfAreaId           AreaId
+	IfState            IfState
+	IfDesignatedRouter RouterId
+	IfBackupRouter     RouterId
+	IfNbrCount         int32
+	IfAdjacentNbrCount int32
+}
+
+type Ospfv3NbrState struct {
+	InstanceId     uint8
+	IfIndex        int32
+	NbrRouterId    RouterId
+	NbrAddress     string
+	NbrInterfaceId uint32
+	NbrPriority    uint8
+	NbrState       string
+}
+
+type Ospfv3RouteState struct {
+	InstanceId    uint8
+	DestinationNw string
+	AreaId        AreaId
+	PathType      string
+	Cost          uint32
+	NextHops      string
+}
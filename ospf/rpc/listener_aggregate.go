@@ -0,0 +1,121 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertAreaRangeFromThrift(ospfAreaRange *ospfdInt.OspfAreaRange) config.AreaRangeConf {
+	conf := config.AreaRangeConf{
+		RangeAreaId:     config.AreaId(ospfAreaRange.AreaId),
+		AreaRangeNet:    config.IpAddress(ospfAreaRange.RangeNet),
+		ArearangeMask:   config.IpAddress(ospfAreaRange.RangeMask),
+		AreaRangeEffect: config.DoNotAdvertiseMatching,
+		AreaRangeCost:   ospfAreaRange.Cost,
+	}
+	if ospfAreaRange.Advertise {
+		conf.AreaRangeEffect = config.AdvertiseMatching
+	}
+	return conf
+}
+
+func convertSummaryAddressFromThrift(ospfSummaryAddress *ospfdInt.OspfSummaryAddress) config.SummaryAddressConf {
+	conf := config.SummaryAddressConf{
+		SummaryAddressNet:    config.IpAddress(ospfSummaryAddress.SummaryNet),
+		SummaryAddressMask:   config.IpAddress(ospfSummaryAddress.SummaryMask),
+		SummaryAddressEffect: config.DoNotAdvertiseMatching,
+		SummaryAddressTag:    uint32(ospfSummaryAddress.RouteTag),
+	}
+	if ospfSummaryAddress.Advertise {
+		conf.SummaryAddressEffect = config.AdvertiseMatching
+	}
+	return conf
+}
+
+func (h *OSPFHandler) SendOspfAreaRange(ospfAreaRange *ospfdInt.OspfAreaRange, op bool) (bool, error) {
+	if ospfAreaRange == nil {
+		err := errors.New("Invalid Area Range Configuration")
+		return false, err
+	}
+	conf := convertAreaRangeFromThrift(ospfAreaRange)
+	err := h.server.ValidateAreaRangeConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.AggregateConfigCh <- server.AggregateConfMsg{Op: op, AreaRange: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfAreaRange(ospfAreaRange *ospfdInt.OspfAreaRange) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create area range:", ospfAreaRange))
+	return h.SendOspfAreaRange(ospfAreaRange, true)
+}
+
+func (h *OSPFHandler) UpdateOspfAreaRange(ospfAreaRange *ospfdInt.OspfAreaRange) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update area range:", ospfAreaRange))
+	return h.SendOspfAreaRange(ospfAreaRange, true)
+}
+
+func (h *OSPFHandler) DeleteOspfAreaRange(ospfAreaRange *ospfdInt.OspfAreaRange) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete area range:", ospfAreaRange))
+	return h.SendOspfAreaRange(ospfAreaRange, false)
+}
+
+func (h *OSPFHandler) SendOspfSummaryAddress(ospfSummaryAddress *ospfdInt.OspfSummaryAddress, op bool) (bool, error) {
+	if ospfSummaryAddress == nil {
+		err := errors.New("Invalid Summary Address Configuration")
+		return false, err
+	}
+	if ospfSummaryAddress.RouteTag < 0 || ospfSummaryAddress.RouteTag > 0xffffffff {
+		err := errors.New(fmt.Sprintln("Invalid route tag", ospfSummaryAddress.RouteTag))
+		return false, err
+	}
+	conf := convertSummaryAddressFromThrift(ospfSummaryAddress)
+	err := h.server.ValidateSummaryAddressConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.AggregateConfigCh <- server.AggregateConfMsg{Op: op, SummaryAddress: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfSummaryAddress(ospfSummaryAddress *ospfdInt.OspfSummaryAddress) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create summary address:", ospfSummaryAddress))
+	return h.SendOspfSummaryAddress(ospfSummaryAddress, true)
+}
+
+func (h *OSPFHandler) UpdateOspfSummaryAddress(ospfSummaryAddress *ospfdInt.OspfSummaryAddress) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update summary address:", ospfSummaryAddress))
+	return h.SendOspfSummaryAddress(ospfSummaryAddress, true)
+}
+
+func (h *OSPFHandler) DeleteOspfSummaryAddress(ospfSummaryAddress *ospfdInt.OspfSummaryAddress) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete summary address:", ospfSummaryAddress))
+	return h.SendOspfSummaryAddress(ospfSummaryAddress, false)
+}
@@ -0,0 +1,233 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+	"strings"
+	"time"
+)
+
+func parseKeyLifetime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func (h *OSPFHandler) convertKeyChainFromThrift(ospfKeyChain *ospfdInt.OspfKeyChain) (config.KeyChainConf, error) {
+	conf := config.KeyChainConf{
+		Name: ospfKeyChain.Name,
+	}
+	for _, key := range ospfKeyChain.KeyList {
+		if key.KeyId < 0 || key.KeyId > 0xff {
+			return conf, errors.New(fmt.Sprintln("Invalid key id", key.KeyId))
+		}
+		keyConf := config.AuthKeyConf{
+			KeyId: uint8(key.KeyId),
+			Key:   key.Key,
+		}
+		for index, algName := range config.AuthAlgorithmList {
+			if strings.EqualFold(key.Algorithm, algName) {
+				keyConf.Algorithm = config.AuthAlgorithm(index)
+				break
+			}
+		}
+		lifetimes := []struct {
+			value string
+			ent   *time.Time
+		}{
+			{key.SendStart, &keyConf.SendStart},
+			{key.SendEnd, &keyConf.SendEnd},
+			{key.AcceptStart, &keyConf.AcceptStart},
+			{key.AcceptEnd, &keyConf.AcceptEnd},
+		}
+		for _, lifetime := range lifetimes {
+			t, err := parseKeyLifetime(lifetime.value)
+			if err != nil {
+				return conf, errors.New(fmt.Sprintln("Invalid key lifetime", lifetime.value, "for key id", key.KeyId))
+			}
+			*lifetime.ent = t
+		}
+		conf.KeyList = append(conf.KeyList, keyConf)
+	}
+	return conf, nil
+}
+
+func (h *OSPFHandler) SendOspfKeyChain(ospfKeyChain *ospfdInt.OspfKeyChain) error {
+	conf, err := h.convertKeyChainFromThrift(ospfKeyChain)
+	if err != nil {
+		return err
+	}
+	err = h.server.ValidateKeyChainConf(conf)
+	if err != nil {
+		return err
+	}
+	h.server.AuthConfigCh <- server.AuthConfMsg{Op: true, KeyChain: &conf}
+	return nil
+}
+
+func (h *OSPFHandler) CreateOspfKeyChain(ospfKeyChain *ospfdInt.OspfKeyChain) (bool, error) {
+	if ospfKeyChain == nil {
+		err := errors.New("Invalid Key Chain Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Create key chain:", ospfKeyChain.Name))
+	err := h.SendOspfKeyChain(ospfKeyChain)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) UpdateOspfKeyChain(ospfKeyChain *ospfdInt.OspfKeyChain) (bool, error) {
+	if ospfKeyChain == nil {
+		err := errors.New("Invalid Key Chain Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Update key chain:", ospfKeyChain.Name))
+	err := h.SendOspfKeyChain(ospfKeyChain)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) DeleteOspfKeyChain(name string) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete key chain:", name))
+	err := h.server.ValidateKeyChainDelete(name)
+	if err != nil {
+		return false, err
+	}
+	conf := config.KeyChainConf{
+		Name: name,
+	}
+	h.server.AuthConfigCh <- server.AuthConfMsg{Op: false, KeyChain: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfIfKeyChain(ospfIfKeyChain *ospfdInt.OspfIfKeyChain) (bool, error) {
+	if ospfIfKeyChain == nil {
+		err := errors.New("Invalid Interface Key Chain Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Create interface key chain:", ospfIfKeyChain))
+	err := h.server.ValidateKeyChainBinding(ospfIfKeyChain.KeyChain)
+	if err != nil {
+		return false, err
+	}
+	conf := config.IfKeyChainConf{
+		IfIpAddress:   config.IpAddress(ospfIfKeyChain.IfIpAddress),
+		AddressLessIf: config.InterfaceIndexOrZero(ospfIfKeyChain.AddressLessIf),
+		KeyChain:      ospfIfKeyChain.KeyChain,
+	}
+	h.server.AuthConfigCh <- server.AuthConfMsg{Op: true, IfKeyChain: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) DeleteOspfIfKeyChain(ospfIfKeyChain *ospfdInt.OspfIfKeyChain) (bool, error) {
+	if ospfIfKeyChain == nil {
+		err := errors.New("Invalid Interface Key Chain Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Delete interface key chain:", ospfIfKeyChain))
+	conf := config.IfKeyChainConf{
+		IfIpAddress:   config.IpAddress(ospfIfKeyChain.IfIpAddress),
+		AddressLessIf: config.InterfaceIndexOrZero(ospfIfKeyChain.AddressLessIf),
+	}
+	h.server.AuthConfigCh <- server.AuthConfMsg{Op: false, IfKeyChain: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfAreaKeyChain(ospfAreaKeyChain *ospfdInt.OspfAreaKeyChain) (bool, error) {
+	if ospfAreaKeyChain == nil {
+		err := errors.New("Invalid Area Key Chain Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Create area key chain:", ospfAreaKeyChain))
+	err := h.server.ValidateKeyChainBinding(ospfAreaKeyChain.KeyChain)
+	if err != nil {
+		return false, err
+	}
+	conf := config.AreaKeyChainConf{
+		AreaId:   config.AreaId(ospfAreaKeyChain.AreaId),
+		KeyChain: ospfAreaKeyChain.KeyChain,
+	}
+	h.server.AuthConfigCh <- server.AuthConfMsg{Op: true, AreaKeyChain: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) DeleteOspfAreaKeyChain(ospfAreaKeyChain *ospfdInt.OspfAreaKeyChain) (bool, error) {
+	if ospfAreaKeyChain == nil {
+		err := errors.New("Invalid Area Key Chain Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Delete area key chain:", ospfAreaKeyChain))
+	conf := config.AreaKeyChainConf{
+		AreaId: config.AreaId(ospfAreaKeyChain.AreaId),
+	}
+	h.server.AuthConfigCh <- server.AuthConfMsg{Op: false, AreaKeyChain: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) convertIfAuthStateToThrift(ent config.InterfaceState) *ospfdInt.OspfIfAuthState {
+	authState := ospfdInt.NewOspfIfAuthState()
+	authState.IfIpAddress = string(ent.IfIpAddress)
+	authState.AddressLessIf = int32(ent.AddressLessIf)
+	authState.KeyChain = ent.IfKeyChain
+	authState.TxKeyId = ent.IfAuthTxKeyId
+	authState.TxCryptoSeqNum = int64(ent.IfAuthTxSeqNum)
+	authState.AuthTypeMismatch = ent.IfAuthTypeMismatch
+	authState.AuthKeyNotFound = ent.IfAuthKeyNotFound
+	authState.AuthDigestFailure = ent.IfAuthDigestFailure
+	authState.AuthReplayDrops = ent.IfAuthReplayDrops
+	authState.AuthPasswordFailure = ent.IfAuthPasswordFailure
+	authState.AuthTxNoKey = ent.IfAuthTxNoKey
+	return authState
+}
+
+func (h *OSPFHandler) GetBulkOspfIfAuthState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfIfAuthStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get Interface auth attrs"))
+
+	nextIdx, currCount, ospfIfEntryStates := h.server.GetBulkOspfIfEntryState(int(fromIdx), int(count))
+	if ospfIfEntryStates == nil {
+		err := errors.New("Ospf is busy refreshing the cache")
+		return nil, err
+	}
+	ospfIfAuthStateResponse := make([]*ospfdInt.OspfIfAuthState, len(ospfIfEntryStates))
+	for idx, item := range ospfIfEntryStates {
+		ospfIfAuthStateResponse[idx] = h.convertIfAuthStateToThrift(item)
+	}
+	ospfIfAuthStateGetInfo := ospfdInt.NewOspfIfAuthStateGetInfo()
+	ospfIfAuthStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfIfAuthStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfIfAuthStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfIfAuthStateGetInfo.More = (nextIdx != 0)
+	ospfIfAuthStateGetInfo.OspfIfAuthStateList = ospfIfAuthStateResponse
+	return ospfIfAuthStateGetInfo, nil
+}
@@ -0,0 +1,101 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertIfBfdFromThrift(ospfIfBfd *ospfdInt.OspfIfBfd) config.IfBfdConf {
+	return config.IfBfdConf{
+		IfIpAddress:   config.IpAddress(ospfIfBfd.IfIpAddress),
+		AddressLessIf: config.InterfaceIndexOrZero(ospfIfBfd.AddressLessIf),
+		Enable:        ospfIfBfd.Enable,
+		SessionParam:  ospfIfBfd.SessionParam,
+	}
+}
+
+func (h *OSPFHandler) SendOspfIfBfd(ospfIfBfd *ospfdInt.OspfIfBfd, op bool) (bool, error) {
+	if ospfIfBfd == nil {
+		err := errors.New("Invalid interface BFD Configuration")
+		return false, err
+	}
+	conf := convertIfBfdFromThrift(ospfIfBfd)
+	err := h.server.ValidateIfBfdConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.BfdConfigCh <- server.BfdConfMsg{Op: op, IfBfd: conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfIfBfd(ospfIfBfd *ospfdInt.OspfIfBfd) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create interface BFD:", ospfIfBfd))
+	return h.SendOspfIfBfd(ospfIfBfd, true)
+}
+
+func (h *OSPFHandler) UpdateOspfIfBfd(ospfIfBfd *ospfdInt.OspfIfBfd) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update interface BFD:", ospfIfBfd))
+	return h.SendOspfIfBfd(ospfIfBfd, true)
+}
+
+func (h *OSPFHandler) DeleteOspfIfBfd(ospfIfBfd *ospfdInt.OspfIfBfd) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete interface BFD:", ospfIfBfd))
+	return h.SendOspfIfBfd(ospfIfBfd, false)
+}
+
+func (h *OSPFHandler) convertNbrBfdStateToThrift(ent config.NeighborState) *ospfdInt.OspfNbrBfdState {
+	nbrBfdState := ospfdInt.NewOspfNbrBfdState()
+	nbrBfdState.NbrIpAddress = string(ent.NbrIpAddress)
+	nbrBfdState.NbrAddressLessIndex = int32(ent.NbrAddressLessIndex)
+	nbrBfdState.NbrRtrId = ent.NbrRtrId
+	nbrBfdState.NbrState = ent.NbrState
+	nbrBfdState.BfdState = ent.NbrBfdState
+	return nbrBfdState
+}
+
+func (h *OSPFHandler) GetBulkOspfNbrBfdState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfNbrBfdStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get neighbor BFD state"))
+
+	nextIdx, currCount, ospfNbrEntryStates := h.server.GetBulkOspfNbrEntryState(int(fromIdx), int(count))
+	if ospfNbrEntryStates == nil {
+		err := errors.New("Ospf NBR is busy refreshing the cache")
+		return nil, err
+	}
+	ospfNbrBfdStateResponse := make([]*ospfdInt.OspfNbrBfdState, len(ospfNbrEntryStates))
+	for idx, item := range ospfNbrEntryStates {
+		ospfNbrBfdStateResponse[idx] = h.convertNbrBfdStateToThrift(item)
+	}
+	ospfNbrBfdStateGetInfo := ospfdInt.NewOspfNbrBfdStateGetInfo()
+	ospfNbrBfdStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfNbrBfdStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfNbrBfdStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfNbrBfdStateGetInfo.More = (nextIdx != 0)
+	ospfNbrBfdStateGetInfo.OspfNbrBfdStateList = ospfNbrBfdStateResponse
+	return ospfNbrBfdStateGetInfo, nil
+}
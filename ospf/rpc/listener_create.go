@@ -27,6 +27,7 @@ import (
 	"errors"
 	"fmt"
 	"l3/ospf/config"
+	"l3/ospf/server"
 	"ospfd"
 	"strings"
 )
@@ -69,6 +70,10 @@ func (h *OSPFHandler) SendOspfIfConf(ospfIfConf *ospfd.OspfIfEntry) error {
 			break
 		}
 	}
+	err := h.server.ValidateIfAuthKey(ifConf)
+	if err != nil {
+		return err
+	}
 	h.server.IntfConfigCh <- ifConf
 
 	//retMsg := <-h.server.IntfConfigRetCh
@@ -106,6 +111,27 @@ func (h *OSPFHandler) SendOspfIfMetricConf(ospfIfMetricConf *ospfd.OspfIfMetricE
 	return nil
 }
 
+func (h *OSPFHandler) SendOspfVirtIfConf(ospfVirtIfConf *ospfd.OspfVirtIfEntry, op bool) error {
+	virtIfConf := config.VirtIfConf{
+		VirtIfAreaId:          config.AreaId(ospfVirtIfConf.VirtIfAreaId),
+		VirtIfNeighbor:        config.RouterId(ospfVirtIfConf.VirtIfNeighbor),
+		VirtIfTransitDelay:    config.UpToMaxAge(ospfVirtIfConf.VirtIfTransitDelay),
+		VirtIfRetransInterval: config.UpToMaxAge(ospfVirtIfConf.VirtIfRetransInterval),
+		VirtIfHelloInterval:   config.HelloRange(ospfVirtIfConf.VirtIfHelloInterval),
+		VirtIfRtrDeadInterval: config.PositiveInteger(ospfVirtIfConf.VirtIfRtrDeadInterval),
+		VirtIfAuthKey:         ospfVirtIfConf.VirtIfAuthKey,
+		VirtIfAuthType:        config.AuthType(ospfVirtIfConf.VirtIfAuthType),
+	}
+	if op {
+		err := h.server.ValidateVirtIfConf(virtIfConf)
+		if err != nil {
+			return err
+		}
+	}
+	h.server.VirtIfConfigCh <- server.VirtIfConfMsg{Op: op, Conf: virtIfConf}
+	return nil
+}
+
 func (h *OSPFHandler) CreateOspfGlobal(ospfGlobalConf *ospfd.OspfGlobal) (bool, error) {
 	if ospfGlobalConf == nil {
 		err := errors.New("Invalid Global Configuration")
@@ -159,6 +185,14 @@ func (h *OSPFHandler) CreateOspfIfMetricEntry(ospfIfMetricConf *ospfd.OspfIfMetr
 }
 
 func (h *OSPFHandler) CreateOspfVirtIfEntry(ospfVirtIfConf *ospfd.OspfVirtIfEntry) (bool, error) {
+	if ospfVirtIfConf == nil {
+		err := errors.New("Invalid Virtual Interface Configuration")
+		return false, err
+	}
 	h.logger.Info(fmt.Sprintln("Create virtual interface config attrs:", ospfVirtIfConf))
+	err := h.SendOspfVirtIfConf(ospfVirtIfConf, true)
+	if err != nil {
+		return false, err
+	}
 	return true, nil
 }
@@ -24,6 +24,7 @@
 package rpc
 
 import (
+	"errors"
 	"fmt"
 	"ospfd"
 	//    "l3/ospf/config"
@@ -54,6 +55,14 @@ func (h *OSPFHandler) DeleteOspfIfMetricEntry(ospfIfMetricConf *ospfd.OspfIfMetr
 }
 
 func (h *OSPFHandler) DeleteOspfVirtIfEntry(ospfVirtIfConf *ospfd.OspfVirtIfEntry) (bool, error) {
+	if ospfVirtIfConf == nil {
+		err := errors.New("Invalid Virtual Interface Configuration")
+		return false, err
+	}
 	h.logger.Info(fmt.Sprintln("Delete virtual interface config attrs:", ospfVirtIfConf))
+	err := h.SendOspfVirtIfConf(ospfVirtIfConf, false)
+	if err != nil {
+		return false, err
+	}
 	return true, nil
 }
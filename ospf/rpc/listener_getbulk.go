@@ -99,6 +99,19 @@ func (h *OSPFHandler) convertNbrEntryStateToThrift(nbr config.NeighborState) *os
 
 }
 
+func (h *OSPFHandler) convertVirtNbrEntryStateToThrift(nbr config.VirtNbrState) *ospfd.OspfVirtNbrEntryState {
+	nbrEntry := ospfd.NewOspfVirtNbrEntryState()
+	nbrEntry.VirtNbrArea = string(nbr.VirtNbrArea)
+	nbrEntry.VirtNbrRtrId = string(nbr.VirtNbrRtrId)
+	nbrEntry.VirtNbrIpAddr = string(nbr.VirtNbrIpAddress)
+	nbrEntry.VirtNbrOptions = int32(nbr.VirtNbrOptions)
+	nbrEntry.VirtNbrState = config.NbrStateList[int(nbr.VirtNbrState)%len(config.NbrStateList)]
+	nbrEntry.VirtNbrEvents = int32(nbr.VirtNbrEvents)
+	nbrEntry.VirtNbrHelloSuppressed = bool(nbr.VirtNbrHelloSuppressed)
+
+	return nbrEntry
+}
+
 func (h *OSPFHandler) GetBulkOspfAreaEntryState(fromIdx ospfd.Int, count ospfd.Int) (*ospfd.OspfAreaEntryStateGetInfo, error) {
 	h.logger.Info(fmt.Sprintln("Get Area attrs"))
 
@@ -185,7 +198,17 @@ func (h *OSPFHandler) GetBulkOspfNbrEntryState(fromIdx ospfd.Int, count ospfd.In
 
 func (h *OSPFHandler) GetBulkOspfVirtNbrEntryState(fromIdx ospfd.Int, count ospfd.Int) (*ospfd.OspfVirtNbrEntryStateGetInfo, error) {
 	h.logger.Info(fmt.Sprintln("Get Virtual Neighbor attrs"))
+	nextIdx, currCount, ospfVirtNbrStates := h.server.GetBulkOspfVirtNbrState(int(fromIdx), int(count))
+	ospfVirtNbrEntryResponse := make([]*ospfd.OspfVirtNbrEntryState, len(ospfVirtNbrStates))
+	for idx, item := range ospfVirtNbrStates {
+		ospfVirtNbrEntryResponse[idx] = h.convertVirtNbrEntryStateToThrift(item)
+	}
 	ospfVirtNbrResponse := ospfd.NewOspfVirtNbrEntryStateGetInfo()
+	ospfVirtNbrResponse.Count = ospfd.Int(currCount)
+	ospfVirtNbrResponse.StartIdx = ospfd.Int(fromIdx)
+	ospfVirtNbrResponse.EndIdx = ospfd.Int(nextIdx)
+	ospfVirtNbrResponse.More = (nextIdx != 0)
+	ospfVirtNbrResponse.OspfVirtNbrEntryStateList = ospfVirtNbrEntryResponse
 	return ospfVirtNbrResponse, nil
 }
 
@@ -0,0 +1,141 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertLfaFromThrift(ospfLfa *ospfdInt.OspfLfa) config.LfaConf {
+	return config.LfaConf{
+		Protection:     config.LfaProtection(ospfLfa.Protection),
+		DownstreamOnly: ospfLfa.DownstreamOnly,
+		RemoteLfa:      ospfLfa.RemoteLfa,
+	}
+}
+
+func convertIfLfaFromThrift(ospfIfLfa *ospfdInt.OspfIfLfa) config.IfLfaConf {
+	return config.IfLfaConf{
+		IfIpAddress:   config.IpAddress(ospfIfLfa.IfIpAddress),
+		AddressLessIf: config.InterfaceIndexOrZero(ospfIfLfa.AddressLessIf),
+		Exclude:       ospfIfLfa.Exclude,
+	}
+}
+
+func (h *OSPFHandler) SendOspfLfa(ospfLfa *ospfdInt.OspfLfa) (bool, error) {
+	if ospfLfa == nil {
+		err := errors.New("Invalid LFA Configuration")
+		return false, err
+	}
+	conf := convertLfaFromThrift(ospfLfa)
+	err := h.server.ValidateLfaConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.LfaConfigCh <- server.LfaConfMsg{Op: true, Lfa: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfLfa(ospfLfa *ospfdInt.OspfLfa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create LFA:", ospfLfa))
+	return h.SendOspfLfa(ospfLfa)
+}
+
+func (h *OSPFHandler) UpdateOspfLfa(ospfLfa *ospfdInt.OspfLfa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update LFA:", ospfLfa))
+	return h.SendOspfLfa(ospfLfa)
+}
+
+func (h *OSPFHandler) DeleteOspfLfa(ospfLfa *ospfdInt.OspfLfa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete LFA:", ospfLfa))
+	conf := server.DefaultLfaConf()
+	h.server.LfaConfigCh <- server.LfaConfMsg{Op: false, Lfa: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) SendOspfIfLfa(ospfIfLfa *ospfdInt.OspfIfLfa, op bool) (bool, error) {
+	if ospfIfLfa == nil {
+		err := errors.New("Invalid interface LFA Configuration")
+		return false, err
+	}
+	conf := convertIfLfaFromThrift(ospfIfLfa)
+	err := h.server.ValidateIfLfaConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.LfaConfigCh <- server.LfaConfMsg{Op: op, IfLfa: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfIfLfa(ospfIfLfa *ospfdInt.OspfIfLfa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create interface LFA:", ospfIfLfa))
+	return h.SendOspfIfLfa(ospfIfLfa, true)
+}
+
+func (h *OSPFHandler) UpdateOspfIfLfa(ospfIfLfa *ospfdInt.OspfIfLfa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update interface LFA:", ospfIfLfa))
+	return h.SendOspfIfLfa(ospfIfLfa, true)
+}
+
+func (h *OSPFHandler) DeleteOspfIfLfa(ospfIfLfa *ospfdInt.OspfIfLfa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete interface LFA:", ospfIfLfa))
+	return h.SendOspfIfLfa(ospfIfLfa, false)
+}
+
+func (h *OSPFHandler) convertAreaLfaStateToThrift(ent config.AreaState) *ospfdInt.OspfAreaLfaState {
+	areaLfaState := ospfdInt.NewOspfAreaLfaState()
+	areaLfaState.AreaId = string(ent.AreaId)
+	areaLfaState.Prefixes = ent.LfaPrefixes
+	areaLfaState.Protected = ent.LfaProtected
+	areaLfaState.NodeProtected = ent.LfaNodeProtected
+	areaLfaState.RemoteProtected = ent.LfaRemoteProtected
+	areaLfaState.Unprotected = ent.LfaPrefixes - ent.LfaProtected - ent.LfaRemoteProtected
+	areaLfaState.PQNodes = ent.LfaPQNodes
+	return areaLfaState
+}
+
+func (h *OSPFHandler) GetBulkOspfAreaLfaState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfAreaLfaStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get area LFA state"))
+
+	nextIdx, currCount, ospfAreaEntryStates := h.server.GetBulkOspfAreaEntryState(int(fromIdx), int(count))
+	if ospfAreaEntryStates == nil {
+		err := errors.New("Ospf is busy refreshing the cache")
+		return nil, err
+	}
+	ospfAreaLfaStateResponse := make([]*ospfdInt.OspfAreaLfaState, len(ospfAreaEntryStates))
+	for idx, item := range ospfAreaEntryStates {
+		ospfAreaLfaStateResponse[idx] = h.convertAreaLfaStateToThrift(item)
+	}
+	ospfAreaLfaStateGetInfo := ospfdInt.NewOspfAreaLfaStateGetInfo()
+	ospfAreaLfaStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfAreaLfaStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfAreaLfaStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfAreaLfaStateGetInfo.More = (nextIdx != 0)
+	ospfAreaLfaStateGetInfo.OspfAreaLfaStateList = ospfAreaLfaStateResponse
+	return ospfAreaLfaStateGetInfo, nil
+}
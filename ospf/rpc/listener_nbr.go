@@ -0,0 +1,69 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertNbrEntryFromThrift(ospfNbrEntry *ospfdInt.OspfNbrEntry) config.NbrConf {
+	return config.NbrConf{
+		NbrIpAddress:        config.IpAddress(ospfNbrEntry.NbrIpAddress),
+		NbrAddressLessIndex: config.InterfaceIndexOrZero(ospfNbrEntry.NbrAddressLessIndex),
+		NbrPriority:         config.DesignatedRouterPriority(ospfNbrEntry.NbrPriority),
+	}
+}
+
+func (h *OSPFHandler) SendOspfNbrEntry(ospfNbrEntry *ospfdInt.OspfNbrEntry, op bool) (bool, error) {
+	if ospfNbrEntry == nil {
+		err := errors.New("Invalid Neighbor Configuration")
+		return false, err
+	}
+	conf := convertNbrEntryFromThrift(ospfNbrEntry)
+	err := h.server.ValidateNbmaNbrConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.NbmaNbrConfigCh <- server.NbmaNbrConfMsg{Op: op, Conf: conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfNbrEntry(ospfNbrEntry *ospfdInt.OspfNbrEntry) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create neighbor:", ospfNbrEntry))
+	return h.SendOspfNbrEntry(ospfNbrEntry, true)
+}
+
+func (h *OSPFHandler) UpdateOspfNbrEntry(ospfNbrEntry *ospfdInt.OspfNbrEntry) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update neighbor:", ospfNbrEntry))
+	return h.SendOspfNbrEntry(ospfNbrEntry, true)
+}
+
+func (h *OSPFHandler) DeleteOspfNbrEntry(ospfNbrEntry *ospfdInt.OspfNbrEntry) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete neighbor:", ospfNbrEntry))
+	return h.SendOspfNbrEntry(ospfNbrEntry, false)
+}
@@ -0,0 +1,187 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertRouteMapFromThrift(ospfRouteMap *ospfdInt.OspfRouteMap) config.RouteMapConf {
+	return config.RouteMapConf{
+		Name:          ospfRouteMap.Name,
+		Sequence:      ospfRouteMap.Sequence,
+		Action:        config.RouteMapAction(ospfRouteMap.Action),
+		MatchPrefix:   config.IpAddress(ospfRouteMap.MatchPrefix),
+		MatchMask:     config.IpAddress(ospfRouteMap.MatchMask),
+		MatchExact:    ospfRouteMap.MatchExact,
+		MatchTag:      uint32(ospfRouteMap.MatchTag),
+		MatchProtocol: ospfRouteMap.MatchProtocol,
+		SetMetric:     uint32(ospfRouteMap.SetMetric),
+		SetMetricType: config.ExtMetricType(ospfRouteMap.SetMetricType),
+		SetTag:        uint32(ospfRouteMap.SetTag),
+	}
+}
+
+func convertPolicyFromThrift(ospfPolicy *ospfdInt.OspfPolicy) config.PolicyConf {
+	return config.PolicyConf{
+		RedistRouteMap: ospfPolicy.RedistRouteMap,
+		DistributeList: ospfPolicy.DistributeList,
+	}
+}
+
+func convertIfPolicyFromThrift(ospfIfPolicy *ospfdInt.OspfIfPolicy) config.IfPolicyConf {
+	return config.IfPolicyConf{
+		IfIpAddress:    config.IpAddress(ospfIfPolicy.IfIpAddress),
+		AddressLessIf:  config.InterfaceIndexOrZero(ospfIfPolicy.AddressLessIf),
+		Passive:        ospfIfPolicy.Passive,
+		SuppressPrefix: ospfIfPolicy.SuppressPrefix,
+	}
+}
+
+func (h *OSPFHandler) SendOspfRouteMap(ospfRouteMap *ospfdInt.OspfRouteMap, op bool) (bool, error) {
+	if ospfRouteMap == nil {
+		err := errors.New("Invalid Route Map Configuration")
+		return false, err
+	}
+	if ospfRouteMap.MatchTag < 0 || ospfRouteMap.MatchTag > 0xffffffff ||
+		ospfRouteMap.SetTag < 0 || ospfRouteMap.SetTag > 0xffffffff {
+		err := errors.New(fmt.Sprintln("Invalid route tag", ospfRouteMap.MatchTag, ospfRouteMap.SetTag))
+		return false, err
+	}
+	if ospfRouteMap.SetMetric < 0 {
+		err := errors.New(fmt.Sprintln("Invalid route map metric", ospfRouteMap.SetMetric))
+		return false, err
+	}
+	conf := convertRouteMapFromThrift(ospfRouteMap)
+	if op {
+		err := h.server.ValidateRouteMapConf(conf)
+		if err != nil {
+			return false, err
+		}
+	}
+	h.server.PolicyConfigCh <- server.PolicyConfMsg{Op: op, RouteMap: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfRouteMap(ospfRouteMap *ospfdInt.OspfRouteMap) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create route map:", ospfRouteMap))
+	return h.SendOspfRouteMap(ospfRouteMap, true)
+}
+
+func (h *OSPFHandler) UpdateOspfRouteMap(ospfRouteMap *ospfdInt.OspfRouteMap) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update route map:", ospfRouteMap))
+	return h.SendOspfRouteMap(ospfRouteMap, true)
+}
+
+func (h *OSPFHandler) DeleteOspfRouteMap(ospfRouteMap *ospfdInt.OspfRouteMap) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete route map:", ospfRouteMap))
+	return h.SendOspfRouteMap(ospfRouteMap, false)
+}
+
+func (h *OSPFHandler) SendOspfPolicy(ospfPolicy *ospfdInt.OspfPolicy, op bool) (bool, error) {
+	if ospfPolicy == nil {
+		err := errors.New("Invalid Policy Configuration")
+		return false, err
+	}
+	conf := convertPolicyFromThrift(ospfPolicy)
+	h.server.PolicyConfigCh <- server.PolicyConfMsg{Op: op, Policy: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfPolicy(ospfPolicy *ospfdInt.OspfPolicy) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create policy:", ospfPolicy))
+	return h.SendOspfPolicy(ospfPolicy, true)
+}
+
+func (h *OSPFHandler) UpdateOspfPolicy(ospfPolicy *ospfdInt.OspfPolicy) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update policy:", ospfPolicy))
+	return h.SendOspfPolicy(ospfPolicy, true)
+}
+
+func (h *OSPFHandler) DeleteOspfPolicy(ospfPolicy *ospfdInt.OspfPolicy) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete policy:", ospfPolicy))
+	return h.SendOspfPolicy(ospfPolicy, false)
+}
+
+func (h *OSPFHandler) SendOspfIfPolicy(ospfIfPolicy *ospfdInt.OspfIfPolicy, op bool) (bool, error) {
+	if ospfIfPolicy == nil {
+		err := errors.New("Invalid interface Policy Configuration")
+		return false, err
+	}
+	conf := convertIfPolicyFromThrift(ospfIfPolicy)
+	err := h.server.ValidateIfPolicyConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.PolicyConfigCh <- server.PolicyConfMsg{Op: op, IfPolicy: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfIfPolicy(ospfIfPolicy *ospfdInt.OspfIfPolicy) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create interface policy:", ospfIfPolicy))
+	return h.SendOspfIfPolicy(ospfIfPolicy, true)
+}
+
+func (h *OSPFHandler) UpdateOspfIfPolicy(ospfIfPolicy *ospfdInt.OspfIfPolicy) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update interface policy:", ospfIfPolicy))
+	return h.SendOspfIfPolicy(ospfIfPolicy, true)
+}
+
+func (h *OSPFHandler) DeleteOspfIfPolicy(ospfIfPolicy *ospfdInt.OspfIfPolicy) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete interface policy:", ospfIfPolicy))
+	return h.SendOspfIfPolicy(ospfIfPolicy, false)
+}
+
+func (h *OSPFHandler) convertRedistRouteStateToThrift(ent config.RedistRouteState) *ospfdInt.OspfRedistRouteState {
+	redistRouteState := ospfdInt.NewOspfRedistRouteState()
+	redistRouteState.DestIp = ent.DestIp
+	redistRouteState.Mask = ent.Mask
+	redistRouteState.Protocol = ent.Protocol
+	redistRouteState.Permitted = ent.Permitted
+	redistRouteState.Metric = int32(ent.Metric)
+	redistRouteState.MetricType = int32(ent.MetricType)
+	redistRouteState.Tag = int64(ent.Tag)
+	return redistRouteState
+}
+
+func (h *OSPFHandler) GetBulkOspfRedistRouteState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfRedistRouteStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get redistributed routes"))
+
+	nextIdx, currCount, redistRouteStates := h.server.GetBulkOspfRedistRouteState(int(fromIdx), int(count))
+	ospfRedistRouteStateResponse := make([]*ospfdInt.OspfRedistRouteState, len(redistRouteStates))
+	for idx, item := range redistRouteStates {
+		ospfRedistRouteStateResponse[idx] = h.convertRedistRouteStateToThrift(item)
+	}
+	ospfRedistRouteStateGetInfo := ospfdInt.NewOspfRedistRouteStateGetInfo()
+	ospfRedistRouteStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfRedistRouteStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfRedistRouteStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfRedistRouteStateGetInfo.More = (nextIdx != 0)
+	ospfRedistRouteStateGetInfo.OspfRedistRouteStateList = ospfRedistRouteStateResponse
+	return ospfRedistRouteStateGetInfo, nil
+}
@@ -0,0 +1,86 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertGracefulRestartFromThrift(ospfGracefulRestart *ospfdInt.OspfGracefulRestart) config.GracefulRestartConf {
+	return config.GracefulRestartConf{
+		HelperSupport:     config.RestartSupport(ospfGracefulRestart.HelperSupport),
+		StrictLsaChecking: ospfGracefulRestart.StrictLsaChecking,
+	}
+}
+
+func (h *OSPFHandler) SendOspfGracefulRestart(ospfGracefulRestart *ospfdInt.OspfGracefulRestart) (bool, error) {
+	if ospfGracefulRestart == nil {
+		err := errors.New("Invalid Graceful Restart Configuration")
+		return false, err
+	}
+	conf := convertGracefulRestartFromThrift(ospfGracefulRestart)
+	err := h.server.ValidateGracefulRestartConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.GracefulRestartConfigCh <- conf
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfGracefulRestart(ospfGracefulRestart *ospfdInt.OspfGracefulRestart) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create graceful restart:", ospfGracefulRestart))
+	return h.SendOspfGracefulRestart(ospfGracefulRestart)
+}
+
+func (h *OSPFHandler) UpdateOspfGracefulRestart(ospfGracefulRestart *ospfdInt.OspfGracefulRestart) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update graceful restart:", ospfGracefulRestart))
+	return h.SendOspfGracefulRestart(ospfGracefulRestart)
+}
+
+func (h *OSPFHandler) DeleteOspfGracefulRestart(ospfGracefulRestart *ospfdInt.OspfGracefulRestart) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete graceful restart:", ospfGracefulRestart))
+	h.server.GracefulRestartConfigCh <- server.DefaultGracefulRestartConf()
+	return true, nil
+}
+
+/* Grace LSAs are sent to the neighbors, ospfd has to be restarted
+   within the grace period (RestartInterval of OspfGlobal). */
+func (h *OSPFHandler) ExecuteOspfRestartPrepare(ospfRestartPrepare *ospfdInt.OspfRestartPrepare) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Prepare graceful restart:", ospfRestartPrepare))
+	if ospfRestartPrepare == nil || ospfRestartPrepare.Reason < 0 || ospfRestartPrepare.Reason > 255 {
+		err := errors.New("Invalid Restart Prepare request")
+		return false, err
+	}
+	reason := uint8(ospfRestartPrepare.Reason)
+	err := h.server.ValidateGraceRestartPrepare(reason)
+	if err != nil {
+		return false, err
+	}
+	h.server.GraceRestartPrepareCh <- reason
+	return true, nil
+}
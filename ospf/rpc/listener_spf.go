@@ -0,0 +1,134 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertSpfThrottleFromThrift(ospfSpfThrottle *ospfdInt.OspfSpfThrottle) config.SpfThrottleConf {
+	return config.SpfThrottleConf{
+		SpfInitialDelay: ospfSpfThrottle.InitialDelay,
+		SpfShortDelay:   ospfSpfThrottle.ShortDelay,
+		SpfLongDelay:    ospfSpfThrottle.LongDelay,
+		SpfTimeToLearn:  ospfSpfThrottle.TimeToLearn,
+		SpfHolddown:     ospfSpfThrottle.Holddown,
+	}
+}
+
+func (h *OSPFHandler) SendOspfSpfThrottle(ospfSpfThrottle *ospfdInt.OspfSpfThrottle) (bool, error) {
+	if ospfSpfThrottle == nil {
+		err := errors.New("Invalid SPF Throttle Configuration")
+		return false, err
+	}
+	conf := convertSpfThrottleFromThrift(ospfSpfThrottle)
+	err := h.server.ValidateSpfThrottleConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.SpfThrottleConfigCh <- conf
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfSpfThrottle(ospfSpfThrottle *ospfdInt.OspfSpfThrottle) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create SPF throttle:", ospfSpfThrottle))
+	return h.SendOspfSpfThrottle(ospfSpfThrottle)
+}
+
+func (h *OSPFHandler) UpdateOspfSpfThrottle(ospfSpfThrottle *ospfdInt.OspfSpfThrottle) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update SPF throttle:", ospfSpfThrottle))
+	return h.SendOspfSpfThrottle(ospfSpfThrottle)
+}
+
+func (h *OSPFHandler) DeleteOspfSpfThrottle(ospfSpfThrottle *ospfdInt.OspfSpfThrottle) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete SPF throttle:", ospfSpfThrottle))
+	h.server.SpfThrottleConfigCh <- server.DefaultSpfThrottleConf()
+	return true, nil
+}
+
+func (h *OSPFHandler) GetBulkOspfSpfThrottleState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfSpfThrottleStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get SPF throttle state"))
+
+	if fromIdx != 0 {
+		err := errors.New("Invalid range")
+		return nil, err
+	}
+	globalState := h.server.GetOspfGlobalState()
+	spfState := ospfdInt.NewOspfSpfThrottleState()
+	spfState.State = globalState.SpfThrottleState
+	spfState.Events = globalState.SpfEvents
+	spfState.DeferredEvents = globalState.SpfDeferredEvents
+	spfState.Runs = globalState.SpfRunsTotal
+	spfStateGetInfo := ospfdInt.NewOspfSpfThrottleStateGetInfo()
+	spfStateGetInfo.Count = ospfdInt.Int(1)
+	spfStateGetInfo.StartIdx = ospfdInt.Int(0)
+	spfStateGetInfo.EndIdx = ospfdInt.Int(0)
+	spfStateGetInfo.More = false
+	spfStateGetInfo.OspfSpfThrottleStateList = []*ospfdInt.OspfSpfThrottleState{spfState}
+	return spfStateGetInfo, nil
+}
+
+func (h *OSPFHandler) convertAreaSpfStateToThrift(ent config.AreaState) *ospfdInt.OspfAreaSpfState {
+	areaSpfState := ospfdInt.NewOspfAreaSpfState()
+	areaSpfState.AreaId = string(ent.AreaId)
+	areaSpfState.SpfRuns = ent.SpfRuns
+	areaSpfState.SpfPartialRuns = ent.SpfPartialRuns
+	areaSpfState.SpfHistory = make([]*ospfdInt.OspfSpfRunLog, len(ent.SpfHistory))
+	for idx, run := range ent.SpfHistory {
+		runLog := ospfdInt.NewOspfSpfRunLog()
+		runLog.StartTime = run.StartTime
+		runLog.Trigger = run.Trigger
+		runLog.Events = run.Events
+		runLog.FullSpf = run.FullSpf
+		runLog.Duration = run.Duration
+		runLog.RoutesChanged = run.RoutesChanged
+		areaSpfState.SpfHistory[idx] = runLog
+	}
+	return areaSpfState
+}
+
+func (h *OSPFHandler) GetBulkOspfAreaSpfState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfAreaSpfStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get area SPF state"))
+
+	nextIdx, currCount, ospfAreaEntryStates := h.server.GetBulkOspfAreaEntryState(int(fromIdx), int(count))
+	if ospfAreaEntryStates == nil {
+		err := errors.New("Ospf is busy refreshing the cache")
+		return nil, err
+	}
+	ospfAreaSpfStateResponse := make([]*ospfdInt.OspfAreaSpfState, len(ospfAreaEntryStates))
+	for idx, item := range ospfAreaEntryStates {
+		ospfAreaSpfStateResponse[idx] = h.convertAreaSpfStateToThrift(item)
+	}
+	ospfAreaSpfStateGetInfo := ospfdInt.NewOspfAreaSpfStateGetInfo()
+	ospfAreaSpfStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfAreaSpfStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfAreaSpfStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfAreaSpfStateGetInfo.More = (nextIdx != 0)
+	ospfAreaSpfStateGetInfo.OspfAreaSpfStateList = ospfAreaSpfStateResponse
+	return ospfAreaSpfStateGetInfo, nil
+}
@@ -0,0 +1,174 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"ospfdInt"
+)
+
+func convertPktStatsToThrift(stats config.PktStats) *ospfdInt.OspfPktStats {
+	pktStats := ospfdInt.NewOspfPktStats()
+	pktStats.TxHello = int32(stats.TxHello)
+	pktStats.TxDbd = int32(stats.TxDbd)
+	pktStats.TxLsReq = int32(stats.TxLsReq)
+	pktStats.TxLsUpd = int32(stats.TxLsUpd)
+	pktStats.TxLsAck = int32(stats.TxLsAck)
+	pktStats.RxHello = int32(stats.RxHello)
+	pktStats.RxDbd = int32(stats.RxDbd)
+	pktStats.RxLsReq = int32(stats.RxLsReq)
+	pktStats.RxLsUpd = int32(stats.RxLsUpd)
+	pktStats.RxLsAck = int32(stats.RxLsAck)
+	pktStats.RxBadLength = int32(stats.RxBadLength)
+	pktStats.RxBadVersion = int32(stats.RxBadVersion)
+	pktStats.RxBadChecksum = int32(stats.RxBadChecksum)
+	pktStats.RxAreaMismatch = int32(stats.RxAreaMismatch)
+	pktStats.RxAuthFailure = int32(stats.RxAuthFailure)
+	pktStats.RxNetmaskMismatch = int32(stats.RxNetmaskMismatch)
+	pktStats.RxHelloIntervalMismatch = int32(stats.RxHelloIntervalMismatch)
+	pktStats.RxDeadIntervalMismatch = int32(stats.RxDeadIntervalMismatch)
+	pktStats.RxOptionsMismatch = int32(stats.RxOptionsMismatch)
+	pktStats.RxMtuMismatch = int32(stats.RxMtuMismatch)
+	pktStats.RxUnknownNbr = int32(stats.RxUnknownNbr)
+	pktStats.RxBadPktType = int32(stats.RxBadPktType)
+	return pktStats
+}
+
+func (h *OSPFHandler) convertIfStatsStateToThrift(ent config.IfStatsState) *ospfdInt.OspfIfStatsState {
+	ifStatsState := ospfdInt.NewOspfIfStatsState()
+	ifStatsState.IfIpAddress = string(ent.IfIpAddress)
+	ifStatsState.AddressLessIf = int32(ent.AddressLessIf)
+	ifStatsState.Stats = convertPktStatsToThrift(ent.Stats)
+	ifStatsState.LastDropReason = ent.LastDropReason
+	ifStatsState.LastDropSrc = string(ent.LastDropSrc)
+	ifStatsState.LastDropTime = ent.LastDropTime
+	return ifStatsState
+}
+
+func (h *OSPFHandler) GetBulkOspfIfStatsState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfIfStatsStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get interface packet statistics"))
+
+	nextIdx, currCount, ifStatsStates := h.server.GetBulkOspfIfStatsState(int(fromIdx), int(count))
+	ospfIfStatsStateResponse := make([]*ospfdInt.OspfIfStatsState, len(ifStatsStates))
+	for idx, item := range ifStatsStates {
+		ospfIfStatsStateResponse[idx] = h.convertIfStatsStateToThrift(item)
+	}
+	ospfIfStatsStateGetInfo := ospfdInt.NewOspfIfStatsStateGetInfo()
+	ospfIfStatsStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfIfStatsStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfIfStatsStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfIfStatsStateGetInfo.More = (nextIdx != 0)
+	ospfIfStatsStateGetInfo.OspfIfStatsStateList = ospfIfStatsStateResponse
+	return ospfIfStatsStateGetInfo, nil
+}
+
+func (h *OSPFHandler) convertNbrStatsStateToThrift(ent config.NbrStatsState) *ospfdInt.OspfNbrStatsState {
+	nbrStatsState := ospfdInt.NewOspfNbrStatsState()
+	nbrStatsState.NbrIpAddress = string(ent.NbrIpAddress)
+	nbrStatsState.NbrAddressLessIndex = int32(ent.NbrAddressLessIndex)
+	nbrStatsState.NbrRtrId = ent.NbrRtrId
+	nbrStatsState.NbrState = ent.NbrState
+	nbrStatsState.NbrStateChanges = int32(ent.NbrStateChanges)
+	nbrStatsState.NbrUptime = ent.NbrUptime
+	nbrStatsState.NbrLsRetransQLen = int32(ent.NbrLsRetransQLen)
+	nbrStatsState.Stats = convertPktStatsToThrift(ent.Stats)
+	return nbrStatsState
+}
+
+func (h *OSPFHandler) GetBulkOspfNbrStatsState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfNbrStatsStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get neighbor packet statistics"))
+
+	nextIdx, currCount, nbrStatsStates := h.server.GetBulkOspfNbrStatsState(int(fromIdx), int(count))
+	ospfNbrStatsStateResponse := make([]*ospfdInt.OspfNbrStatsState, len(nbrStatsStates))
+	for idx, item := range nbrStatsStates {
+		ospfNbrStatsStateResponse[idx] = h.convertNbrStatsStateToThrift(item)
+	}
+	ospfNbrStatsStateGetInfo := ospfdInt.NewOspfNbrStatsStateGetInfo()
+	ospfNbrStatsStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfNbrStatsStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfNbrStatsStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfNbrStatsStateGetInfo.More = (nextIdx != 0)
+	ospfNbrStatsStateGetInfo.OspfNbrStatsStateList = ospfNbrStatsStateResponse
+	return ospfNbrStatsStateGetInfo, nil
+}
+
+func (h *OSPFHandler) convertSrcDropStatsStateToThrift(ent config.SrcDropStatsState) *ospfdInt.OspfSrcDropStatsState {
+	srcDropStatsState := ospfdInt.NewOspfSrcDropStatsState()
+	srcDropStatsState.IfIpAddress = string(ent.IfIpAddress)
+	srcDropStatsState.AddressLessIf = int32(ent.AddressLessIf)
+	srcDropStatsState.SrcIpAddress = string(ent.SrcIpAddress)
+	srcDropStatsState.Stats = convertPktStatsToThrift(ent.Stats)
+	srcDropStatsState.LastDropReason = ent.LastDropReason
+	srcDropStatsState.LastDropTime = ent.LastDropTime
+	return srcDropStatsState
+}
+
+func (h *OSPFHandler) GetBulkOspfSrcDropStatsState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfSrcDropStatsStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get per source drop statistics"))
+
+	nextIdx, currCount, srcDropStatsStates := h.server.GetBulkOspfSrcDropStatsState(int(fromIdx), int(count))
+	ospfSrcDropStatsStateResponse := make([]*ospfdInt.OspfSrcDropStatsState, len(srcDropStatsStates))
+	for idx, item := range srcDropStatsStates {
+		ospfSrcDropStatsStateResponse[idx] = h.convertSrcDropStatsStateToThrift(item)
+	}
+	ospfSrcDropStatsStateGetInfo := ospfdInt.NewOspfSrcDropStatsStateGetInfo()
+	ospfSrcDropStatsStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfSrcDropStatsStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfSrcDropStatsStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfSrcDropStatsStateGetInfo.More = (nextIdx != 0)
+	ospfSrcDropStatsStateGetInfo.OspfSrcDropStatsStateList = ospfSrcDropStatsStateResponse
+	return ospfSrcDropStatsStateGetInfo, nil
+}
+
+func (h *OSPFHandler) convertNbrEventStateToThrift(ent config.NbrEventState) *ospfdInt.OspfNbrEventState {
+	nbrEventState := ospfdInt.NewOspfNbrEventState()
+	nbrEventState.EventIdx = ent.EventIdx
+	nbrEventState.TimeStamp = ent.TimeStamp
+	nbrEventState.NbrIpAddress = string(ent.NbrIpAddress)
+	nbrEventState.NbrAddressLessIndex = int32(ent.NbrAddressLessIndex)
+	nbrEventState.NbrRtrId = ent.NbrRtrId
+	nbrEventState.OldState = ent.OldState
+	nbrEventState.NewState = ent.NewState
+	nbrEventState.Reason = ent.Reason
+	nbrEventState.NbrLsRetransQLen = int32(ent.NbrLsRetransQLen)
+	return nbrEventState
+}
+
+func (h *OSPFHandler) GetBulkOspfNbrEventState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfNbrEventStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get neighbor event log"))
+
+	nextIdx, currCount, nbrEventStates := h.server.GetBulkOspfNbrEventState(int(fromIdx), int(count))
+	ospfNbrEventStateResponse := make([]*ospfdInt.OspfNbrEventState, len(nbrEventStates))
+	for idx, item := range nbrEventStates {
+		ospfNbrEventStateResponse[idx] = h.convertNbrEventStateToThrift(item)
+	}
+	ospfNbrEventStateGetInfo := ospfdInt.NewOspfNbrEventStateGetInfo()
+	ospfNbrEventStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfNbrEventStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfNbrEventStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfNbrEventStateGetInfo.More = (nextIdx != 0)
+	ospfNbrEventStateGetInfo.OspfNbrEventStateList = ospfNbrEventStateResponse
+	return ospfNbrEventStateGetInfo, nil
+}
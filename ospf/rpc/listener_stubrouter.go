@@ -0,0 +1,106 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertStubRouterFromThrift(ospfStubRouter *ospfdInt.OspfStubRouter) config.StubRouterConf {
+	return config.StubRouterConf{
+		OnStartup:      ospfStubRouter.OnStartup,
+		StartupTime:    ospfStubRouter.StartupTime,
+		WaitForBgp:     ospfStubRouter.WaitForBgp,
+		Administrative: ospfStubRouter.Administrative,
+		SummaryLsa:     ospfStubRouter.SummaryLsa,
+		ExternalLsa:    ospfStubRouter.ExternalLsa,
+	}
+}
+
+func (h *OSPFHandler) SendOspfStubRouter(ospfStubRouter *ospfdInt.OspfStubRouter) (bool, error) {
+	if ospfStubRouter == nil {
+		err := errors.New("Invalid Stub Router Configuration")
+		return false, err
+	}
+	conf := convertStubRouterFromThrift(ospfStubRouter)
+	err := h.server.ValidateStubRouterConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.StubRouterConfigCh <- conf
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfStubRouter(ospfStubRouter *ospfdInt.OspfStubRouter) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create stub router:", ospfStubRouter))
+	return h.SendOspfStubRouter(ospfStubRouter)
+}
+
+func (h *OSPFHandler) UpdateOspfStubRouter(ospfStubRouter *ospfdInt.OspfStubRouter) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update stub router:", ospfStubRouter))
+	return h.SendOspfStubRouter(ospfStubRouter)
+}
+
+func (h *OSPFHandler) DeleteOspfStubRouter(ospfStubRouter *ospfdInt.OspfStubRouter) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete stub router:", ospfStubRouter))
+	h.server.StubRouterConfigCh <- server.DefaultStubRouterConf()
+	return true, nil
+}
+
+/* Ends the startup max-metric period of the routers configured
+   with WaitForBgp. Sent by bgpd once it has converged, the call
+   does not wait for the server when a notification is pending. */
+func (h *OSPFHandler) ExecuteOspfBgpConverged(ospfBgpConverged *ospfdInt.OspfBgpConverged) (bool, error) {
+	h.logger.Info(fmt.Sprintln("BGP converged:", ospfBgpConverged))
+	select {
+	case h.server.BgpConvergedCh <- true:
+	default:
+		h.logger.Info("BGP convergence already pending")
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) GetBulkOspfStubRouterState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfStubRouterStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get stub router state"))
+
+	if fromIdx != 0 {
+		err := errors.New("Invalid range")
+		return nil, err
+	}
+	globalState := h.server.GetOspfGlobalState()
+	stubState := ospfdInt.NewOspfStubRouterState()
+	stubState.Status = globalState.StubRouterStatus
+	stubState.RemainingTime = globalState.StubRouterRemain
+	stubStateGetInfo := ospfdInt.NewOspfStubRouterStateGetInfo()
+	stubStateGetInfo.Count = ospfdInt.Int(1)
+	stubStateGetInfo.StartIdx = ospfdInt.Int(0)
+	stubStateGetInfo.EndIdx = ospfdInt.Int(0)
+	stubStateGetInfo.More = false
+	stubStateGetInfo.OspfStubRouterStateList = []*ospfdInt.OspfStubRouterState{stubState}
+	return stubStateGetInfo, nil
+}
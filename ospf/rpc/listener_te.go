@@ -0,0 +1,206 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+)
+
+func convertOpaqueFromThrift(ospfOpaque *ospfdInt.OspfOpaque) config.OpaqueConf {
+	return config.OpaqueConf{
+		OpaqueLsaSupport: ospfOpaque.OpaqueLsaSupport,
+		TeEnable:         ospfOpaque.TeEnable,
+		TeRouterAddress:  config.IpAddress(ospfOpaque.TeRouterAddress),
+	}
+}
+
+func (h *OSPFHandler) SendOspfOpaque(ospfOpaque *ospfdInt.OspfOpaque, op bool) (bool, error) {
+	if ospfOpaque == nil {
+		err := errors.New("Invalid Opaque LSA Configuration")
+		return false, err
+	}
+	conf := convertOpaqueFromThrift(ospfOpaque)
+	err := h.server.ValidateOpaqueConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.OpaqueConfigCh <- server.OpaqueConfMsg{Op: op, Opaque: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfOpaque(ospfOpaque *ospfdInt.OspfOpaque) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create Opaque LSA config:", ospfOpaque))
+	return h.SendOspfOpaque(ospfOpaque, true)
+}
+
+func (h *OSPFHandler) UpdateOspfOpaque(ospfOpaque *ospfdInt.OspfOpaque) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update Opaque LSA config:", ospfOpaque))
+	return h.SendOspfOpaque(ospfOpaque, true)
+}
+
+func (h *OSPFHandler) DeleteOspfOpaque(ospfOpaque *ospfdInt.OspfOpaque) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete Opaque LSA config:", ospfOpaque))
+	return h.SendOspfOpaque(ospfOpaque, false)
+}
+
+func convertIfTeFromThrift(ospfIfTe *ospfdInt.OspfIfTe) config.IfTeConf {
+	return config.IfTeConf{
+		IfIpAddress:     config.IpAddress(ospfIfTe.IfIpAddress),
+		AddressLessIf:   config.InterfaceIndexOrZero(ospfIfTe.AddressLessIf),
+		TeMetric:        uint32(ospfIfTe.TeMetric),
+		AdminGroup:      uint32(ospfIfTe.AdminGroup),
+		MaxBandwidth:    uint32(ospfIfTe.MaxBandwidth),
+		MaxRsvBandwidth: uint32(ospfIfTe.MaxRsvBandwidth),
+	}
+}
+
+func (h *OSPFHandler) SendOspfIfTe(ospfIfTe *ospfdInt.OspfIfTe, op bool) (bool, error) {
+	if ospfIfTe == nil {
+		err := errors.New("Invalid interface TE Configuration")
+		return false, err
+	}
+	conf := convertIfTeFromThrift(ospfIfTe)
+	err := h.server.ValidateIfTeConf(conf)
+	if err != nil {
+		return false, err
+	}
+	h.server.OpaqueConfigCh <- server.OpaqueConfMsg{Op: op, IfTe: &conf}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfIfTe(ospfIfTe *ospfdInt.OspfIfTe) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create interface TE:", ospfIfTe))
+	return h.SendOspfIfTe(ospfIfTe, true)
+}
+
+func (h *OSPFHandler) UpdateOspfIfTe(ospfIfTe *ospfdInt.OspfIfTe) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update interface TE:", ospfIfTe))
+	return h.SendOspfIfTe(ospfIfTe, true)
+}
+
+func (h *OSPFHandler) DeleteOspfIfTe(ospfIfTe *ospfdInt.OspfIfTe) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete interface TE:", ospfIfTe))
+	return h.SendOspfIfTe(ospfIfTe, false)
+}
+
+/* Opaque information is passed as a hex string */
+func convertOpaqueLsaFromThrift(ospfOpaqueLsa *ospfdInt.OspfOpaqueLsa) (config.OpaqueLsaConf, error) {
+	data, err := hex.DecodeString(ospfOpaqueLsa.Data)
+	if err != nil {
+		return config.OpaqueLsaConf{}, errors.New(fmt.Sprintln("Invalid opaque data", err))
+	}
+	return config.OpaqueLsaConf{
+		LsaType:       config.LsaType(ospfOpaqueLsa.LsaType),
+		OpaqueType:    uint8(ospfOpaqueLsa.OpaqueType),
+		OpaqueId:      uint32(ospfOpaqueLsa.OpaqueId),
+		AreaId:        config.AreaId(ospfOpaqueLsa.AreaId),
+		IfIpAddress:   config.IpAddress(ospfOpaqueLsa.IfIpAddress),
+		AddressLessIf: config.InterfaceIndexOrZero(ospfOpaqueLsa.AddressLessIf),
+		Data:          data,
+	}, nil
+}
+
+func (h *OSPFHandler) SendOspfOpaqueLsa(ospfOpaqueLsa *ospfdInt.OspfOpaqueLsa, op bool) (bool, error) {
+	if ospfOpaqueLsa == nil {
+		err := errors.New("Invalid Opaque LSA")
+		return false, err
+	}
+	if ospfOpaqueLsa.OpaqueType < 0 || ospfOpaqueLsa.OpaqueType > 255 {
+		return false, errors.New(fmt.Sprintln("Invalid opaque type", ospfOpaqueLsa.OpaqueType))
+	}
+	conf, err := convertOpaqueLsaFromThrift(ospfOpaqueLsa)
+	if err != nil {
+		return false, err
+	}
+	if op {
+		err = h.server.OriginateOpaqueLsa(conf)
+	} else {
+		err = h.server.WithdrawOpaqueLsa(conf)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfOpaqueLsa(ospfOpaqueLsa *ospfdInt.OspfOpaqueLsa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Create Opaque LSA:", ospfOpaqueLsa))
+	return h.SendOspfOpaqueLsa(ospfOpaqueLsa, true)
+}
+
+func (h *OSPFHandler) UpdateOspfOpaqueLsa(ospfOpaqueLsa *ospfdInt.OspfOpaqueLsa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Update Opaque LSA:", ospfOpaqueLsa))
+	return h.SendOspfOpaqueLsa(ospfOpaqueLsa, true)
+}
+
+func (h *OSPFHandler) DeleteOspfOpaqueLsa(ospfOpaqueLsa *ospfdInt.OspfOpaqueLsa) (bool, error) {
+	h.logger.Info(fmt.Sprintln("Delete Opaque LSA:", ospfOpaqueLsa))
+	return h.SendOspfOpaqueLsa(ospfOpaqueLsa, false)
+}
+
+func (h *OSPFHandler) convertTeLinkStateToThrift(ent config.TeLinkState) *ospfdInt.OspfTeLinkState {
+	teLinkState := ospfdInt.NewOspfTeLinkState()
+	teLinkState.AreaId = string(ent.AreaId)
+	teLinkState.AdvRouter = string(ent.AdvRouter)
+	teLinkState.RouterAddress = string(ent.RouterAddress)
+	teLinkState.OpaqueId = int32(ent.OpaqueId)
+	teLinkState.LinkType = int32(ent.LinkType)
+	teLinkState.LinkId = string(ent.LinkId)
+	for _, addr := range ent.LocalIpAddress {
+		teLinkState.LocalIpAddress = append(teLinkState.LocalIpAddress, string(addr))
+	}
+	for _, addr := range ent.RemoteIpAddress {
+		teLinkState.RemoteIpAddress = append(teLinkState.RemoteIpAddress, string(addr))
+	}
+	teLinkState.TeMetric = int32(ent.TeMetric)
+	teLinkState.MaxBandwidth = float64(ent.MaxBandwidth)
+	teLinkState.MaxRsvBandwidth = float64(ent.MaxRsvBandwidth)
+	for _, bw := range ent.UnrsvBandwidth {
+		teLinkState.UnrsvBandwidth = append(teLinkState.UnrsvBandwidth, float64(bw))
+	}
+	teLinkState.AdminGroup = int32(ent.AdminGroup)
+	return teLinkState
+}
+
+func (h *OSPFHandler) GetBulkOspfTeLinkState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfTeLinkStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get TE link state"))
+
+	nextIdx, currCount, teLinkStates := h.server.GetBulkOspfTeLinkState(int(fromIdx), int(count))
+	ospfTeLinkStateResponse := make([]*ospfdInt.OspfTeLinkState, len(teLinkStates))
+	for idx, item := range teLinkStates {
+		ospfTeLinkStateResponse[idx] = h.convertTeLinkStateToThrift(item)
+	}
+	ospfTeLinkStateGetInfo := ospfdInt.NewOspfTeLinkStateGetInfo()
+	ospfTeLinkStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfTeLinkStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfTeLinkStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfTeLinkStateGetInfo.More = (nextIdx != 0)
+	ospfTeLinkStateGetInfo.OspfTeLinkStateList = ospfTeLinkStateResponse
+	return ospfTeLinkStateGetInfo, nil
+}
@@ -24,6 +24,7 @@
 package rpc
 
 import (
+	"errors"
 	"fmt"
 	"ospfd"
 	//    "l3/ospf/config"
@@ -59,6 +60,14 @@ func (h *OSPFHandler) UpdateOspfIfMetricEntry(origConf *ospfd.OspfIfMetricEntry,
 func (h *OSPFHandler) UpdateOspfVirtIfEntry(origConf *ospfd.OspfVirtIfEntry, newConf *ospfd.OspfVirtIfEntry, attrset []bool, op []*ospfd.PatchOpInfo) (bool, error) {
 	h.logger.Info(fmt.Sprintln("Original virtual interface config attrs:", origConf))
 	h.logger.Info(fmt.Sprintln("New virtual interface config attrs:", newConf))
+	if newConf == nil {
+		err := errors.New("Invalid Virtual Interface Configuration")
+		return false, err
+	}
+	err := h.SendOspfVirtIfConf(newConf, true)
+	if err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
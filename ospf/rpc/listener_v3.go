@@ -0,0 +1,255 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"ospfdInt"
+	"strings"
+)
+
+func (h *OSPFHandler) convertOspfv3GlobalFromThrift(ospfv3Global *ospfdInt.Ospfv3Global) (config.Ospfv3GlobalConf, error) {
+	conf := config.Ospfv3GlobalConf{
+		RouterId:  config.RouterId(ospfv3Global.RouterId),
+		AdminStat: config.Status(ospfv3Global.AdminStat),
+	}
+	if ospfv3Global.InstanceId < 0 || ospfv3Global.InstanceId > 0xff {
+		return conf, errors.New(fmt.Sprintln("Invalid Ospfv3 instance id", ospfv3Global.InstanceId))
+	}
+	conf.InstanceId = uint8(ospfv3Global.InstanceId)
+	return conf, nil
+}
+
+func (h *OSPFHandler) convertOspfv3IfEntryFromThrift(ospfv3IfEntry *ospfdInt.Ospfv3IfEntry) (config.Ospfv3IntfConf, error) {
+	conf := config.Ospfv3IntfConf{
+		IfIndex:           ospfv3IfEntry.IfIndex,
+		IfAreaId:          config.AreaId(ospfv3IfEntry.AreaId),
+		IfAdminStat:       config.Status(ospfv3IfEntry.AdminStat),
+		IfRtrPriority:     config.DesignatedRouterPriority(ospfv3IfEntry.RtrPriority),
+		IfHelloInterval:   config.HelloRange(ospfv3IfEntry.HelloInterval),
+		IfRtrDeadInterval: config.PositiveInteger(ospfv3IfEntry.RtrDeadInterval),
+		IfCost:            config.Metric(ospfv3IfEntry.Cost),
+	}
+	if ospfv3IfEntry.InstanceId < 0 || ospfv3IfEntry.InstanceId > 0xff {
+		return conf, errors.New(fmt.Sprintln("Invalid Ospfv3 instance id", ospfv3IfEntry.InstanceId))
+	}
+	conf.InstanceId = uint8(ospfv3IfEntry.InstanceId)
+	if ospfv3IfEntry.RtrPriority < 0 || ospfv3IfEntry.RtrPriority > 0xff {
+		return conf, errors.New(fmt.Sprintln("Invalid router priority", ospfv3IfEntry.RtrPriority))
+	}
+	if ospfv3IfEntry.HelloInterval < 0 || ospfv3IfEntry.HelloInterval > 0xffff ||
+		ospfv3IfEntry.RtrDeadInterval < 0 || ospfv3IfEntry.RtrDeadInterval > 0xffff {
+		return conf, errors.New("Invalid hello or dead interval")
+	}
+	if ospfv3IfEntry.Cost < 0 || ospfv3IfEntry.Cost > 0xffff {
+		return conf, errors.New(fmt.Sprintln("Invalid interface cost", ospfv3IfEntry.Cost))
+	}
+	for index, ifName := range config.IfTypeList {
+		if strings.EqualFold(ospfv3IfEntry.IfType, ifName) {
+			conf.IfType = config.IfType(index)
+			break
+		}
+	}
+	if conf.IfType != 0 && conf.IfType != config.Broadcast &&
+		conf.IfType != config.NumberedP2P && conf.IfType != config.UnnumberedP2P {
+		return conf, errors.New(fmt.Sprintln("Unsupported Ospfv3 interface type", ospfv3IfEntry.IfType))
+	}
+	return conf, nil
+}
+
+func (h *OSPFHandler) SendOspfv3Global(ospfv3Global *ospfdInt.Ospfv3Global, op bool) error {
+	conf, err := h.convertOspfv3GlobalFromThrift(ospfv3Global)
+	if err != nil {
+		return err
+	}
+	h.server.Ospfv3ConfigCh <- server.Ospfv3ConfMsg{Op: op, Global: &conf}
+	return nil
+}
+
+func (h *OSPFHandler) SendOspfv3IfEntry(ospfv3IfEntry *ospfdInt.Ospfv3IfEntry, op bool) error {
+	conf, err := h.convertOspfv3IfEntryFromThrift(ospfv3IfEntry)
+	if err != nil {
+		return err
+	}
+	h.server.Ospfv3ConfigCh <- server.Ospfv3ConfMsg{Op: op, Intf: &conf}
+	return nil
+}
+
+func (h *OSPFHandler) CreateOspfv3Global(ospfv3Global *ospfdInt.Ospfv3Global) (bool, error) {
+	if ospfv3Global == nil {
+		err := errors.New("Invalid Ospfv3 Global Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Create Ospfv3 global config attrs:", ospfv3Global))
+	err := h.SendOspfv3Global(ospfv3Global, true)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) UpdateOspfv3Global(ospfv3Global *ospfdInt.Ospfv3Global) (bool, error) {
+	if ospfv3Global == nil {
+		err := errors.New("Invalid Ospfv3 Global Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Update Ospfv3 global config attrs:", ospfv3Global))
+	err := h.SendOspfv3Global(ospfv3Global, true)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) DeleteOspfv3Global(ospfv3Global *ospfdInt.Ospfv3Global) (bool, error) {
+	if ospfv3Global == nil {
+		err := errors.New("Invalid Ospfv3 Global Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Delete Ospfv3 global config attrs:", ospfv3Global))
+	err := h.SendOspfv3Global(ospfv3Global, false)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) CreateOspfv3IfEntry(ospfv3IfEntry *ospfdInt.Ospfv3IfEntry) (bool, error) {
+	if ospfv3IfEntry == nil {
+		err := errors.New("Invalid Ospfv3 Interface Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Create Ospfv3 interface config attrs:", ospfv3IfEntry))
+	err := h.SendOspfv3IfEntry(ospfv3IfEntry, true)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) UpdateOspfv3IfEntry(ospfv3IfEntry *ospfdInt.Ospfv3IfEntry) (bool, error) {
+	if ospfv3IfEntry == nil {
+		err := errors.New("Invalid Ospfv3 Interface Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Update Ospfv3 interface config attrs:", ospfv3IfEntry))
+	err := h.SendOspfv3IfEntry(ospfv3IfEntry, true)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) DeleteOspfv3IfEntry(ospfv3IfEntry *ospfdInt.Ospfv3IfEntry) (bool, error) {
+	if ospfv3IfEntry == nil {
+		err := errors.New("Invalid Ospfv3 Interface Configuration")
+		return false, err
+	}
+	h.logger.Info(fmt.Sprintln("Delete Ospfv3 interface config attrs:", ospfv3IfEntry))
+	err := h.SendOspfv3IfEntry(ospfv3IfEntry, false)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OSPFHandler) GetBulkOspfv3IfState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.Ospfv3IfStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get Ospfv3 interface attrs"))
+
+	nextIdx, currCount, states := h.server.GetBulkOspfv3IntfState(int(fromIdx), int(count))
+	response := make([]*ospfdInt.Ospfv3IfState, len(states))
+	for idx, ent := range states {
+		ifState := ospfdInt.NewOspfv3IfState()
+		ifState.InstanceId = int32(ent.InstanceId)
+		ifState.IfIndex = ent.IfIndex
+		ifState.IfName = ent.IfName
+		ifState.LinkLocalAddress = ent.IfLinkLocalAddress
+		ifState.AreaId = string(ent.IfAreaId)
+		ifState.State = int32(ent.IfState)
+		ifState.DesignatedRouter = string(ent.IfDesignatedRouter)
+		ifState.BackupDesignatedRouter = string(ent.IfBackupRouter)
+		ifState.NbrCount = ent.IfNbrCount
+		ifState.AdjacentNbrCount = ent.IfAdjacentNbrCount
+		response[idx] = ifState
+	}
+	getInfo := ospfdInt.NewOspfv3IfStateGetInfo()
+	getInfo.Count = ospfdInt.Int(currCount)
+	getInfo.StartIdx = ospfdInt.Int(fromIdx)
+	getInfo.EndIdx = ospfdInt.Int(nextIdx)
+	getInfo.More = (nextIdx != 0)
+	getInfo.Ospfv3IfStateList = response
+	return getInfo, nil
+}
+
+func (h *OSPFHandler) GetBulkOspfv3NbrState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.Ospfv3NbrStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get Ospfv3 neighbor attrs"))
+
+	nextIdx, currCount, states := h.server.GetBulkOspfv3NbrState(int(fromIdx), int(count))
+	response := make([]*ospfdInt.Ospfv3NbrState, len(states))
+	for idx, ent := range states {
+		nbrState := ospfdInt.NewOspfv3NbrState()
+		nbrState.InstanceId = int32(ent.InstanceId)
+		nbrState.IfIndex = ent.IfIndex
+		nbrState.RouterId = string(ent.NbrRouterId)
+		nbrState.Address = ent.NbrAddress
+		nbrState.InterfaceId = int64(ent.NbrInterfaceId)
+		nbrState.Priority = int32(ent.NbrPriority)
+		nbrState.State = ent.NbrState
+		response[idx] = nbrState
+	}
+	getInfo := ospfdInt.NewOspfv3NbrStateGetInfo()
+	getInfo.Count = ospfdInt.Int(currCount)
+	getInfo.StartIdx = ospfdInt.Int(fromIdx)
+	getInfo.EndIdx = ospfdInt.Int(nextIdx)
+	getInfo.More = (nextIdx != 0)
+	getInfo.Ospfv3NbrStateList = response
+	return getInfo, nil
+}
+
+func (h *OSPFHandler) GetBulkOspfv3RouteState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.Ospfv3RouteStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get Ospfv3 route attrs"))
+
+	nextIdx, currCount, states := h.server.GetBulkOspfv3RouteState(int(fromIdx), int(count))
+	response := make([]*ospfdInt.Ospfv3RouteState, len(states))
+	for idx, ent := range states {
+		routeState := ospfdInt.NewOspfv3RouteState()
+		routeState.InstanceId = int32(ent.InstanceId)
+		routeState.DestinationNw = ent.DestinationNw
+		routeState.AreaId = string(ent.AreaId)
+		routeState.PathType = ent.PathType
+		routeState.Cost = int64(ent.Cost)
+		routeState.NextHops = ent.NextHops
+		response[idx] = routeState
+	}
+	getInfo := ospfdInt.NewOspfv3RouteStateGetInfo()
+	getInfo.Count = ospfdInt.Int(currCount)
+	getInfo.StartIdx = ospfdInt.Int(fromIdx)
+	getInfo.EndIdx = ospfdInt.Int(nextIdx)
+	getInfo.More = (nextIdx != 0)
+	getInfo.Ospfv3RouteStateList = response
+	return getInfo, nil
+}
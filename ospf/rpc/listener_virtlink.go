@@ -0,0 +1,59 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package rpc
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"ospfdInt"
+)
+
+func (h *OSPFHandler) convertVirtLinkStateToThrift(ent config.VirtIfState) *ospfdInt.OspfVirtLinkState {
+	vlState := ospfdInt.NewOspfVirtLinkState()
+	vlState.TransitAreaId = string(ent.VirtIfAreaId)
+	vlState.NbrRouterId = string(ent.VirtIfNeighbor)
+	vlState.State = int32(ent.VirtIfState)
+	vlState.Events = int32(ent.VirtIfEvents)
+	vlState.Cost = int32(ent.VirtIfCost)
+	vlState.LocalIpAddress = string(ent.VirtIfLocalIpAddress)
+	vlState.NbrIpAddress = string(ent.VirtIfNbrIpAddress)
+	return vlState
+}
+
+func (h *OSPFHandler) GetBulkOspfVirtLinkState(fromIdx ospfdInt.Int, count ospfdInt.Int) (*ospfdInt.OspfVirtLinkStateGetInfo, error) {
+	h.logger.Info(fmt.Sprintln("Get virtual link attrs"))
+
+	nextIdx, currCount, ospfVirtIfStates := h.server.GetBulkOspfVirtIfState(int(fromIdx), int(count))
+	ospfVirtLinkStateResponse := make([]*ospfdInt.OspfVirtLinkState, len(ospfVirtIfStates))
+	for idx, item := range ospfVirtIfStates {
+		ospfVirtLinkStateResponse[idx] = h.convertVirtLinkStateToThrift(item)
+	}
+	ospfVirtLinkStateGetInfo := ospfdInt.NewOspfVirtLinkStateGetInfo()
+	ospfVirtLinkStateGetInfo.Count = ospfdInt.Int(currCount)
+	ospfVirtLinkStateGetInfo.StartIdx = ospfdInt.Int(fromIdx)
+	ospfVirtLinkStateGetInfo.EndIdx = ospfdInt.Int(nextIdx)
+	ospfVirtLinkStateGetInfo.More = (nextIdx != 0)
+	ospfVirtLinkStateGetInfo.OspfVirtLinkStateList = ospfVirtLinkStateResponse
+	return ospfVirtLinkStateGetInfo, nil
+}
@@ -0,0 +1,590 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______   __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __  
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  | 
+// |  |__   |  |     |  |__   \  V  /     |   (----  \   \/    \/   /  |  |  ---|  |---- |  ,---- |  |__|  | 
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   | 
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  | 
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__| 
+//                                                                                                           
+namespace go ospfdInt
+typedef i32 int
+
+struct OspfAuthKey {
+	1 : i32 KeyId
+	2 : string Algorithm
+	3 : string Key
+	4 : string SendStart
+	5 : string SendEnd
+	6 : string AcceptStart
+	7 : string AcceptEnd
+}
+
+struct OspfKeyChain {
+	1 : string Name
+	2 : list<OspfAuthKey> KeyList
+}
+
+struct OspfIfKeyChain {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : string KeyChain
+}
+
+struct OspfAreaKeyChain {
+	1 : string AreaId
+	2 : string KeyChain
+}
+
+struct OspfIfAuthState {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : string KeyChain
+	4 : i32 TxKeyId
+	5 : i64 TxCryptoSeqNum
+	6 : i32 AuthTypeMismatch
+	7 : i32 AuthKeyNotFound
+	8 : i32 AuthDigestFailure
+	9 : i32 AuthReplayDrops
+	10 : i32 AuthPasswordFailure
+	11 : i32 AuthTxNoKey
+}
+
+struct OspfIfAuthStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfIfAuthState> OspfIfAuthStateList
+}
+
+struct OspfVirtLinkState {
+	1 : string TransitAreaId
+	2 : string NbrRouterId
+	3 : i32 State
+	4 : i32 Events
+	5 : i32 Cost
+	6 : string LocalIpAddress
+	7 : string NbrIpAddress
+}
+
+struct OspfVirtLinkStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfVirtLinkState> OspfVirtLinkStateList
+}
+
+struct OspfAreaRange {
+	1 : string AreaId
+	2 : string RangeNet
+	3 : string RangeMask
+	4 : bool Advertise
+	5 : i32 Cost
+}
+
+struct OspfSummaryAddress {
+	1 : string SummaryNet
+	2 : string SummaryMask
+	3 : bool Advertise
+	4 : i64 RouteTag
+}
+
+struct OspfNbrEntry {
+	1 : string NbrIpAddress
+	2 : i32 NbrAddressLessIndex
+	3 : i32 NbrPriority
+}
+
+struct Ospfv3Global {
+	1 : i32 InstanceId
+	2 : string RouterId
+	3 : i32 AdminStat
+}
+
+struct Ospfv3IfEntry {
+	1 : i32 InstanceId
+	2 : i32 IfIndex
+	3 : string AreaId
+	4 : string IfType
+	5 : i32 AdminStat
+	6 : i32 RtrPriority
+	7 : i32 HelloInterval
+	8 : i32 RtrDeadInterval
+	9 : i32 Cost
+}
+
+struct Ospfv3IfState {
+	1 : i32 InstanceId
+	2 : i32 IfIndex
+	3 : string IfName
+	4 : string LinkLocalAddress
+	5 : string AreaId
+	6 : i32 State
+	7 : string DesignatedRouter
+	8 : string BackupDesignatedRouter
+	9 : i32 NbrCount
+	10 : i32 AdjacentNbrCount
+}
+
+struct Ospfv3IfStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<Ospfv3IfState> Ospfv3IfStateList
+}
+
+struct Ospfv3NbrState {
+	1 : i32 InstanceId
+	2 : i32 IfIndex
+	3 : string RouterId
+	4 : string Address
+	5 : i64 InterfaceId
+	6 : i32 Priority
+	7 : string State
+}
+
+struct Ospfv3NbrStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<Ospfv3NbrState> Ospfv3NbrStateList
+}
+
+struct Ospfv3RouteState {
+	1 : i32 InstanceId
+	2 : string DestinationNw
+	3 : string AreaId
+	4 : string PathType
+	5 : i64 Cost
+	6 : string NextHops
+}
+
+struct Ospfv3RouteStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<Ospfv3RouteState> Ospfv3RouteStateList
+}
+
+struct OspfSpfThrottle {
+	1 : i32 InitialDelay
+	2 : i32 ShortDelay
+	3 : i32 LongDelay
+	4 : i32 TimeToLearn
+	5 : i32 Holddown
+}
+
+struct OspfSpfThrottleState {
+	1 : string State
+	2 : i32 Events
+	3 : i32 DeferredEvents
+	4 : i32 Runs
+}
+
+struct OspfSpfThrottleStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfSpfThrottleState> OspfSpfThrottleStateList
+}
+
+struct OspfSpfRunLog {
+	1 : string StartTime
+	2 : string Trigger
+	3 : i32 Events
+	4 : bool FullSpf
+	5 : i32 Duration
+	6 : i32 RoutesChanged
+}
+
+struct OspfAreaSpfState {
+	1 : string AreaId
+	2 : i32 SpfRuns
+	3 : i32 SpfPartialRuns
+	4 : list<OspfSpfRunLog> SpfHistory
+}
+
+struct OspfAreaSpfStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfAreaSpfState> OspfAreaSpfStateList
+}
+
+struct OspfGracefulRestart {
+	1 : i32 HelperSupport
+	2 : bool StrictLsaChecking
+}
+
+struct OspfRestartPrepare {
+	1 : i32 Reason
+}
+
+struct OspfLfa {
+	1 : i32 Protection
+	2 : bool DownstreamOnly
+	3 : bool RemoteLfa
+}
+
+struct OspfIfLfa {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : bool Exclude
+}
+
+struct OspfAreaLfaState {
+	1 : string AreaId
+	2 : i32 Prefixes
+	3 : i32 Protected
+	4 : i32 NodeProtected
+	5 : i32 RemoteProtected
+	6 : i32 Unprotected
+	7 : i32 PQNodes
+}
+
+struct OspfAreaLfaStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfAreaLfaState> OspfAreaLfaStateList
+}
+
+struct OspfStubRouter {
+	1 : bool OnStartup
+	2 : i32 StartupTime
+	3 : bool WaitForBgp
+	4 : bool Administrative
+	5 : bool SummaryLsa
+	6 : bool ExternalLsa
+}
+
+struct OspfBgpConverged {
+	1 : string Source
+}
+
+struct OspfStubRouterState {
+	1 : string Status
+	2 : i32 RemainingTime
+}
+
+struct OspfStubRouterStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfStubRouterState> OspfStubRouterStateList
+}
+
+struct OspfIfBfd {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : bool Enable
+	4 : string SessionParam
+}
+
+struct OspfNbrBfdState {
+	1 : string NbrIpAddress
+	2 : i32 NbrAddressLessIndex
+	3 : string NbrRtrId
+	4 : string NbrState
+	5 : string BfdState
+}
+
+struct OspfNbrBfdStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfNbrBfdState> OspfNbrBfdStateList
+}
+
+struct OspfOpaque {
+	1 : bool OpaqueLsaSupport
+	2 : bool TeEnable
+	3 : string TeRouterAddress
+}
+
+struct OspfIfTe {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : i32 TeMetric
+	4 : i32 AdminGroup
+	5 : i32 MaxBandwidth
+	6 : i32 MaxRsvBandwidth
+}
+
+struct OspfOpaqueLsa {
+	1 : i32 LsaType
+	2 : i32 OpaqueType
+	3 : i32 OpaqueId
+	4 : string AreaId
+	5 : string IfIpAddress
+	6 : i32 AddressLessIf
+	7 : string Data
+}
+
+struct OspfTeLinkState {
+	1 : string AreaId
+	2 : string AdvRouter
+	3 : string RouterAddress
+	4 : i32 OpaqueId
+	5 : i32 LinkType
+	6 : string LinkId
+	7 : list<string> LocalIpAddress
+	8 : list<string> RemoteIpAddress
+	9 : i32 TeMetric
+	10 : double MaxBandwidth
+	11 : double MaxRsvBandwidth
+	12 : list<double> UnrsvBandwidth
+	13 : i32 AdminGroup
+}
+
+struct OspfTeLinkStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfTeLinkState> OspfTeLinkStateList
+}
+
+struct OspfPktStats {
+	1 : i32 TxHello
+	2 : i32 TxDbd
+	3 : i32 TxLsReq
+	4 : i32 TxLsUpd
+	5 : i32 TxLsAck
+	6 : i32 RxHello
+	7 : i32 RxDbd
+	8 : i32 RxLsReq
+	9 : i32 RxLsUpd
+	10 : i32 RxLsAck
+	11 : i32 RxBadLength
+	12 : i32 RxBadVersion
+	13 : i32 RxBadChecksum
+	14 : i32 RxAreaMismatch
+	15 : i32 RxAuthFailure
+	16 : i32 RxNetmaskMismatch
+	17 : i32 RxHelloIntervalMismatch
+	18 : i32 RxDeadIntervalMismatch
+	19 : i32 RxOptionsMismatch
+	20 : i32 RxMtuMismatch
+	21 : i32 RxUnknownNbr
+	22 : i32 RxBadPktType
+}
+
+struct OspfIfStatsState {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : OspfPktStats Stats
+	4 : string LastDropReason
+	5 : string LastDropSrc
+	6 : string LastDropTime
+}
+
+struct OspfIfStatsStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfIfStatsState> OspfIfStatsStateList
+}
+
+struct OspfNbrStatsState {
+	1 : string NbrIpAddress
+	2 : i32 NbrAddressLessIndex
+	3 : string NbrRtrId
+	4 : string NbrState
+	5 : i32 NbrStateChanges
+	6 : i32 NbrUptime
+	7 : i32 NbrLsRetransQLen
+	8 : OspfPktStats Stats
+}
+
+struct OspfNbrStatsStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfNbrStatsState> OspfNbrStatsStateList
+}
+
+struct OspfSrcDropStatsState {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : string SrcIpAddress
+	4 : OspfPktStats Stats
+	5 : string LastDropReason
+	6 : string LastDropTime
+}
+
+struct OspfSrcDropStatsStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfSrcDropStatsState> OspfSrcDropStatsStateList
+}
+
+struct OspfNbrEventState {
+	1 : i32 EventIdx
+	2 : string TimeStamp
+	3 : string NbrIpAddress
+	4 : i32 NbrAddressLessIndex
+	5 : string NbrRtrId
+	6 : string OldState
+	7 : string NewState
+	8 : string Reason
+	9 : i32 NbrLsRetransQLen
+}
+
+struct OspfNbrEventStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfNbrEventState> OspfNbrEventStateList
+}
+
+struct OspfRouteMap {
+	1 : string Name
+	2 : i32 Sequence
+	3 : i32 Action
+	4 : string MatchPrefix
+	5 : string MatchMask
+	6 : bool MatchExact
+	7 : i64 MatchTag
+	8 : string MatchProtocol
+	9 : i32 SetMetric
+	10 : i32 SetMetricType
+	11 : i64 SetTag
+}
+
+struct OspfPolicy {
+	1 : string RedistRouteMap
+	2 : string DistributeList
+}
+
+struct OspfIfPolicy {
+	1 : string IfIpAddress
+	2 : i32 AddressLessIf
+	3 : bool Passive
+	4 : bool SuppressPrefix
+}
+
+struct OspfRedistRouteState {
+	1 : string DestIp
+	2 : string Mask
+	3 : string Protocol
+	4 : bool Permitted
+	5 : i32 Metric
+	6 : i32 MetricType
+	7 : i64 Tag
+}
+
+struct OspfRedistRouteStateGetInfo {
+	1 : int StartIdx
+	2 : int EndIdx
+	3 : int Count
+	4 : bool More
+	5 : list<OspfRedistRouteState> OspfRedistRouteStateList
+}
+
+service OSPFDINTServices {
+	bool CreateOspfKeyChain(1: OspfKeyChain config);
+	bool UpdateOspfKeyChain(1: OspfKeyChain config);
+	bool DeleteOspfKeyChain(1: string name);
+	bool CreateOspfIfKeyChain(1: OspfIfKeyChain config);
+	bool DeleteOspfIfKeyChain(1: OspfIfKeyChain config);
+	bool CreateOspfAreaKeyChain(1: OspfAreaKeyChain config);
+	bool DeleteOspfAreaKeyChain(1: OspfAreaKeyChain config);
+	OspfIfAuthStateGetInfo GetBulkOspfIfAuthState(1: int fromIndex, 2: int count);
+	bool CreateOspfAreaRange(1: OspfAreaRange config);
+	bool UpdateOspfAreaRange(1: OspfAreaRange config);
+	bool DeleteOspfAreaRange(1: OspfAreaRange config);
+	bool CreateOspfSummaryAddress(1: OspfSummaryAddress config);
+	bool UpdateOspfSummaryAddress(1: OspfSummaryAddress config);
+	bool DeleteOspfSummaryAddress(1: OspfSummaryAddress config);
+	bool CreateOspfNbrEntry(1: OspfNbrEntry config);
+	bool UpdateOspfNbrEntry(1: OspfNbrEntry config);
+	bool DeleteOspfNbrEntry(1: OspfNbrEntry config);
+	OspfVirtLinkStateGetInfo GetBulkOspfVirtLinkState(1: int fromIndex, 2: int count);
+	bool CreateOspfv3Global(1: Ospfv3Global config);
+	bool UpdateOspfv3Global(1: Ospfv3Global config);
+	bool DeleteOspfv3Global(1: Ospfv3Global config);
+	bool CreateOspfv3IfEntry(1: Ospfv3IfEntry config);
+	bool UpdateOspfv3IfEntry(1: Ospfv3IfEntry config);
+	bool DeleteOspfv3IfEntry(1: Ospfv3IfEntry config);
+	Ospfv3IfStateGetInfo GetBulkOspfv3IfState(1: int fromIndex, 2: int count);
+	Ospfv3NbrStateGetInfo GetBulkOspfv3NbrState(1: int fromIndex, 2: int count);
+	Ospfv3RouteStateGetInfo GetBulkOspfv3RouteState(1: int fromIndex, 2: int count);
+	bool CreateOspfSpfThrottle(1: OspfSpfThrottle config);
+	bool UpdateOspfSpfThrottle(1: OspfSpfThrottle config);
+	bool DeleteOspfSpfThrottle(1: OspfSpfThrottle config);
+	OspfSpfThrottleStateGetInfo GetBulkOspfSpfThrottleState(1: int fromIndex, 2: int count);
+	OspfAreaSpfStateGetInfo GetBulkOspfAreaSpfState(1: int fromIndex, 2: int count);
+	bool CreateOspfGracefulRestart(1: OspfGracefulRestart config);
+	bool UpdateOspfGracefulRestart(1: OspfGracefulRestart config);
+	bool DeleteOspfGracefulRestart(1: OspfGracefulRestart config);
+	bool ExecuteOspfRestartPrepare(1: OspfRestartPrepare config);
+	bool CreateOspfLfa(1: OspfLfa config);
+	bool UpdateOspfLfa(1: OspfLfa config);
+	bool DeleteOspfLfa(1: OspfLfa config);
+	bool CreateOspfIfLfa(1: OspfIfLfa config);
+	bool UpdateOspfIfLfa(1: OspfIfLfa config);
+	bool DeleteOspfIfLfa(1: OspfIfLfa config);
+	OspfAreaLfaStateGetInfo GetBulkOspfAreaLfaState(1: int fromIndex, 2: int count);
+	bool CreateOspfStubRouter(1: OspfStubRouter config);
+	bool UpdateOspfStubRouter(1: OspfStubRouter config);
+	bool DeleteOspfStubRouter(1: OspfStubRouter config);
+	bool ExecuteOspfBgpConverged(1: OspfBgpConverged config);
+	OspfStubRouterStateGetInfo GetBulkOspfStubRouterState(1: int fromIndex, 2: int count);
+	bool CreateOspfIfBfd(1: OspfIfBfd config);
+	bool UpdateOspfIfBfd(1: OspfIfBfd config);
+	bool DeleteOspfIfBfd(1: OspfIfBfd config);
+	OspfNbrBfdStateGetInfo GetBulkOspfNbrBfdState(1: int fromIndex, 2: int count);
+	bool CreateOspfOpaque(1: OspfOpaque config);
+	bool UpdateOspfOpaque(1: OspfOpaque config);
+	bool DeleteOspfOpaque(1: OspfOpaque config);
+	bool CreateOspfIfTe(1: OspfIfTe config);
+	bool UpdateOspfIfTe(1: OspfIfTe config);
+	bool DeleteOspfIfTe(1: OspfIfTe config);
+	bool CreateOspfOpaqueLsa(1: OspfOpaqueLsa config);
+	bool UpdateOspfOpaqueLsa(1: OspfOpaqueLsa config);
+	bool DeleteOspfOpaqueLsa(1: OspfOpaqueLsa config);
+	OspfTeLinkStateGetInfo GetBulkOspfTeLinkState(1: int fromIndex, 2: int count);
+	OspfIfStatsStateGetInfo GetBulkOspfIfStatsState(1: int fromIndex, 2: int count);
+	OspfNbrStatsStateGetInfo GetBulkOspfNbrStatsState(1: int fromIndex, 2: int count);
+	OspfSrcDropStatsStateGetInfo GetBulkOspfSrcDropStatsState(1: int fromIndex, 2: int count);
+	OspfNbrEventStateGetInfo GetBulkOspfNbrEventState(1: int fromIndex, 2: int count);
+	bool CreateOspfRouteMap(1: OspfRouteMap config);
+	bool UpdateOspfRouteMap(1: OspfRouteMap config);
+	bool DeleteOspfRouteMap(1: OspfRouteMap config);
+	bool CreateOspfPolicy(1: OspfPolicy config);
+	bool UpdateOspfPolicy(1: OspfPolicy config);
+	bool DeleteOspfPolicy(1: OspfPolicy config);
+	bool CreateOspfIfPolicy(1: OspfIfPolicy config);
+	bool UpdateOspfIfPolicy(1: OspfIfPolicy config);
+	bool DeleteOspfIfPolicy(1: OspfIfPolicy config);
+	OspfRedistRouteStateGetInfo GetBulkOspfRedistRouteState(1: int fromIndex, 2: int count);
+}
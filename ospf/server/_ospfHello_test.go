@@ -94,7 +94,7 @@ func helloTestLogic(tNum int) int {
 
 	case 4:
 		fmt.Println(tNum, ": Running BuildHelloPkt")
-		pkt := ospf.BuildHelloPkt(intf)
+		pkt := ospf.BuildHelloPkt(key, intf)
 		fmt.Println(" Encoded packet : ", pkt)
 
 	case 5:
@@ -133,7 +133,7 @@ func checkHeaderAPIs() {
 	pkt := encodeOspfHdr(*ospfHdr)
 	fmt.Println("Encoded header pkt : ", pkt)
 
-	ospf.processOspfHeader(hello, key, &ospfHdrMd)
+	ospf.processOspfHeader(hello, key, &ospfHdrMd, &ipHdrMd)
 	ospf.processOspfData(hello, &ethHdrMd, &ipHdrMd, &ospfHdrMd, key)
 	ospf.processOspfData(lsaupd, &ethHdrMd, &ipHdrMd, &ospfHdrMd, key)
 	ospf.processOspfData(lsaack, &ethHdrMd, &ipHdrMd, &ospfHdrMd, key)
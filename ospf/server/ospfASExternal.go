@@ -155,6 +155,7 @@ func (server *OSPFServer) HandleASExternalLsa(areaId uint32) {
 				rEnt.PathType = pathType
 				rEnt.Cost = cost
 				rEnt.Type2Cost = uint16(lsaEnt.Metric)
+				rEnt.RouteTag = lsaEnt.ExtRouteTag
 				//rEnt.LSOrigin = lsaKey
 				rEnt.NumOfPaths = numOfNextHops
 				rEnt.NextHops = make(map[NextHop]bool)
@@ -183,6 +184,7 @@ func (server *OSPFServer) HandleASExternalLsa(areaId uint32) {
 			}
 			rEnt.Cost = cost
 			rEnt.Type2Cost = uint16(lsaEnt.Metric)
+			rEnt.RouteTag = lsaEnt.ExtRouteTag
 			//rEnt.LSOrigin = lsaKey
 			rEnt.NumOfPaths = numOfNextHops
 			rEnt.NextHops = make(map[NextHop]bool)
@@ -332,7 +334,7 @@ func (server *OSPFServer) GenerateType4SummaryLSA(rKey RoutingTblEntryKey, rEnt
 	summaryLsa.LsaMd.LSSequenceNum = seq_num
 	summaryLsa.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 8)
 	summaryLsa.Netmask = rKey.AddrMask
-	summaryLsa.Metric = uint32(rEnt.RoutingTblEnt.Cost)
+	summaryLsa.Metric = server.getStubRouterSummaryMetric(uint32(rEnt.RoutingTblEnt.Cost))
 
 	return lsaKey, summaryLsa
 }
@@ -0,0 +1,463 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"ribd"
+)
+
+/*
+   Route aggregation.
+   Area ranges make an ABR originate a single Type 3 summary LSA for
+   all the intra area routes of an area falling in the range.
+   Summary addresses make an ASBR originate a single AS external LSA
+   for all the redistributed routes falling in the prefix.
+   A discard route is installed in ribd for every advertised aggregate
+   so that traffic to unknown components is not looped back.
+*/
+
+type AggrPrefixKey struct {
+	Net  uint32
+	Mask uint32
+}
+
+type AreaRangeKey struct {
+	AreaId uint32
+	Net    uint32
+	Mask   uint32
+}
+
+type AreaRangeEnt struct {
+	Advertise  bool
+	Cost       uint32 // 0 advertises the highest component cost
+	Active     bool
+	ActiveCost uint32
+}
+
+type SummaryAddrEnt struct {
+	Advertise  bool
+	Tag        uint32
+	Originated bool
+	Metric     uint32 // metric and tag of the originated LSA
+	OrigTag    uint32
+}
+
+/*
+   Op is true for create/update and false for delete. Only one of
+   the config pointers is set.
+*/
+type AggregateConfMsg struct {
+	Op             bool
+	AreaRange      *config.AreaRangeConf
+	SummaryAddress *config.SummaryAddressConf
+}
+
+func (server *OSPFServer) initAggregateDB() {
+	server.AreaRangeMap = make(map[AreaRangeKey]AreaRangeEnt)
+	server.SummaryAddrMap = make(map[AggrPrefixKey]SummaryAddrEnt)
+	server.ExtRouteMap = make(map[AggrPrefixKey]RouteMdata)
+	server.AggrDiscardMap = make(map[AggrPrefixKey]bool)
+}
+
+func validateAggrPrefix(net config.IpAddress, mask config.IpAddress) (AggrPrefixKey, error) {
+	var key AggrPrefixKey
+	netIp := convertAreaOrRouterIdUint32(string(net))
+	maskIp := convertAreaOrRouterIdUint32(string(mask))
+	if maskIp == 0 || ^maskIp&(^maskIp+1) != 0 {
+		return key, errors.New(fmt.Sprintln("Invalid aggregate mask", mask))
+	}
+	if netIp&maskIp != netIp {
+		return key, errors.New(fmt.Sprintln("Aggregate address", net, "has host bits set for mask", mask))
+	}
+	key.Net = netIp
+	key.Mask = maskIp
+	return key, nil
+}
+
+func (server *OSPFServer) ValidateAreaRangeConf(conf config.AreaRangeConf) error {
+	_, err := validateAggrPrefix(conf.AreaRangeNet, conf.ArearangeMask)
+	if err != nil {
+		return err
+	}
+	if conf.AreaRangeEffect != config.AdvertiseMatching &&
+		conf.AreaRangeEffect != config.DoNotAdvertiseMatching {
+		return errors.New(fmt.Sprintln("Invalid area range effect", conf.AreaRangeEffect))
+	}
+	if conf.AreaRangeCost < 0 || uint32(conf.AreaRangeCost) >= LSInfinity {
+		return errors.New(fmt.Sprintln("Invalid area range cost", conf.AreaRangeCost))
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateSummaryAddressConf(conf config.SummaryAddressConf) error {
+	_, err := validateAggrPrefix(conf.SummaryAddressNet, conf.SummaryAddressMask)
+	if err != nil {
+		return err
+	}
+	if conf.SummaryAddressEffect != config.AdvertiseMatching &&
+		conf.SummaryAddressEffect != config.DoNotAdvertiseMatching {
+		return errors.New(fmt.Sprintln("Invalid summary address effect", conf.SummaryAddressEffect))
+	}
+	return nil
+}
+
+func (server *OSPFServer) processAggregateConfig(msg AggregateConfMsg) error {
+	switch {
+	case msg.AreaRange != nil:
+		prefix, err := validateAggrPrefix(msg.AreaRange.AreaRangeNet, msg.AreaRange.ArearangeMask)
+		if err != nil {
+			return err
+		}
+		key := AreaRangeKey{
+			AreaId: convertAreaOrRouterIdUint32(string(msg.AreaRange.RangeAreaId)),
+			Net:    prefix.Net,
+			Mask:   prefix.Mask,
+		}
+		if msg.Op {
+			server.AreaRangeMap[key] = AreaRangeEnt{
+				Advertise: msg.AreaRange.AreaRangeEffect == config.AdvertiseMatching,
+				Cost:      uint32(msg.AreaRange.AreaRangeCost),
+			}
+		} else {
+			delete(server.AreaRangeMap, key)
+		}
+		if server.ospfGlobalConf.AreaBdrRtrStatus {
+			server.GenerateSummaryLsa()
+			server.installSummaryLsa()
+		}
+	case msg.SummaryAddress != nil:
+		key, err := validateAggrPrefix(msg.SummaryAddress.SummaryAddressNet, msg.SummaryAddress.SummaryAddressMask)
+		if err != nil {
+			return err
+		}
+		oldCover := server.getExtRouteCover()
+		ent, exist := server.SummaryAddrMap[key]
+		if msg.Op {
+			ent.Advertise = msg.SummaryAddress.SummaryAddressEffect == config.AdvertiseMatching
+			ent.Tag = msg.SummaryAddress.SummaryAddressTag
+			server.SummaryAddrMap[key] = ent
+		} else if exist {
+			if ent.Originated {
+				server.originateExtRoute(RouteMdata{
+					metric: ent.Metric,
+					ipaddr: key.Net,
+					mask:   key.Mask,
+					tag:    ent.OrigTag,
+					isDel:  true,
+				})
+			}
+			delete(server.SummaryAddrMap, key)
+		}
+		newCover := server.getExtRouteCover()
+		server.processExtRouteCoverChange(oldCover, newCover)
+		if msg.Op {
+			server.updateSummaryAddr(key)
+		}
+		server.syncAggrDiscardRoutes()
+	default:
+		return errors.New("Empty aggregate configuration")
+	}
+	return nil
+}
+
+/*@fn getAreaRange
+Returns the longest area range of the area containing
+the destination.
+*/
+func (server *OSPFServer) getAreaRange(areaId uint32, destId uint32, mask uint32) (AreaRangeKey, bool) {
+	var best AreaRangeKey
+	found := false
+	for key, _ := range server.AreaRangeMap {
+		if key.AreaId != areaId ||
+			mask < key.Mask ||
+			destId&key.Mask != key.Net {
+			continue
+		}
+		if !found || key.Mask > best.Mask {
+			best = key
+			found = true
+		}
+	}
+	return best, found
+}
+
+/*@fn computeAreaRanges
+RFC 2328 12.4.3: a range is active when at least one intra area
+network of its area falls in it. The summary cost is the highest
+cost of the component networks unless configured.
+*/
+func (server *OSPFServer) computeAreaRanges() {
+	for key, ent := range server.AreaRangeMap {
+		ent.Active = false
+		ent.ActiveCost = 0
+		server.AreaRangeMap[key] = ent
+	}
+	for rKey, rEnt := range server.GlobalRoutingTbl {
+		if rKey.DestType != Network ||
+			rEnt.RoutingTblEnt.PathType != IntraArea {
+			continue
+		}
+		key, found := server.getAreaRange(rEnt.AreaId, rKey.DestId, rKey.AddrMask)
+		if !found {
+			continue
+		}
+		ent := server.AreaRangeMap[key]
+		ent.Active = true
+		if uint32(rEnt.RoutingTblEnt.Cost) > ent.ActiveCost {
+			ent.ActiveCost = uint32(rEnt.RoutingTblEnt.Cost)
+		}
+		server.AreaRangeMap[key] = ent
+	}
+	for key, ent := range server.AreaRangeMap {
+		if ent.Active && ent.Cost != 0 {
+			ent.ActiveCost = ent.Cost
+			server.AreaRangeMap[key] = ent
+		}
+	}
+}
+
+/*@fn generateAreaRangeSummaryLsa
+Adds the Type 3 summary LSAs of the active area ranges of
+the other areas to the summary LSAs of the area.
+*/
+func (server *OSPFServer) generateAreaRangeSummaryLsa(lsDbKey LsdbKey, sEnt SummaryLsaMap) {
+	for key, ent := range server.AreaRangeMap {
+		if key.AreaId == lsDbKey.AreaId ||
+			!ent.Active || !ent.Advertise {
+			continue
+		}
+		rKey := RoutingTblEntryKey{
+			DestId:   key.Net,
+			AddrMask: key.Mask,
+			DestType: Network,
+		}
+		var rEnt GlobalRoutingTblEntry
+		rEnt.AreaId = key.AreaId
+		rEnt.RoutingTblEnt.PathType = IntraArea
+		rEnt.RoutingTblEnt.Cost = uint16(ent.ActiveCost)
+		lsaKey, summaryLsa := server.GenerateType3SummaryLSA(rKey, rEnt, lsDbKey)
+		sEnt[lsaKey] = summaryLsa
+	}
+}
+
+/*@fn getSummaryAddr
+Returns the longest summary address containing the route.
+*/
+func (server *OSPFServer) getSummaryAddr(route AggrPrefixKey) (AggrPrefixKey, bool) {
+	var best AggrPrefixKey
+	found := false
+	for key, _ := range server.SummaryAddrMap {
+		if route.Mask < key.Mask ||
+			route.Net&key.Mask != key.Net {
+			continue
+		}
+		if !found || key.Mask > best.Mask {
+			best = key
+			found = true
+		}
+	}
+	return best, found
+}
+
+func (server *OSPFServer) getExtRouteCover() map[AggrPrefixKey]AggrPrefixKey {
+	cover := make(map[AggrPrefixKey]AggrPrefixKey)
+	for rKey, _ := range server.ExtRouteMap {
+		if sKey, found := server.getSummaryAddr(rKey); found {
+			cover[rKey] = sKey
+		}
+	}
+	return cover
+}
+
+/*@fn processExtRouteCoverChange
+Re-originates the redistributed routes which are no longer covered
+by a summary address and withdraws the ones which got covered.
+Summary addresses with changed components are updated.
+*/
+func (server *OSPFServer) processExtRouteCoverChange(oldCover map[AggrPrefixKey]AggrPrefixKey,
+	newCover map[AggrPrefixKey]AggrPrefixKey) {
+	changed := make(map[AggrPrefixKey]bool)
+	for rKey, route := range server.ExtRouteMap {
+		oldKey, wasCovered := oldCover[rKey]
+		newKey, isCovered := newCover[rKey]
+		if wasCovered == isCovered && oldKey == newKey {
+			continue
+		}
+		if wasCovered {
+			changed[oldKey] = true
+		}
+		if isCovered {
+			changed[newKey] = true
+		}
+		if !wasCovered {
+			if rKey != newKey {
+				route.isDel = true
+				server.originateExtRoute(route)
+			}
+		} else if !isCovered {
+			server.originateExtRoute(route)
+		}
+	}
+	for sKey, _ := range changed {
+		server.updateSummaryAddr(sKey)
+	}
+}
+
+/*@fn updateSummaryAddr
+Originates, refreshes or withdraws the AS external LSA of a
+summary address. The metric is the highest metric of the
+components.
+*/
+func (server *OSPFServer) updateSummaryAddr(sKey AggrPrefixKey) {
+	ent, exist := server.SummaryAddrMap[sKey]
+	if !exist {
+		return
+	}
+	numOfRoutes := 0
+	metric := uint32(0)
+	for rKey, route := range server.ExtRouteMap {
+		if cKey, found := server.getSummaryAddr(rKey); !found || cKey != sKey {
+			continue
+		}
+		numOfRoutes++
+		if route.metric > metric {
+			metric = route.metric
+		}
+	}
+	aggr := RouteMdata{
+		metric: metric,
+		ipaddr: sKey.Net,
+		mask:   sKey.Mask,
+		tag:    ent.Tag,
+	}
+	if ent.Advertise && numOfRoutes > 0 {
+		if ent.Originated && ent.Metric == metric && ent.OrigTag == ent.Tag {
+			return
+		}
+		server.logger.Info(fmt.Sprintln("ASBR: Originate summary address ", sKey, " metric ", metric))
+		server.originateExtRoute(aggr)
+		ent.Originated = true
+		ent.Metric = metric
+		ent.OrigTag = ent.Tag
+	} else if ent.Originated {
+		server.logger.Info(fmt.Sprintln("ASBR: Withdraw summary address ", sKey))
+		aggr.metric = ent.Metric
+		aggr.tag = ent.OrigTag
+		aggr.isDel = true
+		server.originateExtRoute(aggr)
+		ent.Originated = false
+	}
+	server.SummaryAddrMap[sKey] = ent
+}
+
+/*@fn aggregateExtRoute
+Records the redistributed route. Returns true when the route
+is covered by a summary address, the summary is updated instead
+of originating the route.
+*/
+func (server *OSPFServer) aggregateExtRoute(route RouteMdata) bool {
+	rKey := AggrPrefixKey{
+		Net:  route.ipaddr & route.mask,
+		Mask: route.mask,
+	}
+	if route.isDel {
+		delete(server.ExtRouteMap, rKey)
+	} else {
+		server.ExtRouteMap[rKey] = route
+	}
+	sKey, found := server.getSummaryAddr(rKey)
+	if !found {
+		return false
+	}
+	server.updateSummaryAddr(sKey)
+	server.syncAggrDiscardRoutes()
+	return true
+}
+
+/*@fn syncAggrDiscardRoutes
+Installs discard routes for the advertised aggregates and
+removes the ones which are no longer needed. Area ranges
+are only summarized by an ABR, their discard routes are
+withdrawn when the router stops being one.
+*/
+func (server *OSPFServer) syncAggrDiscardRoutes() {
+	wanted := make(map[AggrPrefixKey]bool)
+	if server.ospfGlobalConf.AreaBdrRtrStatus {
+		for key, ent := range server.AreaRangeMap {
+			if ent.Active && ent.Advertise {
+				wanted[AggrPrefixKey{Net: key.Net, Mask: key.Mask}] = true
+			}
+		}
+	}
+	for key, ent := range server.SummaryAddrMap {
+		if ent.Originated {
+			wanted[key] = true
+		}
+	}
+	for key, _ := range wanted {
+		if !server.AggrDiscardMap[key] {
+			server.installAggrDiscardRoute(key, true)
+			server.AggrDiscardMap[key] = true
+		}
+	}
+	for key, _ := range server.AggrDiscardMap {
+		if !wanted[key] {
+			server.installAggrDiscardRoute(key, false)
+			delete(server.AggrDiscardMap, key)
+		}
+	}
+}
+
+func (server *OSPFServer) installAggrDiscardRoute(key AggrPrefixKey, add bool) {
+	cfg := ribd.IPv4Route{
+		DestinationNw: convertUint32ToIPv4(key.Net),
+		NetworkMask:   convertUint32ToIPv4(key.Mask),
+		Protocol:      "OSPF",
+		Cost:          0,
+		NullRoute:     true,
+	}
+	nextHopInfo := ribd.NextHopInfo{
+		NextHopIp: "255.255.255.255",
+	}
+	cfg.NextHop = make([]*ribd.NextHopInfo, 0)
+	cfg.NextHop = append(cfg.NextHop, &nextHopInfo)
+	if server.ribdClient.ClientHdl == nil {
+		server.logger.Err("Nil ribd handle. Can not program discard route. ")
+		return
+	}
+	var err error
+	if add {
+		server.logger.Info(fmt.Sprintln("Installing discard route for aggregate ", cfg.DestinationNw, cfg.NetworkMask))
+		_, err = server.ribdClient.ClientHdl.CreateIPv4Route(&cfg)
+	} else {
+		server.logger.Info(fmt.Sprintln("Deleting discard route for aggregate ", cfg.DestinationNw, cfg.NetworkMask))
+		_, err = server.ribdClient.ClientHdl.DeleteIPv4Route(&cfg)
+	}
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Error programming discard route:", err))
+	}
+}
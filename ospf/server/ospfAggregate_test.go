@@ -0,0 +1,226 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfAggregate_test
+   This test covers
+   1) Aggregate prefix validation.
+   2) Area range summary LSA origination on ABR.
+   3) External summary address aggregation on ASBR.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"testing"
+)
+
+func initAggregateTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+
+	for _, areaId := range []config.AreaId{"0.0.0.0", "0.0.0.1"} {
+		intfList := make(map[IntfConfKey]bool)
+		intfList[key] = true
+		ospf.AreaConfMap[AreaConfKey{AreaId: areaId}] = AreaConf{
+			ImportAsExtern: config.ImportExternal,
+			AreaSummary:    config.SendAreaSummary,
+			IntfListMap:    intfList,
+		}
+		ospf.initLSDatabase(convertAreaOrRouterIdUint32(string(areaId)))
+	}
+	ospf.ospfGlobalConf.AreaBdrRtrStatus = true
+}
+
+func TestOspfAggregate(t *testing.T) {
+	fmt.Println("\n**************** AGGREGATE ************\n")
+	initAggregateTestParams()
+	for index := 1; index < 5; index++ {
+		err := aggregateTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for aggregation ", index)
+		}
+	}
+}
+
+func aggregateTestLogic(tNum int) int {
+	bbArea := convertAreaOrRouterIdUint32("0.0.0.0")
+	area1 := convertAreaOrRouterIdUint32("0.0.0.1")
+	rtrId := convertIPv4ToUint32(ospf.ospfGlobalConf.RouterId)
+	rangeKey := LsaKey{
+		LSType:    Summary3LSA,
+		LSId:      convertAreaOrRouterIdUint32("10.1.0.0"),
+		AdvRouter: rtrId,
+	}
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running validateAggrPrefix")
+		if _, err := validateAggrPrefix("10.1.0.0", "255.255.0.0"); err != nil {
+			fmt.Println("Valid prefix rejected ", err)
+			return FAIL
+		}
+		if _, err := validateAggrPrefix("10.1.1.0", "255.255.0.0"); err == nil {
+			fmt.Println("Prefix with host bits accepted")
+			return FAIL
+		}
+		if _, err := validateAggrPrefix("10.1.0.0", "255.0.255.0"); err == nil {
+			fmt.Println("Non contiguous mask accepted")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running area range summarization")
+		ospf.GlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
+		for i, dest := range []string{"10.1.1.1", "10.1.2.1"} {
+			rKey := RoutingTblEntryKey{
+				DestId:   convertAreaOrRouterIdUint32(dest),
+				AddrMask: convertAreaOrRouterIdUint32("255.255.255.255"),
+				DestType: Network,
+			}
+			ospf.GlobalRoutingTbl[rKey] = GlobalRoutingTblEntry{
+				AreaId: area1,
+				RoutingTblEnt: RoutingTblEntry{
+					PathType: IntraArea,
+					Cost:     uint16(10 * (i + 1)),
+				},
+			}
+		}
+		rangeConf := config.AreaRangeConf{
+			RangeAreaId:     "0.0.0.1",
+			AreaRangeNet:    "10.1.0.0",
+			ArearangeMask:   "255.255.0.0",
+			AreaRangeEffect: config.AdvertiseMatching,
+		}
+		ospf.processAggregateConfig(AggregateConfMsg{Op: true, AreaRange: &rangeConf})
+		ospf.GenerateSummaryLsa()
+		sEnt := ospf.SummaryLsDb[LsdbKey{AreaId: bbArea}]
+		summaryLsa, exist := sEnt[rangeKey]
+		if !exist || summaryLsa.Metric != 20 || len(sEnt) != 1 {
+			fmt.Println("Area range summary not generated ", sEnt)
+			return FAIL
+		}
+		if len(ospf.SummaryLsDb[LsdbKey{AreaId: area1}]) != 0 {
+			fmt.Println("Area range summary leaked into its own area")
+			return FAIL
+		}
+		if !ospf.AggrDiscardMap[AggrPrefixKey{Net: rangeKey.LSId, Mask: summaryLsa.Netmask}] {
+			fmt.Println("Discard route not installed for area range")
+			return FAIL
+		}
+		ospf.ospfGlobalConf.AreaBdrRtrStatus = false
+		ospf.syncAggrDiscardRoutes()
+		if len(ospf.AggrDiscardMap) != 0 {
+			fmt.Println("Area range discard route kept by a non ABR ", ospf.AggrDiscardMap)
+			return FAIL
+		}
+		ospf.ospfGlobalConf.AreaBdrRtrStatus = true
+		ospf.syncAggrDiscardRoutes()
+		if !ospf.AggrDiscardMap[AggrPrefixKey{Net: rangeKey.LSId, Mask: summaryLsa.Netmask}] {
+			fmt.Println("Discard route not reinstalled for area range")
+			return FAIL
+		}
+
+		rangeConf.AreaRangeEffect = config.DoNotAdvertiseMatching
+		ospf.processAggregateConfig(AggregateConfMsg{Op: true, AreaRange: &rangeConf})
+		ospf.GenerateSummaryLsa()
+		if len(ospf.SummaryLsDb[LsdbKey{AreaId: bbArea}]) != 0 || len(ospf.AggrDiscardMap) != 0 {
+			fmt.Println("Not advertised area range leaked ", ospf.SummaryLsDb)
+			return FAIL
+		}
+		ospf.processAggregateConfig(AggregateConfMsg{Op: false, AreaRange: &rangeConf})
+		ospf.GenerateSummaryLsa()
+		if len(ospf.SummaryLsDb[LsdbKey{AreaId: bbArea}]) != 2 {
+			fmt.Println("Component routes not advertised after range delete ", ospf.SummaryLsDb)
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running summary address aggregation")
+		mask := convertAreaOrRouterIdUint32("255.255.255.0")
+		for i, dest := range []string{"20.1.1.0", "20.1.2.0"} {
+			ospf.processExtRouteUpd(RouteMdata{
+				metric: uint32(5 * (i + 1)),
+				ipaddr: convertAreaOrRouterIdUint32(dest),
+				mask:   mask,
+			})
+		}
+		compKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      convertAreaOrRouterIdUint32("20.1.1.0"),
+			AdvRouter: rtrId,
+		}
+		if _, ret := ospf.getASExternalLsaFromLsdb(bbArea, compKey); ret != LsdbEntryFound {
+			fmt.Println("Redistributed route not originated")
+			return FAIL
+		}
+		sumConf := config.SummaryAddressConf{
+			SummaryAddressNet:    "20.1.0.0",
+			SummaryAddressMask:   "255.255.0.0",
+			SummaryAddressEffect: config.AdvertiseMatching,
+			SummaryAddressTag:    7,
+		}
+		ospf.processAggregateConfig(AggregateConfMsg{Op: true, SummaryAddress: &sumConf})
+		sumKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      convertAreaOrRouterIdUint32("20.1.0.0"),
+			AdvRouter: rtrId,
+		}
+		lsa, ret := ospf.getASExternalLsaFromLsdb(bbArea, sumKey)
+		if ret != LsdbEntryFound || lsa.Metric != 10 || lsa.ExtRouteTag != 7 {
+			fmt.Println("Summary address not originated ", lsa)
+			return FAIL
+		}
+		if ospf.AreaSelfOrigLsa[LsdbKey{AreaId: bbArea}][compKey] {
+			fmt.Println("Component route still originated")
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running summary address delete")
+		sumConf := config.SummaryAddressConf{
+			SummaryAddressNet:  "20.1.0.0",
+			SummaryAddressMask: "255.255.0.0",
+		}
+		ospf.processAggregateConfig(AggregateConfMsg{Op: false, SummaryAddress: &sumConf})
+		sumKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      convertAreaOrRouterIdUint32("20.1.0.0"),
+			AdvRouter: rtrId,
+		}
+		compKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      convertAreaOrRouterIdUint32("20.1.2.0"),
+			AdvRouter: rtrId,
+		}
+		if ospf.AreaSelfOrigLsa[LsdbKey{AreaId: bbArea}][sumKey] ||
+			!ospf.AreaSelfOrigLsa[LsdbKey{AreaId: bbArea}][compKey] ||
+			len(ospf.AggrDiscardMap) != 0 {
+			fmt.Println("Summary address not withdrawn")
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
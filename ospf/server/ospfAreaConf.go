@@ -52,6 +52,13 @@ type AreaState struct {
 	AreaLsaCksumSum          int32
 	AreaNssaTranslatorState  config.NssaTranslatorState
 	AreaNssaTranslatorEvents int32
+	SpfPartialRuns           int32
+	SpfHistory               []config.SpfRunLog
+	LfaPrefixes              int32
+	LfaProtected             int32
+	LfaNodeProtected         int32
+	LfaRemoteProtected       int32
+	LfaPQNodes               int32
 }
 
 func (server *OSPFServer) processAreaConfig(areaConf config.AreaConf) error {
@@ -104,6 +111,13 @@ func (server *OSPFServer) initAreaStateSlice(key AreaConfKey) {
 	ent.AreaLsaCksumSum = 0
 	ent.AreaNssaTranslatorState = config.NssaTranslatorDisabled
 	ent.AreaNssaTranslatorEvents = 0
+	ent.SpfPartialRuns = 0
+	ent.SpfHistory = nil
+	ent.LfaPrefixes = 0
+	ent.LfaProtected = 0
+	ent.LfaNodeProtected = 0
+	ent.LfaRemoteProtected = 0
+	ent.LfaPQNodes = 0
 	server.AreaStateMap[key] = ent
 	if !exist {
 		server.AreaStateSlice = append(server.AreaStateSlice, key)
@@ -191,6 +205,7 @@ func (server *OSPFServer) updateIntfToAreaMap(key IntfConfKey, oldAreaId string,
 }
 
 func (server *OSPFServer) updateIfABR() {
+	wasABR := server.ospfGlobalConf.AreaBdrRtrStatus
 	index := 0
 	for _, areaEnt := range server.AreaConfMap {
 		if len(areaEnt.IntfListMap) > 0 {
@@ -204,6 +219,9 @@ func (server *OSPFServer) updateIfABR() {
 		server.ospfGlobalConf.isABR = false
 		server.ospfGlobalConf.AreaBdrRtrStatus = false
 	}
+	if wasABR != server.ospfGlobalConf.AreaBdrRtrStatus {
+		server.syncAggrDiscardRoutes()
+	}
 }
 
 func (server *OSPFServer) isStubArea(areaid config.AreaId) bool {
@@ -221,3 +239,57 @@ func (server *OSPFServer) isStubArea(areaid config.AreaId) bool {
 	}
 	return false
 }
+
+func (server *OSPFServer) isNssaArea(areaid config.AreaId) bool {
+	areaConfKey := AreaConfKey{
+		AreaId: areaid,
+	}
+
+	conf, exist := server.AreaConfMap[areaConfKey]
+	if !exist {
+		return false
+	}
+	if conf.ImportAsExtern == config.ImportNssa {
+		return true
+	}
+	return false
+}
+
+/*
+   A no-summary NSSA only gets the default summary LSA from its
+   border routers, just like a totally stubby area.
+*/
+func (server *OSPFServer) isNssaNoSummaryArea(areaid config.AreaId) bool {
+	if !server.isNssaArea(areaid) {
+		return false
+	}
+	conf, _ := server.AreaConfMap[AreaConfKey{AreaId: areaid}]
+	return conf.AreaSummary == config.NoAreaSummary
+}
+
+/*
+   Options advertised in hellos and database description packets.
+   RFC 3101 2.1: the N-bit is set and the E-bit clear on interfaces
+   attached to an NSSA, stub areas clear both.
+*/
+func (server *OSPFServer) getAreaOptions(areaid config.AreaId) uint8 {
+	if server.isStubArea(areaid) {
+		return 0
+	}
+	if server.isNssaArea(areaid) {
+		return NPOption
+	}
+	return EOption
+}
+
+/*
+   RFC 5250 A.1: routers supporting opaque LSAs set the O-bit
+   in their hellos and database description packets.
+*/
+func (server *OSPFServer) getPktOptions(areaid config.AreaId) uint8 {
+	option := server.getAreaOptions(areaid)
+	if server.ospfGlobalConf.OpaqueLsaSupport {
+		option |= OOption
+	}
+	return option
+}
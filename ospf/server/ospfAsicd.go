@@ -114,6 +114,15 @@ func (server *OSPFServer) processAsicdNotification(asicdrxBuf []byte) {
 			return
 		}
 		server.UpdateIPv4Infra(NewIpv4IntfMsg, msg.MsgType)
+	} else if msg.MsgType == asicdCommonDefs.NOTIFY_IPV6INTF_CREATE ||
+		msg.MsgType == asicdCommonDefs.NOTIFY_IPV6INTF_DELETE {
+		var newIpv6IntfMsg asicdCommonDefs.IPv6IntfNotifyMsg
+		err = json.Unmarshal(msg.Msg, &newIpv6IntfMsg)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("Unable to unmarshal msg:", msg.Msg))
+			return
+		}
+		server.UpdateIPv6Infra(newIpv6IntfMsg, msg.MsgType)
 	} else if msg.MsgType == asicdCommonDefs.NOTIFY_VLAN_CREATE ||
 		msg.MsgType == asicdCommonDefs.NOTIFY_VLAN_DELETE {
 		var vlanNotifyMsg asicdCommonDefs.VlanNotifyMsg
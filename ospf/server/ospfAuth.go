@@ -0,0 +1,613 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"l3/ospf/config"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	OSPF_AUTH_SIMPLE_LEN  = 8
+	OSPF_AUTH_MD5_KEY_LEN = 16
+	OSPF_AUTH_APAD        = 0x878FE1F3
+
+	OSPF_AUTH_SEQ_NUM_FILE  = "ospfAuthSeqNum.json"
+	OSPF_AUTH_SEQ_NUM_BLOCK = 1 << 16
+)
+
+type AuthKeyEnt struct {
+	KeyId       uint8
+	Algorithm   config.AuthAlgorithm
+	Key         []byte
+	SendStart   time.Time
+	SendEnd     time.Time
+	AcceptStart time.Time
+	AcceptEnd   time.Time
+}
+
+type IntfAuthEnt struct {
+	KeyChain        string
+	TxKeyId         int32
+	TxSeqNum        uint32
+	TypeMismatch    int32
+	KeyNotFound     int32
+	DigestFailure   int32
+	ReplayDrops     int32
+	PasswordFailure int32
+	TxNoKey         int32
+}
+
+/*
+   Key chains and their bindings are written by the main server
+   loop while packets are authenticated from the per interface tx and
+   per packet rx go routines, hence the lock.
+*/
+type OspfAuthDB struct {
+	sync.RWMutex
+	KeyChainMap     map[string][]AuthKeyEnt
+	AreaKeyChainMap map[config.AreaId]string
+	IntfAuthMap     map[IntfConfKey]IntfAuthEnt
+	NbrRxSeqNumMap  map[NeighborConfKey]uint32
+	TxSeqNumFile    string
+	TxSeqNumStart   uint32 // above every seq num sent before the restart
+	TxSeqNumLimit   uint32 // seq nums up to this one are reserved in TxSeqNumFile
+}
+
+type authSeqNumState struct {
+	TxSeqNumLimit uint32
+}
+
+/*
+   Op is true for create/update and false for delete. Only one of
+   the config pointers is set.
+*/
+type AuthConfMsg struct {
+	Op           bool
+	KeyChain     *config.KeyChainConf
+	IfKeyChain   *config.IfKeyChainConf
+	AreaKeyChain *config.AreaKeyChainConf
+}
+
+func (server *OSPFServer) initAuthDB() {
+	server.AuthDB.KeyChainMap = make(map[string][]AuthKeyEnt)
+	server.AuthDB.AreaKeyChainMap = make(map[config.AreaId]string)
+	server.AuthDB.IntfAuthMap = make(map[IntfConfKey]IntfAuthEnt)
+	server.AuthDB.NbrRxSeqNumMap = make(map[NeighborConfKey]uint32)
+}
+
+func authDigestLen(alg config.AuthAlgorithm) int {
+	switch alg {
+	case config.KeyedMd5:
+		return md5.Size
+	case config.HmacSha1:
+		return sha1.Size
+	case config.HmacSha256:
+		return sha256.Size
+	case config.HmacSha384:
+		return sha512.Size384
+	case config.HmacSha512:
+		return sha512.Size
+	}
+	return 0
+}
+
+func authHashFunc(alg config.AuthAlgorithm) func() hash.Hash {
+	switch alg {
+	case config.HmacSha1:
+		return sha1.New
+	case config.HmacSha256:
+		return sha256.New
+	case config.HmacSha384:
+		return sha512.New384
+	case config.HmacSha512:
+		return sha512.New
+	}
+	return nil
+}
+
+/*
+   Compute the digest of an ospf packet of pktlen bytes whose
+   authentication header is already filled in.
+   Keyed MD5 is RFC 2328 D.4.3: MD5(packet || key padded to 16 bytes).
+   HMAC-SHA is RFC 5709 3.3: the key is zero padded to the hash length
+   (or hashed if longer) and the HMAC runs over packet || Apad, where
+   Apad is 0x878FE1F3 repeated to the hash length.
+*/
+func computeAuthDigest(alg config.AuthAlgorithm, key []byte, pkt []byte) []byte {
+	if alg == config.KeyedMd5 {
+		md5Key := make([]byte, OSPF_AUTH_MD5_KEY_LEN)
+		copy(md5Key, key)
+		h := md5.New()
+		h.Write(pkt)
+		h.Write(md5Key)
+		return h.Sum(nil)
+	}
+	hashFunc := authHashFunc(alg)
+	if hashFunc == nil {
+		return nil
+	}
+	length := authDigestLen(alg)
+	ks := make([]byte, length)
+	if len(key) > length {
+		h := hashFunc()
+		h.Write(key)
+		ks = h.Sum(nil)
+	} else {
+		copy(ks, key)
+	}
+	apad := make([]byte, length)
+	for i := 0; i < length; i += 4 {
+		binary.BigEndian.PutUint32(apad[i:i+4], OSPF_AUTH_APAD)
+	}
+	mac := hmac.New(hashFunc, ks)
+	mac.Write(pkt)
+	mac.Write(apad)
+	return mac.Sum(nil)
+}
+
+func isKeyLifetimeValid(start time.Time, end time.Time, now time.Time) bool {
+	if !start.IsZero() && now.Before(start) {
+		return false
+	}
+	if !end.IsZero() && !now.Before(end) {
+		return false
+	}
+	return true
+}
+
+/*
+   Pick the key to send with. Among the keys whose send lifetime is
+   valid the most recently started one wins, ties broken by the highest
+   key id, so a new key takes over as soon as its send lifetime starts
+   while neighbors still accept the old one during the overlap of the
+   accept lifetimes.
+   If every key has expired, RFC 2328 D.3 says to keep using the last
+   one rather than falling back to no authentication; expired is set.
+*/
+func selectSendKey(keys []AuthKeyEnt, now time.Time) (AuthKeyEnt, bool, bool) {
+	var sendKey AuthKeyEnt
+	found := false
+	for _, key := range keys {
+		if !isKeyLifetimeValid(key.SendStart, key.SendEnd, now) {
+			continue
+		}
+		if !found || key.SendStart.After(sendKey.SendStart) ||
+			(key.SendStart.Equal(sendKey.SendStart) && key.KeyId > sendKey.KeyId) {
+			sendKey = key
+			found = true
+		}
+	}
+	if found {
+		return sendKey, false, true
+	}
+	for _, key := range keys {
+		if key.SendEnd.IsZero() || !now.After(key.SendStart) {
+			continue
+		}
+		if !found || key.SendEnd.After(sendKey.SendEnd) {
+			sendKey = key
+			found = true
+		}
+	}
+	return sendKey, found, found
+}
+
+func findAcceptKey(keys []AuthKeyEnt, keyId uint8, now time.Time) (AuthKeyEnt, bool) {
+	for _, key := range keys {
+		if key.KeyId == keyId && isKeyLifetimeValid(key.AcceptStart, key.AcceptEnd, now) {
+			return key, true
+		}
+	}
+	return AuthKeyEnt{}, false
+}
+
+/*
+   Simple password keys are either 8 dotted octets or a plain
+   string of at most 8 characters which is zero padded.
+*/
+func convertSimpleAuthKey(s string) []byte {
+	authKey := convertAuthKey(s)
+	if authKey != nil {
+		return authKey
+	}
+	if len(s) > OSPF_AUTH_SIMPLE_LEN {
+		return nil
+	}
+	authKey = make([]byte, OSPF_AUTH_SIMPLE_LEN)
+	copy(authKey, s)
+	return authKey
+}
+
+/*
+   The interface auth type wins; an interface left at NoAuth inherits
+   the auth type of its area.
+*/
+func (server *OSPFServer) getIntfAuthType(ent IntfConf) uint16 {
+	if ent.IfAuthType != uint16(config.NoAuth) || ent.IfAreaId == nil {
+		return ent.IfAuthType
+	}
+	areaId := config.AreaId(convertIPInByteToString(ent.IfAreaId))
+	areaConf, exist := server.AreaConfMap[AreaConfKey{AreaId: areaId}]
+	if !exist {
+		return ent.IfAuthType
+	}
+	return uint16(areaConf.AuthType)
+}
+
+/* Caller holds the AuthDB lock */
+func (server *OSPFServer) getIntfKeyChain(key IntfConfKey, ent IntfConf) string {
+	authEnt, exist := server.AuthDB.IntfAuthMap[key]
+	if exist && authEnt.KeyChain != "" {
+		return authEnt.KeyChain
+	}
+	if ent.IfAreaId == nil {
+		return ""
+	}
+	areaId := config.AreaId(convertIPInByteToString(ent.IfAreaId))
+	return server.AuthDB.AreaKeyChainMap[areaId]
+}
+
+/*
+   Fill in the checksum and authentication fields of an encoded ospf
+   packet. For cryptographic authentication the checksum stays zero and
+   the digest is appended after pktlen bytes, so the returned slice is
+   longer than the packet length in the ospf header.
+   Returns nil when no key is available to authenticate the packet.
+*/
+func (server *OSPFServer) encodeOspfAuth(key IntfConfKey, ent IntfConf, ospf []byte) []byte {
+	authType := server.getIntfAuthType(ent)
+	binary.BigEndian.PutUint16(ospf[14:16], authType)
+	copy(ospf[16:OSPF_HEADER_SIZE], []byte{0, 0, 0, 0, 0, 0, 0, 0})
+	if authType != uint16(config.Md5) {
+		csum := computeCheckSum(ospf)
+		binary.BigEndian.PutUint16(ospf[12:14], csum)
+		if authType == uint16(config.SimplePassword) {
+			copy(ospf[16:OSPF_HEADER_SIZE], ent.IfAuthKey)
+		}
+		return ospf
+	}
+
+	server.AuthDB.Lock()
+	defer server.AuthDB.Unlock()
+	authEnt := server.AuthDB.IntfAuthMap[key]
+	keyChain := server.getIntfKeyChain(key, ent)
+	sendKey, expired, found := selectSendKey(server.AuthDB.KeyChainMap[keyChain], time.Now())
+	if !found {
+		authEnt.TxNoKey++
+		authEnt.TxKeyId = -1
+		server.AuthDB.IntfAuthMap[key] = authEnt
+		server.logger.Err(fmt.Sprintln("AUTH: No send key in key chain", keyChain, "for interface", key))
+		return nil
+	}
+	if expired {
+		authEnt.TxNoKey++
+		server.logger.Warning(fmt.Sprintln("AUTH: Sending with expired key", sendKey.KeyId, "of key chain", keyChain))
+	}
+	/*
+	   The sequence number must never decrease, also across restarts
+	   where it may have run ahead of the wall clock it is seeded from.
+	*/
+	seqNum := uint32(time.Now().Unix())
+	if seqNum < server.AuthDB.TxSeqNumStart {
+		seqNum = server.AuthDB.TxSeqNumStart
+	}
+	if authEnt.TxSeqNum >= seqNum {
+		seqNum = authEnt.TxSeqNum + 1
+	}
+	if seqNum > server.AuthDB.TxSeqNumLimit {
+		server.reserveAuthSeqNum(seqNum)
+	}
+	authEnt.TxSeqNum = seqNum
+	authEnt.TxKeyId = int32(sendKey.KeyId)
+	server.AuthDB.IntfAuthMap[key] = authEnt
+
+	binary.BigEndian.PutUint16(ospf[12:14], 0)
+	ospf[18] = sendKey.KeyId
+	ospf[19] = uint8(authDigestLen(sendKey.Algorithm))
+	binary.BigEndian.PutUint32(ospf[20:24], seqNum)
+	digest := computeAuthDigest(sendKey.Algorithm, sendKey.Key, ospf)
+	return append(ospf, digest...)
+}
+
+/*
+   Neighbors drop the packets whose sequence number went back, so the
+   last sequence number sent has to survive a restart. Blocks of
+   sequence numbers are saved to the file before they are used and
+   a restart starts above the saved block, or at the wall clock if
+   it is ahead of it.
+*/
+func (server *OSPFServer) loadAuthSeqNum(fileName string) {
+	server.AuthDB.Lock()
+	defer server.AuthDB.Unlock()
+	server.AuthDB.TxSeqNumFile = fileName
+	server.AuthDB.TxSeqNumStart = uint32(time.Now().Unix())
+	server.AuthDB.TxSeqNumLimit = 0
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			server.logger.Err(fmt.Sprintln("AUTH: Failed to read the tx seq num from", fileName, err))
+		}
+		return
+	}
+	var state authSeqNumState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("AUTH: Failed to read the tx seq num from", fileName, err))
+		return
+	}
+	if state.TxSeqNumLimit >= server.AuthDB.TxSeqNumStart {
+		server.AuthDB.TxSeqNumStart = state.TxSeqNumLimit + 1
+	}
+	server.logger.Info(fmt.Sprintln("AUTH: Tx seq num starts at", server.AuthDB.TxSeqNumStart))
+}
+
+/*
+   Called with the AuthDB lock held
+*/
+func (server *OSPFServer) reserveAuthSeqNum(seqNum uint32) {
+	server.AuthDB.TxSeqNumLimit = seqNum + OSPF_AUTH_SEQ_NUM_BLOCK
+	if server.AuthDB.TxSeqNumFile == "" {
+		return
+	}
+	data, err := json.Marshal(authSeqNumState{TxSeqNumLimit: server.AuthDB.TxSeqNumLimit})
+	if err == nil {
+		err = ioutil.WriteFile(server.AuthDB.TxSeqNumFile, data, 0644)
+	}
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("AUTH: Failed to save the tx seq num to", server.AuthDB.TxSeqNumFile, err))
+	}
+}
+
+/*
+   RFC 2328 D.5 authentication of a received packet, done in place of
+   the plain checksum check. For cryptographic authentication the
+   sequence number of each neighbor must not go backwards; packets
+   with a lower sequence number are dropped as replays.
+*/
+func (server *OSPFServer) verifyOspfAuth(key IntfConfKey, ent IntfConf, ospfHdr *OSPFHeader, ospfPkt []byte, srcIP net.IP) error {
+	authType := server.getIntfAuthType(ent)
+	if authType != ospfHdr.authType {
+		server.updateIntfAuthStats(key, func(authEnt *IntfAuthEnt) { authEnt.TypeMismatch++ })
+		server.countRxDrop(key, srcIP, DropAuthFailure)
+		err := errors.New(fmt.Sprintln("Dropped because of auth type mismatch, expected", authType, "received", ospfHdr.authType))
+		return err
+	}
+
+	if authType != uint16(config.Md5) {
+		if authType == uint16(config.SimplePassword) &&
+			bytesEqual(ospfPkt[16:OSPF_HEADER_SIZE], ent.IfAuthKey) == false {
+			server.updateIntfAuthStats(key, func(authEnt *IntfAuthEnt) { authEnt.PasswordFailure++ })
+			server.countRxDrop(key, srcIP, DropAuthFailure)
+			err := errors.New("Dropped because of simple password mismatch")
+			return err
+		}
+		binary.BigEndian.PutUint16(ospfPkt[12:14], 0)
+		copy(ospfPkt[16:OSPF_HEADER_SIZE], []byte{0, 0, 0, 0, 0, 0, 0, 0})
+		csum := computeCheckSum(ospfPkt[:ospfHdr.pktlen])
+		if csum != ospfHdr.chksum {
+			server.countRxDrop(key, srcIP, DropBadChecksum)
+			err := errors.New("Dropped because of invalid checksum")
+			return err
+		}
+		return nil
+	}
+
+	keyId := ospfPkt[18]
+	authLen := int(ospfPkt[19])
+	seqNum := binary.BigEndian.Uint32(ospfPkt[20:24])
+	pktlen := int(ospfHdr.pktlen)
+
+	server.AuthDB.Lock()
+	defer server.AuthDB.Unlock()
+	authEnt := server.AuthDB.IntfAuthMap[key]
+	defer func() { server.AuthDB.IntfAuthMap[key] = authEnt }()
+
+	keyChain := server.getIntfKeyChain(key, ent)
+	authKey, found := findAcceptKey(server.AuthDB.KeyChainMap[keyChain], keyId, time.Now())
+	if !found {
+		authEnt.KeyNotFound++
+		server.countRxDrop(key, srcIP, DropAuthFailure)
+		err := errors.New(fmt.Sprintln("Dropped because key id", keyId, "is not accepted by key chain", keyChain))
+		return err
+	}
+	if authLen != authDigestLen(authKey.Algorithm) || len(ospfPkt) < pktlen+authLen {
+		authEnt.DigestFailure++
+		server.countRxDrop(key, srcIP, DropAuthFailure)
+		err := errors.New(fmt.Sprintln("Dropped because of invalid auth data length", authLen))
+		return err
+	}
+	digest := computeAuthDigest(authKey.Algorithm, authKey.Key, ospfPkt[:pktlen])
+	if !hmac.Equal(digest, ospfPkt[pktlen:pktlen+authLen]) {
+		authEnt.DigestFailure++
+		server.countRxDrop(key, srcIP, DropAuthFailure)
+		err := errors.New(fmt.Sprintln("Dropped because of digest mismatch for key id", keyId))
+		return err
+	}
+
+	nbrKey := NeighborConfKey{
+		IPAddr:  config.IpAddress(srcIP.String()),
+		IntfIdx: key.IntfIdx,
+	}
+	lastSeqNum, exist := server.AuthDB.NbrRxSeqNumMap[nbrKey]
+	if exist && seqNum < lastSeqNum {
+		authEnt.ReplayDrops++
+		server.countRxDrop(key, srcIP, DropAuthFailure)
+		err := errors.New(fmt.Sprintln("Dropped because of replayed sequence number", seqNum, "last", lastSeqNum))
+		return err
+	}
+	server.AuthDB.NbrRxSeqNumMap[nbrKey] = seqNum
+	return nil
+}
+
+func (server *OSPFServer) updateIntfAuthStats(key IntfConfKey, update func(authEnt *IntfAuthEnt)) {
+	server.AuthDB.Lock()
+	authEnt := server.AuthDB.IntfAuthMap[key]
+	update(&authEnt)
+	server.AuthDB.IntfAuthMap[key] = authEnt
+	server.AuthDB.Unlock()
+}
+
+func (server *OSPFServer) clearNbrAuthSeqNum(nbrKey NeighborConfKey) {
+	server.AuthDB.Lock()
+	delete(server.AuthDB.NbrRxSeqNumMap, nbrKey)
+	server.AuthDB.Unlock()
+}
+
+func (server *OSPFServer) getIntfAuthState(key IntfConfKey, state *config.InterfaceState) {
+	server.AuthDB.RLock()
+	defer server.AuthDB.RUnlock()
+	ent, _ := server.IntfConfMap[key]
+	authEnt := server.AuthDB.IntfAuthMap[key]
+	state.IfKeyChain = server.getIntfKeyChain(key, ent)
+	state.IfAuthTxKeyId = authEnt.TxKeyId
+	state.IfAuthTxSeqNum = authEnt.TxSeqNum
+	state.IfAuthTypeMismatch = authEnt.TypeMismatch
+	state.IfAuthKeyNotFound = authEnt.KeyNotFound
+	state.IfAuthDigestFailure = authEnt.DigestFailure
+	state.IfAuthReplayDrops = authEnt.ReplayDrops
+	state.IfAuthPasswordFailure = authEnt.PasswordFailure
+	state.IfAuthTxNoKey = authEnt.TxNoKey
+}
+
+func (server *OSPFServer) ValidateKeyChainConf(conf config.KeyChainConf) error {
+	if conf.Name == "" {
+		return errors.New("Key chain name is required")
+	}
+	keyIds := make(map[uint8]bool)
+	for _, key := range conf.KeyList {
+		if keyIds[key.KeyId] {
+			return errors.New(fmt.Sprintln("Duplicate key id", key.KeyId, "in key chain", conf.Name))
+		}
+		keyIds[key.KeyId] = true
+		if authDigestLen(key.Algorithm) == 0 {
+			return errors.New(fmt.Sprintln("Invalid algorithm for key id", key.KeyId))
+		}
+		if key.Key == "" {
+			return errors.New(fmt.Sprintln("Empty key for key id", key.KeyId))
+		}
+		if key.Algorithm == config.KeyedMd5 && len(key.Key) > OSPF_AUTH_MD5_KEY_LEN {
+			return errors.New(fmt.Sprintln("MD5 key for key id", key.KeyId, "is longer than 16 bytes"))
+		}
+		if (!key.SendEnd.IsZero() && !key.SendEnd.After(key.SendStart)) ||
+			(!key.AcceptEnd.IsZero() && !key.AcceptEnd.After(key.AcceptStart)) {
+			return errors.New(fmt.Sprintln("Key lifetime ends before it starts for key id", key.KeyId))
+		}
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateKeyChainDelete(name string) error {
+	server.AuthDB.RLock()
+	defer server.AuthDB.RUnlock()
+	if _, exist := server.AuthDB.KeyChainMap[name]; !exist {
+		return errors.New(fmt.Sprintln("Key chain", name, "does not exist"))
+	}
+	for key, authEnt := range server.AuthDB.IntfAuthMap {
+		if authEnt.KeyChain == name {
+			return errors.New(fmt.Sprintln("Key chain", name, "is in use by interface", key))
+		}
+	}
+	for areaId, keyChain := range server.AuthDB.AreaKeyChainMap {
+		if keyChain == name {
+			return errors.New(fmt.Sprintln("Key chain", name, "is in use by area", areaId))
+		}
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateKeyChainBinding(name string) error {
+	server.AuthDB.RLock()
+	defer server.AuthDB.RUnlock()
+	if _, exist := server.AuthDB.KeyChainMap[name]; !exist {
+		return errors.New(fmt.Sprintln("Key chain", name, "does not exist"))
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateIfAuthKey(ifConf config.InterfaceConf) error {
+	if ifConf.IfAuthType == config.SimplePassword && convertSimpleAuthKey(ifConf.IfAuthKey) == nil {
+		return errors.New("Invalid simple password authentication key")
+	}
+	return nil
+}
+
+func (server *OSPFServer) processAuthConfig(msg AuthConfMsg) error {
+	server.AuthDB.Lock()
+	defer server.AuthDB.Unlock()
+	switch {
+	case msg.KeyChain != nil:
+		if !msg.Op {
+			delete(server.AuthDB.KeyChainMap, msg.KeyChain.Name)
+			return nil
+		}
+		var keys []AuthKeyEnt
+		for _, key := range msg.KeyChain.KeyList {
+			keys = append(keys, AuthKeyEnt{
+				KeyId:       key.KeyId,
+				Algorithm:   key.Algorithm,
+				Key:         []byte(key.Key),
+				SendStart:   key.SendStart,
+				SendEnd:     key.SendEnd,
+				AcceptStart: key.AcceptStart,
+				AcceptEnd:   key.AcceptEnd,
+			})
+		}
+		server.AuthDB.KeyChainMap[msg.KeyChain.Name] = keys
+	case msg.IfKeyChain != nil:
+		key := IntfConfKey{
+			IPAddr:  msg.IfKeyChain.IfIpAddress,
+			IntfIdx: msg.IfKeyChain.AddressLessIf,
+		}
+		authEnt := server.AuthDB.IntfAuthMap[key]
+		authEnt.KeyChain = ""
+		if msg.Op {
+			authEnt.KeyChain = msg.IfKeyChain.KeyChain
+		}
+		server.AuthDB.IntfAuthMap[key] = authEnt
+	case msg.AreaKeyChain != nil:
+		if !msg.Op {
+			delete(server.AuthDB.AreaKeyChainMap, msg.AreaKeyChain.AreaId)
+			return nil
+		}
+		server.AuthDB.AreaKeyChainMap[msg.AreaKeyChain.AreaId] = msg.AreaKeyChain.KeyChain
+	default:
+		return errors.New("Empty auth configuration")
+	}
+	return nil
+}
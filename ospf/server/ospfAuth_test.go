@@ -0,0 +1,335 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfAuth_test
+   This test covers
+   1) Send key selection and key rollover.
+   2) Digest generation and verification for every algorithm.
+   3) Replay protection and auth failure counters.
+   4) Digests against vectors computed independently from RFC 2328 D.4.3 and RFC 5709 3.3.
+   5) Tx sequence numbers across a restart.
+*/
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"l3/ospf/config"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+var authSrcIP = net.IP{10, 1, 1, 2}
+
+func initAuthTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	intf.IfAuthType = uint16(config.Md5)
+	ospf.IntfConfMap[key] = intf
+}
+
+func buildAuthTestPkt() []byte {
+	ospfHdr := OSPFHeader{
+		ver:      OSPF_VERSION_2,
+		pktType:  uint8(HelloType),
+		pktlen:   uint16(OSPF_HEADER_SIZE + 4),
+		routerId: []byte{1, 1, 1, 1},
+		areaId:   intf.IfAreaId,
+		authType: intf.IfAuthType,
+	}
+	return append(encodeOspfHdr(ospfHdr), []byte{0xa, 0xb, 0xc, 0xd}...)
+}
+
+func verifyAuthTestPkt(pkt []byte) error {
+	ospfHdr := NewOSPFHeader()
+	decodeOspfHdr(pkt, ospfHdr)
+	return ospf.verifyOspfAuth(key, intf, ospfHdr, pkt, authSrcIP)
+}
+
+func getIntfAuthTestState() config.InterfaceState {
+	var state config.InterfaceState
+	ospf.getIntfAuthState(key, &state)
+	return state
+}
+
+func printIntfAuthState() {
+	state := getIntfAuthTestState()
+	fmt.Println("KeyChain:", state.IfKeyChain, "TxKeyId:", state.IfAuthTxKeyId,
+		"TypeMismatch:", state.IfAuthTypeMismatch, "KeyNotFound:", state.IfAuthKeyNotFound,
+		"DigestFailure:", state.IfAuthDigestFailure, "ReplayDrops:", state.IfAuthReplayDrops,
+		"TxNoKey:", state.IfAuthTxNoKey)
+}
+
+func bindAuthTestKeyChain(keys []config.AuthKeyConf) {
+	conf := config.KeyChainConf{
+		Name:    "ospf-keys",
+		KeyList: keys,
+	}
+	ospf.processAuthConfig(AuthConfMsg{Op: true, KeyChain: &conf})
+	ospf.processAuthConfig(AuthConfMsg{Op: true, IfKeyChain: &config.IfKeyChainConf{
+		IfIpAddress:   key.IPAddr,
+		AddressLessIf: key.IntfIdx,
+		KeyChain:      "ospf-keys",
+	}})
+}
+
+func TestOspfAuth(t *testing.T) {
+	fmt.Println("\n**************** AUTH ************\n")
+	initAuthTestParams()
+	for index := 1; index < 9; index++ {
+		err := authTestLogic(index)
+		if err != SUCCESS {
+			t.Fatal("Failed test case", index, "for auth")
+		}
+	}
+}
+
+/*
+   Digests of authDigestTestPkt, computed with an HMAC and hash
+   implementation other than the one under test
+*/
+var authDigestTestPkt = []byte("OSPFv2 RFC 5709 test packet")
+var authDigestTestVectors = []struct {
+	alg    config.AuthAlgorithm
+	key    string
+	digest string
+}{
+	{config.KeyedMd5, "ospf-secret", "fad894e398045965f3371febc199114e"},
+	{config.HmacSha1, "ospf-secret", "df6bb470af5ccd077534bf7b011fa55525d47f28"},
+	{config.HmacSha256, "ospf-secret", "9af360704996d58bf11dfd4d135fd94a88d0c305024396a80cf7577a3693d901"},
+	{config.HmacSha384, "ospf-secret", "abc7c1f23023660fb236cbda1d59aecde868a902533fba49c080a40b7a47ec183d0299ea838fa4341a66fa74ee90900e"},
+	{config.HmacSha512, "ospf-secret", "1af4d7351305affb066951309e0f57d399e80c27c4aef87be1f08b45c58e81673a0e3573335fe824461501b58750d5f2591b8e79d029457085bddd9bff0f47e8"},
+	//keys longer than the hash length are hashed first (RFC 5709 3.3), not only those longer than the block size
+	{config.HmacSha1, string(bytes.Repeat([]byte("k"), 25)), "136e5972dddb3ce88370b306c108fba005a38a5c"},
+	{config.HmacSha256, string(bytes.Repeat([]byte("k"), 37)), "ea8759c44a50f76bbf0e51b5b842c13be3d02e3ba479ce07cd5ff1697c5c940b"},
+	{config.HmacSha384, string(bytes.Repeat([]byte("k"), 53)), "0224a55eaca0b11f80ce17ef53bd1dfcbeea2d9ea031b0269a2881e25ec1fe5d82ec89641201d3437303b05073a003be"},
+	{config.HmacSha512, string(bytes.Repeat([]byte("k"), 69)), "7092d721649ffc2756cf3b6aa477e89082aab1510c5866e1b43d48385592e54c7c0d21e34e4ab7e6749b4acef758296e530c4551365959d8420f96430a4303e5"},
+}
+
+func authTestLogic(tNum int) int {
+	now := time.Now()
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running selectSendKey ")
+		keys := []AuthKeyEnt{
+			{KeyId: 1, SendEnd: now.Add(time.Hour)},
+			{KeyId: 2, SendStart: now.Add(-time.Minute)},
+			{KeyId: 3, SendStart: now.Add(time.Hour)},
+		}
+		sendKey, expired, found := selectSendKey(keys, now)
+		fmt.Println("Rollover send key:", sendKey.KeyId, "expired:", expired, "found:", found)
+		if sendKey.KeyId != 2 || expired || !found {
+			return FAIL
+		}
+		keys = []AuthKeyEnt{
+			{KeyId: 1, SendEnd: now.Add(-time.Hour)},
+			{KeyId: 2, SendEnd: now.Add(-time.Minute)},
+		}
+		sendKey, expired, found = selectSendKey(keys, now)
+		fmt.Println("Expired send key:", sendKey.KeyId, "expired:", expired, "found:", found)
+		if sendKey.KeyId != 2 || !expired || !found {
+			return FAIL
+		}
+		sendKey, expired, found = selectSendKey(nil, now)
+		fmt.Println("Empty key chain found:", found)
+		if found {
+			return FAIL
+		}
+	case 2:
+		fmt.Println(tNum, ": Running key chain validation ")
+		conf := config.KeyChainConf{
+			Name: "ospf-keys",
+			KeyList: []config.AuthKeyConf{
+				{KeyId: 1, Algorithm: config.KeyedMd5, Key: "0123456789abcdefg"},
+			},
+		}
+		err := ospf.ValidateKeyChainConf(conf)
+		fmt.Println("Long MD5 key:", err)
+		if err == nil {
+			return FAIL
+		}
+		conf.KeyList[0].Key = "md5-key"
+		conf.KeyList = append(conf.KeyList, config.AuthKeyConf{KeyId: 1, Algorithm: config.HmacSha256, Key: "sha-key"})
+		err = ospf.ValidateKeyChainConf(conf)
+		fmt.Println("Duplicate key id:", err)
+		if err == nil {
+			return FAIL
+		}
+		err = ospf.ValidateKeyChainBinding("ospf-keys")
+		fmt.Println("Unknown key chain binding:", err)
+		if err == nil {
+			return FAIL
+		}
+	case 3:
+		fmt.Println(tNum, ": Running digest round trip ")
+		for alg := config.KeyedMd5; alg <= config.HmacSha512; alg++ {
+			bindAuthTestKeyChain([]config.AuthKeyConf{
+				{KeyId: uint8(alg), Algorithm: alg, Key: "ospf-secret"},
+			})
+			pkt := ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+			err := verifyAuthTestPkt(pkt)
+			fmt.Println(config.AuthAlgorithmList[alg], "pkt len:", len(pkt), "key id:", pkt[18],
+				"auth len:", pkt[19], "verify:", err)
+			if err != nil || pkt[18] != uint8(alg) || int(pkt[19]) != authDigestLen(alg) ||
+				len(pkt) != OSPF_HEADER_SIZE+4+authDigestLen(alg) {
+				return FAIL
+			}
+		}
+	case 4:
+		fmt.Println(tNum, ": Running digest failure ")
+		before := getIntfAuthTestState()
+		pkt := ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+		pkt[len(pkt)-1] ^= 0xff
+		err := verifyAuthTestPkt(pkt)
+		fmt.Println("Tampered digest:", err)
+		if err == nil {
+			return FAIL
+		}
+		pkt = ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+		pkt[18] = 100
+		err = verifyAuthTestPkt(pkt)
+		fmt.Println("Unknown key id:", err)
+		if err == nil {
+			return FAIL
+		}
+		printIntfAuthState()
+		after := getIntfAuthTestState()
+		if after.IfAuthDigestFailure != before.IfAuthDigestFailure+1 ||
+			after.IfAuthKeyNotFound != before.IfAuthKeyNotFound+1 {
+			return FAIL
+		}
+	case 5:
+		fmt.Println(tNum, ": Running replay protection ")
+		pkt := ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+		replay := make([]byte, len(pkt))
+		copy(replay, pkt)
+		newer := ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+		fmt.Println("Seq nums:", binary.BigEndian.Uint32(pkt[20:24]), binary.BigEndian.Uint32(newer[20:24]))
+		if binary.BigEndian.Uint32(newer[20:24]) <= binary.BigEndian.Uint32(pkt[20:24]) {
+			return FAIL
+		}
+		err := verifyAuthTestPkt(newer)
+		fmt.Println("Newer packet:", err)
+		if err != nil {
+			return FAIL
+		}
+		err = verifyAuthTestPkt(replay)
+		fmt.Println("Replayed packet:", err)
+		if err == nil {
+			return FAIL
+		}
+		ospf.clearNbrAuthSeqNum(NeighborConfKey{
+			IPAddr:  config.IpAddress(authSrcIP.String()),
+			IntfIdx: key.IntfIdx,
+		})
+		err = verifyAuthTestPkt(replay)
+		fmt.Println("Replayed packet after neighbor delete:", err)
+		if err != nil {
+			return FAIL
+		}
+		printIntfAuthState()
+	case 6:
+		fmt.Println(tNum, ": Running auth type mismatch and simple password ")
+		pkt := buildAuthTestPkt()
+		binary.BigEndian.PutUint16(pkt[14:16], uint16(config.SimplePassword))
+		err := verifyAuthTestPkt(pkt)
+		fmt.Println("Auth type mismatch:", err)
+		if err == nil {
+			return FAIL
+		}
+		dotted := convertSimpleAuthKey("1.2.3.4.5.6.7.8")
+		plain := convertSimpleAuthKey("secret")
+		long := convertSimpleAuthKey("secret-too-long")
+		fmt.Println("Dotted key:", dotted)
+		fmt.Println("Plain key:", plain)
+		fmt.Println("Long key:", long)
+		if !bytes.Equal(dotted, []byte{1, 2, 3, 4, 5, 6, 7, 8}) ||
+			!bytes.Equal(plain, []byte{'s', 'e', 'c', 'r', 'e', 't', 0, 0}) || long != nil {
+			return FAIL
+		}
+		printIntfAuthState()
+	case 7:
+		fmt.Println(tNum, ": Running digest test vectors ")
+		for _, vector := range authDigestTestVectors {
+			digest := hex.EncodeToString(computeAuthDigest(vector.alg, []byte(vector.key), authDigestTestPkt))
+			fmt.Println(config.AuthAlgorithmList[vector.alg], "key len:", len(vector.key), "digest:", digest)
+			if digest != vector.digest {
+				return FAIL
+			}
+		}
+	case 8:
+		fmt.Println(tNum, ": Running tx seq num across a restart ")
+		file, err := ioutil.TempFile("", "ospfAuthSeqNum")
+		if err != nil {
+			return FAIL
+		}
+		fileName := file.Name()
+		file.Close()
+		os.Remove(fileName)
+		defer os.Remove(fileName)
+		nbrKey := NeighborConfKey{
+			IPAddr:  config.IpAddress(authSrcIP.String()),
+			IntfIdx: key.IntfIdx,
+		}
+		authKeys := []config.AuthKeyConf{{KeyId: 1, Algorithm: config.HmacSha256, Key: "ospf-secret"}}
+		ospf.initAuthDB()
+		ospf.loadAuthSeqNum(fileName)
+		bindAuthTestKeyChain(authKeys)
+		//a burst of packets runs the seq num ahead of the wall clock
+		authEnt := ospf.AuthDB.IntfAuthMap[key]
+		authEnt.TxSeqNum = uint32(now.Unix()) + 1000
+		ospf.AuthDB.IntfAuthMap[key] = authEnt
+		sent := ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+		if err = verifyAuthTestPkt(sent); err != nil {
+			return FAIL
+		}
+		sentSeqNum := binary.BigEndian.Uint32(sent[20:24])
+		rxSeqNum := ospf.AuthDB.NbrRxSeqNumMap[nbrKey]
+
+		//restart, the neighbor keeps the seq num it last received
+		ospf.initAuthDB()
+		ospf.loadAuthSeqNum(fileName)
+		bindAuthTestKeyChain(authKeys)
+		ospf.AuthDB.NbrRxSeqNumMap[nbrKey] = rxSeqNum
+		pkt := ospf.encodeOspfAuth(key, intf, buildAuthTestPkt())
+		seqNum := binary.BigEndian.Uint32(pkt[20:24])
+		err = verifyAuthTestPkt(pkt)
+		fmt.Println("Seq num before restart:", sentSeqNum, "after restart:", seqNum, "verify:", err)
+		if seqNum <= sentSeqNum || err != nil {
+			return FAIL
+		}
+		err = verifyAuthTestPkt(sent)
+		fmt.Println("Packet replayed from before the restart:", err)
+		if err == nil {
+			return FAIL
+		}
+		ospf.AuthDB.TxSeqNumFile = ""
+	}
+	return SUCCESS
+}
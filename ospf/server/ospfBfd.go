@@ -0,0 +1,319 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"bfdd"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nanomsg "github.com/op/go-nanomsg"
+	"l3/bfd/bfddCommonDefs"
+	"l3/ospf/config"
+	"net"
+	"time"
+	"utils/ipcutils"
+)
+
+/* BFD for OSPF neighbors (RFC 5882).
+   A BFD session is created with bfdd, as the ospf owner, towards each
+   neighbor in state 2-Way or higher on the BFD enabled interfaces. The
+   neighbor is brought down as soon as bfdd reports its session down,
+   without waiting for the router dead interval.
+*/
+
+const (
+	BfdSessionStateDisabled = "Disabled"
+	BfdSessionStateDown     = "Down"
+	BfdSessionStateUp       = "Up"
+)
+
+type BfddClient struct {
+	OspfClientBase
+	ClientHdl *bfdd.BFDDServicesClient
+}
+
+type BfdConfMsg struct {
+	Op    bool
+	IfBfd config.IfBfdConf
+}
+
+type BfdSessionEnt struct {
+	DestIp    string
+	IfName    string
+	ParamName string
+	Up        bool // session came up, a down report ends the adjacency
+}
+
+func (server *OSPFServer) initBfd() {
+	server.BfdConfigCh = make(chan BfdConfMsg)
+	server.bfdSubSocketCh = make(chan []byte)
+	server.bfdSubSocketErrCh = make(chan error)
+	server.IfBfdMap = make(map[IntfConfKey]config.IfBfdConf)
+	server.BfdSessionMap = make(map[NeighborConfKey]BfdSessionEnt)
+}
+
+func (server *OSPFServer) ValidateIfBfdConf(conf config.IfBfdConf) error {
+	ip := net.ParseIP(string(conf.IfIpAddress))
+	if ip == nil || ip.To4() == nil {
+		return errors.New(fmt.Sprintln("Invalid interface address", conf.IfIpAddress))
+	}
+	if ip.IsUnspecified() && conf.AddressLessIf == 0 {
+		return errors.New("Unnumbered interface needs an interface index")
+	}
+	return nil
+}
+
+/* @fn connectToBfdd
+bfdd is optional, the connection is retried in the background and
+the sessions of the neighbors which came up meanwhile are created
+once it is connected.
+*/
+func (server *OSPFServer) connectToBfdd(address string) {
+	var err error
+	server.bfddClient.Address = address
+	server.bfddClient.Transport, server.bfddClient.PtrProtocolFactory, err = ipcutils.CreateIPCHandles(address)
+	if err != nil {
+		server.logger.Info(fmt.Sprintln("Failed to connect to Bfdd, retrying until connection is successful"))
+		ticker := time.NewTicker(time.Duration(1000) * time.Millisecond)
+		for _ = range ticker.C {
+			server.bfddClient.Transport, server.bfddClient.PtrProtocolFactory, err = ipcutils.CreateIPCHandles(address)
+			if err == nil {
+				ticker.Stop()
+				break
+			}
+		}
+	}
+	server.logger.Info("Ospfd is connected to Bfdd")
+	server.bfddClient.ClientHdl = bfdd.NewBFDDServicesClientFactory(server.bfddClient.Transport, server.bfddClient.PtrProtocolFactory)
+	err = server.listenForBfdUpdates(bfddCommonDefs.PUB_SOCKET_ADDR)
+	if err == nil {
+		go server.createBfdSubscriber()
+	}
+	server.BfdMutex.Lock()
+	server.bfddClient.IsConnected = true
+	sessions := make([]BfdSessionEnt, 0)
+	for _, ent := range server.BfdSessionMap {
+		sessions = append(sessions, ent)
+	}
+	server.BfdMutex.Unlock()
+	for _, ent := range sessions {
+		server.createBfdSession(ent)
+	}
+}
+
+func (server *OSPFServer) listenForBfdUpdates(address string) error {
+	var err error
+	if server.bfdSubSocket, err = nanomsg.NewSubSocket(); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to create BFD subscribe socket, error:", err))
+		return err
+	}
+
+	if err = server.bfdSubSocket.Subscribe(""); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to subscribe to \"\" on BFD subscribe socket, error:", err))
+		return err
+	}
+
+	if _, err = server.bfdSubSocket.Connect(address); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to connect to BFD publisher socket, address:", address, "error:", err))
+		return err
+	}
+
+	server.logger.Info(fmt.Sprintln("Connected to BFD publisher at address:", address))
+	if err = server.bfdSubSocket.SetRecvBuffer(1024 * 1024); err != nil {
+		server.logger.Err(fmt.Sprintln("Failed to set the buffer size for BFD publisher socket, error:", err))
+		return err
+	}
+	return nil
+}
+
+func (server *OSPFServer) createBfdSubscriber() {
+	for {
+		server.logger.Info("Read on BFD subscriber socket...")
+		bfdrxBuf, err := server.bfdSubSocket.Recv(0)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("Recv on BFD subscriber socket failed with error:", err))
+			server.bfdSubSocketErrCh <- err
+			continue
+		}
+		server.bfdSubSocketCh <- bfdrxBuf
+	}
+}
+
+func (server *OSPFServer) createBfdSession(ent BfdSessionEnt) {
+	if !server.bfddClient.IsConnected {
+		server.logger.Info(fmt.Sprintln("BFD: Not connected to bfdd, session for ", ent.DestIp, " created on connect"))
+		return
+	}
+	bfdSession := bfdd.NewBfdSession()
+	bfdSession.IpAddr = ent.DestIp
+	bfdSession.ParamName = ent.ParamName
+	bfdSession.Interface = ent.IfName
+	bfdSession.Owner = bfddCommonDefs.ConvertBfdSessionOwnerValToStr(bfddCommonDefs.OSPF)
+	server.logger.Info(fmt.Sprintln("BFD: Creating session ", bfdSession))
+	ret, err := server.bfddClient.ClientHdl.CreateBfdSession(bfdSession)
+	if !ret || err != nil {
+		server.logger.Err(fmt.Sprintln("BFD: CreateBfdSession for ", ent.DestIp, " failed, err:", err))
+	}
+}
+
+func (server *OSPFServer) deleteBfdSession(ent BfdSessionEnt) {
+	if !server.bfddClient.IsConnected {
+		return
+	}
+	bfdSession := bfdd.NewBfdSession()
+	bfdSession.IpAddr = ent.DestIp
+	bfdSession.Interface = ent.IfName
+	bfdSession.Owner = bfddCommonDefs.ConvertBfdSessionOwnerValToStr(bfddCommonDefs.OSPF)
+	server.logger.Info(fmt.Sprintln("BFD: Deleting session ", bfdSession))
+	ret, err := server.bfddClient.ClientHdl.DeleteBfdSession(bfdSession)
+	if !ret || err != nil {
+		server.logger.Err(fmt.Sprintln("BFD: DeleteBfdSession for ", ent.DestIp, " failed, err:", err))
+	}
+}
+
+/* @fn addNbrBfdSession
+Called when the neighbor reaches 2-Way or a higher state.
+*/
+func (server *OSPFServer) addNbrBfdSession(nbrKey NeighborConfKey, nbrConf OspfNeighborEntry) {
+	server.BfdMutex.Lock()
+	if _, exist := server.BfdSessionMap[nbrKey]; exist {
+		server.BfdMutex.Unlock()
+		return
+	}
+	conf, enabled := server.IfBfdMap[nbrConf.intfConfKey]
+	if !enabled {
+		server.BfdMutex.Unlock()
+		return
+	}
+	ent := BfdSessionEnt{
+		DestIp:    nbrConf.OspfNbrIPAddr.String(),
+		IfName:    server.IntfConfMap[nbrConf.intfConfKey].IfName,
+		ParamName: conf.SessionParam,
+	}
+	server.BfdSessionMap[nbrKey] = ent
+	server.BfdMutex.Unlock()
+	server.createBfdSession(ent)
+}
+
+func (server *OSPFServer) deleteNbrBfdSession(nbrKey NeighborConfKey) {
+	server.BfdMutex.Lock()
+	ent, exist := server.BfdSessionMap[nbrKey]
+	delete(server.BfdSessionMap, nbrKey)
+	server.BfdMutex.Unlock()
+	if exist {
+		server.deleteBfdSession(ent)
+	}
+}
+
+func (server *OSPFServer) processIfBfdConfig(msg BfdConfMsg) error {
+	err := server.ValidateIfBfdConf(msg.IfBfd)
+	if err != nil {
+		return err
+	}
+	key := IntfConfKey{
+		IPAddr:  msg.IfBfd.IfIpAddress,
+		IntfIdx: msg.IfBfd.AddressLessIf,
+	}
+	server.BfdMutex.Lock()
+	old, wasEnabled := server.IfBfdMap[key]
+	if msg.Op && msg.IfBfd.Enable {
+		server.IfBfdMap[key] = msg.IfBfd
+	} else {
+		delete(server.IfBfdMap, key)
+	}
+	server.BfdMutex.Unlock()
+
+	isEnabled := msg.Op && msg.IfBfd.Enable
+	for nbrKey, nbrConf := range server.NeighborConfigMap {
+		if nbrConf.intfConfKey != key {
+			continue
+		}
+		if wasEnabled && (!isEnabled || old.SessionParam != msg.IfBfd.SessionParam) {
+			server.deleteNbrBfdSession(nbrKey)
+		}
+		if isEnabled && nbrConf.OspfNbrState >= config.NbrTwoWay {
+			server.addNbrBfdSession(nbrKey, nbrConf)
+		}
+	}
+	return nil
+}
+
+/* @fn processBfdNotification
+RFC 5882 4.1. A session which never came up does not bring the
+neighbor down, the hello protocol keeps running next to BFD.
+*/
+func (server *OSPFServer) processBfdNotification(bfdrxBuf []byte) {
+	var msg bfddCommonDefs.BfddNotifyMsg
+	err := json.Unmarshal(bfdrxBuf, &msg)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("BFD: Unable to unmarshal bfdrxBuf:", bfdrxBuf))
+		return
+	}
+	downNbrs := make([]NeighborConfKey, 0)
+	server.BfdMutex.Lock()
+	for nbrKey, ent := range server.BfdSessionMap {
+		if ent.DestIp != msg.DestIp || ent.Up == msg.State {
+			continue
+		}
+		server.logger.Info(fmt.Sprintln("BFD: Session to ", ent.DestIp, " state up ", msg.State))
+		if !msg.State {
+			downNbrs = append(downNbrs, nbrKey)
+		}
+		ent.Up = msg.State
+		server.BfdSessionMap[nbrKey] = ent
+	}
+	server.BfdMutex.Unlock()
+	for _, nbrKey := range downNbrs {
+		server.processBfdNbrDown(nbrKey)
+	}
+}
+
+func (server *OSPFServer) processBfdNbrDown(nbrKey NeighborConfKey) {
+	nbrConf, exist := server.NeighborConfigMap[nbrKey]
+	if !exist {
+		return
+	}
+	if server.isGraceHelperNbr(nbrKey) {
+		// restarting neighbor stays up till the grace period ends
+		return
+	}
+	if nbrConf.NbrDeadTimer != nil {
+		nbrConf.NbrDeadTimer.Stop()
+	}
+	server.processNbrDown(nbrKey, "BFD session down ")
+}
+
+func (server *OSPFServer) getNbrBfdState(nbrKey NeighborConfKey) string {
+	server.BfdMutex.RLock()
+	defer server.BfdMutex.RUnlock()
+	ent, exist := server.BfdSessionMap[nbrKey]
+	if !exist {
+		return BfdSessionStateDisabled
+	}
+	if ent.Up {
+		return BfdSessionStateUp
+	}
+	return BfdSessionStateDown
+}
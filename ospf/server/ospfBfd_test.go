@@ -0,0 +1,187 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfBfd_test
+   This test covers
+   1) Interface BFD configuration validation.
+   2) Sessions for the neighbors in 2-Way or higher state.
+   3) Down report for a session which never came up.
+   4) Neighbor down on BFD session down.
+   5) BFD disable on the interface.
+*/
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"l3/bfd/bfddCommonDefs"
+	"l3/ospf/config"
+	"net"
+	"testing"
+)
+
+var bfdIntfKey IntfConfKey
+var bfdNbrKey NeighborConfKey
+var bfdInitNbrKey NeighborConfKey
+
+func initBfdTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+
+	bfdIntfKey = IntfConfKey{
+		IPAddr:  config.IpAddress("10.1.1.1"),
+		IntfIdx: 0,
+	}
+	bfdIntf := intf
+	bfdIntf.IfName = "fpPort1"
+	bfdIntf.NbrStateChangeCh = make(chan NbrStateChangeMsg, 1)
+	ospf.IntfConfMap[bfdIntfKey] = bfdIntf
+
+	bfdNbrKey = NeighborConfKey{
+		IPAddr:  config.IpAddress("10.1.1.2"),
+		IntfIdx: 0,
+	}
+	nbr := nbrConf
+	nbr.intfConfKey = bfdIntfKey
+	nbr.OspfNbrState = config.NbrFull
+	ospf.NeighborConfigMap[bfdNbrKey] = nbr
+
+	bfdInitNbrKey = NeighborConfKey{
+		IPAddr:  config.IpAddress("10.1.1.3"),
+		IntfIdx: 0,
+	}
+	nbr.OspfNbrIPAddr = net.IP{10, 1, 1, 3}
+	nbr.OspfNbrState = config.NbrInit
+	ospf.NeighborConfigMap[bfdInitNbrKey] = nbr
+}
+
+func getBfdTestNotification(destIp string, state bool) []byte {
+	buf, _ := json.Marshal(bfddCommonDefs.BfddNotifyMsg{DestIp: destIp, State: state})
+	return buf
+}
+
+func isBfdNbrDownSignalled() bool {
+	select {
+	case data := <-ospf.IntfConfMap[bfdIntfKey].NbrStateChangeCh:
+		return data.nbrKey == bfdNbrKey
+	default:
+	}
+	return false
+}
+
+func TestOspfBfd(t *testing.T) {
+	fmt.Println("\n**************** BFD ************\n")
+	initBfdTestParams()
+	for index := 1; index < 6; index++ {
+		err := bfdTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for BFD ", index)
+		}
+	}
+}
+
+func bfdTestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running ValidateIfBfdConf")
+		conf := config.IfBfdConf{IfIpAddress: "10.1.1", Enable: true}
+		if ospf.ValidateIfBfdConf(conf) == nil {
+			fmt.Println("Invalid interface address accepted")
+			return FAIL
+		}
+		conf = config.IfBfdConf{IfIpAddress: "0.0.0.0", Enable: true}
+		if ospf.ValidateIfBfdConf(conf) == nil {
+			fmt.Println("Unnumbered interface accepted without index")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running processIfBfdConfig")
+		conf := config.IfBfdConf{
+			IfIpAddress:  bfdIntfKey.IPAddr,
+			Enable:       true,
+			SessionParam: "default",
+		}
+		err := ospf.processIfBfdConfig(BfdConfMsg{Op: true, IfBfd: conf})
+		if err != nil {
+			fmt.Println("BFD configuration failed ", err)
+			return FAIL
+		}
+		ent, exist := ospf.BfdSessionMap[bfdNbrKey]
+		if !exist || ent.DestIp != "10.1.1.2" || ent.IfName != "fpPort1" ||
+			ent.ParamName != "default" {
+			fmt.Println("Unexpected session for full neighbor ", ent)
+			return FAIL
+		}
+		if ospf.getNbrBfdState(bfdNbrKey) != BfdSessionStateDown {
+			fmt.Println("Unexpected BFD state ", ospf.getNbrBfdState(bfdNbrKey))
+			return FAIL
+		}
+		if ospf.getNbrBfdState(bfdInitNbrKey) != BfdSessionStateDisabled {
+			fmt.Println("Session created for neighbor in Init state")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running processBfdNotification for a session which never came up")
+		ospf.processBfdNotification(getBfdTestNotification("10.1.1.2", false))
+		if isBfdNbrDownSignalled() {
+			fmt.Println("Neighbor brought down before the session came up")
+			return FAIL
+		}
+		ospf.processBfdNotification(getBfdTestNotification("10.1.1.2", true))
+		if ospf.getNbrBfdState(bfdNbrKey) != BfdSessionStateUp {
+			fmt.Println("Session not marked up ", ospf.getNbrBfdState(bfdNbrKey))
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running processBfdNotification for session down")
+		ospf.processBfdNotification(getBfdTestNotification("10.1.1.2", false))
+		if !isBfdNbrDownSignalled() {
+			fmt.Println("Neighbor not brought down on BFD session down")
+			return FAIL
+		}
+		if ospf.getNbrBfdState(bfdNbrKey) != BfdSessionStateDown {
+			fmt.Println("Session not marked down ", ospf.getNbrBfdState(bfdNbrKey))
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running processIfBfdConfig disable")
+		conf := config.IfBfdConf{IfIpAddress: bfdIntfKey.IPAddr}
+		ospf.processIfBfdConfig(BfdConfMsg{Op: false, IfBfd: conf})
+		if len(ospf.BfdSessionMap) != 0 || len(ospf.IfBfdMap) != 0 {
+			fmt.Println("Sessions left after BFD disable ", ospf.BfdSessionMap)
+			return FAIL
+		}
+		if ospf.getNbrBfdState(bfdNbrKey) != BfdSessionStateDisabled {
+			fmt.Println("Unexpected BFD state ", ospf.getNbrBfdState(bfdNbrKey))
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
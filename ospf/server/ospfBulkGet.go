@@ -27,6 +27,9 @@ import (
 	"fmt"
 	"l3/ospf/config"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 func (server *OSPFServer) GetBulkOspfAreaEntryState(idx int, cnt int) (int, int, []config.AreaState) {
@@ -49,7 +52,9 @@ func (server *OSPFServer) GetBulkOspfAreaEntryState(idx int, cnt int) (int, int,
 	for i := 0; i < count; i++ {
 		key := server.AreaStateSlice[idx+i]
 		result[i].AreaId = key.AreaId
+		server.AreaStateMutex.RLock()
 		ent, exist := server.AreaStateMap[key]
+		server.AreaStateMutex.RUnlock()
 		if exist {
 			result[i].SpfRuns = ent.SpfRuns
 			result[i].AreaBdrRtrCount = ent.AreaBdrRtrCount
@@ -58,6 +63,13 @@ func (server *OSPFServer) GetBulkOspfAreaEntryState(idx int, cnt int) (int, int,
 			result[i].AreaLsaCksumSum = ent.AreaLsaCksumSum
 			result[i].AreaNssaTranslatorState = ent.AreaNssaTranslatorState
 			result[i].AreaNssaTranslatorEvents = ent.AreaNssaTranslatorEvents
+			result[i].SpfPartialRuns = ent.SpfPartialRuns
+			result[i].SpfHistory = ent.SpfHistory
+			result[i].LfaPrefixes = ent.LfaPrefixes
+			result[i].LfaProtected = ent.LfaProtected
+			result[i].LfaNodeProtected = ent.LfaNodeProtected
+			result[i].LfaRemoteProtected = ent.LfaRemoteProtected
+			result[i].LfaPQNodes = ent.LfaPQNodes
 		} else {
 			result[i].SpfRuns = -1
 			result[i].AreaBdrRtrCount = -1
@@ -66,6 +78,12 @@ func (server *OSPFServer) GetBulkOspfAreaEntryState(idx int, cnt int) (int, int,
 			result[i].AreaLsaCksumSum = -1
 			result[i].AreaNssaTranslatorState = -1
 			result[i].AreaNssaTranslatorEvents = -1
+			result[i].SpfPartialRuns = -1
+			result[i].LfaPrefixes = -1
+			result[i].LfaProtected = -1
+			result[i].LfaNodeProtected = -1
+			result[i].LfaRemoteProtected = -1
+			result[i].LfaPQNodes = -1
 		}
 
 	}
@@ -144,6 +162,21 @@ func (server *OSPFServer) GetBulkOspfLsdbEntryState(idx int, cnt int) (int, int,
 			}
 			lsaEnc = encodeASExternalLsa(lsa, lsaKey)
 			lsaMd = lsa.LsaMd
+		} else if lsdbSliceEnt.LSType == NSSAExternalLSA {
+			lsa, exist := lsDbEnt.NSSAExternalLsaMap[lsaKey]
+			if !exist {
+				continue
+			}
+			lsaEnc = encodeASExternalLsa(lsa, lsaKey)
+			lsaMd = lsa.LsaMd
+		} else if lsdbSliceEnt.LSType == OpaqueAreaLSA ||
+			lsdbSliceEnt.LSType == OpaqueASLSA {
+			lsa, exist := getOpaqueLsaMap(lsDbEnt, lsaKey.LSType)[lsaKey]
+			if !exist {
+				continue
+			}
+			lsaEnc = encodeOpaqueLsa(lsa, lsaKey)
+			lsaMd = lsa.LsaMd
 		}
 
 		server.logger.Info(fmt.Sprintln(lsaEnc))
@@ -215,6 +248,7 @@ func (server *OSPFServer) GetBulkOspfIfEntryState(idx int, cnt int) (int, int, [
 			result[i].IfDesignatedRouterId = "0.0.0.0"
 			result[i].IfBackupDesignatedRouterId = "0.0.0.0"
 		}
+		server.getIntfAuthState(key, &result[i])
 	}
 
 	server.IntfStateTimer.Reset(server.RefreshDuration)
@@ -235,13 +269,26 @@ func (server *OSPFServer) GetOspfGlobalState() *config.GlobalState {
 	result.OriginateNewLsas = ent.OriginateNewLsas
 	result.RxNewLsas = ent.RxNewLsas
 	result.OpaqueLsaSupport = ent.OpaqueLsaSupport
+	server.GraceRestartMutex.RLock()
 	result.RestartStatus = ent.RestartStatus
 	result.RestartAge = ent.RestartAge
+	if ent.RestartStatus != config.NotRestarting {
+		record := server.GraceRestart.Record
+		result.RestartAge = getGraceRemaining(record.StartTime, record.GracePeriod)
+	}
 	result.RestartExitReason = ent.RestartExitReason
+	server.GraceRestartMutex.RUnlock()
 	result.AsLsaCount = ent.AsLsaCount
 	result.AsLsaCksumSum = ent.AsLsaCksumSum
 	result.StubRouterSupport = ent.StubRouterSupport
 	result.DiscontinuityTime = ent.DiscontinuityTime
+	server.SpfThrottleMutex.RLock()
+	result.SpfThrottleState = SpfDelayStateList[server.SpfThrottle.State]
+	result.SpfEvents = server.SpfThrottle.Events
+	result.SpfDeferredEvents = server.SpfThrottle.DeferredEvents
+	result.SpfRunsTotal = server.SpfThrottle.Runs
+	server.SpfThrottleMutex.RUnlock()
+	server.getStubRouterState(result)
 	server.logger.Info(fmt.Sprintln("Global State:", result))
 	return result
 }
@@ -280,12 +327,14 @@ func (server *OSPFServer) GetBulkOspfNbrEntryState(idx int, cnt int) (int, int,
 			result[i].NbrPriority = uint8(ent.OspfRtrPrio)
 			result[i].NbrState = config.NbrStateList[int(ent.OspfNbrState)%NbrStateLen]
 			result[i].NbrEvents = int(ent.nbrEvent)
-			result[i].NbrLsRetransQLen = 0
-			result[i].NbmaNbrPermanence = 0
+			result[i].NbrLsRetransQLen = server.getNbrRetxQLen(key)
+			result[i].NbmaNbrPermanence = int(config.DynamicNbr)
+			if server.isNbmaNbrConfigured(key) {
+				result[i].NbmaNbrPermanence = int(config.PermanentNbr)
+			}
 			result[i].NbrHelloSuppressed = false
-			result[i].NbrRestartHelperStatus = 0
-			result[i].NbrRestartHelperAge = 0
-			result[i].NbrRestartHelperExitReason = 0
+			server.getNbrRestartHelperState(key, &result[i])
+			result[i].NbrBfdState = server.getNbrBfdState(key)
 		}
 
 	}
@@ -294,3 +343,186 @@ func (server *OSPFServer) GetBulkOspfNbrEntryState(idx int, cnt int) (int, int,
 	server.logger.Info(fmt.Sprintln("length:", length, "count:", count, "nextIdx:", nextIdx, "result:", result))
 	return nextIdx, count, result
 }
+
+func (server *OSPFServer) GetBulkOspfIfStatsState(idx int, cnt int) (int, int, []config.IfStatsState) {
+	keys := []IntfConfKey{}
+	for key, _ := range server.IntfConfMap {
+		keys = append(keys, key)
+	}
+	sort.Sort(intfConfKeyList(keys))
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(keys))
+	result := make([]config.IfStatsState, count)
+	for i := 0; i < count; i++ {
+		result[i] = server.getIntfStatsState(keys[idx+i])
+	}
+	return nextIdx, count, result
+}
+
+func (server *OSPFServer) GetBulkOspfNbrStatsState(idx int, cnt int) (int, int, []config.NbrStatsState) {
+	keys := []NeighborConfKey{}
+	for key, _ := range server.NeighborConfigMap {
+		keys = append(keys, key)
+	}
+	sort.Sort(nbrConfKeyList(keys))
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(keys))
+	result := make([]config.NbrStatsState, count)
+	for i := 0; i < count; i++ {
+		result[i] = server.getNbrStatsState(keys[idx+i])
+	}
+	return nextIdx, count, result
+}
+
+func (server *OSPFServer) GetBulkOspfSrcDropStatsState(idx int, cnt int) (int, int, []config.SrcDropStatsState) {
+	srcDropStats := server.getSrcDropStatsState()
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(srcDropStats))
+	result := make([]config.SrcDropStatsState, count)
+	for i := 0; i < count; i++ {
+		result[i] = srcDropStats[idx+i]
+	}
+	return nextIdx, count, result
+}
+
+func (server *OSPFServer) GetBulkOspfNbrEventState(idx int, cnt int) (int, int, []config.NbrEventState) {
+	server.StatsMutex.RLock()
+	defer server.StatsMutex.RUnlock()
+	NbrStateLen := len(config.NbrStateList)
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(server.NbrEventLog))
+	result := make([]config.NbrEventState, count)
+	for i := 0; i < count; i++ {
+		record := server.NbrEventLog[idx+i]
+		result[i] = config.NbrEventState{
+			EventIdx:            record.EventIdx,
+			TimeStamp:           record.Time.String(),
+			NbrIpAddress:        record.NbrKey.IPAddr,
+			NbrAddressLessIndex: int(record.NbrKey.IntfIdx),
+			NbrRtrId:            convertUint32ToIPv4(record.NbrRtrId),
+			OldState:            config.NbrStateList[int(record.OldState)%NbrStateLen],
+			NewState:            config.NbrStateList[int(record.NewState)%NbrStateLen],
+			Reason:              record.Reason,
+			NbrLsRetransQLen:    record.LsRetransQLen,
+		}
+	}
+	return nextIdx, count, result
+}
+
+func (server *OSPFServer) sortedOspfv3Instances() []*Ospfv3Instance {
+	server.Ospfv3Mutex.RLock()
+	defer server.Ospfv3Mutex.RUnlock()
+	var instanceIds []int
+	for instanceId, _ := range server.Ospfv3InstanceMap {
+		instanceIds = append(instanceIds, int(instanceId))
+	}
+	sort.Ints(instanceIds)
+	insts := make([]*Ospfv3Instance, 0, len(instanceIds))
+	for _, instanceId := range instanceIds {
+		insts = append(insts, server.Ospfv3InstanceMap[uint8(instanceId)])
+	}
+	return insts
+}
+
+func getBulkOspfv3Range(idx int, cnt int, length int) (int, int) {
+	if idx >= length {
+		return 0, 0
+	}
+	if idx+cnt >= length {
+		return 0, length - idx
+	}
+	return idx + cnt, cnt
+}
+
+func (server *OSPFServer) GetBulkOspfv3IntfState(idx int, cnt int) (int, int, []config.Ospfv3IntfState) {
+	result := make([]config.Ospfv3IntfState, 0)
+	for _, inst := range server.sortedOspfv3Instances() {
+		inst.RLock()
+		for _, intf := range inst.sortedOspfv3Intfs() {
+			state := config.Ospfv3IntfState{
+				IfIndex:            intf.IfIndex,
+				InstanceId:         inst.InstanceId,
+				IfName:             intf.IfName,
+				IfAreaId:           config.AreaId(convertUint32ToIPv4(intf.AreaId)),
+				IfState:            intf.State,
+				IfDesignatedRouter: config.RouterId(convertUint32ToIPv4(intf.DRtrId)),
+				IfBackupRouter:     config.RouterId(convertUint32ToIPv4(intf.BDRtrId)),
+				IfNbrCount:         int32(len(intf.NbrMap)),
+			}
+			if intf.LinkLocal != nil {
+				state.IfLinkLocalAddress = intf.LinkLocal.String()
+			}
+			for _, nbr := range intf.NbrMap {
+				if nbr.State == config.NbrFull {
+					state.IfAdjacentNbrCount++
+				}
+			}
+			result = append(result, state)
+		}
+		inst.RUnlock()
+	}
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(result))
+	if count == 0 {
+		return 0, 0, result[:0]
+	}
+	return nextIdx, count, result[idx : idx+count]
+}
+
+func (server *OSPFServer) GetBulkOspfv3NbrState(idx int, cnt int) (int, int, []config.Ospfv3NbrState) {
+	result := make([]config.Ospfv3NbrState, 0)
+	NbrStateLen := len(config.NbrStateList)
+	for _, inst := range server.sortedOspfv3Instances() {
+		inst.RLock()
+		for _, intf := range inst.sortedOspfv3Intfs() {
+			for _, nbr := range sortedOspfv3Nbrs(intf) {
+				state := config.Ospfv3NbrState{
+					InstanceId:     inst.InstanceId,
+					IfIndex:        intf.IfIndex,
+					NbrRouterId:    config.RouterId(convertUint32ToIPv4(nbr.RouterId)),
+					NbrInterfaceId: nbr.InterfaceId,
+					NbrPriority:    nbr.RtrPrio,
+					NbrState:       config.NbrStateList[int(nbr.State)%NbrStateLen],
+				}
+				if nbr.Addr != nil {
+					state.NbrAddress = nbr.Addr.String()
+				}
+				result = append(result, state)
+			}
+		}
+		inst.RUnlock()
+	}
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(result))
+	if count == 0 {
+		return 0, 0, result[:0]
+	}
+	return nextIdx, count, result[idx : idx+count]
+}
+
+func (server *OSPFServer) GetBulkOspfv3RouteState(idx int, cnt int) (int, int, []config.Ospfv3RouteState) {
+	result := make([]config.Ospfv3RouteState, 0)
+	for _, inst := range server.sortedOspfv3Instances() {
+		inst.RLock()
+		var keys []string
+		for key, _ := range inst.RouteTbl {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			route := inst.RouteTbl[key]
+			var nextHops []string
+			for _, nextHop := range route.NextHops {
+				nextHops = append(nextHops, nextHop.NextHopIp+"%"+strconv.Itoa(int(nextHop.IfIndex)))
+			}
+			result = append(result, config.Ospfv3RouteState{
+				InstanceId:    inst.InstanceId,
+				DestinationNw: key,
+				AreaId:        config.AreaId(convertUint32ToIPv4(route.AreaId)),
+				PathType:      Ospfv3PathTypeList[int(route.PathType)%len(Ospfv3PathTypeList)],
+				Cost:          route.Cost,
+				NextHops:      strings.Join(nextHops, ","),
+			})
+		}
+		inst.RUnlock()
+	}
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(result))
+	if count == 0 {
+		return 0, 0, result[:0]
+	}
+	return nextIdx, count, result[idx : idx+count]
+}
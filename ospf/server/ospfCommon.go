@@ -130,7 +130,7 @@ const (
 	MCOption = 0x04
 	NPOption = 0x08
 	EAOption = 0x20
-	DCOption = 0x40
+	OOption  = 0x40 // RFC 5250 opaque capable
 )
 
 type IntfTxHandle struct {
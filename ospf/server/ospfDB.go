@@ -330,6 +330,67 @@ func (server *OSPFServer) DelIPv4RoutesState(entry RoutingTblEntryKey) error {
 	return nil
 }
 
+/*@fn readIPv4RoutesStateFromDB
+Routes stored by the previous ospfd instance. They are kept as stale routes
+during a graceful restart.
+*/
+func (server *OSPFServer) readIPv4RoutesStateFromDB() map[RoutingTblEntryKey]GlobalRoutingTblEntry {
+	routes := make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
+	var dbObj objects.OspfIPv4RouteState
+	if server.dbHdl == nil {
+		server.logger.Err("Null db handle. No routes read from db.")
+		return routes
+	}
+	objList, err := server.dbHdl.GetAllObjFromDb(dbObj)
+	if err != nil {
+		server.logger.Err("DB query failed for OspfIPv4RouteState")
+		return routes
+	}
+	for idx := 0; idx < len(objList); idx++ {
+		obj := ospfd.NewOspfIPv4RouteState()
+		dbObject := objList[idx].(objects.OspfIPv4RouteState)
+		objects.ConvertospfdOspfIPv4RouteStateObjToThrift(&dbObject, obj)
+		key := RoutingTblEntryKey{
+			DestId:   convertAreaOrRouterIdUint32(obj.DestId),
+			AddrMask: convertAreaOrRouterIdUint32(obj.AddrMask),
+			DestType: DestType(decodeDbRune(obj.DestType)),
+		}
+		ent := GlobalRoutingTblEntry{
+			AreaId: convertAreaOrRouterIdUint32(obj.AreaId),
+		}
+		ent.RoutingTblEnt.OptCapabilities = uint8(obj.OptCapabilities)
+		ent.RoutingTblEnt.PathType = PathType(decodeDbRune(obj.PathType))
+		ent.RoutingTblEnt.Cost = uint16(obj.Cost)
+		ent.RoutingTblEnt.Type2Cost = uint16(obj.Type2Cost)
+		ent.RoutingTblEnt.NumOfPaths = int(obj.NumOfPaths)
+		ent.RoutingTblEnt.NextHops = make(map[NextHop]bool)
+		for _, nh := range obj.NextHops {
+			nextHop := NextHop{
+				IfIPAddr:  convertAreaOrRouterIdUint32(nh.IfIPAddr),
+				IfIdx:     uint32(nh.IfIdx),
+				NextHopIP: convertAreaOrRouterIdUint32(nh.NextHopIP),
+				AdvRtr:    convertAreaOrRouterIdUint32(nh.AdvRtr),
+			}
+			ent.RoutingTblEnt.NextHops[nextHop] = true
+		}
+		if obj.LSOrigin != nil {
+			ent.RoutingTblEnt.LSOrigin.LSType = uint8(obj.LSOrigin.LSType)
+			ent.RoutingTblEnt.LSOrigin.LSId = uint32(obj.LSOrigin.LSId)
+			ent.RoutingTblEnt.LSOrigin.AdvRouter = uint32(obj.LSOrigin.AdvRouter)
+		}
+		routes[key] = ent
+	}
+	return routes
+}
+
+/* DestType and PathType are stored as a single character */
+func decodeDbRune(str string) int {
+	for _, r := range str {
+		return int(r)
+	}
+	return 0
+}
+
 func (server *OSPFServer) AddLsdbEntry(entry LsdbSliceEnt) error {
 	server.logger.Info(fmt.Sprintln("DB: Add lsdb entry. ", entry))
 	var lsaEnc []byte
@@ -387,6 +448,20 @@ func (server *OSPFServer) AddLsdbEntry(entry LsdbSliceEnt) error {
 		}
 		lsaEnc = encodeASExternalLsa(lsa, lsaKey)
 		lsaMd = lsa.LsaMd
+	} else if entry.LSType == NSSAExternalLSA {
+		lsa, exist := lsDbEnt.NSSAExternalLsaMap[lsaKey]
+		if !exist {
+			return nil
+		}
+		lsaEnc = encodeASExternalLsa(lsa, lsaKey)
+		lsaMd = lsa.LsaMd
+	} else if entry.LSType == OpaqueAreaLSA || entry.LSType == OpaqueASLSA {
+		lsa, exist := getOpaqueLsaMap(lsDbEnt, lsaKey.LSType)[lsaKey]
+		if !exist {
+			return nil
+		}
+		lsaEnc = encodeOpaqueLsa(lsa, lsaKey)
+		lsaMd = lsa.LsaMd
 	}
 	adv := convertByteToOctetString(lsaEnc[OSPF_LSA_HEADER_SIZE:])
 
@@ -25,6 +25,7 @@ package server
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -286,9 +287,10 @@ func (server *OSPFServer) BuildDBDPkt(intfKey IntfConfKey, ent IntfConf,
 
 	ospf := append(ospfEncHdr, dbdDataEnc...)
 	//server.logger.Info(fmt.Sprintln("OSPF DBD:", ospf))
-	csum := computeCheckSum(ospf)
-	binary.BigEndian.PutUint16(ospf[12:14], csum)
-	copy(ospf[16:24], ent.IfAuthKey)
+	ospf = server.encodeOspfAuth(intfKey, ent, ospf)
+	if ospf == nil {
+		return nil
+	}
 
 	var DstIP net.IP
 	var DstMAC net.HardwareAddr
@@ -348,6 +350,16 @@ func (server *OSPFServer) ProcessRxDbdPkt(data []byte, ospfHdrMd *OspfHdrMetadat
 	}
 
 	DecodeDatabaseDescriptionData(data, ospfdbd_data, pktlen)
+	/* RFC 2328 10.6 reject the DD packet if the neighbor's MTU is
+	   larger than the interface can receive, the MTU is 0 on virtual links */
+	intf, _ := server.IntfConfMap[key]
+	if intf.IfType != config.VirtualLink && intf.IfMtu != 0 &&
+		ospfdbd_data.interface_mtu != 0 && int32(ospfdbd_data.interface_mtu) > intf.IfMtu {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropMtuMismatch)
+		err := errors.New(fmt.Sprintln("DBD: MTU mismatch, neighbor MTU", ospfdbd_data.interface_mtu,
+			"interface MTU", intf.IfMtu))
+		return err
+	}
 	//ipaddr := convertIPInByteToString(ipHdrMd.srcIP)
 	ipaddr := net.IPv4(ipHdrMd.srcIP[0], ipHdrMd.srcIP[1], ipHdrMd.srcIP[2], ipHdrMd.srcIP[3])
 
@@ -396,6 +408,10 @@ func (server *OSPFServer) ConstructAndSendDbdPacket(nbrKey NeighborConfKey,
 	server.logger.Debug(fmt.Sprintln("DBD: MTU ", ifMtu))
 	dbd_mdata.interface_mtu = uint16(ifMtu)
 	dbd_mdata.options = options
+	if intf, ok := server.IntfConfMap[nbrCon.intfConfKey]; ok && intf.IfAreaId != nil {
+		areaId := config.AreaId(convertIPInByteToString(intf.IfAreaId))
+		dbd_mdata.options = (options &^ (EOption | NPOption | OOption)) | server.getPktOptions(areaId)
+	}
 	dbd_mdata.dd_sequence_number = seq
 
 	lsa_count_done := 0
@@ -126,6 +126,23 @@ func (server *OSPFServer) SendSelfOrigLSA(areaId uint32, intfKey IntfConfKey) []
 				ospfLsaPkt.no_lsas++
 				total_len += pktLen
 
+			case NSSAExternalLSA:
+				entry, ret := server.getNssaExternalLsaFromLsdb(areaId, key)
+				if ret == LsdbEntryNotFound {
+					continue
+				}
+				LsaEnc = encodeASExternalLsa(entry, key)
+				checksumOffset := uint16(14)
+				checkSum := computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+				binary.BigEndian.PutUint16(LsaEnc[16:18], checkSum)
+				pktLen = len(LsaEnc)
+				binary.BigEndian.PutUint16(LsaEnc[18:20], uint16(pktLen))
+				lsaid := convertUint32ToIPv4(key.LSId)
+				server.logger.Info(fmt.Sprintln("Flood: NSSA external LSA = ", lsaid))
+				ospfLsaPkt.lsa = append(ospfLsaPkt.lsa, LsaEnc...)
+				ospfLsaPkt.no_lsas++
+				total_len += pktLen
+
 			} // end of case
 		}
 	}
@@ -187,10 +204,9 @@ func (server *OSPFServer) processFloodMsg(lsa_data ospfFloodMsg) {
 		rxIntf := server.IntfConfMap[nbrConf.intfConfKey]
 		lsid := convertUint32ToIPv4(lsa_data.linkid)
 		server.logger.Info(fmt.Sprintln("LSASELFLOOD: Received lsid ", lsid, " lstype ", lsa_data.lsType))
-		var lsaEncPkt []byte
 		for key, intf := range server.IntfConfMap {
-			areaid := convertIPv4ToUint32(intf.IfAreaId)
-			if intf.IfIpAddr.Equal(rxIntf.IfIpAddr) || lsa_data.areaId != areaid {
+			if intf.IfIpAddr.Equal(rxIntf.IfIpAddr) ||
+				!server.isFloodScopeIntf(lsa_data.lsType, lsa_data.areaId, key, intf) {
 				server.logger.Info(fmt.Sprintln("LSASELFLOOD:Dont flood on rx intf ", rxIntf.IfIpAddr))
 				continue // dont flood the LSA on the interface it is received.
 			}
@@ -198,6 +214,7 @@ func (server *OSPFServer) processFloodMsg(lsa_data ospfFloodMsg) {
 			if send {
 				if lsa_data.pkt != nil {
 					server.logger.Info(fmt.Sprintln("LSASELFLOOD: Unicast LSA interface ", intf.IfIpAddr, " lsid ", lsid, " lstype ", lsa_data.lsType))
+					var lsaEncPkt []byte
 					lsas_enc := make([]byte, 4)
 					var no_lsa uint32
 					no_lsa = 1
@@ -262,12 +279,44 @@ func (server *OSPFServer) processFloodMsg(lsa_data ospfFloodMsg) {
 		server.logger.Info(fmt.Sprintln("LSAEXTFLOOD: Flood external routes for lsa key ", lsa_data.lsaKey))
 		server.processAsExternalLSAFlood(lsa_data.lsaKey)
 
+	case LSANSSAFLOOD: //flood NSSA External LSA
+		server.logger.Info(fmt.Sprintln("LSANSSAFLOOD: Flood NSSA external routes for lsa key ", lsa_data.lsaKey,
+			" area ", lsa_data.areaId))
+		server.processNssaExternalLSAFlood(lsa_data.areaId, lsa_data.lsaKey)
+
+	case LSAOPAQUEFLOOD: //flood self originated opaque LSA
+		server.logger.Info(fmt.Sprintln("LSAOPAQUEFLOOD: Flood opaque LSA for lsa key ", lsa_data.lsaKey,
+			" area ", lsa_data.areaId))
+		server.processOpaqueLSAFlood(lsa_data.areaId, lsa_data.lsaKey)
+
 	case LSAAGE: // Flood aged LSAs
 		server.constructAndSendLsaAgeFlood()
 
 	}
 }
 
+/* @fn isFloodScopeIntf
+Link scope opaque LSAs are never reflooded, AS scope LSAs are flooded
+on all interfaces except virtual links and interfaces in stub areas,
+all the other LSAs within their area. Opaque LSAs are only sent on
+interfaces with an opaque capable neighbor.
+*/
+func (server *OSPFServer) isFloodScopeIntf(lsType uint8, areaId uint32,
+	key IntfConfKey, intf IntfConf) bool {
+	switch lsType {
+	case OpaqueLinkLSA:
+		return false
+	case OpaqueASLSA:
+		return !isVirtualIntfKey(key) &&
+			server.isAsScopeArea(convertIPv4ToUint32(intf.IfAreaId)) &&
+			server.intfHasOpaqueNbr(key)
+	case OpaqueAreaLSA:
+		return convertIPv4ToUint32(intf.IfAreaId) == areaId &&
+			server.intfHasOpaqueNbr(key)
+	}
+	return convertIPv4ToUint32(intf.IfAreaId) == areaId
+}
+
 /*@fn sendRouterLsa
 At the event of interface down need to flood
 updated router LSA.
@@ -373,6 +422,9 @@ func (server *OSPFServer) nbrFloodCheck(nbrKey NeighborConfKey, key IntfConfKey,
 		server.logger.Info(fmt.Sprintln("IF FLOOD: Nbr is DR/BDR.   flood on this interface . nbr - ", nbrKey.IPAddr, nbrConf.OspfNbrIPAddr))
 		return false
 	}
+	if isVirtualIntfKey(key) && lsType == ASExternalLSA {
+		return false
+	}
 	flood_check = server.interfaceFloodCheck(key)
 	return flood_check
 }
@@ -476,7 +528,13 @@ func (server *OSPFServer) floodSummaryLsa(pkt []byte, areaid uint32) {
 func (server *OSPFServer) processAsExternalLSAFlood(lsakey LsaKey) {
 	areaId := convertAreaOrRouterIdUint32("0.0.0.0")
 	for ent, _ := range server.AreaConfMap {
+		if server.isStubArea(ent.AreaId) || server.isNssaArea(ent.AreaId) {
+			continue
+		}
 		areaId = convertAreaOrRouterIdUint32(string(ent.AreaId))
+		if _, ret := server.getASExternalLsaFromLsdb(areaId, lsakey); ret == LsdbEntryFound {
+			break
+		}
 	}
 	var lsaEncPkt []byte
 	LsaEnc := []byte{}
@@ -516,8 +574,12 @@ func (server *OSPFServer) floodASExternalLsa(pkt []byte) {
 		}
 		areaId := config.AreaId(convertIPInByteToString(intf.IfAreaId))
 		isStub := server.isStubArea(areaId)
-		if isStub {
-			server.logger.Info(fmt.Sprintln("ASBR: Dont flood AS external as area is stub ", areaId))
+		if isStub || server.isNssaArea(areaId) {
+			server.logger.Info(fmt.Sprintln("ASBR: Dont flood AS external as area is stub or NSSA ", areaId))
+			continue
+		}
+		if isVirtualIntfKey(key) {
+			// RFC 2328 13.3: AS external LSAs are never flooded over virtual links
 			continue
 		}
 		nbrMdata, ok := ospfIntfToNbrMap[key]
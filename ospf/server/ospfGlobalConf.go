@@ -42,6 +42,7 @@ type GlobalConf struct {
 	RestartSupport           config.RestartSupport
 	RestartInterval          int32
 	RestartStrictLsaChecking bool
+	RestartHelperSupport     config.RestartSupport
 	StubRouterAdvertisement  config.AdvertiseAction
 	Version                  uint8
 	AreaBdrRtrStatus         bool
@@ -95,6 +96,7 @@ func (server *OSPFServer) initOspfGlobalConfDefault() {
 	server.ospfGlobalConf.RestartSupport = config.None
 	server.ospfGlobalConf.RestartInterval = 0
 	server.ospfGlobalConf.RestartStrictLsaChecking = false
+	server.ospfGlobalConf.RestartHelperSupport = config.None
 	server.ospfGlobalConf.StubRouterAdvertisement = config.DoNotAdvertise
 	server.ospfGlobalConf.Version = uint8(OSPF_VERSION_2)
 	server.ospfGlobalConf.AreaBdrRtrStatus = false
@@ -108,7 +110,7 @@ func (server *OSPFServer) initOspfGlobalConfDefault() {
 	server.ospfGlobalConf.RestartExitReason = config.NoAttempt
 	server.ospfGlobalConf.AsLsaCount = 0
 	server.ospfGlobalConf.AsLsaCksumSum = 0
-	server.ospfGlobalConf.StubRouterSupport = false
+	server.ospfGlobalConf.StubRouterSupport = true
 	//server.ospfGlobalConf.DiscontinuityTime = "0"
 	server.ospfGlobalConf.DiscontinuityTime = 0 //This should be string
 	server.ospfGlobalConf.isABR = false
@@ -151,6 +153,7 @@ func (server *OSPFServer) processGlobalConfig(gConf config.GlobalConf) error {
 		go server.ProcessNbrStateMachine()
 		go server.ProcessTxNbrPkt()
 		go server.ProcessRxNbrPkt()
+		server.checkGraceRestart()
 		server.StartLSDatabase()
 
 	}
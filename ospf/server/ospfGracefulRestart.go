@@ -0,0 +1,929 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"l3/ospf/config"
+	"net"
+	"os"
+	"time"
+)
+
+/* Graceful restart (RFC 3623).
+   Restarting router : grace LSAs are sent before a planned restart and the
+   restart state is kept in GRACE_RESTART_FILE. After the restart the routes
+   in ribd are left alone and no router/network LSAs are originated until
+   all pre-restart adjacencies are full again or the grace period expires.
+   Helper : a neighbor which sent a grace LSA is kept fully adjacent until
+   it flushes the grace LSA, the grace period expires or the topology
+   changes (strict LSA checking).
+*/
+
+const (
+	GraceLsaOpaqueType uint8 = 3
+
+	GRACE_TLV_PERIOD  uint16 = 1
+	GRACE_TLV_REASON  uint16 = 2
+	GRACE_TLV_IP_ADDR uint16 = 3
+
+	GRACE_LSA_LEN = OSPF_LSA_HEADER_SIZE + 24
+
+	DEFAULT_RESTART_INTERVAL int32 = 120
+	MAX_RESTART_INTERVAL     int32 = 1800
+
+	GRACE_RESTART_FILE = "/var/run/ospfd_grace_restart.json"
+)
+
+/* Restart reason TLV values */
+const (
+	GraceReasonUnknown         uint8 = 0
+	GraceReasonSoftwareRestart uint8 = 1
+	GraceReasonSoftwareUpgrade uint8 = 2
+	GraceReasonSwitchover      uint8 = 3
+)
+
+/* LS Type 9, opaque type 3 */
+type GraceLsa struct {
+	LsaMd       LsaMetadata
+	GracePeriod uint32 /* seconds */
+	Reason      uint8
+	IntfIpAddr  uint32
+}
+
+/* Restart state kept across the restart of ospfd */
+type GraceRestartRecord struct {
+	Status      config.RestartStatus
+	Reason      uint8
+	StartTime   time.Time
+	GracePeriod int32
+}
+
+type GraceRestarter struct {
+	Record      GraceRestartRecord
+	Active      bool // ospfd came up within the grace period
+	Checked     bool // restart record looked up after startup
+	GraceTimer  *time.Timer
+	FileName    string
+	StaleRoutes map[RoutingTblEntryKey]GlobalRoutingTblEntry
+}
+
+type GraceHelperEnt struct {
+	NbrRtrId    uint32
+	AreaId      uint32
+	Reason      uint8
+	GracePeriod uint32
+	StartTime   time.Time
+	GraceTimer  *time.Timer
+}
+
+type GraceHelperExitMsg struct {
+	nbrKey NeighborConfKey
+	reason config.RestartExitReason
+}
+
+func DefaultGracefulRestartConf() config.GracefulRestartConf {
+	return config.GracefulRestartConf{
+		HelperSupport:     config.None,
+		StrictLsaChecking: false,
+	}
+}
+
+func (server *OSPFServer) initGracefulRestart() {
+	server.GracefulRestartConfigCh = make(chan config.GracefulRestartConf)
+	server.GraceRestartPrepareCh = make(chan uint8)
+	server.GraceHelperExitCh = make(chan GraceHelperExitMsg)
+	server.GraceRestart = GraceRestarter{
+		FileName:   GRACE_RESTART_FILE,
+		GraceTimer: newStoppedTimer(),
+	}
+	server.GraceHelperMap = make(map[NeighborConfKey]GraceHelperEnt)
+	server.GraceHelperExitMap = make(map[NeighborConfKey]config.RestartExitReason)
+}
+
+func getGraceLsaKey(rtrId uint32) LsaKey {
+	return LsaKey{
+		LSType:    OpaqueLinkLSA,
+		LSId:      uint32(GraceLsaOpaqueType) << 24,
+		AdvRouter: rtrId,
+	}
+}
+
+func isGraceLsaKey(lsakey LsaKey) bool {
+	return lsakey.LSType == OpaqueLinkLSA &&
+		uint8(lsakey.LSId>>24) == GraceLsaOpaqueType
+}
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |            LS age             |     Options   |       9       |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |       3       |                    Opaque ID                  |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                     Advertising Router                        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                     LS sequence number                        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |         LS checksum           |             length            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |              Type             |             Length            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                            Value...                           |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+
+func encodeGraceTlv(data []byte, tlvType uint16, tlvLen uint16) []byte {
+	binary.BigEndian.PutUint16(data[0:2], tlvType)
+	binary.BigEndian.PutUint16(data[2:4], tlvLen)
+	return data[4:]
+}
+
+func encodeGraceLsa(lsa GraceLsa, lsakey LsaKey) []byte {
+	graceLsa := make([]byte, GRACE_LSA_LEN)
+	lsaHdr := encodeLsaHeader(lsa.LsaMd, lsakey)
+	copy(graceLsa[0:OSPF_LSA_HEADER_SIZE], lsaHdr)
+	start := OSPF_LSA_HEADER_SIZE
+	val := encodeGraceTlv(graceLsa[start:start+8], GRACE_TLV_PERIOD, 4)
+	binary.BigEndian.PutUint32(val, lsa.GracePeriod)
+	start += 8
+	val = encodeGraceTlv(graceLsa[start:start+8], GRACE_TLV_REASON, 1)
+	val[0] = lsa.Reason
+	start += 8
+	val = encodeGraceTlv(graceLsa[start:start+8], GRACE_TLV_IP_ADDR, 4)
+	binary.BigEndian.PutUint32(val, lsa.IntfIpAddr)
+	return graceLsa
+}
+
+func decodeGraceLsa(data []byte, lsa *GraceLsa, lsakey *LsaKey) error {
+	if len(data) < OSPF_LSA_HEADER_SIZE {
+		return errors.New("Grace LSA shorter than LSA header")
+	}
+	lsa.LsaMd.LSAge = binary.BigEndian.Uint16(data[0:2])
+	lsa.LsaMd.Options = uint8(data[2])
+	lsakey.LSType = uint8(data[3])
+	lsakey.LSId = binary.BigEndian.Uint32(data[4:8])
+	lsakey.AdvRouter = binary.BigEndian.Uint32(data[8:12])
+	lsa.LsaMd.LSSequenceNum = int(binary.BigEndian.Uint32(data[12:16]))
+	lsa.LsaMd.LSChecksum = binary.BigEndian.Uint16(data[16:18])
+	lsa.LsaMd.LSLen = binary.BigEndian.Uint16(data[18:20])
+	end := int(lsa.LsaMd.LSLen)
+	if end > len(data) {
+		return errors.New(fmt.Sprintln("Grace LSA truncated, length", end, "received", len(data)))
+	}
+	lsa.GracePeriod = 0
+	start := OSPF_LSA_HEADER_SIZE
+	for start+4 <= end {
+		tlvType := binary.BigEndian.Uint16(data[start : start+2])
+		tlvLen := int(binary.BigEndian.Uint16(data[start+2 : start+4]))
+		start += 4
+		if start+tlvLen > end {
+			return errors.New(fmt.Sprintln("Grace LSA TLV", tlvType, "exceeds the LSA"))
+		}
+		switch tlvType {
+		case GRACE_TLV_PERIOD:
+			if tlvLen == 4 {
+				lsa.GracePeriod = binary.BigEndian.Uint32(data[start : start+4])
+			}
+		case GRACE_TLV_REASON:
+			if tlvLen == 1 {
+				lsa.Reason = data[start]
+			}
+		case GRACE_TLV_IP_ADDR:
+			if tlvLen == 4 {
+				lsa.IntfIpAddr = binary.BigEndian.Uint32(data[start : start+4])
+			}
+		}
+		// values are padded to 4 bytes
+		start += (tlvLen + 3) &^ 3
+	}
+	if lsa.GracePeriod == 0 {
+		return errors.New("Grace LSA without grace period")
+	}
+	return nil
+}
+
+func isPlannedGraceReason(reason uint8) bool {
+	return reason == GraceReasonSoftwareRestart || reason == GraceReasonSoftwareUpgrade
+}
+
+func (server *OSPFServer) getGracePeriod() int32 {
+	period := server.ospfGlobalConf.RestartInterval
+	if period <= 0 {
+		period = DEFAULT_RESTART_INTERVAL
+	}
+	if period > MAX_RESTART_INTERVAL {
+		period = MAX_RESTART_INTERVAL
+	}
+	return period
+}
+
+func getGraceRemaining(start time.Time, period int32) int32 {
+	elapsed := int32(time.Since(start) / time.Second)
+	if elapsed >= period {
+		return 0
+	}
+	return period - elapsed
+}
+
+/* @fn sendGraceLsa
+Grace LSAs have link local scope, they are sent on the interface only.
+flush sends the LSA with MaxAge to end the grace period.
+*/
+func (server *OSPFServer) sendGraceLsa(key IntfConfKey, flush bool) {
+	ent, exist := server.IntfConfMap[key]
+	if !exist {
+		return
+	}
+	server.GraceRestartMutex.RLock()
+	record := server.GraceRestart.Record
+	server.GraceRestartMutex.RUnlock()
+
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	lsaKey := getGraceLsaKey(rtrId)
+	lsa := GraceLsa{
+		GracePeriod: uint32(record.GracePeriod),
+		Reason:      record.Reason,
+		IntfIpAddr:  convertAreaOrRouterIdUint32(ent.IfIpAddr.String()),
+	}
+	lsa.LsaMd.LSAge = uint16(int32(record.GracePeriod) - getGraceRemaining(record.StartTime, record.GracePeriod))
+	if flush {
+		lsa.LsaMd.LSAge = config.MaxAge
+	}
+	lsa.LsaMd.Options = INTF_OPTIONS
+	lsa.LsaMd.LSSequenceNum = InitialSequenceNumber
+	lsa.LsaMd.LSLen = uint16(GRACE_LSA_LEN)
+	lsaEnc := encodeGraceLsa(lsa, lsaKey)
+	checksumOffset := uint16(14)
+	checkSum := computeFletcherChecksum(lsaEnc[2:], checksumOffset)
+	binary.BigEndian.PutUint16(lsaEnc[16:18], checkSum)
+
+	lsaUpd := make([]byte, OSPF_NO_OF_LSA_FIELD)
+	binary.BigEndian.PutUint32(lsaUpd, 1)
+	lsaUpd = append(lsaUpd, lsaEnc...)
+	dstMac := net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05}
+	dstIp := net.IP{224, 0, 0, 5}
+	pkt := server.BuildLsaUpdPkt(key, ent, dstMac, dstIp, len(lsaUpd), lsaUpd)
+	server.logger.Info(fmt.Sprintln("GR: Send grace LSA on ", ent.IfIpAddr, " flush ", flush))
+	err := server.SendOspfPkt(key, pkt)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("GR: Failed to send grace LSA on ", ent.IfIpAddr, err))
+	}
+}
+
+func (server *OSPFServer) saveGraceRestartRecord(record GraceRestartRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(server.GraceRestart.FileName, data, 0644)
+}
+
+func (server *OSPFServer) readGraceRestartRecord() (GraceRestartRecord, error) {
+	var record GraceRestartRecord
+	data, err := ioutil.ReadFile(server.GraceRestart.FileName)
+	if err != nil {
+		return record, err
+	}
+	err = json.Unmarshal(data, &record)
+	return record, err
+}
+
+func (server *OSPFServer) removeGraceRestartRecord() {
+	err := os.Remove(server.GraceRestart.FileName)
+	if err != nil && !os.IsNotExist(err) {
+		server.logger.Err(fmt.Sprintln("GR: Failed to remove restart state ", err))
+	}
+}
+
+func (server *OSPFServer) isGraceRestarting() bool {
+	server.GraceRestartMutex.RLock()
+	defer server.GraceRestartMutex.RUnlock()
+	return server.GraceRestart.Active
+}
+
+func (server *OSPFServer) ValidateGraceRestartPrepare(reason uint8) error {
+	if server.ospfGlobalConf.RestartSupport != config.PlannedOnly &&
+		server.ospfGlobalConf.RestartSupport != config.PlannedAndUnplanned {
+		return errors.New("Graceful restart is not enabled")
+	}
+	if !isPlannedGraceReason(reason) {
+		return errors.New(fmt.Sprintln("Invalid planned restart reason", reason))
+	}
+	if server.ospfGlobalConf.AdminStat != config.Enabled {
+		return errors.New("Ospf is not enabled")
+	}
+	if server.isGraceRestarting() {
+		return errors.New("Graceful restart in progress")
+	}
+	return nil
+}
+
+/* @fn processGraceRestartPrepare
+Planned restart (RFC 3623 2.1). The restart state is saved and grace LSAs
+are sent to the neighbors. ospfd is expected to be restarted afterwards,
+if it is not the state is cleared when the grace period expires.
+*/
+func (server *OSPFServer) processGraceRestartPrepare(reason uint8) error {
+	record := GraceRestartRecord{
+		Status:      config.PlannedRestart,
+		Reason:      reason,
+		StartTime:   time.Now(),
+		GracePeriod: server.getGracePeriod(),
+	}
+	err := server.saveGraceRestartRecord(record)
+	if err != nil {
+		return err
+	}
+	server.GraceRestartMutex.Lock()
+	server.GraceRestart.Record = record
+	server.ospfGlobalConf.RestartStatus = config.PlannedRestart
+	server.ospfGlobalConf.RestartExitReason = config.InProgress
+	server.GraceRestartMutex.Unlock()
+	resetTimer(server.GraceRestart.GraceTimer, record.GracePeriod*1000)
+
+	for key, _ := range server.IntfConfMap {
+		if server.interfaceFloodCheck(key) {
+			server.sendGraceLsa(key, false)
+		}
+	}
+	server.logger.Info(fmt.Sprintln("GR: Ready for planned restart, grace period ", record.GracePeriod))
+	return nil
+}
+
+/* @fn checkGraceRestart
+Called once Ospf is enabled after startup. A saved planned restart within
+its grace period starts the restart. Without one an unplanned restart is
+assumed when routes of the previous instance are still in the DB.
+*/
+func (server *OSPFServer) checkGraceRestart() {
+	server.GraceRestartMutex.Lock()
+	if server.GraceRestart.Checked {
+		server.GraceRestartMutex.Unlock()
+		return
+	}
+	server.GraceRestart.Checked = true
+	server.GraceRestartMutex.Unlock()
+
+	if server.ospfGlobalConf.RestartSupport != config.PlannedOnly &&
+		server.ospfGlobalConf.RestartSupport != config.PlannedAndUnplanned {
+		server.removeGraceRestartRecord()
+		return
+	}
+	record, err := server.readGraceRestartRecord()
+	if err == nil {
+		if record.Status == config.NotRestarting ||
+			getGraceRemaining(record.StartTime, record.GracePeriod) == 0 {
+			server.logger.Info(fmt.Sprintln("GR: Restart record expired ", record))
+			server.removeGraceRestartRecord()
+			return
+		}
+		server.enterGraceRestart(record, server.readIPv4RoutesStateFromDB())
+		return
+	}
+	if server.ospfGlobalConf.RestartSupport != config.PlannedAndUnplanned {
+		return
+	}
+	staleRoutes := server.readIPv4RoutesStateFromDB()
+	if len(staleRoutes) == 0 {
+		return
+	}
+	record = GraceRestartRecord{
+		Status:      config.UnplannedRestart,
+		Reason:      GraceReasonUnknown,
+		StartTime:   time.Now(),
+		GracePeriod: server.getGracePeriod(),
+	}
+	err = server.saveGraceRestartRecord(record)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("GR: Failed to save restart state ", err))
+	}
+	server.enterGraceRestart(record, staleRoutes)
+}
+
+func (server *OSPFServer) enterGraceRestart(record GraceRestartRecord, staleRoutes map[RoutingTblEntryKey]GlobalRoutingTblEntry) {
+	server.GraceRestartMutex.Lock()
+	server.GraceRestart.Record = record
+	server.GraceRestart.Active = true
+	server.GraceRestart.StaleRoutes = staleRoutes
+	server.ospfGlobalConf.RestartStatus = record.Status
+	server.ospfGlobalConf.RestartExitReason = config.InProgress
+	server.GraceRestartMutex.Unlock()
+	resetTimer(server.GraceRestart.GraceTimer, getGraceRemaining(record.StartTime, record.GracePeriod)*1000)
+	server.logger.Info(fmt.Sprintln("GR: Restarting, status ", record.Status,
+		" stale routes ", len(staleRoutes)))
+}
+
+/* @fn sendUnplannedGraceLsa
+After an unplanned restart the grace LSA goes out before the first hello.
+*/
+func (server *OSPFServer) sendUnplannedGraceLsa(key IntfConfKey) {
+	server.GraceRestartMutex.RLock()
+	unplanned := server.GraceRestart.Active &&
+		server.GraceRestart.Record.Status == config.UnplannedRestart
+	server.GraceRestartMutex.RUnlock()
+	if unplanned {
+		server.sendGraceLsa(key, false)
+	}
+}
+
+/* @fn getGraceRestartNbrs
+Router ids of the pre-restart adjacencies, taken from the self originated
+router LSAs (and network LSAs) the neighbors sent back.
+*/
+func (server *OSPFServer) getGraceRestartNbrs() map[uint32]bool {
+	nbrs := make(map[uint32]bool)
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	selfKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	for _, lsDbEnt := range server.AreaLsdb {
+		lsa, exist := lsDbEnt.RouterLsaMap[selfKey]
+		if !exist {
+			continue
+		}
+		for _, link := range lsa.LinkDetails {
+			switch link.LinkType {
+			case P2PLink, VirtualLink:
+				nbrs[link.LinkId] = true
+			case TransitLink:
+				for nKey, nLsa := range lsDbEnt.NetworkLsaMap {
+					if nKey.LSId != link.LinkId {
+						continue
+					}
+					if nKey.AdvRouter != rtrId {
+						// adjacent to the DR only
+						nbrs[nKey.AdvRouter] = true
+						continue
+					}
+					for _, attached := range nLsa.AttachedRtr {
+						if attached != rtrId {
+							nbrs[attached] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return nbrs
+}
+
+func (server *OSPFServer) isGraceRestartDone() bool {
+	nbrs := server.getGraceRestartNbrs()
+	if len(nbrs) == 0 {
+		return false
+	}
+	for _, nbrConf := range server.NeighborConfigMap {
+		if nbrConf.OspfNbrState == config.NbrFull {
+			delete(nbrs, nbrConf.OspfNbrRtrId)
+		}
+	}
+	return len(nbrs) == 0
+}
+
+/* @fn isGraceRestartLsaConsistent
+RFC 3623 2.2 : a router LSA of a pre-restart neighbor which no longer has
+a link back to this router means the topology changed.
+*/
+func (server *OSPFServer) isGraceRestartLsaConsistent(areaId uint32, lsaKey LsaKey) bool {
+	if lsaKey.LSType != RouterLSA || !server.getGraceRestartNbrs()[lsaKey.AdvRouter] {
+		return true
+	}
+	lsa, ret := server.getRouterLsaFromLsdb(areaId, lsaKey)
+	if ret == LsdbEntryNotFound {
+		return true
+	}
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	selfKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	transitLinks := make(map[uint32]bool)
+	selfLsa, _ := server.getRouterLsaFromLsdb(areaId, selfKey)
+	for _, link := range selfLsa.LinkDetails {
+		if link.LinkType == TransitLink {
+			transitLinks[link.LinkId] = true
+		}
+	}
+	for _, link := range lsa.LinkDetails {
+		switch link.LinkType {
+		case P2PLink, VirtualLink:
+			if link.LinkId == rtrId {
+				return true
+			}
+		case TransitLink:
+			if transitLinks[link.LinkId] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* @fn checkGraceRestartExit
+Called on LSDB updates and new full adjacencies while restarting.
+*/
+func (server *OSPFServer) checkGraceRestartExit(areaId uint32, lsaKey LsaKey) {
+	if !server.isGraceRestarting() {
+		return
+	}
+	if !server.isGraceRestartLsaConsistent(areaId, lsaKey) {
+		server.logger.Info(fmt.Sprintln("GR: Topology changed, router LSA ", lsaKey))
+		server.exitGraceRestart(config.TopologyChanged)
+		return
+	}
+	if server.isGraceRestartDone() {
+		server.exitGraceRestart(config.Completed)
+	}
+}
+
+func (server *OSPFServer) processGraceTimerExpiry() {
+	if server.isGraceRestarting() {
+		server.exitGraceRestart(config.TimeedOut)
+		return
+	}
+	// ospfd was not restarted within the grace period
+	server.GraceRestartMutex.Lock()
+	server.GraceRestart.Record = GraceRestartRecord{}
+	server.ospfGlobalConf.RestartStatus = config.NotRestarting
+	server.ospfGlobalConf.RestartExitReason = config.TimeedOut
+	server.GraceRestartMutex.Unlock()
+	server.removeGraceRestartRecord()
+}
+
+/* @fn exitGraceRestart
+RFC 3623 2.3 : flush the grace LSAs, reoriginate router and network LSAs,
+flush the self originated LSAs which are not originated any more and
+recalculate the routes. Routes kept from before the restart are compared
+with the new ones so that only stale routes are removed from ribd.
+*/
+func (server *OSPFServer) exitGraceRestart(reason config.RestartExitReason) {
+	server.GraceRestartMutex.Lock()
+	if !server.GraceRestart.Active {
+		server.GraceRestartMutex.Unlock()
+		return
+	}
+	staleRoutes := server.GraceRestart.StaleRoutes
+	server.GraceRestart.Active = false
+	server.GraceRestart.StaleRoutes = nil
+	server.ospfGlobalConf.RestartStatus = config.NotRestarting
+	server.ospfGlobalConf.RestartExitReason = reason
+	server.GraceRestartMutex.Unlock()
+	stopTimer(server.GraceRestart.GraceTimer)
+	server.removeGraceRestartRecord()
+	server.logger.Info(fmt.Sprintln("GR: Restart exit, reason ", reason))
+
+	for key, _ := range server.IntfConfMap {
+		server.sendGraceLsa(key, true)
+	}
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	nbr := NeighborConfKey{}
+	for lsdbKey, _ := range server.AreaLsdb {
+		for key, ent := range server.IntfConfMap {
+			if convertIPv4ToUint32(ent.IfAreaId) != lsdbKey.AreaId {
+				continue
+			}
+			if ent.IfDRtrId == rtrId &&
+				(ent.IfType == config.Broadcast || ent.IfType == config.Nbma) {
+				server.generateNetworkLSA(lsdbKey.AreaId, key, true)
+			}
+		}
+		server.generateRouterLSA(lsdbKey.AreaId)
+		server.flushStaleSelfLsa(lsdbKey)
+		for key, ent := range server.IntfConfMap {
+			if convertIPv4ToUint32(ent.IfAreaId) == lsdbKey.AreaId {
+				server.sendLsdbToNeighborEvent(key, nbr, lsdbKey.AreaId, 0, 0, LsaKey{}, LSAFLOOD)
+			}
+		}
+	}
+	for rKey, rEnt := range staleRoutes {
+		if _, exist := server.GlobalRoutingTbl[rKey]; !exist {
+			server.GlobalRoutingTbl[rKey] = rEnt
+		}
+	}
+	server.scheduleSpf(SpfRequest{AllAreas: true, Trigger: "Graceful restart exit"})
+}
+
+func (server *OSPFServer) flushStaleSelfLsa(lsdbKey LsdbKey) {
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		return
+	}
+	selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+	flush := false
+	for lsaKey, lsa := range lsDbEnt.RouterLsaMap {
+		if lsaKey.AdvRouter != rtrId || selfOrigLsaEnt[lsaKey] {
+			continue
+		}
+		lsa.LsaMd.LSAge = config.MaxAge
+		maxAgeLsaMap[lsaKey] = encodeRouterLsa(lsa, lsaKey)
+		delete(lsDbEnt.RouterLsaMap, lsaKey)
+		flush = true
+	}
+	for lsaKey, lsa := range lsDbEnt.NetworkLsaMap {
+		if lsaKey.AdvRouter != rtrId || selfOrigLsaEnt[lsaKey] {
+			continue
+		}
+		lsa.LsaMd.LSAge = config.MaxAge
+		maxAgeLsaMap[lsaKey] = encodeNetworkLsa(lsa, lsaKey)
+		delete(lsDbEnt.NetworkLsaMap, lsaKey)
+		flush = true
+	}
+	server.AreaLsdb[lsdbKey] = lsDbEnt
+	if flush {
+		server.logger.Info(fmt.Sprintln("GR: Flush stale self originated LSAs, area ", lsdbKey.AreaId))
+		server.ospfNbrLsaUpdSendCh <- ospfFloodMsg{
+			lsOp: LSAAGE,
+		}
+	}
+}
+
+func (server *OSPFServer) ValidateGracefulRestartConf(conf config.GracefulRestartConf) error {
+	if conf.HelperSupport != config.None &&
+		conf.HelperSupport != config.PlannedOnly &&
+		conf.HelperSupport != config.PlannedAndUnplanned {
+		return errors.New(fmt.Sprintln("Invalid helper support", conf.HelperSupport))
+	}
+	return nil
+}
+
+func (server *OSPFServer) processGracefulRestartConfig(conf config.GracefulRestartConf) error {
+	err := server.ValidateGracefulRestartConf(conf)
+	if err != nil {
+		return err
+	}
+	server.GraceRestartMutex.Lock()
+	server.ospfGlobalConf.RestartHelperSupport = conf.HelperSupport
+	server.ospfGlobalConf.RestartStrictLsaChecking = conf.StrictLsaChecking
+	var helped []NeighborConfKey
+	if conf.HelperSupport == config.None {
+		for nbrKey, _ := range server.GraceHelperMap {
+			helped = append(helped, nbrKey)
+		}
+	}
+	server.GraceRestartMutex.Unlock()
+	for _, nbrKey := range helped {
+		server.exitGraceHelper(nbrKey, config.NoAttempt)
+	}
+	return nil
+}
+
+func (server *OSPFServer) isGraceHelperNbr(nbrKey NeighborConfKey) bool {
+	server.GraceRestartMutex.RLock()
+	defer server.GraceRestartMutex.RUnlock()
+	_, exist := server.GraceHelperMap[nbrKey]
+	return exist
+}
+
+func (server *OSPFServer) hasNbrRetxLsa(nbrKey NeighborConfKey) bool {
+	for _, retx := range ospfNeighborRetx_list[nbrKey] {
+		if retx.valid {
+			return true
+		}
+	}
+	return false
+}
+
+/* @fn processRxGraceLsa
+Grace LSA received from a neighbor. Helper entry checks of RFC 3623 3.1,
+a grace LSA with MaxAge ends helping.
+*/
+func (server *OSPFServer) processRxGraceLsa(nbrKey NeighborConfKey, data []byte) {
+	lsa := GraceLsa{}
+	lsaKey := LsaKey{}
+	err := decodeGraceLsa(data, &lsa, &lsaKey)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("GR: Invalid grace LSA from ", nbrKey.IPAddr, err))
+		return
+	}
+	nbrConf, exist := server.NeighborConfigMap[nbrKey]
+	if !exist || nbrConf.OspfNbrRtrId != lsaKey.AdvRouter {
+		return
+	}
+	if lsa.LsaMd.LSAge >= config.MaxAge {
+		if server.isGraceHelperNbr(nbrKey) {
+			server.GraceHelperExitCh <- GraceHelperExitMsg{
+				nbrKey: nbrKey,
+				reason: config.Completed,
+			}
+		}
+		return
+	}
+
+	server.GraceRestartMutex.Lock()
+	defer server.GraceRestartMutex.Unlock()
+	helperSupport := server.ospfGlobalConf.RestartHelperSupport
+	if helperSupport == config.None ||
+		(helperSupport == config.PlannedOnly && !isPlannedGraceReason(lsa.Reason)) {
+		server.logger.Info(fmt.Sprintln("GR: Helper not enabled for restart reason ", lsa.Reason))
+		return
+	}
+	if server.GraceRestart.Active {
+		server.logger.Info("GR: Restarting, can not help the neighbor")
+		return
+	}
+	if nbrConf.OspfNbrState != config.NbrFull {
+		server.logger.Info(fmt.Sprintln("GR: Neighbor not full, can not help ", nbrKey.IPAddr))
+		return
+	}
+	if uint32(lsa.LsaMd.LSAge) >= lsa.GracePeriod {
+		server.logger.Info(fmt.Sprintln("GR: Grace period expired ", nbrKey.IPAddr))
+		return
+	}
+	if server.ospfGlobalConf.RestartStrictLsaChecking && server.hasNbrRetxLsa(nbrKey) {
+		// topology changed since the start of the grace period
+		server.logger.Info(fmt.Sprintln("GR: Pending retransmissions, can not help ", nbrKey.IPAddr))
+		server.GraceHelperExitMap[nbrKey] = config.TopologyChanged
+		return
+	}
+	ent, helping := server.GraceHelperMap[nbrKey]
+	if helping {
+		ent.GraceTimer.Stop()
+	}
+	remaining := time.Duration(lsa.GracePeriod-uint32(lsa.LsaMd.LSAge)) * time.Second
+	ent = GraceHelperEnt{
+		NbrRtrId:    nbrConf.OspfNbrRtrId,
+		AreaId:      convertIPv4ToUint32(server.IntfConfMap[nbrConf.intfConfKey].IfAreaId),
+		Reason:      lsa.Reason,
+		GracePeriod: lsa.GracePeriod,
+		StartTime:   time.Now().Add(-time.Duration(lsa.LsaMd.LSAge) * time.Second),
+	}
+	ent.GraceTimer = time.AfterFunc(remaining, func() {
+		server.GraceHelperExitCh <- GraceHelperExitMsg{
+			nbrKey: nbrKey,
+			reason: config.TimeedOut,
+		}
+	})
+	server.GraceHelperMap[nbrKey] = ent
+	server.GraceHelperExitMap[nbrKey] = config.InProgress
+	server.logger.Info(fmt.Sprintln("GR: Helping neighbor ", nbrKey.IPAddr, " grace period ", lsa.GracePeriod,
+		" reason ", lsa.Reason))
+}
+
+/* @fn exitGraceHelper
+RFC 3623 3.2 : reoriginate the LSAs of the segment and recalculate the
+routes as if the neighbor had not been helped. If the adjacency did not
+come back the dead timer removes the neighbor.
+*/
+func (server *OSPFServer) exitGraceHelper(nbrKey NeighborConfKey, reason config.RestartExitReason) {
+	server.GraceRestartMutex.Lock()
+	ent, exist := server.GraceHelperMap[nbrKey]
+	if !exist {
+		server.GraceRestartMutex.Unlock()
+		return
+	}
+	ent.GraceTimer.Stop()
+	delete(server.GraceHelperMap, nbrKey)
+	server.GraceHelperExitMap[nbrKey] = reason
+	server.GraceRestartMutex.Unlock()
+	server.logger.Info(fmt.Sprintln("GR: Stop helping neighbor ", nbrKey.IPAddr, " reason ", reason))
+
+	nbrConf, exist := server.NeighborConfigMap[nbrKey]
+	if !exist {
+		return
+	}
+	if nbrConf.OspfNbrState != config.NbrFull && nbrConf.NbrDeadTimer != nil {
+		nbrConf.NbrDeadTimer.Reset(nbrConf.OspfNbrDeadTimer)
+	}
+	intfConf := server.IntfConfMap[nbrConf.intfConfKey]
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	if intfConf.IfDRtrId == rtrId &&
+		(intfConf.IfType == config.Broadcast || intfConf.IfType == config.Nbma) {
+		server.generateNetworkLSA(ent.AreaId, nbrConf.intfConfKey, true)
+	}
+	server.generateRouterLSA(ent.AreaId)
+	server.sendLsdbToNeighborEvent(nbrConf.intfConfKey, NeighborConfKey{}, ent.AreaId, 0, 0, LsaKey{}, LSAFLOOD)
+	server.scheduleSpf(newAreaSpfRequest(ent.AreaId, true, "Graceful restart helper exit"))
+}
+
+/* @fn encodeLsaFromLsdb
+Encoded copy of an LSA in the LSDB, nil if there is none.
+*/
+func (server *OSPFServer) encodeLsaFromLsdb(areaId uint32, lsaKey LsaKey) []byte {
+	switch lsaKey.LSType {
+	case RouterLSA:
+		lsa, ret := server.getRouterLsaFromLsdb(areaId, lsaKey)
+		if ret == LsdbEntryFound {
+			return encodeRouterLsa(lsa, lsaKey)
+		}
+	case NetworkLSA:
+		lsa, ret := server.getNetworkLsaFromLsdb(areaId, lsaKey)
+		if ret == LsdbEntryFound {
+			return encodeNetworkLsa(lsa, lsaKey)
+		}
+	case Summary3LSA, Summary4LSA:
+		lsa, ret := server.getSummaryLsaFromLsdb(areaId, lsaKey)
+		if ret == LsdbEntryFound {
+			return encodeSummaryLsa(lsa, lsaKey)
+		}
+	case ASExternalLSA:
+		lsa, ret := server.getASExternalLsaFromLsdb(areaId, lsaKey)
+		if ret == LsdbEntryFound {
+			return encodeASExternalLsa(lsa, lsaKey)
+		}
+	case NSSAExternalLSA:
+		lsa, ret := server.getNssaExternalLsaFromLsdb(areaId, lsaKey)
+		if ret == LsdbEntryFound {
+			return encodeASExternalLsa(lsa, lsaKey)
+		}
+	case OpaqueAreaLSA, OpaqueASLSA:
+		lsa, ret := server.getOpaqueLsaFromLsdb(areaId, lsaKey)
+		if ret == LsdbEntryFound {
+			return encodeOpaqueLsa(lsa, lsaKey)
+		}
+	}
+	return nil
+}
+
+/* Content changed, not just a refresh of the LSA */
+func isLsaContentChanged(old []byte, data []byte) bool {
+	if len(old) < OSPF_LSA_HEADER_SIZE || len(data) < OSPF_LSA_HEADER_SIZE {
+		return true
+	}
+	oldLen := int(binary.BigEndian.Uint16(old[18:20]))
+	newLen := int(binary.BigEndian.Uint16(data[18:20]))
+	if oldLen != newLen || oldLen > len(old) || newLen > len(data) {
+		return true
+	}
+	if binary.BigEndian.Uint16(old[0:2]) >= config.MaxAge ||
+		binary.BigEndian.Uint16(data[0:2]) >= config.MaxAge {
+		return true
+	}
+	for idx := OSPF_LSA_HEADER_SIZE; idx < newLen; idx++ {
+		if old[idx] != data[idx] {
+			return true
+		}
+	}
+	return false
+}
+
+/* @fn checkGraceHelperTopology
+Strict LSA checking (RFC 3623 3.2) : a changed LSA which would be flooded
+to a restarting neighbor ends helping.
+*/
+func (server *OSPFServer) checkGraceHelperTopology(areaId uint32, lsaKey LsaKey, old []byte, data []byte) {
+	server.GraceRestartMutex.RLock()
+	var helped []NeighborConfKey
+	if server.ospfGlobalConf.RestartStrictLsaChecking {
+		for nbrKey, ent := range server.GraceHelperMap {
+			if ent.NbrRtrId == lsaKey.AdvRouter {
+				continue
+			}
+			if ent.AreaId == areaId || lsaKey.LSType == ASExternalLSA {
+				helped = append(helped, nbrKey)
+			}
+		}
+	}
+	server.GraceRestartMutex.RUnlock()
+	if len(helped) == 0 || !isLsaContentChanged(old, data) {
+		return
+	}
+	for _, nbrKey := range helped {
+		server.exitGraceHelper(nbrKey, config.TopologyChanged)
+	}
+}
+
+func (server *OSPFServer) getNbrRestartHelperState(nbrKey NeighborConfKey, state *config.NeighborState) {
+	server.GraceRestartMutex.RLock()
+	defer server.GraceRestartMutex.RUnlock()
+	state.NbrRestartHelperStatus = int(config.NotHelping)
+	state.NbrRestartHelperAge = 0
+	state.NbrRestartHelperExitReason = int(config.NoAttempt)
+	if reason, exist := server.GraceHelperExitMap[nbrKey]; exist {
+		state.NbrRestartHelperExitReason = int(reason)
+	}
+	if ent, exist := server.GraceHelperMap[nbrKey]; exist {
+		state.NbrRestartHelperStatus = int(config.Helping)
+		state.NbrRestartHelperAge = uint32(getGraceRemaining(ent.StartTime, int32(ent.GracePeriod)))
+	}
+}
@@ -0,0 +1,225 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfGracefulRestart_test
+   This test covers
+   1) Grace LSA encoding and decoding.
+   2) Helper mode entry, exit and strict LSA checking.
+   3) Restarter prepare, restart and exit.
+*/
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"l3/ospf/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var graceTestDir string
+
+func initGracefulRestartTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+	maxAgeLsaMap = make(map[LsaKey][]byte)
+	ospf.initLSDatabase(areaId)
+	ospf.IntfConfMap[key] = intf
+	graceTestDir, _ = ioutil.TempDir("", "ospfgr")
+	ospf.GraceRestart.FileName = filepath.Join(graceTestDir, "grace_restart.json")
+}
+
+func TestOspfGracefulRestart(t *testing.T) {
+	fmt.Println("\n**************** GRACEFUL RESTART ************\n")
+	initGracefulRestartTestParams()
+	defer os.RemoveAll(graceTestDir)
+	for index := 1; index < 4; index++ {
+		err := gracefulRestartTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for graceful restart ", index)
+		}
+	}
+}
+
+func gracefulRestartTestLogic(tNum int) int {
+	rtrId := convertIPv4ToUint32(ospf.ospfGlobalConf.RouterId)
+	lsaAreaId := convertIPv4ToUint32(intf.IfAreaId)
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running encodeGraceLsa")
+		lsaKey := getGraceLsaKey(nbrConf.OspfNbrRtrId)
+		lsa := GraceLsa{
+			GracePeriod: 60,
+			Reason:      GraceReasonSoftwareUpgrade,
+			IntfIpAddr:  0x0a010102,
+		}
+		lsa.LsaMd.LSAge = 5
+		lsa.LsaMd.LSSequenceNum = InitialSequenceNumber
+		lsa.LsaMd.LSLen = uint16(GRACE_LSA_LEN)
+		data := encodeGraceLsa(lsa, lsaKey)
+		decoded := GraceLsa{}
+		decodedKey := LsaKey{}
+		err := decodeGraceLsa(data, &decoded, &decodedKey)
+		if err != nil || decoded != lsa || decodedKey != lsaKey || !isGraceLsaKey(decodedKey) {
+			fmt.Println("Grace LSA not decoded ", decoded, decodedKey, err)
+			return FAIL
+		}
+		if decodeGraceLsa(data[:GRACE_LSA_LEN-4], &decoded, &decodedKey) == nil {
+			fmt.Println("Truncated grace LSA accepted")
+			return FAIL
+		}
+		lsa.GracePeriod = 0
+		data = encodeGraceLsa(lsa, lsaKey)
+		if decodeGraceLsa(data, &decoded, &decodedKey) == nil {
+			fmt.Println("Grace LSA without grace period accepted")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running processRxGraceLsa")
+		nbr := nbrConf
+		nbr.OspfNbrState = config.NbrFull
+		ospf.NeighborConfigMap[nbrKey] = nbr
+		lsaKey := getGraceLsaKey(nbr.OspfNbrRtrId)
+		lsa := GraceLsa{GracePeriod: 60, Reason: GraceReasonUnknown}
+		lsa.LsaMd.LSLen = uint16(GRACE_LSA_LEN)
+
+		ospf.processGracefulRestartConfig(config.GracefulRestartConf{
+			HelperSupport:     config.PlannedOnly,
+			StrictLsaChecking: true,
+		})
+		ospf.processRxGraceLsa(nbrKey, encodeGraceLsa(lsa, lsaKey))
+		if ospf.isGraceHelperNbr(nbrKey) {
+			fmt.Println("Helping an unplanned restart with planned only support")
+			return FAIL
+		}
+		lsa.Reason = GraceReasonSoftwareRestart
+		ospf.processRxGraceLsa(nbrKey, encodeGraceLsa(lsa, lsaKey))
+		state := config.NeighborState{}
+		ospf.getNbrRestartHelperState(nbrKey, &state)
+		if !ospf.isGraceHelperNbr(nbrKey) || state.NbrRestartHelperStatus != int(config.Helping) ||
+			state.NbrRestartHelperAge == 0 || state.NbrRestartHelperExitReason != int(config.InProgress) {
+			fmt.Println("Not helping the restarting neighbor ", state)
+			return FAIL
+		}
+
+		otherKey := LsaKey{LSType: RouterLSA, LSId: 0x05050505, AdvRouter: 0x05050505}
+		old := encodeRouterLsa(RouterLsa{}, otherKey)
+		ospf.checkGraceHelperTopology(lsaAreaId, otherKey, old, old)
+		if !ospf.isGraceHelperNbr(nbrKey) {
+			fmt.Println("LSA refresh ended helping")
+			return FAIL
+		}
+		changed := encodeRouterLsa(RouterLsa{NumOfLinks: 1,
+			LinkDetails: []LinkDetail{LinkDetail{LinkType: StubLink, LinkId: 0x0a050500}}}, otherKey)
+		ospf.checkGraceHelperTopology(lsaAreaId, otherKey, old, changed)
+		ospf.getNbrRestartHelperState(nbrKey, &state)
+		if ospf.isGraceHelperNbr(nbrKey) || state.NbrRestartHelperExitReason != int(config.TopologyChanged) {
+			fmt.Println("Topology change did not end helping ", state)
+			return FAIL
+		}
+		ospf.processGracefulRestartConfig(DefaultGracefulRestartConf())
+
+	case 3:
+		fmt.Println(tNum, ": Running checkGraceRestart")
+		ospf.ospfGlobalConf.RestartSupport = config.PlannedOnly
+		ospf.ospfGlobalConf.AdminStat = config.Enabled
+		if ospf.ValidateGraceRestartPrepare(GraceReasonSwitchover) == nil {
+			fmt.Println("Switchover accepted as planned restart")
+			return FAIL
+		}
+		err := ospf.processGraceRestartPrepare(GraceReasonSoftwareUpgrade)
+		if err != nil || ospf.ospfGlobalConf.RestartStatus != config.PlannedRestart {
+			fmt.Println("Planned restart not prepared ", err)
+			return FAIL
+		}
+
+		/* ospfd comes up again */
+		stopTimer(ospf.GraceRestart.GraceTimer)
+		ospf.GraceRestart.Record = GraceRestartRecord{}
+		ospf.GraceRestart.Checked = false
+		ospf.checkGraceRestart()
+		if !ospf.isGraceRestarting() || ospf.GraceRestart.Record.Reason != GraceReasonSoftwareUpgrade {
+			fmt.Println("Restart not started from the saved record ", ospf.GraceRestart.Record)
+			return FAIL
+		}
+		spfEvents := ospf.SpfThrottle.Events
+		ospf.scheduleSpf(newAreaSpfRequest(lsaAreaId, true, "restart"))
+		if ospf.SpfThrottle.Events != spfEvents {
+			fmt.Println("SPF scheduled while restarting")
+			return FAIL
+		}
+
+		/* LSAs of the previous instance relearnt from the neighbor */
+		lsdbKey := LsdbKey{AreaId: lsaAreaId}
+		ospf.initLSDatabase(lsaAreaId)
+		selfKey := LsaKey{LSType: RouterLSA, LSId: rtrId, AdvRouter: rtrId}
+		p2p := LinkDetail{LinkType: P2PLink, LinkId: nbrConf.OspfNbrRtrId}
+		lsDbEnt := ospf.AreaLsdb[lsdbKey]
+		lsDbEnt.RouterLsaMap[selfKey] = RouterLsa{NumOfLinks: 1, LinkDetails: []LinkDetail{p2p}}
+		staleKey := LsaKey{LSType: NetworkLSA, LSId: 0x0a060601, AdvRouter: rtrId}
+		lsDbEnt.NetworkLsaMap[staleKey] = NetworkLsa{AttachedRtr: []uint32{rtrId}}
+		ospf.AreaLsdb[lsdbKey] = lsDbEnt
+		ospf.generateRouterLSA(lsaAreaId)
+		if ospf.AreaSelfOrigLsa[lsdbKey][selfKey] {
+			fmt.Println("Router LSA originated while restarting")
+			return FAIL
+		}
+		nbrs := ospf.getGraceRestartNbrs()
+		if len(nbrs) != 1 || !nbrs[nbrConf.OspfNbrRtrId] || ospf.isGraceRestartDone() {
+			fmt.Println("Unexpected pre-restart neighbors ", nbrs)
+			return FAIL
+		}
+
+		staleRoute := RoutingTblEntryKey{DestId: 0x0a070700, AddrMask: 0xffffff00, DestType: Network}
+		ospf.GraceRestart.StaleRoutes[staleRoute] = GlobalRoutingTblEntry{AreaId: lsaAreaId}
+		nbr := nbrConf
+		nbr.OspfNbrState = config.NbrFull
+		ospf.NeighborConfigMap[nbrKey] = nbr
+		ospf.checkGraceRestartExit(lsaAreaId, LsaKey{})
+		if ospf.isGraceRestarting() || ospf.ospfGlobalConf.RestartExitReason != config.Completed {
+			fmt.Println("Restart not completed with all neighbors full")
+			return FAIL
+		}
+		if _, err := os.Stat(ospf.GraceRestart.FileName); !os.IsNotExist(err) {
+			fmt.Println("Restart record not removed")
+			return FAIL
+		}
+		if _, exist := maxAgeLsaMap[staleKey]; !exist {
+			fmt.Println("Stale network LSA not flushed")
+			return FAIL
+		}
+		if _, exist := ospf.GlobalRoutingTbl[staleRoute]; !exist {
+			fmt.Println("Stale routes not handed to the route calculation")
+			return FAIL
+		}
+		stopTimer(ospf.SpfThrottle.SpfTimer)
+		stopTimer(ospf.SpfThrottle.LearnTimer)
+		ospf.ospfGlobalConf.AdminStat = config.Disabled
+	}
+	return SUCCESS
+}
@@ -74,7 +74,13 @@ func decodeOspfHelloData(data []byte, ospfHelloData *OSPFHelloData) {
 	ospfHelloData.backupDesignatedRtr = data[16:20]
 }
 
-func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
+func (server *OSPFServer) BuildHelloPkt(key IntfConfKey, ent IntfConf) []byte {
+	return server.buildHelloPktToDst(key, ent, net.IP{224, 0, 0, 5},
+		net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05})
+}
+
+func (server *OSPFServer) buildHelloPktToDst(key IntfConfKey, ent IntfConf,
+	dstIP net.IP, dstMAC net.HardwareAddr) []byte {
 	ospfHdr := OSPFHeader{
 		ver:      OSPF_VERSION_2,
 		pktType:  uint8(HelloType),
@@ -83,7 +89,6 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 		areaId:   ent.IfAreaId,
 		chksum:   0,
 		authType: ent.IfAuthType,
-		//authKey:        ent.IfAuthKey,
 	}
 
 	/*
@@ -102,11 +107,7 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 		return nil
 	}
 	areaId := config.AreaId(convertIPInByteToString(ent.IfAreaId))
-	isStub := server.isStubArea(areaId)
-	option := uint8(2)
-	if isStub {
-		option = uint8(0)
-	}
+	option := server.getPktOptions(areaId)
 	helloData := OSPFHelloData{
 		netmask:             ent.IfNetmask,
 		helloInterval:       ent.IfHelloInterval,
@@ -144,9 +145,10 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 
 	ospf := append(ospfEncHdr, helloDataNbrEnc...)
 	//server.logger.Debug(fmt.Sprintln("ospf:", ospf))
-	csum := computeCheckSum(ospf)
-	binary.BigEndian.PutUint16(ospf[12:14], csum)
-	copy(ospf[16:24], ent.IfAuthKey)
+	ospf = server.encodeOspfAuth(key, ent, ospf)
+	if ospf == nil {
+		return nil
+	}
 
 	ipPktlen := IP_HEADER_MIN_LEN + ospfHdr.pktlen
 	ipLayer := layers.IPv4{
@@ -157,12 +159,12 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 		TTL:      uint8(1),
 		Protocol: layers.IPProtocol(OSPF_PROTO_ID),
 		SrcIP:    ent.IfIpAddr,
-		DstIP:    net.IP{224, 0, 0, 5},
+		DstIP:    dstIP,
 	}
 
 	ethLayer := layers.Ethernet{
 		SrcMAC:       ent.IfMacAddr,
-		DstMAC:       net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05},
+		DstMAC:       dstMAC,
 		EthernetType: layers.EthernetTypeIPv4,
 	}
 
@@ -181,28 +183,37 @@ func (server *OSPFServer) BuildHelloPkt(ent IntfConf) []byte {
 func (server *OSPFServer) processRxHelloPkt(data []byte, ospfHdrMd *OspfHdrMetadata,
 	ipHdrMd *IpHdrMetadata, ethHdrMd *EthHdrMetadata, key IntfConfKey) error {
 	ent, _ := server.IntfConfMap[key]
+	if server.isPassiveIntf(key) {
+		err := errors.New("Hello received on passive interface")
+		return err
+	}
 	ospfHelloData := NewOSPFHelloData()
 	if len(data) < OSPF_HELLO_MIN_SIZE {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropBadLength)
 		err := errors.New("Invalid Hello Pkt data length")
 		return err
 	}
 	decodeOspfHelloData(data, ospfHelloData)
 
-	//  Todo: Sec 10.5 RFC2328 Need to add check for Virtual links
-	if ent.IfType != config.NumberedP2P || ent.IfType != config.UnnumberedP2P {
+	// Sec 10.5 RFC2328 the mask is not checked on p2p and virtual links
+	if ent.IfType != config.NumberedP2P && ent.IfType != config.UnnumberedP2P &&
+		ent.IfType != config.VirtualLink {
 		if bytesEqual(ent.IfNetmask, ospfHelloData.netmask) == false {
 			server.logger.Debug(fmt.Sprintln("HELLO: Netmask mismatch. Int mask", ent.IfNetmask, " Hello mask ", ospfHelloData.netmask, " ip ", ipHdrMd.srcIP))
+			server.countRxDrop(key, ipHdrMd.srcIP, DropNetmaskMismatch)
 			err := errors.New("Netmask mismatch")
 			return err
 		}
 	}
 
 	if ent.IfHelloInterval != ospfHelloData.helloInterval {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropHelloIntervalMismatch)
 		err := errors.New("Hello Interval mismatch")
 		return err
 	}
 
 	if ent.IfRtrDeadInterval != ospfHelloData.rtrDeadInterval {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropDeadIntervalMismatch)
 		err := errors.New("Router Dead Interval mismatch")
 		return err
 	}
@@ -210,11 +221,29 @@ func (server *OSPFServer) processRxHelloPkt(data []byte, ospfHdrMd *OspfHdrMetad
 	if ospfHdrMd.backbone == true {
 		//server.logger.Debug(fmt.Sprintln("Options:", ospfHelloData.options, "EOPTIONS:", EOption))
 		if (ospfHelloData.options & EOption) == 0 {
+			server.countRxDrop(key, ipHdrMd.srcIP, DropOptionsMismatch)
 			err := errors.New("External Routing Capability mismatch")
 			return err
 		}
 	}
 
+	/*
+	   RFC 3101 2.1
+	   Routers attached to an NSSA must agree on the N-bit and
+	   clear the E-bit, otherwise the hello is dropped.
+	*/
+	areaOptions := server.getAreaOptions(config.AreaId(convertIPInByteToString(ent.IfAreaId)))
+	if (ospfHelloData.options & NPOption) != (areaOptions & NPOption) {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropOptionsMismatch)
+		err := errors.New("NSSA Capability mismatch")
+		return err
+	}
+	if areaOptions&NPOption != 0 && (ospfHelloData.options&EOption) != 0 {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropOptionsMismatch)
+		err := errors.New("External Routing Capability mismatch")
+		return err
+	}
+
 	//Todo: Find whether one way or two way
 	TwoWayStatus := false
 	/*
@@ -276,6 +305,10 @@ func (server *OSPFServer) processOspfHelloNeighbor(TwoWayStatus bool, ospfHelloD
 		IPAddr:  config.IpAddress(ipaddr.String()),
 		IntfIdx: key.IntfIdx,
 	}
+	if server.isGraceHelperNbr(neighborKey) {
+		/* Restarting neighbor, keep the adjacency during the grace period */
+		TwoWayStatus = true
+	}
 
 	//Todo: Find whether one way or two way
 	ent, _ := server.IntfConfMap[key]
@@ -349,10 +382,11 @@ func (server *OSPFServer) CreateAndSendHelloRecvdMsg(routerId uint32,
 	if ifType == config.Broadcast ||
 		ifType == config.Nbma ||
 		ifType == config.PointToMultipoint ||
-		ifType == config.NumberedP2P {
+		ifType == config.NumberedP2P ||
+		ifType == config.VirtualLink {
 		msg.NeighborIP = net.IPv4(ipHdrMd.srcIP[0], ipHdrMd.srcIP[1], ipHdrMd.srcIP[2], ipHdrMd.srcIP[3])
 		//copy(msg.NeighborIP, ipHdrMd.srcIP)
-	} else { //Check for Virtual Links and unnumbered p2p
+	} else { // unnumbered p2p
 		msg.NeighborIP = net.IPv4(ospfHdrMd.routerId[0], ospfHdrMd.routerId[1], ospfHdrMd.routerId[2], ospfHdrMd.routerId[3])
 		//copy(msg.NeighborIP, ospfHdrMd.routerId)
 	}
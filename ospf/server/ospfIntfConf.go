@@ -349,6 +349,7 @@ func (server *OSPFServer) deleteIPIntfConfMap(msg IPv4IntfNotifyMsg, ifIndex int
 	server.logger.Info(fmt.Sprintln("1:delete IPIntfConfMap for ", intfConfKey))
 	server.IntfKeyToSliceIdxMap[intfConfKey] = false
 	delete(server.IntfConfMap, intfConfKey)
+	server.deleteIntfStats(intfConfKey)
 	if flag == true {
 		msg := NetworkLSAChangeMsg{
 			areaId:  areaId,
@@ -393,12 +394,12 @@ func (server *OSPFServer) updateIPIntfConfMap(ifConf config.InterfaceConf) {
 		ent.IfHelloInterval = uint16(ifConf.IfHelloInterval)
 		ent.IfRtrDeadInterval = uint32(ifConf.IfRtrDeadInterval)
 		ent.IfPollInterval = ifConf.IfPollInterval
-		//authKey := convertAuthKey(string(ifConf.IfAuthKey))
-		//if authKey == nil {
-		//	server.logger.Err("Invalid authKey")
-		//	return
-		//}
-		//ent.IfAuthKey = authKey
+		authKey := convertSimpleAuthKey(ifConf.IfAuthKey)
+		if authKey != nil {
+			ent.IfAuthKey = authKey
+		} else if ifConf.IfAuthType == config.SimplePassword {
+			server.logger.Err("Invalid authKey")
+		}
 		ent.IfAuthType = uint16(ifConf.IfAuthType)
 		/* Re initiate the Interface State */
 		ent.IfDRIp = []byte{0, 0, 0, 0}
@@ -461,8 +462,10 @@ func (server *OSPFServer) processIntfConfig(ifConf config.InterfaceConf) error {
 func (server *OSPFServer) StopSendRecvPkts(intfConfKey IntfConfKey) {
 	server.logger.Info("Stop Sending Hello Pkt")
 	server.StopOspfIntfFSM(intfConfKey)
-	server.logger.Info("Stop Receiving Hello Pkt")
-	server.StopOspfRecvPkts(intfConfKey)
+	if !isVirtualIntfKey(intfConfKey) {
+		server.logger.Info("Stop Receiving Hello Pkt")
+		server.StopOspfRecvPkts(intfConfKey)
+	}
 	ent, _ := server.IntfConfMap[intfConfKey]
 	ent.NeighborMap = nil
 	ent.IfEvents = ent.IfEvents + 1
@@ -474,21 +477,27 @@ func (server *OSPFServer) StartSendRecvPkts(intfConfKey IntfConfKey) {
 	ent, _ := server.IntfConfMap[intfConfKey]
 	helloInterval := time.Duration(ent.IfHelloInterval) * time.Second
 	ent.HelloIntervalTicker = time.NewTicker(helloInterval)
-	if ent.IfType == config.Broadcast {
+	if ent.IfType == config.Broadcast || ent.IfType == config.Nbma {
 		waitTime := time.Duration(ent.IfRtrDeadInterval) * time.Second
 		ent.WaitTimer = time.NewTimer(waitTime)
 	}
 	// rtrDeadInterval := time.Duration(ent.IfRtrDeadInterval * time.Second)
 	ent.NeighborMap = make(map[NeighborConfKey]NeighborData)
 	ent.IfEvents = ent.IfEvents + 1
-	if ent.IfType == config.Broadcast {
+	if ent.IfType == config.Broadcast || ent.IfType == config.Nbma {
 		ent.IfFSMState = config.Waiting
-	} else if ent.IfType == config.NumberedP2P || ent.IfType == config.UnnumberedP2P {
+	} else if ent.IfType == config.NumberedP2P || ent.IfType == config.UnnumberedP2P ||
+		ent.IfType == config.PointToMultipoint || ent.IfType == config.VirtualLink {
 		ent.IfFSMState = config.P2P
 	}
 	server.IntfConfMap[intfConfKey] = ent
+	server.sendUnplannedGraceLsa(intfConfKey)
 	server.logger.Info("Start Sending Hello Pkt")
 	go server.StartOspfIntfFSM(intfConfKey)
+	if isVirtualIntfKey(intfConfKey) {
+		// Virtual links receive through their transit interface
+		return
+	}
 	server.logger.Info("Start Receiving Hello Pkt")
 	go server.StartOspfRecvPkts(intfConfKey)
 }
@@ -511,6 +520,9 @@ func (server *OSPFServer) initIntfStateSlice() {
 
 func (server *OSPFServer) refreshIntfKeySlice() {
 	for key, _ := range server.IntfConfMap {
+		if isVirtualIntfKey(key) {
+			continue
+		}
 		server.IntfKeySlice = append(server.IntfKeySlice, key)
 		server.IntfKeyToSliceIdxMap[key] = true
 	}
@@ -45,9 +45,10 @@ func (server *OSPFServer) StartOspfIntfFSM(key IntfConfKey) {
 	server.logger.Info("Sending msg for router LSA generation")
 	server.IntfStateChangeCh <- msg
 
-	if ent.IfType == config.NumberedP2P || ent.IfType == config.UnnumberedP2P {
+	if ent.IfType == config.NumberedP2P || ent.IfType == config.UnnumberedP2P ||
+		ent.IfType == config.PointToMultipoint || ent.IfType == config.VirtualLink {
 		server.StartOspfP2PIntfFSM(key)
-	} else if ent.IfType == config.Broadcast {
+	} else if ent.IfType == config.Broadcast || ent.IfType == config.Nbma {
 		server.StartOspfBroadcastIntfFSM(key)
 	}
 }
@@ -188,9 +188,10 @@ func (server *OSPFServer) EncodeLSAReqPkt(intfKey IntfConfKey, ent IntfConf,
 
 	ospf := append(ospfEncHdr, lsaDataEnc...)
 	server.logger.Info(fmt.Sprintln("OSPF LSA REQ:", ospf))
-	csum := computeCheckSum(ospf)
-	binary.BigEndian.PutUint16(ospf[12:14], csum)
-	copy(ospf[16:24], ent.IfAuthKey)
+	ospf = server.encodeOspfAuth(intfKey, ent, ospf)
+	if ospf == nil {
+		return nil
+	}
 
 	ipPktlen := IP_HEADER_MIN_LEN + ospfHdr.pktlen
 	var dstIp net.IP
@@ -340,9 +341,10 @@ func (server *OSPFServer) BuildLsaUpdPkt(intfKey IntfConfKey, ent IntfConf,
 
 	ospf := append(ospfEncHdr, lsaUpdEnc...)
 	//server.logger.Info(fmt.Sprintln("OSPF LSA UPD:", ospf))
-	csum := computeCheckSum(ospf)
-	binary.BigEndian.PutUint16(ospf[12:14], csum)
-	copy(ospf[16:24], ent.IfAuthKey)
+	ospf = server.encodeOspfAuth(intfKey, ent, ospf)
+	if ospf == nil {
+		return nil
+	}
 
 	if ent.IfType == config.NumberedP2P {
 		dstIp = net.ParseIP(config.AllSPFRouters)
@@ -466,6 +468,7 @@ func (server *OSPFServer) DecodeLSAUpd(msg ospfNeighborLSAUpdMsg) {
 			//continue
 		}
 		lsa_key := NewLsaKey()
+		link_local := false
 
 		switch lsa_header.LSType {
 		case RouterLSA:
@@ -495,18 +498,53 @@ func (server *OSPFServer) DecodeLSAUpd(msg ospfNeighborLSAUpdMsg) {
 			dalsa, ret := server.getASExternalLsaFromLsdb(msg.areaId, *lsa_key)
 			discard, op = server.sanityCheckASExternalLsa(*alsa, dalsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
 
+		case NSSAExternalLSA:
+			nlsa := NewASExternalLsa()
+			decodeASExternalLsa(lsdb_msg.Data, nlsa, lsa_key)
+			dnlsa, ret := server.getNssaExternalLsaFromLsdb(msg.areaId, *lsa_key)
+			discard, op = server.sanityCheckNssaExternalLsa(*nlsa, dnlsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
+
+		case OpaqueLinkLSA:
+			/* Link local, not kept in the LSDB and not flooded */
+			link_local = true
+			discard = true
+			if uint8(lsa_header.LinkId>>24) == GraceLsaOpaqueType {
+				server.processRxGraceLsa(msg.nbrKey, lsdb_msg.Data)
+			} else if server.ospfGlobalConf.OpaqueLsaSupport {
+				server.processRecvdLinkOpaqueLsa(nbr.intfConfKey, lsdb_msg.Data)
+			}
+			lsa_key.LSType = lsa_header.LSType
+			lsa_key.LSId = lsa_header.LinkId
+			lsa_key.AdvRouter = lsa_header.Adv_router
+
+		case OpaqueAreaLSA, OpaqueASLSA:
+			var olsa OpaqueLsa
+			err := decodeOpaqueLsa(lsdb_msg.Data, &olsa, lsa_key)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("LSAUPD: Opaque LSA Discard. ", err))
+				discard = true
+				lsa_key.LSType = lsa_header.LSType
+				lsa_key.LSId = lsa_header.LinkId
+				lsa_key.AdvRouter = lsa_header.Adv_router
+				break
+			}
+			dolsa, ret := server.getOpaqueLsaFromLsdb(msg.areaId, *lsa_key)
+			discard, op = server.sanityCheckOpaqueLsa(olsa, dolsa, *lsa_key, nbr, intf, ret, lsa_max_age)
 		}
 		lsid := convertUint32ToIPv4(lsa_header.LinkId)
 		router_id := convertUint32ToIPv4(lsa_header.Adv_router)
 
 		self_gen := false
 		self_gen = server.selfGenLsaCheck(*lsa_key)
-		if self_gen {
+		restarting := server.isGraceRestarting()
+		if self_gen && !restarting {
 			server.logger.Info(fmt.Sprintln("LSAUPD: discard . Received self generated. ", lsa_key))
 
 		}
 
-		if !discard && !self_gen && op == FloodLsa {
+		/* While restarting the self originated LSAs of the previous instance are
+		   relearnt from the neighbors (RFC 3623 2.2) */
+		if !discard && (!self_gen || restarting) && op == FloodLsa {
 			server.logger.Info(fmt.Sprintln("LSAUPD: add to lsdb lsid ", lsid, " router_id ", router_id, " lstype ", lsa_header.LSType))
 			lsdb_msg.MsgType = LsdbAdd
 			server.LsdbUpdateCh <- *lsdb_msg
@@ -522,7 +560,7 @@ func (server *OSPFServer) DecodeLSAUpd(msg ospfNeighborLSAUpdMsg) {
 		}
 		flood_pkt.pkt = make([]byte, end_index-index)
 		copy(flood_pkt.pkt, lsdb_msg.Data)
-		if lsop != LSASUMMARYFLOOD && !self_gen { // for ABR summary lsa is flooded after LSDB/SPF changes are done.
+		if lsop != LSASUMMARYFLOOD && !self_gen && !link_local { // for ABR summary lsa is flooded after LSDB/SPF changes are done.
 			server.ospfNbrLsaUpdSendCh <- flood_pkt
 		}
 
@@ -665,7 +703,11 @@ func (server *OSPFServer) sanityCheckSummaryLsa(slsa SummaryLsa, dslsa SummaryLs
 func (server *OSPFServer) sanityCheckASExternalLsa(alsa ASExternalLsa, dalsa ASExternalLsa, nbr OspfNeighborEntry, intf IntfConf, areaid []byte, exist int, lsa_max_age bool) (discard bool, op uint8) {
 	discard = false
 	op = LsdbAdd
-	// TODO Reject this lsa if area is configured as stub area.
+	areaId := config.AreaId(convertIPInByteToString(areaid))
+	if server.isStubArea(areaId) || server.isNssaArea(areaId) {
+		server.logger.Info(fmt.Sprintln("LSAUPD: As external LSA Discard. Area is stub or NSSA ", areaId))
+		return true, LsdbNoAction
+	}
 	send_ack := server.lsAgeCheck(nbr.intfConfKey, lsa_max_age, exist)
 	if send_ack {
 		op = LsdbNoAction
@@ -685,6 +727,28 @@ func (server *OSPFServer) sanityCheckASExternalLsa(alsa ASExternalLsa, dalsa ASE
 	return discard, op
 }
 
+/*
+   RFC 3101 3.5
+   Type-7 LSAs are only accepted on interfaces attached to an NSSA.
+*/
+func (server *OSPFServer) sanityCheckNssaExternalLsa(nlsa ASExternalLsa, dnlsa ASExternalLsa, nbr OspfNeighborEntry, intf IntfConf, areaid []byte, exist int, lsa_max_age bool) (discard bool, op uint8) {
+	areaId := config.AreaId(convertIPInByteToString(areaid))
+	if !server.isNssaArea(areaId) {
+		server.logger.Info(fmt.Sprintln("LSAUPD: NSSA external LSA Discard. Area is not NSSA ", areaId))
+		return true, LsdbNoAction
+	}
+	send_ack := server.lsAgeCheck(nbr.intfConfKey, lsa_max_age, exist)
+	if send_ack {
+		server.logger.Info(fmt.Sprintln("LSAUPD: NSSA external LSA Discard.", " nbr ", nbr))
+		return true, LsdbNoAction
+	}
+	isNew := server.validateLsaIsNew(nlsa.LsaMd, dnlsa.LsaMd)
+	if isNew {
+		return false, FloodLsa
+	}
+	return true, LsdbNoAction
+}
+
 func validateChecksum(data []byte) bool {
 
 	csum := computeFletcherChecksum(data[2:], FLETCHER_CHECKSUM_VALIDATE)
@@ -770,9 +834,10 @@ func (server *OSPFServer) BuildLSAAckPkt(intfKey IntfConfKey, ent IntfConf,
 
 	ospf := append(ospfEncHdr, lsaAckEnc...)
 	//server.logger.Info(fmt.Sprintln("OSPF LSA ACK:", ospf))
-	csum := computeCheckSum(ospf)
-	binary.BigEndian.PutUint16(ospf[12:14], csum)
-	copy(ospf[16:24], ent.IfAuthKey)
+	ospf = server.encodeOspfAuth(intfKey, ent, ospf)
+	if ospf == nil {
+		return nil
+	}
 
 	ipPktlen := IP_HEADER_MIN_LEN + ospfHdr.pktlen
 	if ent.IfType == config.NumberedP2P {
@@ -999,6 +1064,31 @@ func (server *OSPFServer) generateLsaUpdUnicast(req ospfLSAReq, nbrKey NeighborC
 			server.logger.Info(fmt.Sprintln("LSAREQ: AS external lsa not fount. lsaid ",
 				req.link_state_id, " lstype ", lsa_key.LSType, " adv_router ", lsa_key.AdvRouter, " areaid ", areaid))
 		}
+	case NSSAExternalLSA:
+		dnlsa, ret := server.getNssaExternalLsaFromLsdb(areaid, *lsa_key)
+		if ret == LsdbEntryFound {
+			lsa_pkt = encodeASExternalLsa(dnlsa, *lsa_key)
+			flood = true
+		} else {
+			server.logger.Info(fmt.Sprintln("LSAREQ: NSSA external lsa not found. lsaid ",
+				req.link_state_id, " lstype ", lsa_key.LSType, " adv_router ", lsa_key.AdvRouter, " areaid ", areaid))
+		}
+	case OpaqueLinkLSA:
+		dolsa, ret := server.getLinkOpaqueLsa(nbrConf.intfConfKey, *lsa_key)
+		if ret == LsdbEntryFound {
+			lsa_pkt = encodeOpaqueLsa(dolsa, *lsa_key)
+			flood = true
+		} else {
+			server.logger.Info(fmt.Sprintln("LSAREQ: Link opaque lsa not found. lsaid ", req.link_state_id, " lstype ", lsa_key.LSType))
+		}
+	case OpaqueAreaLSA, OpaqueASLSA:
+		dolsa, ret := server.getOpaqueLsaFromLsdb(areaid, *lsa_key)
+		if ret == LsdbEntryFound {
+			lsa_pkt = encodeOpaqueLsa(dolsa, *lsa_key)
+			flood = true
+		} else {
+			server.logger.Info(fmt.Sprintln("LSAREQ: Opaque lsa not found. lsaid ", req.link_state_id, " lstype ", lsa_key.LSType))
+		}
 	}
 	lsid := convertUint32ToIPv4(req.link_state_id)
 	router_id := convertUint32ToIPv4(req.adv_router_id)
@@ -1096,6 +1186,11 @@ func (server *OSPFServer) lsaAddCheck(lsaheader ospfLSAHeader,
 		dalsa, ret := server.getASExternalLsaFromLsdb(areaId, *lsa_key)
 		discard, op = server.sanityCheckASExternalLsa(*alsa, dalsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
 
+	case NSSAExternalLSA:
+		nlsa := NewASExternalLsa()
+		dnlsa, ret := server.getNssaExternalLsaFromLsdb(areaId, *lsa_key)
+		discard, op = server.sanityCheckNssaExternalLsa(*nlsa, dnlsa, nbr, intf, intf.IfAreaId, ret, lsa_max_age)
+
 	}
 	if discard {
 		server.logger.Info(fmt.Sprintln("DBD: LSA is not added in the request list. Adv router ", adv_router,
@@ -0,0 +1,628 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"sort"
+)
+
+const (
+	LFA_INFINITY uint32 = 0x7fffffff // the sum of two distances does not wrap
+)
+
+type LfaConfMsg struct {
+	Op    bool
+	Lfa   *config.LfaConf
+	IfLfa *config.IfLfaConf
+}
+
+/*
+   Repair path of a vertex of the area graph. The alternate next hops
+   are installed in ribd next to the primary ones. ribd has no tunnel
+   next hops, so a remote LFA PQ node is only reported.
+*/
+type LfaBackup struct {
+	NextHops      map[NextHop]bool
+	NodeProtected bool
+	PQNode        uint32
+}
+
+/*
+   Neighbor of the calculating router and the link it is reached on,
+   the link is the transit network or the neighbor itself on p2p links.
+*/
+type lfaNbr struct {
+	vKey     VertexKey
+	link     VertexKey
+	nextHop  NextHop
+	cost     uint16
+	excluded bool
+}
+
+type lfaPrimary struct {
+	link VertexKey
+	nbr  VertexKey
+}
+
+type lfaDist map[VertexKey]uint32
+
+func (dist lfaDist) to(vKey VertexKey) uint32 {
+	d, exist := dist[vKey]
+	if !exist {
+		return LFA_INFINITY
+	}
+	return d
+}
+
+type lfaCalc struct {
+	conf     config.LfaConf
+	root     VertexKey
+	nbrs     []lfaNbr
+	dist     map[VertexKey]lfaDist // distances from the vertex
+	revDist  map[VertexKey]lfaDist // distances towards the vertex
+	revGraph map[VertexKey]Vertex
+	pqNodes  map[lfaPrimary][]VertexKey
+}
+
+type lfaCoverage struct {
+	prefixes        int32
+	protected       int32
+	nodeProtected   int32
+	remoteProtected int32
+	pqNodes         int32
+}
+
+type lfaPQList []VertexData
+
+func (v lfaPQList) Len() int {
+	return len(v)
+}
+
+func (v lfaPQList) Less(i, j int) bool {
+	if v[i].distance == v[j].distance {
+		return v[i].vKey.ID < v[j].vKey.ID
+	}
+	return v[i].distance < v[j].distance
+}
+
+func (v lfaPQList) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+func (server *OSPFServer) initLfa() {
+	server.LfaConfigCh = make(chan LfaConfMsg)
+	server.LfaConf = DefaultLfaConf()
+	server.LfaExcludeMap = make(map[IntfConfKey]bool)
+}
+
+func DefaultLfaConf() config.LfaConf {
+	return config.LfaConf{
+		Protection:     config.LfaDisabled,
+		DownstreamOnly: false,
+		RemoteLfa:      false,
+	}
+}
+
+func (server *OSPFServer) ValidateLfaConf(conf config.LfaConf) error {
+	if conf.Protection < config.LfaDisabled || conf.Protection > config.LfaNodeProtection {
+		return errors.New(fmt.Sprintln("Invalid LFA protection", conf.Protection))
+	}
+	if conf.Protection == config.LfaDisabled && (conf.DownstreamOnly || conf.RemoteLfa) {
+		return errors.New("LFA protection has to be enabled for downstream paths or remote LFA")
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateIfLfaConf(conf config.IfLfaConf) error {
+	ip := net.ParseIP(string(conf.IfIpAddress))
+	if ip == nil || ip.To4() == nil {
+		return errors.New(fmt.Sprintln("Invalid interface address", conf.IfIpAddress))
+	}
+	if ip.IsUnspecified() && conf.AddressLessIf == 0 {
+		return errors.New("Unnumbered interface needs an interface index")
+	}
+	return nil
+}
+
+func (server *OSPFServer) processLfaConfig(msg LfaConfMsg) error {
+	switch {
+	case msg.Lfa != nil:
+		conf := DefaultLfaConf()
+		if msg.Op {
+			err := server.ValidateLfaConf(*msg.Lfa)
+			if err != nil {
+				return err
+			}
+			conf = *msg.Lfa
+		}
+		server.LfaMutex.Lock()
+		server.LfaConf = conf
+		server.LfaMutex.Unlock()
+	case msg.IfLfa != nil:
+		key := IntfConfKey{
+			IPAddr:  msg.IfLfa.IfIpAddress,
+			IntfIdx: msg.IfLfa.AddressLessIf,
+		}
+		server.LfaMutex.Lock()
+		if msg.Op && msg.IfLfa.Exclude {
+			server.LfaExcludeMap[key] = true
+		} else {
+			delete(server.LfaExcludeMap, key)
+		}
+		server.LfaMutex.Unlock()
+	default:
+		return errors.New("Empty LFA configuration")
+	}
+	server.scheduleSpf(SpfRequest{AllAreas: true, Trigger: "LFA configuration"})
+	return nil
+}
+
+func (server *OSPFServer) getLfaConf() config.LfaConf {
+	server.LfaMutex.RLock()
+	defer server.LfaMutex.RUnlock()
+	return server.LfaConf
+}
+
+// Unnumbered links carry the interface index in the link data
+func (server *OSPFServer) isLfaExcludedIntf(ifIPAddr uint32) bool {
+	server.LfaMutex.RLock()
+	defer server.LfaMutex.RUnlock()
+	for key, _ := range server.LfaExcludeMap {
+		if convertAreaOrRouterIdUint32(string(key.IPAddr)) == ifIPAddr ||
+			(key.IntfIdx != 0 && uint32(key.IntfIdx) == ifIPAddr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (server *OSPFServer) getLfaNbrs(areaId uint32, root VertexKey) []lfaNbr {
+	nbrs := make([]lfaNbr, 0)
+	rootEnt, exist := server.AreaGraph[root]
+	if !exist {
+		return nbrs
+	}
+	for i, link := range rootEnt.NbrVertexKey {
+		cost := rootEnt.NbrVertexCost[i]
+		if link.Type == RouterVertex {
+			if areaId == 0 {
+				if _, _, ok := server.getVirtLinkNextHop(link.ID); ok {
+					// virtual links only forward over the transit area path
+					continue
+				}
+			}
+			ifIPAddr, nextHopIP, err := server.findP2PNextHopIP(root, link, AreaIdKey{AreaId: areaId})
+			if err != nil {
+				server.logger.Info(fmt.Sprintln("LFA: no next hop for neighbor", link, err))
+				continue
+			}
+			nbrs = append(nbrs, lfaNbr{
+				vKey:     link,
+				link:     link,
+				nextHop:  NextHop{IfIPAddr: ifIPAddr, NextHopIP: nextHopIP},
+				cost:     cost,
+				excluded: server.isLfaExcludedIntf(ifIPAddr),
+			})
+			continue
+		}
+		netEnt, exist := server.AreaGraph[link]
+		if link.Type != TNetworkVertex || !exist {
+			continue
+		}
+		ifIPAddr := rootEnt.LinkData[link]
+		excluded := server.isLfaExcludedIntf(ifIPAddr)
+		for _, vKey := range netEnt.NbrVertexKey {
+			gEnt, exist := server.AreaGraph[vKey]
+			if vKey == root || !exist {
+				continue
+			}
+			nbrs = append(nbrs, lfaNbr{
+				vKey:     vKey,
+				link:     link,
+				nextHop:  NextHop{IfIPAddr: ifIPAddr, NextHopIP: gEnt.LinkData[link]},
+				cost:     cost,
+				excluded: excluded,
+			})
+		}
+	}
+	return nbrs
+}
+
+func (server *OSPFServer) lfaDistFrom(calc *lfaCalc, vKey VertexKey) lfaDist {
+	if dist, exist := calc.dist[vKey]; exist {
+		return dist
+	}
+	spfTree := make(map[VertexKey]TreeVertex)
+	err := server.runDijkstra(server.AreaGraph, spfTree, vKey)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("LFA: Dijkstra from", vKey, "failed", err))
+		spfTree = nil
+	}
+	dist := make(lfaDist)
+	for key, ent := range spfTree {
+		dist[key] = ent.Distance
+	}
+	calc.dist[vKey] = dist
+	return dist
+}
+
+func (server *OSPFServer) lfaDistTo(calc *lfaCalc, vKey VertexKey) lfaDist {
+	if dist, exist := calc.revDist[vKey]; exist {
+		return dist
+	}
+	if calc.revGraph == nil {
+		calc.revGraph = reverseAreaGraph(server.AreaGraph)
+	}
+	spfTree := make(map[VertexKey]TreeVertex)
+	err := server.runDijkstra(calc.revGraph, spfTree, vKey)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("LFA: reverse Dijkstra from", vKey, "failed", err))
+		spfTree = nil
+	}
+	dist := make(lfaDist)
+	for key, ent := range spfTree {
+		dist[key] = ent.Distance
+	}
+	calc.revDist[vKey] = dist
+	return dist
+}
+
+func reverseAreaGraph(areaGraph map[VertexKey]Vertex) map[VertexKey]Vertex {
+	revGraph := make(map[VertexKey]Vertex)
+	for vKey, _ := range areaGraph {
+		revGraph[vKey] = Vertex{
+			NbrVertexKey:  make([]VertexKey, 0),
+			NbrVertexCost: make([]uint16, 0),
+		}
+	}
+	for vKey, ent := range areaGraph {
+		for i, nbrKey := range ent.NbrVertexKey {
+			revEnt, exist := revGraph[nbrKey]
+			if !exist {
+				continue
+			}
+			revEnt.NbrVertexKey = append(revEnt.NbrVertexKey, vKey)
+			revEnt.NbrVertexCost = append(revEnt.NbrVertexCost, ent.NbrVertexCost[i])
+			revGraph[nbrKey] = revEnt
+		}
+	}
+	return revGraph
+}
+
+/*
+   First hop of every shortest path towards the vertex. Paths start
+   at the root and end at the parent of the vertex.
+*/
+func getLfaPrimaries(root VertexKey, vKey VertexKey, tVertex TreeVertex) map[lfaPrimary]bool {
+	primaries := make(map[lfaPrimary]bool)
+	for i := 0; i < tVertex.NumOfPaths && i < len(tVertex.Paths); i++ {
+		path := tVertex.Paths[i]
+		if len(path) == 0 || path[0] != root {
+			continue
+		}
+		var prim lfaPrimary
+		if len(path) == 1 {
+			if vKey.Type != RouterVertex {
+				// directly attached network
+				continue
+			}
+			prim = lfaPrimary{link: vKey, nbr: vKey}
+		} else if path[1].Type == TNetworkVertex {
+			prim.link = path[1]
+			if len(path) == 2 {
+				prim.nbr = vKey
+			} else {
+				prim.nbr = path[2]
+			}
+		} else {
+			prim = lfaPrimary{link: path[1], nbr: path[1]}
+		}
+		primaries[prim] = true
+	}
+	return primaries
+}
+
+/* @fn computeLfa
+Called after Dijkstra of a full run. A shortest path tree is built
+from every neighbor of the calculating router and, per vertex with a
+single primary first hop, the alternates passing the RFC 5286
+inequalities are kept. Vertices without one fall back to a remote
+LFA PQ node (RFC 7490) when enabled.
+*/
+func (server *OSPFServer) computeLfa(areaId uint32, root VertexKey) map[VertexKey]LfaBackup {
+	backups := make(map[VertexKey]LfaBackup)
+	conf := server.getLfaConf()
+	if conf.Protection == config.LfaDisabled {
+		return backups
+	}
+	calc := lfaCalc{
+		conf:    conf,
+		root:    root,
+		nbrs:    server.getLfaNbrs(areaId, root),
+		dist:    make(map[VertexKey]lfaDist),
+		revDist: make(map[VertexKey]lfaDist),
+		pqNodes: make(map[lfaPrimary][]VertexKey),
+	}
+	rootDist := make(lfaDist)
+	for key, ent := range server.SPFTree {
+		rootDist[key] = ent.Distance
+	}
+	calc.dist[root] = rootDist
+	for vKey, tVertex := range server.SPFTree {
+		if vKey == root {
+			continue
+		}
+		primaries := getLfaPrimaries(root, vKey, tVertex)
+		if len(primaries) != 1 {
+			// connected, or the equal cost paths protect each other
+			continue
+		}
+		for prim, _ := range primaries {
+			backup, found := server.selectLfa(&calc, vKey, prim)
+			if !found && conf.RemoteLfa {
+				backup, found = server.selectRemoteLfa(&calc, vKey, prim)
+			}
+			if found {
+				backups[vKey] = backup
+			}
+		}
+	}
+	server.logger.Info(fmt.Sprintln("LFA: areaId:", areaId, "protected vertices:", len(backups), "of", len(server.SPFTree)-1))
+	return backups
+}
+
+func (server *OSPFServer) selectLfa(calc *lfaCalc, vKey VertexKey, prim lfaPrimary) (LfaBackup, bool) {
+	var backup LfaBackup
+	dSD := calc.dist[calc.root].to(vKey)
+	best := make([]lfaNbr, 0)
+	bestNode := false
+	bestCost := uint32(0)
+	for _, nbr := range calc.nbrs {
+		if nbr.excluded || nbr.link == prim.link {
+			// an alternate over the primary link does not survive its failure
+			continue
+		}
+		dN := server.lfaDistFrom(calc, nbr.vKey)
+		dND := dN.to(vKey)
+		if dND >= LFA_INFINITY {
+			continue
+		}
+		// Inequality 1: loop-free
+		if dND >= dN.to(calc.root)+dSD {
+			continue
+		}
+		// Inequality 2: downstream path
+		if calc.conf.DownstreamOnly && dND >= dSD {
+			continue
+		}
+		// Inequality 3: the alternate has to avoid the pseudonode of a broadcast primary link
+		if prim.link.Type == TNetworkVertex &&
+			dND >= dN.to(prim.link)+server.lfaDistFrom(calc, prim.link).to(vKey) {
+			continue
+		}
+		// Inequality 4: node protecting
+		nodeProtected := prim.nbr != vKey &&
+			dND < dN.to(prim.nbr)+server.lfaDistFrom(calc, prim.nbr).to(vKey)
+		cost := uint32(nbr.cost) + dND
+		cmp := 0
+		if len(best) == 0 {
+			cmp = 1
+		} else if calc.conf.Protection == config.LfaNodeProtection && nodeProtected != bestNode {
+			if nodeProtected {
+				cmp = 1
+			} else {
+				cmp = -1
+			}
+		} else if cost < bestCost {
+			cmp = 1
+		} else if cost > bestCost {
+			cmp = -1
+		}
+		if cmp > 0 {
+			best = []lfaNbr{nbr}
+			bestNode = nodeProtected
+			bestCost = cost
+		} else if cmp == 0 {
+			best = append(best, nbr)
+			bestNode = bestNode && nodeProtected
+		}
+	}
+	if len(best) == 0 {
+		return backup, false
+	}
+	backup.NextHops = make(map[NextHop]bool)
+	for _, nbr := range best {
+		backup.NextHops[nbr.nextHop] = true
+	}
+	backup.NodeProtected = bestNode
+	return backup, true
+}
+
+/*
+   PQ nodes of a primary link, nearest first. The extended P-space
+   holds the routers a neighbor reaches without going through the
+   calculating router, the Q-space the routers reaching the primary
+   neighbor without the protected link.
+*/
+func (server *OSPFServer) getPQNodes(calc *lfaCalc, prim lfaPrimary) []VertexKey {
+	if pqNodes, exist := calc.pqNodes[prim]; exist {
+		return pqNodes
+	}
+	dS := calc.dist[calc.root]
+	toE := server.lfaDistTo(calc, prim.nbr)
+	toS := server.lfaDistTo(calc, calc.root)
+	dSE := dS.to(prim.nbr)
+	pqList := make([]VertexData, 0)
+	for yKey, _ := range server.AreaGraph {
+		if yKey.Type != RouterVertex || yKey == calc.root || yKey == prim.nbr {
+			continue
+		}
+		if toE.to(yKey) >= toS.to(yKey)+dSE {
+			continue
+		}
+		inPSpace := false
+		for _, nbr := range calc.nbrs {
+			if nbr.excluded || nbr.link == prim.link {
+				continue
+			}
+			dN := server.lfaDistFrom(calc, nbr.vKey)
+			if dN.to(yKey) < dN.to(calc.root)+dS.to(yKey) {
+				inPSpace = true
+				break
+			}
+		}
+		if inPSpace {
+			pqList = append(pqList, VertexData{vKey: yKey, distance: dS.to(yKey)})
+		}
+	}
+	sort.Sort(lfaPQList(pqList))
+	pqNodes := make([]VertexKey, 0)
+	for _, pq := range pqList {
+		pqNodes = append(pqNodes, pq.vKey)
+	}
+	calc.pqNodes[prim] = pqNodes
+	return pqNodes
+}
+
+func (server *OSPFServer) selectRemoteLfa(calc *lfaCalc, vKey VertexKey, prim lfaPrimary) (LfaBackup, bool) {
+	var backup LfaBackup
+	dSD := calc.dist[calc.root].to(vKey)
+	for _, pq := range server.getPQNodes(calc, prim) {
+		dPQ := server.lfaDistFrom(calc, pq)
+		// the PQ node has to forward to the destination without coming back
+		if pq == vKey || dPQ.to(vKey) < dPQ.to(calc.root)+dSD {
+			backup.PQNode = pq.ID
+			return backup, true
+		}
+	}
+	return backup, false
+}
+
+/*
+   Vertex a route was learnt from. Intra area routes come from router
+   and network LSAs, stubs from the router LSA of their parent. Inter
+   area and external routes go through the border router advertising
+   them.
+*/
+func getLfaVertex(rEnt RoutingTblEntry) VertexKey {
+	if rEnt.PathType == IntraArea {
+		vKey := VertexKey{
+			Type:   RouterVertex,
+			ID:     rEnt.LSOrigin.LSId,
+			AdvRtr: rEnt.LSOrigin.AdvRouter,
+		}
+		if rEnt.LSOrigin.LSType == NetworkLSA {
+			vKey.Type = TNetworkVertex
+		}
+		return vKey
+	}
+	var vKey VertexKey
+	for nextHop, _ := range rEnt.NextHops {
+		vKey = VertexKey{
+			Type:   RouterVertex,
+			ID:     nextHop.AdvRtr,
+			AdvRtr: nextHop.AdvRtr,
+		}
+	}
+	return vKey
+}
+
+func isPrimaryNextHop(rEnt RoutingTblEntry, nextHop NextHop) bool {
+	for key, _ := range rEnt.NextHops {
+		if key.IfIPAddr == nextHop.IfIPAddr && key.NextHopIP == nextHop.NextHopIP {
+			return true
+		}
+	}
+	return false
+}
+
+/* @fn applyLfaBackups
+Attaches the alternates of the area to its network routes once the
+intra area, inter area and external routes are calculated, and
+records the coverage of the area.
+*/
+func (server *OSPFServer) applyLfaBackups(areaId uint32) {
+	areaIdKey := AreaIdKey{
+		AreaId: areaId,
+	}
+	var coverage lfaCoverage
+	pqNodes := make(map[uint32]bool)
+	tempAreaRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
+	for rKey, rEnt := range tempAreaRoutingTbl.RoutingTblMap {
+		rEnt.BackupNextHops = nil
+		if rKey.DestType == Network && len(rEnt.NextHops) > 0 {
+			coverage.prefixes++
+		}
+		if rKey.DestType != Network || len(rEnt.NextHops) != 1 {
+			if len(rEnt.NextHops) > 1 {
+				coverage.protected++
+			}
+			tempAreaRoutingTbl.RoutingTblMap[rKey] = rEnt
+			continue
+		}
+		backup, exist := server.LfaBackups[getLfaVertex(rEnt)]
+		if exist && len(backup.NextHops) > 0 {
+			rEnt.BackupNextHops = make(map[NextHop]bool)
+			for nextHop, _ := range backup.NextHops {
+				if !isPrimaryNextHop(rEnt, nextHop) {
+					rEnt.BackupNextHops[nextHop] = true
+				}
+			}
+			if len(rEnt.BackupNextHops) > 0 {
+				coverage.protected++
+				if backup.NodeProtected {
+					coverage.nodeProtected++
+				}
+			}
+		} else if exist && backup.PQNode != 0 {
+			coverage.remoteProtected++
+			pqNodes[backup.PQNode] = true
+		}
+		tempAreaRoutingTbl.RoutingTblMap[rKey] = rEnt
+	}
+	coverage.pqNodes = int32(len(pqNodes))
+	server.recordLfaCoverage(areaId, coverage)
+}
+
+func (server *OSPFServer) recordLfaCoverage(areaId uint32, coverage lfaCoverage) {
+	server.AreaStateMutex.Lock()
+	defer server.AreaStateMutex.Unlock()
+	areaConfKey := AreaConfKey{
+		AreaId: config.AreaId(convertUint32ToIPv4(areaId)),
+	}
+	ent, exist := server.AreaStateMap[areaConfKey]
+	if !exist {
+		return
+	}
+	ent.LfaPrefixes = coverage.prefixes
+	ent.LfaProtected = coverage.protected
+	ent.LfaNodeProtected = coverage.nodeProtected
+	ent.LfaRemoteProtected = coverage.remoteProtected
+	ent.LfaPQNodes = coverage.pqNodes
+	server.AreaStateMap[areaConfKey] = ent
+}
@@ -0,0 +1,261 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfLfa_test
+   This test covers
+   1) LFA and interface exclusion configuration.
+   2) Link and node protecting alternates on a ring.
+   3) Remote LFA PQ node when no alternate is loop-free.
+   4) Links excluded from being backups.
+   5) Backup next hops of the routes and the area coverage.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"testing"
+)
+
+var lfaRtr map[int]VertexKey
+var lfaNet map[int]VertexKey
+
+func initLfaTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+}
+
+func addLfaTestLink(rtr VertexKey, net VertexKey, cost uint16, ifIpAddr string) {
+	rEnt := ospf.AreaGraph[rtr]
+	if rEnt.LinkData == nil {
+		rEnt.LinkData = make(map[VertexKey]uint32)
+	}
+	rEnt.NbrVertexKey = append(rEnt.NbrVertexKey, net)
+	rEnt.NbrVertexCost = append(rEnt.NbrVertexCost, cost)
+	rEnt.LinkData[net] = convertAreaOrRouterIdUint32(ifIpAddr)
+	rEnt.LsaKey = LsaKey{LSType: RouterLSA, LSId: rtr.ID, AdvRouter: rtr.AdvRtr}
+	ospf.AreaGraph[rtr] = rEnt
+	nEnt := ospf.AreaGraph[net]
+	if nEnt.LinkData == nil {
+		nEnt.LinkData = make(map[VertexKey]uint32)
+	}
+	nEnt.NbrVertexKey = append(nEnt.NbrVertexKey, rtr)
+	nEnt.NbrVertexCost = append(nEnt.NbrVertexCost, 0)
+	nEnt.LinkData[rtr] = 0xffffff00
+	nEnt.LsaKey = LsaKey{LSType: NetworkLSA, LSId: net.ID, AdvRouter: net.AdvRtr}
+	ospf.AreaGraph[net] = nEnt
+}
+
+/*
+   R1 - N12 - R2 - N24 - R4 - N34 - R3 - N13 - R1
+   R1 is the calculating router, N13 is the expensive side of the ring.
+*/
+func initLfaTestGraph() {
+	lfaRtr = make(map[int]VertexKey)
+	lfaNet = make(map[int]VertexKey)
+	for i := 1; i <= 4; i++ {
+		rtrId := convertAreaOrRouterIdUint32(fmt.Sprintf("%d.%d.%d.%d", i, i, i, i))
+		lfaRtr[i] = VertexKey{Type: RouterVertex, ID: rtrId, AdvRtr: rtrId}
+	}
+	for _, n := range []int{12, 13, 24, 34} {
+		dr := n / 10
+		lfaNet[n] = VertexKey{
+			Type:   TNetworkVertex,
+			ID:     convertAreaOrRouterIdUint32(fmt.Sprintf("10.1.%d.%d", n, dr)),
+			AdvRtr: lfaRtr[dr].ID,
+		}
+	}
+	ospf.initialiseSPFStructs()
+	addLfaTestLink(lfaRtr[1], lfaNet[12], 10, "10.1.12.1")
+	addLfaTestLink(lfaRtr[1], lfaNet[13], 20, "10.1.13.1")
+	addLfaTestLink(lfaRtr[2], lfaNet[12], 10, "10.1.12.2")
+	addLfaTestLink(lfaRtr[2], lfaNet[24], 10, "10.1.24.2")
+	addLfaTestLink(lfaRtr[3], lfaNet[13], 10, "10.1.13.3")
+	addLfaTestLink(lfaRtr[3], lfaNet[34], 10, "10.1.34.3")
+	addLfaTestLink(lfaRtr[4], lfaNet[24], 10, "10.1.24.4")
+	addLfaTestLink(lfaRtr[4], lfaNet[34], 5, "10.1.34.4")
+	ospf.ExecuteDijkstra(lfaRtr[1], 0)
+}
+
+func TestOspfLfa(t *testing.T) {
+	fmt.Println("\n**************** LFA ************\n")
+	initLfaTestParams()
+	for index := 1; index < 6; index++ {
+		err := lfaTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for LFA ", index)
+		}
+	}
+}
+
+func lfaTestLogic(tNum int) int {
+	viaR3 := NextHop{
+		IfIPAddr:  convertAreaOrRouterIdUint32("10.1.13.1"),
+		NextHopIP: convertAreaOrRouterIdUint32("10.1.13.3"),
+	}
+	viaR2 := NextHop{
+		IfIPAddr:  convertAreaOrRouterIdUint32("10.1.12.1"),
+		NextHopIP: convertAreaOrRouterIdUint32("10.1.12.2"),
+	}
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running processLfaConfig")
+		bad := config.LfaConf{Protection: config.LfaDisabled, RemoteLfa: true}
+		if ospf.ValidateLfaConf(bad) == nil {
+			fmt.Println("Remote LFA accepted without LFA protection")
+			return FAIL
+		}
+		if ospf.ValidateIfLfaConf(config.IfLfaConf{IfIpAddress: "0.0.0.0"}) == nil {
+			fmt.Println("Unnumbered interface accepted without an index")
+			return FAIL
+		}
+		conf := config.LfaConf{Protection: config.LfaNodeProtection}
+		ospf.processLfaConfig(LfaConfMsg{Op: true, Lfa: &conf})
+		if ospf.getLfaConf() != conf {
+			fmt.Println("LFA configuration not applied ", ospf.LfaConf)
+			return FAIL
+		}
+		ifConf := config.IfLfaConf{IfIpAddress: "10.1.13.1", Exclude: true}
+		ospf.processLfaConfig(LfaConfMsg{Op: true, IfLfa: &ifConf})
+		if !ospf.isLfaExcludedIntf(viaR3.IfIPAddr) {
+			fmt.Println("Interface not excluded from LFA")
+			return FAIL
+		}
+		ospf.processLfaConfig(LfaConfMsg{Op: false, IfLfa: &ifConf})
+		if ospf.isLfaExcludedIntf(viaR3.IfIPAddr) {
+			fmt.Println("Interface exclusion not removed")
+			return FAIL
+		}
+		stopTimer(ospf.SpfThrottle.SpfTimer)
+
+	case 2:
+		fmt.Println(tNum, ": Running computeLfa")
+		ospf.LfaConf = config.LfaConf{Protection: config.LfaNodeProtection}
+		initLfaTestGraph()
+		backups := ospf.computeLfa(0, lfaRtr[1])
+		backup, exist := backups[lfaRtr[4]]
+		if !exist || !backup.NextHops[viaR3] || len(backup.NextHops) != 1 {
+			fmt.Println("No alternate through R3 for R4 ", backups[lfaRtr[4]])
+			return FAIL
+		}
+		if !backup.NodeProtected {
+			fmt.Println("Alternate for R4 not node protecting")
+			return FAIL
+		}
+		if _, exist := backups[lfaRtr[2]]; exist {
+			fmt.Println("Looping alternate accepted for R2 ", backups[lfaRtr[2]])
+			return FAIL
+		}
+		if _, exist := backups[lfaNet[12]]; exist {
+			fmt.Println("Alternate computed for a connected network")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running remote LFA")
+		ospf.LfaConf = config.LfaConf{Protection: config.LfaLinkProtection, RemoteLfa: true}
+		initLfaTestGraph()
+		backups := ospf.computeLfa(0, lfaRtr[1])
+		backup, exist := backups[lfaRtr[2]]
+		if !exist || backup.PQNode != lfaRtr[4].ID || len(backup.NextHops) != 0 {
+			fmt.Println("R4 not chosen as PQ node for R2 ", backup)
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running excluded interface")
+		ospf.LfaConf = config.LfaConf{Protection: config.LfaLinkProtection}
+		ospf.LfaExcludeMap[IntfConfKey{IPAddr: "10.1.13.1"}] = true
+		initLfaTestGraph()
+		backups := ospf.computeLfa(0, lfaRtr[1])
+		delete(ospf.LfaExcludeMap, IntfConfKey{IPAddr: "10.1.13.1"})
+		if len(backups) != 0 {
+			fmt.Println("Alternate through an excluded interface ", backups)
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running applyLfaBackups")
+		ospf.LfaConf = config.LfaConf{Protection: config.LfaNodeProtection, RemoteLfa: true}
+		areaConfKey := AreaConfKey{AreaId: config.AreaId("0.0.0.0")}
+		ospf.initAreaStateSlice(areaConfKey)
+		initLfaTestGraph()
+		ospf.LfaBackups = ospf.computeLfa(0, lfaRtr[1])
+		areaIdKey := AreaIdKey{AreaId: 0}
+		net34Key := RoutingTblEntryKey{DestId: 0x0a012200, AddrMask: 0xffffff00, DestType: Network}
+		stub4Key := RoutingTblEntryKey{DestId: 0x0a040400, AddrMask: 0xffffff00, DestType: Network}
+		stub2Key := RoutingTblEntryKey{DestId: 0x0a020200, AddrMask: 0xffffff00, DestType: Network}
+		ecmpKey := RoutingTblEntryKey{DestId: 0x0a050500, AddrMask: 0xffffff00, DestType: Network}
+		rTbl := AreaRoutingTbl{RoutingTblMap: make(map[RoutingTblEntryKey]RoutingTblEntry)}
+		rTbl.RoutingTblMap[net34Key] = RoutingTblEntry{
+			PathType: IntraArea,
+			Cost:     25,
+			LSOrigin: ospf.AreaGraph[lfaNet[34]].LsaKey,
+			NextHops: map[NextHop]bool{viaR2: true},
+		}
+		rTbl.RoutingTblMap[stub4Key] = RoutingTblEntry{
+			PathType: IntraArea,
+			Cost:     30,
+			LSOrigin: ospf.AreaGraph[lfaRtr[4]].LsaKey,
+			NextHops: map[NextHop]bool{viaR2: true},
+		}
+		rTbl.RoutingTblMap[stub2Key] = RoutingTblEntry{
+			PathType: IntraArea,
+			Cost:     20,
+			LSOrigin: ospf.AreaGraph[lfaRtr[2]].LsaKey,
+			NextHops: map[NextHop]bool{viaR2: true},
+		}
+		rTbl.RoutingTblMap[ecmpKey] = RoutingTblEntry{
+			PathType: IntraArea,
+			Cost:     40,
+			NextHops: map[NextHop]bool{viaR2: true, viaR3: true},
+		}
+		ospf.TempAreaRoutingTbl[areaIdKey] = rTbl
+		ospf.applyLfaBackups(0)
+		ospf.LfaBackups = nil
+		rTbl = ospf.TempAreaRoutingTbl[areaIdKey]
+		for _, rKey := range []RoutingTblEntryKey{net34Key, stub4Key} {
+			rEnt := rTbl.RoutingTblMap[rKey]
+			if len(rEnt.BackupNextHops) != 1 || !rEnt.BackupNextHops[viaR3] {
+				fmt.Println("Backup next hop not attached to ", rKey, rEnt.BackupNextHops)
+				return FAIL
+			}
+		}
+		if len(rTbl.RoutingTblMap[stub2Key].BackupNextHops) != 0 ||
+			len(rTbl.RoutingTblMap[ecmpKey].BackupNextHops) != 0 {
+			fmt.Println("Unexpected backup next hops")
+			return FAIL
+		}
+		ent := ospf.AreaStateMap[areaConfKey]
+		if ent.LfaPrefixes != 4 || ent.LfaProtected != 3 || ent.LfaNodeProtected != 2 ||
+			ent.LfaRemoteProtected != 1 || ent.LfaPQNodes != 1 {
+			fmt.Println("Unexpected LFA coverage ", ent.LfaPrefixes, ent.LfaProtected,
+				ent.LfaNodeProtected, ent.LfaRemoteProtected, ent.LfaPQNodes)
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
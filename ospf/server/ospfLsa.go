@@ -30,11 +30,15 @@ import (
 )
 
 const (
-	RouterLSA     uint8 = 1
-	NetworkLSA    uint8 = 2
-	Summary3LSA   uint8 = 3
-	Summary4LSA   uint8 = 4
-	ASExternalLSA uint8 = 5
+	RouterLSA       uint8 = 1
+	NetworkLSA      uint8 = 2
+	Summary3LSA     uint8 = 3
+	Summary4LSA     uint8 = 4
+	ASExternalLSA   uint8 = 5
+	NSSAExternalLSA uint8 = 7
+	OpaqueLinkLSA   uint8 = 9
+	OpaqueAreaLSA   uint8 = 10
+	OpaqueASLSA     uint8 = 11
 )
 
 type LsaKey struct {
@@ -87,6 +91,7 @@ func NewLsaHeader() *LsaHeader {
 /* LS Type 1 */
 type RouterLsa struct {
 	LsaMd       LsaMetadata
+	BitNt       bool         /* Nt Bit, NSSA translator (RFC 3101) */
 	BitV        bool         /* V Bit */
 	BitE        bool         /* Bit E */
 	BitB        bool         /* Bit B */
@@ -134,7 +139,7 @@ type ASExtTOSDetail struct {
 	TOSExtRouteTag uint32
 }
 
-/* LS Type 5 */
+/* LS Type 5, also used for the NSSA LS Type 7 */
 type ASExternalLsa struct {
 	LsaMd           LsaMetadata
 	Netmask         uint32 /* Network Mask */
@@ -150,11 +155,14 @@ func NewASExternalLsa() *ASExternalLsa {
 }
 
 type LSDatabase struct {
-	RouterLsaMap     map[LsaKey]RouterLsa
-	NetworkLsaMap    map[LsaKey]NetworkLsa
-	Summary3LsaMap   map[LsaKey]SummaryLsa
-	Summary4LsaMap   map[LsaKey]SummaryLsa
-	ASExternalLsaMap map[LsaKey]ASExternalLsa
+	RouterLsaMap       map[LsaKey]RouterLsa
+	NetworkLsaMap      map[LsaKey]NetworkLsa
+	Summary3LsaMap     map[LsaKey]SummaryLsa
+	Summary4LsaMap     map[LsaKey]SummaryLsa
+	ASExternalLsaMap   map[LsaKey]ASExternalLsa
+	NSSAExternalLsaMap map[LsaKey]ASExternalLsa
+	OpaqueAreaLsaMap   map[LsaKey]OpaqueLsa
+	OpaqueASLsaMap     map[LsaKey]OpaqueLsa
 }
 
 type maxAgeLsaMsg struct {
@@ -260,6 +268,11 @@ func decodeRouterLsa(data []byte, lsa *RouterLsa, lsakey *LsaKey) {
 	lsa.LsaMd.LSSequenceNum = int(binary.BigEndian.Uint32(data[12:16]))
 	lsa.LsaMd.LSChecksum = binary.BigEndian.Uint16(data[16:18])
 	lsa.LsaMd.LSLen = binary.BigEndian.Uint16(data[18:20])
+	if data[20]&0x10 != 0 {
+		lsa.BitNt = true
+	} else {
+		lsa.BitNt = false
+	}
 	if data[20]&0x04 != 0 {
 		lsa.BitV = true
 	} else {
@@ -332,6 +345,9 @@ func encodeRouterLsa(lsa RouterLsa, lsakey LsaKey) []byte {
 	lsaHdr := encodeLsaHeader(lsa.LsaMd, lsakey)
 	copy(rtrLsa[0:20], lsaHdr)
 	var val uint8 = 0
+	if lsa.BitNt == true {
+		val = val | 1<<4
+	}
 	if lsa.BitV == true {
 		val = val | 1<<2
 	}
@@ -668,6 +684,18 @@ func (server *OSPFServer) getASExternalLsaFromLsdb(areaId uint32, lsaKey LsaKey)
 	return lsa, LsdbEntryFound
 }
 
+func (server *OSPFServer) getNssaExternalLsaFromLsdb(areaId uint32, lsaKey LsaKey) (lsa ASExternalLsa, retVal int) {
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	lsDbEnt, _ := server.AreaLsdb[lsdbKey]
+	lsa, exist := lsDbEnt.NSSAExternalLsaMap[lsaKey]
+	if !exist {
+		return lsa, LsdbEntryNotFound
+	}
+	return lsa, LsdbEntryFound
+}
+
 func (server *OSPFServer) processMaxAgeLSA(lsdbKey LsdbKey, lsdbEnt LSDatabase) {
 	flood_lsa := false
 	/* Router LSA */
@@ -727,6 +755,25 @@ func (server *OSPFServer) processMaxAgeLSA(lsdbKey LsdbKey, lsdbEnt LSDatabase)
 			lsdbEnt.ASExternalLsaMap[lsakey] = lsa_ex
 		}
 	}
+	/* NSSA external LSA */
+	for lsakey, lsa_nssa := range lsdbEnt.NSSAExternalLsaMap {
+		if lsa_nssa.LsaMd.LSAge == config.MaxAge {
+			// add to flood list
+			lsa_pkt := encodeASExternalLsa(lsa_nssa, lsakey)
+			maxAgeLsaMap[lsakey] = lsa_pkt
+			// delete LSA
+			delete(lsdbEnt.NSSAExternalLsaMap, lsakey)
+			advRouter := convertUint32ToIPv4(lsakey.AdvRouter)
+			lsid := convertUint32ToIPv4(lsakey.LSId)
+			server.logger.Info(fmt.Sprintln("DELETE: Max age reached. adv_router ",
+				advRouter, " lstype ", lsakey.LSType, " lsid ", lsid))
+			flood_lsa = true
+
+		} else {
+			lsa_nssa.LsaMd.LSAge++
+			lsdbEnt.NSSAExternalLsaMap[lsakey] = lsa_nssa
+		}
+	}
 	/* Summary 3 */
 	for lsakey, lsa_sum := range lsdbEnt.Summary3LsaMap {
 		if lsa_sum.LsaMd.LSAge == config.MaxAge {
@@ -764,6 +811,14 @@ func (server *OSPFServer) processMaxAgeLSA(lsdbKey LsdbKey, lsdbEnt LSDatabase)
 			lsdbEnt.Summary4LsaMap[lsakey] = lsa_sum4
 		}
 	}
+
+	/* Opaque */
+	if server.processMaxAgeOpaqueLsa(lsdbEnt.OpaqueAreaLsaMap) {
+		flood_lsa = true
+	}
+	if server.processMaxAgeOpaqueLsa(lsdbEnt.OpaqueASLsaMap) {
+		flood_lsa = true
+	}
 	if flood_lsa {
 		/* send msg to ospfNbrLsaUpdSendCh */
 		flood_pkt := ospfFloodMsg{
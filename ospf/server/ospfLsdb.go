@@ -85,6 +85,9 @@ func (server *OSPFServer) initLSDatabase(areaId uint32) {
 		lsDbEnt.Summary3LsaMap = make(map[LsaKey]SummaryLsa)
 		lsDbEnt.Summary4LsaMap = make(map[LsaKey]SummaryLsa)
 		lsDbEnt.ASExternalLsaMap = make(map[LsaKey]ASExternalLsa)
+		lsDbEnt.NSSAExternalLsaMap = make(map[LsaKey]ASExternalLsa)
+		lsDbEnt.OpaqueAreaLsaMap = make(map[LsaKey]OpaqueLsa)
+		lsDbEnt.OpaqueASLsaMap = make(map[LsaKey]OpaqueLsa)
 		server.AreaLsdb[lsdbKey] = lsDbEnt
 	}
 	selfOrigLsaEnt, exist := server.AreaSelfOrigLsa[lsdbKey]
@@ -107,6 +110,7 @@ func (server *OSPFServer) StartLSDatabase() {
 	// start LSDB aging ticker
 	lsdbTickerCh = time.NewTimer(time.Second * 1)
 	lsdbRefreshTickerCh = time.NewTimer(time.Second * time.Duration(config.LSRefreshTime))
+	server.startStubRouter()
 	go server.processLSDatabaseUpdates()
 	return
 }
@@ -265,6 +269,7 @@ func (server *OSPFServer) installSummaryLsa() {
 		oldSelfOrigSummaryLsa = nil
 	}
 	server.SummaryLsDb = nil
+	server.installNssaTranslatedLsa()
 }
 
 func (server *OSPFServer) flushNetworkLSA(areaId uint32, key IntfConfKey) {
@@ -311,6 +316,9 @@ func (server *OSPFServer) flushNetworkLSA(areaId uint32, key IntfConfKey) {
 }
 
 func (server *OSPFServer) generateNetworkLSA(areaId uint32, key IntfConfKey, isDR bool) {
+	if server.isGraceRestarting() {
+		return
+	}
 
 	//routerId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
 	ent := server.IntfConfMap[key]
@@ -325,6 +333,10 @@ func (server *OSPFServer) generateNetworkLSA(areaId uint32, key IntfConfKey, isD
 	}
 
 	netmask := convertIPv4ToUint32(ent.IfNetmask)
+	if server.isPrefixSuppressed(key) {
+		// RFC 6860 4.2: only the host route of the DR is advertised
+		netmask = 0xffffffff
+	}
 	attachedRtr := make([]uint32, 0)
 
 	for index := range nbrmdata.nbrList {
@@ -452,6 +464,9 @@ func (server *OSPFServer) constructStubLinkP2P(ent IntfConf, likType config.IfTy
 }
 
 func (server *OSPFServer) generateRouterLSA(areaId uint32) {
+	if server.isGraceRestarting() {
+		return
+	}
 	var linkDetails []LinkDetail = nil
 	for key, ent := range server.IntfConfMap {
 		AreaId := convertIPv4ToUint32(ent.IfAreaId)
@@ -471,8 +486,12 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 		}
 		var linkDetail LinkDetail
 		switch ent.IfType {
-		case config.Broadcast:
+		case config.Broadcast, config.Nbma:
 			if len(ent.NeighborMap) == 0 { // Stub Network
+				if server.isPrefixSuppressed(key) {
+					server.logger.Info(fmt.Sprintln("LSDB: Stub network suppressed ", ent.IfIpAddr))
+					continue
+				}
 				server.logger.Info("Stub Network")
 				ipAddr := convertAreaOrRouterIdUint32(ent.IfIpAddr.String())
 				netmask := convertIPv4ToUint32(ent.IfNetmask)
@@ -506,8 +525,10 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 				                    network) should be added.
 
 			*/
-			stub_link := server.constructStubLinkP2P(ent, config.NumberedP2P)
-			linkDetails = append(linkDetails, stub_link)
+			if !server.isPrefixSuppressed(key) {
+				stub_link := server.constructStubLinkP2P(ent, config.NumberedP2P)
+				linkDetails = append(linkDetails, stub_link)
+			}
 			/* The Link ID should be
 			   set to the Router ID of the neighboring router. For
 			   numbered point-to-point networks, the Link Data
@@ -535,8 +556,10 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 			linkDetail.LinkMetric = uint16(ent.IfCost)
 
 		case config.UnnumberedP2P:
-			stub_link := server.constructStubLinkP2P(ent, config.UnnumberedP2P)
-			linkDetails = append(linkDetails, stub_link)
+			if !server.isPrefixSuppressed(key) {
+				stub_link := server.constructStubLinkP2P(ent, config.UnnumberedP2P)
+				linkDetails = append(linkDetails, stub_link)
+			}
 			if len(ent.NeighborMap) == 0 {
 				server.logger.Info(fmt.Sprintln("LSDB: No neighbor detected for P2P link ", ent.IfIpAddr))
 				continue
@@ -553,21 +576,40 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 			linkDetail.LinkType = P2PLink
 			linkDetail.NumOfTOS = 0
 			linkDetail.LinkMetric = uint16(ent.IfCost)
+		case config.PointToMultipoint:
+			linkDetails = append(linkDetails, server.constructP2MPLinks(key, ent)...)
+			continue
+		case config.VirtualLink:
+			vLink, full := server.constructVirtualLink(key, ent)
+			if !full {
+				server.logger.Info(fmt.Sprintln("LSDB: Virtual link not adjacent ", key.IPAddr))
+				continue
+			}
+			linkDetail = vLink
 		}
 		linkDetails = append(linkDetails, linkDetail)
 	}
+	server.applyStubRouterMetric(linkDetails)
 
 	numOfLinks := len(linkDetails)
 
 	LSType := RouterLSA
 	LSId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
-	Options := uint8(2) // Need to be revisited
+	Options := server.getAreaOptions(config.AreaId(convertUint32ToIPv4(areaId)))
 	LSAge := 0
 	AdvRouter := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
 	BitE := false //not an AS boundary router (Todo)
 	BitB := false
+	BitNt := false
+	BitV := areaId != 0 && server.isVirtLinkTransitArea(areaId)
 	if server.ospfGlobalConf.AreaBdrRtrStatus == true {
 		BitB = true
+		// RFC 3101 2.3: Nt is set by NSSA border routers which always translate
+		aConf, _ := server.AreaConfMap[AreaConfKey{AreaId: config.AreaId(convertUint32ToIPv4(areaId))}]
+		if aConf.ImportAsExtern == config.ImportNssa &&
+			aConf.AreaNssaTranslatorRole == config.Always {
+			BitNt = true
+		}
 	}
 	lsaKey := LsaKey{
 		LSType:    LSType,
@@ -606,6 +648,8 @@ func (server *OSPFServer) generateRouterLSA(areaId uint32) {
 	ent.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 4 + (12 * numOfLinks))
 	ent.BitE = BitE
 	ent.BitB = BitB
+	ent.BitNt = BitNt
+	ent.BitV = BitV
 	ent.NumOfLinks = uint16(numOfLinks)
 	ent.LinkDetails = make([]LinkDetail, numOfLinks)
 	copy(ent.LinkDetails, linkDetails[0:])
@@ -649,8 +693,13 @@ func (server *OSPFServer) generateASExternalLsa(route RouteMdata) LsaKey {
 		AdvRouter: AdvRouter,
 	}
 
-	BitE := true
+	BitE := !route.type1
 	for lsdbKey, _ := range server.AreaLsdb {
+		areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+		if server.isStubArea(areaId) || server.isNssaArea(areaId) {
+			// AS external LSAs are not flooded into stub areas and NSSAs
+			continue
+		}
 		lsDbEnt, _ := server.AreaLsdb[lsdbKey]
 		ent, exist := lsDbEnt.ASExternalLsaMap[lsaKey]
 		LSAge := 0
@@ -668,9 +717,9 @@ func (server *OSPFServer) generateASExternalLsa(route RouteMdata) LsaKey {
 		}
 		ent.BitE = BitE
 		ent.FwdAddr = convertAreaOrRouterIdUint32("0.0.0.0")
-		ent.Metric = route.metric
+		ent.Metric = server.getStubRouterExtMetric(route.metric)
 		ent.Netmask = route.mask
-		ent.ExtRouteTag = 0
+		ent.ExtRouteTag = route.tag
 
 		LsaEnc := encodeASExternalLsa(ent, lsaKey)
 		checksumOffset := uint16(14)
@@ -1043,6 +1092,12 @@ func (server *OSPFServer) processRecvdLsa(data []byte, areaId uint32) bool {
 		return server.processRecvdSummaryLsa(data, areaId, LSType)
 	} else if LSType == ASExternalLSA {
 		return server.processRecvdASExternalLsa(data, areaId)
+	} else if LSType == NSSAExternalLSA {
+		server.logger.Info("LSDB: Received NSSA external lsa")
+		return server.processRecvdNssaExternalLsa(data, areaId)
+	} else if LSType == OpaqueAreaLSA || LSType == OpaqueASLSA {
+		server.logger.Info("LSDB: Received opaque lsa")
+		return server.processRecvdOpaqueLsa(data, areaId)
 	} else {
 		server.logger.Info("LSDB: Invalid LSA packet from nbr")
 		return false
@@ -1062,6 +1117,10 @@ func (server *OSPFServer) processDeleteLsa(data []byte, areaId uint32) bool {
 		return server.processDeleteSummaryLsa(data, areaId, LSType)
 	} else if LSType == ASExternalLSA {
 		return server.processDeleteASExternalLsa(data, areaId)
+	} else if LSType == NSSAExternalLSA {
+		return server.processDeleteNssaExternalLsa(data, areaId)
+	} else if LSType == OpaqueAreaLSA || LSType == OpaqueASLSA {
+		return server.processDeleteOpaqueLsa(data, areaId)
 	} else {
 		return false
 	}
@@ -1071,63 +1130,45 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 	for {
 		select {
 		case msg := <-server.LsdbUpdateCh:
+			lsaKey := getLsaKeyFromData(msg.Data)
+			before := server.getRouterLsaTopology(msg.AreaId, lsaKey)
+			oldLsa := server.encodeLsaFromLsdb(msg.AreaId, lsaKey)
 			if msg.MsgType == LsdbAdd {
 				server.logger.Info("Adding LS in the Lsdb")
 				server.logger.Info("Received New LSA")
 				ret := server.processRecvdLsa(msg.Data, msg.AreaId)
 				server.logger.Info(fmt.Sprintln("Return Code:", ret))
 				//server.LsaUpdateRetCodeCh <- ret
-				server.StartCalcSPFCh <- true
-				spfStatus := <-server.DoneCalcSPFCh
-				server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
-				if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-					server.installSummaryLsa()
-				}
 			} else if msg.MsgType == LsdbDel {
 				server.logger.Info("Deleting LS in the Lsdb")
 				ret := server.processDeleteLsa(msg.Data, msg.AreaId)
 				//server.LsaUpdateRetCodeCh <- ret
 				server.logger.Info(fmt.Sprintln("Return Code:", ret))
-				server.StartCalcSPFCh <- true
-				spfStatus := <-server.DoneCalcSPFCh
-				server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
-				if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-					server.installSummaryLsa()
-				}
 			} else if msg.MsgType == LsdbUpdate {
 				server.logger.Info("Deleting LS in the Lsdb")
 				ret := server.processRecvdLsa(msg.Data, msg.AreaId)
 				//server.LsaUpdateRetCodeCh <- ret
 				server.logger.Info(fmt.Sprintln("Return Code:", ret))
-				server.StartCalcSPFCh <- true
-				spfStatus := <-server.DoneCalcSPFCh
-				server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
-				if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-					server.installSummaryLsa()
-				}
+			}
+			server.checkGraceHelperTopology(msg.AreaId, lsaKey, oldLsa, msg.Data)
+			server.checkGraceRestartExit(msg.AreaId, lsaKey)
+			if !isOpaqueLsaType(lsaKey.LSType) {
+				server.scheduleSpf(server.getLsaSpfRequest(msg.AreaId, lsaKey, before))
 			}
 		case msg := <-server.IntfStateChangeCh:
 			server.logger.Info(fmt.Sprintf("Interface State change msg", msg))
 			server.generateRouterLSA(msg.areaId)
 			//server.logger.Info(fmt.Sprintln("LS Database", server.AreaLsdb))
-			server.StartCalcSPFCh <- true
-			spfStatus := <-server.DoneCalcSPFCh
-			server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
+			server.scheduleSpf(newAreaSpfRequest(msg.areaId, true, "Interface state change"))
 			server.processInterfaceChangeMsg(msg)
-			if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-				server.installSummaryLsa()
-			}
+			server.refreshTeLsas()
 		case msg := <-server.NetworkDRChangeCh:
 			server.logger.Info(fmt.Sprintf("Network DR change msg", msg))
 			// Create a new router LSA
 			//server.logger.Info(fmt.Sprintln("LS Database", server.AreaLsdb))
 			server.processDrBdrChangeMsg(msg)
-			server.StartCalcSPFCh <- true
-			spfStatus := <-server.DoneCalcSPFCh
-			server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
-			if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-				server.installSummaryLsa()
-			}
+			server.scheduleSpf(newAreaSpfRequest(msg.areaId, true, "DR change"))
+			server.refreshTeLsas()
 		case msg := <-server.CreateNetworkLSACh:
 			server.logger.Info(fmt.Sprintf("Create Network LSA msg", msg))
 			server.processNeighborFullEvent(msg)
@@ -1137,16 +1178,83 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 			// If link is broadcast
 			// Create Network LSA
 			//server.logger.Info(fmt.Sprintln("LS Database", server.AreaLsdb))
-			server.StartCalcSPFCh <- true
-			spfStatus := <-server.DoneCalcSPFCh
-			server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
-			if server.ospfGlobalConf.AreaBdrRtrStatus == true {
-				server.installSummaryLsa()
+			server.checkGraceRestartExit(msg.areaId, LsaKey{})
+			server.scheduleSpf(newAreaSpfRequest(msg.areaId, true, "Neighbor full"))
+			server.refreshTeLsas()
+
+		case <-server.SpfThrottle.SpfTimer.C:
+			server.processSpfTimerExpiry()
+
+		case <-server.SpfThrottle.LearnTimer.C:
+			server.processSpfLearnTimerExpiry()
+
+		case <-server.SpfThrottle.HolddownTimer.C:
+			server.processSpfHolddownTimerExpiry()
+
+		case conf := <-server.SpfThrottleConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing SPF throttle Configuration", conf))
+			err := server.processSpfThrottleConfig(conf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("SPF throttle configuration failed", err))
+			}
+
+		case msg := <-server.LfaConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing LFA Configuration", msg))
+			err := server.processLfaConfig(msg)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("LFA configuration failed", err))
+			}
+
+		case conf := <-server.StubRouterConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Stub Router Configuration", conf))
+			err := server.processStubRouterConfig(conf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Stub router configuration failed", err))
+			}
+
+		case <-server.StubRouter.StartupTimer.C:
+			server.processStubRouterTimerExpiry()
+
+		case <-server.BgpConvergedCh:
+			server.processBgpConverged()
+
+		case <-server.GraceRestart.GraceTimer.C:
+			server.processGraceTimerExpiry()
+
+		case reason := <-server.GraceRestartPrepareCh:
+			server.logger.Info(fmt.Sprintln("Received call for preparing graceful restart", reason))
+			err := server.processGraceRestartPrepare(reason)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Graceful restart prepare failed", err))
+			}
+
+		case msg := <-server.GraceHelperExitCh:
+			server.exitGraceHelper(msg.nbrKey, msg.reason)
+
+		case conf := <-server.GracefulRestartConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Graceful Restart Configuration", conf))
+			err := server.processGracefulRestartConfig(conf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Graceful restart configuration failed", err))
+			}
+
+		case msg := <-server.OpaqueConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Opaque LSA Configuration", msg))
+			err := server.processOpaqueConfig(msg)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Opaque LSA configuration failed", err))
 			}
 
 		case msg := <-server.ExternalRouteNotif: //Generate external LSA
 			server.processExtRouteUpd(msg)
 
+		case msg := <-server.AggregateConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Aggregate Configuration", msg))
+			err := server.processAggregateConfig(msg)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Aggregate configuration failed", err))
+			}
+
 		case msg := <-server.maxAgeLsaCh: //Flood MaxAge LSA
 			server.processMaxAgeLsaMsg(msg)
 
@@ -1164,16 +1272,33 @@ func (server *OSPFServer) processLSDatabaseUpdates() {
 }
 
 /*@fn processExtRouteUpd
+Routes covered by a summary address are aggregated,
+the others are originated as they are.
+*/
+func (server *OSPFServer) processExtRouteUpd(msg RouteMdata) {
+	if server.aggregateExtRoute(msg) {
+		return
+	}
+	server.originateExtRoute(msg)
+}
+
+/*@fn originateExtRoute
 Generate / delete As external LSA.
 Send flood message if new route is added.
 */
-func (server *OSPFServer) processExtRouteUpd(msg RouteMdata) {
+func (server *OSPFServer) originateExtRoute(msg RouteMdata) {
 	ifkey := IntfConfKey{}
 	nbr := NeighborConfKey{}
 	lsaKey := server.generateASExternalLsa(msg)
 	if !msg.isDel {
 		server.sendLsdbToNeighborEvent(ifkey, nbr, 0, 0, 0, lsaKey, LSAEXTFLOOD)
 	}
+	nssaLsaKeys := server.generateNssaExternalLsa(msg)
+	if !msg.isDel {
+		for areaId, nssaLsaKey := range nssaLsaKeys {
+			server.sendLsdbToNeighborEvent(ifkey, nbr, areaId, 0, 0, nssaLsaKey, LSANSSAFLOOD)
+		}
+	}
 }
 
 /*
@@ -1196,6 +1321,9 @@ func (server *OSPFServer) processInterfaceChangeMsg(msg NetworkLSAChangeMsg) {
 
 	// send message for flooding.
 	server.sendLsdbToNeighborEvent(msg.intfKey, nbr, msg.areaId, 0, 0, lsaKey, LSAROUTERFLOOD)
+	if isVirtualIntfKey(msg.intfKey) {
+		server.refreshVirtLinkTransitLsa(msg.intfKey)
+	}
 }
 
 /* @fn processNeighborFullEvent
@@ -1211,12 +1339,16 @@ func (server *OSPFServer) processNeighborFullEvent(msg ospfNbrMdata) {
 	intConf := server.IntfConfMap[msg.intf]
 	server.logger.Info(fmt.Sprintln("LSDB: Nbr full. Generate router and network LSA  area id  ",
 		msg.areaId, " intf ", intConf.IfIpAddr))
-	if intConf.IfDRtrId == rtr_id && intConf.IfType == config.Broadcast {
+	if intConf.IfDRtrId == rtr_id &&
+		(intConf.IfType == config.Broadcast || intConf.IfType == config.Nbma) {
 		server.logger.Info(fmt.Sprintln("Generate network LSA ", msg.intf))
 		server.generateNetworkLSA(msg.areaId, msg.intf, true)
 	}
 	server.generateRouterLSA(msg.areaId)
 	server.sendLsdbToNeighborEvent(msg.intf, nbr, msg.areaId, 0, 0, lsaKey, LSAFLOOD)
+	if isVirtualIntfKey(msg.intf) {
+		server.refreshVirtLinkTransitLsa(msg.intf)
+	}
 }
 
 /* @fn processDrBdrChangeMsg
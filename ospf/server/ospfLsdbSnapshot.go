@@ -0,0 +1,582 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"l3/ospf/config"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* LSDB snapshots and offline SPF.
+   A snapshot holds the LSDB as returned by GetBulkOspfLsdbEntryState.
+   It can be loaded into an OSPFServer which is not running, the SPF
+   is then calculated from the point of view of any router in the
+   snapshot. Links and routers can be failed before the calculation
+   to see the effect of a change before it is made.
+*/
+
+const (
+	LSDB_SNAPSHOT_VERSION = 1
+)
+
+type LsdbSnapshotArea struct {
+	AreaId         config.AreaId
+	ImportAsExtern config.ImportAsExtern
+}
+
+type LsdbSnapshot struct {
+	Version  int
+	RouterId config.RouterId // router the LSDB was read from
+	Time     string
+	Areas    []LsdbSnapshotArea
+	Lsdb     []config.LsdbState
+}
+
+/* Routing table entry of the offline SPF */
+type OfflineRoute struct {
+	DestId    string
+	AddrMask  string
+	DestType  string
+	AreaId    string
+	PathType  string
+	Cost      uint16
+	Type2Cost uint16
+	NextHops  []string
+}
+
+type OfflineRouteDiff struct {
+	Op  string // Added, Removed or Changed
+	Old OfflineRoute
+	New OfflineRoute
+}
+
+type lsdbStateSlice []config.LsdbState
+
+func (s lsdbStateSlice) Len() int      { return len(s) }
+func (s lsdbStateSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s lsdbStateSlice) Less(i, j int) bool {
+	if s[i].LsdbAreaId != s[j].LsdbAreaId {
+		return convertAreaOrRouterIdUint32(string(s[i].LsdbAreaId)) <
+			convertAreaOrRouterIdUint32(string(s[j].LsdbAreaId))
+	}
+	if s[i].LsdbType != s[j].LsdbType {
+		return s[i].LsdbType < s[j].LsdbType
+	}
+	if s[i].LsdbLsid != s[j].LsdbLsid {
+		return convertAreaOrRouterIdUint32(string(s[i].LsdbLsid)) <
+			convertAreaOrRouterIdUint32(string(s[j].LsdbLsid))
+	}
+	return convertAreaOrRouterIdUint32(string(s[i].LsdbRouterId)) <
+		convertAreaOrRouterIdUint32(string(s[j].LsdbRouterId))
+}
+
+/* @fn NewLsdbSnapshot
+The area types are not part of the LSDB. An area with NSSA external
+LSAs is an NSSA, a non backbone area without the AS external LSAs
+which the other areas carry is a stub area.
+*/
+func NewLsdbSnapshot(routerId config.RouterId, lsdb []config.LsdbState) LsdbSnapshot {
+	snapshot := LsdbSnapshot{
+		Version:  LSDB_SNAPSHOT_VERSION,
+		RouterId: routerId,
+		Time:     time.Now().Format(time.RFC3339),
+		Lsdb:     make([]config.LsdbState, len(lsdb)),
+	}
+	copy(snapshot.Lsdb, lsdb)
+	sort.Sort(lsdbStateSlice(snapshot.Lsdb))
+
+	areaIds := []uint32{}
+	asExtAreas := make(map[config.AreaId]bool)
+	nssaAreas := make(map[config.AreaId]bool)
+	seen := make(map[config.AreaId]bool)
+	for _, ent := range snapshot.Lsdb {
+		if !seen[ent.LsdbAreaId] {
+			seen[ent.LsdbAreaId] = true
+			areaIds = append(areaIds, convertAreaOrRouterIdUint32(string(ent.LsdbAreaId)))
+		}
+		switch ent.LsdbType {
+		case config.AsExternalLink:
+			asExtAreas[ent.LsdbAreaId] = true
+		case config.NssaExternalLink:
+			nssaAreas[ent.LsdbAreaId] = true
+		}
+	}
+	for _, id := range areaIds {
+		area := LsdbSnapshotArea{
+			AreaId:         config.AreaId(convertUint32ToIPv4(id)),
+			ImportAsExtern: config.ImportExternal,
+		}
+		if nssaAreas[area.AreaId] {
+			area.ImportAsExtern = config.ImportNssa
+		} else if id != 0 && len(asExtAreas) != 0 && !asExtAreas[area.AreaId] {
+			area.ImportAsExtern = config.ImportNoExternal
+		}
+		snapshot.Areas = append(snapshot.Areas, area)
+	}
+	return snapshot
+}
+
+func WriteLsdbSnapshot(fileName string, snapshot LsdbSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+func ReadLsdbSnapshot(fileName string) (LsdbSnapshot, error) {
+	var snapshot LsdbSnapshot
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+	if snapshot.Version != LSDB_SNAPSHOT_VERSION {
+		return snapshot, errors.New(fmt.Sprintln("Unsupported LSDB snapshot version", snapshot.Version))
+	}
+	return snapshot, nil
+}
+
+func convertOctetStringToByte(str string) ([]byte, error) {
+	data := []byte{}
+	if str == "" {
+		return data, nil
+	}
+	for _, octet := range strings.Split(str, ":") {
+		val, err := strconv.Atoi(octet)
+		if err != nil || val < 0 || val > 255 {
+			return nil, errors.New(fmt.Sprintln("Invalid octet", octet))
+		}
+		data = append(data, byte(val))
+	}
+	return data, nil
+}
+
+/* @fn ExportLsdbSnapshot
+Snapshot of the LSDB of this router.
+*/
+func (server *OSPFServer) ExportLsdbSnapshot() LsdbSnapshot {
+	lsdb := []config.LsdbState{}
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		lsaKeys := []LsaKey{}
+		for lsaKey, _ := range lsDbEnt.RouterLsaMap {
+			lsaKeys = append(lsaKeys, lsaKey)
+		}
+		for lsaKey, _ := range lsDbEnt.NetworkLsaMap {
+			lsaKeys = append(lsaKeys, lsaKey)
+		}
+		for lsaKey, _ := range lsDbEnt.Summary3LsaMap {
+			lsaKeys = append(lsaKeys, lsaKey)
+		}
+		for lsaKey, _ := range lsDbEnt.Summary4LsaMap {
+			lsaKeys = append(lsaKeys, lsaKey)
+		}
+		for lsaKey, _ := range lsDbEnt.ASExternalLsaMap {
+			lsaKeys = append(lsaKeys, lsaKey)
+		}
+		for lsaKey, _ := range lsDbEnt.NSSAExternalLsaMap {
+			lsaKeys = append(lsaKeys, lsaKey)
+		}
+		for _, lsaKey := range lsaKeys {
+			lsaEnc := server.encodeLsaFromLsdb(lsdbKey.AreaId, lsaKey)
+			if len(lsaEnc) <= OSPF_LSA_HEADER_SIZE {
+				continue
+			}
+			ent := config.LsdbState{
+				LsdbAreaId:        config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId)),
+				LsdbType:          config.LsaType(lsaKey.LSType),
+				LsdbLsid:          config.IpAddress(convertUint32ToIPv4(lsaKey.LSId)),
+				LsdbRouterId:      config.RouterId(convertUint32ToIPv4(lsaKey.AdvRouter)),
+				LsdbSequence:      int(binary.BigEndian.Uint32(lsaEnc[12:16])),
+				LsdbAge:           int(binary.BigEndian.Uint16(lsaEnc[0:2])),
+				LsdbCheckSum:      int(binary.BigEndian.Uint16(lsaEnc[16:18])),
+				LsdbAdvertisement: convertByteToOctetString(lsaEnc[OSPF_LSA_HEADER_SIZE:]),
+			}
+			lsdb = append(lsdb, ent)
+		}
+	}
+	routerId := config.RouterId(convertIPInByteToString(server.ospfGlobalConf.RouterId))
+	return NewLsdbSnapshot(routerId, lsdb)
+}
+
+/* @fn ImportLsdbSnapshot
+Loads the snapshot into the LSDB of a server which is not running.
+*/
+func (server *OSPFServer) ImportLsdbSnapshot(snapshot LsdbSnapshot) error {
+	for _, area := range snapshot.Areas {
+		areaId := convertAreaOrRouterIdUint32(string(area.AreaId))
+		server.initLSDatabase(areaId)
+		areaConfKey := AreaConfKey{
+			AreaId: config.AreaId(convertUint32ToIPv4(areaId)),
+		}
+		server.AreaConfMap[areaConfKey] = AreaConf{
+			ImportAsExtern: area.ImportAsExtern,
+			IntfListMap:    make(map[IntfConfKey]bool),
+		}
+	}
+	for _, ent := range snapshot.Lsdb {
+		body, err := convertOctetStringToByte(ent.LsdbAdvertisement)
+		if err != nil {
+			return err
+		}
+		lsdbKey := LsdbKey{
+			AreaId: convertAreaOrRouterIdUint32(string(ent.LsdbAreaId)),
+		}
+		lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+		if !exist {
+			return errors.New(fmt.Sprintln("Area", ent.LsdbAreaId, "is not in the snapshot areas"))
+		}
+		lsaKey := LsaKey{
+			LSType:    uint8(ent.LsdbType),
+			LSId:      convertAreaOrRouterIdUint32(string(ent.LsdbLsid)),
+			AdvRouter: convertAreaOrRouterIdUint32(string(ent.LsdbRouterId)),
+		}
+		lsaMd := LsaMetadata{
+			LSAge:         uint16(ent.LsdbAge),
+			LSSequenceNum: ent.LsdbSequence,
+			LSChecksum:    uint16(ent.LsdbCheckSum),
+			LSLen:         uint16(OSPF_LSA_HEADER_SIZE + len(body)),
+		}
+		data := encodeLsaHeader(lsaMd, lsaKey)
+		binary.BigEndian.PutUint16(data[16:18], lsaMd.LSChecksum)
+		data = append(data, body...)
+
+		switch lsaKey.LSType {
+		case RouterLSA:
+			lsa := NewRouterLsa()
+			decodeRouterLsa(data, lsa, &lsaKey)
+			lsDbEnt.RouterLsaMap[lsaKey] = *lsa
+		case NetworkLSA:
+			lsa := NewNetworkLsa()
+			decodeNetworkLsa(data, lsa, &lsaKey)
+			lsDbEnt.NetworkLsaMap[lsaKey] = *lsa
+		case Summary3LSA:
+			lsa := NewSummaryLsa()
+			decodeSummaryLsa(data, lsa, &lsaKey)
+			lsDbEnt.Summary3LsaMap[lsaKey] = *lsa
+		case Summary4LSA:
+			lsa := NewSummaryLsa()
+			decodeSummaryLsa(data, lsa, &lsaKey)
+			lsDbEnt.Summary4LsaMap[lsaKey] = *lsa
+		case ASExternalLSA:
+			lsa := NewASExternalLsa()
+			decodeASExternalLsa(data, lsa, &lsaKey)
+			lsDbEnt.ASExternalLsaMap[lsaKey] = *lsa
+		case NSSAExternalLSA:
+			lsa := NewASExternalLsa()
+			decodeASExternalLsa(data, lsa, &lsaKey)
+			lsDbEnt.NSSAExternalLsaMap[lsaKey] = *lsa
+		}
+	}
+	return nil
+}
+
+func removeLinkDetails(lsa RouterLsa, match func(link LinkDetail) bool) (RouterLsa, bool) {
+	links := []LinkDetail{}
+	for _, link := range lsa.LinkDetails {
+		if !match(link) {
+			links = append(links, link)
+		}
+	}
+	removed := len(links) != len(lsa.LinkDetails)
+	lsa.LinkDetails = links
+	lsa.NumOfLinks = uint16(len(links))
+	return lsa, removed
+}
+
+func removeAttachedRtr(lsa NetworkLsa, rtrId uint32) (NetworkLsa, bool) {
+	attached := []uint32{}
+	for _, rtr := range lsa.AttachedRtr {
+		if rtr != rtrId {
+			attached = append(attached, rtr)
+		}
+	}
+	removed := len(attached) != len(lsa.AttachedRtr)
+	lsa.AttachedRtr = attached
+	return lsa, removed
+}
+
+/* @fn FailOfflineLink
+Removes the links of the router with the given link id from the
+imported LSDB. The link id is the neighbor router id of a
+point-to-point link, the DR address of a transit network or the
+stub network. Both ends of a link are removed.
+*/
+func (server *OSPFServer) FailOfflineLink(rtr string, linkId string) error {
+	rtrId := convertAreaOrRouterIdUint32(rtr)
+	lId := convertAreaOrRouterIdUint32(linkId)
+	found := false
+	for _, lsDbEnt := range server.AreaLsdb {
+		for lsaKey, lsa := range lsDbEnt.RouterLsaMap {
+			var removed bool
+			if lsaKey.AdvRouter == rtrId {
+				lsa, removed = removeLinkDetails(lsa, func(link LinkDetail) bool {
+					return link.LinkId == lId
+				})
+			} else if lsaKey.AdvRouter == lId {
+				lsa, removed = removeLinkDetails(lsa, func(link LinkDetail) bool {
+					return link.LinkId == rtrId &&
+						(link.LinkType == P2PLink || link.LinkType == VirtualLink)
+				})
+			}
+			if removed {
+				lsDbEnt.RouterLsaMap[lsaKey] = lsa
+				found = true
+			}
+		}
+		for lsaKey, lsa := range lsDbEnt.NetworkLsaMap {
+			if lsaKey.LSId != lId {
+				continue
+			}
+			lsa, removed := removeAttachedRtr(lsa, rtrId)
+			if removed {
+				lsDbEnt.NetworkLsaMap[lsaKey] = lsa
+				found = true
+			}
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintln("No link", linkId, "on router", rtr))
+	}
+	return nil
+}
+
+/* @fn FailOfflineRouter
+Removes the router LSAs of the router and its attachments to the
+transit networks from the imported LSDB.
+*/
+func (server *OSPFServer) FailOfflineRouter(rtr string) error {
+	rtrId := convertAreaOrRouterIdUint32(rtr)
+	found := false
+	for _, lsDbEnt := range server.AreaLsdb {
+		for lsaKey, _ := range lsDbEnt.RouterLsaMap {
+			if lsaKey.AdvRouter == rtrId {
+				delete(lsDbEnt.RouterLsaMap, lsaKey)
+				found = true
+			}
+		}
+		for lsaKey, lsa := range lsDbEnt.NetworkLsaMap {
+			lsa, removed := removeAttachedRtr(lsa, rtrId)
+			if removed {
+				lsDbEnt.NetworkLsaMap[lsaKey] = lsa
+			}
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintln("No router LSA for router", rtr))
+	}
+	return nil
+}
+
+/* @fn RunOfflineSpf
+Routing table calculation of the imported LSDB from the point of view
+of the given router. It follows spfCalculation, nothing is installed
+and no LSA is originated.
+*/
+func (server *OSPFServer) RunOfflineSpf(rtr string) error {
+	rtrId := convertAreaOrRouterIdUint32(rtr)
+	if rtrId == 0 {
+		return errors.New(fmt.Sprintln("Invalid router id", rtr))
+	}
+	server.ospfGlobalConf.RouterId = convertAreaOrRouterId(rtr)
+	rtrLsaKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	attached := 0
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		selfOrigLsaEnt := make(map[LsaKey]bool)
+		if _, exist := lsDbEnt.RouterLsaMap[rtrLsaKey]; exist {
+			selfOrigLsaEnt[rtrLsaKey] = true
+			attached++
+		}
+		server.AreaSelfOrigLsa[lsdbKey] = selfOrigLsaEnt
+	}
+	if attached == 0 {
+		return errors.New(fmt.Sprintln("No router LSA for router", rtr))
+	}
+	server.ospfGlobalConf.AreaBdrRtrStatus = attached > 1
+
+	server.TempAreaRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
+	for _, key := range server.getSpfAreaOrder() {
+		areaId := convertAreaOrRouterIdUint32(string(key.AreaId))
+		areaIdKey := AreaIdKey{
+			AreaId: areaId,
+		}
+		if len(server.AreaSelfOrigLsa[LsdbKey{AreaId: areaId}]) == 0 {
+			continue
+		}
+		server.initialiseSPFStructs()
+		tempRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
+		tempRoutingTbl.RoutingTblMap = make(map[RoutingTblEntryKey]RoutingTblEntry)
+		server.TempAreaRoutingTbl[areaIdKey] = tempRoutingTbl
+
+		vKey, err := server.CreateAreaGraph(areaId)
+		if err != nil {
+			return err
+		}
+		err = server.ExecuteDijkstra(vKey, areaId)
+		if err != nil {
+			return err
+		}
+		server.UpdateRoutingTbl(vKey, areaId)
+		server.HandleStubs(vKey, areaId)
+		server.HandleSummaryLsa(areaId)
+		server.AreaGraph = nil
+		server.AreaStubs = nil
+		server.SPFTree = nil
+	}
+	server.TempGlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
+	server.ConsolidatingRoutingTbl()
+	server.GlobalRoutingTbl = server.TempGlobalRoutingTbl
+	server.TempGlobalRoutingTbl = nil
+	server.TempAreaRoutingTbl = nil
+	return nil
+}
+
+func getDestTypeName(destType DestType) string {
+	switch destType {
+	case Network:
+		return "Network"
+	case InternalRouter:
+		return "InternalRouter"
+	case ASBdrRouter:
+		return "ASBdrRouter"
+	case AreaBdrRouter:
+		return "AreaBdrRouter"
+	case ASAreaBdrRouter:
+		return "ASAreaBdrRouter"
+	}
+	return "None"
+}
+
+func getPathTypeName(pathType PathType) string {
+	switch pathType {
+	case IntraArea:
+		return "IntraArea"
+	case InterArea:
+		return "InterArea"
+	case Type1Ext:
+		return "Type1Ext"
+	case Type2Ext:
+		return "Type2Ext"
+	}
+	return "None"
+}
+
+type offlineRouteSlice []OfflineRoute
+
+func (s offlineRouteSlice) Len() int      { return len(s) }
+func (s offlineRouteSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s offlineRouteSlice) Less(i, j int) bool {
+	if s[i].DestType != s[j].DestType {
+		return s[i].DestType < s[j].DestType
+	}
+	if s[i].DestId != s[j].DestId {
+		return convertAreaOrRouterIdUint32(s[i].DestId) < convertAreaOrRouterIdUint32(s[j].DestId)
+	}
+	return convertAreaOrRouterIdUint32(s[i].AddrMask) < convertAreaOrRouterIdUint32(s[j].AddrMask)
+}
+
+/* Routing table of the last offline SPF */
+func (server *OSPFServer) GetOfflineRoutingTbl() []OfflineRoute {
+	routes := []OfflineRoute{}
+	for rKey, ent := range server.GlobalRoutingTbl {
+		rEnt := ent.RoutingTblEnt
+		route := OfflineRoute{
+			DestId:    convertUint32ToIPv4(rKey.DestId),
+			AddrMask:  convertUint32ToIPv4(rKey.AddrMask),
+			DestType:  getDestTypeName(rKey.DestType),
+			AreaId:    convertUint32ToIPv4(ent.AreaId),
+			PathType:  getPathTypeName(rEnt.PathType),
+			Cost:      rEnt.Cost,
+			Type2Cost: rEnt.Type2Cost,
+			NextHops:  []string{},
+		}
+		for nextHop, _ := range rEnt.NextHops {
+			route.NextHops = append(route.NextHops, fmt.Sprint(convertUint32ToIPv4(nextHop.NextHopIP),
+				" via ", convertUint32ToIPv4(nextHop.IfIPAddr)))
+		}
+		sort.Strings(route.NextHops)
+		routes = append(routes, route)
+	}
+	sort.Sort(offlineRouteSlice(routes))
+	return routes
+}
+
+func isOfflineRouteEqual(old OfflineRoute, route OfflineRoute) bool {
+	if old.AreaId != route.AreaId || old.PathType != route.PathType ||
+		old.Cost != route.Cost || old.Type2Cost != route.Type2Cost ||
+		len(old.NextHops) != len(route.NextHops) {
+		return false
+	}
+	for idx, nextHop := range old.NextHops {
+		if route.NextHops[idx] != nextHop {
+			return false
+		}
+	}
+	return true
+}
+
+/* @fn DiffOfflineRoutes
+Routes added, removed or changed between two offline routing tables.
+*/
+func DiffOfflineRoutes(oldRoutes []OfflineRoute, newRoutes []OfflineRoute) []OfflineRouteDiff {
+	getKey := func(route OfflineRoute) string {
+		return route.DestType + " " + route.DestId + "/" + route.AddrMask
+	}
+	oldMap := make(map[string]OfflineRoute)
+	for _, route := range oldRoutes {
+		oldMap[getKey(route)] = route
+	}
+	diffs := []OfflineRouteDiff{}
+	newMap := make(map[string]bool)
+	for _, route := range newRoutes {
+		newMap[getKey(route)] = true
+		old, exist := oldMap[getKey(route)]
+		if !exist {
+			diffs = append(diffs, OfflineRouteDiff{Op: "Added", New: route})
+		} else if !isOfflineRouteEqual(old, route) {
+			diffs = append(diffs, OfflineRouteDiff{Op: "Changed", Old: old, New: route})
+		}
+	}
+	for _, route := range oldRoutes {
+		if !newMap[getKey(route)] {
+			diffs = append(diffs, OfflineRouteDiff{Op: "Removed", Old: route})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,265 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfLsdbSnapshot_test
+   This test covers
+   1) LSDB advertisement octet string parsing.
+   2) Snapshot write and read.
+   3) Snapshot import and offline SPF.
+   4) Offline link failure.
+   5) Offline router failure and routing table diff.
+*/
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"l3/ospf/config"
+	"os"
+	"testing"
+)
+
+var snapshotTest LsdbSnapshot
+
+/* Triangle of p2p links between 1.1.1.1, 2.2.2.2 and 3.3.3.3 in the
+   backbone, 3.3.3.3 has a loopback */
+func getSnapshotRouterLsa(rtr string, links []LinkDetail) config.LsdbState {
+	rtrId := convertAreaOrRouterIdUint32(rtr)
+	lsaKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	lsa := RouterLsa{
+		NumOfLinks:  uint16(len(links)),
+		LinkDetails: links,
+	}
+	lsa.LsaMd.LSSequenceNum = InitialSequenceNumber
+	lsa.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 4 + 12*len(links))
+	data := encodeRouterLsa(lsa, lsaKey)
+	return config.LsdbState{
+		LsdbAreaId:        "0.0.0.0",
+		LsdbType:          config.RouterLink,
+		LsdbLsid:          config.IpAddress(rtr),
+		LsdbRouterId:      config.RouterId(rtr),
+		LsdbSequence:      InitialSequenceNumber,
+		LsdbAdvertisement: convertByteToOctetString(data[OSPF_LSA_HEADER_SIZE:]),
+	}
+}
+
+func getSnapshotLink(linkType uint8, linkId string, linkData string, metric uint16) LinkDetail {
+	return LinkDetail{
+		LinkId:     convertAreaOrRouterIdUint32(linkId),
+		LinkData:   convertAreaOrRouterIdUint32(linkData),
+		LinkType:   linkType,
+		LinkMetric: metric,
+	}
+}
+
+func initLsdbSnapshotTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+
+	lsdb := []config.LsdbState{
+		getSnapshotRouterLsa("1.1.1.1", []LinkDetail{
+			getSnapshotLink(P2PLink, "2.2.2.2", "10.0.12.1", 10),
+			getSnapshotLink(StubLink, "10.0.12.0", "255.255.255.0", 10),
+			getSnapshotLink(P2PLink, "3.3.3.3", "10.0.13.1", 10),
+			getSnapshotLink(StubLink, "10.0.13.0", "255.255.255.0", 10),
+		}),
+		getSnapshotRouterLsa("2.2.2.2", []LinkDetail{
+			getSnapshotLink(P2PLink, "1.1.1.1", "10.0.12.2", 10),
+			getSnapshotLink(StubLink, "10.0.12.0", "255.255.255.0", 10),
+			getSnapshotLink(P2PLink, "3.3.3.3", "10.0.23.2", 10),
+			getSnapshotLink(StubLink, "10.0.23.0", "255.255.255.0", 10),
+		}),
+		getSnapshotRouterLsa("3.3.3.3", []LinkDetail{
+			getSnapshotLink(P2PLink, "1.1.1.1", "10.0.13.3", 10),
+			getSnapshotLink(StubLink, "10.0.13.0", "255.255.255.0", 10),
+			getSnapshotLink(P2PLink, "2.2.2.2", "10.0.23.3", 10),
+			getSnapshotLink(StubLink, "10.0.23.0", "255.255.255.0", 10),
+			getSnapshotLink(StubLink, "3.3.3.3", "255.255.255.255", 1),
+		}),
+	}
+	snapshotTest = NewLsdbSnapshot("1.1.1.1", lsdb)
+}
+
+func getSnapshotRoute(routes []OfflineRoute, destId string) (OfflineRoute, bool) {
+	for _, route := range routes {
+		if route.DestType == "Network" && route.DestId == destId {
+			return route, true
+		}
+	}
+	return OfflineRoute{}, false
+}
+
+func runSnapshotSpf(failLink []string, failRouter string) ([]OfflineRoute, error) {
+	server := NewOfflineServerObject()
+	err := server.ImportLsdbSnapshot(snapshotTest)
+	if err != nil {
+		return nil, err
+	}
+	if failLink != nil {
+		err = server.FailOfflineLink(failLink[0], failLink[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if failRouter != "" {
+		err = server.FailOfflineRouter(failRouter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = server.RunOfflineSpf("1.1.1.1")
+	if err != nil {
+		return nil, err
+	}
+	return server.GetOfflineRoutingTbl(), nil
+}
+
+func TestOspfLsdbSnapshot(t *testing.T) {
+	fmt.Println("\n**************** LSDB SNAPSHOT ************\n")
+	initLsdbSnapshotTestParams()
+	for index := 1; index < 6; index++ {
+		err := lsdbSnapshotTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for LSDB snapshot ", index)
+		}
+	}
+}
+
+func lsdbSnapshotTestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running convertOctetStringToByte")
+		data := []byte{0, 1, 128, 255}
+		parsed, err := convertOctetStringToByte(convertByteToOctetString(data))
+		if err != nil || len(parsed) != len(data) || parsed[2] != 128 || parsed[3] != 255 {
+			fmt.Println("Octet string mismatch ", parsed, err)
+			return FAIL
+		}
+		_, err = convertOctetStringToByte("1:256")
+		if err == nil {
+			fmt.Println("Invalid octet accepted")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running WriteLsdbSnapshot/ReadLsdbSnapshot")
+		file, err := ioutil.TempFile("", "lsdb")
+		if err != nil {
+			fmt.Println("Failed to create the snapshot file ", err)
+			return FAIL
+		}
+		fileName := file.Name()
+		file.Close()
+		defer os.Remove(fileName)
+		err = WriteLsdbSnapshot(fileName, snapshotTest)
+		if err != nil {
+			fmt.Println("Failed to write the snapshot ", err)
+			return FAIL
+		}
+		snapshot, err := ReadLsdbSnapshot(fileName)
+		if err != nil || len(snapshot.Lsdb) != 3 || len(snapshot.Areas) != 1 ||
+			snapshot.Lsdb[2].LsdbAdvertisement != snapshotTest.Lsdb[2].LsdbAdvertisement {
+			fmt.Println("Snapshot mismatch ", snapshot, err)
+			return FAIL
+		}
+		snapshot.Version = LSDB_SNAPSHOT_VERSION + 1
+		WriteLsdbSnapshot(fileName, snapshot)
+		_, err = ReadLsdbSnapshot(fileName)
+		if err == nil {
+			fmt.Println("Unsupported snapshot version accepted")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running ImportLsdbSnapshot/RunOfflineSpf")
+		routes, err := runSnapshotSpf(nil, "")
+		if err != nil {
+			fmt.Println("Offline SPF failed ", err)
+			return FAIL
+		}
+		route, exist := getSnapshotRoute(routes, "3.3.3.3")
+		if !exist || route.Cost != 11 || route.PathType != "IntraArea" ||
+			len(route.NextHops) != 1 || route.NextHops[0] != "10.0.13.3 via 10.0.13.1" {
+			fmt.Println("Invalid route to 3.3.3.3 ", route, exist)
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running FailOfflineLink")
+		routes, err := runSnapshotSpf([]string{"1.1.1.1", "3.3.3.3"}, "")
+		if err != nil {
+			fmt.Println("Offline SPF failed ", err)
+			return FAIL
+		}
+		route, exist := getSnapshotRoute(routes, "3.3.3.3")
+		if !exist || route.Cost != 21 || len(route.NextHops) != 1 ||
+			route.NextHops[0] != "10.0.12.2 via 10.0.12.1" {
+			fmt.Println("Invalid route to 3.3.3.3 after link failure ", route, exist)
+			return FAIL
+		}
+		server := NewOfflineServerObject()
+		server.ImportLsdbSnapshot(snapshotTest)
+		if server.FailOfflineLink("1.1.1.1", "4.4.4.4") == nil {
+			fmt.Println("Unknown link failed")
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running FailOfflineRouter/DiffOfflineRoutes")
+		baseRoutes, err := runSnapshotSpf(nil, "")
+		if err != nil {
+			fmt.Println("Offline SPF failed ", err)
+			return FAIL
+		}
+		routes, err := runSnapshotSpf(nil, "3.3.3.3")
+		if err != nil {
+			fmt.Println("Offline SPF failed ", err)
+			return FAIL
+		}
+		if _, exist := getSnapshotRoute(routes, "3.3.3.3"); exist {
+			fmt.Println("Route to failed router's loopback")
+			return FAIL
+		}
+		removed := false
+		for _, diff := range DiffOfflineRoutes(baseRoutes, routes) {
+			if diff.Op == "Removed" && diff.Old.DestId == "3.3.3.3" &&
+				diff.Old.DestType == "Network" {
+				removed = true
+			}
+		}
+		if !removed {
+			fmt.Println("Removed route not in diff")
+			return FAIL
+		}
+		if len(DiffOfflineRoutes(baseRoutes, baseRoutes)) != 0 {
+			fmt.Println("Diff of the same routing table")
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
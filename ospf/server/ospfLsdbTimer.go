@@ -81,6 +81,30 @@ func (server *OSPFServer) lsdbStateRefresh() {
 				val.AdvRtr = lsakey.AdvRouter
 				server.LsdbSlice = append(server.LsdbSlice, val)
 			}
+			for lsakey, _ := range lsdbEnt.NSSAExternalLsaMap {
+				var val LsdbSliceEnt
+				val.AreaId = lsdbkey.AreaId
+				val.LSType = lsakey.LSType
+				val.LSId = lsakey.LSId
+				val.AdvRtr = lsakey.AdvRouter
+				server.LsdbSlice = append(server.LsdbSlice, val)
+			}
+			for lsakey, _ := range lsdbEnt.OpaqueAreaLsaMap {
+				var val LsdbSliceEnt
+				val.AreaId = lsdbkey.AreaId
+				val.LSType = lsakey.LSType
+				val.LSId = lsakey.LSId
+				val.AdvRtr = lsakey.AdvRouter
+				server.LsdbSlice = append(server.LsdbSlice, val)
+			}
+			for lsakey, _ := range lsdbEnt.OpaqueASLsaMap {
+				var val LsdbSliceEnt
+				val.AreaId = lsdbkey.AreaId
+				val.LSType = lsakey.LSType
+				val.LSId = lsakey.LSId
+				val.AdvRtr = lsakey.AdvRouter
+				server.LsdbSlice = append(server.LsdbSlice, val)
+			}
 		}
 		server.logger.Info(fmt.Sprintln("The new Lsdb Slice after refresh", server.LsdbSlice))
 		server.LsdbStateTimer.Reset(server.RefreshDuration)
@@ -101,6 +125,9 @@ From RFC 2328
         state database.
 */
 func (server *OSPFServer) lsdbSelfLsaRefresh() {
+	if server.isGraceRestarting() {
+		return
+	}
 	server.logger.Info(fmt.Sprintln("REFRESH: LSDB refresh started..."))
 	floodAsExt := 0
 	ifkey := IntfConfKey{}
@@ -113,15 +140,23 @@ func (server *OSPFServer) lsdbSelfLsaRefresh() {
 				if floodAsExt == 0 && lsaKey.LSType == ASExternalLSA {
 					server.sendLsdbToNeighborEvent(ifkey, nbr, 0, 0, 0, lsaKey, LSAEXTFLOOD)
 				}
+				if lsaKey.LSType == NSSAExternalLSA {
+					server.sendLsdbToNeighborEvent(ifkey, nbr, lsdbKey.AreaId, 0, 0, lsaKey, LSANSSAFLOOD)
+				}
 				if err != nil {
 					server.logger.Warning(fmt.Sprintln("LSDB: Failed to regenerate LSA ", lsaKey, " Area ", lsdbKey))
 				}
 			}
 		}
-		floodAsExt += 1
+		areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+		if !server.isStubArea(areaId) && !server.isNssaArea(areaId) {
+			// AS external LSAs are only held by the other areas
+			floodAsExt += 1
+		}
 	}
 	// generate Summary LSAs
 	server.GenerateSummaryLsa()
+	server.refreshSelfOpaqueLsas()
 	lsaKey := LsaKey{}
 
 	for entKey, ent := range server.IntfConfMap {
@@ -158,6 +193,9 @@ func (server *OSPFServer) regenerateLsa(lsdbKey LsdbKey, lsaKey LsaKey) error {
 	case ASExternalLSA:
 		server.updateAsExternalLSA(lsdbKey, lsaKey)
 
+	case NSSAExternalLSA:
+		server.updateNssaExternalLSA(lsdbKey, lsaKey)
+
 	}
 	return nil
 }
@@ -174,6 +212,7 @@ func (server *OSPFServer) processLSDatabaseTicker() {
 		server.processMaxAgeLSA(lsdbKey, lsDbEnt)
 
 	}
+	server.processMaxAgeLinkOpaqueLsa()
 
 }
 
@@ -347,7 +347,7 @@ func checkFloodAPIs() {
 	ospf.generateDbsummary4LsaList(lsdbKey.AreaId)
 	ospf.generateDbsummary3LsaList(lsdbKey.AreaId)
 	ospf.generateDbasExternalList(lsdbKey.AreaId)
-	ospf.generateDbSummaryList(nbrKey)
+	ospf.generateDbSummaryList(nbrKey, INTF_OPTIONS)
 	ospf.SendSelfOrigLSA(lsdbKey.AreaId, key)
 	ospf.processFloodMsg(floodMsg)
 	floodMsg.lsOp = LSASELFLOOD
@@ -35,6 +35,9 @@ func (server *OSPFServer) HandleSummaryLsa(areaId uint32) {
 	server.HandleSummaryType3Lsa(areaId)
 	server.HandleSummaryType4Lsa(areaId)
 	server.HandleASExternalLsa(areaId)
+	if server.isNssaArea(config.AreaId(convertUint32ToIPv4(areaId))) {
+		server.HandleNssaExternalLsa(areaId)
+	}
 }
 
 func (server *OSPFServer) HandleSummaryType3Lsa(areaId uint32) {
@@ -164,6 +167,7 @@ func (server *OSPFServer) CalcInterAreaRoutes(areaId uint32) {
 func (server *OSPFServer) GenerateSummaryLsa() {
 	server.logger.Info("Generating Summary LSA")
 	server.SummaryLsDb = make(map[LsdbKey]SummaryLsaMap)
+	server.computeAreaRanges()
 	for aKey, aEnt := range server.AreaConfMap {
 		if len(aEnt.IntfListMap) == 0 {
 			continue
@@ -174,9 +178,15 @@ func (server *OSPFServer) GenerateSummaryLsa() {
 			AreaId: areaId,
 		}
 		isStub := server.isStubArea(aKey.AreaId)
+		// NSSAs dont carry AS external LSAs, so no Type 4 summaries either
+		isNssa := server.isNssaArea(aKey.AreaId)
+		noSummary := server.isNssaNoSummaryArea(aKey.AreaId)
 		sEnt, _ := server.SummaryLsDb[lsDbKey]
 		sEnt = make(map[LsaKey]SummaryLsa)
 		for rKey, rEnt := range server.GlobalRoutingTbl {
+			if noSummary {
+				break
+			}
 			if rKey.DestType == AreaBdrRouter ||
 				rEnt.RoutingTblEnt.PathType == Type1Ext ||
 				rEnt.RoutingTblEnt.PathType == Type2Ext ||
@@ -210,7 +220,7 @@ func (server *OSPFServer) GenerateSummaryLsa() {
 			*/
 
 			if (rKey.DestType == ASAreaBdrRouter ||
-				rKey.DestType == ASBdrRouter) && !isStub {
+				rKey.DestType == ASBdrRouter) && !isStub && !isNssa {
 				lsaKey, summaryLsa := server.GenerateType4SummaryLSA(rKey, rEnt, lsDbKey)
 				sEnt[lsaKey] = summaryLsa
 			}
@@ -225,7 +235,10 @@ func (server *OSPFServer) GenerateSummaryLsa() {
 				sEnt[lsaKey] = summaryLsa
 			} else if rKey.DestType == Network &&
 				rEnt.RoutingTblEnt.PathType == IntraArea {
-				//TODO: Address Range
+				if _, found := server.getAreaRange(rEnt.AreaId, rKey.DestId, rKey.AddrMask); found {
+					// Advertised through the area range
+					continue
+				}
 				// By default LSId = network's address
 				// Metric = Routing Table cost
 				lsaKey, summaryLsa := server.GenerateType3SummaryLSA(rKey, rEnt, lsDbKey)
@@ -233,13 +246,16 @@ func (server *OSPFServer) GenerateSummaryLsa() {
 			}
 		}
 
+		if !noSummary {
+			server.generateAreaRangeSummaryLsa(lsDbKey, sEnt)
+		}
 		server.SummaryLsDb[lsDbKey] = sEnt
-		if isStub {
+		if isStub || noSummary {
 			lsaKey, defsummaryLsa := server.GenerateDefaultSummary3LSA(lsDbKey)
 			sEnt[lsaKey] = defsummaryLsa
 		}
 	}
-
+	server.syncAggrDiscardRoutes()
 }
 
 /*
@@ -315,7 +331,7 @@ func (server *OSPFServer) GenerateType3SummaryLSA(rKey RoutingTblEntryKey, rEnt
 	summaryLsa.LsaMd.LSSequenceNum = seq_num
 	summaryLsa.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 8)
 	summaryLsa.Netmask = rKey.AddrMask
-	summaryLsa.Metric = uint32(rEnt.RoutingTblEnt.Cost)
+	summaryLsa.Metric = server.getStubRouterSummaryMetric(uint32(rEnt.RoutingTblEnt.Cost))
 
 	return lsaKey, summaryLsa
 }
@@ -0,0 +1,294 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l3/ospf/config"
+	"net"
+	"time"
+)
+
+/*
+   Non broadcast networks.
+   Neighbors on NBMA and non broadcast point-to-multipoint interfaces
+   cannot be discovered with multicast hellos, so they are configured
+   statically and hellos are unicast to them (RFC 2328 9.5.1).
+   A neighbor which is down is only polled every PollInterval.
+   Every other multicast packet (LS update, LS ack) sent on such an
+   interface is replicated as unicast to the neighbors.
+   A point-to-multipoint interface without configured neighbors is
+   treated as broadcast capable and keeps using multicast.
+*/
+
+type NbmaNbrEnt struct {
+	Priority uint8
+	LastPoll time.Time
+}
+
+/*
+   Op is true for create/update and false for delete.
+*/
+type NbmaNbrConfMsg struct {
+	Op   bool
+	Conf config.NbrConf
+}
+
+var broadcastMAC = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+func (server *OSPFServer) initNbmaNbrDB() {
+	server.NbmaNbrMap = make(map[NeighborConfKey]NbmaNbrEnt)
+}
+
+func (server *OSPFServer) ValidateNbmaNbrConf(conf config.NbrConf) error {
+	nbrIp := net.ParseIP(string(conf.NbrIpAddress))
+	if nbrIp == nil || nbrIp.To4() == nil || nbrIp.IsUnspecified() ||
+		nbrIp.IsMulticast() {
+		return errors.New(fmt.Sprintln("Invalid neighbor address", conf.NbrIpAddress))
+	}
+	if conf.NbrAddressLessIndex < 0 {
+		return errors.New(fmt.Sprintln("Invalid neighbor address less index", conf.NbrAddressLessIndex))
+	}
+	if conf.NbrPriority < 0 || conf.NbrPriority > 255 {
+		return errors.New(fmt.Sprintln("Invalid neighbor priority", conf.NbrPriority))
+	}
+	return nil
+}
+
+func (server *OSPFServer) processNbmaNbrConfig(msg NbmaNbrConfMsg) error {
+	nbrKey := NeighborConfKey{
+		IPAddr:  config.IpAddress(net.ParseIP(string(msg.Conf.NbrIpAddress)).To4().String()),
+		IntfIdx: msg.Conf.NbrAddressLessIndex,
+	}
+	server.NbmaNbrMutex.Lock()
+	defer server.NbmaNbrMutex.Unlock()
+	if msg.Op == false {
+		if _, exist := server.NbmaNbrMap[nbrKey]; !exist {
+			return errors.New(fmt.Sprintln("Neighbor", nbrKey.IPAddr, "is not configured"))
+		}
+		delete(server.NbmaNbrMap, nbrKey)
+		server.logger.Info(fmt.Sprintln("NBMA: Deleted static neighbor", nbrKey))
+		return nil
+	}
+	ent, _ := server.NbmaNbrMap[nbrKey]
+	ent.Priority = uint8(msg.Conf.NbrPriority)
+	server.NbmaNbrMap[nbrKey] = ent
+	server.logger.Info(fmt.Sprintln("NBMA: Configured static neighbor", nbrKey, "priority", ent.Priority))
+	return nil
+}
+
+func (server *OSPFServer) isNbmaNbrConfigured(nbrKey NeighborConfKey) bool {
+	server.NbmaNbrMutex.RLock()
+	defer server.NbmaNbrMutex.RUnlock()
+	_, exist := server.NbmaNbrMap[nbrKey]
+	return exist
+}
+
+/* @fn getNbmaNbrList
+Static neighbors reachable over the interface. Neighbors of
+numbered interfaces are matched on the interface subnet.
+*/
+func (server *OSPFServer) getNbmaNbrList(key IntfConfKey, ent IntfConf) []NeighborConfKey {
+	var nbrList []NeighborConfKey
+	ifIp := convertAreaOrRouterIdUint32(ent.IfIpAddr.String())
+	netmask := convertIPv4ToUint32(ent.IfNetmask)
+	server.NbmaNbrMutex.RLock()
+	defer server.NbmaNbrMutex.RUnlock()
+	for nbrKey, _ := range server.NbmaNbrMap {
+		if nbrKey.IntfIdx != key.IntfIdx {
+			continue
+		}
+		if key.IntfIdx == 0 {
+			nbrIp := convertAreaOrRouterIdUint32(string(nbrKey.IPAddr))
+			if nbrIp&netmask != ifIp&netmask || nbrIp == ifIp {
+				continue
+			}
+		}
+		nbrList = append(nbrList, nbrKey)
+	}
+	return nbrList
+}
+
+func (server *OSPFServer) isNonBroadcastIntf(key IntfConfKey, ent IntfConf) bool {
+	switch ent.IfType {
+	case config.Nbma:
+		return true
+	case config.PointToMultipoint:
+		return len(server.getNbmaNbrList(key, ent)) != 0
+	}
+	return false
+}
+
+/* @fn nbmaHelloDue
+RFC 2328 9.5.1. A router which is not eligible only talks to the
+DR and BDR. An eligible router talks to the other eligible routers,
+and to everyone once it is DR or BDR. Down neighbors are polled.
+*/
+func (server *OSPFServer) nbmaHelloDue(ent IntfConf, nbrKey NeighborConfKey, now time.Time) bool {
+	server.NbmaNbrMutex.Lock()
+	defer server.NbmaNbrMutex.Unlock()
+	nbr, exist := server.NbmaNbrMap[nbrKey]
+	if !exist {
+		return false
+	}
+	nbrIp := net.ParseIP(string(nbrKey.IPAddr)).To4()
+	nbrData, nbrUp := ent.NeighborMap[nbrKey]
+	if ent.IfType == config.Nbma &&
+		ent.IfFSMState != config.DesignatedRouter &&
+		ent.IfFSMState != config.BackupDesignatedRouter {
+		if ent.IfRtrPriority == 0 {
+			if bytesEqual(nbrIp, ent.IfDRIp) == false &&
+				bytesEqual(nbrIp, ent.IfBDRIp) == false {
+				return false
+			}
+		} else {
+			nbrPrio := nbr.Priority
+			if nbrUp {
+				nbrPrio = nbrData.RtrPrio
+			}
+			if nbrPrio == 0 {
+				return false
+			}
+		}
+	}
+	if !nbrUp {
+		pollInterval := time.Duration(ent.IfPollInterval) * time.Second
+		if now.Sub(nbr.LastPoll) < pollInterval {
+			return false
+		}
+		nbr.LastPoll = now
+		server.NbmaNbrMap[nbrKey] = nbr
+	}
+	return true
+}
+
+func (server *OSPFServer) getNbrUnicastMAC(nbrKey NeighborConfKey) net.HardwareAddr {
+	if dstMAC, exist := ospfNeighborIPToMAC[nbrKey]; exist && dstMAC != nil {
+		return dstMAC
+	}
+	// Neighbor not heard from yet, let it pick up the frame by its IP
+	return broadcastMAC
+}
+
+func (server *OSPFServer) sendNonBroadcastHelloPkt(key IntfConfKey, ent IntfConf) {
+	now := time.Now()
+	for _, nbrKey := range server.getNbmaNbrList(key, ent) {
+		if server.nbmaHelloDue(ent, nbrKey, now) == false {
+			continue
+		}
+		dstIP := net.ParseIP(string(nbrKey.IPAddr)).To4()
+		pkt := server.buildHelloPktToDst(key, ent, dstIP, server.getNbrUnicastMAC(nbrKey))
+		if pkt == nil {
+			continue
+		}
+		err := server.SendOspfPkt(key, pkt)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("NBMA: Unable to send hello to", nbrKey.IPAddr, err))
+		}
+	}
+}
+
+func isMcastOspfPkt(ospfPkt []byte) bool {
+	// Ethernet header followed by the IPv4 destination address
+	if len(ospfPkt) < 34 {
+		return false
+	}
+	return net.IP(ospfPkt[30:34]).IsMulticast()
+}
+
+/* @fn replicateOspfPkt
+Send a copy of a multicast packet to each neighbor on the
+interface. Packets to AllDRouters only go to the DR and BDR.
+*/
+func (server *OSPFServer) replicateOspfPkt(key IntfConfKey, ent IntfConf, ospfPkt []byte) error {
+	pkt := gopacket.NewPacket(ospfPkt, layers.LayerTypeEthernet, gopacket.Default)
+	ethLayer, _ := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	ipLayer, _ := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if ethLayer == nil || ipLayer == nil {
+		return errors.New("Unable to decode ospf pkt for replication")
+	}
+	toDRouters := ipLayer.DstIP.Equal(net.ParseIP(config.AllDRouters))
+	payload := ipLayer.Payload
+	var err error
+	for nbrKey, _ := range ent.NeighborMap {
+		nbrIp := net.ParseIP(string(nbrKey.IPAddr)).To4()
+		if nbrIp == nil {
+			continue
+		}
+		if toDRouters && bytesEqual(nbrIp, ent.IfDRIp) == false &&
+			bytesEqual(nbrIp, ent.IfBDRIp) == false {
+			continue
+		}
+		ipLayer.DstIP = nbrIp
+		ethLayer.DstMAC = server.getNbrUnicastMAC(nbrKey)
+		buffer := gopacket.NewSerializeBuffer()
+		options := gopacket.SerializeOptions{
+			FixLengths:       true,
+			ComputeChecksums: true,
+		}
+		gopacket.SerializeLayers(buffer, options, ethLayer, ipLayer, gopacket.Payload(payload))
+		sendErr := server.writeOspfPkt(key, buffer.Bytes())
+		if sendErr != nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+/* @fn constructP2MPLinks
+RFC 2328 12.4.1.4. A point-to-multipoint interface advertises a
+stub host route to its own address and a point-to-point link to
+each fully adjacent neighbor. The host route is left out when the
+prefix is suppressed.
+*/
+func (server *OSPFServer) constructP2MPLinks(key IntfConfKey, ent IntfConf) []LinkDetail {
+	var linkDetails []LinkDetail
+	ifIp := convertAreaOrRouterIdUint32(ent.IfIpAddr.String())
+	if !server.isPrefixSuppressed(key) {
+		linkDetails = append(linkDetails, LinkDetail{
+			LinkId:     ifIp,
+			LinkData:   0xffffffff,
+			LinkType:   StubLink,
+			NumOfTOS:   0,
+			LinkMetric: 0,
+		})
+	}
+	for nbrKey, _ := range ent.NeighborMap {
+		nbr, exist := server.NeighborConfigMap[nbrKey]
+		if !exist || nbr.OspfNbrState != config.NbrFull {
+			continue
+		}
+		linkDetails = append(linkDetails, LinkDetail{
+			LinkId:     nbr.OspfNbrRtrId,
+			LinkData:   ifIp,
+			LinkType:   P2PLink,
+			NumOfTOS:   0,
+			LinkMetric: uint16(ent.IfCost),
+		})
+	}
+	return linkDetails
+}
@@ -0,0 +1,172 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfNbma_test
+   This test covers
+   1) Static neighbor configuration and interface matching.
+   2) Unicast hello and poll scheduling on NBMA.
+   3) Multicast detection for packet replication.
+   4) Point-to-multipoint router LSA links.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"testing"
+	"time"
+)
+
+var nbmaKey IntfConfKey
+var nbmaIntf IntfConf
+
+func initNbmaTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+
+	nbmaKey = IntfConfKey{
+		IPAddr:  config.IpAddress("10.1.1.2"),
+		IntfIdx: 0,
+	}
+	nbmaIntf = intf
+	nbmaIntf.IfType = config.Nbma
+	nbmaIntf.IfIpAddr = net.IP{10, 1, 1, 2}
+	nbmaIntf.IfNetmask = []byte{255, 255, 255, 0}
+	nbmaIntf.IfDRIp = []byte{0, 0, 0, 0}
+	nbmaIntf.IfBDRIp = []byte{0, 0, 0, 0}
+	nbmaIntf.IfFSMState = config.OtherDesignatedRouter
+	nbmaIntf.IfCost = 10
+	nbmaIntf.NeighborMap = make(map[NeighborConfKey]NeighborData)
+	ospf.IntfConfMap[nbmaKey] = nbmaIntf
+}
+
+func TestOspfNbma(t *testing.T) {
+	fmt.Println("\n**************** NBMA ************\n")
+	initNbmaTestParams()
+	for index := 1; index < 5; index++ {
+		err := nbmaTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for nbma ", index)
+		}
+	}
+}
+
+func nbmaTestLogic(tNum int) int {
+	nbr1 := NeighborConfKey{IPAddr: "10.1.1.5", IntfIdx: 0}
+	nbr2 := NeighborConfKey{IPAddr: "10.1.1.6", IntfIdx: 0}
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running processNbmaNbrConfig")
+		if ospf.ValidateNbmaNbrConf(config.NbrConf{NbrIpAddress: "224.0.0.5"}) == nil {
+			fmt.Println("Multicast neighbor address accepted")
+			return FAIL
+		}
+		for _, conf := range []config.NbrConf{
+			{NbrIpAddress: "10.1.1.5", NbrPriority: 1},
+			{NbrIpAddress: "10.1.1.6", NbrPriority: 0},
+			{NbrIpAddress: "10.2.2.2", NbrPriority: 1},
+		} {
+			if err := ospf.ValidateNbmaNbrConf(conf); err != nil {
+				fmt.Println("Valid neighbor rejected ", err)
+				return FAIL
+			}
+			ospf.processNbmaNbrConfig(NbmaNbrConfMsg{Op: true, Conf: conf})
+		}
+		nbrList := ospf.getNbmaNbrList(nbmaKey, nbmaIntf)
+		if len(nbrList) != 2 {
+			fmt.Println("Expected 2 neighbors on the interface ", nbrList)
+			return FAIL
+		}
+		if !ospf.isNonBroadcastIntf(nbmaKey, nbmaIntf) {
+			fmt.Println("NBMA interface not treated as non broadcast")
+			return FAIL
+		}
+		if !ospf.isNbmaNbrConfigured(nbr1) {
+			fmt.Println("Neighbor not marked permanent")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running nbmaHelloDue")
+		now := time.Now()
+		if !ospf.nbmaHelloDue(nbmaIntf, nbr1, now) {
+			fmt.Println("Down neighbor not polled")
+			return FAIL
+		}
+		if ospf.nbmaHelloDue(nbmaIntf, nbr1, now.Add(time.Second)) {
+			fmt.Println("Down neighbor polled before PollInterval")
+			return FAIL
+		}
+		if ospf.nbmaHelloDue(nbmaIntf, nbr2, now) {
+			fmt.Println("Hello sent to ineligible neighbor")
+			return FAIL
+		}
+		drIntf := nbmaIntf
+		drIntf.IfFSMState = config.DesignatedRouter
+		if !ospf.nbmaHelloDue(drIntf, nbr2, now) {
+			fmt.Println("DR did not send hello to ineligible neighbor")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running isMcastOspfPkt")
+		pkt := ospf.BuildHelloPkt(nbmaKey, nbmaIntf)
+		if pkt != nil && !isMcastOspfPkt(pkt) {
+			fmt.Println("Multicast hello not detected")
+			return FAIL
+		}
+		pkt = ospf.buildHelloPktToDst(nbmaKey, nbmaIntf, net.IP{10, 1, 1, 5}, broadcastMAC)
+		if pkt != nil && isMcastOspfPkt(pkt) {
+			fmt.Println("Unicast hello detected as multicast")
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running constructP2MPLinks")
+		p2mpIntf := nbmaIntf
+		p2mpIntf.IfType = config.PointToMultipoint
+		p2mpIntf.NeighborMap = make(map[NeighborConfKey]NeighborData)
+		p2mpIntf.NeighborMap[nbr1] = NeighborData{}
+		p2mpIntf.NeighborMap[nbr2] = NeighborData{}
+		ospf.NeighborConfigMap[nbr1] = OspfNeighborEntry{OspfNbrRtrId: 5, OspfNbrState: config.NbrFull}
+		ospf.NeighborConfigMap[nbr2] = OspfNeighborEntry{OspfNbrRtrId: 6, OspfNbrState: config.NbrTwoWay}
+		links := ospf.constructP2MPLinks(nbmaKey, p2mpIntf)
+		if len(links) != 2 {
+			fmt.Println("Expected host link and one p2p link ", links)
+			return FAIL
+		}
+		if links[0].LinkType != StubLink || links[0].LinkData != 0xffffffff {
+			fmt.Println("Host route stub link missing ", links[0])
+			return FAIL
+		}
+		if links[1].LinkType != P2PLink || links[1].LinkId != 5 {
+			fmt.Println("P2P link to full neighbor missing ", links[1])
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
@@ -27,6 +27,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"l3/ospf/config"
+	"strings"
 	"time"
 )
 
@@ -174,7 +175,9 @@ func (server *OSPFServer) processNeighborExstart(nbrKey NeighborConfKey,
 	var lsa_attach uint8
 	if negotiationDone {
 		//server.logger.Debug(fmt.Sprintln("DBD: (Exstart) lsa_headers = ", len(nbrDbPkt.lsa_headers)))
-		server.generateDbSummaryList(nbrKey)
+		/* RFC 2328 10.6 record the neighbor options once negotiation is done */
+		nbrConf.OspfNbrOptions = int(nbrDbPkt.options)
+		server.generateDbSummaryList(nbrKey, nbrDbPkt.options)
 		if nbrConf.isMaster != true { // i am the master
 			dbd_mdata, last_exchange = server.ConstructAndSendDbdPacket(nbrKey, false, true, true,
 				nbrDbPkt.options, nbrDbPkt.dd_sequence_number+1, true, false, ifMtu)
@@ -213,7 +216,7 @@ func (server *OSPFServer) processNeighborExstart(nbrKey NeighborConfKey,
 			OspfNbrIPAddr:          nbrConf.OspfNbrIPAddr,
 			OspfRtrPrio:            nbrConf.OspfRtrPrio,
 			intfConfKey:            nbrConf.intfConfKey,
-			OspfNbrOptions:         0,
+			OspfNbrOptions:         nbrConf.OspfNbrOptions,
 			OspfNbrState:           nbrConf.OspfNbrState,
 			isStateUpdate:          true,
 			OspfNbrInactivityTimer: time.Now(),
@@ -222,6 +225,7 @@ func (server *OSPFServer) processNeighborExstart(nbrKey NeighborConfKey,
 			isSeqNumUpdate:         true,
 			isMaster:               nbrConf.isMaster,
 			isMasterUpdate:         true,
+			isOptionsUpdate:        negotiationDone,
 			nbrEvent:               nbrConf.nbrEvent,
 			ospfNbrLsaIndex:        nbrConf.ospfNbrLsaIndex + lsa_attach,
 		},
@@ -683,7 +687,7 @@ func (server *OSPFServer) ProcessTxNbrPkt() {
 
 }
 
-func (server *OSPFServer) generateDbSummaryList(nbrConfKey NeighborConfKey) {
+func (server *OSPFServer) generateDbSummaryList(nbrConfKey NeighborConfKey, nbrOptions uint8) {
 	nbrConf, exists := server.NeighborConfigMap[nbrConfKey]
 
 	if !exists {
@@ -754,10 +758,20 @@ func (server *OSPFServer) generateDbSummaryList(nbrConfKey NeighborConfKey) {
 	}
 
 	asExternal_list := server.generateDbasExternalList(areaId)
-	if asExternal_list != nil {
+	if asExternal_list != nil && !isVirtualIntfKey(nbrConf.intfConfKey) {
 		db_list = append(db_list, asExternal_list...)
 	}
 
+	nssaExternal_list := server.generateDbNssaExternalList(areaId)
+	if nssaExternal_list != nil {
+		db_list = append(db_list, nssaExternal_list...)
+	}
+
+	opaque_list := server.generateDbOpaqueList(areaId, nbrConf.intfConfKey, nbrOptions)
+	if opaque_list != nil {
+		db_list = append(db_list, opaque_list...)
+	}
+
 	for lsa := range db_list {
 		rtr_id := convertUint32ToIPv4(db_list[lsa].lsa_headers.adv_router_id)
 		server.logger.Info(fmt.Sprintln(lsa, ": ", rtr_id, " lsatype ", db_list[lsa].lsa_headers.ls_type))
@@ -774,6 +788,10 @@ func (server *OSPFServer) generateDbasExternalList(self_areaId uint32) []*ospfNe
 	if !server.ospfGlobalConf.AreaBdrRtrStatus {
 		return nil // dont add self gen LSA if I am not ASBR
 	}
+	areaId := config.AreaId(convertUint32ToIPv4(self_areaId))
+	if server.isStubArea(areaId) || server.isNssaArea(areaId) {
+		return nil
+	}
 	db_list := []*ospfNeighborDBSummary{}
 	lsdbKey := LsdbKey{
 		AreaId: self_areaId,
@@ -806,6 +824,36 @@ func (server *OSPFServer) generateDbasExternalList(self_areaId uint32) []*ospfNe
 	return db_list
 }
 
+/*@fn generateDbNssaExternalList
+This function generates the Type-7 list of an NSSA
+*/
+func (server *OSPFServer) generateDbNssaExternalList(self_areaId uint32) []*ospfNeighborDBSummary {
+	if !server.isNssaArea(config.AreaId(convertUint32ToIPv4(self_areaId))) {
+		return nil
+	}
+	db_list := []*ospfNeighborDBSummary{}
+	lsdbKey := LsdbKey{
+		AreaId: self_areaId,
+	}
+
+	area_lsa, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("negotiation: NSSA external LSA doesnt exist"))
+		return nil
+	}
+
+	for lsaKey, drlsa := range area_lsa.NSSAExternalLsaMap {
+		db_nssa := newospfNeighborDBSummary()
+		db_nssa.lsa_headers = getLsaHeaderFromLsa(drlsa.LsaMd.LSAge, drlsa.LsaMd.Options,
+			NSSAExternalLSA, lsaKey.LSId, lsaKey.AdvRouter,
+			uint32(drlsa.LsaMd.LSSequenceNum), drlsa.LsaMd.LSChecksum,
+			drlsa.LsaMd.LSLen)
+		db_nssa.valid = true
+		db_list = append(db_list, db_nssa)
+	}
+	return db_list
+}
+
 /* @fn generateDbsummaryLsaList
 This function will attach summary LSAs if the router is ABR
 */
@@ -921,30 +969,14 @@ func (server *OSPFServer) neighborDeadTimerEvent(nbrConfKey NeighborConfKey) {
 		server.logger.Info(fmt.Sprintln("NBRSCAN: DEAD ", nbrConfKey.IPAddr))
 
 		_, exists := server.NeighborConfigMap[nbrConfKey]
-		if exists {
+		if exists && server.isGraceHelperNbr(nbrConfKey) {
+			// restarting neighbor stays up till the grace period ends
 			nbrConf := server.NeighborConfigMap[nbrConfKey]
-			msg := DbEventMsg{
-				eventType: config.ADJACENCY,
-				eventInfo: "Neighbor Dead " + nbrConf.OspfNbrIPAddr.String(),
-			}
-			server.DbEventOp <- msg
-			nbrConfMsg := ospfNeighborConfMsg{
-				ospfNbrConfKey: nbrConfKey,
-				ospfNbrEntry: OspfNeighborEntry{
-					OspfNbrIPAddr:          nbrConf.OspfNbrIPAddr,
-					OspfRtrPrio:            nbrConf.OspfRtrPrio,
-					intfConfKey:            nbrConf.intfConfKey,
-					OspfNbrOptions:         0,
-					OspfNbrState:           config.NbrDown,
-					isStateUpdate:          true,
-					OspfNbrInactivityTimer: time.Now(),
-					OspfNbrDeadTimer:       nbrConf.OspfNbrDeadTimer,
-				},
-				nbrMsgType: NBRDEL,
-			}
-			// update neighbor map
-			server.processNeighborDeadEvent(nbrConfKey, nbrConf.intfConfKey)
-			server.neighborConfCh <- nbrConfMsg
+			nbrConf.NbrDeadTimer.Reset(nbrConf.OspfNbrDeadTimer)
+			return
+		}
+		if exists {
+			server.processNbrDown(nbrConfKey, "Neighbor Dead ")
 		}
 	} // end of afterFunc callback
 
@@ -957,6 +989,40 @@ func (server *OSPFServer) neighborDeadTimerEvent(nbrConfKey NeighborConfKey) {
 
 }
 
+/* @fn processNbrDown
+Tear down the adjacency, used by the router dead interval expiry
+and by the BFD session down event.
+*/
+func (server *OSPFServer) processNbrDown(nbrConfKey NeighborConfKey, reason string) {
+	nbrConf, exists := server.NeighborConfigMap[nbrConfKey]
+	if !exists {
+		return
+	}
+	msg := DbEventMsg{
+		eventType: config.ADJACENCY,
+		eventInfo: reason + nbrConf.OspfNbrIPAddr.String(),
+	}
+	server.DbEventOp <- msg
+	nbrConfMsg := ospfNeighborConfMsg{
+		ospfNbrConfKey: nbrConfKey,
+		ospfNbrEntry: OspfNeighborEntry{
+			OspfNbrIPAddr:          nbrConf.OspfNbrIPAddr,
+			OspfRtrPrio:            nbrConf.OspfRtrPrio,
+			intfConfKey:            nbrConf.intfConfKey,
+			OspfNbrOptions:         0,
+			OspfNbrState:           config.NbrDown,
+			isStateUpdate:          true,
+			OspfNbrInactivityTimer: time.Now(),
+			OspfNbrDeadTimer:       nbrConf.OspfNbrDeadTimer,
+		},
+		nbrMsgType: NBRDEL,
+		reason:     strings.TrimSpace(reason),
+	}
+	// update neighbor map
+	server.processNeighborDeadEvent(nbrConfKey, nbrConf.intfConfKey)
+	server.neighborConfCh <- nbrConfMsg
+}
+
 /*@fn refreshNeighborSlice
 Refresh get bulk slice for all keys.
 */
@@ -1035,6 +1101,7 @@ func (server *OSPFServer) processIntfDownEvent(intfKey IntfConfKey) {
 		nbrConfMsg := ospfNeighborConfMsg{
 			ospfNbrConfKey: nbr,
 			nbrMsgType:     NBRDEL,
+			reason:         "InterfaceDown",
 		}
 		server.neighborConfCh <- nbrConfMsg
 	}
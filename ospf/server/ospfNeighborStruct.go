@@ -54,6 +54,8 @@ const (
 	LSASUMMARYFLOOD = 4 //flood summary LSAs in different areas.
 	LSAEXTFLOOD     = 5 //flood AS External summary LSA
 	LSAROUTERFLOOD  = 6 //flood only router LSA
+	LSANSSAFLOOD    = 7 //flood NSSA external LSA within its area
+	LSAOPAQUEFLOOD  = 8 //flood self originated opaque LSA within its scope
 )
 
 type NeighborConfKey struct {
@@ -82,6 +84,7 @@ type OspfNeighborEntry struct {
 	isSeqNumUpdate         bool
 	isMaster               bool
 	isMasterUpdate         bool
+	isOptionsUpdate        bool
 	ospfNbrDBDTickerCh     *time.Ticker
 
 	ospfNbrLsaIndex        uint8       // db_summary list index
@@ -124,6 +127,7 @@ type ospfNeighborConfMsg struct {
 	ospfNbrConfKey NeighborConfKey
 	ospfNbrEntry   OspfNeighborEntry
 	nbrMsgType     NbrMsgType
+	reason         string // state change reason for the event log
 }
 
 type ospfNeighborDBDMsg struct {
@@ -197,7 +201,11 @@ func (server *OSPFServer) UpdateNeighborConf() {
 			intfConf, _ := server.IntfConfMap[nbrMsg.ospfNbrEntry.intfConfKey]
 			//server.logger.Info(fmt.Sprintln("Update neighbor conf.  received"))
 			if nbrMsg.nbrMsgType == NBRDEL {
+				server.recordNbrState(nbrMsg.ospfNbrConfKey, server.NeighborConfigMap[nbrMsg.ospfNbrConfKey].OspfNbrRtrId,
+					config.NbrDown, nbrMsg.reason)
 				delete(server.NeighborConfigMap, nbrMsg.ospfNbrConfKey)
+				server.clearNbrAuthSeqNum(nbrMsg.ospfNbrConfKey)
+				server.deleteNbrBfdSession(nbrMsg.ospfNbrConfKey)
 				server.logger.Info(fmt.Sprintln("DELETE neighbor with nbr id - ",
 					nbrMsg.ospfNbrConfKey.IPAddr, nbrMsg.ospfNbrConfKey.IntfIdx))
 				continue
@@ -216,6 +224,9 @@ func (server *OSPFServer) UpdateNeighborConf() {
 			if nbrMsg.ospfNbrEntry.isMasterUpdate {
 				nbrConf.isMaster = nbrMsg.ospfNbrEntry.isMaster
 			}
+			if nbrMsg.ospfNbrEntry.isOptionsUpdate {
+				nbrConf.OspfNbrOptions = nbrMsg.ospfNbrEntry.OspfNbrOptions
+			}
 			nbrConf.OspfNbrRtrId = nbrMsg.ospfNbrEntry.OspfNbrRtrId
 			nbrConf.ospfNbrDBDTickerCh = nbrMsg.ospfNbrEntry.ospfNbrDBDTickerCh
 			nbrConf.ospfNbrLsaReqIndex = nbrMsg.ospfNbrEntry.ospfNbrLsaReqIndex
@@ -253,6 +264,10 @@ func (server *OSPFServer) UpdateNeighborConf() {
 				nbrConf.NbrDeadTimer.Stop()
 				nbrConf.NbrDeadTimer.Reset(nbrMsg.ospfNbrEntry.OspfNbrDeadTimer)
 			}
+			server.recordNbrState(nbrMsg.ospfNbrConfKey, nbrConf.OspfNbrRtrId, nbrConf.OspfNbrState, nbrMsg.reason)
+			if nbrConf.OspfNbrState >= config.NbrTwoWay {
+				server.addNbrBfdSession(nbrMsg.ospfNbrConfKey, server.NeighborConfigMap[nbrMsg.ospfNbrConfKey])
+			}
 
 			//rtr_id := convertUint32ToIPv4(nbrMsg.ospfNbrEntry.OspfNbrRtrId)
 		//	server.logger.Info(fmt.Sprintln("NBR UPDATE: Nbr , state ", rtr_id, " : ", nbrConf.OspfNbrState))
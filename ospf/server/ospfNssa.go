@@ -0,0 +1,654 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+)
+
+/*
+   NSSA support (RFC 3101)
+   Redistributed routes are originated as Type-7 LSAs inside every
+   NSSA the router is attached to. Type-7 LSAs are flooded only within
+   their area. An NSSA border router elected (or configured) as
+   translator converts Type-7 LSAs with the P-bit set into Type-5 LSAs
+   for the rest of the AS.
+*/
+
+/*@fn getNssaForwardingAddr
+Returns the lowest interface address the router has in the NSSA.
+It is used as forwarding address of self originated Type-7 LSAs
+so that the translated Type-5 LSAs still point inside the NSSA.
+*/
+func (server *OSPFServer) getNssaForwardingAddr(areaId uint32) uint32 {
+	fwdAddr := uint32(0)
+	for _, intf := range server.IntfConfMap {
+		if convertIPv4ToUint32(intf.IfAreaId) != areaId {
+			continue
+		}
+		ipAddr := convertAreaOrRouterIdUint32(intf.IfIpAddr.String())
+		if ipAddr == 0 {
+			continue
+		}
+		if fwdAddr == 0 || ipAddr < fwdAddr {
+			fwdAddr = ipAddr
+		}
+	}
+	return fwdAddr
+}
+
+/*@fn generateNssaExternalLsa
+Generate / delete Type-7 LSA for the redistributed route in
+each of the NSSA areas. Returns the LSA key per area.
+*/
+func (server *OSPFServer) generateNssaExternalLsa(route RouteMdata) map[uint32]LsaKey {
+	server.logger.Info(fmt.Sprintln("LSDB: Generating NSSA External LSA routemdata ", route))
+	lsaKeys := make(map[uint32]LsaKey)
+
+	lsaKey := LsaKey{
+		LSType:    NSSAExternalLSA,
+		LSId:      route.ipaddr & route.mask,
+		AdvRouter: convertIPv4ToUint32(server.ospfGlobalConf.RouterId),
+	}
+
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+		if !server.isNssaArea(areaId) {
+			continue
+		}
+		ent, exist := lsDbEnt.NSSAExternalLsaMap[lsaKey]
+		if !exist && route.isDel {
+			continue
+		}
+		ent.LsaMd.LSChecksum = 0
+		/* RFC 3101 2.4: the P-bit tells the NSSA border routers to
+		   translate the LSA. A border router originating the LSA
+		   itself already announces the route as Type-5 */
+		ent.LsaMd.Options = 0
+		if !server.ospfGlobalConf.AreaBdrRtrStatus {
+			ent.LsaMd.Options = NPOption
+		}
+		ent.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 16)
+		if !exist {
+			ent.LsaMd.LSSequenceNum = InitialSequenceNumber
+		} else if !route.isDel {
+			ent.LsaMd.LSSequenceNum = ent.LsaMd.LSSequenceNum + 1
+		}
+		ent.BitE = !route.type1
+		ent.FwdAddr = server.getNssaForwardingAddr(lsdbKey.AreaId)
+		ent.Metric = server.getStubRouterExtMetric(route.metric)
+		ent.Netmask = route.mask
+		ent.ExtRouteTag = route.tag
+
+		ent.LsaMd.LSAge = 0
+		LsaEnc := encodeASExternalLsa(ent, lsaKey)
+		checksumOffset := uint16(14)
+		ent.LsaMd.LSChecksum = computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+		if route.isDel {
+			ent.LsaMd.LSAge = config.MaxAge
+		}
+		lsDbEnt.NSSAExternalLsaMap[lsaKey] = ent
+		server.AreaLsdb[lsdbKey] = lsDbEnt
+
+		selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+		selfOrigLsaEnt[lsaKey] = !route.isDel
+		server.AreaSelfOrigLsa[lsdbKey] = selfOrigLsaEnt
+		lsaKeys[lsdbKey.AreaId] = lsaKey
+		server.logger.Info(fmt.Sprintln("NSSA: Added LSA to area ", lsdbKey, " lsaKey ", lsaKey))
+		if !exist {
+			var val LsdbSliceEnt
+			val.AreaId = lsdbKey.AreaId
+			val.LSType = lsaKey.LSType
+			val.LSId = lsaKey.LSId
+			val.AdvRtr = lsaKey.AdvRouter
+			server.LsdbSlice = append(server.LsdbSlice, val)
+			msg := DbLsdbMsg{
+				entry: val,
+				op:    true,
+			}
+			server.DbLsdbOp <- msg
+		}
+	}
+	return lsaKeys
+}
+
+func (server *OSPFServer) updateNssaExternalLSA(lsdbKey LsdbKey, lsaKey LsaKey) error {
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if exist {
+		ent, valid := lsDbEnt.NSSAExternalLsaMap[lsaKey]
+		if !valid {
+			server.logger.Warning(fmt.Sprintln("LSDB: NSSA external LSA doesnt exist lsdb ", lsdbKey, lsaKey))
+			return nil
+		}
+		ent.LsaMd.LSAge = 0
+		ent.LsaMd.LSChecksum = 0
+		LsaEnc := encodeASExternalLsa(ent, lsaKey)
+		checksumOffset := uint16(14)
+		ent.LsaMd.LSChecksum = computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+		lsDbEnt.NSSAExternalLsaMap[lsaKey] = ent
+		server.AreaLsdb[lsdbKey] = lsDbEnt
+
+		//update db entry
+		var val LsdbSliceEnt
+		val.AreaId = lsdbKey.AreaId
+		val.LSType = lsaKey.LSType
+		val.LSId = lsaKey.LSId
+		val.AdvRtr = lsaKey.AdvRouter
+		server.LsdbSlice = append(server.LsdbSlice, val)
+		msg := DbLsdbMsg{
+			entry: val,
+			op:    true,
+		}
+		server.DbLsdbOp <- msg
+	}
+	return nil
+}
+
+func (server *OSPFServer) processDeleteNssaExternalLsa(data []byte, areaId uint32) bool {
+	lsakey := NewLsaKey()
+	var val LsdbSliceEnt
+	nssaLsa := NewASExternalLsa()
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	decodeASExternalLsa(data, nssaLsa, lsakey)
+	lsDbEnt, _ := server.AreaLsdb[lsdbKey]
+	delete(lsDbEnt.NSSAExternalLsaMap, *lsakey)
+	server.AreaLsdb[lsdbKey] = lsDbEnt
+
+	val.AreaId = lsdbKey.AreaId
+	val.LSType = lsakey.LSType
+	val.LSId = lsakey.LSId
+	val.AdvRtr = lsakey.AdvRouter
+	err := server.DelLsdbEntry(val)
+	if err != nil {
+		server.logger.Info(fmt.Sprintln("DB: Failed to delete entry from db ", lsakey))
+	}
+	return true
+}
+
+func (server *OSPFServer) processRecvdNssaExternalLsa(data []byte, areaId uint32) bool {
+	lsakey := NewLsaKey()
+	nssaLsa := NewASExternalLsa()
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	if !server.isNssaArea(config.AreaId(convertUint32ToIPv4(areaId))) {
+		server.logger.Err(fmt.Sprintln("Recvd NSSA External LSA in non NSSA area ", areaId))
+		return false
+	}
+	decodeASExternalLsa(data, nssaLsa, lsakey)
+	selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+	_, exist := selfOrigLsaEnt[*lsakey]
+	if exist {
+		server.logger.Info("Recvd a self generated NSSA External LSA")
+		return false
+	}
+
+	//Check Checksum
+	csum := computeFletcherChecksum(data[2:], FLETCHER_CHECKSUM_VALIDATE)
+	if csum != 0 {
+		server.logger.Err("Invalid NSSA External LSA Checksum")
+		return false
+	}
+	lsDbEnt, _ := server.AreaLsdb[lsdbKey]
+	ent, exist := lsDbEnt.NSSAExternalLsaMap[*lsakey]
+	if exist {
+		if ent.LsaMd.LSSequenceNum >= nssaLsa.LsaMd.LSSequenceNum {
+			server.logger.Err("Old instance of NSSA External LSA Recvd")
+			return false
+		}
+	}
+	lsDbEnt.NSSAExternalLsaMap[*lsakey] = *nssaLsa
+	server.AreaLsdb[lsdbKey] = lsDbEnt
+	if !exist {
+		var val LsdbSliceEnt
+		val.AreaId = lsdbKey.AreaId
+		val.LSType = lsakey.LSType
+		val.LSId = lsakey.LSId
+		val.AdvRtr = lsakey.AdvRouter
+		server.LsdbSlice = append(server.LsdbSlice, val)
+		msg := DbLsdbMsg{
+			entry: val,
+			op:    true,
+		}
+		server.DbLsdbOp <- msg
+	}
+	return true
+}
+
+/*
+@fn processNssaExternalLSAFlood
+	Type-7 LSAs are area scoped. Flood only on the
+	interfaces attached to the NSSA.
+*/
+func (server *OSPFServer) processNssaExternalLSAFlood(areaId uint32, lsakey LsaKey) {
+	var lsaEncPkt []byte
+	entry, ret := server.getNssaExternalLsaFromLsdb(areaId, lsakey)
+	if ret == LsdbEntryNotFound {
+		server.logger.Info(fmt.Sprintln("NSSA: Lsa not found . Area",
+			areaId, " LSA key ", lsakey))
+		return
+	}
+	LsaEnc := encodeASExternalLsa(entry, lsakey)
+	pktLen := len(LsaEnc)
+	checksumOffset := uint16(14)
+	checkSum := computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+	binary.BigEndian.PutUint16(LsaEnc[16:18], checkSum)
+	binary.BigEndian.PutUint16(LsaEnc[18:20], uint16(pktLen))
+
+	no_lsas := uint32(1)
+	lsas_enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(lsas_enc, no_lsas)
+	lsaEncPkt = append(lsaEncPkt, lsas_enc...)
+	lsaEncPkt = append(lsaEncPkt, LsaEnc...)
+
+	dstMac := net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05}
+	dstIp := net.IP{224, 0, 0, 5}
+	for key, intf := range server.IntfConfMap {
+		if convertIPv4ToUint32(intf.IfAreaId) != areaId {
+			continue
+		}
+		nbrMdata, ok := ospfIntfToNbrMap[key]
+		if ok && len(nbrMdata.nbrList) > 0 {
+			send_pkt := server.BuildLsaUpdPkt(key, intf, dstMac, dstIp, len(lsaEncPkt), lsaEncPkt)
+			server.logger.Info(fmt.Sprintln("NSSA: Send  LSA to interface ", intf.IfIpAddr, " area ", intf.IfAreaId))
+			server.SendOspfPkt(key, send_pkt)
+		}
+	}
+}
+
+/*@fn getNssaFwdAddrRoute
+Type-7 forwarding addresses must be reachable through an
+intra area path of the NSSA (RFC 3101 2.5 (3)).
+Returns the longest matching intra area network entry.
+*/
+func (server *OSPFServer) getNssaFwdAddrRoute(areaId uint32, fwdAddr uint32) (RoutingTblEntry, bool) {
+	var best RoutingTblEntry
+	found := false
+	bestMask := uint32(0)
+	tempAreaRoutingTbl := server.TempAreaRoutingTbl[AreaIdKey{AreaId: areaId}]
+	for rKey, rEnt := range tempAreaRoutingTbl.RoutingTblMap {
+		if rKey.DestType != Network ||
+			rEnt.PathType != IntraArea {
+			continue
+		}
+		if fwdAddr&rKey.AddrMask != rKey.DestId {
+			continue
+		}
+		if !found || rKey.AddrMask > bestMask {
+			best = rEnt
+			bestMask = rKey.AddrMask
+			found = true
+		}
+	}
+	return best, found
+}
+
+/*@fn getNssaAsbrRoute
+Type-7 LSAs without forwarding address are routed
+towards the originating ASBR.
+*/
+func (server *OSPFServer) getNssaAsbrRoute(areaId uint32, advRouter uint32) (RoutingTblEntry, bool) {
+	tempAreaRoutingTbl := server.TempAreaRoutingTbl[AreaIdKey{AreaId: areaId}]
+	for _, destType := range []DestType{ASBdrRouter, ASAreaBdrRouter, AreaBdrRouter} {
+		rKey := RoutingTblEntryKey{
+			DestId:   advRouter,
+			AddrMask: 0,
+			DestType: destType,
+		}
+		rEnt, exist := tempAreaRoutingTbl.RoutingTblMap[rKey]
+		if exist {
+			return rEnt, true
+		}
+	}
+	return RoutingTblEntry{}, false
+}
+
+/*@fn HandleNssaExternalLsa
+Routing table calculation for Type-7 LSAs (RFC 3101 2.5).
+Same as AS external calculation except that the forwarding
+address is resolved inside the NSSA.
+*/
+func (server *OSPFServer) HandleNssaExternalLsa(areaId uint32) {
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		server.logger.Err(fmt.Sprintln("Unable to find Area Lsdb entry"))
+		return
+	}
+	areaIdKey := AreaIdKey{
+		AreaId: areaId,
+	}
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+
+	for lsaKey, lsaEnt := range lsDbEnt.NSSAExternalLsaMap {
+		if lsaEnt.Metric == LSInfinity ||
+			lsaEnt.LsaMd.LSAge == config.MaxAge {
+			server.logger.Info("Ignoring NSSA External LSA...")
+			continue
+		}
+		if lsaKey.AdvRouter == rtrId {
+			continue
+		}
+
+		var fEnt RoutingTblEntry
+		var exist bool
+		if lsaEnt.FwdAddr == 0 {
+			fEnt, exist = server.getNssaAsbrRoute(areaId, lsaKey.AdvRouter)
+		} else {
+			fEnt, exist = server.getNssaFwdAddrRoute(areaId, lsaEnt.FwdAddr)
+		}
+		if !exist || fEnt.NumOfPaths == 0 {
+			server.logger.Info(fmt.Sprintln("NSSA: no route to forwarding address / ASBR for ", lsaKey))
+			continue
+		}
+
+		var pathType PathType
+		if lsaEnt.BitE == true {
+			pathType = Type2Ext
+		} else {
+			pathType = Type1Ext
+		}
+		cost := fEnt.Cost + uint16(lsaEnt.Metric)
+		rKey := RoutingTblEntryKey{
+			DestId:   lsaKey.LSId & lsaEnt.Netmask,
+			AddrMask: lsaEnt.Netmask,
+			DestType: Network,
+		}
+
+		tempAreaRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
+		rEnt, exist := tempAreaRoutingTbl.RoutingTblMap[rKey]
+		if exist {
+			if rEnt.PathType > pathType {
+				// Intra, inter area and Type-1 paths are preferred
+				continue
+			}
+			if rEnt.PathType == pathType &&
+				rEnt.Cost < cost {
+				server.logger.Info("Route already exists with lesser cost")
+				continue
+			}
+			if rEnt.PathType == pathType &&
+				rEnt.Cost == cost {
+				for key, _ := range fEnt.NextHops {
+					key.AdvRtr = lsaKey.AdvRouter
+					rEnt.NextHops[key] = true
+				}
+				rEnt.NumOfPaths = len(rEnt.NextHops)
+				tempAreaRoutingTbl.RoutingTblMap[rKey] = rEnt
+				server.TempAreaRoutingTbl[areaIdKey] = tempAreaRoutingTbl
+				continue
+			}
+		}
+		rEnt.OptCapabilities = 0
+		rEnt.PathType = pathType
+		rEnt.Cost = cost
+		rEnt.Type2Cost = uint16(lsaEnt.Metric)
+		rEnt.RouteTag = lsaEnt.ExtRouteTag
+		rEnt.NextHops = make(map[NextHop]bool)
+		for key, _ := range fEnt.NextHops {
+			key.AdvRtr = lsaKey.AdvRouter
+			rEnt.NextHops[key] = true
+		}
+		rEnt.NumOfPaths = len(rEnt.NextHops)
+		tempAreaRoutingTbl.RoutingTblMap[rKey] = rEnt
+		server.TempAreaRoutingTbl[areaIdKey] = tempAreaRoutingTbl
+	}
+}
+
+/*@fn electNssaTranslator
+RFC 3101 3.1 translator election. A border router configured
+as Always translates unconditionally. Candidates translate only if
+no other reachable NSSA border router has the Nt bit set and they
+have the highest router id among the reachable NSSA border routers.
+*/
+func (server *OSPFServer) electNssaTranslator(areaConfKey AreaConfKey) {
+	areaId := convertAreaOrRouterIdUint32(string(areaConfKey.AreaId))
+	aConf, exist := server.AreaConfMap[areaConfKey]
+	if !exist {
+		return
+	}
+	state := config.NssaTranslatorDisabled
+	if server.ospfGlobalConf.AreaBdrRtrStatus {
+		if aConf.AreaNssaTranslatorRole == config.Always {
+			state = config.NssaTranslatorEnabled
+		} else {
+			state = config.NssaTranslatorElected
+			rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+			lsDbEnt, _ := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+			tempAreaRoutingTbl := server.TempAreaRoutingTbl[AreaIdKey{AreaId: areaId}]
+			for lsaKey, lsaEnt := range lsDbEnt.RouterLsaMap {
+				if lsaKey.AdvRouter == rtrId ||
+					lsaEnt.BitB == false ||
+					lsaEnt.LsaMd.LSAge == config.MaxAge {
+					continue
+				}
+				_, abr := tempAreaRoutingTbl.RoutingTblMap[RoutingTblEntryKey{
+					DestId: lsaKey.AdvRouter, AddrMask: 0, DestType: AreaBdrRouter}]
+				_, asAbr := tempAreaRoutingTbl.RoutingTblMap[RoutingTblEntryKey{
+					DestId: lsaKey.AdvRouter, AddrMask: 0, DestType: ASAreaBdrRouter}]
+				if !abr && !asAbr {
+					continue
+				}
+				if lsaEnt.BitNt || lsaKey.AdvRouter > rtrId {
+					state = config.NssaTranslatorDisabled
+					break
+				}
+			}
+		}
+	}
+
+	ent, _ := server.AreaStateMap[areaConfKey]
+	if ent.AreaNssaTranslatorState != state {
+		server.logger.Info(fmt.Sprintln("NSSA: translator state of area ", areaConfKey.AreaId,
+			" changed from ", ent.AreaNssaTranslatorState, " to ", state))
+		ent.AreaNssaTranslatorState = state
+		ent.AreaNssaTranslatorEvents++
+		server.AreaStateMap[areaConfKey] = ent
+	}
+}
+
+/*@fn translateNssaExternalLsa
+Collect the Type-5 LSAs this router has to originate on behalf of
+the Type-7 LSAs of the NSSA (RFC 3101 3.2). Only LSAs with P-bit
+and a non zero forwarding address which is reachable are translated.
+The results are installed by installNssaTranslatedLsa.
+*/
+func (server *OSPFServer) translateNssaExternalLsa(areaConfKey AreaConfKey) {
+	state := server.AreaStateMap[areaConfKey].AreaNssaTranslatorState
+	if state != config.NssaTranslatorEnabled &&
+		state != config.NssaTranslatorElected {
+		return
+	}
+	areaId := convertAreaOrRouterIdUint32(string(areaConfKey.AreaId))
+	lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+	if !exist {
+		return
+	}
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	for lsaKey, lsaEnt := range lsDbEnt.NSSAExternalLsaMap {
+		if lsaKey.AdvRouter == rtrId ||
+			lsaEnt.LsaMd.Options&NPOption == 0 ||
+			lsaEnt.FwdAddr == 0 ||
+			lsaEnt.Metric == LSInfinity ||
+			lsaEnt.LsaMd.LSAge == config.MaxAge {
+			continue
+		}
+		if _, ok := server.getNssaFwdAddrRoute(areaId, lsaEnt.FwdAddr); !ok {
+			continue
+		}
+		tKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      lsaKey.LSId & lsaEnt.Netmask,
+			AdvRouter: rtrId,
+		}
+		old, exist := server.NssaTranslatedLsDb[tKey]
+		if exist {
+			// prefer Type-1 metric, then the lower metric
+			if old.BitE == false && lsaEnt.BitE == true {
+				continue
+			}
+			if old.BitE == lsaEnt.BitE && old.Metric <= lsaEnt.Metric {
+				continue
+			}
+		}
+		var tLsa ASExternalLsa
+		tLsa.LsaMd.Options = EOption
+		tLsa.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + 16)
+		tLsa.Netmask = lsaEnt.Netmask
+		tLsa.BitE = lsaEnt.BitE
+		tLsa.Metric = lsaEnt.Metric
+		tLsa.FwdAddr = lsaEnt.FwdAddr
+		tLsa.ExtRouteTag = lsaEnt.ExtRouteTag
+		server.NssaTranslatedLsDb[tKey] = tLsa
+	}
+}
+
+/*@fn installTranslatedAsExternalLsa
+Install a translated Type-5 LSA in all the areas which carry
+AS external LSAs. When flush is set the LSA is aged out instead.
+*/
+func (server *OSPFServer) installTranslatedAsExternalLsa(lsaKey LsaKey, lsa ASExternalLsa, flush bool) {
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		areaId := config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId))
+		if server.isStubArea(areaId) || server.isNssaArea(areaId) {
+			continue
+		}
+		ent, exist := lsDbEnt.ASExternalLsaMap[lsaKey]
+		if !exist && flush {
+			continue
+		}
+		if flush {
+			lsa = ent
+		} else if exist {
+			lsa.LsaMd.LSSequenceNum = ent.LsaMd.LSSequenceNum + 1
+		} else {
+			lsa.LsaMd.LSSequenceNum = InitialSequenceNumber
+		}
+		lsa.LsaMd.LSAge = 0
+		lsa.LsaMd.LSChecksum = 0
+		LsaEnc := encodeASExternalLsa(lsa, lsaKey)
+		checksumOffset := uint16(14)
+		lsa.LsaMd.LSChecksum = computeFletcherChecksum(LsaEnc[2:], checksumOffset)
+		if flush {
+			lsa.LsaMd.LSAge = config.MaxAge
+		}
+		lsDbEnt.ASExternalLsaMap[lsaKey] = lsa
+		server.AreaLsdb[lsdbKey] = lsDbEnt
+
+		selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+		selfOrigLsaEnt[lsaKey] = !flush
+		server.AreaSelfOrigLsa[lsdbKey] = selfOrigLsaEnt
+		if !exist {
+			var val LsdbSliceEnt
+			val.AreaId = lsdbKey.AreaId
+			val.LSType = lsaKey.LSType
+			val.LSId = lsaKey.LSId
+			val.AdvRtr = lsaKey.AdvRouter
+			server.LsdbSlice = append(server.LsdbSlice, val)
+			msg := DbLsdbMsg{
+				entry: val,
+				op:    true,
+			}
+			server.DbLsdbOp <- msg
+		}
+	}
+}
+
+/*@fn isRedistributedAsExternalLsa
+Checks if the Type-5 LSA is already originated for a
+locally redistributed route. Those take precedence over
+translations.
+*/
+func (server *OSPFServer) isRedistributedAsExternalLsa(lsaKey LsaKey) bool {
+	if server.NssaTranslatedLsa[lsaKey] {
+		return false
+	}
+	for _, selfOrigLsaEnt := range server.AreaSelfOrigLsa {
+		if selfOrigLsaEnt[lsaKey] {
+			return true
+		}
+	}
+	return false
+}
+
+func (server *OSPFServer) sameTranslatedLsa(lsaKey LsaKey, lsa ASExternalLsa) bool {
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		ent, exist := lsDbEnt.ASExternalLsaMap[lsaKey]
+		if !exist {
+			continue
+		}
+		selfOrigLsaEnt, _ := server.AreaSelfOrigLsa[lsdbKey]
+		if !selfOrigLsaEnt[lsaKey] {
+			continue
+		}
+		return ent.Netmask == lsa.Netmask &&
+			ent.BitE == lsa.BitE &&
+			ent.Metric == lsa.Metric &&
+			ent.FwdAddr == lsa.FwdAddr &&
+			ent.ExtRouteTag == lsa.ExtRouteTag
+	}
+	return false
+}
+
+/*@fn installNssaTranslatedLsa
+Originate the Type-5 translations collected during SPF and flush
+the translations which are no longer valid.
+*/
+func (server *OSPFServer) installNssaTranslatedLsa() {
+	if server.NssaTranslatedLsDb == nil {
+		// no SPF run since the last install
+		return
+	}
+	ifkey := IntfConfKey{}
+	nbr := NeighborConfKey{}
+	for lsaKey, lsa := range server.NssaTranslatedLsDb {
+		if server.isRedistributedAsExternalLsa(lsaKey) {
+			continue
+		}
+		if server.NssaTranslatedLsa[lsaKey] &&
+			server.sameTranslatedLsa(lsaKey, lsa) {
+			continue
+		}
+		server.logger.Info(fmt.Sprintln("NSSA: Originate translated LSA ", lsaKey))
+		server.installTranslatedAsExternalLsa(lsaKey, lsa, false)
+		server.NssaTranslatedLsa[lsaKey] = true
+		server.sendLsdbToNeighborEvent(ifkey, nbr, 0, 0, 0, lsaKey, LSAEXTFLOOD)
+	}
+	for lsaKey, _ := range server.NssaTranslatedLsa {
+		if _, exist := server.NssaTranslatedLsDb[lsaKey]; exist {
+			continue
+		}
+		server.logger.Info(fmt.Sprintln("NSSA: Flush translated LSA ", lsaKey))
+		server.installTranslatedAsExternalLsa(lsaKey, ASExternalLsa{}, true)
+		delete(server.NssaTranslatedLsa, lsaKey)
+	}
+	server.NssaTranslatedLsDb = nil
+}
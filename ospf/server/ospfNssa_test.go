@@ -0,0 +1,280 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfNssa_test
+   This test covers
+   1) Type-7 LSA origination for redistributed routes.
+   2) NSSA route calculation using the forwarding address.
+   3) Translator election.
+   4) Type-7 to Type-5 translation and flush.
+   5) External path preference across areas.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"testing"
+)
+
+var nssaAreaId uint32
+var bbAreaId uint32
+var nssaAreaKey AreaConfKey
+var bbAreaKey AreaConfKey
+
+func initNssaTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+
+	nssaAreaKey = AreaConfKey{AreaId: config.AreaId("0.0.0.1")}
+	bbAreaKey = AreaConfKey{AreaId: config.AreaId("0.0.0.0")}
+	nssaAreaId = convertAreaOrRouterIdUint32("0.0.0.1")
+	bbAreaId = convertAreaOrRouterIdUint32("0.0.0.0")
+	ospf.AreaConfMap[nssaAreaKey] = AreaConf{
+		ImportAsExtern:         config.ImportNssa,
+		AreaSummary:            config.SendAreaSummary,
+		AreaNssaTranslatorRole: config.Candidate,
+		IntfListMap:            make(map[IntfConfKey]bool),
+	}
+	ospf.AreaConfMap[bbAreaKey] = AreaConf{
+		ImportAsExtern: config.ImportExternal,
+		AreaSummary:    config.SendAreaSummary,
+		IntfListMap:    make(map[IntfConfKey]bool),
+	}
+	ospf.initLSDatabase(nssaAreaId)
+	ospf.initLSDatabase(bbAreaId)
+
+	nssaIntf := intf
+	nssaIntf.IfAreaId = []byte{0, 0, 0, 1}
+	nssaIntf.IfIpAddr = net.IP{40, 1, 1, 1}
+	ospf.IntfConfMap[key] = nssaIntf
+}
+
+func TestOspfNssa(t *testing.T) {
+	fmt.Println("\n**************** NSSA ************\n")
+	initNssaTestParams()
+	for index := 1; index < 8; index++ {
+		err := nssaTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for NSSA ", index)
+		}
+	}
+}
+
+func nssaTestLogic(tNum int) int {
+	rtrId := convertIPv4ToUint32(ospf.ospfGlobalConf.RouterId)
+	route := RouteMdata{
+		metric: 20,
+		ipaddr: convertAreaOrRouterIdUint32("50.1.1.0"),
+		mask:   convertAreaOrRouterIdUint32("255.255.255.0"),
+		tag:    100,
+	}
+	remoteKey := LsaKey{
+		LSType:    NSSAExternalLSA,
+		LSId:      convertAreaOrRouterIdUint32("60.1.1.0"),
+		AdvRouter: convertAreaOrRouterIdUint32("9.9.9.9"),
+	}
+	fwdAddr := convertAreaOrRouterIdUint32("40.1.1.9")
+
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running generateNssaExternalLsa")
+		ospf.ospfGlobalConf.AreaBdrRtrStatus = false
+		lsaKeys := ospf.generateNssaExternalLsa(route)
+		lsaKey, exist := lsaKeys[nssaAreaId]
+		if !exist || len(lsaKeys) != 1 {
+			fmt.Println("Type-7 LSA not originated in NSSA ", lsaKeys)
+			return FAIL
+		}
+		lsa, ret := ospf.getNssaExternalLsaFromLsdb(nssaAreaId, lsaKey)
+		if ret != LsdbEntryFound || lsa.LsaMd.Options&NPOption == 0 ||
+			lsa.FwdAddr != convertAreaOrRouterIdUint32("40.1.1.1") {
+			fmt.Println("Bad Type-7 LSA ", lsa)
+			return FAIL
+		}
+		if _, ret = ospf.getASExternalLsaFromLsdb(nssaAreaId, LsaKey{LSType: ASExternalLSA, LSId: lsaKey.LSId, AdvRouter: rtrId}); ret == LsdbEntryFound {
+			fmt.Println("Type-5 LSA found in NSSA")
+			return FAIL
+		}
+		ospf.processNssaExternalLSAFlood(nssaAreaId, lsaKey)
+		route.isDel = true
+		ospf.generateNssaExternalLsa(route)
+		lsa, _ = ospf.getNssaExternalLsaFromLsdb(nssaAreaId, lsaKey)
+		if lsa.LsaMd.LSAge != config.MaxAge {
+			fmt.Println("Type-7 LSA not aged out ", lsa)
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running HandleNssaExternalLsa")
+		lsDbEnt := ospf.AreaLsdb[LsdbKey{AreaId: nssaAreaId}]
+		var lsa ASExternalLsa
+		lsa.LsaMd.Options = NPOption
+		lsa.LsaMd.LSSequenceNum = InitialSequenceNumber
+		lsa.Netmask = convertAreaOrRouterIdUint32("255.255.255.0")
+		lsa.BitE = true
+		lsa.Metric = 30
+		lsa.FwdAddr = fwdAddr
+		lsa.ExtRouteTag = 200
+		lsDbEnt.NSSAExternalLsaMap[remoteKey] = lsa
+		ospf.AreaLsdb[LsdbKey{AreaId: nssaAreaId}] = lsDbEnt
+
+		initNssaAreaRoutingTbl()
+		ospf.HandleNssaExternalLsa(nssaAreaId)
+		rKey := RoutingTblEntryKey{
+			DestId:   remoteKey.LSId,
+			AddrMask: lsa.Netmask,
+			DestType: Network,
+		}
+		rEnt, exist := ospf.TempAreaRoutingTbl[AreaIdKey{AreaId: nssaAreaId}].RoutingTblMap[rKey]
+		if !exist || rEnt.PathType != Type2Ext || rEnt.Cost != 40 || rEnt.RouteTag != 200 {
+			fmt.Println("NSSA route not computed ", rEnt)
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running electNssaTranslator")
+		ospf.ospfGlobalConf.AreaBdrRtrStatus = true
+		ospf.electNssaTranslator(nssaAreaKey)
+		if ospf.AreaStateMap[nssaAreaKey].AreaNssaTranslatorState != config.NssaTranslatorElected {
+			fmt.Println("Translator not elected ", ospf.AreaStateMap[nssaAreaKey])
+			return FAIL
+		}
+		aConf := ospf.AreaConfMap[nssaAreaKey]
+		aConf.AreaNssaTranslatorRole = config.Always
+		ospf.AreaConfMap[nssaAreaKey] = aConf
+		ospf.electNssaTranslator(nssaAreaKey)
+		if ospf.AreaStateMap[nssaAreaKey].AreaNssaTranslatorState != config.NssaTranslatorEnabled {
+			fmt.Println("Translator not enabled ", ospf.AreaStateMap[nssaAreaKey])
+			return FAIL
+		}
+		ospf.ospfGlobalConf.AreaBdrRtrStatus = false
+		ospf.electNssaTranslator(nssaAreaKey)
+		if ospf.AreaStateMap[nssaAreaKey].AreaNssaTranslatorState != config.NssaTranslatorDisabled {
+			fmt.Println("Translator not disabled ", ospf.AreaStateMap[nssaAreaKey])
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running translateNssaExternalLsa")
+		ospf.ospfGlobalConf.AreaBdrRtrStatus = true
+		ospf.electNssaTranslator(nssaAreaKey)
+		initNssaAreaRoutingTbl()
+		ospf.NssaTranslatedLsDb = make(map[LsaKey]ASExternalLsa)
+		ospf.translateNssaExternalLsa(nssaAreaKey)
+		ospf.installNssaTranslatedLsa()
+		tKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      remoteKey.LSId,
+			AdvRouter: rtrId,
+		}
+		lsa, ret := ospf.getASExternalLsaFromLsdb(bbAreaId, tKey)
+		if ret != LsdbEntryFound || lsa.FwdAddr != fwdAddr || lsa.Metric != 30 {
+			fmt.Println("Type-5 translation not installed ", lsa)
+			return FAIL
+		}
+		if _, ret = ospf.getASExternalLsaFromLsdb(nssaAreaId, tKey); ret == LsdbEntryFound {
+			fmt.Println("Type-5 translation installed in NSSA")
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running installNssaTranslatedLsa flush")
+		ospf.NssaTranslatedLsDb = make(map[LsaKey]ASExternalLsa)
+		ospf.installNssaTranslatedLsa()
+		tKey := LsaKey{
+			LSType:    ASExternalLSA,
+			LSId:      remoteKey.LSId,
+			AdvRouter: rtrId,
+		}
+		lsa, _ := ospf.getASExternalLsaFromLsdb(bbAreaId, tKey)
+		if lsa.LsaMd.LSAge != config.MaxAge || len(ospf.NssaTranslatedLsa) != 0 {
+			fmt.Println("Type-5 translation not flushed ", lsa)
+			return FAIL
+		}
+
+	case 6:
+		fmt.Println(tNum, ": Running isPreferredExtRoute")
+		nssaRoute := GlobalRoutingTblEntry{
+			AreaId: nssaAreaId,
+			RoutingTblEnt: RoutingTblEntry{
+				PathType:  Type2Ext,
+				Cost:      40,
+				Type2Cost: 30,
+			},
+		}
+		extRoute := nssaRoute.RoutingTblEnt
+		if !ospf.isPreferredExtRoute(bbAreaId, extRoute, nssaRoute) {
+			fmt.Println("Type-5 route not preferred over Type-7 route")
+			return FAIL
+		}
+		extRoute.PathType = InterArea
+		if ospf.isPreferredExtRoute(bbAreaId, nssaRoute.RoutingTblEnt, GlobalRoutingTblEntry{AreaId: bbAreaId, RoutingTblEnt: extRoute}) {
+			fmt.Println("External route preferred over inter area route")
+			return FAIL
+		}
+
+	case 7:
+		fmt.Println(tNum, ": Running getAreaOptions / isNssaNoSummaryArea")
+		if ospf.getAreaOptions(nssaAreaKey.AreaId) != NPOption ||
+			ospf.getAreaOptions(bbAreaKey.AreaId) != EOption {
+			fmt.Println("Wrong area options")
+			return FAIL
+		}
+		aConf := ospf.AreaConfMap[nssaAreaKey]
+		aConf.AreaSummary = config.NoAreaSummary
+		ospf.AreaConfMap[nssaAreaKey] = aConf
+		if !ospf.isNssaNoSummaryArea(nssaAreaKey.AreaId) {
+			fmt.Println("No summary NSSA not detected")
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
+
+func initNssaAreaRoutingTbl() {
+	ospf.TempAreaRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
+	var tbl AreaRoutingTbl
+	tbl.RoutingTblMap = make(map[RoutingTblEntryKey]RoutingTblEntry)
+	rKey := RoutingTblEntryKey{
+		DestId:   convertAreaOrRouterIdUint32("40.1.1.0"),
+		AddrMask: convertAreaOrRouterIdUint32("255.255.255.0"),
+		DestType: Network,
+	}
+	nextHop := NextHop{
+		IfIPAddr:  convertAreaOrRouterIdUint32("40.1.1.1"),
+		IfIdx:     1,
+		NextHopIP: 0,
+	}
+	tbl.RoutingTblMap[rKey] = RoutingTblEntry{
+		PathType:   IntraArea,
+		Cost:       10,
+		NumOfPaths: 1,
+		NextHops:   map[NextHop]bool{nextHop: true},
+	}
+	ospf.TempAreaRoutingTbl[AreaIdKey{AreaId: nssaAreaId}] = tbl
+}
@@ -0,0 +1,729 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+)
+
+/* Opaque LSAs (RFC 5250).
+   Link scope (type 9) LSAs are kept per interface, area scope (type 10)
+   LSAs in the area LSDB and AS scope (type 11) LSAs in the LSDB of each
+   area which carries AS external LSAs. The opaque information is not
+   interpreted here, grace LSAs and TE LSAs are handled by their owners.
+   Self originated opaque LSAs are kept in SelfOpaqueLsaMap and refreshed
+   with the other self originated LSAs.
+*/
+
+const (
+	OPAQUE_MAX_DATA_LEN = INTF_MTU_MIN - OSPF_HEADER_SIZE - OSPF_NO_OF_LSA_FIELD - OSPF_LSA_HEADER_SIZE - 20
+	OPAQUE_MAX_ID       = 0xffffff
+)
+
+type OpaqueLsa struct {
+	LsaMd LsaMetadata
+	Data  []byte
+}
+
+type OpaqueConfMsg struct {
+	Op     bool
+	Opaque *config.OpaqueConf
+	IfTe   *config.IfTeConf
+	Lsa    *config.OpaqueLsaConf
+}
+
+/* Area is set for area scope LSAs, the interface for link scope LSAs */
+type SelfOpaqueKey struct {
+	LSType  uint8
+	LSId    uint32
+	AreaId  uint32
+	IntfKey IntfConfKey
+}
+
+func (server *OSPFServer) initOpaque() {
+	server.OpaqueConfigCh = make(chan OpaqueConfMsg)
+	server.OpaqueLinkLsaMap = make(map[IntfConfKey]map[LsaKey]OpaqueLsa)
+	server.SelfOpaqueLsaMap = make(map[SelfOpaqueKey][]byte)
+	server.IfTeMap = make(map[IntfConfKey]config.IfTeConf)
+	server.TeLinkIdMap = make(map[IntfConfKey]uint32)
+}
+
+func isOpaqueLsaType(lsType uint8) bool {
+	return lsType == OpaqueLinkLSA || lsType == OpaqueAreaLSA || lsType == OpaqueASLSA
+}
+
+func getOpaqueLsId(opaqueType uint8, opaqueId uint32) uint32 {
+	return uint32(opaqueType)<<24 | (opaqueId & OPAQUE_MAX_ID)
+}
+
+func getOpaqueType(lsId uint32) uint8 {
+	return uint8(lsId >> 24)
+}
+
+func encodeOpaqueLsa(lsa OpaqueLsa, lsakey LsaKey) []byte {
+	opaqueLsa := make([]byte, OSPF_LSA_HEADER_SIZE+len(lsa.Data))
+	lsaHdr := encodeLsaHeader(lsa.LsaMd, lsakey)
+	copy(opaqueLsa[0:OSPF_LSA_HEADER_SIZE], lsaHdr)
+	copy(opaqueLsa[OSPF_LSA_HEADER_SIZE:], lsa.Data)
+	return opaqueLsa
+}
+
+func decodeOpaqueLsa(data []byte, lsa *OpaqueLsa, lsakey *LsaKey) error {
+	if len(data) < OSPF_LSA_HEADER_SIZE {
+		return errors.New("Opaque LSA shorter than LSA header")
+	}
+	lsa.LsaMd.LSAge = binary.BigEndian.Uint16(data[0:2])
+	lsa.LsaMd.Options = uint8(data[2])
+	lsakey.LSType = uint8(data[3])
+	lsakey.LSId = binary.BigEndian.Uint32(data[4:8])
+	lsakey.AdvRouter = binary.BigEndian.Uint32(data[8:12])
+	lsa.LsaMd.LSSequenceNum = int(binary.BigEndian.Uint32(data[12:16]))
+	lsa.LsaMd.LSChecksum = binary.BigEndian.Uint16(data[16:18])
+	lsa.LsaMd.LSLen = binary.BigEndian.Uint16(data[18:20])
+	end := int(lsa.LsaMd.LSLen)
+	if end < OSPF_LSA_HEADER_SIZE || end > len(data) {
+		return errors.New(fmt.Sprintln("Opaque LSA truncated, length", end, "received", len(data)))
+	}
+	lsa.Data = make([]byte, end-OSPF_LSA_HEADER_SIZE)
+	copy(lsa.Data, data[OSPF_LSA_HEADER_SIZE:end])
+	return nil
+}
+
+func getOpaqueLsaMap(lsDbEnt LSDatabase, lsType uint8) map[LsaKey]OpaqueLsa {
+	if lsType == OpaqueASLSA {
+		return lsDbEnt.OpaqueASLsaMap
+	}
+	return lsDbEnt.OpaqueAreaLsaMap
+}
+
+func (server *OSPFServer) getOpaqueLsaFromLsdb(areaId uint32, lsaKey LsaKey) (lsa OpaqueLsa, retVal int) {
+	lsdbKey := LsdbKey{
+		AreaId: areaId,
+	}
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		return lsa, LsdbEntryNotFound
+	}
+	lsa, exist = getOpaqueLsaMap(lsDbEnt, lsaKey.LSType)[lsaKey]
+	if !exist {
+		return lsa, LsdbEntryNotFound
+	}
+	return lsa, LsdbEntryFound
+}
+
+func (server *OSPFServer) getLinkOpaqueLsa(intfKey IntfConfKey, lsaKey LsaKey) (lsa OpaqueLsa, retVal int) {
+	lsa, exist := server.OpaqueLinkLsaMap[intfKey][lsaKey]
+	if !exist {
+		return lsa, LsdbEntryNotFound
+	}
+	return lsa, LsdbEntryFound
+}
+
+/* AS scope LSAs are not carried in stub areas and NSSAs */
+func (server *OSPFServer) isAsScopeArea(areaId uint32) bool {
+	areaConfId := config.AreaId(convertUint32ToIPv4(areaId))
+	return !server.isStubArea(areaConfId) && !server.isNssaArea(areaConfId)
+}
+
+func (server *OSPFServer) ValidateOpaqueConf(conf config.OpaqueConf) error {
+	if conf.TeEnable && !conf.OpaqueLsaSupport {
+		return errors.New("Traffic engineering needs opaque LSA support")
+	}
+	if conf.TeRouterAddress != "" {
+		ip := net.ParseIP(string(conf.TeRouterAddress))
+		if ip == nil || ip.To4() == nil {
+			return errors.New(fmt.Sprintln("Invalid TE router address", conf.TeRouterAddress))
+		}
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateIfTeConf(conf config.IfTeConf) error {
+	ip := net.ParseIP(string(conf.IfIpAddress))
+	if ip == nil || ip.To4() == nil {
+		return errors.New(fmt.Sprintln("Invalid interface address", conf.IfIpAddress))
+	}
+	if ip.IsUnspecified() && conf.AddressLessIf == 0 {
+		return errors.New("Unnumbered interface needs an interface index")
+	}
+	if conf.MaxBandwidth != 0 && conf.MaxRsvBandwidth > conf.MaxBandwidth {
+		return errors.New(fmt.Sprintln("Max reservable bandwidth", conf.MaxRsvBandwidth,
+			"above max bandwidth", conf.MaxBandwidth))
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateOpaqueLsaConf(conf config.OpaqueLsaConf) error {
+	switch uint8(conf.LsaType) {
+	case OpaqueLinkLSA:
+		ip := net.ParseIP(string(conf.IfIpAddress))
+		if ip == nil || ip.To4() == nil {
+			return errors.New(fmt.Sprintln("Invalid interface address", conf.IfIpAddress))
+		}
+	case OpaqueAreaLSA:
+		if convertAreaOrRouterId(string(conf.AreaId)) == nil {
+			return errors.New(fmt.Sprintln("Invalid area id", conf.AreaId))
+		}
+	case OpaqueASLSA:
+	default:
+		return errors.New(fmt.Sprintln("Invalid opaque LSA type", conf.LsaType))
+	}
+	if conf.OpaqueType == GraceLsaOpaqueType || conf.OpaqueType == TeLsaOpaqueType {
+		return errors.New(fmt.Sprintln("Opaque type", conf.OpaqueType, "is originated by ospfd"))
+	}
+	if conf.OpaqueId > OPAQUE_MAX_ID {
+		return errors.New(fmt.Sprintln("Invalid opaque id", conf.OpaqueId))
+	}
+	if len(conf.Data) > OPAQUE_MAX_DATA_LEN {
+		return errors.New(fmt.Sprintln("Opaque data longer than", OPAQUE_MAX_DATA_LEN, "bytes"))
+	}
+	return nil
+}
+
+func (server *OSPFServer) getSelfOpaqueKey(conf config.OpaqueLsaConf) SelfOpaqueKey {
+	key := SelfOpaqueKey{
+		LSType: uint8(conf.LsaType),
+		LSId:   getOpaqueLsId(conf.OpaqueType, conf.OpaqueId),
+	}
+	switch key.LSType {
+	case OpaqueLinkLSA:
+		key.IntfKey = IntfConfKey{
+			IPAddr:  conf.IfIpAddress,
+			IntfIdx: conf.AddressLessIf,
+		}
+	case OpaqueAreaLSA:
+		key.AreaId = convertAreaOrRouterIdUint32(string(conf.AreaId))
+	}
+	return key
+}
+
+/* @fn OriginateOpaqueLsa
+Api for the other subsystems to originate opaque information.
+The LSA is refreshed by ospfd until it is withdrawn.
+*/
+func (server *OSPFServer) OriginateOpaqueLsa(conf config.OpaqueLsaConf) error {
+	err := server.ValidateOpaqueLsaConf(conf)
+	if err != nil {
+		return err
+	}
+	server.OpaqueConfigCh <- OpaqueConfMsg{Op: true, Lsa: &conf}
+	return nil
+}
+
+func (server *OSPFServer) WithdrawOpaqueLsa(conf config.OpaqueLsaConf) error {
+	err := server.ValidateOpaqueLsaConf(conf)
+	if err != nil {
+		return err
+	}
+	server.OpaqueConfigCh <- OpaqueConfMsg{Op: false, Lsa: &conf}
+	return nil
+}
+
+func (server *OSPFServer) processOpaqueConfig(msg OpaqueConfMsg) error {
+	if msg.Opaque != nil {
+		conf := *msg.Opaque
+		if !msg.Op {
+			conf = config.OpaqueConf{}
+		}
+		err := server.ValidateOpaqueConf(conf)
+		if err != nil {
+			return err
+		}
+		server.OpaqueConf = conf
+		server.ospfGlobalConf.OpaqueLsaSupport = conf.OpaqueLsaSupport
+		if !conf.OpaqueLsaSupport {
+			server.flushSelfOpaqueLsas()
+		}
+		server.refreshTeLsas()
+	}
+	if msg.IfTe != nil {
+		err := server.ValidateIfTeConf(*msg.IfTe)
+		if err != nil {
+			return err
+		}
+		key := IntfConfKey{
+			IPAddr:  msg.IfTe.IfIpAddress,
+			IntfIdx: msg.IfTe.AddressLessIf,
+		}
+		if msg.Op {
+			server.IfTeMap[key] = *msg.IfTe
+		} else {
+			delete(server.IfTeMap, key)
+		}
+		server.refreshTeLsas()
+	}
+	if msg.Lsa != nil {
+		err := server.ValidateOpaqueLsaConf(*msg.Lsa)
+		if err != nil {
+			return err
+		}
+		if !server.ospfGlobalConf.OpaqueLsaSupport {
+			return errors.New("Opaque LSA support is disabled")
+		}
+		key := server.getSelfOpaqueKey(*msg.Lsa)
+		server.updateSelfOpaqueLsa(key, msg.Lsa.Data, !msg.Op)
+	}
+	return nil
+}
+
+/* @fn updateSelfOpaqueLsa
+Originate a new instance of the LSA if its content changed,
+flush withdraws it.
+*/
+func (server *OSPFServer) updateSelfOpaqueLsa(key SelfOpaqueKey, data []byte, flush bool) {
+	old, exist := server.SelfOpaqueLsaMap[key]
+	if flush {
+		if !exist {
+			return
+		}
+		delete(server.SelfOpaqueLsaMap, key)
+		server.originateSelfOpaqueLsa(key, nil, true)
+		return
+	}
+	if exist && string(old) == string(data) {
+		return
+	}
+	server.SelfOpaqueLsaMap[key] = data
+	server.originateSelfOpaqueLsa(key, data, false)
+}
+
+func (server *OSPFServer) flushSelfOpaqueLsas() {
+	for key, _ := range server.SelfOpaqueLsaMap {
+		server.updateSelfOpaqueLsa(key, nil, true)
+	}
+}
+
+/* @fn refreshSelfOpaqueLsas
+Called every LSRefreshTime, new instances of the self originated
+opaque LSAs are flooded.
+*/
+func (server *OSPFServer) refreshSelfOpaqueLsas() {
+	for key, data := range server.SelfOpaqueLsaMap {
+		server.originateSelfOpaqueLsa(key, data, false)
+	}
+}
+
+func (server *OSPFServer) originateSelfOpaqueLsa(key SelfOpaqueKey, data []byte, flush bool) {
+	lsaKey := LsaKey{
+		LSType:    key.LSType,
+		LSId:      key.LSId,
+		AdvRouter: convertIPv4ToUint32(server.ospfGlobalConf.RouterId),
+	}
+	switch key.LSType {
+	case OpaqueLinkLSA:
+		server.originateLinkOpaqueLsa(key.IntfKey, lsaKey, data, flush)
+
+	case OpaqueAreaLSA:
+		lsdbKey := LsdbKey{
+			AreaId: key.AreaId,
+		}
+		installed := server.installSelfOpaqueLsa(lsdbKey, lsaKey, data, flush)
+		if installed && !flush {
+			server.sendLsdbToNeighborEvent(IntfConfKey{}, NeighborConfKey{}, key.AreaId, 0, 0, lsaKey, LSAOPAQUEFLOOD)
+		}
+
+	case OpaqueASLSA:
+		installed := false
+		for lsdbKey, _ := range server.AreaLsdb {
+			if !server.isAsScopeArea(lsdbKey.AreaId) {
+				continue
+			}
+			if server.installSelfOpaqueLsa(lsdbKey, lsaKey, data, flush) {
+				installed = true
+			}
+		}
+		if installed && !flush {
+			server.sendLsdbToNeighborEvent(IntfConfKey{}, NeighborConfKey{}, 0, 0, 0, lsaKey, LSAOPAQUEFLOOD)
+		}
+	}
+}
+
+func buildSelfOpaqueLsa(old OpaqueLsa, exist bool, lsaKey LsaKey, data []byte) OpaqueLsa {
+	var lsa OpaqueLsa
+	lsa.LsaMd.LSSequenceNum = InitialSequenceNumber
+	if exist {
+		lsa.LsaMd.LSSequenceNum = old.LsaMd.LSSequenceNum + 1
+	}
+	lsa.LsaMd.LSAge = 0
+	lsa.LsaMd.Options = INTF_OPTIONS
+	lsa.LsaMd.LSLen = uint16(OSPF_LSA_HEADER_SIZE + len(data))
+	lsa.Data = make([]byte, len(data))
+	copy(lsa.Data, data)
+	lsaEnc := encodeOpaqueLsa(lsa, lsaKey)
+	checksumOffset := uint16(14)
+	lsa.LsaMd.LSChecksum = computeFletcherChecksum(lsaEnc[2:], checksumOffset)
+	return lsa
+}
+
+/* @fn installSelfOpaqueLsa
+A flushed LSA is set to MaxAge, the LSDB ticker floods and removes it.
+*/
+func (server *OSPFServer) installSelfOpaqueLsa(lsdbKey LsdbKey, lsaKey LsaKey, data []byte, flush bool) bool {
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		return false
+	}
+	lsaMap := getOpaqueLsaMap(lsDbEnt, lsaKey.LSType)
+	ent, exist := lsaMap[lsaKey]
+	if flush {
+		if !exist {
+			return false
+		}
+		ent.LsaMd.LSAge = config.MaxAge
+		lsaMap[lsaKey] = ent
+		return true
+	}
+	lsaMap[lsaKey] = buildSelfOpaqueLsa(ent, exist, lsaKey, data)
+	server.logger.Info(fmt.Sprintln("OPAQUE: Originated LSA ", lsaKey, " area ", lsdbKey.AreaId))
+	if !exist {
+		server.addOpaqueLsdbSliceEnt(lsdbKey, lsaKey)
+	}
+	return true
+}
+
+func (server *OSPFServer) addOpaqueLsdbSliceEnt(lsdbKey LsdbKey, lsaKey LsaKey) {
+	var val LsdbSliceEnt
+	val.AreaId = lsdbKey.AreaId
+	val.LSType = lsaKey.LSType
+	val.LSId = lsaKey.LSId
+	val.AdvRtr = lsaKey.AdvRouter
+	server.LsdbSlice = append(server.LsdbSlice, val)
+	msg := DbLsdbMsg{
+		entry: val,
+		op:    true,
+	}
+	server.DbLsdbOp <- msg
+}
+
+/* @fn originateLinkOpaqueLsa
+Link scope LSAs are sent on the interface only.
+*/
+func (server *OSPFServer) originateLinkOpaqueLsa(intfKey IntfConfKey, lsaKey LsaKey, data []byte, flush bool) {
+	lsaMap, exist := server.OpaqueLinkLsaMap[intfKey]
+	if !exist {
+		lsaMap = make(map[LsaKey]OpaqueLsa)
+		server.OpaqueLinkLsaMap[intfKey] = lsaMap
+	}
+	ent, exist := lsaMap[lsaKey]
+	if flush {
+		if !exist {
+			return
+		}
+		ent.LsaMd.LSAge = config.MaxAge
+		delete(lsaMap, lsaKey)
+	} else {
+		ent = buildSelfOpaqueLsa(ent, exist, lsaKey, data)
+		lsaMap[lsaKey] = ent
+	}
+	server.sendLinkOpaqueLsa(intfKey, encodeOpaqueLsa(ent, lsaKey))
+}
+
+func (server *OSPFServer) sendLinkOpaqueLsa(intfKey IntfConfKey, lsaEnc []byte) {
+	ent, exist := server.IntfConfMap[intfKey]
+	if !exist || !server.interfaceFloodCheck(intfKey) {
+		return
+	}
+	lsaUpd := make([]byte, OSPF_NO_OF_LSA_FIELD)
+	binary.BigEndian.PutUint32(lsaUpd, 1)
+	lsaUpd = append(lsaUpd, lsaEnc...)
+	dstMac := net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05}
+	dstIp := net.IP{224, 0, 0, 5}
+	pkt := server.BuildLsaUpdPkt(intfKey, ent, dstMac, dstIp, len(lsaUpd), lsaUpd)
+	err := server.SendOspfPkt(intfKey, pkt)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("OPAQUE: Failed to send link LSA on ", ent.IfIpAddr, err))
+	}
+}
+
+/* @fn processRecvdLinkOpaqueLsa
+Link scope LSAs are not flooded further, the newer instance is kept
+for the interface it was received on.
+*/
+func (server *OSPFServer) processRecvdLinkOpaqueLsa(intfKey IntfConfKey, data []byte) bool {
+	var lsa OpaqueLsa
+	var lsakey LsaKey
+	err := decodeOpaqueLsa(data, &lsa, &lsakey)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("OPAQUE: ", err))
+		return false
+	}
+	if server.selfGenLsaCheck(lsakey) {
+		return false
+	}
+	csum := computeFletcherChecksum(data[2:lsa.LsaMd.LSLen], FLETCHER_CHECKSUM_VALIDATE)
+	if csum != 0 {
+		server.logger.Err("Invalid link opaque LSA Checksum")
+		return false
+	}
+	lsaMap, exist := server.OpaqueLinkLsaMap[intfKey]
+	if !exist {
+		lsaMap = make(map[LsaKey]OpaqueLsa)
+		server.OpaqueLinkLsaMap[intfKey] = lsaMap
+	}
+	ent, exist := lsaMap[lsakey]
+	if exist && ent.LsaMd.LSSequenceNum > lsa.LsaMd.LSSequenceNum {
+		return false
+	}
+	if lsa.LsaMd.LSAge >= config.MaxAge {
+		delete(lsaMap, lsakey)
+		return true
+	}
+	lsaMap[lsakey] = lsa
+	return true
+}
+
+func (server *OSPFServer) installOpaqueLsa(lsdbKey LsdbKey, lsakey LsaKey, lsa OpaqueLsa) bool {
+	lsDbEnt, exist := server.AreaLsdb[lsdbKey]
+	if !exist {
+		return false
+	}
+	lsaMap := getOpaqueLsaMap(lsDbEnt, lsakey.LSType)
+	ent, exist := lsaMap[lsakey]
+	if exist && ent.LsaMd.LSSequenceNum >= lsa.LsaMd.LSSequenceNum &&
+		lsa.LsaMd.LSAge < config.MaxAge {
+		server.logger.Err("Old instance of opaque LSA Recvd")
+		return false
+	}
+	lsaMap[lsakey] = lsa
+	if !exist {
+		server.addOpaqueLsdbSliceEnt(lsdbKey, lsakey)
+	}
+	return true
+}
+
+func (server *OSPFServer) processRecvdOpaqueLsa(data []byte, areaId uint32) bool {
+	var lsa OpaqueLsa
+	var lsakey LsaKey
+	err := decodeOpaqueLsa(data, &lsa, &lsakey)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("OPAQUE: ", err))
+		return false
+	}
+	if server.selfGenLsaCheck(lsakey) && !server.isGraceRestarting() {
+		server.logger.Info("Recvd a self generated opaque LSA")
+		return false
+	}
+
+	//Check Checksum
+	csum := computeFletcherChecksum(data[2:lsa.LsaMd.LSLen], FLETCHER_CHECKSUM_VALIDATE)
+	if csum != 0 {
+		server.logger.Err("Invalid opaque LSA Checksum")
+		return false
+	}
+	if lsakey.LSType == OpaqueAreaLSA {
+		return server.installOpaqueLsa(LsdbKey{AreaId: areaId}, lsakey, lsa)
+	}
+	installed := false
+	for lsdbKey, _ := range server.AreaLsdb {
+		if !server.isAsScopeArea(lsdbKey.AreaId) {
+			continue
+		}
+		if server.installOpaqueLsa(lsdbKey, lsakey, lsa) {
+			installed = true
+		}
+	}
+	return installed
+}
+
+func (server *OSPFServer) processDeleteOpaqueLsa(data []byte, areaId uint32) bool {
+	var lsa OpaqueLsa
+	var lsakey LsaKey
+	err := decodeOpaqueLsa(data, &lsa, &lsakey)
+	if err != nil {
+		return false
+	}
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		if lsdbKey.AreaId != areaId && lsakey.LSType != OpaqueASLSA {
+			continue
+		}
+		lsaMap := getOpaqueLsaMap(lsDbEnt, lsakey.LSType)
+		if _, exist := lsaMap[lsakey]; !exist {
+			continue
+		}
+		delete(lsaMap, lsakey)
+		val := LsdbSliceEnt{
+			AreaId: lsdbKey.AreaId,
+			LSType: lsakey.LSType,
+			LSId:   lsakey.LSId,
+			AdvRtr: lsakey.AdvRouter,
+		}
+		err = server.DelLsdbEntry(val)
+		if err != nil {
+			server.logger.Info(fmt.Sprintln("DB: Failed to delete entry from db ", lsakey))
+		}
+	}
+	return true
+}
+
+func (server *OSPFServer) sanityCheckOpaqueLsa(olsa OpaqueLsa, dolsa OpaqueLsa, lsaKey LsaKey, nbr OspfNeighborEntry, intf IntfConf, exist int, lsa_max_age bool) (discard bool, op uint8) {
+	if !server.ospfGlobalConf.OpaqueLsaSupport {
+		server.logger.Info(fmt.Sprintln("LSAUPD: Opaque LSA Discard. Opaque LSA support is disabled"))
+		return true, LsdbNoAction
+	}
+	if lsaKey.LSType == OpaqueASLSA &&
+		!server.isAsScopeArea(convertIPv4ToUint32(intf.IfAreaId)) {
+		server.logger.Info(fmt.Sprintln("LSAUPD: AS opaque LSA Discard. Area is stub or NSSA ", intf.IfAreaId))
+		return true, LsdbNoAction
+	}
+	send_ack := server.lsAgeCheck(nbr.intfConfKey, lsa_max_age, exist)
+	if send_ack {
+		server.logger.Info(fmt.Sprintln("LSAUPD: Opaque LSA Discard.", " nbr ", nbr))
+		return true, LsdbNoAction
+	}
+	if exist == LsdbEntryFound && !server.validateLsaIsNew(olsa.LsaMd, dolsa.LsaMd) {
+		return true, LsdbNoAction
+	}
+	return false, FloodLsa
+}
+
+/* @fn processOpaqueLSAFlood
+Flood a self originated area or AS scope opaque LSA.
+*/
+func (server *OSPFServer) processOpaqueLSAFlood(areaId uint32, lsaKey LsaKey) {
+	var lsaEnc []byte
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		if lsaKey.LSType == OpaqueAreaLSA && lsdbKey.AreaId != areaId {
+			continue
+		}
+		lsa, exist := getOpaqueLsaMap(lsDbEnt, lsaKey.LSType)[lsaKey]
+		if exist {
+			lsaEnc = encodeOpaqueLsa(lsa, lsaKey)
+			break
+		}
+	}
+	if lsaEnc == nil {
+		server.logger.Info(fmt.Sprintln("OPAQUE: Lsa not found . Area ", areaId, " LSA key ", lsaKey))
+		return
+	}
+	lsaEncPkt := make([]byte, OSPF_NO_OF_LSA_FIELD)
+	binary.BigEndian.PutUint32(lsaEncPkt, 1)
+	lsaEncPkt = append(lsaEncPkt, lsaEnc...)
+
+	dstMac := net.HardwareAddr{0x01, 0x00, 0x5e, 0x00, 0x00, 0x05}
+	dstIp := net.IP{224, 0, 0, 5}
+	for key, intf := range server.IntfConfMap {
+		if !server.isFloodScopeIntf(lsaKey.LSType, areaId, key, intf) ||
+			!server.interfaceFloodCheck(key) {
+			continue
+		}
+		pkt := server.BuildLsaUpdPkt(key, intf, dstMac, dstIp, len(lsaEncPkt), lsaEncPkt)
+		server.logger.Info(fmt.Sprintln("OPAQUE: Send LSA to interface ", intf.IfIpAddr, " lsa ", lsaKey))
+		server.SendOspfPkt(key, pkt)
+	}
+}
+
+/* @fn generateDbOpaqueList
+Opaque LSAs described to the neighbor during the database exchange,
+only if the neighbor set the O-bit in its DD options.
+*/
+func (server *OSPFServer) generateDbOpaqueList(areaId uint32, intfKey IntfConfKey, nbrOptions uint8) []*ospfNeighborDBSummary {
+	if !server.ospfGlobalConf.OpaqueLsaSupport || nbrOptions&OOption == 0 {
+		return nil
+	}
+	db_list := []*ospfNeighborDBSummary{}
+	addLsa := func(lsaKey LsaKey, lsa OpaqueLsa) {
+		db_opaque := newospfNeighborDBSummary()
+		db_opaque.lsa_headers = getLsaHeaderFromLsa(lsa.LsaMd.LSAge, lsa.LsaMd.Options,
+			lsaKey.LSType, lsaKey.LSId, lsaKey.AdvRouter,
+			uint32(lsa.LsaMd.LSSequenceNum), lsa.LsaMd.LSChecksum,
+			lsa.LsaMd.LSLen)
+		db_opaque.valid = true
+		db_list = append(db_list, db_opaque)
+	}
+	for lsaKey, lsa := range server.OpaqueLinkLsaMap[intfKey] {
+		addLsa(lsaKey, lsa)
+	}
+	lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+	if !exist {
+		return db_list
+	}
+	for lsaKey, lsa := range lsDbEnt.OpaqueAreaLsaMap {
+		addLsa(lsaKey, lsa)
+	}
+	if !isVirtualIntfKey(intfKey) && server.isAsScopeArea(areaId) {
+		for lsaKey, lsa := range lsDbEnt.OpaqueASLsaMap {
+			addLsa(lsaKey, lsa)
+		}
+	}
+	return db_list
+}
+
+/* @fn intfHasOpaqueNbr
+RFC 5250 3.1 opaque LSAs are flooded only to opaque capable neighbors.
+Returns true if a neighbor on the interface in Exchange or above
+advertised the O-bit.
+*/
+func (server *OSPFServer) intfHasOpaqueNbr(intfKey IntfConfKey) bool {
+	for _, nbr := range server.NeighborConfigMap {
+		if nbr.intfConfKey == intfKey &&
+			nbr.OspfNbrState >= config.NbrExchange &&
+			uint8(nbr.OspfNbrOptions)&OOption != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+/* @fn processMaxAgeOpaqueLsa
+Returns true if an LSA reached MaxAge and has to be flooded.
+*/
+func (server *OSPFServer) processMaxAgeOpaqueLsa(lsaMap map[LsaKey]OpaqueLsa) bool {
+	flood_lsa := false
+	for lsakey, lsa := range lsaMap {
+		if lsa.LsaMd.LSAge >= config.MaxAge {
+			maxAgeLsaMap[lsakey] = encodeOpaqueLsa(lsa, lsakey)
+			delete(lsaMap, lsakey)
+			server.logger.Info(fmt.Sprintln("DELETE: Max age reached. adv_router ",
+				convertUint32ToIPv4(lsakey.AdvRouter), " lstype ", lsakey.LSType,
+				" lsid ", convertUint32ToIPv4(lsakey.LSId)))
+			flood_lsa = true
+		} else {
+			lsa.LsaMd.LSAge++
+			lsaMap[lsakey] = lsa
+		}
+	}
+	return flood_lsa
+}
+
+/* Link scope LSAs age out on their own, the neighbors on the link age them too */
+func (server *OSPFServer) processMaxAgeLinkOpaqueLsa() {
+	for intfKey, lsaMap := range server.OpaqueLinkLsaMap {
+		if _, exist := server.IntfConfMap[intfKey]; !exist {
+			delete(server.OpaqueLinkLsaMap, intfKey)
+			continue
+		}
+		for lsakey, lsa := range lsaMap {
+			if lsa.LsaMd.LSAge >= config.MaxAge {
+				delete(lsaMap, lsakey)
+			} else {
+				lsa.LsaMd.LSAge++
+				lsaMap[lsakey] = lsa
+			}
+		}
+	}
+}
@@ -0,0 +1,293 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfOpaque_test
+   This test covers
+   1) Opaque LSA encode and decode.
+   2) Opaque LSA and TE configuration validation.
+   3) Origination, refresh and withdrawal of opaque LSAs.
+   4) TE TLV encode and decode.
+   5) TE LSAs built from the interface state.
+   6) TE LSAs flushed when TE is disabled.
+   7) O-bit negotiation with the neighbors.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"testing"
+)
+
+var opaqueAreaId uint32
+var opaqueLsaConf config.OpaqueLsaConf
+
+func initOpaqueTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+	maxAgeLsaMap = make(map[LsaKey][]byte)
+
+	opaqueAreaId = convertIPv4ToUint32(intf.IfAreaId)
+	ospf.initLSDatabase(opaqueAreaId)
+	teIntf := intf
+	teIntf.IfFSMState = config.OtherDesignatedRouter
+	teIntf.IfCost = 10
+	ospf.IntfConfMap[key] = teIntf
+	ospf.portPropertyMap[1] = PortProperty{Name: "fpPort1", Speed: 1000}
+
+	opaqueLsaConf = config.OpaqueLsaConf{
+		LsaType:    config.AreaOpaqueLink,
+		OpaqueType: 200,
+		OpaqueId:   1,
+		AreaId:     config.AreaId(convertUint32ToIPv4(opaqueAreaId)),
+		Data:       []byte{1, 2, 3, 4},
+	}
+}
+
+func getTestOpaqueLsa() (OpaqueLsa, bool) {
+	lsaKey := LsaKey{
+		LSType:    OpaqueAreaLSA,
+		LSId:      getOpaqueLsId(opaqueLsaConf.OpaqueType, opaqueLsaConf.OpaqueId),
+		AdvRouter: convertIPv4ToUint32(ospf.ospfGlobalConf.RouterId),
+	}
+	lsa, ret := ospf.getOpaqueLsaFromLsdb(opaqueAreaId, lsaKey)
+	return lsa, ret == LsdbEntryFound
+}
+
+func TestOspfOpaque(t *testing.T) {
+	fmt.Println("\n**************** OPAQUE LSA ************\n")
+	initOpaqueTestParams()
+	for index := 1; index < 8; index++ {
+		err := opaqueTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for opaque LSA ", index)
+		}
+	}
+}
+
+func opaqueTestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running encodeOpaqueLsa/decodeOpaqueLsa")
+		lsaKey := LsaKey{
+			LSType:    OpaqueASLSA,
+			LSId:      getOpaqueLsId(200, 5),
+			AdvRouter: 20,
+		}
+		lsa := buildSelfOpaqueLsa(OpaqueLsa{}, false, lsaKey, []byte{1, 2, 3, 4, 5, 6, 7, 8})
+		data := encodeOpaqueLsa(lsa, lsaKey)
+		var dlsa OpaqueLsa
+		var dlsaKey LsaKey
+		err := decodeOpaqueLsa(data, &dlsa, &dlsaKey)
+		if err != nil || dlsaKey != lsaKey || len(dlsa.Data) != 8 ||
+			dlsa.LsaMd.LSLen != uint16(len(data)) {
+			fmt.Println("Opaque LSA decode mismatch ", dlsaKey, dlsa, err)
+			return FAIL
+		}
+		if getOpaqueType(dlsaKey.LSId) != 200 || dlsaKey.LSId&OPAQUE_MAX_ID != 5 {
+			fmt.Println("Invalid opaque type/id ", dlsaKey.LSId)
+			return FAIL
+		}
+		if decodeOpaqueLsa(data[:OSPF_LSA_HEADER_SIZE+4], &dlsa, &dlsaKey) == nil {
+			fmt.Println("Truncated opaque LSA accepted")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running ValidateOpaqueLsaConf/ValidateIfTeConf")
+		conf := opaqueLsaConf
+		conf.OpaqueType = TeLsaOpaqueType
+		if ospf.ValidateOpaqueLsaConf(conf) == nil {
+			fmt.Println("TE opaque type accepted from other subsystems")
+			return FAIL
+		}
+		conf = opaqueLsaConf
+		conf.LsaType = config.AsExternalLink
+		if ospf.ValidateOpaqueLsaConf(conf) == nil {
+			fmt.Println("Non opaque LSA type accepted")
+			return FAIL
+		}
+		conf = opaqueLsaConf
+		conf.Data = make([]byte, OPAQUE_MAX_DATA_LEN+1)
+		if ospf.ValidateOpaqueLsaConf(conf) == nil {
+			fmt.Println("Oversized opaque data accepted")
+			return FAIL
+		}
+		teConf := config.IfTeConf{
+			IfIpAddress:     "10.1.1.2",
+			MaxBandwidth:    100,
+			MaxRsvBandwidth: 200,
+		}
+		if ospf.ValidateIfTeConf(teConf) == nil {
+			fmt.Println("Reservable bandwidth above max bandwidth accepted")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running opaque LSA origination")
+		if ospf.processOpaqueConfig(OpaqueConfMsg{Op: true, Lsa: &opaqueLsaConf}) == nil {
+			fmt.Println("Opaque LSA originated with opaque support disabled")
+			return FAIL
+		}
+		opaque := config.OpaqueConf{OpaqueLsaSupport: true}
+		ospf.processOpaqueConfig(OpaqueConfMsg{Op: true, Opaque: &opaque})
+		err := ospf.processOpaqueConfig(OpaqueConfMsg{Op: true, Lsa: &opaqueLsaConf})
+		if err != nil {
+			fmt.Println("Opaque LSA origination failed ", err)
+			return FAIL
+		}
+		lsa, exist := getTestOpaqueLsa()
+		if !exist || lsa.LsaMd.LSSequenceNum != InitialSequenceNumber {
+			fmt.Println("Opaque LSA not originated ", lsa)
+			return FAIL
+		}
+		ospf.refreshSelfOpaqueLsas()
+		lsa, exist = getTestOpaqueLsa()
+		if !exist || lsa.LsaMd.LSSequenceNum != InitialSequenceNumber+1 {
+			fmt.Println("Opaque LSA not refreshed ", lsa)
+			return FAIL
+		}
+		ospf.processOpaqueConfig(OpaqueConfMsg{Op: false, Lsa: &opaqueLsaConf})
+		lsa, exist = getTestOpaqueLsa()
+		if !exist || lsa.LsaMd.LSAge != config.MaxAge {
+			fmt.Println("Opaque LSA not flushed ", lsa)
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running TE TLV encode/decode")
+		link := TeLink{
+			LinkType:        TE_LINK_P2P,
+			LinkId:          20,
+			LocalAddr:       []uint32{0x0a010101},
+			RemoteAddr:      []uint32{0x0a010102},
+			TeMetric:        30,
+			MaxBandwidth:    125000000,
+			MaxRsvBandwidth: 62500000,
+			AdminGroup:      5,
+		}
+		link.UnrsvBandwidth[7] = 1000
+		var teLsa TeLsa
+		err := decodeTeLsa(encodeTeLinkTlv(link), &teLsa)
+		if err != nil || !teLsa.HasLink || teLsa.HasRouterAddr ||
+			teLsa.Link.LinkId != 20 || teLsa.Link.TeMetric != 30 ||
+			teLsa.Link.MaxRsvBandwidth != 62500000 || teLsa.Link.UnrsvBandwidth[7] != 1000 ||
+			teLsa.Link.AdminGroup != 5 || len(teLsa.Link.RemoteAddr) != 1 {
+			fmt.Println("TE link TLV decode mismatch ", teLsa, err)
+			return FAIL
+		}
+		teLsa = TeLsa{}
+		err = decodeTeLsa(encodeTeRouterAddrTlv(0x14000101), &teLsa)
+		if err != nil || !teLsa.HasRouterAddr || teLsa.RouterAddr != 0x14000101 {
+			fmt.Println("TE router address TLV decode mismatch ", teLsa, err)
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running refreshTeLsas")
+		opaque := config.OpaqueConf{OpaqueLsaSupport: true, TeEnable: true}
+		ospf.processOpaqueConfig(OpaqueConfMsg{Op: true, Opaque: &opaque})
+		teConf := config.IfTeConf{
+			IfIpAddress:   key.IPAddr,
+			AddressLessIf: key.IntfIdx,
+			TeMetric:      20,
+			AdminGroup:    3,
+		}
+		ospf.processOpaqueConfig(OpaqueConfMsg{Op: true, IfTe: &teConf})
+		links := ospf.getTeLinkStates()
+		if len(links) != 1 {
+			fmt.Println("Unexpected TE database ", links)
+			return FAIL
+		}
+		link := links[0]
+		if link.LinkType != TE_LINK_MULTIACCESS || link.LinkId != "10.1.1.2" ||
+			link.TeMetric != 20 || link.AdminGroup != 3 ||
+			link.MaxBandwidth != 1000*TE_MBPS_TO_BYTES_PER_SEC ||
+			link.UnrsvBandwidth[0] != link.MaxRsvBandwidth ||
+			link.RouterAddress != config.IpAddress(gConf.RouterId) {
+			fmt.Println("Unexpected TE link ", link)
+			return FAIL
+		}
+
+	case 6:
+		fmt.Println(tNum, ": Running TE disable")
+		opaque := config.OpaqueConf{OpaqueLsaSupport: true}
+		ospf.processOpaqueConfig(OpaqueConfMsg{Op: true, Opaque: &opaque})
+		if len(ospf.getTeLinkStates()) != 0 {
+			fmt.Println("TE links left after TE disable ", ospf.getTeLinkStates())
+			return FAIL
+		}
+		for selfKey, _ := range ospf.SelfOpaqueLsaMap {
+			if getOpaqueType(selfKey.LSId) == TeLsaOpaqueType {
+				fmt.Println("TE LSA not withdrawn ", selfKey)
+				return FAIL
+			}
+		}
+
+	case 7:
+		fmt.Println(tNum, ": Running O-bit negotiation")
+		areaId := config.AreaId(convertUint32ToIPv4(opaqueAreaId))
+		if ospf.getPktOptions(areaId)&OOption == 0 {
+			fmt.Println("O-bit not advertised with opaque support enabled")
+			return FAIL
+		}
+		if ospf.generateDbOpaqueList(opaqueAreaId, key, EOption) != nil {
+			fmt.Println("Opaque LSAs described to a neighbor without the O-bit")
+			return FAIL
+		}
+		if len(ospf.generateDbOpaqueList(opaqueAreaId, key, EOption|OOption)) == 0 {
+			fmt.Println("Opaque LSAs not described to an opaque capable neighbor")
+			return FAIL
+		}
+		ifEnt := ospf.IntfConfMap[key]
+		opaqueNbrKey := NeighborConfKey{IPAddr: config.IpAddress("10.1.1.9"), IntfIdx: key.IntfIdx}
+		ospf.NeighborConfigMap[opaqueNbrKey] = OspfNeighborEntry{
+			intfConfKey:    key,
+			OspfNbrState:   config.NbrFull,
+			OspfNbrOptions: EOption,
+		}
+		if ospf.isFloodScopeIntf(OpaqueAreaLSA, opaqueAreaId, key, ifEnt) {
+			fmt.Println("Opaque LSA flooded to a neighbor without the O-bit")
+			return FAIL
+		}
+		ospf.NeighborConfigMap[opaqueNbrKey] = OspfNeighborEntry{
+			intfConfKey:    key,
+			OspfNbrState:   config.NbrFull,
+			OspfNbrOptions: EOption | OOption,
+		}
+		if !ospf.isFloodScopeIntf(OpaqueAreaLSA, opaqueAreaId, key, ifEnt) {
+			fmt.Println("Opaque LSA not flooded to an opaque capable neighbor")
+			return FAIL
+		}
+		delete(ospf.NeighborConfigMap, opaqueNbrKey)
+		ospf.processOpaqueConfig(OpaqueConfMsg{Op: false, Opaque: &config.OpaqueConf{}})
+		if ospf.getPktOptions(areaId)&OOption != 0 {
+			fmt.Println("O-bit advertised with opaque support disabled")
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
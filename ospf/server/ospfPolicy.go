@@ -0,0 +1,485 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"ribdInt"
+	"sort"
+	"strings"
+)
+
+/* Prefix and interface policy.
+   Route maps are lists of match / set entries evaluated in sequence
+   order, a route which no entry matches is denied.
+   The redistribution route map is applied to the routes ribd hands over
+   for redistribution. The routes it denies are not advertised, the ones
+   it permits get their metric, metric type and tag from the entry.
+   Summary addresses covering them keep an E2 metric.
+   The distribute-list route map is applied when the OSPF routes are
+   installed in ribd. The routes it denies stay in the LSDB and the OSPF
+   routing table, they are only kept out of ribd.
+   Passive interfaces and prefix suppression change what is advertised
+   for the interfaces.
+*/
+
+const (
+	DISTRIBUTE_LIST_PROTOCOL = "OSPF"
+)
+
+/*
+   Op is true for create/update and false for delete. Only one of
+   the config pointers is set.
+*/
+type PolicyConfMsg struct {
+	Op       bool
+	Policy   *config.PolicyConf
+	RouteMap *config.RouteMapConf
+	IfPolicy *config.IfPolicyConf
+}
+
+/* Route received from ribd and the route redistributed for it */
+type RedistRouteEnt struct {
+	Route     ribdInt.Routes
+	Permitted bool
+	Mdata     RouteMdata
+}
+
+type policyRoute struct {
+	net      uint32
+	mask     uint32
+	tag      uint32
+	protocol string
+}
+
+func (server *OSPFServer) initPolicy() {
+	server.PolicyConfigCh = make(chan PolicyConfMsg)
+	server.RouteMapTbl = make(map[string][]config.RouteMapConf)
+	server.IfPolicyMap = make(map[IntfConfKey]config.IfPolicyConf)
+	server.RedistRouteMap = make(map[AggrPrefixKey]RedistRouteEnt)
+	server.DistListFilteredMap = make(map[RoutingTblEntryKey]bool)
+}
+
+func validatePolicyPrefix(prefix config.IpAddress, mask config.IpAddress) error {
+	netIp := net.ParseIP(string(prefix))
+	maskIp := net.ParseIP(string(mask))
+	if netIp == nil || netIp.To4() == nil || maskIp == nil || maskIp.To4() == nil {
+		return errors.New(fmt.Sprintln("Invalid match prefix", prefix, mask))
+	}
+	netVal := convertAreaOrRouterIdUint32(string(prefix))
+	maskVal := convertAreaOrRouterIdUint32(string(mask))
+	if ^maskVal&(^maskVal+1) != 0 {
+		return errors.New(fmt.Sprintln("Invalid match mask", mask))
+	}
+	if netVal&maskVal != netVal {
+		return errors.New(fmt.Sprintln("Match prefix", prefix, "has host bits set for mask", mask))
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateRouteMapConf(conf config.RouteMapConf) error {
+	if conf.Name == "" {
+		return errors.New("Route map needs a name")
+	}
+	if conf.Sequence <= 0 {
+		return errors.New(fmt.Sprintln("Invalid route map sequence", conf.Sequence))
+	}
+	if conf.Action != config.RouteMapPermit && conf.Action != config.RouteMapDeny {
+		return errors.New(fmt.Sprintln("Invalid route map action", conf.Action))
+	}
+	if conf.MatchPrefix != "" || conf.MatchMask != "" {
+		err := validatePolicyPrefix(conf.MatchPrefix, conf.MatchMask)
+		if err != nil {
+			return err
+		}
+	} else if conf.MatchExact {
+		return errors.New("Exact match needs a match prefix")
+	}
+	if conf.SetMetric >= LSInfinity {
+		return errors.New(fmt.Sprintln("Invalid route map metric", conf.SetMetric))
+	}
+	if conf.SetMetricType < config.ExtMetricTypeNone || conf.SetMetricType > config.ExtMetricType2 {
+		return errors.New(fmt.Sprintln("Invalid route map metric type", conf.SetMetricType))
+	}
+	return nil
+}
+
+func (server *OSPFServer) ValidateIfPolicyConf(conf config.IfPolicyConf) error {
+	ip := net.ParseIP(string(conf.IfIpAddress))
+	if ip == nil || ip.To4() == nil {
+		return errors.New(fmt.Sprintln("Invalid interface address", conf.IfIpAddress))
+	}
+	if ip.IsUnspecified() && conf.AddressLessIf == 0 {
+		return errors.New("Unnumbered interface needs an interface index")
+	}
+	return nil
+}
+
+func (server *OSPFServer) processPolicyConfig(msg PolicyConfMsg) error {
+	switch {
+	case msg.Policy != nil:
+		conf := config.PolicyConf{}
+		if msg.Op {
+			conf = *msg.Policy
+		}
+		server.PolicyMutex.Lock()
+		old := server.PolicyConf
+		server.PolicyConf = conf
+		server.PolicyMutex.Unlock()
+		if old.RedistRouteMap != conf.RedistRouteMap {
+			server.refreshRedistRoutes()
+		}
+		if old.DistributeList != conf.DistributeList {
+			server.scheduleSpf(SpfRequest{AllAreas: true, Trigger: "Distribute list"})
+		}
+	case msg.RouteMap != nil:
+		if msg.Op {
+			err := server.ValidateRouteMapConf(*msg.RouteMap)
+			if err != nil {
+				return err
+			}
+		}
+		server.updateRouteMap(*msg.RouteMap, msg.Op)
+		policy := server.getPolicyConf()
+		if msg.RouteMap.Name == policy.RedistRouteMap {
+			server.refreshRedistRoutes()
+		}
+		if msg.RouteMap.Name == policy.DistributeList {
+			server.scheduleSpf(SpfRequest{AllAreas: true, Trigger: "Distribute list"})
+		}
+	case msg.IfPolicy != nil:
+		err := server.ValidateIfPolicyConf(*msg.IfPolicy)
+		if err != nil {
+			return err
+		}
+		key := IntfConfKey{
+			IPAddr:  msg.IfPolicy.IfIpAddress,
+			IntfIdx: msg.IfPolicy.AddressLessIf,
+		}
+		conf := config.IfPolicyConf{}
+		server.PolicyMutex.Lock()
+		old := server.IfPolicyMap[key]
+		if msg.Op {
+			conf = *msg.IfPolicy
+			server.IfPolicyMap[key] = conf
+		} else {
+			delete(server.IfPolicyMap, key)
+		}
+		server.PolicyMutex.Unlock()
+		server.processIfPolicyChange(key, old, conf)
+	default:
+		return errors.New("Empty policy configuration")
+	}
+	return nil
+}
+
+/* @fn updateRouteMap
+Adds, replaces or removes the entry with the sequence of conf.
+A route map without entries is removed.
+*/
+func (server *OSPFServer) updateRouteMap(conf config.RouteMapConf, op bool) {
+	server.PolicyMutex.Lock()
+	defer server.PolicyMutex.Unlock()
+	entries := []config.RouteMapConf{}
+	for _, ent := range server.RouteMapTbl[conf.Name] {
+		if ent.Sequence != conf.Sequence {
+			entries = append(entries, ent)
+		}
+	}
+	if op {
+		entries = append(entries, conf)
+	}
+	if len(entries) == 0 {
+		delete(server.RouteMapTbl, conf.Name)
+		return
+	}
+	sort.Sort(routeMapEntryList(entries))
+	server.RouteMapTbl[conf.Name] = entries
+}
+
+type routeMapEntryList []config.RouteMapConf
+
+func (l routeMapEntryList) Len() int           { return len(l) }
+func (l routeMapEntryList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l routeMapEntryList) Less(i, j int) bool { return l[i].Sequence < l[j].Sequence }
+
+/* @fn processIfPolicyChange
+Neighbors of an interface turned passive are brought down, the
+router and network LSAs are re-originated when the prefix
+suppression changed.
+*/
+func (server *OSPFServer) processIfPolicyChange(key IntfConfKey, old config.IfPolicyConf,
+	conf config.IfPolicyConf) {
+	ent, exist := server.IntfConfMap[key]
+	if !exist {
+		return
+	}
+	if conf.Passive && !old.Passive {
+		for nbrKey, nbrConf := range server.NeighborConfigMap {
+			if nbrConf.intfConfKey == key {
+				server.processNbrDown(nbrKey, "Passive interface ")
+			}
+		}
+	}
+	if conf.SuppressPrefix != old.SuppressPrefix {
+		msg := DrChangeMsg{
+			areaId:   convertIPv4ToUint32(ent.IfAreaId),
+			intfKey:  key,
+			oldstate: ent.IfFSMState,
+			newstate: ent.IfFSMState,
+		}
+		server.NetworkDRChangeCh <- msg
+	}
+}
+
+func (server *OSPFServer) getPolicyConf() config.PolicyConf {
+	server.PolicyMutex.RLock()
+	defer server.PolicyMutex.RUnlock()
+	return server.PolicyConf
+}
+
+func (server *OSPFServer) isPassiveIntf(key IntfConfKey) bool {
+	server.PolicyMutex.RLock()
+	defer server.PolicyMutex.RUnlock()
+	return server.IfPolicyMap[key].Passive
+}
+
+func (server *OSPFServer) isPrefixSuppressed(key IntfConfKey) bool {
+	server.PolicyMutex.RLock()
+	defer server.PolicyMutex.RUnlock()
+	return server.IfPolicyMap[key].SuppressPrefix
+}
+
+/* @fn matchRouteMapEnt
+A prefix match is met by the routes within the prefix,
+the route mask has to be at least as long as the match mask.
+*/
+func matchRouteMapEnt(ent config.RouteMapConf, route policyRoute) bool {
+	if ent.MatchPrefix != "" {
+		prefix := convertAreaOrRouterIdUint32(string(ent.MatchPrefix))
+		mask := convertAreaOrRouterIdUint32(string(ent.MatchMask))
+		if ent.MatchExact {
+			if route.net != prefix || route.mask != mask {
+				return false
+			}
+		} else if route.mask&mask != mask || route.net&mask != prefix {
+			return false
+		}
+	}
+	if ent.MatchTag != 0 && ent.MatchTag != route.tag {
+		return false
+	}
+	if ent.MatchProtocol != "" && !strings.EqualFold(ent.MatchProtocol, route.protocol) {
+		return false
+	}
+	return true
+}
+
+/* @fn evalRouteMap
+Returns the first entry of the route map matching the route and
+whether the route is permitted.
+*/
+func (server *OSPFServer) evalRouteMap(name string, route policyRoute) (config.RouteMapConf, bool) {
+	server.PolicyMutex.RLock()
+	defer server.PolicyMutex.RUnlock()
+	for _, ent := range server.RouteMapTbl[name] {
+		if matchRouteMapEnt(ent, route) {
+			return ent, ent.Action == config.RouteMapPermit
+		}
+	}
+	return config.RouteMapConf{}, false
+}
+
+/* @fn applyRedistRouteMap
+Applies the set actions of the matching entry to the route.
+Everything is redistributed without a route map.
+*/
+func (server *OSPFServer) applyRedistRouteMap(route ribdInt.Routes, mdata *RouteMdata) bool {
+	name := server.getPolicyConf().RedistRouteMap
+	if name == "" {
+		return true
+	}
+	pRoute := policyRoute{
+		net:      mdata.ipaddr & mdata.mask,
+		mask:     mdata.mask,
+		tag:      mdata.tag,
+		protocol: route.RoutePrototypeString,
+	}
+	ent, permit := server.evalRouteMap(name, pRoute)
+	if !permit {
+		return false
+	}
+	if ent.SetMetric != 0 {
+		mdata.metric = ent.SetMetric
+	}
+	switch ent.SetMetricType {
+	case config.ExtMetricType1:
+		mdata.type1 = true
+	case config.ExtMetricType2:
+		mdata.type1 = false
+	}
+	if ent.SetTag != 0 {
+		mdata.tag = ent.SetTag
+	}
+	return true
+}
+
+/* @fn getRedistRouteUpd
+Returns the update for the LSDB when the redistribution of a route
+changes from old to ent. Only previously permitted routes are
+withdrawn.
+*/
+func getRedistRouteUpd(old RedistRouteEnt, exist bool, ent RedistRouteEnt, isDel bool) (RouteMdata, bool) {
+	wasPermitted := exist && old.Permitted
+	if !isDel && ent.Permitted {
+		return ent.Mdata, true
+	}
+	if !wasPermitted {
+		return RouteMdata{}, false
+	}
+	upd := old.Mdata
+	upd.isDel = true
+	return upd, true
+}
+
+/* @fn redistributeRibdRoute
+Records the ribd route and applies the redistribution route map.
+Returns the route to send to the LSDB, if any.
+*/
+func (server *OSPFServer) redistributeRibdRoute(route ribdInt.Routes, mdata RouteMdata) (RouteMdata, bool) {
+	rKey := AggrPrefixKey{
+		Net:  mdata.ipaddr & mdata.mask,
+		Mask: mdata.mask,
+	}
+	ent := RedistRouteEnt{
+		Route: route,
+		Mdata: mdata,
+	}
+	if !mdata.isDel {
+		ent.Permitted = server.applyRedistRouteMap(route, &ent.Mdata)
+	}
+	server.PolicyMutex.Lock()
+	old, exist := server.RedistRouteMap[rKey]
+	if mdata.isDel {
+		delete(server.RedistRouteMap, rKey)
+	} else {
+		server.RedistRouteMap[rKey] = ent
+	}
+	server.PolicyMutex.Unlock()
+	return getRedistRouteUpd(old, exist, ent, mdata.isDel)
+}
+
+/* @fn refreshRedistRoutes
+Re-applies the redistribution route map to the ribd routes,
+the routes whose result changed are originated or withdrawn.
+*/
+func (server *OSPFServer) refreshRedistRoutes() {
+	server.PolicyMutex.RLock()
+	routes := make([]RedistRouteEnt, 0, len(server.RedistRouteMap))
+	for _, ent := range server.RedistRouteMap {
+		routes = append(routes, ent)
+	}
+	server.PolicyMutex.RUnlock()
+	for _, old := range routes {
+		mdata := old.Mdata
+		route := RouteMdata{
+			metric: uint32(old.Route.Metric),
+			ipaddr: mdata.ipaddr,
+			mask:   mdata.mask,
+			tag:    uint32(old.Route.RouteTag),
+		}
+		upd, send := server.redistributeRibdRoute(old.Route, route)
+		if !send {
+			continue
+		}
+		if old.Permitted && !upd.isDel && old.Mdata == upd {
+			continue
+		}
+		server.logger.Info(fmt.Sprintln("ASBR: Route map changed redistribution of ", old.Route.Ipaddr,
+			old.Route.Mask, " withdraw ", upd.isDel))
+		server.ExternalRouteNotif <- upd
+	}
+}
+
+/* @fn isDistListPermitted
+OSPF routes are matched as protocol OSPF with the tag of the
+external route. Everything is installed without a distribute-list.
+*/
+func (server *OSPFServer) isDistListPermitted(rKey RoutingTblEntryKey, rEnt RoutingTblEntry) bool {
+	name := server.getPolicyConf().DistributeList
+	if name == "" {
+		return true
+	}
+	pRoute := policyRoute{
+		net:      rKey.DestId & rKey.AddrMask,
+		mask:     rKey.AddrMask,
+		tag:      rEnt.RouteTag,
+		protocol: DISTRIBUTE_LIST_PROTOCOL,
+	}
+	_, permit := server.evalRouteMap(name, pRoute)
+	return permit
+}
+
+func (server *OSPFServer) GetBulkOspfRedistRouteState(idx int, cnt int) (int, int, []config.RedistRouteState) {
+	server.PolicyMutex.RLock()
+	defer server.PolicyMutex.RUnlock()
+	keys := []AggrPrefixKey{}
+	for key, _ := range server.RedistRouteMap {
+		keys = append(keys, key)
+	}
+	sort.Sort(aggrPrefixKeyList(keys))
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(keys))
+	result := make([]config.RedistRouteState, count)
+	for i := 0; i < count; i++ {
+		ent := server.RedistRouteMap[keys[idx+i]]
+		result[i].DestIp = convertUint32ToIPv4(keys[idx+i].Net)
+		result[i].Mask = convertUint32ToIPv4(keys[idx+i].Mask)
+		result[i].Protocol = ent.Route.RoutePrototypeString
+		result[i].Permitted = ent.Permitted
+		if !ent.Permitted {
+			continue
+		}
+		result[i].Metric = ent.Mdata.metric
+		result[i].MetricType = config.ExtMetricType2
+		if ent.Mdata.type1 {
+			result[i].MetricType = config.ExtMetricType1
+		}
+		result[i].Tag = ent.Mdata.tag
+	}
+	return nextIdx, count, result
+}
+
+type aggrPrefixKeyList []AggrPrefixKey
+
+func (l aggrPrefixKeyList) Len() int      { return len(l) }
+func (l aggrPrefixKeyList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l aggrPrefixKeyList) Less(i, j int) bool {
+	if l[i].Net != l[j].Net {
+		return l[i].Net < l[j].Net
+	}
+	return l[i].Mask < l[j].Mask
+}
@@ -0,0 +1,309 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfPolicy_test
+   This test covers
+   1) Route map validation and entry order.
+   2) Route map matching of prefix, tag and protocol.
+   3) Redistribution route map, set actions and withdrawal.
+   4) Distribute-list keeping routes out of ribd.
+   5) Passive interfaces.
+   6) Prefix suppression and the redistributed routes GetBulk API.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"ribdInt"
+	"testing"
+)
+
+var policyRoute1 ribdInt.Routes
+var policyRoute2 ribdInt.Routes
+
+func initPolicyTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+
+	ospf.IntfConfMap[key] = intf
+	ospf.NeighborConfigMap[nbrKey] = nbrConf
+	policyRoute1 = ribdInt.Routes{
+		Ipaddr:               "40.1.1.0",
+		Mask:                 "255.255.255.0",
+		Metric:               10,
+		RoutePrototypeString: "BGP",
+		RouteTag:             100,
+	}
+	policyRoute2 = ribdInt.Routes{
+		Ipaddr:               "50.1.0.0",
+		Mask:                 "255.255.0.0",
+		Metric:               20,
+		RoutePrototypeString: "STATIC",
+	}
+}
+
+func getPolicyRouteMdata(route ribdInt.Routes, isDel bool) RouteMdata {
+	ipaddr := convertAreaOrRouterIdUint32(route.Ipaddr)
+	mask := convertAreaOrRouterIdUint32(route.Mask)
+	return RouteMdata{
+		ipaddr: ipaddr,
+		mask:   mask,
+		metric: uint32(route.Metric),
+		tag:    uint32(route.RouteTag),
+		isDel:  isDel,
+	}
+}
+
+func TestOspfPolicy(t *testing.T) {
+	fmt.Println("\n**************** PREFIX AND INTERFACE POLICY ************\n")
+	initPolicyTestParams()
+	for index := 1; index < 7; index++ {
+		err := policyTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for policy ", index)
+		}
+	}
+}
+
+func policyTestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running ValidateRouteMapConf/updateRouteMap")
+		invalid := []config.RouteMapConf{
+			{Name: "", Sequence: 10, Action: config.RouteMapPermit},
+			{Name: "RM", Sequence: 0, Action: config.RouteMapPermit},
+			{Name: "RM", Sequence: 10, Action: 3},
+			{Name: "RM", Sequence: 10, Action: config.RouteMapPermit, MatchPrefix: "40.1.1.1", MatchMask: "255.255.255.0"},
+			{Name: "RM", Sequence: 10, Action: config.RouteMapPermit, MatchPrefix: "40.1.1.0", MatchMask: "255.0.255.0"},
+			{Name: "RM", Sequence: 10, Action: config.RouteMapPermit, MatchExact: true},
+			{Name: "RM", Sequence: 10, Action: config.RouteMapPermit, SetMetricType: 3},
+		}
+		for _, conf := range invalid {
+			if ospf.ValidateRouteMapConf(conf) == nil {
+				fmt.Println("Invalid route map accepted ", conf)
+				return FAIL
+			}
+		}
+		ospf.updateRouteMap(config.RouteMapConf{Name: "RM", Sequence: 20, Action: config.RouteMapDeny}, true)
+		ospf.updateRouteMap(config.RouteMapConf{Name: "RM", Sequence: 10, Action: config.RouteMapPermit}, true)
+		ospf.updateRouteMap(config.RouteMapConf{Name: "RM", Sequence: 30, Action: config.RouteMapPermit}, true)
+		ospf.updateRouteMap(config.RouteMapConf{Name: "RM", Sequence: 10}, false)
+		entries := ospf.RouteMapTbl["RM"]
+		if len(entries) != 2 || entries[0].Sequence != 20 || entries[1].Sequence != 30 {
+			fmt.Println("Invalid route map entries ", entries)
+			return FAIL
+		}
+		ospf.updateRouteMap(config.RouteMapConf{Name: "RM", Sequence: 20}, false)
+		ospf.updateRouteMap(config.RouteMapConf{Name: "RM", Sequence: 30}, false)
+		if _, exist := ospf.RouteMapTbl["RM"]; exist {
+			fmt.Println("Empty route map not removed")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running matchRouteMapEnt")
+		route := policyRoute{
+			net:      convertAreaOrRouterIdUint32("40.1.1.0"),
+			mask:     convertAreaOrRouterIdUint32("255.255.255.0"),
+			tag:      100,
+			protocol: "BGP",
+		}
+		ent := config.RouteMapConf{MatchPrefix: "40.1.0.0", MatchMask: "255.255.0.0"}
+		if !matchRouteMapEnt(ent, route) {
+			fmt.Println("Route within the prefix not matched")
+			return FAIL
+		}
+		ent.MatchExact = true
+		if matchRouteMapEnt(ent, route) {
+			fmt.Println("Exact match met by a longer prefix")
+			return FAIL
+		}
+		ent = config.RouteMapConf{MatchPrefix: "40.1.1.0", MatchMask: "255.255.255.128"}
+		if matchRouteMapEnt(ent, route) {
+			fmt.Println("Shorter route matched by a longer prefix")
+			return FAIL
+		}
+		ent = config.RouteMapConf{MatchTag: 100, MatchProtocol: "bgp"}
+		if !matchRouteMapEnt(ent, route) {
+			fmt.Println("Tag and protocol not matched")
+			return FAIL
+		}
+		ent.MatchTag = 200
+		if matchRouteMapEnt(ent, route) {
+			fmt.Println("Tag mismatch matched")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running redistribution route map")
+		mdata, send := ospf.redistributeRibdRoute(policyRoute1, getPolicyRouteMdata(policyRoute1, false))
+		if !send || mdata.metric != 10 || mdata.type1 {
+			fmt.Println("Route not redistributed without route map ", mdata)
+			return FAIL
+		}
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, RouteMap: &config.RouteMapConf{
+			Name: "REDIST", Sequence: 10, Action: config.RouteMapPermit, MatchProtocol: "BGP",
+			SetMetric: 50, SetMetricType: config.ExtMetricType1, SetTag: 300}})
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, Policy: &config.PolicyConf{RedistRouteMap: "REDIST"}})
+		if !ospf.RedistRouteMap[AggrPrefixKey{Net: mdata.ipaddr, Mask: mdata.mask}].Permitted {
+			fmt.Println("Route denied by the route map")
+			return FAIL
+		}
+		mdata, send = ospf.redistributeRibdRoute(policyRoute1, getPolicyRouteMdata(policyRoute1, false))
+		if !send || mdata.metric != 50 || !mdata.type1 || mdata.tag != 300 {
+			fmt.Println("Set actions not applied ", mdata)
+			return FAIL
+		}
+		_, send = ospf.redistributeRibdRoute(policyRoute2, getPolicyRouteMdata(policyRoute2, false))
+		if send {
+			fmt.Println("Route redistributed without matching entry")
+			return FAIL
+		}
+		_, send = ospf.redistributeRibdRoute(policyRoute2, getPolicyRouteMdata(policyRoute2, true))
+		if send {
+			fmt.Println("Withdrawal sent for a route which was never redistributed")
+			return FAIL
+		}
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, RouteMap: &config.RouteMapConf{
+			Name: "REDIST", Sequence: 5, Action: config.RouteMapDeny, MatchTag: 100}})
+		rEnt := ospf.RedistRouteMap[AggrPrefixKey{Net: mdata.ipaddr, Mask: mdata.mask}]
+		if rEnt.Permitted {
+			fmt.Println("Route not withdrawn after route map change")
+			return FAIL
+		}
+		mdata, send = ospf.redistributeRibdRoute(policyRoute1, getPolicyRouteMdata(policyRoute1, true))
+		if send {
+			fmt.Println("Withdrawal sent for a denied route ", mdata)
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running distribute-list")
+		rKey := RoutingTblEntryKey{
+			DestId:   convertAreaOrRouterIdUint32("60.1.1.0"),
+			AddrMask: convertAreaOrRouterIdUint32("255.255.255.0"),
+			DestType: Network,
+		}
+		rEnt := RoutingTblEntry{
+			NextHops: map[NextHop]bool{NextHop{NextHopIP: 1}: true},
+			RouteTag: 7,
+		}
+		if !ospf.isDistListPermitted(rKey, rEnt) {
+			fmt.Println("Route filtered without distribute-list")
+			return FAIL
+		}
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, RouteMap: &config.RouteMapConf{
+			Name: "DIST", Sequence: 10, Action: config.RouteMapDeny, MatchTag: 7, MatchProtocol: "OSPF"}})
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, RouteMap: &config.RouteMapConf{
+			Name: "DIST", Sequence: 20, Action: config.RouteMapPermit}})
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, Policy: &config.PolicyConf{
+			RedistRouteMap: "REDIST", DistributeList: "DIST"}})
+		ospf.TempAreaRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
+		ospf.OldGlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
+		ospf.TempGlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
+		ospf.TempGlobalRoutingTbl[rKey] = GlobalRoutingTblEntry{RoutingTblEnt: rEnt}
+		changed := ospf.InstallRoutingTbl()
+		if changed != 0 || !ospf.DistListFilteredMap[rKey] {
+			fmt.Println("Route not kept out of ribd ", changed)
+			return FAIL
+		}
+		ospf.OldGlobalRoutingTbl = ospf.TempGlobalRoutingTbl
+		changed = ospf.InstallRoutingTbl()
+		if changed != 0 {
+			fmt.Println("Filtered route deleted from ribd ", changed)
+			return FAIL
+		}
+		rEnt.RouteTag = 8
+		if !ospf.isDistListPermitted(rKey, rEnt) {
+			fmt.Println("Route without matching tag filtered")
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running passive interface")
+		if ospf.isPassiveIntf(key) {
+			fmt.Println("Interface passive without configuration")
+			return FAIL
+		}
+		ifPolicy := config.IfPolicyConf{
+			IfIpAddress: key.IPAddr,
+			Passive:     true,
+		}
+		err := ospf.processPolicyConfig(PolicyConfMsg{Op: true, IfPolicy: &ifPolicy})
+		if err != nil || !ospf.isPassiveIntf(key) {
+			fmt.Println("Passive interface not configured ", err)
+			return FAIL
+		}
+		err = ospf.processRxHelloPkt(make([]byte, OSPF_HELLO_MIN_SIZE), nil, nil, nil, key)
+		if err == nil {
+			fmt.Println("Hello accepted on passive interface")
+			return FAIL
+		}
+		ospf.processPolicyConfig(PolicyConfMsg{Op: false, IfPolicy: &ifPolicy})
+		if ospf.isPassiveIntf(key) {
+			fmt.Println("Passive interface not removed")
+			return FAIL
+		}
+		ifPolicy.IfIpAddress = "0.0.0.0"
+		if ospf.ValidateIfPolicyConf(ifPolicy) == nil {
+			fmt.Println("Unnumbered interface accepted without index")
+			return FAIL
+		}
+
+	case 6:
+		fmt.Println(tNum, ": Running prefix suppression")
+		links := ospf.constructP2MPLinks(key, intf)
+		if len(links) == 0 || links[0].LinkType != StubLink {
+			fmt.Println("Host route missing ", links)
+			return FAIL
+		}
+		ifPolicy := config.IfPolicyConf{
+			IfIpAddress:    key.IPAddr,
+			SuppressPrefix: true,
+		}
+		ospf.processPolicyConfig(PolicyConfMsg{Op: true, IfPolicy: &ifPolicy})
+		links = ospf.constructP2MPLinks(key, intf)
+		for _, link := range links {
+			if link.LinkType == StubLink {
+				fmt.Println("Host route of suppressed prefix advertised ", links)
+				return FAIL
+			}
+		}
+		ospf.processPolicyConfig(PolicyConfMsg{Op: false, IfPolicy: &ifPolicy})
+
+		fmt.Println(tNum, ": Running GetBulkOspfRedistRouteState")
+		ospf.redistributeRibdRoute(policyRoute1, getPolicyRouteMdata(policyRoute1, false))
+		ospf.redistributeRibdRoute(policyRoute2, getPolicyRouteMdata(policyRoute2, false))
+		nextIdx, count, states := ospf.GetBulkOspfRedistRouteState(0, 10)
+		if nextIdx != 0 || count != 2 || states[0].DestIp != "40.1.1.0" ||
+			states[0].Permitted || states[1].Permitted {
+			fmt.Println("Invalid redistributed routes ", states)
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
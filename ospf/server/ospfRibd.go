@@ -42,6 +42,8 @@ type RouteMdata struct {
 	metric uint32
 	ipaddr uint32
 	mask   uint32
+	tag    uint32 // external route tag of the AS external LSA
+	type1  bool   // E1 metric, E2 otherwise
 	isDel  bool
 }
 
@@ -163,10 +165,17 @@ func (server *OSPFServer) ProcessRibdRoutes(route ribdInt.Routes, msgType uint16
 		ipaddr: ipaddr,
 		mask:   mask,
 		metric: metric,
+		tag:    uint32(route.RouteTag),
 		isDel:  isDel,
 	}
 	ignore := server.verifyOspfRoute(ipaddr, mask)
 	if !ignore {
+		var send bool
+		routemdata, send = server.redistributeRibdRoute(route, routemdata)
+		if !send {
+			server.logger.Info(fmt.Sprintln("ASBR: Route not redistributed by route map ", route.Ipaddr, route.Mask))
+			return
+		}
 		server.logger.Info(fmt.Sprintln("ASBR: Generate As external for ", route.Ipaddr, route.Mask))
 		/* send message to LSDB to generate AS ext LSA */
 		server.ExternalRouteNotif <- routemdata
@@ -27,7 +27,9 @@ import (
 	"asicd/asicdCommonDefs"
 	"errors"
 	"fmt"
+	"l3/ospf/config"
 	"ribd"
+	"ribdInt"
 	"strconv"
 )
 
@@ -87,6 +89,8 @@ type RoutingTblEntry struct {
 	LSOrigin        LsaKey
 	NumOfPaths      int
 	NextHops        map[NextHop]bool // Next Hop
+	RouteTag        uint32           // External route tag, AS external routes only
+	BackupNextHops  map[NextHop]bool // Loop-free alternates, used when all the next hops fail
 }
 
 type GlobalRoutingTblEntry struct {
@@ -214,9 +218,18 @@ func (server *OSPFServer) findP2PNextHopIP(vFirst VertexKey, vSecond VertexKey,
 	} else {
 		flag = false
 	}
+	if firstLink.LinkType == VirtualLink {
+		// Virtual links forward through the transit area path
+		ifIPAddr, nextHopIP, ok := server.getVirtLinkNextHop(vSecond.AdvRtr)
+		if !ok {
+			err = errors.New("Virtual link to second vertex is down")
+			return 0, 0, err
+		}
+		return ifIPAddr, nextHopIP, nil
+	}
 	for _, link := range secondLsa.LinkDetails {
 		if link.LinkId == vFirst.AdvRtr &&
-			link.LinkType == P2PLink {
+			(link.LinkType == P2PLink || link.LinkType == VirtualLink) {
 			secondLink = link
 			flag = true
 			break
@@ -233,6 +246,14 @@ func (server *OSPFServer) findP2PNextHopIP(vFirst VertexKey, vSecond VertexKey,
 
 }
 
+// Routing table costs are 16 bit, longer paths over max-metric links are kept at the largest cost
+func spfRouteCost(distance uint32) uint16 {
+	if distance > uint32(MaxLinkMetric) {
+		return MaxLinkMetric
+	}
+	return uint16(distance)
+}
+
 func (server *OSPFServer) UpdateRoutingTblForRouter(areaIdKey AreaIdKey, vKey VertexKey, tVertex TreeVertex, rootVKey VertexKey) {
 	server.logger.Info(fmt.Sprintln("Updating Routing Table for Router Vertex", vKey, tVertex))
 
@@ -281,7 +302,7 @@ func (server *OSPFServer) UpdateRoutingTblForRouter(areaIdKey AreaIdKey, vKey Ve
 	rEnt.OptCapabilities = 0 //TODO
 	//rEnt.Area = gEnt.AreaId
 	rEnt.PathType = IntraArea
-	rEnt.Cost = tVertex.Distance
+	rEnt.Cost = spfRouteCost(tVertex.Distance)
 	rEnt.Type2Cost = 0 //TODO
 	rEnt.LSOrigin = gEnt.LsaKey
 	rEnt.NumOfPaths = tVertex.NumOfPaths
@@ -426,7 +447,7 @@ func (server *OSPFServer) UpdateRoutingTblWithStub(areaId uint32, vKey VertexKey
 	}
 	rEnt.OptCapabilities = pREnt.OptCapabilities //TODO
 	rEnt.PathType = IntraArea                    //TODO
-	rEnt.Cost = tVertex.Distance
+	rEnt.Cost = spfRouteCost(tVertex.Distance)
 	rEnt.Type2Cost = 0 //TODO
 	rEnt.LSOrigin = sEnt.LsaKey
 	rEnt.NumOfPaths = tVertex.NumOfPaths
@@ -463,7 +484,7 @@ func (server *OSPFServer) UpdateRoutingTblForSNetwork(areaIdKey AreaIdKey, vKey
 
 	rEnt.OptCapabilities = 0  //TODO
 	rEnt.PathType = IntraArea //TODO
-	rEnt.Cost = tVertex.Distance
+	rEnt.Cost = spfRouteCost(tVertex.Distance)
 	rEnt.Type2Cost = 0 //TODO
 	rEnt.LSOrigin = sEnt.LsaKey
 	rEnt.NumOfPaths = tVertex.NumOfPaths
@@ -545,7 +566,7 @@ func (server *OSPFServer) UpdateRoutingTblForTNetwork(areaIdKey AreaIdKey, vKey
 	rEnt.OptCapabilities = 0 //TODO
 	//rEnt.Area = gEnt.AreaId
 	rEnt.PathType = IntraArea //TODO
-	rEnt.Cost = tVertex.Distance
+	rEnt.Cost = spfRouteCost(tVertex.Distance)
 	rEnt.Type2Cost = 0 //TODO
 	rEnt.LSOrigin = gEnt.LsaKey
 	rEnt.NumOfPaths = tVertex.NumOfPaths
@@ -625,6 +646,9 @@ func (server *OSPFServer) CompareRoutes(rKey RoutingTblEntryKey) bool {
 	if oldEnt.RoutingTblEnt.Cost != newEnt.RoutingTblEnt.Cost {
 		return false
 	}
+	if oldEnt.RoutingTblEnt.RouteTag != newEnt.RoutingTblEnt.RouteTag {
+		return false
+	}
 	if len(oldEnt.RoutingTblEnt.NextHops) != len(newEnt.RoutingTblEnt.NextHops) {
 		return false
 	}
@@ -635,6 +659,15 @@ func (server *OSPFServer) CompareRoutes(rKey RoutingTblEntryKey) bool {
 			return false
 		}
 	}
+	if len(oldEnt.RoutingTblEnt.BackupNextHops) != len(newEnt.RoutingTblEnt.BackupNextHops) {
+		return false
+	}
+	for key, _ := range oldEnt.RoutingTblEnt.BackupNextHops {
+		_, exist := newEnt.RoutingTblEnt.BackupNextHops[key]
+		if !exist {
+			return false
+		}
+	}
 	return true
 }
 
@@ -679,6 +712,25 @@ func (server *OSPFServer) DeleteRoute(rKey RoutingTblEntryKey) {
 		}
 
 	}
+	for key, _ := range oldEnt.RoutingTblEnt.BackupNextHops {
+		nextHopIp := convertUint32ToIPv4(key.NextHopIP)
+		server.logger.Info(fmt.Sprintln("Deleting backup next hop: destNetIp:", destNetIp, "networkMask:", networkMask, "nextHopIp:", nextHopIp))
+		if server.ribdClient.ClientHdl == nil {
+			server.logger.Err("Nil ribd handle. Can not delete backup next hop. ")
+			return
+		}
+		cfg := ribd.IPv4Route{
+			DestinationNw: destNetIp,
+			Protocol:      routeType,
+			Cost:          0,
+			NetworkMask:   networkMask,
+			NextHop:       []*ribd.NextHopInfo{&ribd.NextHopInfo{NextHopIp: nextHopIp}},
+		}
+		_, err := server.ribdClient.ClientHdl.DeleteIPv4Route(&cfg)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("Error Deleting backup next hop:", err))
+		}
+	}
 }
 
 func (server *OSPFServer) UpdateRoute(rKey RoutingTblEntryKey) {
@@ -722,7 +774,13 @@ func (server *OSPFServer) InstallRoute(rKey RoutingTblEntryKey) {
 		}
 		cfg.NextHop = make([]*ribd.NextHopInfo, 0)
 		cfg.NextHop = append(cfg.NextHop, &nextHopInfo)
-		ret, err := server.ribdClient.ClientHdl.CreateIPv4Route(&cfg) //destNetIp, networkMask, metric, nextHopIp, nextHopIfType, nextHopIfIndex, routeType)
+		var ret bool
+		var err error
+		if newEnt.RoutingTblEnt.RouteTag != 0 {
+			ret, err = server.installRouteConfig(cfg, newEnt.RoutingTblEnt.RouteTag, false)
+		} else {
+			ret, err = server.ribdClient.ClientHdl.CreateIPv4Route(&cfg) //destNetIp, networkMask, metric, nextHopIp, nextHopIfType, nextHopIfIndex, routeType)
+		}
 		if err != nil {
 			server.logger.Err(fmt.Sprintln("Error Installing Route:", err))
 		}
@@ -738,6 +796,53 @@ func (server *OSPFServer) InstallRoute(rKey RoutingTblEntryKey) {
 		}
 
 	}
+	// Backup next hops share the cost of the route, ribd only forwards on them once all the next hops are down
+	for key, _ := range newEnt.RoutingTblEnt.BackupNextHops {
+		nextHopIp := convertUint32ToIPv4(key.NextHopIP)
+		ipProp, exist := server.ipPropertyMap[key.IfIPAddr]
+		if !exist {
+			server.logger.Err(fmt.Sprintln("Unable to find entry for ip:", key.IfIPAddr, "in ipPropertyMap"))
+			continue
+		}
+		nextHopIfIndex := asicdCommonDefs.GetIfIndexFromIntfIdAndIntfType(int(ipProp.IfId), int(ipProp.IfType))
+		server.logger.Info(fmt.Sprintln("Installing backup next hop: destNetIp:", destNetIp, "networkMask:", networkMask, "nextHopIp:", nextHopIp, "nextHopIfIndex:", nextHopIfIndex))
+		cfg := ribd.IPv4Route{
+			DestinationNw: destNetIp,
+			Protocol:      routeType,
+			Cost:          int32(metric),
+			NetworkMask:   networkMask,
+			NextHop: []*ribd.NextHopInfo{&ribd.NextHopInfo{
+				NextHopIp:     nextHopIp,
+				NextHopIntRef: strconv.Itoa(int(nextHopIfIndex)),
+			}},
+		}
+		_, err := server.installRouteConfig(cfg, newEnt.RoutingTblEnt.RouteTag, true)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("Error Installing backup next hop:", err))
+		}
+	}
+}
+
+// Route tags and backup next hops are only carried by the route config create of ribd
+func (server *OSPFServer) installRouteConfig(cfg ribd.IPv4Route, routeTag uint32, backup bool) (bool, error) {
+	server.logger.Info(fmt.Sprintln("Installing Route:", cfg.DestinationNw, cfg.NetworkMask, "with route tag", routeTag, "backup", backup))
+	routeCfg := ribdInt.IPv4RouteConfig{
+		DestinationNw: cfg.DestinationNw,
+		NetworkMask:   cfg.NetworkMask,
+		Protocol:      cfg.Protocol,
+		Cost:          cfg.Cost,
+		NextHop:       make([]*ribdInt.RouteNextHopInfo, 0),
+		RouteTag:      int64(routeTag),
+	}
+	for _, nextHop := range cfg.NextHop {
+		routeCfg.NextHop = append(routeCfg.NextHop, &ribdInt.RouteNextHopInfo{
+			NextHopIp:     nextHop.NextHopIp,
+			NextHopIntRef: nextHop.NextHopIntRef,
+			Weight:        nextHop.Weight,
+			Backup:        backup,
+		})
+	}
+	return server.ribdClient.ClientHdl.CreateIPv4RouteConfig(&routeCfg)
 }
 
 func (server *OSPFServer) ConsolidatingRoutingTbl() {
@@ -754,7 +859,10 @@ func (server *OSPFServer) ConsolidatingRoutingTbl() {
 		for rKey, rEnt := range tempAreaRoutingTbl.RoutingTblMap {
 			ent, exist := server.TempGlobalRoutingTbl[rKey]
 			if exist {
-
+				if server.isPreferredExtRoute(areaId, rEnt, ent) {
+					ent.AreaId = areaId
+					ent.RoutingTblEnt = rEnt
+				}
 			} else {
 				ent.AreaId = areaId
 				ent.RoutingTblEnt = rEnt
@@ -764,7 +872,38 @@ func (server *OSPFServer) ConsolidatingRoutingTbl() {
 	}
 }
 
-func (server *OSPFServer) InstallRoutingTbl() {
+/*
+   External path preference across areas (RFC 3101 2.5 (6)).
+   Only decides between external routes, the first area
+   still wins otherwise. On a tie Type-5 routes are preferred
+   over Type-7 routes.
+*/
+func (server *OSPFServer) isPreferredExtRoute(areaId uint32, rEnt RoutingTblEntry, ent GlobalRoutingTblEntry) bool {
+	old := ent.RoutingTblEnt
+	isExt := func(pathType PathType) bool {
+		return pathType == Type1Ext || pathType == Type2Ext
+	}
+	if !isExt(old.PathType) {
+		return false
+	}
+	if !isExt(rEnt.PathType) {
+		return true
+	}
+	if rEnt.PathType != old.PathType {
+		return rEnt.PathType > old.PathType
+	}
+	if rEnt.PathType == Type2Ext && rEnt.Type2Cost != old.Type2Cost {
+		return rEnt.Type2Cost < old.Type2Cost
+	}
+	if rEnt.Cost != old.Cost {
+		return rEnt.Cost < old.Cost
+	}
+	newNssa := server.isNssaArea(config.AreaId(convertUint32ToIPv4(areaId)))
+	oldNssa := server.isNssaArea(config.AreaId(convertUint32ToIPv4(ent.AreaId)))
+	return oldNssa && !newNssa
+}
+
+func (server *OSPFServer) InstallRoutingTbl() int {
 	server.logger.Info(fmt.Sprintln("Routing Table Consolidation:"))
 	server.ConsolidatingRoutingTbl()
 	server.logger.Info(fmt.Sprintln("Installing Routing Table "))
@@ -776,25 +915,37 @@ func (server *OSPFServer) InstallRoutingTbl() {
 		if rKey.DestType != Network {
 			continue
 		}
+		if server.DistListFilteredMap[rKey] {
+			// never installed in ribd
+			continue
+		}
 		if len(rEnt.RoutingTblEnt.NextHops) > 0 {
 			OldRoutingTblKeys[rKey] = false
 		}
 	}
 
+	filtered := make(map[RoutingTblEntryKey]bool)
 	for rKey, rEnt := range server.TempGlobalRoutingTbl {
 		if rKey.DestType != Network {
 			continue
 		}
 		if len(rEnt.RoutingTblEnt.NextHops) > 0 {
+			if !server.isDistListPermitted(rKey, rEnt.RoutingTblEnt) {
+				filtered[rKey] = true
+				continue
+			}
 			NewRoutingTblKeys[rKey] = false
 		}
 	}
+	server.DistListFilteredMap = filtered
+	changed := 0
 	for rKey, _ := range NewRoutingTblKeys {
 		_, exist := OldRoutingTblKeys[rKey]
 		if exist {
 			ret := server.CompareRoutes(rKey)
 			if ret == false { // Old Routes and New Routes are not same
 				server.UpdateRoute(rKey)
+				changed++
 				OldRoutingTblKeys[rKey] = true
 				NewRoutingTblKeys[rKey] = true
 			} else { // Old Routes and New Routes are same
@@ -807,6 +958,7 @@ func (server *OSPFServer) InstallRoutingTbl() {
 	for rKey, ent := range OldRoutingTblKeys {
 		if ent == false {
 			server.DeleteRoute(rKey)
+			changed++
 		}
 		OldRoutingTblKeys[rKey] = true
 	}
@@ -814,9 +966,11 @@ func (server *OSPFServer) InstallRoutingTbl() {
 	for rKey, ent := range NewRoutingTblKeys {
 		if ent == false {
 			server.InstallRoute(rKey)
+			changed++
 		}
 		NewRoutingTblKeys[rKey] = true
 	}
+	return changed
 }
 
 /*
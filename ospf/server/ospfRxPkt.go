@@ -82,8 +82,9 @@ func (server *OSPFServer) processIPv4Layer(ipLayer gopacket.Layer, ipAddr net.IP
 	return nil
 }
 
-func (server *OSPFServer) processOspfHeader(ospfPkt []byte, key IntfConfKey, md *OspfHdrMetadata) error {
+func (server *OSPFServer) processOspfHeader(ospfPkt []byte, key IntfConfKey, md *OspfHdrMetadata, ipHdrMd *IpHdrMetadata) error {
 	if len(ospfPkt) < OSPF_HEADER_SIZE {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropBadLength)
 		err := errors.New("Invalid length of Ospf Header")
 		return err
 	}
@@ -98,7 +99,14 @@ func (server *OSPFServer) processOspfHeader(ospfPkt []byte, key IntfConfKey, md
 
 	decodeOspfHdr(ospfPkt, ospfHdr)
 
+	if int(ospfHdr.pktlen) < OSPF_HEADER_SIZE || int(ospfHdr.pktlen) > len(ospfPkt) {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropBadLength)
+		err := errors.New("Dropped because of invalid Ospf packet length")
+		return err
+	}
+
 	if server.ospfGlobalConf.Version != ospfHdr.ver {
+		server.countRxDrop(key, ipHdrMd.srcIP, DropBadVersion)
 		err := errors.New("Dropped because of Ospf Version not matching")
 		return err
 	}
@@ -106,6 +114,7 @@ func (server *OSPFServer) processOspfHeader(ospfPkt []byte, key IntfConfKey, md
 	if ent.IfType != config.NumberedP2P || ent.IfType != config.UnnumberedP2P {
 		if bytesEqual(ent.IfAreaId, ospfHdr.areaId) == false &&
 			isInSubnet(net.IP(ent.IfAreaId), net.IP(ospfHdr.areaId), net.IPMask(ent.IfNetmask)) == false {
+			server.countRxDrop(key, ipHdrMd.srcIP, DropAreaMismatch)
 			err := errors.New("Dropped because of Src IP is not in subnet or Area ID not matching")
 			return err
 		}
@@ -118,18 +127,9 @@ func (server *OSPFServer) processOspfHeader(ospfPkt []byte, key IntfConfKey, md
 		md.backbone = false
 	}
 
-	//OSPF Auth Type
-	if ent.IfAuthType != ospfHdr.authType {
-		err := errors.New("Dropped because of Router Id not matching")
-		return err
-	}
-
-	//OSPF Header CheckSum
-	binary.BigEndian.PutUint16(ospfPkt[12:14], 0)
-	copy(ospfPkt[16:OSPF_HEADER_SIZE], []byte{0, 0, 0, 0, 0, 0, 0, 0})
-	csum := computeCheckSum(ospfPkt)
-	if csum != ospfHdr.chksum {
-		err := errors.New("Dropped because of invalid checksum")
+	//OSPF Authentication and Header CheckSum
+	err := server.verifyOspfAuth(key, ent, ospfHdr, ospfPkt, ipHdrMd.srcIP)
+	if err != nil {
 		return err
 	}
 
@@ -137,7 +137,6 @@ func (server *OSPFServer) processOspfHeader(ospfPkt []byte, key IntfConfKey, md
 	   ToDo:
 	   RFC 2328 Section 8.2
 	   1. Complete AreaID check
-	   2. Authentication
 	*/
 	md.pktType = OspfType(ospfHdr.pktType)
 	md.pktlen = ospfHdr.pktlen
@@ -182,7 +181,8 @@ func (server *OSPFServer) ProcessOspfRecvPkt(key IntfConfKey, pkt gopacket.Packe
 
 	ospfHdrMd := NewOspfHdrMetadata()
 	ospfPkt := ipLayer.LayerPayload()
-	err = server.processOspfHeader(ospfPkt, key, ospfHdrMd)
+	key = server.getVirtLinkRxKey(key, ent, ospfPkt, ipHdrMd)
+	err = server.processOspfHeader(ospfPkt, key, ospfHdrMd, ipHdrMd)
 	if err != nil {
 		server.logger.Err(fmt.Sprintln("Dropped because of Ospf Header processing", err))
 		return
@@ -190,7 +190,7 @@ func (server *OSPFServer) ProcessOspfRecvPkt(key IntfConfKey, pkt gopacket.Packe
 		//server.logger.Info("Ospfv2 Header is processed successfully")
 	}
 
-	ospfData := ospfPkt[OSPF_HEADER_SIZE:]
+	ospfData := ospfPkt[OSPF_HEADER_SIZE:ospfHdrMd.pktlen]
 	err = server.processOspfData(ospfData, ethHdrMd, ipHdrMd, ospfHdrMd, key)
 	if err != nil {
 		server.logger.Err(fmt.Sprintln("Dropped because of Ospf Header processing", err))
@@ -213,7 +213,11 @@ func (server *OSPFServer) processOspfData(data []byte, ethHdrMd *EthHdrMetadata,
 	exist := server.neighborExist(ospfNbrConfKey)
 	if !exist {
 		server.logger.Info(fmt.Sprintln("PACKET: neighbor doesnt exist..", NeighborIP, key.IntfIdx))
+		if ospfHdrMd.pktType != HelloType {
+			server.countRxDrop(key, ipHdrMd.srcIP, DropUnknownNbr)
+		}
 	}
+	server.countPkt(key, ipHdrMd.srcIP, ospfHdrMd.pktType, false)
 	switch ospfHdrMd.pktType {
 	case HelloType:
 		err = server.processRxHelloPkt(data, ospfHdrMd, ipHdrMd, ethHdrMd, key)
@@ -234,6 +238,7 @@ func (server *OSPFServer) processOspfData(data []byte, ethHdrMd *EthHdrMetadata,
 			err = server.ProcessRxLSAAckPkt(data, ospfHdrMd, ipHdrMd, key)
 		}
 	default:
+		server.countRxDrop(key, ipHdrMd.srcIP, DropBadPktType)
 		err = errors.New("Invalid Ospf packet type")
 	}
 	return err
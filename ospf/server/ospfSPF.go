@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"l3/ospf/config"
 	"sort"
+	"time"
 )
 
 type VertexKey struct {
@@ -40,10 +41,17 @@ type Path []VertexKey
 
 type TreeVertex struct {
 	Paths      []Path
-	Distance   uint16
+	Distance   uint32
 	NumOfPaths int
 }
 
+/*
+   Distance of a vertex not reached yet. The distances are 32 bit so
+   that paths over max-metric links (RFC 6987) do not wrap, they stay
+   usable as a last resort.
+*/
+const SPF_INFINITY uint32 = 0xffffffff
+
 type StubVertex struct {
 	NbrVertexKey  VertexKey
 	NbrVertexCost uint16
@@ -72,7 +80,7 @@ const (
 
 type VertexData struct {
 	vKey     VertexKey
-	distance uint16
+	distance uint32
 }
 
 var check bool = true
@@ -309,9 +317,13 @@ func (server *OSPFServer) UpdateAreaGraphRouterLsa(lsaEnt RouterLsa, lsaKey LsaK
 	ent.NbrVertexKey = make([]VertexKey, 0)
 	ent.NbrVertexCost = make([]uint16, 0)
 	ent.LinkData = make(map[VertexKey]uint32)
+	selfRtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
 	for i := 0; i < int(lsaEnt.NumOfLinks); i++ {
 		server.logger.Info(fmt.Sprintln("Link Detail at index", i, "is:", lsaEnt.LinkDetails[i]))
 		linkDetail := lsaEnt.LinkDetails[i]
+		if lsaKey.AdvRouter == selfRtrId {
+			linkDetail.LinkMetric = server.getSelfLinkMetric(linkDetail)
+		}
 		var vKey VertexKey
 		var cost uint16
 		var lData uint32
@@ -354,7 +366,8 @@ func (server *OSPFServer) UpdateAreaGraphRouterLsa(lsaEnt RouterLsa, lsaKey LsaK
 			sentry.LsaKey = lsaKey
 			sentry.LinkStateId = lsaKey.LSId
 			server.AreaStubs[vKey] = sentry
-		} else if linkDetail.LinkType == P2PLink {
+		} else if linkDetail.LinkType == P2PLink ||
+			linkDetail.LinkType == VirtualLink {
 			server.logger.Info("===It is P2PLink===")
 			vKey = VertexKey{
 				Type:   RouterVertex,
@@ -501,6 +514,15 @@ func (server *OSPFServer) CreateAreaGraph(areaId uint32) (VertexKey, error) {
 }
 
 func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
+	return server.runDijkstra(server.AreaGraph, server.SPFTree, vKey)
+}
+
+/*
+   Shortest path tree computation over a topology only graph. It is
+   shared by OSPFv2 and OSPFv3, which attach prefixes to the tree
+   afterwards.
+*/
+func (server *OSPFServer) runDijkstra(areaGraph map[VertexKey]Vertex, spfTree map[VertexKey]TreeVertex, vKey VertexKey) error {
 	//var treeVSlice []VertexKey = make([]VertexKey, 0)
 	var treeVSlice []VertexData = make([]VertexData, 0)
 
@@ -510,7 +532,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 		distance: 0,
 	}
 	treeVSlice = append(treeVSlice, vData)
-	ent, exist := server.SPFTree[vKey]
+	ent, exist := spfTree[vKey]
 	if !exist {
 		ent.Distance = 0
 		ent.NumOfPaths = 1
@@ -518,7 +540,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 		var path Path
 		path = make(Path, 0)
 		ent.Paths[0] = path
-		server.SPFTree[vKey] = ent
+		spfTree[vKey] = ent
 	}
 
 	for j := 0; j < len(treeVSlice); j++ {
@@ -526,7 +548,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 		server.logger.Debug(fmt.Sprintln("treeVSlice:", treeVSlice))
 		server.logger.Debug(fmt.Sprintln("The value of j:", j, "treeVSlice:", treeVSlice[j].vKey))
 		//ent, exist := server.AreaGraph[treeVSlice[j]]
-		ent, exist := server.AreaGraph[treeVSlice[j].vKey]
+		ent, exist := areaGraph[treeVSlice[j].vKey]
 		if !exist {
 			server.logger.Info(fmt.Sprintln("No entry found for:", treeVSlice[j].vKey))
 			err := errors.New(fmt.Sprintln("No entry found for:", treeVSlice[j].vKey))
@@ -537,24 +559,24 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 		for i := 0; i < len(ent.NbrVertexKey); i++ {
 			verKey := ent.NbrVertexKey[i]
 			cost := ent.NbrVertexCost[i]
-			entry, exist := server.AreaGraph[verKey]
+			entry, exist := areaGraph[verKey]
 			server.logger.Debug(fmt.Sprintln("Neighboring Vertex Number :", i, "verKey", verKey, "cost:", cost, "entry:", entry))
 			if !exist {
 				server.logger.Err("Something is wrong in SPF Calculation: Entry should exist in Area Graph")
 				err := errors.New("Something is wrong in SPF Calculation: Entry should exist in Area Graph")
 				return err
 			}
-			tEnt, exist := server.SPFTree[verKey]
+			tEnt, exist := spfTree[verKey]
 			if !exist {
 				server.logger.Debug("Entry doesnot exist for the neighbor in SPF hence adding it")
 				tEnt.Paths = make([]Path, 1)
 				var path Path
 				path = make(Path, 0)
 				tEnt.Paths[0] = path
-				tEnt.Distance = 0xff00 // LSInfinity
+				tEnt.Distance = SPF_INFINITY
 				tEnt.NumOfPaths = 1
 			}
-			tEntry, exist := server.SPFTree[treeVSlice[j].vKey]
+			tEntry, exist := spfTree[treeVSlice[j].vKey]
 			if !exist {
 				server.logger.Err("Something is wrong is SPF Calculation")
 				err := errors.New("Something is wrong is SPF Calculation")
@@ -562,9 +584,9 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 			}
 			server.logger.Debug(fmt.Sprintln("Parent Node:", treeVSlice[j].vKey, tEntry))
 			server.logger.Debug(fmt.Sprintln("Child Node:", verKey, tEnt))
-			if tEnt.Distance > tEntry.Distance+cost {
+			if tEnt.Distance > tEntry.Distance+uint32(cost) {
 				server.logger.Debug(fmt.Sprintln("We have lower cost path via", tEntry))
-				tEnt.Distance = tEntry.Distance + cost
+				tEnt.Distance = tEntry.Distance + uint32(cost)
 				for l := 0; l < tEnt.NumOfPaths; l++ {
 					tEnt.Paths[l] = nil
 				}
@@ -580,7 +602,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 					tEnt.Paths[l] = path
 				}
 				tEnt.NumOfPaths = tEntry.NumOfPaths
-			} else if tEnt.Distance == tEntry.Distance+cost {
+			} else if tEnt.Distance == tEntry.Distance+uint32(cost) {
 				server.logger.Debug(fmt.Sprintln("We have equal cost path via:", tEntry))
 				server.logger.Debug(fmt.Sprintln("tEnt:", tEnt, "tEntry:", tEntry))
 				server.logger.Debug(fmt.Sprintln("tEnt.NumOfPaths:", tEnt.NumOfPaths, "tEntry.NumOfPaths:", tEntry.NumOfPaths))
@@ -606,7 +628,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 				tEnt.Paths = paths
 				tEnt.NumOfPaths = tEntry.NumOfPaths + tEnt.NumOfPaths
 			}
-			if _, ok := server.SPFTree[verKey]; !ok {
+			if _, ok := spfTree[verKey]; !ok {
 				server.logger.Debug(fmt.Sprintln("Adding verKey:", verKey, "to treeVSlice"))
 				vData := VertexData{
 					vKey:     verKey,
@@ -614,7 +636,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 				}
 				verArr = append(verArr, vData)
 			}
-			server.SPFTree[verKey] = tEnt
+			spfTree[verKey] = tEnt
 		}
 		treeVSlice = append(treeVSlice, verArr...)
 		if len(treeVSlice[j+1:]) > 0 {
@@ -623,7 +645,7 @@ func (server *OSPFServer) ExecuteDijkstra(vKey VertexKey, areaId uint32) error {
 		verArr = verArr[:0]
 		verArr = nil
 		ent.Visited = true
-		server.AreaGraph[treeVSlice[j].vKey] = ent
+		areaGraph[treeVSlice[j].vKey] = ent
 	}
 
 	return nil
@@ -639,7 +661,7 @@ func (server *OSPFServer) HandleStubs(vKey VertexKey, areaId uint32) {
 			continue
 		}
 		ent, _ := server.SPFTree[key]
-		ent.Distance = parent.Distance + entry.NbrVertexCost
+		ent.Distance = parent.Distance + uint32(entry.NbrVertexCost)
 		ent.Paths = make([]Path, parent.NumOfPaths)
 		for i := 0; i < parent.NumOfPaths; i++ {
 			var path Path
@@ -784,6 +806,8 @@ func (server *OSPFServer) spfCalculation() {
 	for {
 		msg := <-server.StartCalcSPFCh
 		server.logger.Info(fmt.Sprintln("Recevd SPF Calculation Notification for:", msg))
+		spfStart := time.Now()
+		areaRuns := make(map[uint32]bool)
 		server.logger.Info(fmt.Sprintln("Area LS Database:", server.AreaLsdb))
 		// Create New Routing table
 		// Invalidate Old Routing table
@@ -795,14 +819,16 @@ func (server *OSPFServer) spfCalculation() {
 		server.OldGlobalRoutingTbl = server.GlobalRoutingTbl
 		server.TempAreaRoutingTbl = nil
 		server.TempAreaRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
-		for key, aEnt := range server.AreaConfMap {
+		server.NssaTranslatedLsDb = make(map[LsaKey]ASExternalLsa)
+		for _, key := range server.getSpfAreaOrder() {
+			aEnt := server.AreaConfMap[key]
+			areaId := convertAreaOrRouterIdUint32(string(key.AreaId))
 
 			//server.logger.Info(fmt.Sprintln("===========Area Id : ", key.AreaId, "Area Bdr Status:", server.ospfGlobalConf.isABR, "======================================================="))
-			if len(aEnt.IntfListMap) == 0 {
+			if len(aEnt.IntfListMap) == 0 &&
+				!(areaId == 0 && server.hasVirtLinks()) {
 				continue
 			}
-			aEnt.TransitCapability = false
-			areaId := convertAreaOrRouterIdUint32(string(key.AreaId))
 			server.initialiseSPFStructs()
 			areaIdKey := AreaIdKey{
 				AreaId: areaId,
@@ -812,35 +838,59 @@ func (server *OSPFServer) spfCalculation() {
 			tempRoutingTbl.RoutingTblMap = make(map[RoutingTblEntryKey]RoutingTblEntry)
 			server.TempAreaRoutingTbl[areaIdKey] = tempRoutingTbl
 
-			vKey, err := server.CreateAreaGraph(areaId)
-			if err != nil {
-				server.logger.Err(fmt.Sprintln("Error while creating graph for areaId:", areaId))
-				//flag = true
-				continue
-			}
-			//server.logger.Info("=========================Start before Dijkstra=================")
-			//server.dumpAreaGraph()
-			//server.dumpAreaStubs()
-			//server.logger.Info("=========================End before Dijkstra=================")
-			//server.printRouterLsa()
-			err = server.ExecuteDijkstra(vKey, areaId)
-			if err != nil {
-				server.logger.Err(fmt.Sprintln("Error while executing Dijkstra for areaId:", areaId))
-				//flag = true
-				continue
+			var vKey VertexKey
+			cache, cached := server.SpfAreaCache[areaId]
+			if cached && !msg.AllAreas && !msg.FullAreas[areaId] {
+				server.logger.Info(fmt.Sprintln("Partial route calculation for areaId:", areaId))
+				vKey = server.restoreSpfAreaCache(areaId, cache)
+				areaRuns[areaId] = false
+			} else {
+				aEnt.TransitCapability = false
+				server.AreaConfMap[key] = aEnt
+				delete(server.SpfAreaCache, areaId)
+				var err error
+				vKey, err = server.CreateAreaGraph(areaId)
+				if err != nil {
+					server.logger.Err(fmt.Sprintln("Error while creating graph for areaId:", areaId))
+					//flag = true
+					continue
+				}
+				//server.logger.Info("=========================Start before Dijkstra=================")
+				//server.dumpAreaGraph()
+				//server.dumpAreaStubs()
+				//server.logger.Info("=========================End before Dijkstra=================")
+				//server.printRouterLsa()
+				err = server.ExecuteDijkstra(vKey, areaId)
+				if err != nil {
+					server.logger.Err(fmt.Sprintln("Error while executing Dijkstra for areaId:", areaId))
+					//flag = true
+					continue
+				}
+				server.logger.Info("=========================Start after Dijkstra=================")
+				//	server.dumpAreaGraph()
+				//	server.dumpAreaStubs()
+				//	server.dumpSPFTree()
+				server.logger.Info("=========================End after Dijkstra=================")
+				server.UpdateRoutingTbl(vKey, areaId)
+				server.LfaBackups = server.computeLfa(areaId, vKey)
+				server.saveSpfAreaCache(areaId, vKey)
+				areaRuns[areaId] = true
 			}
-			server.logger.Info("=========================Start after Dijkstra=================")
-			//	server.dumpAreaGraph()
-			//	server.dumpAreaStubs()
-			//	server.dumpSPFTree()
-			server.logger.Info("=========================End after Dijkstra=================")
-			server.UpdateRoutingTbl(vKey, areaId)
 			server.logger.Info("==============Handling Stub links...====================")
 			server.HandleStubs(vKey, areaId)
+			if areaId != 0 {
+				server.resolveVirtLinks(areaId)
+			}
 			server.HandleSummaryLsa(areaId)
+			if server.isNssaArea(key.AreaId) {
+				server.electNssaTranslator(key)
+				server.translateNssaExternalLsa(key)
+			}
+			server.applyLfaBackups(areaId)
 			server.AreaGraph = nil
 			server.AreaStubs = nil
 			server.SPFTree = nil
+			server.LfaBackups = nil
 		}
 		/*
 			server.dumpRoutingTbl()
@@ -848,7 +898,7 @@ func (server *OSPFServer) spfCalculation() {
 		server.TempGlobalRoutingTbl = nil
 		server.TempGlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
 		/* Summarize and Install/Delete Routes In Routing Table */
-		server.InstallRoutingTbl()
+		routesChanged := server.InstallRoutingTbl()
 		// Copy the Summarize Routing Table in Global Routing Table
 		server.GlobalRoutingTbl = nil
 		server.GlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
@@ -874,6 +924,7 @@ func (server *OSPFServer) spfCalculation() {
 			server.GenerateSummaryLsa()
 			server.logger.Info(fmt.Sprintln("========", server.SummaryLsDb, "=========="))
 		}
+		server.recordSpfRun(msg, areaRuns, spfStart, routesChanged)
 		server.DoneCalcSPFCh <- true
 	}
 }
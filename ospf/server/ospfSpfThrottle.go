@@ -0,0 +1,496 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"time"
+)
+
+/*
+   SPF scheduling (RFC 8405).
+   LSDB changes do not run the SPF inline. They are merged into a
+   pending request and the run is delayed by a back-off state machine.
+   The first event after a quiet period is served after the initial
+   delay, events within time to learn after the short delay and a
+   sustained burst after the long delay. The machine falls back to
+   quiet once no event was seen for the holddown interval.
+   Requests which only changed summary, external or stub information
+   reuse the shortest path tree of the previous run.
+*/
+
+type SpfDelayState uint8
+
+const (
+	SpfQuiet     SpfDelayState = 0
+	SpfShortWait SpfDelayState = 1
+	SpfLongWait  SpfDelayState = 2
+)
+
+var SpfDelayStateList = []string{
+	"Quiet",
+	"ShortWait",
+	"LongWait"}
+
+const (
+	SPF_INITIAL_DELAY = 50 // milliseconds
+	SPF_SHORT_DELAY   = 200
+	SPF_LONG_DELAY    = 5000
+	SPF_TIME_TO_LEARN = 500
+	SPF_HOLDDOWN      = 10000
+	SPF_HISTORY_LEN   = 16
+)
+
+/*
+   AllAreas asks for Dijkstra in every area. Otherwise only the areas
+   in FullAreas rerun it and the others reuse their last tree.
+*/
+type SpfRequest struct {
+	AllAreas  bool
+	FullAreas map[uint32]bool
+	Trigger   string
+	Events    int32
+}
+
+type SpfThrottle struct {
+	State           SpfDelayState
+	Conf            config.SpfThrottleConf
+	SpfTimer        *time.Timer
+	SpfTimerRunning bool
+	SpfTimerDelay   int32 // milliseconds the spf timer was last started with
+	LearnTimer      *time.Timer
+	HolddownTimer   *time.Timer
+	Pending         SpfRequest
+	Events          int32
+	DeferredEvents  int32
+	Runs            int32
+}
+
+/*
+   Intra area result of the last Dijkstra run of an area.
+*/
+type SpfAreaCache struct {
+	Root              VertexKey
+	AreaGraph         map[VertexKey]Vertex
+	SPFTree           map[VertexKey]TreeVertex
+	IntraRoutes       map[RoutingTblEntryKey]RoutingTblEntry
+	TransitCapability bool
+	LfaBackups        map[VertexKey]LfaBackup
+}
+
+type rtrLsaTopology struct {
+	exist bool
+	bitB  bool
+	bitE  bool
+	bitV  bool
+	links []LinkDetail
+}
+
+func DefaultSpfThrottleConf() config.SpfThrottleConf {
+	return config.SpfThrottleConf{
+		SpfInitialDelay: SPF_INITIAL_DELAY,
+		SpfShortDelay:   SPF_SHORT_DELAY,
+		SpfLongDelay:    SPF_LONG_DELAY,
+		SpfTimeToLearn:  SPF_TIME_TO_LEARN,
+		SpfHolddown:     SPF_HOLDDOWN,
+	}
+}
+
+func newStoppedTimer() *time.Timer {
+	t := time.NewTimer(time.Hour)
+	t.Stop()
+	return t
+}
+
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+func resetTimer(t *time.Timer, ms int32) {
+	stopTimer(t)
+	t.Reset(time.Duration(ms) * time.Millisecond)
+}
+
+func (server *OSPFServer) initSpfThrottle() {
+	server.SpfThrottle.State = SpfQuiet
+	server.SpfThrottle.Conf = DefaultSpfThrottleConf()
+	server.SpfThrottle.SpfTimer = newStoppedTimer()
+	server.SpfThrottle.LearnTimer = newStoppedTimer()
+	server.SpfThrottle.HolddownTimer = newStoppedTimer()
+	server.SpfAreaCache = make(map[uint32]SpfAreaCache)
+}
+
+func (server *OSPFServer) ValidateSpfThrottleConf(conf config.SpfThrottleConf) error {
+	if conf.SpfInitialDelay < 0 || conf.SpfShortDelay < conf.SpfInitialDelay ||
+		conf.SpfLongDelay < conf.SpfShortDelay {
+		return errors.New(fmt.Sprintln("SPF delays must be ordered initial <= short <= long",
+			conf.SpfInitialDelay, conf.SpfShortDelay, conf.SpfLongDelay))
+	}
+	if conf.SpfTimeToLearn <= 0 || conf.SpfHolddown <= conf.SpfTimeToLearn {
+		return errors.New(fmt.Sprintln("SPF holddown must exceed time to learn",
+			conf.SpfTimeToLearn, conf.SpfHolddown))
+	}
+	return nil
+}
+
+func (server *OSPFServer) processSpfThrottleConfig(conf config.SpfThrottleConf) error {
+	err := server.ValidateSpfThrottleConf(conf)
+	if err != nil {
+		return err
+	}
+	server.SpfThrottleMutex.Lock()
+	server.SpfThrottle.Conf = conf
+	server.SpfThrottleMutex.Unlock()
+	return nil
+}
+
+func newAreaSpfRequest(areaId uint32, full bool, trigger string) SpfRequest {
+	req := SpfRequest{
+		Trigger: trigger,
+	}
+	if full {
+		req.FullAreas = map[uint32]bool{areaId: true}
+	}
+	return req
+}
+
+func mergeSpfRequest(pending *SpfRequest, req SpfRequest) {
+	if pending.Events == 0 {
+		pending.Trigger = req.Trigger
+	}
+	pending.Events++
+	if req.AllAreas {
+		pending.AllAreas = true
+	}
+	for areaId, _ := range req.FullAreas {
+		if pending.FullAreas == nil {
+			pending.FullAreas = make(map[uint32]bool)
+		}
+		pending.FullAreas[areaId] = true
+	}
+}
+
+/* @fn scheduleSpf
+RFC 8405 5. Every IGP event restarts the holddown timer. The SPF
+timer is only started when it is not already running, with the delay
+of the current state, so a burst of LSAs is served by one run.
+*/
+func (server *OSPFServer) scheduleSpf(req SpfRequest) {
+	if server.isGraceRestarting() {
+		// routes are recalculated once the restart is over
+		return
+	}
+	server.SpfThrottleMutex.Lock()
+	defer server.SpfThrottleMutex.Unlock()
+	th := &server.SpfThrottle
+	th.Events++
+	if th.SpfTimerRunning {
+		th.DeferredEvents++
+	}
+	mergeSpfRequest(&th.Pending, req)
+	var delay int32
+	switch th.State {
+	case SpfQuiet:
+		th.State = SpfShortWait
+		resetTimer(th.LearnTimer, th.Conf.SpfTimeToLearn)
+		delay = th.Conf.SpfInitialDelay
+	case SpfShortWait:
+		delay = th.Conf.SpfShortDelay
+	case SpfLongWait:
+		delay = th.Conf.SpfLongDelay
+	}
+	resetTimer(th.HolddownTimer, th.Conf.SpfHolddown)
+	if !th.SpfTimerRunning {
+		th.SpfTimerRunning = true
+		th.SpfTimerDelay = delay
+		resetTimer(th.SpfTimer, delay)
+	}
+}
+
+func (server *OSPFServer) processSpfTimerExpiry() {
+	server.SpfThrottleMutex.Lock()
+	th := &server.SpfThrottle
+	th.SpfTimerRunning = false
+	req := th.Pending
+	th.Pending = SpfRequest{}
+	if req.Events == 0 {
+		server.SpfThrottleMutex.Unlock()
+		return
+	}
+	th.Runs++
+	server.SpfThrottleMutex.Unlock()
+	server.runSpf(req)
+}
+
+func (server *OSPFServer) processSpfLearnTimerExpiry() {
+	server.SpfThrottleMutex.Lock()
+	if server.SpfThrottle.State == SpfShortWait {
+		server.SpfThrottle.State = SpfLongWait
+	}
+	server.SpfThrottleMutex.Unlock()
+}
+
+func (server *OSPFServer) processSpfHolddownTimerExpiry() {
+	server.SpfThrottleMutex.Lock()
+	server.SpfThrottle.State = SpfQuiet
+	stopTimer(server.SpfThrottle.LearnTimer)
+	server.SpfThrottleMutex.Unlock()
+}
+
+func (server *OSPFServer) runSpf(req SpfRequest) {
+	server.StartCalcSPFCh <- req
+	spfStatus := <-server.DoneCalcSPFCh
+	server.logger.Info(fmt.Sprintln("SPF Calculation Return Status", spfStatus))
+	if server.ospfGlobalConf.AreaBdrRtrStatus == true {
+		server.installSummaryLsa()
+	}
+}
+
+func getLsaKeyFromData(data []byte) LsaKey {
+	return LsaKey{
+		LSType:    data[3],
+		LSId:      binary.BigEndian.Uint32(data[4:8]),
+		AdvRouter: binary.BigEndian.Uint32(data[8:12]),
+	}
+}
+
+func getSpfTrigger(lsaKey LsaKey) string {
+	var lsType string
+	switch lsaKey.LSType {
+	case RouterLSA:
+		lsType = "RouterLSA"
+	case NetworkLSA:
+		lsType = "NetworkLSA"
+	case Summary3LSA:
+		lsType = "Summary3LSA"
+	case Summary4LSA:
+		lsType = "Summary4LSA"
+	case ASExternalLSA:
+		lsType = "ASExternalLSA"
+	case NSSAExternalLSA:
+		lsType = "NSSAExternalLSA"
+	default:
+		lsType = fmt.Sprint("LSA type ", lsaKey.LSType)
+	}
+	return fmt.Sprint(lsType, " ", convertUint32ToIPv4(lsaKey.LSId), " ",
+		convertUint32ToIPv4(lsaKey.AdvRouter))
+}
+
+func (server *OSPFServer) getRouterLsaTopology(areaId uint32, lsaKey LsaKey) rtrLsaTopology {
+	var topo rtrLsaTopology
+	if lsaKey.LSType != RouterLSA {
+		return topo
+	}
+	lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+	if !exist {
+		return topo
+	}
+	lsaEnt, exist := lsDbEnt.RouterLsaMap[lsaKey]
+	if !exist {
+		return topo
+	}
+	topo.exist = true
+	topo.bitB = lsaEnt.BitB
+	topo.bitE = lsaEnt.BitE
+	topo.bitV = lsaEnt.BitV
+	for _, link := range lsaEnt.LinkDetails {
+		if link.LinkType != StubLink {
+			topo.links = append(topo.links, link)
+		}
+	}
+	return topo
+}
+
+func (topo rtrLsaTopology) equal(other rtrLsaTopology) bool {
+	if topo.exist != other.exist || topo.bitB != other.bitB ||
+		topo.bitE != other.bitE || topo.bitV != other.bitV ||
+		len(topo.links) != len(other.links) {
+		return false
+	}
+	for idx, link := range topo.links {
+		oLink := other.links[idx]
+		if link.LinkId != oLink.LinkId || link.LinkData != oLink.LinkData ||
+			link.LinkType != oLink.LinkType || link.LinkMetric != oLink.LinkMetric {
+			return false
+		}
+	}
+	return true
+}
+
+/* @fn getLsaSpfRequest
+Only router LSAs whose bits or non stub links changed and network
+LSAs alter the shortest path tree of their area. Everything else is
+handled by the route calculation on top of the previous tree.
+*/
+func (server *OSPFServer) getLsaSpfRequest(areaId uint32, lsaKey LsaKey, before rtrLsaTopology) SpfRequest {
+	trigger := getSpfTrigger(lsaKey)
+	switch lsaKey.LSType {
+	case RouterLSA:
+		after := server.getRouterLsaTopology(areaId, lsaKey)
+		return newAreaSpfRequest(areaId, !before.equal(after), trigger)
+	case NetworkLSA:
+		return newAreaSpfRequest(areaId, true, trigger)
+	}
+	return newAreaSpfRequest(areaId, false, trigger)
+}
+
+func copyRoutingTblMap(src map[RoutingTblEntryKey]RoutingTblEntry) map[RoutingTblEntryKey]RoutingTblEntry {
+	dst := make(map[RoutingTblEntryKey]RoutingTblEntry)
+	for rKey, rEnt := range src {
+		nextHops := make(map[NextHop]bool)
+		for nextHop, val := range rEnt.NextHops {
+			nextHops[nextHop] = val
+		}
+		rEnt.NextHops = nextHops
+		if rEnt.BackupNextHops != nil {
+			backupNextHops := make(map[NextHop]bool)
+			for nextHop, val := range rEnt.BackupNextHops {
+				backupNextHops[nextHop] = val
+			}
+			rEnt.BackupNextHops = backupNextHops
+		}
+		dst[rKey] = rEnt
+	}
+	return dst
+}
+
+func (server *OSPFServer) saveSpfAreaCache(areaId uint32, root VertexKey) {
+	areaIdKey := AreaIdKey{
+		AreaId: areaId,
+	}
+	areaConfKey := AreaConfKey{
+		AreaId: config.AreaId(convertUint32ToIPv4(areaId)),
+	}
+	server.SpfAreaCache[areaId] = SpfAreaCache{
+		Root:              root,
+		AreaGraph:         server.AreaGraph,
+		SPFTree:           server.SPFTree,
+		IntraRoutes:       copyRoutingTblMap(server.TempAreaRoutingTbl[areaIdKey].RoutingTblMap),
+		TransitCapability: server.AreaConfMap[areaConfKey].TransitCapability,
+		LfaBackups:        server.LfaBackups,
+	}
+}
+
+/* @fn restoreSpfAreaCache
+Partial route calculation. The tree and the intra area routes of
+the last run are reused and only the stub links are read again.
+*/
+func (server *OSPFServer) restoreSpfAreaCache(areaId uint32, cache SpfAreaCache) VertexKey {
+	server.AreaGraph = cache.AreaGraph
+	server.SPFTree = cache.SPFTree
+	server.LfaBackups = cache.LfaBackups
+	server.rebuildAreaStubs(areaId)
+	areaIdKey := AreaIdKey{
+		AreaId: areaId,
+	}
+	tempRoutingTbl := server.TempAreaRoutingTbl[areaIdKey]
+	tempRoutingTbl.RoutingTblMap = copyRoutingTblMap(cache.IntraRoutes)
+	server.TempAreaRoutingTbl[areaIdKey] = tempRoutingTbl
+	areaConfKey := AreaConfKey{
+		AreaId: config.AreaId(convertUint32ToIPv4(areaId)),
+	}
+	aEnt := server.AreaConfMap[areaConfKey]
+	aEnt.TransitCapability = cache.TransitCapability
+	server.AreaConfMap[areaConfKey] = aEnt
+	return cache.Root
+}
+
+func (server *OSPFServer) rebuildAreaStubs(areaId uint32) {
+	server.AreaStubs = make(map[VertexKey]StubVertex)
+	lsDbEnt, exist := server.AreaLsdb[LsdbKey{AreaId: areaId}]
+	if !exist {
+		return
+	}
+	for lsaKey, lsaEnt := range lsDbEnt.RouterLsaMap {
+		vertexKey := VertexKey{
+			Type:   RouterVertex,
+			ID:     lsaKey.LSId,
+			AdvRtr: lsaKey.AdvRouter,
+		}
+		if _, exist := server.SPFTree[vertexKey]; !exist {
+			continue
+		}
+		for _, linkDetail := range lsaEnt.LinkDetails {
+			if linkDetail.LinkType != StubLink {
+				continue
+			}
+			vKey := VertexKey{
+				Type:   SNetworkVertex,
+				ID:     linkDetail.LinkId,
+				AdvRtr: lsaKey.AdvRouter,
+			}
+			sentry, _ := server.AreaStubs[vKey]
+			sentry.NbrVertexKey = vertexKey
+			sentry.NbrVertexCost = linkDetail.LinkMetric
+			sentry.LinkData = linkDetail.LinkData
+			sentry.AreaId = areaId
+			sentry.LsaKey = lsaKey
+			sentry.LinkStateId = lsaKey.LSId
+			server.AreaStubs[vKey] = sentry
+		}
+	}
+}
+
+func (server *OSPFServer) recordSpfRun(req SpfRequest, areaRuns map[uint32]bool, start time.Time, routesChanged int) {
+	duration := time.Since(start)
+	server.AreaStateMutex.Lock()
+	defer server.AreaStateMutex.Unlock()
+	for areaId, full := range areaRuns {
+		areaConfKey := AreaConfKey{
+			AreaId: config.AreaId(convertUint32ToIPv4(areaId)),
+		}
+		ent, exist := server.AreaStateMap[areaConfKey]
+		if !exist {
+			continue
+		}
+		if full {
+			ent.SpfRuns++
+		} else {
+			ent.SpfPartialRuns++
+		}
+		runLog := config.SpfRunLog{
+			StartTime:     start.String(),
+			Trigger:       req.Trigger,
+			Events:        req.Events,
+			FullSpf:       full,
+			Duration:      int32(duration / time.Microsecond),
+			RoutesChanged: int32(routesChanged),
+		}
+		history := append([]config.SpfRunLog{}, ent.SpfHistory...)
+		history = append(history, runLog)
+		if len(history) > SPF_HISTORY_LEN {
+			history = history[len(history)-SPF_HISTORY_LEN:]
+		}
+		ent.SpfHistory = history
+		server.AreaStateMap[areaConfKey] = ent
+	}
+}
@@ -0,0 +1,201 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfSpfThrottle_test
+   This test covers
+   1) SPF delay state machine and event coalescing.
+   2) Classifying LSA changes into full and partial runs.
+   3) Reusing the shortest path tree for a partial run.
+   4) SPF run history per area.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"testing"
+	"time"
+)
+
+func initSpfThrottleTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+}
+
+func TestOspfSpfThrottle(t *testing.T) {
+	fmt.Println("\n**************** SPF THROTTLE ************\n")
+	initSpfThrottleTestParams()
+	for index := 1; index < 5; index++ {
+		err := spfThrottleTestLogic(index)
+		if err != SUCCESS {
+			t.Fatal("Failed test case for spf throttle ", index)
+		}
+	}
+}
+
+func spfThrottleTestLogic(tNum int) int {
+	rtrId := convertAreaOrRouterIdUint32("2.2.2.2")
+	lsaKey := LsaKey{LSType: RouterLSA, LSId: rtrId, AdvRouter: rtrId}
+	lsdbKey := LsdbKey{AreaId: 0}
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running scheduleSpf")
+		bad := DefaultSpfThrottleConf()
+		bad.SpfShortDelay = bad.SpfInitialDelay - 1
+		if ospf.ValidateSpfThrottleConf(bad) == nil {
+			fmt.Println("Short delay below initial delay accepted")
+			return FAIL
+		}
+		ospf.scheduleSpf(newAreaSpfRequest(0, false, "first"))
+		ospf.scheduleSpf(newAreaSpfRequest(1, true, "second"))
+		th := ospf.SpfThrottle
+		if th.State != SpfShortWait || !th.SpfTimerRunning || th.SpfTimerDelay != SPF_INITIAL_DELAY {
+			fmt.Println("Quiet state did not move to short wait with the initial delay ", th.State, th.SpfTimerDelay)
+			return FAIL
+		}
+		if th.Pending.Events != 2 || th.Pending.Trigger != "first" ||
+			!th.Pending.FullAreas[1] || th.DeferredEvents != 1 {
+			fmt.Println("Events not merged into one pending run ", th.Pending)
+			return FAIL
+		}
+		ospf.processSpfTimerExpiry()
+		if ospf.SpfThrottle.Runs != 1 || ospf.SpfThrottle.Pending.Events != 0 ||
+			ospf.SpfThrottle.SpfTimerRunning {
+			fmt.Println("Pending SPF not run on timer expiry")
+			return FAIL
+		}
+		ospf.scheduleSpf(newAreaSpfRequest(0, false, "third"))
+		if ospf.SpfThrottle.State != SpfShortWait || ospf.SpfThrottle.SpfTimerDelay != SPF_SHORT_DELAY {
+			fmt.Println("Event in short wait not delayed by the short delay ", ospf.SpfThrottle.SpfTimerDelay)
+			return FAIL
+		}
+		ospf.processSpfLearnTimerExpiry()
+		if ospf.SpfThrottle.State != SpfLongWait {
+			fmt.Println("Time to learn expiry did not move to long wait")
+			return FAIL
+		}
+		ospf.processSpfTimerExpiry()
+		ospf.scheduleSpf(newAreaSpfRequest(0, false, "fourth"))
+		if ospf.SpfThrottle.State != SpfLongWait || ospf.SpfThrottle.SpfTimerDelay != SPF_LONG_DELAY {
+			fmt.Println("Event in long wait not delayed by the long delay ", ospf.SpfThrottle.SpfTimerDelay)
+			return FAIL
+		}
+		ospf.processSpfTimerExpiry()
+		if ospf.SpfThrottle.Runs != 3 {
+			fmt.Println("Unexpected SPF runs ", ospf.SpfThrottle.Runs)
+			return FAIL
+		}
+		ospf.processSpfHolddownTimerExpiry()
+		if ospf.SpfThrottle.State != SpfQuiet {
+			fmt.Println("Holddown expiry did not return to quiet")
+			return FAIL
+		}
+		stopTimer(ospf.SpfThrottle.SpfTimer)
+		stopTimer(ospf.SpfThrottle.HolddownTimer)
+		stopTimer(ospf.SpfThrottle.HolddownTimer)
+
+	case 2:
+		fmt.Println(tNum, ": Running getLsaSpfRequest")
+		lsDbEnt := ospf.AreaLsdb[lsdbKey]
+		if lsDbEnt.RouterLsaMap == nil {
+			lsDbEnt.RouterLsaMap = make(map[LsaKey]RouterLsa)
+		}
+		transit := LinkDetail{LinkType: TransitLink, LinkId: 0x0a010101, LinkData: 0x0a010102, LinkMetric: 10}
+		stub := LinkDetail{LinkType: StubLink, LinkId: 0x0a020200, LinkData: 0xffffff00, LinkMetric: 10}
+		lsDbEnt.RouterLsaMap[lsaKey] = RouterLsa{LinkDetails: []LinkDetail{transit, stub}}
+		ospf.AreaLsdb[lsdbKey] = lsDbEnt
+
+		before := ospf.getRouterLsaTopology(0, lsaKey)
+		stub.LinkMetric = 20
+		lsDbEnt.RouterLsaMap[lsaKey] = RouterLsa{LinkDetails: []LinkDetail{transit, stub}}
+		if req := ospf.getLsaSpfRequest(0, lsaKey, before); req.FullAreas[0] {
+			fmt.Println("Stub link change asked for Dijkstra")
+			return FAIL
+		}
+		transit.LinkMetric = 20
+		lsDbEnt.RouterLsaMap[lsaKey] = RouterLsa{LinkDetails: []LinkDetail{transit, stub}}
+		if req := ospf.getLsaSpfRequest(0, lsaKey, before); !req.FullAreas[0] {
+			fmt.Println("Transit link change did not ask for Dijkstra")
+			return FAIL
+		}
+		summaryKey := LsaKey{LSType: Summary3LSA, LSId: 0x0a030300, AdvRouter: rtrId}
+		if req := ospf.getLsaSpfRequest(0, summaryKey, rtrLsaTopology{}); req.FullAreas[0] {
+			fmt.Println("Summary LSA asked for Dijkstra")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running restoreSpfAreaCache")
+		root := VertexKey{Type: RouterVertex, ID: rtrId, AdvRtr: rtrId}
+		areaIdKey := AreaIdKey{AreaId: 0}
+		ospf.initialiseSPFStructs()
+		ospf.SPFTree[root] = TreeVertex{Distance: 0, NumOfPaths: 1, Paths: []Path{Path{root}}}
+		rKey := RoutingTblEntryKey{DestId: 0x0a010100, AddrMask: 0xffffff00, DestType: Network}
+		nextHop := NextHop{IfIPAddr: 0x0a010102}
+		rTbl := AreaRoutingTbl{RoutingTblMap: make(map[RoutingTblEntryKey]RoutingTblEntry)}
+		rTbl.RoutingTblMap[rKey] = RoutingTblEntry{Cost: 10, NextHops: map[NextHop]bool{nextHop: true}}
+		ospf.TempAreaRoutingTbl[areaIdKey] = rTbl
+		ospf.saveSpfAreaCache(0, root)
+
+		ospf.initialiseSPFStructs()
+		ospf.TempAreaRoutingTbl[areaIdKey] = AreaRoutingTbl{RoutingTblMap: make(map[RoutingTblEntryKey]RoutingTblEntry)}
+		vKey := ospf.restoreSpfAreaCache(0, ospf.SpfAreaCache[0])
+		if vKey != root || len(ospf.SPFTree) != 1 {
+			fmt.Println("Shortest path tree not restored ", vKey)
+			return FAIL
+		}
+		stubKey := VertexKey{Type: SNetworkVertex, ID: 0x0a020200, AdvRtr: rtrId}
+		if stubEnt, exist := ospf.AreaStubs[stubKey]; !exist || stubEnt.NbrVertexCost != 20 {
+			fmt.Println("Stub links not read from the LSDB ", ospf.AreaStubs)
+			return FAIL
+		}
+		restored := ospf.TempAreaRoutingTbl[areaIdKey].RoutingTblMap[rKey]
+		delete(restored.NextHops, nextHop)
+		if len(ospf.SpfAreaCache[0].IntraRoutes[rKey].NextHops) != 1 {
+			fmt.Println("Cached intra area routes modified by a partial run")
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running recordSpfRun")
+		areaConfKey := AreaConfKey{AreaId: config.AreaId("0.0.0.0")}
+		ospf.initAreaStateSlice(areaConfKey)
+		req := SpfRequest{Trigger: getSpfTrigger(lsaKey), Events: 3}
+		for i := 0; i < SPF_HISTORY_LEN+4; i++ {
+			ospf.recordSpfRun(req, map[uint32]bool{0: i%2 == 0}, time.Now(), 1)
+		}
+		ent := ospf.AreaStateMap[areaConfKey]
+		if ent.SpfRuns != (SPF_HISTORY_LEN+4)/2 || ent.SpfPartialRuns != (SPF_HISTORY_LEN+4)/2 {
+			fmt.Println("Unexpected SPF run counters ", ent.SpfRuns, ent.SpfPartialRuns)
+			return FAIL
+		}
+		if len(ent.SpfHistory) != SPF_HISTORY_LEN || ent.SpfHistory[0].Trigger != req.Trigger {
+			fmt.Println("SPF history not bounded ", len(ent.SpfHistory))
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
@@ -0,0 +1,489 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* Packet statistics and neighbor event log.
+   Packets are received on a goroutine per packet, hence the counters
+   are protected by StatsMutex.
+   Drops are also counted per source address on the interface, hello
+   mismatches happen before the neighbor exists and the neighbor
+   counters go away with the neighbor.
+*/
+
+const (
+	NBR_EVENT_LOG_SIZE  = 256
+	SRC_DROP_STATS_SIZE = 32 // sources tracked per interface
+)
+
+type PktDropReason int
+
+const (
+	DropBadLength             PktDropReason = 0
+	DropBadVersion            PktDropReason = 1
+	DropBadChecksum           PktDropReason = 2
+	DropAreaMismatch          PktDropReason = 3
+	DropAuthFailure           PktDropReason = 4
+	DropNetmaskMismatch       PktDropReason = 5
+	DropHelloIntervalMismatch PktDropReason = 6
+	DropDeadIntervalMismatch  PktDropReason = 7
+	DropOptionsMismatch       PktDropReason = 8
+	DropMtuMismatch           PktDropReason = 9
+	DropUnknownNbr            PktDropReason = 10
+	DropBadPktType            PktDropReason = 11
+)
+
+var PktDropReasonList = []string{
+	"BadLength",
+	"BadVersion",
+	"BadChecksum",
+	"AreaMismatch",
+	"AuthFailure",
+	"NetmaskMismatch",
+	"HelloIntervalMismatch",
+	"DeadIntervalMismatch",
+	"OptionsMismatch",
+	"MtuMismatch",
+	"UnknownNbr",
+	"BadPktType"}
+
+type IntfStats struct {
+	Stats          config.PktStats
+	LastDropReason PktDropReason
+	LastDropSrc    string
+	LastDropTime   time.Time
+	SrcDrops       map[string]SrcDropStats
+}
+
+type SrcDropStats struct {
+	Stats          config.PktStats // drop counters only
+	LastDropReason PktDropReason
+	LastDropTime   time.Time
+}
+
+type NbrStats struct {
+	Stats        config.PktStats
+	State        config.NbrState
+	StateChanges uint32
+	FullTime     time.Time // zero when the adjacency is not full
+}
+
+type NbrEventRecord struct {
+	EventIdx      int32
+	Time          time.Time
+	NbrKey        NeighborConfKey
+	NbrRtrId      uint32
+	OldState      config.NbrState
+	NewState      config.NbrState
+	Reason        string
+	LsRetransQLen int
+}
+
+func (server *OSPFServer) initStats() {
+	server.IntfStatsMap = make(map[IntfConfKey]IntfStats)
+	server.NbrStatsMap = make(map[NeighborConfKey]NbrStats)
+	server.NbrEventLog = []NbrEventRecord{}
+}
+
+func incPktStats(stats *config.PktStats, pktType OspfType, tx bool) {
+	switch pktType {
+	case HelloType:
+		if tx {
+			stats.TxHello++
+		} else {
+			stats.RxHello++
+		}
+	case DBDescriptionType:
+		if tx {
+			stats.TxDbd++
+		} else {
+			stats.RxDbd++
+		}
+	case LSRequestType:
+		if tx {
+			stats.TxLsReq++
+		} else {
+			stats.RxLsReq++
+		}
+	case LSUpdateType:
+		if tx {
+			stats.TxLsUpd++
+		} else {
+			stats.RxLsUpd++
+		}
+	case LSAckType:
+		if tx {
+			stats.TxLsAck++
+		} else {
+			stats.RxLsAck++
+		}
+	}
+}
+
+func incDropStats(stats *config.PktStats, reason PktDropReason) {
+	switch reason {
+	case DropBadLength:
+		stats.RxBadLength++
+	case DropBadVersion:
+		stats.RxBadVersion++
+	case DropBadChecksum:
+		stats.RxBadChecksum++
+	case DropAreaMismatch:
+		stats.RxAreaMismatch++
+	case DropAuthFailure:
+		stats.RxAuthFailure++
+	case DropNetmaskMismatch:
+		stats.RxNetmaskMismatch++
+	case DropHelloIntervalMismatch:
+		stats.RxHelloIntervalMismatch++
+	case DropDeadIntervalMismatch:
+		stats.RxDeadIntervalMismatch++
+	case DropOptionsMismatch:
+		stats.RxOptionsMismatch++
+	case DropMtuMismatch:
+		stats.RxMtuMismatch++
+	case DropUnknownNbr:
+		stats.RxUnknownNbr++
+	case DropBadPktType:
+		stats.RxBadPktType++
+	}
+}
+
+func getStatsNbrKey(key IntfConfKey, nbrIp net.IP) NeighborConfKey {
+	return NeighborConfKey{
+		IPAddr:  config.IpAddress(nbrIp.String()),
+		IntfIdx: key.IntfIdx,
+	}
+}
+
+/* @fn countPkt
+Counts a packet sent to or received from nbrIp on the interface.
+Neighbor counters are only kept for known neighbors.
+*/
+func (server *OSPFServer) countPkt(key IntfConfKey, nbrIp net.IP, pktType OspfType, tx bool) {
+	server.StatsMutex.Lock()
+	defer server.StatsMutex.Unlock()
+	intfStats := server.IntfStatsMap[key]
+	incPktStats(&intfStats.Stats, pktType, tx)
+	server.IntfStatsMap[key] = intfStats
+	if nbrIp == nil {
+		return
+	}
+	nbrKey := getStatsNbrKey(key, nbrIp)
+	if nbrStats, exist := server.NbrStatsMap[nbrKey]; exist {
+		incPktStats(&nbrStats.Stats, pktType, tx)
+		server.NbrStatsMap[nbrKey] = nbrStats
+	}
+}
+
+func (server *OSPFServer) countRxDrop(key IntfConfKey, nbrIp net.IP, reason PktDropReason) {
+	server.StatsMutex.Lock()
+	defer server.StatsMutex.Unlock()
+	intfStats := server.IntfStatsMap[key]
+	incDropStats(&intfStats.Stats, reason)
+	intfStats.LastDropReason = reason
+	intfStats.LastDropSrc = ""
+	if nbrIp != nil {
+		intfStats.LastDropSrc = nbrIp.String()
+	}
+	intfStats.LastDropTime = time.Now()
+	if nbrIp != nil {
+		countSrcDrop(&intfStats, nbrIp.String(), reason)
+	}
+	server.IntfStatsMap[key] = intfStats
+	if nbrIp == nil {
+		return
+	}
+	nbrKey := getStatsNbrKey(key, nbrIp)
+	if nbrStats, exist := server.NbrStatsMap[nbrKey]; exist {
+		incDropStats(&nbrStats.Stats, reason)
+		server.NbrStatsMap[nbrKey] = nbrStats
+	}
+}
+
+/* @fn countSrcDrop
+Counts the drop against the source address. Once SRC_DROP_STATS_SIZE
+sources are tracked on the interface the one which dropped least
+recently is replaced.
+*/
+func countSrcDrop(intfStats *IntfStats, src string, reason PktDropReason) {
+	if intfStats.SrcDrops == nil {
+		intfStats.SrcDrops = make(map[string]SrcDropStats)
+	}
+	srcStats, exist := intfStats.SrcDrops[src]
+	if !exist && len(intfStats.SrcDrops) >= SRC_DROP_STATS_SIZE {
+		oldest := ""
+		for ent, entStats := range intfStats.SrcDrops {
+			if oldest == "" || entStats.LastDropTime.Before(intfStats.SrcDrops[oldest].LastDropTime) {
+				oldest = ent
+			}
+		}
+		delete(intfStats.SrcDrops, oldest)
+	}
+	incDropStats(&srcStats.Stats, reason)
+	srcStats.LastDropReason = reason
+	srcStats.LastDropTime = intfStats.LastDropTime
+	intfStats.SrcDrops[src] = srcStats
+}
+
+/* @fn countTxPkt
+Counts a packet written on the interface. The packet starts with
+the ethernet header.
+*/
+func (server *OSPFServer) countTxPkt(key IntfConfKey, ospfPkt []byte) {
+	if len(ospfPkt) < 34 {
+		return
+	}
+	ipHdrLen := int(ospfPkt[14]&0x0f) * 4
+	if len(ospfPkt) < 14+ipHdrLen+2 {
+		return
+	}
+	pktType := OspfType(ospfPkt[14+ipHdrLen+1])
+	dstIp := net.IP(ospfPkt[30:34])
+	if dstIp.IsMulticast() {
+		dstIp = nil
+	}
+	server.countPkt(key, dstIp, pktType, true)
+}
+
+func (server *OSPFServer) deleteIntfStats(key IntfConfKey) {
+	server.StatsMutex.Lock()
+	delete(server.IntfStatsMap, key)
+	server.StatsMutex.Unlock()
+}
+
+func (server *OSPFServer) getNbrRetxQLen(nbrKey NeighborConfKey) int {
+	nbrConf, exist := server.NeighborConfigMap[nbrKey]
+	if !exist || nbrConf.retx_list_mutex == nil {
+		return 0
+	}
+	qLen := 0
+	nbrConf.retx_list_mutex.Lock()
+	for _, ent := range ospfNeighborRetx_list[nbrKey] {
+		if ent.valid {
+			qLen++
+		}
+	}
+	nbrConf.retx_list_mutex.Unlock()
+	return qLen
+}
+
+/* @fn getNbrStateChangeReason
+RFC 2328 10.2 event which moves the neighbor between the states,
+used when the caller gives no reason.
+*/
+func getNbrStateChangeReason(oldState config.NbrState, newState config.NbrState) string {
+	switch newState {
+	case config.NbrDown:
+		return "InactivityTimer"
+	case config.NbrAttempt:
+		return "Start"
+	case config.NbrInit:
+		if oldState > config.NbrInit {
+			return "1-WayReceived"
+		}
+		return "HelloReceived"
+	case config.NbrTwoWay:
+		if oldState > config.NbrTwoWay {
+			return "AdjOK?"
+		}
+		return "2-WayReceived"
+	case config.NbrExchangeStart:
+		if oldState >= config.NbrExchange {
+			return "SeqNumberMismatch"
+		}
+		if oldState == config.NbrTwoWay {
+			return "AdjOK?"
+		}
+		return "2-WayReceived"
+	case config.NbrExchange:
+		return "NegotiationDone"
+	case config.NbrLoading:
+		return "ExchangeDone"
+	case config.NbrFull:
+		if oldState == config.NbrLoading {
+			return "LoadingDone"
+		}
+		return "ExchangeDone"
+	}
+	return ""
+}
+
+/* @fn recordNbrState
+Logs the neighbor FSM transition if the state changed. The counters of
+the neighbor are removed when it goes down, the event log keeps its
+history.
+*/
+func (server *OSPFServer) recordNbrState(nbrKey NeighborConfKey, nbrRtrId uint32, state config.NbrState, reason string) {
+	qLen := server.getNbrRetxQLen(nbrKey)
+	server.StatsMutex.Lock()
+	defer server.StatsMutex.Unlock()
+	nbrStats, exist := server.NbrStatsMap[nbrKey]
+	oldState := config.NbrDown
+	if exist {
+		oldState = nbrStats.State
+	}
+	if oldState == state {
+		return
+	}
+	if reason == "" {
+		reason = getNbrStateChangeReason(oldState, state)
+	}
+	now := time.Now()
+	server.NbrEventIdx++
+	record := NbrEventRecord{
+		EventIdx:      server.NbrEventIdx,
+		Time:          now,
+		NbrKey:        nbrKey,
+		NbrRtrId:      nbrRtrId,
+		OldState:      oldState,
+		NewState:      state,
+		Reason:        reason,
+		LsRetransQLen: qLen,
+	}
+	server.NbrEventLog = append(server.NbrEventLog, record)
+	if len(server.NbrEventLog) > NBR_EVENT_LOG_SIZE {
+		server.NbrEventLog = server.NbrEventLog[len(server.NbrEventLog)-NBR_EVENT_LOG_SIZE:]
+	}
+	server.logger.Info(fmt.Sprintln("NBREVENT: neighbor", nbrKey.IPAddr, config.NbrStateList[oldState],
+		"->", config.NbrStateList[state], "reason", reason))
+
+	if state == config.NbrDown {
+		delete(server.NbrStatsMap, nbrKey)
+		return
+	}
+	nbrStats.State = state
+	nbrStats.StateChanges++
+	if state == config.NbrFull {
+		nbrStats.FullTime = now
+	} else {
+		nbrStats.FullTime = time.Time{}
+	}
+	server.NbrStatsMap[nbrKey] = nbrStats
+}
+
+func (server *OSPFServer) getIntfStatsState(key IntfConfKey) config.IfStatsState {
+	server.StatsMutex.RLock()
+	intfStats := server.IntfStatsMap[key]
+	server.StatsMutex.RUnlock()
+	state := config.IfStatsState{
+		IfIpAddress:   key.IPAddr,
+		AddressLessIf: key.IntfIdx,
+		Stats:         intfStats.Stats,
+	}
+	if !intfStats.LastDropTime.IsZero() {
+		state.LastDropReason = PktDropReasonList[intfStats.LastDropReason]
+		state.LastDropSrc = config.IpAddress(intfStats.LastDropSrc)
+		state.LastDropTime = intfStats.LastDropTime.String()
+	}
+	return state
+}
+
+func (server *OSPFServer) getNbrStatsState(nbrKey NeighborConfKey) config.NbrStatsState {
+	nbrConf := server.NeighborConfigMap[nbrKey]
+	state := config.NbrStatsState{
+		NbrIpAddress:        nbrKey.IPAddr,
+		NbrAddressLessIndex: int(nbrKey.IntfIdx),
+		NbrRtrId:            convertUint32ToIPv4(nbrConf.OspfNbrRtrId),
+		NbrState:            config.NbrStateList[int(nbrConf.OspfNbrState)%len(config.NbrStateList)],
+		NbrLsRetransQLen:    server.getNbrRetxQLen(nbrKey),
+	}
+	server.StatsMutex.RLock()
+	nbrStats := server.NbrStatsMap[nbrKey]
+	server.StatsMutex.RUnlock()
+	state.Stats = nbrStats.Stats
+	state.NbrStateChanges = nbrStats.StateChanges
+	if !nbrStats.FullTime.IsZero() {
+		state.NbrUptime = int32(time.Since(nbrStats.FullTime).Seconds())
+	}
+	return state
+}
+
+/* @fn getSrcDropStatsState
+Per source drop counters of all the interfaces, sorted by interface
+and source address.
+*/
+func (server *OSPFServer) getSrcDropStatsState() []config.SrcDropStatsState {
+	server.StatsMutex.RLock()
+	defer server.StatsMutex.RUnlock()
+	result := []config.SrcDropStatsState{}
+	for key, intfStats := range server.IntfStatsMap {
+		for src, srcStats := range intfStats.SrcDrops {
+			result = append(result, config.SrcDropStatsState{
+				IfIpAddress:    key.IPAddr,
+				AddressLessIf:  key.IntfIdx,
+				SrcIpAddress:   config.IpAddress(src),
+				Stats:          srcStats.Stats,
+				LastDropReason: PktDropReasonList[srcStats.LastDropReason],
+				LastDropTime:   srcStats.LastDropTime.String(),
+			})
+		}
+	}
+	sort.Sort(srcDropStatsStateSlice(result))
+	return result
+}
+
+type intfConfKeyList []IntfConfKey
+
+func (l intfConfKeyList) Len() int      { return len(l) }
+func (l intfConfKeyList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l intfConfKeyList) Less(i, j int) bool {
+	if l[i].IPAddr != l[j].IPAddr {
+		return strings.Compare(string(l[i].IPAddr), string(l[j].IPAddr)) < 0
+	}
+	return l[i].IntfIdx < l[j].IntfIdx
+}
+
+type nbrConfKeyList []NeighborConfKey
+
+func (l nbrConfKeyList) Len() int      { return len(l) }
+func (l nbrConfKeyList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l nbrConfKeyList) Less(i, j int) bool {
+	if l[i].IntfIdx != l[j].IntfIdx {
+		return l[i].IntfIdx < l[j].IntfIdx
+	}
+	return strings.Compare(string(l[i].IPAddr), string(l[j].IPAddr)) < 0
+}
+
+type srcDropStatsStateSlice []config.SrcDropStatsState
+
+func (s srcDropStatsStateSlice) Len() int      { return len(s) }
+func (s srcDropStatsStateSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s srcDropStatsStateSlice) Less(i, j int) bool {
+	if s[i].IfIpAddress != s[j].IfIpAddress {
+		return strings.Compare(string(s[i].IfIpAddress), string(s[j].IfIpAddress)) < 0
+	}
+	if s[i].AddressLessIf != s[j].AddressLessIf {
+		return s[i].AddressLessIf < s[j].AddressLessIf
+	}
+	return strings.Compare(string(s[i].SrcIpAddress), string(s[j].SrcIpAddress)) < 0
+}
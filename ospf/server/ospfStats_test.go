@@ -0,0 +1,251 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfStats_test
+   This test covers
+   1) Interface and neighbor packet counters.
+   2) Counters of the packets sent.
+   3) Hello dropped because of a hello interval mismatch.
+   4) Neighbor event log, state change reasons and adjacency uptime.
+   5) Event log size and neighbor down.
+   6) Statistics GetBulk APIs.
+   7) Per source drop counters kept after neighbor down and bounded.
+*/
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"testing"
+)
+
+var statsNbrIp net.IP
+
+func initStatsTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+
+	statsIntf := intf
+	statsIntf.IfNetmask = []byte{255, 255, 255, 0}
+	ospf.IntfConfMap[key] = statsIntf
+	statsNbrIp = net.IP{10, 1, 1, 2}
+	ospf.NeighborConfigMap[nbrKey] = nbrConf
+	ospfNeighborRetx_list = make(map[NeighborConfKey][]*ospfNeighborRetx)
+}
+
+func getStatsTxPkt(dstIp net.IP, pktType OspfType) []byte {
+	pkt := make([]byte, 14+20+OSPF_HEADER_SIZE)
+	pkt[14] = 0x45
+	copy(pkt[30:34], dstIp.To4())
+	pkt[14+20] = OSPF_VERSION_2
+	pkt[14+20+1] = uint8(pktType)
+	return pkt
+}
+
+func TestOspfStats(t *testing.T) {
+	fmt.Println("\n**************** PACKET STATISTICS ************\n")
+	initStatsTestParams()
+	for index := 1; index < 8; index++ {
+		err := statsTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for packet statistics ", index)
+		}
+	}
+}
+
+func statsTestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running countPkt/countRxDrop")
+		ospf.countPkt(key, statsNbrIp, HelloType, false)
+		ospf.countRxDrop(key, statsNbrIp, DropAreaMismatch)
+		intfStats := ospf.IntfStatsMap[key]
+		if intfStats.Stats.RxHello != 1 || intfStats.Stats.RxAreaMismatch != 1 ||
+			intfStats.LastDropReason != DropAreaMismatch || intfStats.LastDropSrc != "10.1.1.2" {
+			fmt.Println("Invalid interface counters ", intfStats)
+			return FAIL
+		}
+		if _, exist := ospf.NbrStatsMap[nbrKey]; exist {
+			fmt.Println("Counters kept for a neighbor which is not up")
+			return FAIL
+		}
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrInit, "")
+		ospf.countPkt(key, statsNbrIp, DBDescriptionType, false)
+		ospf.countRxDrop(key, statsNbrIp, DropMtuMismatch)
+		nbrStats := ospf.NbrStatsMap[nbrKey]
+		if nbrStats.Stats.RxDbd != 1 || nbrStats.Stats.RxMtuMismatch != 1 ||
+			nbrStats.Stats.RxHello != 0 {
+			fmt.Println("Invalid neighbor counters ", nbrStats)
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running countTxPkt")
+		ospf.countTxPkt(key, getStatsTxPkt(net.IP{224, 0, 0, 5}, HelloType))
+		ospf.countTxPkt(key, getStatsTxPkt(statsNbrIp, LSUpdateType))
+		ospf.countTxPkt(key, []byte{1, 2, 3})
+		intfStats := ospf.IntfStatsMap[key]
+		nbrStats := ospf.NbrStatsMap[nbrKey]
+		if intfStats.Stats.TxHello != 1 || intfStats.Stats.TxLsUpd != 1 ||
+			nbrStats.Stats.TxHello != 0 || nbrStats.Stats.TxLsUpd != 1 {
+			fmt.Println("Invalid tx counters ", intfStats, nbrStats)
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running hello interval mismatch")
+		data := make([]byte, OSPF_HELLO_MIN_SIZE)
+		copy(data[0:4], []byte{255, 255, 255, 0})
+		binary.BigEndian.PutUint16(data[4:6], intf.IfHelloInterval+1)
+		binary.BigEndian.PutUint32(data[8:12], intf.IfRtrDeadInterval)
+		hdrMd := OspfHdrMetadata{
+			pktType: HelloType,
+			pktlen:  uint16(OSPF_HEADER_SIZE + OSPF_HELLO_MIN_SIZE),
+		}
+		ipMd := IpHdrMetadata{
+			srcIP: statsNbrIp,
+		}
+		ethMd := EthHdrMetadata{}
+		err := ospf.processRxHelloPkt(data, &hdrMd, &ipMd, &ethMd, key)
+		intfStats := ospf.IntfStatsMap[key]
+		if err == nil || intfStats.Stats.RxHelloIntervalMismatch != 1 ||
+			intfStats.LastDropReason != DropHelloIntervalMismatch {
+			fmt.Println("Hello interval mismatch not counted ", intfStats, err)
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running recordNbrState")
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrExchangeStart, "")
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrExchange, "")
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrExchange, "")
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrFull, "")
+		log := ospf.NbrEventLog
+		if len(log) != 4 || log[1].Reason != "2-WayReceived" ||
+			log[2].Reason != "NegotiationDone" || log[3].Reason != "ExchangeDone" ||
+			log[3].OldState != config.NbrExchange {
+			fmt.Println("Invalid event log ", log)
+			return FAIL
+		}
+		nbrStats := ospf.NbrStatsMap[nbrKey]
+		if nbrStats.StateChanges != 4 || nbrStats.FullTime.IsZero() {
+			fmt.Println("Invalid neighbor state ", nbrStats)
+			return FAIL
+		}
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrExchangeStart, "")
+		if ospf.NbrEventLog[4].Reason != "SeqNumberMismatch" ||
+			!ospf.NbrStatsMap[nbrKey].FullTime.IsZero() {
+			fmt.Println("Invalid adjacency reset ", ospf.NbrEventLog[4])
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running neighbor down and event log size")
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrDown, "BFD session down")
+		if _, exist := ospf.NbrStatsMap[nbrKey]; exist {
+			fmt.Println("Neighbor counters not removed")
+			return FAIL
+		}
+		last := ospf.NbrEventLog[len(ospf.NbrEventLog)-1]
+		if last.NewState != config.NbrDown || last.Reason != "BFD session down" {
+			fmt.Println("Invalid down event ", last)
+			return FAIL
+		}
+		for i := 0; i < NBR_EVENT_LOG_SIZE; i++ {
+			ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrInit, "")
+			ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrDown, "")
+		}
+		if len(ospf.NbrEventLog) != NBR_EVENT_LOG_SIZE ||
+			ospf.NbrEventLog[NBR_EVENT_LOG_SIZE-1].EventIdx != ospf.NbrEventIdx {
+			fmt.Println("Event log not bounded ", len(ospf.NbrEventLog))
+			return FAIL
+		}
+
+	case 6:
+		fmt.Println(tNum, ": Running statistics GetBulk")
+		found := false
+		_, _, ifStats := ospf.GetBulkOspfIfStatsState(0, 10)
+		for _, ent := range ifStats {
+			if ent.IfIpAddress == key.IPAddr && ent.AddressLessIf == key.IntfIdx {
+				found = ent.Stats.TxHello == 1 && ent.LastDropReason == "HelloIntervalMismatch"
+			}
+		}
+		if !found {
+			fmt.Println("Invalid interface statistics ", ifStats)
+			return FAIL
+		}
+		found = false
+		ospf.recordNbrState(nbrKey, nbrConf.OspfNbrRtrId, config.NbrInit, "")
+		_, _, nbrStats := ospf.GetBulkOspfNbrStatsState(0, 10)
+		for _, ent := range nbrStats {
+			if ent.NbrIpAddress == nbrKey.IPAddr {
+				found = ent.NbrRtrId == "0.0.0.20" && ent.NbrStateChanges == 1
+			}
+		}
+		if !found {
+			fmt.Println("Invalid neighbor statistics ", nbrStats)
+			return FAIL
+		}
+		nextIdx, count, events := ospf.GetBulkOspfNbrEventState(0, 10)
+		if nextIdx != 10 || count != 10 || events[0].EventIdx != ospf.NbrEventLog[0].EventIdx {
+			fmt.Println("Invalid event log page ", nextIdx, count)
+			return FAIL
+		}
+		nextIdx, count, events = ospf.GetBulkOspfNbrEventState(NBR_EVENT_LOG_SIZE-5, 10)
+		if nextIdx != 0 || count != 5 || events[4].NewState != "NbrInit" {
+			fmt.Println("Invalid last event log page ", nextIdx, count, events)
+			return FAIL
+		}
+
+	case 7:
+		fmt.Println(tNum, ": Running per source drop counters")
+		srcStats, exist := ospf.IntfStatsMap[key].SrcDrops[statsNbrIp.String()]
+		if !exist || srcStats.Stats.RxHelloIntervalMismatch != 1 ||
+			srcStats.Stats.RxMtuMismatch != 1 || srcStats.Stats.RxAreaMismatch != 1 {
+			fmt.Println("Source drops not kept after neighbor down ", srcStats)
+			return FAIL
+		}
+		for i := 0; i <= SRC_DROP_STATS_SIZE; i++ {
+			ospf.countRxDrop(key, net.IP{10, 1, 2, byte(i)}, DropNetmaskMismatch)
+		}
+		srcDrops := ospf.IntfStatsMap[key].SrcDrops
+		if len(srcDrops) != SRC_DROP_STATS_SIZE ||
+			srcDrops["10.1.2.32"].Stats.RxNetmaskMismatch != 1 {
+			fmt.Println("Source drops not bounded ", len(srcDrops))
+			return FAIL
+		}
+		nextIdx, count, states := ospf.GetBulkOspfSrcDropStatsState(0, 2*SRC_DROP_STATS_SIZE)
+		if nextIdx != 0 || count != SRC_DROP_STATS_SIZE ||
+			states[0].SrcIpAddress >= states[1].SrcIpAddress ||
+			states[0].LastDropReason != "NetmaskMismatch" {
+			fmt.Println("Invalid source drop GetBulk ", nextIdx, count)
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
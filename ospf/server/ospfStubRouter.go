@@ -0,0 +1,329 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"time"
+)
+
+/* Stub router advertisement (RFC 6987).
+   While active the transit links of the router LSAs are advertised with
+   MaxLinkMetric, the other routers only forward through this router when
+   there is no other path. Stub links keep their cost so the local networks
+   stay reachable. Summary and external LSAs can be advertised with
+   StubRouterLsaMetric as well.
+   Startup : max-metric is advertised after ospfd comes up until the startup
+   timer expires or, with WaitForBgp, BGP signals that it has converged.
+   Administrative : max-metric is advertised until the configuration is
+   removed, used to drain the router before maintenance.
+*/
+
+const (
+	MaxLinkMetric       uint16 = 0xffff
+	StubRouterLsaMetric uint32 = 0xff0000
+
+	DEFAULT_STUB_ROUTER_STARTUP_TIME int32 = 600
+	MAX_STUB_ROUTER_STARTUP_TIME     int32 = 86400
+)
+
+const (
+	StubRouterInactive = 0
+	StubRouterStartup  = 1
+	StubRouterAdmin    = 2
+)
+
+var StubRouterStateList = []string{
+	"Inactive",
+	"Startup",
+	"Administrative",
+}
+
+type StubRouter struct {
+	Conf          config.StubRouterConf
+	State         int
+	StartTime     time.Time // start of the LSDB, begin of the startup period
+	StartupActive bool
+	StartupDone   bool // startup timer expired or BGP converged
+	StartupTimer  *time.Timer
+}
+
+func DefaultStubRouterConf() config.StubRouterConf {
+	return config.StubRouterConf{
+		OnStartup:   false,
+		StartupTime: DEFAULT_STUB_ROUTER_STARTUP_TIME,
+	}
+}
+
+func (server *OSPFServer) initStubRouter() {
+	server.StubRouterConfigCh = make(chan config.StubRouterConf)
+	server.BgpConvergedCh = make(chan bool, 1)
+	server.StubRouter = StubRouter{
+		Conf:         DefaultStubRouterConf(),
+		State:        StubRouterInactive,
+		StartupTimer: newStoppedTimer(),
+	}
+}
+
+func (sr *StubRouter) getState() int {
+	if sr.Conf.Administrative {
+		return StubRouterAdmin
+	}
+	if sr.StartupActive {
+		return StubRouterStartup
+	}
+	return StubRouterInactive
+}
+
+func (sr *StubRouter) isActive() bool {
+	return sr.State != StubRouterInactive
+}
+
+/* @fn armStubRouterStartup
+Max-metric is advertised for what is left of the startup period
+when it is configured after ospfd came up.
+*/
+func (sr *StubRouter) armStubRouterStartup() {
+	sr.StartupActive = false
+	stopTimer(sr.StartupTimer)
+	if !sr.Conf.OnStartup || sr.StartupDone || sr.StartTime.IsZero() {
+		return
+	}
+	remain := time.Duration(sr.Conf.StartupTime)*time.Second - time.Since(sr.StartTime)
+	if remain <= 0 {
+		sr.StartupDone = true
+		return
+	}
+	sr.StartupActive = true
+	sr.StartupTimer.Reset(remain)
+}
+
+func (server *OSPFServer) ValidateStubRouterConf(conf config.StubRouterConf) error {
+	if conf.OnStartup &&
+		(conf.StartupTime <= 0 || conf.StartupTime > MAX_STUB_ROUTER_STARTUP_TIME) {
+		return errors.New(fmt.Sprintln("Invalid stub router startup time", conf.StartupTime))
+	}
+	if conf.WaitForBgp && !conf.OnStartup {
+		return errors.New("Waiting for BGP needs stub router on startup")
+	}
+	return nil
+}
+
+/* @fn startStubRouter
+Begins the startup period, called when the LSDB is started.
+No LSAs are originated yet so nothing is refreshed.
+*/
+func (server *OSPFServer) startStubRouter() {
+	server.StubRouterMutex.Lock()
+	sr := &server.StubRouter
+	sr.StartTime = time.Now()
+	sr.StartupDone = false
+	sr.armStubRouterStartup()
+	sr.State = sr.getState()
+	server.StubRouterMutex.Unlock()
+	server.setStubRouterAdvertisement()
+}
+
+func (server *OSPFServer) processStubRouterConfig(conf config.StubRouterConf) error {
+	err := server.ValidateStubRouterConf(conf)
+	if err != nil {
+		return err
+	}
+	server.updateStubRouter("configuration", func(sr *StubRouter) {
+		sr.Conf = conf
+		sr.armStubRouterStartup()
+	})
+	return nil
+}
+
+func (server *OSPFServer) processStubRouterTimerExpiry() {
+	server.updateStubRouter("startup timer expiry", func(sr *StubRouter) {
+		sr.StartupActive = false
+		sr.StartupDone = true
+	})
+}
+
+func (server *OSPFServer) processBgpConverged() {
+	server.updateStubRouter("BGP converged", func(sr *StubRouter) {
+		if !sr.StartupActive || !sr.Conf.WaitForBgp {
+			return
+		}
+		stopTimer(sr.StartupTimer)
+		sr.StartupActive = false
+		sr.StartupDone = true
+	})
+}
+
+/* @fn updateStubRouter
+Applies the change to the stub router state and re-originates the
+LSAs whose metrics are changed by it.
+*/
+func (server *OSPFServer) updateStubRouter(reason string, update func(sr *StubRouter)) {
+	server.StubRouterMutex.Lock()
+	sr := &server.StubRouter
+	oldState := sr.State
+	oldSummary := sr.isActive() && sr.Conf.SummaryLsa
+	oldExternal := sr.isActive() && sr.Conf.ExternalLsa
+	update(sr)
+	sr.State = sr.getState()
+	newSummary := sr.isActive() && sr.Conf.SummaryLsa
+	newExternal := sr.isActive() && sr.Conf.ExternalLsa
+	newState := sr.State
+	server.StubRouterMutex.Unlock()
+
+	if oldState != newState {
+		server.logger.Info(fmt.Sprintln("STUB: State ", StubRouterStateList[oldState], " -> ",
+			StubRouterStateList[newState], " reason ", reason))
+		server.setStubRouterAdvertisement()
+	}
+	if (oldState == StubRouterInactive) != (newState == StubRouterInactive) {
+		server.refreshStubRouterLsa()
+	}
+	if oldSummary != newSummary && server.ospfGlobalConf.AreaBdrRtrStatus {
+		server.GenerateSummaryLsa()
+		server.installSummaryLsa()
+	}
+	if oldExternal != newExternal {
+		server.refreshExtRouteLsa()
+	}
+}
+
+func (server *OSPFServer) setStubRouterAdvertisement() {
+	if server.isStubRouterActive() {
+		server.ospfGlobalConf.StubRouterAdvertisement = config.Advertise
+	} else {
+		server.ospfGlobalConf.StubRouterAdvertisement = config.DoNotAdvertise
+	}
+}
+
+func (server *OSPFServer) isStubRouterActive() bool {
+	server.StubRouterMutex.RLock()
+	defer server.StubRouterMutex.RUnlock()
+	return server.StubRouter.isActive()
+}
+
+/* @fn applyStubRouterMetric
+RFC 6987 2. All the router links except the stub links are advertised
+with MaxLinkMetric.
+*/
+func (server *OSPFServer) applyStubRouterMetric(linkDetails []LinkDetail) {
+	if !server.isStubRouterActive() {
+		return
+	}
+	for i := 0; i < len(linkDetails); i++ {
+		if linkDetails[i].LinkType != StubLink {
+			linkDetails[i].LinkMetric = MaxLinkMetric
+		}
+	}
+}
+
+func (server *OSPFServer) getStubRouterSummaryMetric(metric uint32) uint32 {
+	server.StubRouterMutex.RLock()
+	defer server.StubRouterMutex.RUnlock()
+	if server.StubRouter.isActive() && server.StubRouter.Conf.SummaryLsa &&
+		metric < StubRouterLsaMetric {
+		return StubRouterLsaMetric
+	}
+	return metric
+}
+
+func (server *OSPFServer) getStubRouterExtMetric(metric uint32) uint32 {
+	server.StubRouterMutex.RLock()
+	defer server.StubRouterMutex.RUnlock()
+	if server.StubRouter.isActive() && server.StubRouter.Conf.ExternalLsa &&
+		metric < StubRouterLsaMetric {
+		return StubRouterLsaMetric
+	}
+	return metric
+}
+
+/* @fn getSelfLinkMetric
+The router keeps using its own transit links at the interface cost,
+only the other routers see the max-metric.
+*/
+func (server *OSPFServer) getSelfLinkMetric(linkDetail LinkDetail) uint16 {
+	if linkDetail.LinkMetric != MaxLinkMetric ||
+		linkDetail.LinkType == StubLink {
+		return linkDetail.LinkMetric
+	}
+	for key, ent := range server.IntfConfMap {
+		if convertAreaOrRouterIdUint32(ent.IfIpAddr.String()) == linkDetail.LinkData ||
+			(key.IntfIdx != 0 && uint32(key.IntfIdx) == linkDetail.LinkData) {
+			return uint16(ent.IfCost)
+		}
+	}
+	return linkDetail.LinkMetric
+}
+
+func (server *OSPFServer) refreshStubRouterLsa() {
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	lsaKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	for lsdbKey, _ := range server.AreaLsdb {
+		server.generateRouterLSA(lsdbKey.AreaId)
+		server.sendLsdbToNeighborEvent(IntfConfKey{}, NeighborConfKey{}, lsdbKey.AreaId, 0, 0, lsaKey, LSAROUTERFLOOD)
+	}
+	server.scheduleSpf(SpfRequest{AllAreas: true, Trigger: "Stub router"})
+}
+
+/* @fn refreshExtRouteLsa
+Re-originates the redistributed routes and the summary addresses
+so that they carry the new metric.
+*/
+func (server *OSPFServer) refreshExtRouteLsa() {
+	for rKey, route := range server.ExtRouteMap {
+		if sKey, found := server.getSummaryAddr(rKey); found && sKey != rKey {
+			continue
+		}
+		server.originateExtRoute(route)
+	}
+	for sKey, ent := range server.SummaryAddrMap {
+		if !ent.Originated {
+			continue
+		}
+		aggr := RouteMdata{
+			metric: ent.Metric,
+			ipaddr: sKey.Net,
+			mask:   sKey.Mask,
+			tag:    ent.OrigTag,
+		}
+		server.originateExtRoute(aggr)
+	}
+}
+
+func (server *OSPFServer) getStubRouterState(result *config.GlobalState) {
+	server.StubRouterMutex.RLock()
+	defer server.StubRouterMutex.RUnlock()
+	sr := server.StubRouter
+	result.StubRouterStatus = StubRouterStateList[sr.State]
+	result.StubRouterRemain = 0
+	if sr.State == StubRouterStartup {
+		result.StubRouterRemain = getGraceRemaining(sr.StartTime, sr.Conf.StartupTime)
+	}
+}
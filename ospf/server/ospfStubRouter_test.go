@@ -0,0 +1,196 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfStubRouter_test
+   This test covers
+   1) Stub router configuration validation.
+   2) Max-metric on startup and the router link metrics.
+   3) End of the startup period when BGP converges.
+   4) Administrative max-metric of summary and external LSAs.
+   5) Own links used with the interface cost.
+   6) Paths over max-metric links are still computed.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"testing"
+)
+
+func initStubRouterTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	go startDummyChannels(ospf)
+}
+
+func TestOspfStubRouter(t *testing.T) {
+	fmt.Println("\n**************** STUB ROUTER ************\n")
+	initStubRouterTestParams()
+	for index := 1; index < 7; index++ {
+		err := stubRouterTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for stub router ", index)
+		}
+	}
+	stopTimer(ospf.SpfThrottle.SpfTimer)
+}
+
+func stubRouterTestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running ValidateStubRouterConf")
+		conf := config.StubRouterConf{OnStartup: true, StartupTime: 0}
+		if ospf.ValidateStubRouterConf(conf) == nil {
+			fmt.Println("Startup time 0 accepted")
+			return FAIL
+		}
+		conf = config.StubRouterConf{WaitForBgp: true}
+		if ospf.ValidateStubRouterConf(conf) == nil {
+			fmt.Println("Waiting for BGP accepted without startup")
+			return FAIL
+		}
+		if ospf.ValidateStubRouterConf(DefaultStubRouterConf()) != nil {
+			fmt.Println("Default configuration rejected")
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running startStubRouter")
+		ospf.StubRouter.Conf = config.StubRouterConf{OnStartup: true, StartupTime: 60}
+		ospf.startStubRouter()
+		state := ospf.GetOspfGlobalState()
+		if state.StubRouterStatus != "Startup" ||
+			state.StubRouterRemain <= 0 || state.StubRouterRemain > 60 {
+			fmt.Println("Unexpected stub router state ", state.StubRouterStatus, state.StubRouterRemain)
+			return FAIL
+		}
+		links := []LinkDetail{
+			LinkDetail{LinkType: TransitLink, LinkMetric: 10},
+			LinkDetail{LinkType: StubLink, LinkMetric: 10},
+			LinkDetail{LinkType: P2PLink, LinkMetric: 20},
+		}
+		ospf.applyStubRouterMetric(links)
+		if links[0].LinkMetric != MaxLinkMetric || links[1].LinkMetric != 10 ||
+			links[2].LinkMetric != MaxLinkMetric {
+			fmt.Println("Unexpected link metrics ", links)
+			return FAIL
+		}
+		if ospf.getStubRouterSummaryMetric(20) != 20 ||
+			ospf.getStubRouterExtMetric(20) != 20 {
+			fmt.Println("Summary and external metrics changed without configuration")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running processBgpConverged")
+		ospf.processBgpConverged()
+		if ospf.StubRouter.State != StubRouterStartup {
+			fmt.Println("BGP convergence ended startup without WaitForBgp")
+			return FAIL
+		}
+		conf := config.StubRouterConf{OnStartup: true, StartupTime: 60, WaitForBgp: true}
+		ospf.processStubRouterConfig(conf)
+		ospf.processBgpConverged()
+		if ospf.isStubRouterActive() {
+			fmt.Println("Max-metric kept after BGP convergence")
+			return FAIL
+		}
+		ospf.processStubRouterConfig(conf)
+		if ospf.isStubRouterActive() {
+			fmt.Println("Startup period restarted by configuration")
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running administrative stub router")
+		conf := config.StubRouterConf{Administrative: true, SummaryLsa: true, ExternalLsa: true}
+		ospf.processStubRouterConfig(conf)
+		if ospf.StubRouter.State != StubRouterAdmin ||
+			ospf.ospfGlobalConf.StubRouterAdvertisement != config.Advertise {
+			fmt.Println("Administrative max-metric not active")
+			return FAIL
+		}
+		if ospf.getStubRouterSummaryMetric(20) != StubRouterLsaMetric ||
+			ospf.getStubRouterExtMetric(20) != StubRouterLsaMetric ||
+			ospf.getStubRouterExtMetric(0xff0010) != 0xff0010 {
+			fmt.Println("Unexpected summary and external metrics")
+			return FAIL
+		}
+		ospf.processStubRouterConfig(DefaultStubRouterConf())
+		if ospf.isStubRouterActive() ||
+			ospf.ospfGlobalConf.StubRouterAdvertisement != config.DoNotAdvertise {
+			fmt.Println("Max-metric not withdrawn")
+			return FAIL
+		}
+
+	case 5:
+		fmt.Println(tNum, ": Running getSelfLinkMetric")
+		key := IntfConfKey{IPAddr: config.IpAddress("10.1.77.1"), IntfIdx: 77}
+		ospf.IntfConfMap[key] = IntfConf{
+			IfIpAddr: net.ParseIP("10.1.77.1"),
+			IfCost:   15,
+		}
+		link := LinkDetail{
+			LinkType:   TransitLink,
+			LinkData:   convertAreaOrRouterIdUint32("10.1.77.1"),
+			LinkMetric: MaxLinkMetric,
+		}
+		cost := ospf.getSelfLinkMetric(link)
+		delete(ospf.IntfConfMap, key)
+		if cost != 15 {
+			fmt.Println("Own link not used with interface cost ", cost)
+			return FAIL
+		}
+
+	case 6:
+		fmt.Println(tNum, ": Running runDijkstra over max-metric links")
+		rtr := func(id string) VertexKey {
+			return VertexKey{Type: RouterVertex, ID: convertAreaOrRouterIdUint32(id), AdvRtr: convertAreaOrRouterIdUint32(id)}
+		}
+		r1, r2, r3 := rtr("10.0.0.1"), rtr("10.0.0.2"), rtr("10.0.0.3")
+		areaGraph := map[VertexKey]Vertex{
+			r1: Vertex{NbrVertexKey: []VertexKey{r2}, NbrVertexCost: []uint16{MaxLinkMetric}},
+			r2: Vertex{NbrVertexKey: []VertexKey{r1, r3}, NbrVertexCost: []uint16{MaxLinkMetric, MaxLinkMetric}},
+			r3: Vertex{NbrVertexKey: []VertexKey{r2}, NbrVertexCost: []uint16{MaxLinkMetric}},
+		}
+		spfTree := make(map[VertexKey]TreeVertex)
+		if err := ospf.runDijkstra(areaGraph, spfTree, r1); err != nil {
+			fmt.Println("runDijkstra failed ", err)
+			return FAIL
+		}
+		tEnt, exist := spfTree[r3]
+		if !exist || tEnt.Distance != 2*uint32(MaxLinkMetric) {
+			fmt.Println("Path over max-metric links not computed ", spfTree)
+			return FAIL
+		}
+		if spfRouteCost(tEnt.Distance) != MaxLinkMetric {
+			fmt.Println("Route cost not kept at max-metric ", spfRouteCost(tEnt.Distance))
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
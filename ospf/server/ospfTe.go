@@ -0,0 +1,453 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"math"
+	"sort"
+)
+
+/* Traffic engineering extensions (RFC 3630).
+   A router address LSA (opaque id 0) is originated in each area and a
+   link LSA for each interface with a neighbor (point-to-point) or an
+   elected DR (multi-access). The link attributes come from the interface
+   state, IfTeConf overrides the TE metric and the bandwidths. The TE
+   LSAs of all routers form the TE database read by GetBulkOspfTeLinkState.
+*/
+
+const (
+	TeLsaOpaqueType uint8 = 1
+
+	TE_TLV_ROUTER_ADDR uint16 = 1
+	TE_TLV_LINK        uint16 = 2
+
+	TE_SUBTLV_LINK_TYPE      uint16 = 1
+	TE_SUBTLV_LINK_ID        uint16 = 2
+	TE_SUBTLV_LOCAL_ADDR     uint16 = 3
+	TE_SUBTLV_REMOTE_ADDR    uint16 = 4
+	TE_SUBTLV_TE_METRIC      uint16 = 5
+	TE_SUBTLV_MAX_BW         uint16 = 6
+	TE_SUBTLV_MAX_RSV_BW     uint16 = 7
+	TE_SUBTLV_UNRSV_BW       uint16 = 8
+	TE_SUBTLV_ADMIN_GROUP    uint16 = 9
+	TE_LINK_P2P              uint8  = 1
+	TE_LINK_MULTIACCESS      uint8  = 2
+	TE_NUM_PRIORITIES               = 8
+	TE_ROUTER_ADDR_OPAQUE_ID        = 0
+	TE_MBPS_TO_BYTES_PER_SEC        = 125000
+)
+
+type TeLink struct {
+	LinkType        uint8
+	LinkId          uint32
+	LocalAddr       []uint32
+	RemoteAddr      []uint32
+	TeMetric        uint32
+	MaxBandwidth    float32 // bytes per second
+	MaxRsvBandwidth float32
+	UnrsvBandwidth  [TE_NUM_PRIORITIES]float32
+	AdminGroup      uint32
+}
+
+/* A TE LSA carries one top level TLV */
+type TeLsa struct {
+	RouterAddr    uint32
+	HasRouterAddr bool
+	Link          TeLink
+	HasLink       bool
+}
+
+/* Values are padded to 4 bytes */
+func encodeTeTlv(tlvType uint16, value []byte) []byte {
+	tlv := make([]byte, 4+((len(value)+3)&^3))
+	binary.BigEndian.PutUint16(tlv[0:2], tlvType)
+	binary.BigEndian.PutUint16(tlv[2:4], uint16(len(value)))
+	copy(tlv[4:], value)
+	return tlv
+}
+
+func encodeTeUint32(val uint32) []byte {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, val)
+	return data
+}
+
+func encodeTeFloat32(val float32) []byte {
+	return encodeTeUint32(math.Float32bits(val))
+}
+
+func encodeTeRouterAddrTlv(addr uint32) []byte {
+	return encodeTeTlv(TE_TLV_ROUTER_ADDR, encodeTeUint32(addr))
+}
+
+func encodeTeLinkTlv(link TeLink) []byte {
+	var subTlvs []byte
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_LINK_TYPE, []byte{link.LinkType})...)
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_LINK_ID, encodeTeUint32(link.LinkId))...)
+	if len(link.LocalAddr) > 0 {
+		var addrs []byte
+		for _, addr := range link.LocalAddr {
+			addrs = append(addrs, encodeTeUint32(addr)...)
+		}
+		subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_LOCAL_ADDR, addrs)...)
+	}
+	if len(link.RemoteAddr) > 0 {
+		var addrs []byte
+		for _, addr := range link.RemoteAddr {
+			addrs = append(addrs, encodeTeUint32(addr)...)
+		}
+		subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_REMOTE_ADDR, addrs)...)
+	}
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_TE_METRIC, encodeTeUint32(link.TeMetric))...)
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_MAX_BW, encodeTeFloat32(link.MaxBandwidth))...)
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_MAX_RSV_BW, encodeTeFloat32(link.MaxRsvBandwidth))...)
+	var unrsv []byte
+	for _, bw := range link.UnrsvBandwidth {
+		unrsv = append(unrsv, encodeTeFloat32(bw)...)
+	}
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_UNRSV_BW, unrsv)...)
+	subTlvs = append(subTlvs, encodeTeTlv(TE_SUBTLV_ADMIN_GROUP, encodeTeUint32(link.AdminGroup))...)
+	return encodeTeTlv(TE_TLV_LINK, subTlvs)
+}
+
+func decodeTeAddrList(value []byte) []uint32 {
+	addrs := []uint32{}
+	for idx := 0; idx+4 <= len(value); idx += 4 {
+		addrs = append(addrs, binary.BigEndian.Uint32(value[idx:idx+4]))
+	}
+	return addrs
+}
+
+func decodeTeLinkTlv(data []byte, link *TeLink) error {
+	start := 0
+	for start+4 <= len(data) {
+		tlvType := binary.BigEndian.Uint16(data[start : start+2])
+		tlvLen := int(binary.BigEndian.Uint16(data[start+2 : start+4]))
+		start += 4
+		if start+tlvLen > len(data) {
+			return errors.New(fmt.Sprintln("TE link sub-TLV", tlvType, "exceeds the link TLV"))
+		}
+		value := data[start : start+tlvLen]
+		switch tlvType {
+		case TE_SUBTLV_LINK_TYPE:
+			if tlvLen == 1 {
+				link.LinkType = value[0]
+			}
+		case TE_SUBTLV_LINK_ID:
+			if tlvLen == 4 {
+				link.LinkId = binary.BigEndian.Uint32(value)
+			}
+		case TE_SUBTLV_LOCAL_ADDR:
+			link.LocalAddr = decodeTeAddrList(value)
+		case TE_SUBTLV_REMOTE_ADDR:
+			link.RemoteAddr = decodeTeAddrList(value)
+		case TE_SUBTLV_TE_METRIC:
+			if tlvLen == 4 {
+				link.TeMetric = binary.BigEndian.Uint32(value)
+			}
+		case TE_SUBTLV_MAX_BW:
+			if tlvLen == 4 {
+				link.MaxBandwidth = math.Float32frombits(binary.BigEndian.Uint32(value))
+			}
+		case TE_SUBTLV_MAX_RSV_BW:
+			if tlvLen == 4 {
+				link.MaxRsvBandwidth = math.Float32frombits(binary.BigEndian.Uint32(value))
+			}
+		case TE_SUBTLV_UNRSV_BW:
+			if tlvLen == 4*TE_NUM_PRIORITIES {
+				for prio := 0; prio < TE_NUM_PRIORITIES; prio++ {
+					link.UnrsvBandwidth[prio] = math.Float32frombits(binary.BigEndian.Uint32(value[4*prio : 4*prio+4]))
+				}
+			}
+		case TE_SUBTLV_ADMIN_GROUP:
+			if tlvLen == 4 {
+				link.AdminGroup = binary.BigEndian.Uint32(value)
+			}
+		}
+		start += (tlvLen + 3) &^ 3
+	}
+	return nil
+}
+
+/* @fn decodeTeLsa
+Decodes the opaque information of a TE LSA, unknown TLVs are skipped.
+*/
+func decodeTeLsa(data []byte, lsa *TeLsa) error {
+	start := 0
+	for start+4 <= len(data) {
+		tlvType := binary.BigEndian.Uint16(data[start : start+2])
+		tlvLen := int(binary.BigEndian.Uint16(data[start+2 : start+4]))
+		start += 4
+		if start+tlvLen > len(data) {
+			return errors.New(fmt.Sprintln("TE TLV", tlvType, "exceeds the LSA"))
+		}
+		switch tlvType {
+		case TE_TLV_ROUTER_ADDR:
+			if tlvLen == 4 {
+				lsa.RouterAddr = binary.BigEndian.Uint32(data[start : start+4])
+				lsa.HasRouterAddr = true
+			}
+		case TE_TLV_LINK:
+			err := decodeTeLinkTlv(data[start:start+tlvLen], &lsa.Link)
+			if err != nil {
+				return err
+			}
+			lsa.HasLink = true
+		}
+		start += (tlvLen + 3) &^ 3
+	}
+	return nil
+}
+
+func (server *OSPFServer) getTeRouterAddress() uint32 {
+	if server.OpaqueConf.TeRouterAddress != "" {
+		addr := convertAreaOrRouterIdUint32(string(server.OpaqueConf.TeRouterAddress))
+		if addr != 0 {
+			return addr
+		}
+	}
+	return convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+}
+
+/* Opaque id of the link LSA, kept while the interface exists */
+func (server *OSPFServer) getTeLinkOpaqueId(key IntfConfKey) uint32 {
+	id, exist := server.TeLinkIdMap[key]
+	if exist {
+		return id
+	}
+	id = TE_ROUTER_ADDR_OPAQUE_ID + 1
+	for _, used := range server.TeLinkIdMap {
+		if used >= id {
+			id = used + 1
+		}
+	}
+	server.TeLinkIdMap[key] = id
+	return id
+}
+
+/* Port speed in Mbps, 0 if the interface is not a port */
+func (server *OSPFServer) getIntfSpeed(ifName string) uint32 {
+	for _, port := range server.portPropertyMap {
+		if port.Name == ifName {
+			return port.Speed
+		}
+	}
+	return 0
+}
+
+/* @fn buildTeLink
+Link TLV of the interface, false if there is no TE link to advertise.
+*/
+func (server *OSPFServer) buildTeLink(key IntfConfKey, intf IntfConf) (TeLink, bool) {
+	var link TeLink
+	if isVirtualIntfKey(key) || intf.IfFSMState <= config.Loopback {
+		return link, false
+	}
+	switch intf.IfType {
+	case config.NumberedP2P, config.UnnumberedP2P, config.PointToMultipoint:
+		nbrData, exist := ospfIntfToNbrMap[key]
+		if !exist {
+			return link, false
+		}
+		found := false
+		for _, nbrKey := range nbrData.nbrList {
+			nbr, exist := server.NeighborConfigMap[nbrKey]
+			if exist && nbr.OspfNbrState >= config.NbrTwoWay {
+				link.LinkType = TE_LINK_P2P
+				link.LinkId = nbr.OspfNbrRtrId
+				if intf.IfType != config.UnnumberedP2P {
+					link.RemoteAddr = []uint32{convertIPv4ToUint32(nbr.OspfNbrIPAddr.To4())}
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return link, false
+		}
+	case config.Broadcast, config.Nbma:
+		if len(intf.IfDRIp) != 4 || convertIPv4ToUint32(intf.IfDRIp) == 0 {
+			return link, false
+		}
+		link.LinkType = TE_LINK_MULTIACCESS
+		link.LinkId = convertIPv4ToUint32(intf.IfDRIp)
+	default:
+		return link, false
+	}
+	if intf.IfType != config.UnnumberedP2P && intf.IfIpAddr != nil {
+		link.LocalAddr = []uint32{convertIPv4ToUint32(intf.IfIpAddr.To4())}
+	}
+
+	conf := server.IfTeMap[key]
+	link.TeMetric = intf.IfCost
+	if conf.TeMetric != 0 {
+		link.TeMetric = conf.TeMetric
+	}
+	maxBw := conf.MaxBandwidth
+	if maxBw == 0 {
+		maxBw = server.getIntfSpeed(intf.IfName)
+	}
+	maxRsvBw := conf.MaxRsvBandwidth
+	if maxRsvBw == 0 {
+		maxRsvBw = maxBw
+	}
+	link.MaxBandwidth = float32(maxBw) * TE_MBPS_TO_BYTES_PER_SEC
+	link.MaxRsvBandwidth = float32(maxRsvBw) * TE_MBPS_TO_BYTES_PER_SEC
+	// nothing is reserved without a signalling protocol
+	for prio := 0; prio < TE_NUM_PRIORITIES; prio++ {
+		link.UnrsvBandwidth[prio] = link.MaxRsvBandwidth
+	}
+	link.AdminGroup = conf.AdminGroup
+	return link, true
+}
+
+/* @fn refreshTeLsas
+Originates the TE LSAs whose content changed and flushes the ones
+which are not valid any more. Called on configuration, interface,
+DR and adjacency changes.
+*/
+func (server *OSPFServer) refreshTeLsas() {
+	teLsas := make(map[SelfOpaqueKey][]byte)
+	if server.OpaqueConf.OpaqueLsaSupport && server.OpaqueConf.TeEnable {
+		routerAddr := encodeTeRouterAddrTlv(server.getTeRouterAddress())
+		for lsdbKey, _ := range server.AreaLsdb {
+			key := SelfOpaqueKey{
+				LSType: OpaqueAreaLSA,
+				LSId:   getOpaqueLsId(TeLsaOpaqueType, TE_ROUTER_ADDR_OPAQUE_ID),
+				AreaId: lsdbKey.AreaId,
+			}
+			teLsas[key] = routerAddr
+		}
+		for intfKey, intf := range server.IntfConfMap {
+			link, valid := server.buildTeLink(intfKey, intf)
+			if !valid {
+				continue
+			}
+			key := SelfOpaqueKey{
+				LSType: OpaqueAreaLSA,
+				LSId:   getOpaqueLsId(TeLsaOpaqueType, server.getTeLinkOpaqueId(intfKey)),
+				AreaId: convertIPv4ToUint32(intf.IfAreaId),
+			}
+			teLsas[key] = encodeTeLinkTlv(link)
+		}
+	}
+	for intfKey, _ := range server.TeLinkIdMap {
+		if _, exist := server.IntfConfMap[intfKey]; !exist {
+			delete(server.TeLinkIdMap, intfKey)
+		}
+	}
+	for key, _ := range server.SelfOpaqueLsaMap {
+		if key.LSType != OpaqueAreaLSA || getOpaqueType(key.LSId) != TeLsaOpaqueType {
+			continue
+		}
+		if _, valid := teLsas[key]; !valid {
+			server.updateSelfOpaqueLsa(key, nil, true)
+		}
+	}
+	for key, data := range teLsas {
+		server.updateSelfOpaqueLsa(key, data, false)
+	}
+}
+
+/* @fn getTeLinkStates
+TE database, built from the TE LSAs of the area LSDBs.
+*/
+func (server *OSPFServer) getTeLinkStates() []config.TeLinkState {
+	result := make([]config.TeLinkState, 0)
+	for lsdbKey, lsDbEnt := range server.AreaLsdb {
+		routerAddr := make(map[uint32]uint32)
+		links := make(map[LsaKey]TeLink)
+		for lsaKey, lsa := range lsDbEnt.OpaqueAreaLsaMap {
+			if getOpaqueType(lsaKey.LSId) != TeLsaOpaqueType || lsa.LsaMd.LSAge >= config.MaxAge {
+				continue
+			}
+			var teLsa TeLsa
+			err := decodeTeLsa(lsa.Data, &teLsa)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("TE: Invalid TE LSA ", lsaKey, err))
+				continue
+			}
+			if teLsa.HasRouterAddr {
+				routerAddr[lsaKey.AdvRouter] = teLsa.RouterAddr
+			}
+			if teLsa.HasLink {
+				links[lsaKey] = teLsa.Link
+			}
+		}
+		for lsaKey, link := range links {
+			ent := config.TeLinkState{
+				AreaId:          config.AreaId(convertUint32ToIPv4(lsdbKey.AreaId)),
+				AdvRouter:       config.RouterId(convertUint32ToIPv4(lsaKey.AdvRouter)),
+				OpaqueId:        lsaKey.LSId & OPAQUE_MAX_ID,
+				LinkType:        link.LinkType,
+				LinkId:          config.IpAddress(convertUint32ToIPv4(link.LinkId)),
+				TeMetric:        link.TeMetric,
+				MaxBandwidth:    link.MaxBandwidth,
+				MaxRsvBandwidth: link.MaxRsvBandwidth,
+				UnrsvBandwidth:  make([]float32, TE_NUM_PRIORITIES),
+				AdminGroup:      link.AdminGroup,
+			}
+			if addr, exist := routerAddr[lsaKey.AdvRouter]; exist {
+				ent.RouterAddress = config.IpAddress(convertUint32ToIPv4(addr))
+			}
+			for _, addr := range link.LocalAddr {
+				ent.LocalIpAddress = append(ent.LocalIpAddress, config.IpAddress(convertUint32ToIPv4(addr)))
+			}
+			for _, addr := range link.RemoteAddr {
+				ent.RemoteIpAddress = append(ent.RemoteIpAddress, config.IpAddress(convertUint32ToIPv4(addr)))
+			}
+			copy(ent.UnrsvBandwidth, link.UnrsvBandwidth[:])
+			result = append(result, ent)
+		}
+	}
+	sort.Sort(teLinkStateSlice(result))
+	return result
+}
+
+type teLinkStateSlice []config.TeLinkState
+
+func (s teLinkStateSlice) Len() int      { return len(s) }
+func (s teLinkStateSlice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s teLinkStateSlice) Less(i, j int) bool {
+	if s[i].AreaId != s[j].AreaId {
+		return convertAreaOrRouterIdUint32(string(s[i].AreaId)) < convertAreaOrRouterIdUint32(string(s[j].AreaId))
+	}
+	if s[i].AdvRouter != s[j].AdvRouter {
+		return convertAreaOrRouterIdUint32(string(s[i].AdvRouter)) < convertAreaOrRouterIdUint32(string(s[j].AdvRouter))
+	}
+	return s[i].OpaqueId < s[j].OpaqueId
+}
+
+func (server *OSPFServer) GetBulkOspfTeLinkState(idx int, cnt int) (int, int, []config.TeLinkState) {
+	result := server.getTeLinkStates()
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(result))
+	if count == 0 {
+		return 0, 0, result[:0]
+	}
+	return nextIdx, count, result[idx : idx+count]
+}
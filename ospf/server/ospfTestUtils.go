@@ -614,7 +614,7 @@ func initLsdbData() {
 
 	treeVertex = TreeVertex{
 		Paths:      []Path{p},
-		Distance:   uint16(20),
+		Distance:   uint32(20),
 		NumOfPaths: 3,
 	}
 	floodMsg = ospfFloodMsg{
@@ -904,3 +904,11 @@ func getServerObject() *OSPFServer {
 	ospf = ospfServer
 	return ospfServer
 }
+
+/* Server object for the offline SPF tools, it is not connected to
+   any other daemon */
+func NewOfflineServerObject() *OSPFServer {
+	ospfServer := getServerObject()
+	ospfServer.initOspfGlobalConfDefault()
+	return ospfServer
+}
@@ -44,7 +44,14 @@ func (server *OSPFServer) StopSendHelloPkt(key IntfConfKey) {
 func (server *OSPFServer) StartSendHelloPkt(key IntfConfKey) {
 	ent, _ := server.IntfConfMap[key]
 	//server.logger.Info(fmt.Sprintln("Started Send Hello Pkt Thread", ent.IfName))
-	ospfHelloPkt := server.BuildHelloPkt(ent)
+	if server.isPassiveIntf(key) {
+		return
+	}
+	if server.isNonBroadcastIntf(key, ent) {
+		server.sendNonBroadcastHelloPkt(key, ent)
+		return
+	}
+	ospfHelloPkt := server.BuildHelloPkt(key, ent)
 	err := server.SendOspfPkt(key, ospfHelloPkt)
 	if err != nil {
 		server.logger.Err("Unable to send the ospf Hello pkt")
@@ -53,6 +60,19 @@ func (server *OSPFServer) StartSendHelloPkt(key IntfConfKey) {
 }
 
 func (server *OSPFServer) SendOspfPkt(key IntfConfKey, ospfPkt []byte) error {
+	if isVirtualIntfKey(key) {
+		return server.sendVirtLinkPkt(key, ospfPkt)
+	}
+	if isMcastOspfPkt(ospfPkt) {
+		ent, _ := server.IntfConfMap[key]
+		if server.isNonBroadcastIntf(key, ent) {
+			return server.replicateOspfPkt(key, ent, ospfPkt)
+		}
+	}
+	return server.writeOspfPkt(key, ospfPkt)
+}
+
+func (server *OSPFServer) writeOspfPkt(key IntfConfKey, ospfPkt []byte) error {
 	entry, _ := server.IntfTxMap[key]
 	handle := entry.SendPcapHdl
 	if handle == nil {
@@ -63,5 +83,8 @@ func (server *OSPFServer) SendOspfPkt(key IntfConfKey, ospfPkt []byte) error {
 	entry.SendMutex.Lock()
 	err := handle.WritePacketData(ospfPkt)
 	entry.SendMutex.Unlock()
+	if err == nil {
+		server.countTxPkt(key, ospfPkt)
+	}
 	return err
 }
@@ -0,0 +1,638 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l3/ospf/config"
+	"net"
+	"sort"
+)
+
+/*
+   Virtual links (RFC 2328 15).
+   A virtual link joins two area border routers through a non
+   backbone transit area and is treated as an unnumbered point-to-point
+   backbone interface. It is kept in IntfConfMap under a key with a
+   negative IntfIdx derived from the transit area.
+   The link comes up once the other endpoint is reachable in the SPF
+   tree of the transit area. Packets are then unicast to the endpoint
+   address through the transit area next hop, and packets received on
+   a transit area interface with the backbone area id are handed over
+   to the virtual interface.
+*/
+
+const VIRT_LINK_TTL uint8 = 64
+
+type VirtLinkKey struct {
+	TransitAreaId uint32
+	NbrRtrId      uint32
+}
+
+type VirtLinkEnt struct {
+	TransitDelay    config.UpToMaxAge
+	RetransInterval config.UpToMaxAge
+	HelloInterval   uint16
+	RtrDeadInterval uint32
+	AuthType        uint16
+	AuthKey         []byte
+	Events          int32
+	/* Path through the transit area */
+	Up         bool
+	Cost       uint16
+	LocalIp    uint32
+	NbrIp      uint32
+	NextHopIp  uint32
+	OutIntfKey IntfConfKey
+}
+
+/*
+   Op is true for create/update and false for delete.
+*/
+type VirtIfConfMsg struct {
+	Op   bool
+	Conf config.VirtIfConf
+}
+
+func (server *OSPFServer) initVirtLinkDB() {
+	server.VirtLinkMap = make(map[VirtLinkKey]VirtLinkEnt)
+}
+
+func isVirtualIntfKey(key IntfConfKey) bool {
+	return key.IntfIdx < 0
+}
+
+func getVirtLinkIntfKey(vlKey VirtLinkKey) IntfConfKey {
+	return IntfConfKey{
+		IPAddr:  config.IpAddress(convertUint32ToIPv4(vlKey.NbrRtrId)),
+		IntfIdx: config.InterfaceIndexOrZero(-1 - int(vlKey.TransitAreaId)),
+	}
+}
+
+func getVirtLinkKey(key IntfConfKey) VirtLinkKey {
+	return VirtLinkKey{
+		TransitAreaId: uint32(-1 - int(key.IntfIdx)),
+		NbrRtrId:      convertAreaOrRouterIdUint32(string(key.IPAddr)),
+	}
+}
+
+func (server *OSPFServer) ValidateVirtIfConf(conf config.VirtIfConf) error {
+	transitArea := convertAreaOrRouterId(string(conf.VirtIfAreaId))
+	if transitArea == nil {
+		return errors.New(fmt.Sprintln("Invalid transit area", conf.VirtIfAreaId))
+	}
+	if bytesEqual(transitArea, []byte{0, 0, 0, 0}) {
+		return errors.New("Backbone can not be a transit area")
+	}
+	nbrRtrId := convertAreaOrRouterId(string(conf.VirtIfNeighbor))
+	if nbrRtrId == nil || bytesEqual(nbrRtrId, []byte{0, 0, 0, 0}) {
+		return errors.New(fmt.Sprintln("Invalid virtual neighbor", conf.VirtIfNeighbor))
+	}
+	if conf.VirtIfHelloInterval <= 0 || conf.VirtIfRtrDeadInterval <= 0 ||
+		int(conf.VirtIfRtrDeadInterval) <= int(conf.VirtIfHelloInterval) {
+		return errors.New(fmt.Sprintln("Invalid virtual link hello", conf.VirtIfHelloInterval,
+			"or dead interval", conf.VirtIfRtrDeadInterval))
+	}
+	if conf.VirtIfAuthType == config.SimplePassword && convertSimpleAuthKey(conf.VirtIfAuthKey) == nil {
+		return errors.New("Invalid simple password authentication key")
+	}
+	return nil
+}
+
+func (server *OSPFServer) processVirtIfConfig(msg VirtIfConfMsg) error {
+	vlKey := VirtLinkKey{
+		TransitAreaId: convertAreaOrRouterIdUint32(string(msg.Conf.VirtIfAreaId)),
+		NbrRtrId:      convertAreaOrRouterIdUint32(string(msg.Conf.VirtIfNeighbor)),
+	}
+	server.VirtLinkMutex.Lock()
+	vl, exist := server.VirtLinkMap[vlKey]
+	if msg.Op == false {
+		delete(server.VirtLinkMap, vlKey)
+		server.VirtLinkMutex.Unlock()
+		if !exist {
+			return errors.New(fmt.Sprintln("Virtual link", msg.Conf.VirtIfAreaId, msg.Conf.VirtIfNeighbor, "is not configured"))
+		}
+		if vl.Up {
+			server.setVirtLinkIntfState(vlKey, vl, false)
+		}
+		delete(server.IntfConfMap, getVirtLinkIntfKey(vlKey))
+		server.logger.Info(fmt.Sprintln("VLINK: Deleted virtual link", vlKey))
+		return nil
+	}
+	vl.TransitDelay = msg.Conf.VirtIfTransitDelay
+	vl.RetransInterval = msg.Conf.VirtIfRetransInterval
+	vl.HelloInterval = uint16(msg.Conf.VirtIfHelloInterval)
+	vl.RtrDeadInterval = uint32(msg.Conf.VirtIfRtrDeadInterval)
+	vl.AuthType = uint16(msg.Conf.VirtIfAuthType)
+	vl.AuthKey = convertSimpleAuthKey(msg.Conf.VirtIfAuthKey)
+	server.VirtLinkMap[vlKey] = vl
+	server.VirtLinkMutex.Unlock()
+	server.logger.Info(fmt.Sprintln("VLINK: Configured virtual link", vlKey))
+	if vl.Up {
+		// Restart the virtual interface with the new parameters
+		server.setVirtLinkIntfState(vlKey, vl, false)
+		server.setVirtLinkIntfState(vlKey, vl, true)
+	}
+	return nil
+}
+
+func (server *OSPFServer) getVirtLink(vlKey VirtLinkKey) (VirtLinkEnt, bool) {
+	server.VirtLinkMutex.RLock()
+	defer server.VirtLinkMutex.RUnlock()
+	vl, exist := server.VirtLinkMap[vlKey]
+	return vl, exist
+}
+
+func (server *OSPFServer) hasVirtLinks() bool {
+	server.VirtLinkMutex.RLock()
+	defer server.VirtLinkMutex.RUnlock()
+	return len(server.VirtLinkMap) != 0
+}
+
+/* @fn getSpfAreaOrder
+The backbone is computed last so that virtual links through the
+transit areas are resolved before it (RFC 2328 16.1).
+*/
+func (server *OSPFServer) getSpfAreaOrder() []AreaConfKey {
+	var areas []AreaConfKey
+	var backbone []AreaConfKey
+	for key, _ := range server.AreaConfMap {
+		if convertAreaOrRouterIdUint32(string(key.AreaId)) == 0 {
+			backbone = append(backbone, key)
+			continue
+		}
+		areas = append(areas, key)
+	}
+	return append(areas, backbone...)
+}
+
+type virtLinkNextHopList []NextHop
+
+func (v virtLinkNextHopList) Len() int {
+	return len(v)
+}
+
+func (v virtLinkNextHopList) Less(i, j int) bool {
+	if v[i].IfIPAddr != v[j].IfIPAddr {
+		return v[i].IfIPAddr < v[j].IfIPAddr
+	}
+	return v[i].NextHopIP < v[j].NextHopIP
+}
+
+func (v virtLinkNextHopList) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+/* @fn getVirtLinkPath
+RFC 2328 16.3. Look up the other endpoint in the routing table of
+the transit area. Its address is taken from its router LSA in the
+transit area, preferring the next hop when it is directly attached.
+*/
+func (server *OSPFServer) getVirtLinkPath(vlKey VirtLinkKey) (VirtLinkEnt, bool) {
+	var path VirtLinkEnt
+	transitArea := config.AreaId(convertUint32ToIPv4(vlKey.TransitAreaId))
+	if server.isStubArea(transitArea) || server.isNssaArea(transitArea) {
+		return path, false
+	}
+	areaIdKey := AreaIdKey{
+		AreaId: vlKey.TransitAreaId,
+	}
+	tempAreaRoutingTbl, exist := server.TempAreaRoutingTbl[areaIdKey]
+	if !exist {
+		return path, false
+	}
+	var rEnt RoutingTblEntry
+	found := false
+	for rKey, ent := range tempAreaRoutingTbl.RoutingTblMap {
+		if rKey.DestId == vlKey.NbrRtrId && rKey.DestType != Network {
+			rEnt = ent
+			found = true
+			break
+		}
+	}
+	if !found || len(rEnt.NextHops) == 0 {
+		return path, false
+	}
+	var nextHops []NextHop
+	for nextHop, _ := range rEnt.NextHops {
+		nextHops = append(nextHops, nextHop)
+	}
+	sort.Sort(virtLinkNextHopList(nextHops))
+	nextHop := nextHops[0]
+
+	lsaKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      vlKey.NbrRtrId,
+		AdvRouter: vlKey.NbrRtrId,
+	}
+	lsDbEnt, _ := server.AreaLsdb[LsdbKey{AreaId: vlKey.TransitAreaId}]
+	lsaEnt, exist := lsDbEnt.RouterLsaMap[lsaKey]
+	if !exist {
+		return path, false
+	}
+	for _, link := range lsaEnt.LinkDetails {
+		if link.LinkType != TransitLink && link.LinkType != P2PLink {
+			continue
+		}
+		if link.LinkData == nextHop.NextHopIP {
+			path.NbrIp = link.LinkData
+			break
+		}
+		if path.NbrIp == 0 {
+			path.NbrIp = link.LinkData
+		}
+	}
+	if path.NbrIp == 0 {
+		return path, false
+	}
+	outIntfFound := false
+	for key, intf := range server.IntfConfMap {
+		if isVirtualIntfKey(key) ||
+			convertIPv4ToUint32(intf.IfAreaId) != vlKey.TransitAreaId {
+			continue
+		}
+		if convertAreaOrRouterIdUint32(intf.IfIpAddr.String()) == nextHop.IfIPAddr {
+			path.OutIntfKey = key
+			outIntfFound = true
+			break
+		}
+	}
+	if !outIntfFound {
+		// No interface of ours owns the next hop address
+		return path, false
+	}
+	path.Up = true
+	path.Cost = rEnt.Cost
+	path.LocalIp = nextHop.IfIPAddr
+	path.NextHopIp = nextHop.NextHopIP
+	if path.NextHopIp == 0 {
+		path.NextHopIp = path.NbrIp
+	}
+	return path, true
+}
+
+/* @fn resolveVirtLinks
+Called by the SPF once the routing table of an area is built.
+Brings the virtual links through the area up or down and refreshes
+the backbone router LSA when the path cost changes.
+*/
+func (server *OSPFServer) resolveVirtLinks(areaId uint32) {
+	server.VirtLinkMutex.Lock()
+	defer server.VirtLinkMutex.Unlock()
+	for vlKey, vl := range server.VirtLinkMap {
+		if vlKey.TransitAreaId != areaId {
+			continue
+		}
+		path, up := server.getVirtLinkPath(vlKey)
+		wasUp := vl.Up
+		oldCost := vl.Cost
+		vl.Up = up
+		vl.Cost = path.Cost
+		vl.LocalIp = path.LocalIp
+		vl.NbrIp = path.NbrIp
+		vl.NextHopIp = path.NextHopIp
+		vl.OutIntfKey = path.OutIntfKey
+		if up != wasUp {
+			vl.Events++
+		}
+		server.VirtLinkMap[vlKey] = vl
+		if up != wasUp {
+			server.logger.Info(fmt.Sprintln("VLINK: Virtual link", vlKey, "up", up))
+			go server.setVirtLinkIntfState(vlKey, vl, up)
+		} else if up && oldCost != path.Cost {
+			go server.updateVirtLinkCost(vlKey, path.Cost)
+		}
+	}
+}
+
+func (server *OSPFServer) buildVirtLinkIntfConf(vl VirtLinkEnt) IntfConf {
+	var ent IntfConf
+	outIntf, _ := server.IntfConfMap[vl.OutIntfKey]
+	ent.IfAreaId = []byte{0, 0, 0, 0}
+	ent.IfType = config.VirtualLink
+	ent.IfAdminStat = config.Enabled
+	ent.IfRtrPriority = 0
+	ent.IfTransitDelay = vl.TransitDelay
+	ent.IfRetransInterval = vl.RetransInterval
+	ent.IfHelloInterval = vl.HelloInterval
+	ent.IfRtrDeadInterval = vl.RtrDeadInterval
+	ent.IfAuthKey = vl.AuthKey
+	ent.IfAuthType = vl.AuthType
+	ent.IfMulticastForwarding = config.Blocked
+	ent.FSMCtrlCh = make(chan bool)
+	ent.FSMCtrlStatusCh = make(chan bool)
+	ent.BackupSeenCh = make(chan BackupSeenMsg)
+	ent.NeighCreateCh = make(chan NeighCreateMsg)
+	ent.NeighChangeCh = make(chan NeighChangeMsg)
+	ent.NbrStateChangeCh = make(chan NbrStateChangeMsg)
+	ent.NbrFullStateCh = make(chan NbrFullStateMsg)
+	ent.IfDRIp = []byte{0, 0, 0, 0}
+	ent.IfBDRIp = []byte{0, 0, 0, 0}
+	ent.IfFSMState = config.Down
+	ent.IfName = outIntf.IfName
+	ent.IfIpAddr = net.ParseIP(convertUint32ToIPv4(vl.LocalIp)).To4()
+	ent.IfMacAddr = outIntf.IfMacAddr
+	// Hellos on virtual links carry a null mask and DDs a zero MTU
+	ent.IfNetmask = []byte{0, 0, 0, 0}
+	ent.IfMtu = 0
+	ent.IfCost = uint32(vl.Cost)
+	return ent
+}
+
+func (server *OSPFServer) setVirtLinkIntfState(vlKey VirtLinkKey, vl VirtLinkEnt, up bool) {
+	key := getVirtLinkIntfKey(vlKey)
+	ent, exist := server.IntfConfMap[key]
+	if exist && ent.IfFSMState != config.Down {
+		server.StopSendRecvPkts(key)
+		msg := NetworkLSAChangeMsg{
+			areaId:  0,
+			intfKey: key,
+		}
+		server.IntfStateChangeCh <- msg
+	}
+	if !up {
+		return
+	}
+	ent = server.buildVirtLinkIntfConf(vl)
+	server.IntfConfMap[key] = ent
+	if server.ospfGlobalConf.AdminStat == config.Enabled {
+		server.StartSendRecvPkts(key)
+	}
+}
+
+func (server *OSPFServer) updateVirtLinkCost(vlKey VirtLinkKey, cost uint16) {
+	key := getVirtLinkIntfKey(vlKey)
+	ent, exist := server.IntfConfMap[key]
+	if !exist {
+		return
+	}
+	ent.IfCost = uint32(cost)
+	server.IntfConfMap[key] = ent
+	msg := NetworkLSAChangeMsg{
+		areaId:  0,
+		intfKey: key,
+	}
+	server.IntfStateChangeCh <- msg
+}
+
+/* @fn constructVirtualLink
+RFC 2328 12.4.1.3. A fully adjacent virtual link is advertised in
+the backbone router LSA with the cost of the transit area path.
+*/
+func (server *OSPFServer) constructVirtualLink(key IntfConfKey, ent IntfConf) (LinkDetail, bool) {
+	var linkDetail LinkDetail
+	nbrData, exist := ospfIntfToNbrMap[key]
+	if !exist {
+		return linkDetail, false
+	}
+	for _, nbrKey := range nbrData.nbrList {
+		nbr := server.NeighborConfigMap[nbrKey]
+		if nbr.OspfNbrState != config.NbrFull {
+			continue
+		}
+		linkDetail.LinkId = nbr.OspfNbrRtrId
+		linkDetail.LinkData = convertAreaOrRouterIdUint32(ent.IfIpAddr.String())
+		linkDetail.LinkType = VirtualLink
+		linkDetail.NumOfTOS = 0
+		linkDetail.LinkMetric = uint16(ent.IfCost)
+		return linkDetail, true
+	}
+	return linkDetail, false
+}
+
+/* @fn isVirtLinkTransitArea
+Bit V of the router LSA of a transit area is set while one of the
+virtual links through it is fully adjacent.
+*/
+func (server *OSPFServer) isVirtLinkTransitArea(areaId uint32) bool {
+	server.VirtLinkMutex.RLock()
+	defer server.VirtLinkMutex.RUnlock()
+	for vlKey, vl := range server.VirtLinkMap {
+		if vlKey.TransitAreaId != areaId || !vl.Up {
+			continue
+		}
+		key := getVirtLinkIntfKey(vlKey)
+		if _, full := server.constructVirtualLink(key, server.IntfConfMap[key]); full {
+			return true
+		}
+	}
+	return false
+}
+
+/* @fn refreshVirtLinkTransitLsa
+Adjacency changes on a virtual link flip bit V of the router LSA in
+the transit area, so it is regenerated and flooded there as well.
+*/
+func (server *OSPFServer) refreshVirtLinkTransitLsa(key IntfConfKey) {
+	vlKey := getVirtLinkKey(key)
+	vl, exist := server.getVirtLink(vlKey)
+	if !exist {
+		return
+	}
+	rtrId := convertIPv4ToUint32(server.ospfGlobalConf.RouterId)
+	lsaKey := LsaKey{
+		LSType:    RouterLSA,
+		LSId:      rtrId,
+		AdvRouter: rtrId,
+	}
+	server.generateRouterLSA(vlKey.TransitAreaId)
+	server.sendLsdbToNeighborEvent(vl.OutIntfKey, NeighborConfKey{}, vlKey.TransitAreaId, 0, 0, lsaKey, LSAROUTERFLOOD)
+}
+
+/* @fn getVirtLinkNextHop
+Backbone destinations reached over a virtual link use the next hop
+of the transit area path to the endpoint.
+*/
+func (server *OSPFServer) getVirtLinkNextHop(nbrRtrId uint32) (ifIPAddr uint32, nextHopIP uint32, ok bool) {
+	server.VirtLinkMutex.RLock()
+	defer server.VirtLinkMutex.RUnlock()
+	for vlKey, vl := range server.VirtLinkMap {
+		if vlKey.NbrRtrId == nbrRtrId && vl.Up {
+			return vl.LocalIp, vl.NextHopIp, true
+		}
+	}
+	return 0, 0, false
+}
+
+/* @fn getVirtLinkRxKey
+Packets for the backbone received on a transit area interface from
+a virtual neighbor belong to the virtual interface.
+*/
+func (server *OSPFServer) getVirtLinkRxKey(key IntfConfKey, ent IntfConf, ospfPkt []byte, ipHdrMd *IpHdrMetadata) IntfConfKey {
+	if ipHdrMd.dstIPType != Normal || len(ospfPkt) < OSPF_HEADER_SIZE {
+		return key
+	}
+	transitAreaId := convertIPv4ToUint32(ent.IfAreaId)
+	if transitAreaId == 0 || convertIPv4ToUint32(ospfPkt[8:12]) != 0 {
+		return key
+	}
+	vlKey := VirtLinkKey{
+		TransitAreaId: transitAreaId,
+		NbrRtrId:      convertIPv4ToUint32(ospfPkt[4:8]),
+	}
+	vl, exist := server.getVirtLink(vlKey)
+	if !exist || !vl.Up {
+		return key
+	}
+	virtKey := getVirtLinkIntfKey(vlKey)
+	if _, exist := server.IntfConfMap[virtKey]; !exist {
+		return key
+	}
+	return virtKey
+}
+
+/* @fn sendVirtLinkPkt
+Rewrite a packet built for the virtual interface into a unicast to
+the other endpoint and send it out of the transit area interface.
+*/
+func (server *OSPFServer) sendVirtLinkPkt(key IntfConfKey, ospfPkt []byte) error {
+	vl, exist := server.getVirtLink(getVirtLinkKey(key))
+	if !exist || !vl.Up {
+		return errors.New(fmt.Sprintln("Virtual link", key.IPAddr, "is down"))
+	}
+	outIntf, exist := server.IntfConfMap[vl.OutIntfKey]
+	if !exist {
+		return errors.New(fmt.Sprintln("Transit interface of virtual link", key.IPAddr, "is gone"))
+	}
+	pkt := gopacket.NewPacket(ospfPkt, layers.LayerTypeEthernet, gopacket.Default)
+	ethLayer, _ := pkt.Layer(layers.LayerTypeEthernet).(*layers.Ethernet)
+	ipLayer, _ := pkt.Layer(layers.LayerTypeIPv4).(*layers.IPv4)
+	if ethLayer == nil || ipLayer == nil {
+		return errors.New("Unable to decode ospf pkt for virtual link")
+	}
+	payload := ipLayer.Payload
+	nextHopKey := NeighborConfKey{
+		IPAddr:  config.IpAddress(convertUint32ToIPv4(vl.NextHopIp)),
+		IntfIdx: vl.OutIntfKey.IntfIdx,
+	}
+	ipLayer.SrcIP = net.ParseIP(convertUint32ToIPv4(vl.LocalIp)).To4()
+	ipLayer.DstIP = net.ParseIP(convertUint32ToIPv4(vl.NbrIp)).To4()
+	ipLayer.TTL = VIRT_LINK_TTL
+	ethLayer.SrcMAC = outIntf.IfMacAddr
+	ethLayer.DstMAC = server.getNbrUnicastMAC(nextHopKey)
+	buffer := gopacket.NewSerializeBuffer()
+	options := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	gopacket.SerializeLayers(buffer, options, ethLayer, ipLayer, gopacket.Payload(payload))
+	return server.writeOspfPkt(vl.OutIntfKey, buffer.Bytes())
+}
+
+type virtLinkKeyList []VirtLinkKey
+
+func (v virtLinkKeyList) Len() int {
+	return len(v)
+}
+
+func (v virtLinkKeyList) Less(i, j int) bool {
+	if v[i].TransitAreaId != v[j].TransitAreaId {
+		return v[i].TransitAreaId < v[j].TransitAreaId
+	}
+	return v[i].NbrRtrId < v[j].NbrRtrId
+}
+
+func (v virtLinkKeyList) Swap(i, j int) {
+	v[i], v[j] = v[j], v[i]
+}
+
+func (server *OSPFServer) sortedVirtLinkKeys() []VirtLinkKey {
+	server.VirtLinkMutex.RLock()
+	defer server.VirtLinkMutex.RUnlock()
+	var keys []VirtLinkKey
+	for vlKey, _ := range server.VirtLinkMap {
+		keys = append(keys, vlKey)
+	}
+	sort.Sort(virtLinkKeyList(keys))
+	return keys
+}
+
+func (server *OSPFServer) GetBulkOspfVirtIfState(idx int, cnt int) (int, int, []config.VirtIfState) {
+	result := make([]config.VirtIfState, 0)
+	for _, vlKey := range server.sortedVirtLinkKeys() {
+		vl, exist := server.getVirtLink(vlKey)
+		if !exist {
+			continue
+		}
+		state := config.VirtIfState{
+			VirtIfAreaId:          config.AreaId(convertUint32ToIPv4(vlKey.TransitAreaId)),
+			VirtIfNeighbor:        config.RouterId(convertUint32ToIPv4(vlKey.NbrRtrId)),
+			VirtIfTransitDelay:    vl.TransitDelay,
+			VirtIfRetransInterval: vl.RetransInterval,
+			VirtIfHelloInterval:   config.HelloRange(vl.HelloInterval),
+			VirtIfRtrDeadInterval: config.PositiveInteger(vl.RtrDeadInterval),
+			VirtIfState:           config.Down,
+			VirtIfEvents:          int(vl.Events),
+			VirtIfAuthType:        config.AuthType(vl.AuthType),
+			VirtIfCost:            int(vl.Cost),
+		}
+		if vl.Up {
+			state.VirtIfLocalIpAddress = config.IpAddress(convertUint32ToIPv4(vl.LocalIp))
+			state.VirtIfNbrIpAddress = config.IpAddress(convertUint32ToIPv4(vl.NbrIp))
+		}
+		if ent, exist := server.IntfConfMap[getVirtLinkIntfKey(vlKey)]; exist {
+			state.VirtIfState = ent.IfFSMState
+			state.VirtIfLsaCount = int(ent.IfLsaCount)
+			state.VirtIfLsaCksumSum = int(ent.IfLsaCksumSum)
+		}
+		result = append(result, state)
+	}
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(result))
+	if count == 0 {
+		return 0, 0, result[:0]
+	}
+	return nextIdx, count, result[idx : idx+count]
+}
+
+func (server *OSPFServer) GetBulkOspfVirtNbrState(idx int, cnt int) (int, int, []config.VirtNbrState) {
+	result := make([]config.VirtNbrState, 0)
+	for _, vlKey := range server.sortedVirtLinkKeys() {
+		nbrData, exist := ospfIntfToNbrMap[getVirtLinkIntfKey(vlKey)]
+		if !exist {
+			continue
+		}
+		for _, nbrKey := range nbrData.nbrList {
+			nbr, exist := server.NeighborConfigMap[nbrKey]
+			if !exist {
+				continue
+			}
+			result = append(result, config.VirtNbrState{
+				VirtNbrArea:      config.AreaId(convertUint32ToIPv4(vlKey.TransitAreaId)),
+				VirtNbrRtrId:     config.RouterId(convertUint32ToIPv4(nbr.OspfNbrRtrId)),
+				VirtNbrIpAddress: config.IpAddress(nbr.OspfNbrIPAddr.String()),
+				VirtNbrOptions:   nbr.OspfNbrOptions,
+				VirtNbrState:     nbr.OspfNbrState,
+				VirtNbrEvents:    int(nbr.nbrEvent),
+			})
+		}
+	}
+	nextIdx, count := getBulkOspfv3Range(idx, cnt, len(result))
+	if count == 0 {
+		return 0, 0, result[:0]
+	}
+	return nextIdx, count, result[idx : idx+count]
+}
@@ -0,0 +1,211 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfVirtLink_test
+   This test covers
+   1) Virtual link configuration and interface key mapping.
+   2) Resolving the endpoint through the transit area routing table.
+   3) Virtual link in the backbone router LSA and bit V.
+   4) Handing backbone packets over to the virtual interface.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"testing"
+)
+
+var vlKey VirtLinkKey
+var vlTransitKey IntfConfKey
+
+func initVirtLinkTestParams() {
+	fmt.Println("\n Get Server object")
+	ospf = getServerObject()
+	initAttr()
+	ospf.processGlobalConfig(gConf)
+	ospf.InitNeighborStateMachine()
+	go startDummyChannels(ospf)
+
+	vlKey = VirtLinkKey{
+		TransitAreaId: 1,
+		NbrRtrId:      convertAreaOrRouterIdUint32("5.5.5.5"),
+	}
+	vlTransitKey = IntfConfKey{
+		IPAddr:  config.IpAddress("10.1.1.2"),
+		IntfIdx: 2,
+	}
+	transitIntf := intf
+	transitIntf.IfAreaId = []byte{0, 0, 0, 1}
+	transitIntf.IfIpAddr = net.IP{10, 1, 1, 2}
+	transitIntf.IfNetmask = []byte{255, 255, 255, 0}
+	ospf.IntfConfMap[vlTransitKey] = transitIntf
+}
+
+func TestOspfVirtLink(t *testing.T) {
+	fmt.Println("\n**************** VIRTUAL LINK ************\n")
+	initVirtLinkTestParams()
+	for index := 1; index < 5; index++ {
+		err := virtLinkTestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for virtual link ", index)
+		}
+	}
+}
+
+func virtLinkTestLogic(tNum int) int {
+	virtKey := getVirtLinkIntfKey(vlKey)
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running processVirtIfConfig")
+		conf := config.VirtIfConf{
+			VirtIfAreaId:          "0.0.0.1",
+			VirtIfNeighbor:        "5.5.5.5",
+			VirtIfHelloInterval:   10,
+			VirtIfRtrDeadInterval: 40,
+		}
+		if err := ospf.ValidateVirtIfConf(conf); err != nil {
+			fmt.Println("Valid virtual link rejected ", err)
+			return FAIL
+		}
+		backbone := conf
+		backbone.VirtIfAreaId = "0.0.0.0"
+		if ospf.ValidateVirtIfConf(backbone) == nil {
+			fmt.Println("Virtual link through the backbone accepted")
+			return FAIL
+		}
+		ospf.processVirtIfConfig(VirtIfConfMsg{Op: true, Conf: conf})
+		if _, exist := ospf.getVirtLink(vlKey); !exist {
+			fmt.Println("Virtual link not stored ", vlKey)
+			return FAIL
+		}
+		if !isVirtualIntfKey(virtKey) || isVirtualIntfKey(vlTransitKey) {
+			fmt.Println("Virtual interface key not recognised ", virtKey)
+			return FAIL
+		}
+		if getVirtLinkKey(virtKey) != vlKey {
+			fmt.Println("Virtual interface key mapping mismatch ", getVirtLinkKey(virtKey))
+			return FAIL
+		}
+
+	case 2:
+		fmt.Println(tNum, ": Running getVirtLinkPath")
+		nbrRtrId := vlKey.NbrRtrId
+		areaIdKey := AreaIdKey{AreaId: 1}
+		rTbl := AreaRoutingTbl{
+			RoutingTblMap: make(map[RoutingTblEntryKey]RoutingTblEntry),
+		}
+		nextHop := NextHop{
+			IfIPAddr:  convertAreaOrRouterIdUint32("10.1.1.2"),
+			NextHopIP: convertAreaOrRouterIdUint32("10.1.1.5"),
+		}
+		rTbl.RoutingTblMap[RoutingTblEntryKey{DestId: nbrRtrId, DestType: AreaBdrRouter}] = RoutingTblEntry{
+			Cost:     20,
+			NextHops: map[NextHop]bool{nextHop: true},
+		}
+		ospf.TempAreaRoutingTbl[areaIdKey] = rTbl
+		lsdbKey := LsdbKey{AreaId: 1}
+		lsDbEnt := LSDatabase{
+			RouterLsaMap: make(map[LsaKey]RouterLsa),
+		}
+		lsDbEnt.RouterLsaMap[LsaKey{LSType: RouterLSA, LSId: nbrRtrId, AdvRouter: nbrRtrId}] = RouterLsa{
+			LinkDetails: []LinkDetail{
+				{LinkType: StubLink, LinkId: convertAreaOrRouterIdUint32("10.9.9.0"), LinkData: 0xffffff00},
+				{LinkType: TransitLink, LinkData: convertAreaOrRouterIdUint32("10.1.2.5")},
+			},
+		}
+		ospf.AreaLsdb[lsdbKey] = lsDbEnt
+		path, up := ospf.getVirtLinkPath(vlKey)
+		if !up {
+			fmt.Println("Virtual link endpoint not resolved")
+			return FAIL
+		}
+		if path.Cost != 20 || path.NbrIp != convertAreaOrRouterIdUint32("10.1.2.5") ||
+			path.NextHopIp != nextHop.NextHopIP || path.OutIntfKey != vlTransitKey {
+			fmt.Println("Unexpected virtual link path ", path)
+			return FAIL
+		}
+		delete(ospf.TempAreaRoutingTbl, areaIdKey)
+		if _, up := ospf.getVirtLinkPath(vlKey); up {
+			fmt.Println("Virtual link up without transit area routes")
+			return FAIL
+		}
+
+	case 3:
+		fmt.Println(tNum, ": Running constructVirtualLink")
+		vl, _ := ospf.getVirtLink(vlKey)
+		vl.Up = true
+		vl.Cost = 20
+		vl.LocalIp = convertAreaOrRouterIdUint32("10.1.1.2")
+		vl.OutIntfKey = vlTransitKey
+		ospf.VirtLinkMap[vlKey] = vl
+		ospf.IntfConfMap[virtKey] = ospf.buildVirtLinkIntfConf(vl)
+		nbrKey := NeighborConfKey{IPAddr: "10.1.2.5", IntfIdx: virtKey.IntfIdx}
+		ospfIntfToNbrMap[virtKey] = ospfNbrMdata{intf: virtKey, nbrList: []NeighborConfKey{nbrKey}}
+		ospf.NeighborConfigMap[nbrKey] = OspfNeighborEntry{OspfNbrRtrId: vlKey.NbrRtrId, OspfNbrState: config.NbrExchange}
+		if _, full := ospf.constructVirtualLink(virtKey, ospf.IntfConfMap[virtKey]); full {
+			fmt.Println("Virtual link advertised before adjacency is full")
+			return FAIL
+		}
+		if ospf.isVirtLinkTransitArea(1) {
+			fmt.Println("Bit V set before adjacency is full")
+			return FAIL
+		}
+		ospf.NeighborConfigMap[nbrKey] = OspfNeighborEntry{OspfNbrRtrId: vlKey.NbrRtrId, OspfNbrState: config.NbrFull}
+		link, full := ospf.constructVirtualLink(virtKey, ospf.IntfConfMap[virtKey])
+		if !full || link.LinkType != VirtualLink || link.LinkId != vlKey.NbrRtrId ||
+			link.LinkData != vl.LocalIp || link.LinkMetric != 20 {
+			fmt.Println("Unexpected virtual link ", link)
+			return FAIL
+		}
+		if !ospf.isVirtLinkTransitArea(1) {
+			fmt.Println("Bit V not set for transit area")
+			return FAIL
+		}
+
+	case 4:
+		fmt.Println(tNum, ": Running getVirtLinkRxKey")
+		ospfPkt := make([]byte, OSPF_HEADER_SIZE)
+		copy(ospfPkt[4:8], []byte{5, 5, 5, 5})
+		ipHdrMd := NewIpHdrMetadata()
+		ipHdrMd.dstIPType = Normal
+		transitIntf := ospf.IntfConfMap[vlTransitKey]
+		if key := ospf.getVirtLinkRxKey(vlTransitKey, transitIntf, ospfPkt, ipHdrMd); key != virtKey {
+			fmt.Println("Backbone packet not handed to virtual interface ", key)
+			return FAIL
+		}
+		copy(ospfPkt[8:12], []byte{0, 0, 0, 1})
+		if key := ospf.getVirtLinkRxKey(vlTransitKey, transitIntf, ospfPkt, ipHdrMd); key != vlTransitKey {
+			fmt.Println("Transit area packet handed to virtual interface ", key)
+			return FAIL
+		}
+		ipHdrMd.dstIPType = AllSPFRouter
+		copy(ospfPkt[8:12], []byte{0, 0, 0, 0})
+		if key := ospf.getVirtLinkRxKey(vlTransitKey, transitIntf, ospfPkt, ipHdrMd); key != vlTransitKey {
+			fmt.Println("Multicast packet handed to virtual interface ", key)
+			return FAIL
+		}
+	}
+	return SUCCESS
+}
@@ -0,0 +1,918 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"asicd/asicdCommonDefs"
+	"errors"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcap"
+	"l3/ospf/config"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	OSPFV3_DEFAULT_HELLO_INTERVAL uint16 = 10
+	OSPFV3_DEFAULT_DEAD_INTERVAL  uint16 = 40
+	OSPFV3_DEFAULT_RTR_PRIO       uint8  = 1
+	OSPFV3_DEFAULT_COST           uint16 = 10
+	OSPFV3_DEFAULT_MTU            uint16 = 1500
+	OSPFV3_RXMT_INTERVAL          int    = 5
+	OSPFV3_INITIAL_SEQ_NUM        uint32 = 0x80000001
+)
+
+/* IPv6 addresses learnt from asicd, indexed by IfIndex */
+type IPv6IntfProperty struct {
+	IfIndex   int32
+	LinkLocal net.IP
+	Prefixes  []Ospfv3Prefix
+}
+
+type Ospfv3IntfConfKey struct {
+	InstanceId uint8
+	IfIndex    int32
+}
+
+type Ospfv3Nbr struct {
+	RouterId     uint32
+	Addr         net.IP
+	MacAddr      net.HardwareAddr
+	InterfaceId  uint32
+	RtrPrio      uint8
+	Options      uint32
+	DRtrId       uint32
+	BDRtrId      uint32
+	State        config.NbrState
+	deadTimer    int
+	master       bool // we are the master of the database exchange
+	ddSeqNum     uint32
+	lastDD       []byte
+	lastSentMore bool
+	ddRetxTimer  int
+	summaryList  []Ospfv3LsaHeader
+	reqList      map[Ospfv3LsaKey]Ospfv3LsaHeader
+	reqRetxTimer int
+	retxList     map[Ospfv3LsaKey]Ospfv3LsaHeader
+	retxTimer    int
+}
+
+type Ospfv3Intf struct {
+	IfIndex       int32
+	IfName        string
+	InterfaceId   uint32
+	AreaId        uint32
+	IfType        config.IfType
+	AdminStat     bool
+	RtrPrio       uint8
+	HelloInterval uint16
+	DeadInterval  uint16
+	Cost          uint16
+	Mtu           uint16
+	LinkLocal     net.IP
+	Prefixes      []Ospfv3Prefix
+	MacAddr       net.HardwareAddr
+	State         config.IfState
+	DRtrId        uint32
+	BDRtrId       uint32
+	NbrMap        map[uint32]*Ospfv3Nbr
+	LinkLsdb      map[Ospfv3LsaKey]Ospfv3Lsa
+	helloTimer    int
+	waitTimer     int
+	sendHdl       *pcap.Handle
+	recvHdl       *pcap.Handle
+	pktRecvCh     chan bool
+}
+
+/*
+   An OSPFv3 instance runs independently of the OSPFv2 process. All of
+   its state is guarded by the instance lock; packets received on its
+   interfaces and the one second instance timer are processed under it.
+*/
+type Ospfv3Instance struct {
+	sync.RWMutex
+	server           *OSPFServer
+	InstanceId       uint8
+	RouterId         uint32
+	AdminStat        bool
+	IntfMap          map[int32]*Ospfv3Intf
+	AreaLsdb         map[uint32]map[Ospfv3LsaKey]Ospfv3Lsa
+	ASLsdb           map[Ospfv3LsaKey]Ospfv3Lsa
+	RouteTbl         map[string]Ospfv3Route
+	interAreaLsId    map[string]uint32
+	nextInterAreaId  uint32
+	spfPending       bool
+	originatePending bool
+	stopCh           chan bool
+}
+
+type Ospfv3ConfMsg struct {
+	Op     bool // true for create/update, false for delete
+	Global *config.Ospfv3GlobalConf
+	Intf   *config.Ospfv3IntfConf
+}
+
+func newOspfv3Intf(conf config.Ospfv3IntfConf) *Ospfv3Intf {
+	intf := &Ospfv3Intf{
+		IfIndex:       conf.IfIndex,
+		InterfaceId:   uint32(conf.IfIndex),
+		AreaId:        convertAreaOrRouterIdUint32(string(conf.IfAreaId)),
+		IfType:        conf.IfType,
+		AdminStat:     conf.IfAdminStat == config.Enabled,
+		RtrPrio:       uint8(conf.IfRtrPriority),
+		HelloInterval: uint16(conf.IfHelloInterval),
+		DeadInterval:  uint16(conf.IfRtrDeadInterval),
+		Cost:          uint16(conf.IfCost),
+		Mtu:           OSPFV3_DEFAULT_MTU,
+		State:         config.Down,
+		NbrMap:        make(map[uint32]*Ospfv3Nbr),
+		LinkLsdb:      make(map[Ospfv3LsaKey]Ospfv3Lsa),
+	}
+	if intf.IfType == 0 {
+		intf.IfType = config.Broadcast
+	}
+	if intf.HelloInterval == 0 {
+		intf.HelloInterval = OSPFV3_DEFAULT_HELLO_INTERVAL
+	}
+	if intf.DeadInterval == 0 {
+		intf.DeadInterval = OSPFV3_DEFAULT_DEAD_INTERVAL
+	}
+	if intf.Cost == 0 {
+		intf.Cost = OSPFV3_DEFAULT_COST
+	}
+	return intf
+}
+
+func newOspfv3Instance(server *OSPFServer, instanceId uint8) *Ospfv3Instance {
+	return &Ospfv3Instance{
+		server:        server,
+		InstanceId:    instanceId,
+		IntfMap:       make(map[int32]*Ospfv3Intf),
+		AreaLsdb:      make(map[uint32]map[Ospfv3LsaKey]Ospfv3Lsa),
+		ASLsdb:        make(map[Ospfv3LsaKey]Ospfv3Lsa),
+		RouteTbl:      make(map[string]Ospfv3Route),
+		interAreaLsId: make(map[string]uint32),
+	}
+}
+
+func isPointToPointV3(intf *Ospfv3Intf) bool {
+	return intf.IfType == config.NumberedP2P || intf.IfType == config.UnnumberedP2P
+}
+
+func (server *OSPFServer) processOspfv3Config(msg Ospfv3ConfMsg) error {
+	if msg.Global != nil {
+		if !msg.Op {
+			server.deleteOspfv3Instance(msg.Global.InstanceId)
+			return nil
+		}
+		return server.processOspfv3GlobalConfig(*msg.Global)
+	}
+	if msg.Intf != nil {
+		if !msg.Op {
+			key := Ospfv3IntfConfKey{
+				InstanceId: msg.Intf.InstanceId,
+				IfIndex:    msg.Intf.IfIndex,
+			}
+			return server.deleteOspfv3IntfConfig(key)
+		}
+		return server.processOspfv3IntfConfig(*msg.Intf)
+	}
+	return nil
+}
+
+func (server *OSPFServer) processOspfv3GlobalConfig(conf config.Ospfv3GlobalConf) error {
+	routerId := convertAreaOrRouterIdUint32(string(conf.RouterId))
+	if routerId == 0 {
+		return errors.New(fmt.Sprintln("Invalid Ospfv3 router id", conf.RouterId))
+	}
+	server.Ospfv3Mutex.Lock()
+	inst, exist := server.Ospfv3InstanceMap[conf.InstanceId]
+	if !exist {
+		inst = newOspfv3Instance(server, conf.InstanceId)
+		server.Ospfv3InstanceMap[conf.InstanceId] = inst
+	}
+	server.Ospfv3Mutex.Unlock()
+
+	adminStat := conf.AdminStat == config.Enabled
+	if inst.AdminStat && (!adminStat || inst.RouterId != routerId) {
+		inst.stop()
+	}
+	inst.Lock()
+	inst.RouterId = routerId
+	inst.Unlock()
+	if adminStat && !inst.AdminStat {
+		inst.start()
+	}
+	server.logger.Info(fmt.Sprintln("Ospfv3: instance", conf.InstanceId, "router id", conf.RouterId, "admin state", adminStat))
+	return nil
+}
+
+func (server *OSPFServer) deleteOspfv3Instance(instanceId uint8) {
+	server.Ospfv3Mutex.Lock()
+	inst, exist := server.Ospfv3InstanceMap[instanceId]
+	delete(server.Ospfv3InstanceMap, instanceId)
+	server.Ospfv3Mutex.Unlock()
+	if exist && inst.AdminStat {
+		inst.stop()
+	}
+}
+
+func (server *OSPFServer) getOspfv3Instance(instanceId uint8) *Ospfv3Instance {
+	server.Ospfv3Mutex.RLock()
+	defer server.Ospfv3Mutex.RUnlock()
+	return server.Ospfv3InstanceMap[instanceId]
+}
+
+func (server *OSPFServer) processOspfv3IntfConfig(conf config.Ospfv3IntfConf) error {
+	inst := server.getOspfv3Instance(conf.InstanceId)
+	if inst == nil {
+		return errors.New(fmt.Sprintln("Ospfv3 instance", conf.InstanceId, "is not configured"))
+	}
+	inst.Lock()
+	defer inst.Unlock()
+	if intf, exist := inst.IntfMap[conf.IfIndex]; exist {
+		inst.intfDown(intf)
+		delete(inst.IntfMap, conf.IfIndex)
+	}
+	intf := newOspfv3Intf(conf)
+	inst.IntfMap[conf.IfIndex] = intf
+	if inst.AdminStat {
+		inst.intfUp(intf)
+	}
+	return nil
+}
+
+func (server *OSPFServer) deleteOspfv3IntfConfig(key Ospfv3IntfConfKey) error {
+	inst := server.getOspfv3Instance(key.InstanceId)
+	if inst == nil {
+		return errors.New(fmt.Sprintln("Ospfv3 instance", key.InstanceId, "is not configured"))
+	}
+	inst.Lock()
+	defer inst.Unlock()
+	intf, exist := inst.IntfMap[key.IfIndex]
+	if !exist {
+		return errors.New(fmt.Sprintln("Ospfv3 interface", key.IfIndex, "is not configured"))
+	}
+	inst.intfDown(intf)
+	delete(inst.IntfMap, key.IfIndex)
+	return nil
+}
+
+/*
+   Track the IPv6 addresses of the L3 interfaces. Link local addresses
+   select the adjacency source address and global ones are advertised
+   in the Link and Intra-Area-Prefix LSAs.
+*/
+func (server *OSPFServer) UpdateIPv6Infra(msg asicdCommonDefs.IPv6IntfNotifyMsg, msgType uint8) {
+	ip, ipNet, err := net.ParseCIDR(msg.IpAddr)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: Unable to parse ipv6 address", msg.IpAddr))
+		return
+	}
+	prefixLen, _ := ipNet.Mask.Size()
+	prop := server.ipv6PropertyMap[msg.IfIndex]
+	prop.IfIndex = msg.IfIndex
+	if msgType == asicdCommonDefs.NOTIFY_IPV6INTF_CREATE {
+		server.logger.Info(fmt.Sprintln("Receive IPV6INTF_CREATE", msg))
+		if ip.IsLinkLocalUnicast() {
+			prop.LinkLocal = ip
+		} else if !hasOspfv3Prefix(prop.Prefixes, ipNet.IP, prefixLen) {
+			prop.Prefixes = append(prop.Prefixes, Ospfv3Prefix{
+				PrefixLen: uint8(prefixLen),
+				Prefix:    ipNet.IP,
+			})
+		}
+	} else {
+		server.logger.Info(fmt.Sprintln("Receive IPV6INTF_DELETE", msg))
+		if ip.IsLinkLocalUnicast() {
+			prop.LinkLocal = nil
+		} else {
+			for idx, prefix := range prop.Prefixes {
+				if prefix.Prefix.Equal(ipNet.IP) && int(prefix.PrefixLen) == prefixLen {
+					prop.Prefixes = append(prop.Prefixes[:idx], prop.Prefixes[idx+1:]...)
+					break
+				}
+			}
+		}
+	}
+	server.ipv6PropertyMap[msg.IfIndex] = prop
+
+	server.Ospfv3Mutex.RLock()
+	defer server.Ospfv3Mutex.RUnlock()
+	for _, inst := range server.Ospfv3InstanceMap {
+		inst.Lock()
+		intf, exist := inst.IntfMap[msg.IfIndex]
+		if exist && inst.AdminStat {
+			if intf.State == config.Down || (prop.LinkLocal != nil && !intf.LinkLocal.Equal(prop.LinkLocal)) {
+				// The adjacencies are sourced from the link local address
+				inst.intfDown(intf)
+				inst.intfUp(intf)
+			} else {
+				intf.Prefixes = prop.Prefixes
+				inst.originatePending = true
+			}
+		}
+		inst.Unlock()
+	}
+}
+
+func hasOspfv3Prefix(prefixes []Ospfv3Prefix, ip net.IP, prefixLen int) bool {
+	for _, prefix := range prefixes {
+		if prefix.Prefix.Equal(ip) && int(prefix.PrefixLen) == prefixLen {
+			return true
+		}
+	}
+	return false
+}
+
+func (inst *Ospfv3Instance) start() {
+	inst.Lock()
+	inst.AdminStat = true
+	inst.stopCh = make(chan bool)
+	for _, intf := range inst.IntfMap {
+		inst.intfUp(intf)
+	}
+	inst.Unlock()
+	go inst.run(inst.stopCh)
+}
+
+func (inst *Ospfv3Instance) stop() {
+	inst.Lock()
+	inst.AdminStat = false
+	close(inst.stopCh)
+	for _, intf := range inst.IntfMap {
+		inst.intfDown(intf)
+	}
+	inst.AreaLsdb = make(map[uint32]map[Ospfv3LsaKey]Ospfv3Lsa)
+	inst.ASLsdb = make(map[Ospfv3LsaKey]Ospfv3Lsa)
+	newTbl := make(map[string]Ospfv3Route)
+	inst.installOspfv3Routes(newTbl)
+	inst.Unlock()
+}
+
+/* Instance timer, drives hellos, adjacency timers, LSA aging and SPF */
+func (inst *Ospfv3Instance) run(stopCh chan bool) {
+	ticker := time.NewTicker(time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			inst.Lock()
+			inst.processTick()
+			inst.Unlock()
+		case <-stopCh:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (inst *Ospfv3Instance) processTick() {
+	if !inst.AdminStat {
+		return
+	}
+	for _, intf := range inst.IntfMap {
+		if intf.State == config.Down {
+			continue
+		}
+		intf.helloTimer--
+		if intf.helloTimer <= 0 {
+			inst.sendOspfv3Hello(intf)
+			intf.helloTimer = int(intf.HelloInterval)
+		}
+		if intf.State == config.Waiting {
+			intf.waitTimer--
+			if intf.waitTimer <= 0 {
+				inst.electOspfv3DR(intf)
+			}
+		}
+		for _, nbr := range intf.NbrMap {
+			nbr.deadTimer--
+			if nbr.deadTimer <= 0 {
+				inst.server.logger.Info(fmt.Sprintln("Ospfv3: neighbor", convertUint32ToIPv4(nbr.RouterId), "dead on", intf.IfName))
+				inst.deleteOspfv3Nbr(intf, nbr)
+				continue
+			}
+			inst.nbrRetransmit(intf, nbr)
+		}
+	}
+	inst.ageOspfv3Lsdb()
+	inst.originatePending = true
+	inst.processPending()
+}
+
+/* Originates LSAs and runs SPF for changes made since the last call */
+func (inst *Ospfv3Instance) processPending() {
+	if inst.originatePending {
+		inst.originatePending = false
+		inst.originateOspfv3Lsas()
+	}
+	if inst.spfPending {
+		inst.spfPending = false
+		newTbl := inst.computeOspfv3Routes()
+		inst.installOspfv3Routes(newTbl)
+	}
+}
+
+func (inst *Ospfv3Instance) intfUp(intf *Ospfv3Intf) {
+	server := inst.server
+	if !intf.AdminStat {
+		return
+	}
+	prop, exist := server.ipv6PropertyMap[intf.IfIndex]
+	if exist {
+		intf.Prefixes = prop.Prefixes
+		intf.LinkLocal = prop.LinkLocal
+	}
+	ifType := uint8(asicdCommonDefs.GetIntfTypeFromIfIndex(intf.IfIndex))
+	ifId := int32(asicdCommonDefs.GetIntfIdFromIfIndex(intf.IfIndex))
+	ifName, err := server.getLinuxIntfName(ifId, ifType)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: Unable to find interface name for", intf.IfIndex, err))
+		return
+	}
+	intf.IfName = ifName
+	if port, exist := server.portPropertyMap[ifId]; exist && port.Mtu > 0 {
+		intf.Mtu = uint16(port.Mtu)
+	}
+	intf.MacAddr, err = getMacAddrIntfName(ifName)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: Unable to get mac address of", ifName, err))
+		return
+	}
+	if intf.LinkLocal == nil {
+		intf.LinkLocal = getLinkLocalIntfName(ifName)
+	}
+	if intf.LinkLocal == nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: No link local address on", ifName))
+		return
+	}
+
+	sendHdl, err := pcap.OpenLive(ifName, snapshot_len, promiscuous, timeout_pcap)
+	if sendHdl == nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: SendHdl: No device found.", ifName, err))
+		return
+	}
+	recvHdl, err := pcap.OpenLive(ifName, snapshot_len, promiscuous, timeout_pcap)
+	if recvHdl == nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: RecvHdl: No device found.", ifName, err))
+		sendHdl.Close()
+		return
+	}
+	filter := fmt.Sprintln("ip6 proto ospf and not src host", intf.LinkLocal.String())
+	err = recvHdl.SetBPFFilter(filter)
+	if err != nil {
+		server.logger.Err(fmt.Sprintln("Ospfv3: Unable to set filter on", ifName, err))
+		sendHdl.Close()
+		recvHdl.Close()
+		return
+	}
+	intf.sendHdl = sendHdl
+	intf.recvHdl = recvHdl
+	intf.pktRecvCh = make(chan bool)
+	go inst.startOspfv3RecvPkts(intf.IfIndex, recvHdl, intf.pktRecvCh)
+
+	inst.startOspfv3Intf(intf)
+}
+
+/* Interface state machine InterfaceUp event, once the interface has addresses */
+func (inst *Ospfv3Instance) startOspfv3Intf(intf *Ospfv3Intf) {
+	intf.DRtrId = 0
+	intf.BDRtrId = 0
+	if isPointToPointV3(intf) {
+		intf.State = config.P2P
+	} else if intf.RtrPrio == 0 {
+		intf.State = config.OtherDesignatedRouter
+	} else {
+		intf.State = config.Waiting
+		intf.waitTimer = int(intf.DeadInterval)
+	}
+	intf.helloTimer = 0
+	inst.server.logger.Info(fmt.Sprintln("Ospfv3: interface", intf.IfName, "is up in state", intf.State))
+	inst.originatePending = true
+}
+
+func (inst *Ospfv3Instance) intfDown(intf *Ospfv3Intf) {
+	for _, nbr := range intf.NbrMap {
+		inst.deleteOspfv3Nbr(intf, nbr)
+	}
+	if intf.pktRecvCh != nil {
+		close(intf.pktRecvCh)
+		intf.pktRecvCh = nil
+	}
+	if intf.sendHdl != nil {
+		intf.sendHdl.Close()
+		intf.sendHdl = nil
+	}
+	intf.recvHdl = nil
+	intf.State = config.Down
+	intf.DRtrId = 0
+	intf.BDRtrId = 0
+	intf.LinkLsdb = make(map[Ospfv3LsaKey]Ospfv3Lsa)
+	inst.originatePending = true
+	inst.spfPending = true
+}
+
+func getLinkLocalIntfName(ifName string) net.IP {
+	ifi, err := net.InterfaceByName(ifName)
+	if err != nil {
+		return nil
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && ipNet.IP.To4() == nil && ipNet.IP.IsLinkLocalUnicast() {
+			return ipNet.IP
+		}
+	}
+	return nil
+}
+
+/*
+   The receive handle is owned by this thread, it is closed once the
+   interface goes down and the packet source is drained.
+*/
+func (inst *Ospfv3Instance) startOspfv3RecvPkts(ifIndex int32, handle *pcap.Handle, stopCh chan bool) {
+	recv := gopacket.NewPacketSource(handle, layers.LayerTypeEthernet)
+	in := recv.Packets()
+	for {
+		select {
+		case packet, ok := <-in:
+			if ok {
+				inst.processOspfv3RecvPkt(ifIndex, packet)
+			}
+		case <-stopCh:
+			handle.Close()
+			return
+		}
+	}
+}
+
+func (inst *Ospfv3Instance) sendOspfv3Pkt(intf *Ospfv3Intf, pktType OspfType, body []byte, dstIP net.IP, dstMAC net.HardwareAddr) {
+	if intf.sendHdl == nil {
+		return
+	}
+	hdr := Ospfv3Header{
+		pktType:    uint8(pktType),
+		routerId:   inst.RouterId,
+		areaId:     intf.AreaId,
+		instanceId: inst.InstanceId,
+	}
+	pkt := buildOspfv3Pkt(hdr, body, intf.MacAddr, dstMAC, intf.LinkLocal, dstIP)
+	err := intf.sendHdl.WritePacketData(pkt)
+	if err != nil {
+		inst.server.logger.Err(fmt.Sprintln("Ospfv3: Unable to send packet on", intf.IfName, err))
+	}
+}
+
+func (inst *Ospfv3Instance) sendOspfv3Multicast(intf *Ospfv3Intf, pktType OspfType, body []byte, dst string) {
+	dstIP := net.ParseIP(dst)
+	inst.sendOspfv3Pkt(intf, pktType, body, dstIP, ipv6MulticastMAC(dstIP))
+}
+
+func (inst *Ospfv3Instance) sendOspfv3Unicast(intf *Ospfv3Intf, nbr *Ospfv3Nbr, pktType OspfType, body []byte) {
+	inst.sendOspfv3Pkt(intf, pktType, body, nbr.Addr, nbr.MacAddr)
+}
+
+/* Largest OSPFv3 body that fits the interface MTU */
+func (intf *Ospfv3Intf) maxBodyLen() int {
+	return int(intf.Mtu) - IPV6_HEADER_SIZE - OSPFV3_HEADER_SIZE
+}
+
+func (inst *Ospfv3Instance) processOspfv3RecvPkt(ifIndex int32, pkt gopacket.Packet) {
+	ethLayer := pkt.Layer(layers.LayerTypeEthernet)
+	ipLayer := pkt.Layer(layers.LayerTypeIPv6)
+	if ethLayer == nil || ipLayer == nil {
+		return
+	}
+	eth := ethLayer.(*layers.Ethernet)
+	ipPkt := ipLayer.(*layers.IPv6)
+
+	inst.Lock()
+	defer inst.Unlock()
+	intf, exist := inst.IntfMap[ifIndex]
+	if !exist || intf.State == config.Down {
+		return
+	}
+	err := inst.processOspfv3Pkt(intf, ipPkt.SrcIP, ipPkt.DstIP, eth.SrcMAC, ipPkt.NextHeader, ipPkt.LayerPayload())
+	if err != nil {
+		inst.server.logger.Err(fmt.Sprintln("Ospfv3: Dropped packet on", intf.IfName, err))
+	}
+	inst.processPending()
+}
+
+func (inst *Ospfv3Instance) processOspfv3Pkt(intf *Ospfv3Intf, srcIP net.IP, dstIP net.IP, srcMAC net.HardwareAddr, proto layers.IPProtocol, ospfPkt []byte) error {
+	if proto != layers.IPProtocol(OSPF_PROTO_ID) {
+		return errors.New(fmt.Sprintln("Incorrect next header", proto))
+	}
+	if srcIP.Equal(intf.LinkLocal) {
+		return errors.New("locally generated packet")
+	}
+	if !srcIP.IsLinkLocalUnicast() {
+		return errors.New(fmt.Sprintln("Source address", srcIP, "is not link local"))
+	}
+	allSPFRouter := net.ParseIP(ALLSPFROUTERV6)
+	allDRouter := net.ParseIP(ALLDROUTERV6)
+	if dstIP.Equal(allDRouter) {
+		if intf.State != config.DesignatedRouter && intf.State != config.BackupDesignatedRouter {
+			return errors.New("AllDRouters packet on a non DR interface")
+		}
+	} else if !dstIP.Equal(allSPFRouter) && !dstIP.Equal(intf.LinkLocal) {
+		return errors.New(fmt.Sprintln("Incorrect DstIP", dstIP))
+	}
+
+	var hdr Ospfv3Header
+	err := decodeOspfv3Hdr(ospfPkt, &hdr)
+	if err != nil {
+		return err
+	}
+	ospfPkt = ospfPkt[:hdr.pktlen]
+	if hdr.ver != OSPFV3_VERSION {
+		return errors.New("Ospf version not matching")
+	}
+	if hdr.instanceId != inst.InstanceId {
+		// Other instances share the link, RFC 5340 4.2.2
+		return nil
+	}
+	if computeOspfv3CheckSum(srcIP, dstIP, ospfPkt) != 0 {
+		return errors.New("Invalid Ospfv3 checksum")
+	}
+	if hdr.areaId != intf.AreaId {
+		return errors.New("Area ID not matching")
+	}
+	if hdr.routerId == inst.RouterId {
+		return errors.New("Router ID is our own")
+	}
+
+	data := ospfPkt[OSPFV3_HEADER_SIZE:]
+	if OspfType(hdr.pktType) == HelloType {
+		return inst.processOspfv3Hello(intf, hdr, data, srcIP, srcMAC)
+	}
+	nbr, exist := intf.NbrMap[hdr.routerId]
+	if !exist {
+		return errors.New(fmt.Sprintln("Unknown neighbor", convertUint32ToIPv4(hdr.routerId)))
+	}
+	switch OspfType(hdr.pktType) {
+	case DBDescriptionType:
+		err = inst.processOspfv3DD(intf, nbr, data)
+	case LSRequestType:
+		err = inst.processOspfv3LsReq(intf, nbr, data)
+	case LSUpdateType:
+		err = inst.processOspfv3LsUpd(intf, nbr, data)
+	case LSAckType:
+		err = inst.processOspfv3LsAck(intf, nbr, data)
+	default:
+		err = errors.New("Invalid Ospfv3 packet type")
+	}
+	return err
+}
+
+func (inst *Ospfv3Instance) sendOspfv3Hello(intf *Ospfv3Intf) {
+	hello := Ospfv3HelloData{
+		interfaceId:   intf.InterfaceId,
+		rtrPrio:       intf.RtrPrio,
+		options:       V6Option | EV3Option | RV3Option,
+		helloInterval: intf.HelloInterval,
+		deadInterval:  intf.DeadInterval,
+		dRtrId:        intf.DRtrId,
+		bdRtrId:       intf.BDRtrId,
+	}
+	for rtrId, nbr := range intf.NbrMap {
+		if nbr.State >= config.NbrInit {
+			hello.neighbors = append(hello.neighbors, rtrId)
+		}
+	}
+	inst.sendOspfv3Multicast(intf, HelloType, encodeOspfv3HelloData(hello), ALLSPFROUTERV6)
+}
+
+func (inst *Ospfv3Instance) processOspfv3Hello(intf *Ospfv3Intf, hdr Ospfv3Header, data []byte, srcIP net.IP, srcMAC net.HardwareAddr) error {
+	var hello Ospfv3HelloData
+	err := decodeOspfv3HelloData(data, &hello)
+	if err != nil {
+		return err
+	}
+	if hello.helloInterval != intf.HelloInterval || hello.deadInterval != intf.DeadInterval {
+		return errors.New("Hello or dead interval not matching")
+	}
+	if hello.options&EV3Option == 0 {
+		return errors.New("E bit not matching")
+	}
+
+	nbr, exist := intf.NbrMap[hdr.routerId]
+	if !exist {
+		nbr = &Ospfv3Nbr{
+			RouterId: hdr.routerId,
+			State:    config.NbrInit,
+			reqList:  make(map[Ospfv3LsaKey]Ospfv3LsaHeader),
+			retxList: make(map[Ospfv3LsaKey]Ospfv3LsaHeader),
+		}
+		intf.NbrMap[hdr.routerId] = nbr
+		inst.server.logger.Info(fmt.Sprintln("Ospfv3: new neighbor", convertUint32ToIPv4(hdr.routerId), srcIP, "on", intf.IfName))
+	}
+	prevPrio := nbr.RtrPrio
+	prevDR := nbr.DRtrId
+	prevBDR := nbr.BDRtrId
+	nbr.Addr = srcIP
+	nbr.MacAddr = srcMAC
+	nbr.InterfaceId = hello.interfaceId
+	nbr.RtrPrio = hello.rtrPrio
+	nbr.Options = hello.options
+	nbr.DRtrId = hello.dRtrId
+	nbr.BDRtrId = hello.bdRtrId
+	nbr.deadTimer = int(intf.DeadInterval)
+
+	twoWay := false
+	for _, rtrId := range hello.neighbors {
+		if rtrId == inst.RouterId {
+			twoWay = true
+			break
+		}
+	}
+	electDR := false
+	if !twoWay {
+		if nbr.State >= config.NbrTwoWay {
+			// 1-WayReceived
+			inst.resetOspfv3Adjacency(intf, nbr, config.NbrInit)
+			electDR = true
+		}
+	} else if nbr.State == config.NbrInit {
+		nbr.State = config.NbrTwoWay
+		electDR = true
+		if inst.adjacencyOK(intf, nbr) {
+			inst.startOspfv3ExStart(intf, nbr)
+		}
+	}
+	if isPointToPointV3(intf) {
+		return nil
+	}
+
+	if intf.State == config.Waiting {
+		// BackupSeen
+		if (nbr.DRtrId == nbr.RouterId && nbr.BDRtrId == 0) || nbr.BDRtrId == nbr.RouterId {
+			inst.electOspfv3DR(intf)
+			return nil
+		}
+	} else if exist && (prevPrio != nbr.RtrPrio || prevDR != nbr.DRtrId || prevBDR != nbr.BDRtrId) {
+		electDR = true
+	}
+	if electDR && intf.State != config.Waiting {
+		inst.electOspfv3DR(intf)
+	}
+	return nil
+}
+
+/* RFC 2328 10.4, adjacencies are formed with the DR and BDR only */
+func (inst *Ospfv3Instance) adjacencyOK(intf *Ospfv3Intf, nbr *Ospfv3Nbr) bool {
+	if isPointToPointV3(intf) {
+		return true
+	}
+	return intf.DRtrId == inst.RouterId || intf.BDRtrId == inst.RouterId ||
+		intf.DRtrId == nbr.RouterId || intf.BDRtrId == nbr.RouterId
+}
+
+type ospfv3DRCandidate struct {
+	routerId uint32
+	prio     uint8
+	dRtrId   uint32
+	bdRtrId  uint32
+}
+
+func betterOspfv3Candidate(a ospfv3DRCandidate, b ospfv3DRCandidate) bool {
+	if a.prio != b.prio {
+		return a.prio > b.prio
+	}
+	return a.routerId > b.routerId
+}
+
+/* RFC 2328 9.4 steps 2 and 3 */
+func selectOspfv3DR(candidates []ospfv3DRCandidate) (uint32, uint32) {
+	var bdr, bdrDeclared, dr *ospfv3DRCandidate
+	for idx, _ := range candidates {
+		c := &candidates[idx]
+		if c.dRtrId == c.routerId {
+			if dr == nil || betterOspfv3Candidate(*c, *dr) {
+				dr = c
+			}
+			continue
+		}
+		if c.bdRtrId == c.routerId {
+			if bdrDeclared == nil || betterOspfv3Candidate(*c, *bdrDeclared) {
+				bdrDeclared = c
+			}
+		}
+		if bdr == nil || betterOspfv3Candidate(*c, *bdr) {
+			bdr = c
+		}
+	}
+	if bdrDeclared != nil {
+		bdr = bdrDeclared
+	}
+	var drId, bdrId uint32
+	if bdr != nil {
+		bdrId = bdr.routerId
+	}
+	if dr != nil {
+		drId = dr.routerId
+	} else {
+		drId = bdrId
+		bdrId = 0
+	}
+	return drId, bdrId
+}
+
+func (inst *Ospfv3Instance) electOspfv3DR(intf *Ospfv3Intf) {
+	prevDR := intf.DRtrId
+	prevBDR := intf.BDRtrId
+	elect := func() {
+		var candidates []ospfv3DRCandidate
+		if intf.RtrPrio > 0 {
+			candidates = append(candidates, ospfv3DRCandidate{inst.RouterId, intf.RtrPrio, intf.DRtrId, intf.BDRtrId})
+		}
+		for _, nbr := range intf.NbrMap {
+			if nbr.State >= config.NbrTwoWay && nbr.RtrPrio > 0 {
+				candidates = append(candidates, ospfv3DRCandidate{nbr.RouterId, nbr.RtrPrio, nbr.DRtrId, nbr.BDRtrId})
+			}
+		}
+		intf.DRtrId, intf.BDRtrId = selectOspfv3DR(candidates)
+	}
+	elect()
+	wasDR := prevDR == inst.RouterId
+	wasBDR := prevBDR == inst.RouterId
+	isDR := intf.DRtrId == inst.RouterId
+	isBDR := intf.BDRtrId == inst.RouterId
+	if wasDR != isDR || wasBDR != isBDR {
+		// Step 4, repeat once our own role changed
+		elect()
+	}
+
+	if intf.DRtrId == inst.RouterId {
+		intf.State = config.DesignatedRouter
+	} else if intf.BDRtrId == inst.RouterId {
+		intf.State = config.BackupDesignatedRouter
+	} else {
+		intf.State = config.OtherDesignatedRouter
+	}
+	if prevDR == intf.DRtrId && prevBDR == intf.BDRtrId {
+		return
+	}
+	inst.server.logger.Info(fmt.Sprintln("Ospfv3: DR", convertUint32ToIPv4(intf.DRtrId), "BDR", convertUint32ToIPv4(intf.BDRtrId), "on", intf.IfName))
+	for _, nbr := range intf.NbrMap {
+		if nbr.State == config.NbrTwoWay && inst.adjacencyOK(intf, nbr) {
+			inst.startOspfv3ExStart(intf, nbr)
+		} else if nbr.State > config.NbrTwoWay && !inst.adjacencyOK(intf, nbr) {
+			inst.resetOspfv3Adjacency(intf, nbr, config.NbrTwoWay)
+		}
+	}
+	inst.originatePending = true
+	inst.spfPending = true
+}
+
+func (inst *Ospfv3Instance) deleteOspfv3Nbr(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	delete(intf.NbrMap, nbr.RouterId)
+	if nbr.State == config.NbrFull {
+		inst.originatePending = true
+		inst.spfPending = true
+	}
+	if intf.State != config.Down && !isPointToPointV3(intf) && intf.State != config.Waiting {
+		inst.electOspfv3DR(intf)
+	}
+}
+
+/* Bring a neighbor back to the given state, dropping the database exchange */
+func (inst *Ospfv3Instance) resetOspfv3Adjacency(intf *Ospfv3Intf, nbr *Ospfv3Nbr, state config.NbrState) {
+	if nbr.State == config.NbrFull {
+		inst.originatePending = true
+		inst.spfPending = true
+	}
+	nbr.State = state
+	nbr.lastDD = nil
+	nbr.summaryList = nil
+	nbr.reqList = make(map[Ospfv3LsaKey]Ospfv3LsaHeader)
+	nbr.retxList = make(map[Ospfv3LsaKey]Ospfv3LsaHeader)
+}
@@ -0,0 +1,527 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+/* LS function codes with the flooding scope bits, RFC 5340 A.4.2.1 */
+const (
+	RouterLSAV3          uint16 = 0x2001
+	NetworkLSAV3         uint16 = 0x2002
+	InterAreaPrefixLSAV3 uint16 = 0x2003
+	InterAreaRouterLSAV3 uint16 = 0x2004
+	ASExternalLSAV3      uint16 = 0x4005
+	LinkLSAV3            uint16 = 0x0008
+	IntraAreaPrefixLSAV3 uint16 = 0x2009
+)
+
+const (
+	LinkScopeV3 uint16 = 0
+	AreaScopeV3 uint16 = 1
+	ASScopeV3   uint16 = 2
+)
+
+/* Router-LSA interface types */
+const (
+	P2PLinkV3     uint8 = 1
+	TransitLinkV3 uint8 = 2
+	VirtualLinkV3 uint8 = 4
+)
+
+const (
+	LSA_MAX_AGE_V3      uint16 = 3600
+	LSA_MAX_AGE_DIFF_V3 uint16 = 900
+	LSA_REFRESH_TIME_V3 uint16 = 1800
+)
+
+type Ospfv3LsaKey struct {
+	LSType    uint16
+	LSId      uint32
+	AdvRouter uint32
+}
+
+type Ospfv3LsaHeader struct {
+	LSAge         uint16
+	LSType        uint16
+	LSId          uint32
+	AdvRouter     uint32
+	LSSequenceNum uint32
+	LSChecksum    uint16
+	LSLen         uint16
+}
+
+/* An LSA is kept encoded; Data holds the complete LSA including the header */
+type Ospfv3Lsa struct {
+	Hdr      Ospfv3LsaHeader
+	Data     []byte
+	RcvdTime time.Time
+	SelfOrig bool
+}
+
+func lsaScopeV3(lsType uint16) uint16 {
+	return (lsType >> 13) & 0x3
+}
+
+func (hdr Ospfv3LsaHeader) key() Ospfv3LsaKey {
+	return Ospfv3LsaKey{
+		LSType:    hdr.LSType,
+		LSId:      hdr.LSId,
+		AdvRouter: hdr.AdvRouter,
+	}
+}
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |           LS Age              |           LS Type             |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                       Link State ID                           |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                    Advertising Router                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                    LS Sequence Number                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |        LS Checksum            |             Length            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+func encodeOspfv3LsaHeader(hdr Ospfv3LsaHeader) []byte {
+	pkt := make([]byte, OSPFV3_LSA_HEADER_SIZE)
+	binary.BigEndian.PutUint16(pkt[0:2], hdr.LSAge)
+	binary.BigEndian.PutUint16(pkt[2:4], hdr.LSType)
+	binary.BigEndian.PutUint32(pkt[4:8], hdr.LSId)
+	binary.BigEndian.PutUint32(pkt[8:12], hdr.AdvRouter)
+	binary.BigEndian.PutUint32(pkt[12:16], hdr.LSSequenceNum)
+	binary.BigEndian.PutUint16(pkt[16:18], hdr.LSChecksum)
+	binary.BigEndian.PutUint16(pkt[18:20], hdr.LSLen)
+	return pkt
+}
+
+func decodeOspfv3LsaHeader(data []byte, hdr *Ospfv3LsaHeader) {
+	hdr.LSAge = binary.BigEndian.Uint16(data[0:2])
+	hdr.LSType = binary.BigEndian.Uint16(data[2:4])
+	hdr.LSId = binary.BigEndian.Uint32(data[4:8])
+	hdr.AdvRouter = binary.BigEndian.Uint32(data[8:12])
+	hdr.LSSequenceNum = binary.BigEndian.Uint32(data[12:16])
+	hdr.LSChecksum = binary.BigEndian.Uint16(data[16:18])
+	hdr.LSLen = binary.BigEndian.Uint16(data[18:20])
+}
+
+/*
+   Prepend the LSA header to an encoded body and fill in the length
+   and the fletcher checksum, which covers everything but LS age.
+*/
+func buildOspfv3Lsa(hdr Ospfv3LsaHeader, body []byte) Ospfv3Lsa {
+	hdr.LSLen = uint16(OSPFV3_LSA_HEADER_SIZE + len(body))
+	hdr.LSChecksum = 0
+	data := append(encodeOspfv3LsaHeader(hdr), body...)
+	checksumOffset := uint16(14)
+	hdr.LSChecksum = computeFletcherChecksum(data[2:], checksumOffset)
+	binary.BigEndian.PutUint16(data[16:18], hdr.LSChecksum)
+	return Ospfv3Lsa{
+		Hdr:      hdr,
+		Data:     data,
+		RcvdTime: time.Now(),
+	}
+}
+
+func decodeOspfv3Lsa(data []byte) (Ospfv3Lsa, error) {
+	var lsa Ospfv3Lsa
+	if len(data) < OSPFV3_LSA_HEADER_SIZE {
+		return lsa, errors.New("Invalid length of Ospfv3 LSA")
+	}
+	decodeOspfv3LsaHeader(data, &lsa.Hdr)
+	if int(lsa.Hdr.LSLen) != len(data) {
+		return lsa, errors.New("Ospfv3 LSA length mismatch")
+	}
+	csum := computeFletcherChecksum(data[2:], FLETCHER_CHECKSUM_VALIDATE)
+	if csum != 0 {
+		return lsa, errors.New("Invalid Ospfv3 LSA checksum")
+	}
+	lsa.Data = make([]byte, len(data))
+	copy(lsa.Data, data)
+	lsa.RcvdTime = time.Now()
+	return lsa, nil
+}
+
+/* Current age of a database copy, aged since it was installed */
+func (lsa Ospfv3Lsa) age() uint16 {
+	age := uint32(lsa.Hdr.LSAge) + uint32(time.Since(lsa.RcvdTime).Seconds())
+	if age > uint32(LSA_MAX_AGE_V3) {
+		return LSA_MAX_AGE_V3
+	}
+	return uint16(age)
+}
+
+/* Encoded copy for transmission with the LS age brought up to date */
+func (lsa Ospfv3Lsa) encode() []byte {
+	data := make([]byte, len(lsa.Data))
+	copy(data, lsa.Data)
+	binary.BigEndian.PutUint16(data[0:2], lsa.age())
+	return data
+}
+
+func (lsa Ospfv3Lsa) header() Ospfv3LsaHeader {
+	hdr := lsa.Hdr
+	hdr.LSAge = lsa.age()
+	return hdr
+}
+
+/*
+   RFC 2328 13.1, returns 1 if a is the more recent instance, -1 if b is
+   and 0 if they are the same instance.
+*/
+func compareOspfv3Lsa(a Ospfv3LsaHeader, b Ospfv3LsaHeader) int {
+	if int32(a.LSSequenceNum) != int32(b.LSSequenceNum) {
+		if int32(a.LSSequenceNum) > int32(b.LSSequenceNum) {
+			return 1
+		}
+		return -1
+	}
+	if a.LSChecksum != b.LSChecksum {
+		if a.LSChecksum > b.LSChecksum {
+			return 1
+		}
+		return -1
+	}
+	if a.LSAge == LSA_MAX_AGE_V3 && b.LSAge != LSA_MAX_AGE_V3 {
+		return 1
+	}
+	if b.LSAge == LSA_MAX_AGE_V3 && a.LSAge != LSA_MAX_AGE_V3 {
+		return -1
+	}
+	diff := int(a.LSAge) - int(b.LSAge)
+	if diff > int(LSA_MAX_AGE_DIFF_V3) {
+		return -1
+	} else if -diff > int(LSA_MAX_AGE_DIFF_V3) {
+		return 1
+	}
+	return 0
+}
+
+/*
+   IPv6 prefix as carried in LSAs, RFC 5340 A.4.1. The 16 bit field
+   after the prefix options is a metric, a referenced LS type or zero
+   depending on the LSA.
+*/
+type Ospfv3Prefix struct {
+	PrefixLen     uint8
+	PrefixOptions uint8
+	Metric        uint16
+	Prefix        net.IP
+}
+
+const (
+	PrefixNUBit uint8 = 0x01
+	PrefixLABit uint8 = 0x02
+)
+
+func prefixWordsV3(prefixLen uint8) int {
+	return (int(prefixLen) + 31) / 32
+}
+
+func encodeOspfv3Prefix(prefix Ospfv3Prefix) []byte {
+	words := prefixWordsV3(prefix.PrefixLen)
+	pkt := make([]byte, 4+4*words)
+	pkt[0] = prefix.PrefixLen
+	pkt[1] = prefix.PrefixOptions
+	binary.BigEndian.PutUint16(pkt[2:4], prefix.Metric)
+	ip := prefix.Prefix.Mask(net.CIDRMask(int(prefix.PrefixLen), 128))
+	copy(pkt[4:], ip[:4*words])
+	return pkt
+}
+
+func decodeOspfv3Prefix(data []byte) (Ospfv3Prefix, int, error) {
+	var prefix Ospfv3Prefix
+	if len(data) < 4 {
+		return prefix, 0, errors.New("Truncated Ospfv3 prefix")
+	}
+	prefix.PrefixLen = data[0]
+	prefix.PrefixOptions = data[1]
+	prefix.Metric = binary.BigEndian.Uint16(data[2:4])
+	if prefix.PrefixLen > 128 {
+		return prefix, 0, errors.New("Invalid Ospfv3 prefix length")
+	}
+	words := prefixWordsV3(prefix.PrefixLen)
+	if len(data) < 4+4*words {
+		return prefix, 0, errors.New("Truncated Ospfv3 prefix")
+	}
+	prefix.Prefix = make(net.IP, net.IPv6len)
+	copy(prefix.Prefix, data[4:4+4*words])
+	return prefix, 4 + 4*words, nil
+}
+
+func (prefix Ospfv3Prefix) String() string {
+	ipNet := net.IPNet{
+		IP:   prefix.Prefix,
+		Mask: net.CIDRMask(int(prefix.PrefixLen), 128),
+	}
+	return ipNet.String()
+}
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |  0  |Nt|x|V|E|B|            Options                            |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |     Type      |       0       |          Metric               |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                      Interface ID                             |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                   Neighbor Interface ID                       |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                    Neighbor Router ID                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type Ospfv3RouterLink struct {
+	Type           uint8
+	Metric         uint16
+	InterfaceId    uint32
+	NbrInterfaceId uint32
+	NbrRouterId    uint32
+}
+
+type Ospfv3RouterLsa struct {
+	BitV    bool
+	BitE    bool
+	BitB    bool
+	Options uint32
+	Links   []Ospfv3RouterLink
+}
+
+func encodeOspfv3RouterLsa(lsa Ospfv3RouterLsa) []byte {
+	pkt := make([]byte, 4+16*len(lsa.Links))
+	binary.BigEndian.PutUint32(pkt[0:4], lsa.Options&0xffffff)
+	if lsa.BitV {
+		pkt[0] |= 0x04
+	}
+	if lsa.BitE {
+		pkt[0] |= 0x02
+	}
+	if lsa.BitB {
+		pkt[0] |= 0x01
+	}
+	for i, link := range lsa.Links {
+		off := 4 + 16*i
+		pkt[off] = link.Type
+		binary.BigEndian.PutUint16(pkt[off+2:off+4], link.Metric)
+		binary.BigEndian.PutUint32(pkt[off+4:off+8], link.InterfaceId)
+		binary.BigEndian.PutUint32(pkt[off+8:off+12], link.NbrInterfaceId)
+		binary.BigEndian.PutUint32(pkt[off+12:off+16], link.NbrRouterId)
+	}
+	return pkt
+}
+
+func decodeOspfv3RouterLsa(body []byte, lsa *Ospfv3RouterLsa) error {
+	if len(body) < 4 {
+		return errors.New("Invalid length of Ospfv3 Router LSA")
+	}
+	lsa.BitV = body[0]&0x04 != 0
+	lsa.BitE = body[0]&0x02 != 0
+	lsa.BitB = body[0]&0x01 != 0
+	lsa.Options = binary.BigEndian.Uint32(body[0:4]) & 0xffffff
+	lsa.Links = nil
+	for off := 4; off+16 <= len(body); off += 16 {
+		lsa.Links = append(lsa.Links, Ospfv3RouterLink{
+			Type:           body[off],
+			Metric:         binary.BigEndian.Uint16(body[off+2 : off+4]),
+			InterfaceId:    binary.BigEndian.Uint32(body[off+4 : off+8]),
+			NbrInterfaceId: binary.BigEndian.Uint32(body[off+8 : off+12]),
+			NbrRouterId:    binary.BigEndian.Uint32(body[off+12 : off+16]),
+		})
+	}
+	return nil
+}
+
+/* Network-LSA: options followed by the attached routers */
+type Ospfv3NetworkLsa struct {
+	Options     uint32
+	AttachedRtr []uint32
+}
+
+func encodeOspfv3NetworkLsa(lsa Ospfv3NetworkLsa) []byte {
+	pkt := make([]byte, 4+4*len(lsa.AttachedRtr))
+	binary.BigEndian.PutUint32(pkt[0:4], lsa.Options&0xffffff)
+	for i, rtr := range lsa.AttachedRtr {
+		binary.BigEndian.PutUint32(pkt[4+4*i:], rtr)
+	}
+	return pkt
+}
+
+func decodeOspfv3NetworkLsa(body []byte, lsa *Ospfv3NetworkLsa) error {
+	if len(body) < 4 {
+		return errors.New("Invalid length of Ospfv3 Network LSA")
+	}
+	lsa.Options = binary.BigEndian.Uint32(body[0:4]) & 0xffffff
+	lsa.AttachedRtr = nil
+	for off := 4; off+4 <= len(body); off += 4 {
+		lsa.AttachedRtr = append(lsa.AttachedRtr, binary.BigEndian.Uint32(body[off:off+4]))
+	}
+	return nil
+}
+
+/* Inter-Area-Prefix-LSA: 24 bit metric and one prefix */
+type Ospfv3InterAreaPrefixLsa struct {
+	Metric uint32
+	Prefix Ospfv3Prefix
+}
+
+func encodeOspfv3InterAreaPrefixLsa(lsa Ospfv3InterAreaPrefixLsa) []byte {
+	pkt := make([]byte, 4)
+	binary.BigEndian.PutUint32(pkt[0:4], lsa.Metric&0xffffff)
+	prefix := lsa.Prefix
+	prefix.Metric = 0
+	return append(pkt, encodeOspfv3Prefix(prefix)...)
+}
+
+func decodeOspfv3InterAreaPrefixLsa(body []byte, lsa *Ospfv3InterAreaPrefixLsa) error {
+	if len(body) < 4 {
+		return errors.New("Invalid length of Ospfv3 Inter-Area-Prefix LSA")
+	}
+	lsa.Metric = binary.BigEndian.Uint32(body[0:4]) & 0xffffff
+	prefix, _, err := decodeOspfv3Prefix(body[4:])
+	lsa.Prefix = prefix
+	return err
+}
+
+/* Inter-Area-Router-LSA: options, 24 bit metric and the ASBR router id */
+type Ospfv3InterAreaRouterLsa struct {
+	Options     uint32
+	Metric      uint32
+	DestRouteId uint32
+}
+
+func encodeOspfv3InterAreaRouterLsa(lsa Ospfv3InterAreaRouterLsa) []byte {
+	pkt := make([]byte, 12)
+	binary.BigEndian.PutUint32(pkt[0:4], lsa.Options&0xffffff)
+	binary.BigEndian.PutUint32(pkt[4:8], lsa.Metric&0xffffff)
+	binary.BigEndian.PutUint32(pkt[8:12], lsa.DestRouteId)
+	return pkt
+}
+
+func decodeOspfv3InterAreaRouterLsa(body []byte, lsa *Ospfv3InterAreaRouterLsa) error {
+	if len(body) < 12 {
+		return errors.New("Invalid length of Ospfv3 Inter-Area-Router LSA")
+	}
+	lsa.Options = binary.BigEndian.Uint32(body[0:4]) & 0xffffff
+	lsa.Metric = binary.BigEndian.Uint32(body[4:8]) & 0xffffff
+	lsa.DestRouteId = binary.BigEndian.Uint32(body[8:12])
+	return nil
+}
+
+/*
+   Link-LSA: priority, options, the link local address of the
+   originating interface and the prefixes configured on the link.
+*/
+type Ospfv3LinkLsa struct {
+	RtrPrio   uint8
+	Options   uint32
+	LinkLocal net.IP
+	Prefixes  []Ospfv3Prefix
+}
+
+func encodeOspfv3LinkLsa(lsa Ospfv3LinkLsa) []byte {
+	pkt := make([]byte, 24)
+	binary.BigEndian.PutUint32(pkt[0:4], lsa.Options&0xffffff)
+	pkt[0] = lsa.RtrPrio
+	copy(pkt[4:20], lsa.LinkLocal.To16())
+	binary.BigEndian.PutUint32(pkt[20:24], uint32(len(lsa.Prefixes)))
+	for _, prefix := range lsa.Prefixes {
+		prefix.Metric = 0
+		pkt = append(pkt, encodeOspfv3Prefix(prefix)...)
+	}
+	return pkt
+}
+
+func decodeOspfv3LinkLsa(body []byte, lsa *Ospfv3LinkLsa) error {
+	if len(body) < 24 {
+		return errors.New("Invalid length of Ospfv3 Link LSA")
+	}
+	lsa.RtrPrio = body[0]
+	lsa.Options = binary.BigEndian.Uint32(body[0:4]) & 0xffffff
+	lsa.LinkLocal = make(net.IP, net.IPv6len)
+	copy(lsa.LinkLocal, body[4:20])
+	numOfPrefix := int(binary.BigEndian.Uint32(body[20:24]))
+	lsa.Prefixes = nil
+	off := 24
+	for i := 0; i < numOfPrefix; i++ {
+		prefix, n, err := decodeOspfv3Prefix(body[off:])
+		if err != nil {
+			return err
+		}
+		lsa.Prefixes = append(lsa.Prefixes, prefix)
+		off += n
+	}
+	return nil
+}
+
+/*
+   Intra-Area-Prefix-LSA: the prefixes of a router (referencing its
+   Router-LSA) or of a transit link (referencing the Network-LSA).
+*/
+type Ospfv3IntraAreaPrefixLsa struct {
+	RefLSType    uint16
+	RefLSId      uint32
+	RefAdvRouter uint32
+	Prefixes     []Ospfv3Prefix
+}
+
+func encodeOspfv3IntraAreaPrefixLsa(lsa Ospfv3IntraAreaPrefixLsa) []byte {
+	pkt := make([]byte, 12)
+	binary.BigEndian.PutUint16(pkt[0:2], uint16(len(lsa.Prefixes)))
+	binary.BigEndian.PutUint16(pkt[2:4], lsa.RefLSType)
+	binary.BigEndian.PutUint32(pkt[4:8], lsa.RefLSId)
+	binary.BigEndian.PutUint32(pkt[8:12], lsa.RefAdvRouter)
+	for _, prefix := range lsa.Prefixes {
+		pkt = append(pkt, encodeOspfv3Prefix(prefix)...)
+	}
+	return pkt
+}
+
+func decodeOspfv3IntraAreaPrefixLsa(body []byte, lsa *Ospfv3IntraAreaPrefixLsa) error {
+	if len(body) < 12 {
+		return errors.New("Invalid length of Ospfv3 Intra-Area-Prefix LSA")
+	}
+	numOfPrefix := int(binary.BigEndian.Uint16(body[0:2]))
+	lsa.RefLSType = binary.BigEndian.Uint16(body[2:4])
+	lsa.RefLSId = binary.BigEndian.Uint32(body[4:8])
+	lsa.RefAdvRouter = binary.BigEndian.Uint32(body[8:12])
+	lsa.Prefixes = nil
+	off := 12
+	for i := 0; i < numOfPrefix; i++ {
+		prefix, n, err := decodeOspfv3Prefix(body[off:])
+		if err != nil {
+			return err
+		}
+		lsa.Prefixes = append(lsa.Prefixes, prefix)
+		off += n
+	}
+	return nil
+}
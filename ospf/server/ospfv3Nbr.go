@@ -0,0 +1,551 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"l3/ospf/config"
+	"time"
+)
+
+/* LS database an LSA type belongs to, by its flooding scope */
+func (inst *Ospfv3Instance) getOspfv3Lsdb(intf *Ospfv3Intf, areaId uint32, lsType uint16) map[Ospfv3LsaKey]Ospfv3Lsa {
+	switch lsaScopeV3(lsType) {
+	case LinkScopeV3:
+		if intf == nil {
+			return nil
+		}
+		return intf.LinkLsdb
+	case AreaScopeV3:
+		db, exist := inst.AreaLsdb[areaId]
+		if !exist {
+			db = make(map[Ospfv3LsaKey]Ospfv3Lsa)
+			inst.AreaLsdb[areaId] = db
+		}
+		return db
+	case ASScopeV3:
+		return inst.ASLsdb
+	}
+	return nil
+}
+
+func (inst *Ospfv3Instance) installOspfv3Lsa(db map[Ospfv3LsaKey]Ospfv3Lsa, lsa Ospfv3Lsa) {
+	key := lsa.Hdr.key()
+	cur, exist := db[key]
+	db[key] = lsa
+	if !exist || !bytes.Equal(cur.Data[OSPFV3_LSA_HEADER_SIZE:], lsa.Data[OSPFV3_LSA_HEADER_SIZE:]) ||
+		(cur.Hdr.LSAge == LSA_MAX_AGE_V3) != (lsa.Hdr.LSAge == LSA_MAX_AGE_V3) {
+		inst.spfPending = true
+		if lsa.Hdr.LSType == LinkLSAV3 {
+			// The prefixes of the link are advertised by the DR
+			inst.originatePending = true
+		}
+	}
+}
+
+/* Premature aging, the MaxAge copy is kept until it is acknowledged */
+func (inst *Ospfv3Instance) flushOspfv3Lsa(db map[Ospfv3LsaKey]Ospfv3Lsa, key Ospfv3LsaKey, areaId uint32, intf *Ospfv3Intf) {
+	lsa, exist := db[key]
+	if !exist || lsa.Hdr.LSAge == LSA_MAX_AGE_V3 {
+		return
+	}
+	lsa.Hdr.LSAge = LSA_MAX_AGE_V3
+	lsa.RcvdTime = time.Now()
+	lsa.SelfOrig = false
+	db[key] = lsa
+	inst.spfPending = true
+	inst.floodOspfv3Lsa(lsa, areaId, intf, nil)
+}
+
+func (inst *Ospfv3Instance) onOspfv3RetxList(key Ospfv3LsaKey) bool {
+	for _, intf := range inst.IntfMap {
+		for _, nbr := range intf.NbrMap {
+			if _, exist := nbr.retxList[key]; exist {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (inst *Ospfv3Instance) ageOspfv3Db(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, intf *Ospfv3Intf) {
+	for key, lsa := range db {
+		if lsa.age() < LSA_MAX_AGE_V3 {
+			continue
+		}
+		if lsa.Hdr.LSAge != LSA_MAX_AGE_V3 {
+			lsa.Hdr.LSAge = LSA_MAX_AGE_V3
+			lsa.RcvdTime = time.Now()
+			db[key] = lsa
+			inst.spfPending = true
+			inst.floodOspfv3Lsa(lsa, areaId, intf, nil)
+		} else if !inst.onOspfv3RetxList(key) {
+			delete(db, key)
+		}
+	}
+}
+
+func (inst *Ospfv3Instance) ageOspfv3Lsdb() {
+	for _, intf := range inst.IntfMap {
+		inst.ageOspfv3Db(intf.LinkLsdb, intf.AreaId, intf)
+	}
+	for areaId, db := range inst.AreaLsdb {
+		inst.ageOspfv3Db(db, areaId, nil)
+	}
+	inst.ageOspfv3Db(inst.ASLsdb, 0, nil)
+}
+
+/*
+   RFC 2328 13.3, intf is the interface the LSA was received on (or the
+   link of a link scope LSA) and nbr the neighbor it was received from.
+*/
+func (inst *Ospfv3Instance) floodOspfv3Lsa(lsa Ospfv3Lsa, areaId uint32, rcvIntf *Ospfv3Intf, rcvNbr *Ospfv3Nbr) {
+	key := lsa.Hdr.key()
+	scope := lsaScopeV3(lsa.Hdr.LSType)
+	for _, intf := range inst.IntfMap {
+		if intf.State == config.Down {
+			continue
+		}
+		if scope == LinkScopeV3 && intf != rcvIntf {
+			continue
+		}
+		if scope == AreaScopeV3 && intf.AreaId != areaId {
+			continue
+		}
+		if intf == rcvIntf && rcvNbr != nil {
+			if rcvNbr.RouterId == intf.DRtrId || rcvNbr.RouterId == intf.BDRtrId ||
+				intf.State == config.BackupDesignatedRouter {
+				continue
+			}
+		}
+		var nbrs []*Ospfv3Nbr
+		for _, nbr := range intf.NbrMap {
+			if nbr != rcvNbr && nbr.State >= config.NbrExchange {
+				nbrs = append(nbrs, nbr)
+			}
+		}
+		if len(nbrs) == 0 {
+			continue
+		}
+		for _, nbr := range nbrs {
+			nbr.retxList[key] = lsa.header()
+			if nbr.retxTimer <= 0 {
+				nbr.retxTimer = OSPFV3_RXMT_INTERVAL
+			}
+		}
+		dst := ALLSPFROUTERV6
+		if intf.State == config.OtherDesignatedRouter {
+			dst = ALLDROUTERV6
+		}
+		inst.sendOspfv3Multicast(intf, LSUpdateType, encodeOspfv3LsaUpd([][]byte{lsa.encode()}), dst)
+	}
+}
+
+/* Pack LSAs into as few LS Update bodies as the MTU allows */
+func splitOspfv3LsUpd(lsas [][]byte, maxLen int) [][]byte {
+	var bodies [][]byte
+	var cur [][]byte
+	curLen := OSPFV3_LSU_MIN_SIZE
+	for _, lsa := range lsas {
+		if len(cur) > 0 && curLen+len(lsa) > maxLen {
+			bodies = append(bodies, encodeOspfv3LsaUpd(cur))
+			cur = nil
+			curLen = OSPFV3_LSU_MIN_SIZE
+		}
+		cur = append(cur, lsa)
+		curLen += len(lsa)
+	}
+	if len(cur) > 0 {
+		bodies = append(bodies, encodeOspfv3LsaUpd(cur))
+	}
+	return bodies
+}
+
+func (inst *Ospfv3Instance) sendOspfv3LsUpd(intf *Ospfv3Intf, nbr *Ospfv3Nbr, lsas [][]byte) {
+	for _, body := range splitOspfv3LsUpd(lsas, intf.maxBodyLen()) {
+		inst.sendOspfv3Unicast(intf, nbr, LSUpdateType, body)
+	}
+}
+
+func (inst *Ospfv3Instance) sendOspfv3LsAck(intf *Ospfv3Intf, nbr *Ospfv3Nbr, hdrs []Ospfv3LsaHeader) {
+	maxHdrs := intf.maxBodyLen() / OSPFV3_LSA_HEADER_SIZE
+	for len(hdrs) > 0 {
+		n := len(hdrs)
+		if n > maxHdrs {
+			n = maxHdrs
+		}
+		inst.sendOspfv3Unicast(intf, nbr, LSAckType, encodeOspfv3LsaAck(hdrs[:n]))
+		hdrs = hdrs[n:]
+	}
+}
+
+func (inst *Ospfv3Instance) buildOspfv3SummaryList(intf *Ospfv3Intf) []Ospfv3LsaHeader {
+	var hdrs []Ospfv3LsaHeader
+	dbs := []map[Ospfv3LsaKey]Ospfv3Lsa{intf.LinkLsdb, inst.AreaLsdb[intf.AreaId], inst.ASLsdb}
+	for _, db := range dbs {
+		for _, lsa := range db {
+			hdr := lsa.header()
+			if hdr.LSAge < LSA_MAX_AGE_V3 {
+				hdrs = append(hdrs, hdr)
+			}
+		}
+	}
+	return hdrs
+}
+
+func (inst *Ospfv3Instance) startOspfv3ExStart(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	inst.resetOspfv3Adjacency(intf, nbr, config.NbrExchangeStart)
+	nbr.master = true
+	nbr.ddSeqNum = uint32(time.Now().Unix())
+	dd := Ospfv3DDData{
+		options:  V6Option | EV3Option | RV3Option,
+		mtu:      intf.Mtu,
+		flags:    DD_I_BIT | DD_M_BIT | DD_MS_BIT,
+		ddSeqNum: nbr.ddSeqNum,
+	}
+	inst.sendOspfv3DD(intf, nbr, dd)
+}
+
+func (inst *Ospfv3Instance) sendOspfv3DD(intf *Ospfv3Intf, nbr *Ospfv3Nbr, dd Ospfv3DDData) {
+	body := encodeOspfv3DDData(dd)
+	nbr.lastDD = body
+	nbr.lastSentMore = dd.flags&DD_M_BIT != 0
+	nbr.ddRetxTimer = OSPFV3_RXMT_INTERVAL
+	inst.sendOspfv3Unicast(intf, nbr, DBDescriptionType, body)
+}
+
+func (inst *Ospfv3Instance) sendNextOspfv3DD(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	maxHdrs := (intf.maxBodyLen() - OSPFV3_DD_MIN_SIZE) / OSPFV3_LSA_HEADER_SIZE
+	n := len(nbr.summaryList)
+	if n > maxHdrs {
+		n = maxHdrs
+	}
+	dd := Ospfv3DDData{
+		options:    V6Option | EV3Option | RV3Option,
+		mtu:        intf.Mtu,
+		ddSeqNum:   nbr.ddSeqNum,
+		lsaHeaders: nbr.summaryList[:n],
+	}
+	nbr.summaryList = nbr.summaryList[n:]
+	if len(nbr.summaryList) > 0 {
+		dd.flags |= DD_M_BIT
+	}
+	if nbr.master {
+		dd.flags |= DD_MS_BIT
+	}
+	inst.sendOspfv3DD(intf, nbr, dd)
+}
+
+func (inst *Ospfv3Instance) isDuplicateOspfv3DD(nbr *Ospfv3Nbr, dd Ospfv3DDData) bool {
+	if nbr.master {
+		return dd.ddSeqNum == nbr.ddSeqNum-1
+	}
+	return dd.ddSeqNum == nbr.ddSeqNum
+}
+
+func (inst *Ospfv3Instance) processOspfv3DD(intf *Ospfv3Intf, nbr *Ospfv3Nbr, data []byte) error {
+	var dd Ospfv3DDData
+	err := decodeOspfv3DDData(data, &dd)
+	if err != nil {
+		return err
+	}
+	if dd.mtu > intf.Mtu {
+		return errors.New(fmt.Sprintln("Neighbor MTU", dd.mtu, "is larger than ours", intf.Mtu))
+	}
+	switch nbr.State {
+	case config.NbrExchangeStart:
+		if dd.flags&(DD_I_BIT|DD_M_BIT|DD_MS_BIT) == (DD_I_BIT|DD_M_BIT|DD_MS_BIT) &&
+			len(dd.lsaHeaders) == 0 && nbr.RouterId > inst.RouterId {
+			nbr.master = false
+			nbr.ddSeqNum = dd.ddSeqNum
+			inst.ospfv3NegotiationDone(intf, nbr)
+			inst.sendNextOspfv3DD(intf, nbr)
+			return nil
+		}
+		if dd.flags&(DD_I_BIT|DD_MS_BIT) == 0 && dd.ddSeqNum == nbr.ddSeqNum &&
+			nbr.RouterId < inst.RouterId {
+			nbr.master = true
+			inst.ospfv3NegotiationDone(intf, nbr)
+			return inst.processOspfv3DDExchange(intf, nbr, dd)
+		}
+		return nil
+	case config.NbrExchange:
+		if inst.isDuplicateOspfv3DD(nbr, dd) {
+			if !nbr.master {
+				inst.sendOspfv3Unicast(intf, nbr, DBDescriptionType, nbr.lastDD)
+			}
+			return nil
+		}
+		expSeqNum := nbr.ddSeqNum + 1
+		if nbr.master {
+			expSeqNum = nbr.ddSeqNum
+		}
+		if (dd.flags&DD_MS_BIT != 0) == nbr.master || dd.flags&DD_I_BIT != 0 ||
+			dd.ddSeqNum != expSeqNum {
+			inst.startOspfv3ExStart(intf, nbr)
+			return errors.New("DD sequence number mismatch")
+		}
+		return inst.processOspfv3DDExchange(intf, nbr, dd)
+	case config.NbrLoading, config.NbrFull:
+		if inst.isDuplicateOspfv3DD(nbr, dd) {
+			if !nbr.master {
+				inst.sendOspfv3Unicast(intf, nbr, DBDescriptionType, nbr.lastDD)
+			}
+			return nil
+		}
+		inst.startOspfv3ExStart(intf, nbr)
+		return errors.New("DD sequence number mismatch")
+	}
+	return nil
+}
+
+func (inst *Ospfv3Instance) ospfv3NegotiationDone(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	nbr.State = config.NbrExchange
+	nbr.summaryList = inst.buildOspfv3SummaryList(intf)
+	inst.server.logger.Info(fmt.Sprintln("Ospfv3: exchange with", convertUint32ToIPv4(nbr.RouterId), "master", nbr.master))
+}
+
+func (inst *Ospfv3Instance) processOspfv3DDExchange(intf *Ospfv3Intf, nbr *Ospfv3Nbr, dd Ospfv3DDData) error {
+	for _, hdr := range dd.lsaHeaders {
+		db := inst.getOspfv3Lsdb(intf, intf.AreaId, hdr.LSType)
+		if db == nil {
+			inst.startOspfv3ExStart(intf, nbr)
+			return errors.New(fmt.Sprintln("Invalid LS type", hdr.LSType, "in DD"))
+		}
+		lsa, exist := db[hdr.key()]
+		if !exist || compareOspfv3Lsa(hdr, lsa.header()) > 0 {
+			nbr.reqList[hdr.key()] = hdr
+		}
+	}
+	moreRcvd := dd.flags&DD_M_BIT != 0
+	if nbr.master {
+		nbr.ddSeqNum++
+		if !nbr.lastSentMore && !moreRcvd {
+			inst.ospfv3ExchangeDone(intf, nbr)
+			return nil
+		}
+		inst.sendNextOspfv3DD(intf, nbr)
+		return nil
+	}
+	nbr.ddSeqNum = dd.ddSeqNum
+	inst.sendNextOspfv3DD(intf, nbr)
+	if !nbr.lastSentMore && !moreRcvd {
+		inst.ospfv3ExchangeDone(intf, nbr)
+	}
+	return nil
+}
+
+func (inst *Ospfv3Instance) ospfv3ExchangeDone(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	if len(nbr.reqList) == 0 {
+		inst.ospfv3NbrFull(intf, nbr)
+		return
+	}
+	nbr.State = config.NbrLoading
+	inst.sendOspfv3LsReq(intf, nbr)
+}
+
+func (inst *Ospfv3Instance) ospfv3NbrFull(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	nbr.State = config.NbrFull
+	inst.originatePending = true
+	inst.spfPending = true
+	inst.server.logger.Info(fmt.Sprintln("Ospfv3: adjacency with", convertUint32ToIPv4(nbr.RouterId), "on", intf.IfName, "is full"))
+}
+
+func (inst *Ospfv3Instance) sendOspfv3LsReq(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	maxReqs := intf.maxBodyLen() / OSPFV3_LSA_REQ_SIZE
+	var reqs []Ospfv3LsaKey
+	for key, _ := range nbr.reqList {
+		reqs = append(reqs, key)
+		if len(reqs) == maxReqs {
+			break
+		}
+	}
+	if len(reqs) == 0 {
+		return
+	}
+	nbr.reqRetxTimer = OSPFV3_RXMT_INTERVAL
+	inst.sendOspfv3Unicast(intf, nbr, LSRequestType, encodeOspfv3LsaReq(reqs))
+}
+
+func (inst *Ospfv3Instance) processOspfv3LsReq(intf *Ospfv3Intf, nbr *Ospfv3Nbr, data []byte) error {
+	if nbr.State < config.NbrExchange {
+		return nil
+	}
+	var lsas [][]byte
+	for _, key := range decodeOspfv3LsaReq(data) {
+		db := inst.getOspfv3Lsdb(intf, intf.AreaId, key.LSType)
+		lsa, exist := db[key]
+		if !exist {
+			inst.startOspfv3ExStart(intf, nbr)
+			return errors.New(fmt.Sprintln("Bad LS request for", key))
+		}
+		lsas = append(lsas, lsa.encode())
+	}
+	inst.sendOspfv3LsUpd(intf, nbr, lsas)
+	return nil
+}
+
+func (inst *Ospfv3Instance) nbrExchanging() bool {
+	for _, intf := range inst.IntfMap {
+		for _, nbr := range intf.NbrMap {
+			if nbr.State == config.NbrExchange || nbr.State == config.NbrLoading {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* RFC 2328 13 */
+func (inst *Ospfv3Instance) processOspfv3LsUpd(intf *Ospfv3Intf, nbr *Ospfv3Nbr, data []byte) error {
+	if nbr.State < config.NbrExchange {
+		return nil
+	}
+	rawLsas, err := decodeOspfv3LsaUpd(data)
+	if err != nil {
+		return err
+	}
+	var acks []Ospfv3LsaHeader
+	var newer [][]byte
+	for _, raw := range rawLsas {
+		lsa, err := decodeOspfv3Lsa(raw)
+		if err != nil {
+			inst.server.logger.Err(fmt.Sprintln("Ospfv3: Dropped LSA from", convertUint32ToIPv4(nbr.RouterId), err))
+			continue
+		}
+		db := inst.getOspfv3Lsdb(intf, intf.AreaId, lsa.Hdr.LSType)
+		if db == nil {
+			continue
+		}
+		key := lsa.Hdr.key()
+		cur, exist := db[key]
+		if lsa.Hdr.LSAge == LSA_MAX_AGE_V3 && !exist && !inst.nbrExchanging() {
+			acks = append(acks, lsa.Hdr)
+			continue
+		}
+		cmp := 1
+		if exist {
+			cmp = compareOspfv3Lsa(lsa.Hdr, cur.header())
+		}
+		if cmp > 0 {
+			if exist && time.Since(cur.RcvdTime) < time.Second {
+				// MinLSArrival
+				continue
+			}
+			delete(nbr.reqList, key)
+			delete(nbr.retxList, key)
+			acks = append(acks, lsa.Hdr)
+			if key.AdvRouter == inst.RouterId {
+				// A stale self originated instance, the next origination
+				// either supersedes or flushes it
+				lsa.SelfOrig = false
+				db[key] = lsa
+				inst.originatePending = true
+				continue
+			}
+			inst.installOspfv3Lsa(db, lsa)
+			inst.floodOspfv3Lsa(lsa, intf.AreaId, intf, nbr)
+		} else if cmp == 0 {
+			delete(nbr.reqList, key)
+			if _, exist := nbr.retxList[key]; exist {
+				// Implied acknowledgment
+				delete(nbr.retxList, key)
+			} else {
+				acks = append(acks, lsa.Hdr)
+			}
+		} else {
+			if _, exist := nbr.reqList[key]; exist {
+				inst.startOspfv3ExStart(intf, nbr)
+				return errors.New(fmt.Sprintln("Bad LS request for", key))
+			}
+			newer = append(newer, cur.encode())
+		}
+	}
+	if len(acks) > 0 {
+		inst.sendOspfv3LsAck(intf, nbr, acks)
+	}
+	if len(newer) > 0 {
+		inst.sendOspfv3LsUpd(intf, nbr, newer)
+	}
+	if nbr.State == config.NbrLoading {
+		if len(nbr.reqList) == 0 {
+			inst.ospfv3NbrFull(intf, nbr)
+		} else {
+			inst.sendOspfv3LsReq(intf, nbr)
+		}
+	}
+	return nil
+}
+
+func (inst *Ospfv3Instance) processOspfv3LsAck(intf *Ospfv3Intf, nbr *Ospfv3Nbr, data []byte) error {
+	if nbr.State < config.NbrExchange {
+		return nil
+	}
+	for _, hdr := range decodeOspfv3LsaAck(data) {
+		ent, exist := nbr.retxList[hdr.key()]
+		if exist && compareOspfv3Lsa(hdr, ent) == 0 {
+			delete(nbr.retxList, hdr.key())
+		}
+	}
+	return nil
+}
+
+/* DD, LS request and LS update retransmission */
+func (inst *Ospfv3Instance) nbrRetransmit(intf *Ospfv3Intf, nbr *Ospfv3Nbr) {
+	if nbr.lastDD != nil && (nbr.State == config.NbrExchangeStart ||
+		(nbr.State == config.NbrExchange && nbr.master)) {
+		nbr.ddRetxTimer--
+		if nbr.ddRetxTimer <= 0 {
+			nbr.ddRetxTimer = OSPFV3_RXMT_INTERVAL
+			inst.sendOspfv3Unicast(intf, nbr, DBDescriptionType, nbr.lastDD)
+		}
+	}
+	if nbr.State == config.NbrLoading && len(nbr.reqList) > 0 {
+		nbr.reqRetxTimer--
+		if nbr.reqRetxTimer <= 0 {
+			inst.sendOspfv3LsReq(intf, nbr)
+		}
+	}
+	if len(nbr.retxList) == 0 {
+		return
+	}
+	nbr.retxTimer--
+	if nbr.retxTimer > 0 {
+		return
+	}
+	var lsas [][]byte
+	for key, hdr := range nbr.retxList {
+		db := inst.getOspfv3Lsdb(intf, intf.AreaId, key.LSType)
+		lsa, exist := db[key]
+		if !exist || lsa.Hdr.LSSequenceNum != hdr.LSSequenceNum {
+			delete(nbr.retxList, key)
+			continue
+		}
+		lsas = append(lsas, lsa.encode())
+	}
+	inst.sendOspfv3LsUpd(intf, nbr, lsas)
+	nbr.retxTimer = OSPFV3_RXMT_INTERVAL
+}
@@ -0,0 +1,363 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"net"
+)
+
+const (
+	OSPFV3_VERSION         = 3
+	OSPFV3_HEADER_SIZE     = 16
+	OSPFV3_HELLO_MIN_SIZE  = 20
+	OSPFV3_DD_MIN_SIZE     = 12
+	OSPFV3_LSA_HEADER_SIZE = 20
+	OSPFV3_LSA_REQ_SIZE    = 12
+	OSPFV3_LSU_MIN_SIZE    = 4
+	IPV6_HEADER_SIZE       = 40
+)
+
+var ALLSPFROUTERV6 string = "ff02::5"
+var ALLDROUTERV6 string = "ff02::6"
+
+/* Options, RFC 5340 A.2 */
+const (
+	V6Option  = 0x01
+	EV3Option = 0x02
+	RV3Option = 0x10
+)
+
+/* DD flags */
+const (
+	DD_MS_BIT = 0x01
+	DD_M_BIT  = 0x02
+	DD_I_BIT  = 0x04
+)
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |   Version #   |     Type      |         Packet length         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                         Router ID                             |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                          Area ID                              |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |          Checksum             |  Instance ID  |      0        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type Ospfv3Header struct {
+	ver        uint8
+	pktType    uint8
+	pktlen     uint16
+	routerId   uint32
+	areaId     uint32
+	chksum     uint16
+	instanceId uint8
+}
+
+func encodeOspfv3Hdr(hdr Ospfv3Header) []byte {
+	pkt := make([]byte, OSPFV3_HEADER_SIZE)
+	pkt[0] = hdr.ver
+	pkt[1] = hdr.pktType
+	binary.BigEndian.PutUint16(pkt[2:4], hdr.pktlen)
+	binary.BigEndian.PutUint32(pkt[4:8], hdr.routerId)
+	binary.BigEndian.PutUint32(pkt[8:12], hdr.areaId)
+	binary.BigEndian.PutUint16(pkt[12:14], hdr.chksum)
+	pkt[14] = hdr.instanceId
+	return pkt
+}
+
+func decodeOspfv3Hdr(pkt []byte, hdr *Ospfv3Header) error {
+	if len(pkt) < OSPFV3_HEADER_SIZE {
+		return errors.New("Invalid length of Ospfv3 Header")
+	}
+	hdr.ver = pkt[0]
+	hdr.pktType = pkt[1]
+	hdr.pktlen = binary.BigEndian.Uint16(pkt[2:4])
+	hdr.routerId = binary.BigEndian.Uint32(pkt[4:8])
+	hdr.areaId = binary.BigEndian.Uint32(pkt[8:12])
+	hdr.chksum = binary.BigEndian.Uint16(pkt[12:14])
+	hdr.instanceId = pkt[14]
+	if int(hdr.pktlen) < OSPFV3_HEADER_SIZE || int(hdr.pktlen) > len(pkt) {
+		return errors.New("Invalid Ospfv3 packet length")
+	}
+	return nil
+}
+
+/*
+   OSPFv3 has no checksum of its own, it uses the IPv6 upper layer
+   checksum over the pseudo header (RFC 5340 2.7).
+*/
+func computeOspfv3CheckSum(srcIP net.IP, dstIP net.IP, pkt []byte) uint16 {
+	pseudo := make([]byte, 40, 40+len(pkt)+1)
+	copy(pseudo[0:16], srcIP.To16())
+	copy(pseudo[16:32], dstIP.To16())
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(len(pkt)))
+	pseudo[39] = OSPF_PROTO_ID
+	pseudo = append(pseudo, pkt...)
+	if len(pseudo)%2 != 0 {
+		pseudo = append(pseudo, 0)
+	}
+	return computeCheckSum(pseudo)
+}
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                        Interface ID                           |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   | Rtr Priority  |             Options                           |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |        HelloInterval          |       RouterDeadInterval      |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                   Designated Router ID                        |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                Backup Designated Router ID                    |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                         Neighbor ID                           |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+type Ospfv3HelloData struct {
+	interfaceId   uint32
+	rtrPrio       uint8
+	options       uint32
+	helloInterval uint16
+	deadInterval  uint16
+	dRtrId        uint32
+	bdRtrId       uint32
+	neighbors     []uint32
+}
+
+func encodeOspfv3HelloData(hello Ospfv3HelloData) []byte {
+	pkt := make([]byte, OSPFV3_HELLO_MIN_SIZE+4*len(hello.neighbors))
+	binary.BigEndian.PutUint32(pkt[0:4], hello.interfaceId)
+	binary.BigEndian.PutUint32(pkt[4:8], hello.options)
+	pkt[4] = hello.rtrPrio
+	binary.BigEndian.PutUint16(pkt[8:10], hello.helloInterval)
+	binary.BigEndian.PutUint16(pkt[10:12], hello.deadInterval)
+	binary.BigEndian.PutUint32(pkt[12:16], hello.dRtrId)
+	binary.BigEndian.PutUint32(pkt[16:20], hello.bdRtrId)
+	for i, nbr := range hello.neighbors {
+		binary.BigEndian.PutUint32(pkt[OSPFV3_HELLO_MIN_SIZE+4*i:], nbr)
+	}
+	return pkt
+}
+
+func decodeOspfv3HelloData(data []byte, hello *Ospfv3HelloData) error {
+	if len(data) < OSPFV3_HELLO_MIN_SIZE {
+		return errors.New("Invalid length of Ospfv3 Hello")
+	}
+	hello.interfaceId = binary.BigEndian.Uint32(data[0:4])
+	hello.rtrPrio = data[4]
+	hello.options = binary.BigEndian.Uint32(data[4:8]) & 0xffffff
+	hello.helloInterval = binary.BigEndian.Uint16(data[8:10])
+	hello.deadInterval = binary.BigEndian.Uint16(data[10:12])
+	hello.dRtrId = binary.BigEndian.Uint32(data[12:16])
+	hello.bdRtrId = binary.BigEndian.Uint32(data[16:20])
+	hello.neighbors = nil
+	for i := OSPFV3_HELLO_MIN_SIZE; i+4 <= len(data); i += 4 {
+		hello.neighbors = append(hello.neighbors, binary.BigEndian.Uint32(data[i:i+4]))
+	}
+	return nil
+}
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |       0       |               Options                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |        Interface MTU          |      0        |0|0|0|0|0|I|M|MS
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                    DD sequence number                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                      An LSA Header                            |
+*/
+type Ospfv3DDData struct {
+	options    uint32
+	mtu        uint16
+	flags      uint8
+	ddSeqNum   uint32
+	lsaHeaders []Ospfv3LsaHeader
+}
+
+func encodeOspfv3DDData(dd Ospfv3DDData) []byte {
+	pkt := make([]byte, OSPFV3_DD_MIN_SIZE)
+	binary.BigEndian.PutUint32(pkt[0:4], dd.options&0xffffff)
+	binary.BigEndian.PutUint16(pkt[4:6], dd.mtu)
+	pkt[7] = dd.flags
+	binary.BigEndian.PutUint32(pkt[8:12], dd.ddSeqNum)
+	for _, hdr := range dd.lsaHeaders {
+		pkt = append(pkt, encodeOspfv3LsaHeader(hdr)...)
+	}
+	return pkt
+}
+
+func decodeOspfv3DDData(data []byte, dd *Ospfv3DDData) error {
+	if len(data) < OSPFV3_DD_MIN_SIZE {
+		return errors.New("Invalid length of Ospfv3 DD")
+	}
+	dd.options = binary.BigEndian.Uint32(data[0:4]) & 0xffffff
+	dd.mtu = binary.BigEndian.Uint16(data[4:6])
+	dd.flags = data[7]
+	dd.ddSeqNum = binary.BigEndian.Uint32(data[8:12])
+	dd.lsaHeaders = nil
+	for i := OSPFV3_DD_MIN_SIZE; i+OSPFV3_LSA_HEADER_SIZE <= len(data); i += OSPFV3_LSA_HEADER_SIZE {
+		var hdr Ospfv3LsaHeader
+		decodeOspfv3LsaHeader(data[i:], &hdr)
+		dd.lsaHeaders = append(dd.lsaHeaders, hdr)
+	}
+	return nil
+}
+
+/*
+    0                   1                   2                   3
+    0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |              0                |        LS Type                |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                         Link State ID                         |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+   |                       Advertising Router                      |
+   +-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+-+
+*/
+func encodeOspfv3LsaReq(reqs []Ospfv3LsaKey) []byte {
+	pkt := make([]byte, OSPFV3_LSA_REQ_SIZE*len(reqs))
+	for i, req := range reqs {
+		off := OSPFV3_LSA_REQ_SIZE * i
+		binary.BigEndian.PutUint16(pkt[off+2:off+4], req.LSType)
+		binary.BigEndian.PutUint32(pkt[off+4:off+8], req.LSId)
+		binary.BigEndian.PutUint32(pkt[off+8:off+12], req.AdvRouter)
+	}
+	return pkt
+}
+
+func decodeOspfv3LsaReq(data []byte) []Ospfv3LsaKey {
+	var reqs []Ospfv3LsaKey
+	for off := 0; off+OSPFV3_LSA_REQ_SIZE <= len(data); off += OSPFV3_LSA_REQ_SIZE {
+		reqs = append(reqs, Ospfv3LsaKey{
+			LSType:    binary.BigEndian.Uint16(data[off+2 : off+4]),
+			LSId:      binary.BigEndian.Uint32(data[off+4 : off+8]),
+			AdvRouter: binary.BigEndian.Uint32(data[off+8 : off+12]),
+		})
+	}
+	return reqs
+}
+
+/* LS Update carries a count followed by complete LSAs */
+func encodeOspfv3LsaUpd(lsas [][]byte) []byte {
+	pkt := make([]byte, OSPFV3_LSU_MIN_SIZE)
+	binary.BigEndian.PutUint32(pkt[0:4], uint32(len(lsas)))
+	for _, lsa := range lsas {
+		pkt = append(pkt, lsa...)
+	}
+	return pkt
+}
+
+func decodeOspfv3LsaUpd(data []byte) ([][]byte, error) {
+	if len(data) < OSPFV3_LSU_MIN_SIZE {
+		return nil, errors.New("Invalid length of Ospfv3 LS Update")
+	}
+	numOfLsa := int(binary.BigEndian.Uint32(data[0:4]))
+	var lsas [][]byte
+	off := OSPFV3_LSU_MIN_SIZE
+	for i := 0; i < numOfLsa; i++ {
+		if off+OSPFV3_LSA_HEADER_SIZE > len(data) {
+			return lsas, errors.New("Truncated Ospfv3 LS Update")
+		}
+		lsaLen := int(binary.BigEndian.Uint16(data[off+18 : off+20]))
+		if lsaLen < OSPFV3_LSA_HEADER_SIZE || off+lsaLen > len(data) {
+			return lsas, errors.New("Invalid LSA length in Ospfv3 LS Update")
+		}
+		lsas = append(lsas, data[off:off+lsaLen])
+		off += lsaLen
+	}
+	return lsas, nil
+}
+
+func encodeOspfv3LsaAck(hdrs []Ospfv3LsaHeader) []byte {
+	var pkt []byte
+	for _, hdr := range hdrs {
+		pkt = append(pkt, encodeOspfv3LsaHeader(hdr)...)
+	}
+	return pkt
+}
+
+func decodeOspfv3LsaAck(data []byte) []Ospfv3LsaHeader {
+	var hdrs []Ospfv3LsaHeader
+	for off := 0; off+OSPFV3_LSA_HEADER_SIZE <= len(data); off += OSPFV3_LSA_HEADER_SIZE {
+		var hdr Ospfv3LsaHeader
+		decodeOspfv3LsaHeader(data[off:], &hdr)
+		hdrs = append(hdrs, hdr)
+	}
+	return hdrs
+}
+
+/*
+   Encapsulate an OSPFv3 packet body, filling in the header and the
+   pseudo header checksum, into an ethernet frame sourced from the
+   link local address of the interface.
+*/
+func buildOspfv3Pkt(hdr Ospfv3Header, body []byte, srcMAC net.HardwareAddr, dstMAC net.HardwareAddr, srcIP net.IP, dstIP net.IP) []byte {
+	hdr.ver = OSPFV3_VERSION
+	hdr.pktlen = uint16(OSPFV3_HEADER_SIZE + len(body))
+	hdr.chksum = 0
+	ospf := append(encodeOspfv3Hdr(hdr), body...)
+	csum := computeOspfv3CheckSum(srcIP, dstIP, ospf)
+	binary.BigEndian.PutUint16(ospf[12:14], csum)
+
+	ipLayer := layers.IPv6{
+		Version:      uint8(6),
+		TrafficClass: uint8(0xc0),
+		HopLimit:     uint8(1),
+		NextHeader:   layers.IPProtocol(OSPF_PROTO_ID),
+		SrcIP:        srcIP,
+		DstIP:        dstIP,
+	}
+	ethLayer := layers.Ethernet{
+		SrcMAC:       srcMAC,
+		DstMAC:       dstMAC,
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	options := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	gopacket.SerializeLayers(buffer, options, &ethLayer, &ipLayer, gopacket.Payload(ospf))
+	return buffer.Bytes()
+}
+
+/* Multicast MAC for an IPv6 group address, RFC 2464 7 */
+func ipv6MulticastMAC(ip net.IP) net.HardwareAddr {
+	ip = ip.To16()
+	return net.HardwareAddr{0x33, 0x33, ip[12], ip[13], ip[14], ip[15]}
+}
@@ -0,0 +1,798 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"ribd"
+	"sort"
+	"strconv"
+)
+
+const (
+	Ospfv3IntraArea uint8 = 1
+	Ospfv3InterArea uint8 = 2
+)
+
+var Ospfv3PathTypeList = []string{
+	"",
+	"IntraArea",
+	"InterArea"}
+
+const OSPFV3_LS_INFINITY uint32 = 0xffffff
+
+type Ospfv3NextHop struct {
+	IfIndex   int32
+	NextHopIp string
+}
+
+type Ospfv3Route struct {
+	Prefix   Ospfv3Prefix
+	AreaId   uint32
+	PathType uint8
+	Cost     uint32
+	NextHops []Ospfv3NextHop
+}
+
+/*
+   LSA bodies are built in a stable order so that an unchanged LSA is
+   not reoriginated.
+*/
+type Ospfv3IntfList []*Ospfv3Intf
+
+func (l Ospfv3IntfList) Len() int           { return len(l) }
+func (l Ospfv3IntfList) Less(i, j int) bool { return l[i].IfIndex < l[j].IfIndex }
+func (l Ospfv3IntfList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type Ospfv3NbrList []*Ospfv3Nbr
+
+func (l Ospfv3NbrList) Len() int           { return len(l) }
+func (l Ospfv3NbrList) Less(i, j int) bool { return l[i].RouterId < l[j].RouterId }
+func (l Ospfv3NbrList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type Ospfv3PrefixList []Ospfv3Prefix
+
+func (l Ospfv3PrefixList) Len() int           { return len(l) }
+func (l Ospfv3PrefixList) Less(i, j int) bool { return l[i].String() < l[j].String() }
+func (l Ospfv3PrefixList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+
+type Ospfv3NextHopList []Ospfv3NextHop
+
+func (l Ospfv3NextHopList) Len() int { return len(l) }
+func (l Ospfv3NextHopList) Less(i, j int) bool {
+	if l[i].IfIndex != l[j].IfIndex {
+		return l[i].IfIndex < l[j].IfIndex
+	}
+	return l[i].NextHopIp < l[j].NextHopIp
+}
+func (l Ospfv3NextHopList) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+func (inst *Ospfv3Instance) sortedOspfv3Intfs() []*Ospfv3Intf {
+	var intfs []*Ospfv3Intf
+	for _, intf := range inst.IntfMap {
+		intfs = append(intfs, intf)
+	}
+	sort.Sort(Ospfv3IntfList(intfs))
+	return intfs
+}
+
+func sortedOspfv3Nbrs(intf *Ospfv3Intf) []*Ospfv3Nbr {
+	var nbrs []*Ospfv3Nbr
+	for _, nbr := range intf.NbrMap {
+		nbrs = append(nbrs, nbr)
+	}
+	sort.Sort(Ospfv3NbrList(nbrs))
+	return nbrs
+}
+
+func (inst *Ospfv3Instance) ospfv3AttachedAreas() map[uint32]bool {
+	areas := make(map[uint32]bool)
+	for _, intf := range inst.IntfMap {
+		if intf.State != config.Down {
+			areas[intf.AreaId] = true
+		}
+	}
+	return areas
+}
+
+/*
+   Transit link of a broadcast interface, RFC 5340 4.4.3.2. It is only
+   advertised once the interface is fully adjacent to the DR.
+*/
+func (inst *Ospfv3Instance) ospfv3TransitLink(intf *Ospfv3Intf) (nbrIfId uint32, drRtrId uint32, ok bool) {
+	if isPointToPointV3(intf) || intf.State == config.Down || intf.State == config.Waiting || intf.DRtrId == 0 {
+		return 0, 0, false
+	}
+	if intf.DRtrId == inst.RouterId {
+		for _, nbr := range intf.NbrMap {
+			if nbr.State == config.NbrFull {
+				return intf.InterfaceId, inst.RouterId, true
+			}
+		}
+		return 0, 0, false
+	}
+	nbr, exist := intf.NbrMap[intf.DRtrId]
+	if !exist || nbr.State != config.NbrFull {
+		return 0, 0, false
+	}
+	return nbr.InterfaceId, nbr.RouterId, true
+}
+
+func (inst *Ospfv3Instance) originateOspfv3Lsa(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, intf *Ospfv3Intf,
+	lsType uint16, lsId uint32, body []byte, want map[Ospfv3LsaKey]bool) {
+	key := Ospfv3LsaKey{
+		LSType:    lsType,
+		LSId:      lsId,
+		AdvRouter: inst.RouterId,
+	}
+	want[key] = true
+	cur, exist := db[key]
+	if exist && cur.SelfOrig && cur.age() < LSA_REFRESH_TIME_V3 &&
+		bytes.Equal(cur.Data[OSPFV3_LSA_HEADER_SIZE:], body) {
+		return
+	}
+	hdr := Ospfv3LsaHeader{
+		LSType:        lsType,
+		LSId:          lsId,
+		AdvRouter:     inst.RouterId,
+		LSSequenceNum: OSPFV3_INITIAL_SEQ_NUM,
+	}
+	if exist {
+		hdr.LSSequenceNum = cur.Hdr.LSSequenceNum + 1
+	}
+	lsa := buildOspfv3Lsa(hdr, body)
+	lsa.SelfOrig = true
+	inst.installOspfv3Lsa(db, lsa)
+	inst.floodOspfv3Lsa(lsa, areaId, intf, nil)
+}
+
+func (inst *Ospfv3Instance) flushStaleOspfv3Lsas(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, intf *Ospfv3Intf, want map[Ospfv3LsaKey]bool) {
+	for key, lsa := range db {
+		if key.AdvRouter == inst.RouterId && !want[key] && lsa.Hdr.LSAge != LSA_MAX_AGE_V3 {
+			inst.flushOspfv3Lsa(db, key, areaId, intf)
+		}
+	}
+}
+
+/*
+   Self originated LSAs are rebuilt from the interface and neighbor
+   state; unchanged LSAs are only reoriginated at the refresh time and
+   LSAs that are no longer wanted are flushed.
+*/
+func (inst *Ospfv3Instance) originateOspfv3Lsas() {
+	if !inst.AdminStat || inst.RouterId == 0 {
+		return
+	}
+	areas := inst.ospfv3AttachedAreas()
+	isABR := len(areas) > 1
+	for areaId, _ := range areas {
+		db := inst.getOspfv3Lsdb(nil, areaId, RouterLSAV3)
+		want := make(map[Ospfv3LsaKey]bool)
+		inst.originateOspfv3RouterLsa(db, areaId, isABR, want)
+		inst.originateOspfv3NetworkLsas(db, areaId, want)
+		inst.originateOspfv3IntraAreaPrefixLsa(db, areaId, want)
+		if isABR {
+			inst.originateOspfv3InterAreaPrefixLsas(db, areaId, want)
+		}
+		inst.flushStaleOspfv3Lsas(db, areaId, nil, want)
+	}
+	for areaId, db := range inst.AreaLsdb {
+		if !areas[areaId] {
+			inst.flushStaleOspfv3Lsas(db, areaId, nil, make(map[Ospfv3LsaKey]bool))
+		}
+	}
+	inst.flushStaleOspfv3Lsas(inst.ASLsdb, 0, nil, make(map[Ospfv3LsaKey]bool))
+	for _, intf := range inst.IntfMap {
+		want := make(map[Ospfv3LsaKey]bool)
+		if intf.State != config.Down {
+			inst.originateOspfv3LinkLsa(intf, want)
+		}
+		inst.flushStaleOspfv3Lsas(intf.LinkLsdb, intf.AreaId, intf, want)
+	}
+}
+
+func (inst *Ospfv3Instance) originateOspfv3RouterLsa(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, isABR bool, want map[Ospfv3LsaKey]bool) {
+	rtrLsa := Ospfv3RouterLsa{
+		BitB:    isABR,
+		Options: V6Option | EV3Option | RV3Option,
+	}
+	for _, intf := range inst.sortedOspfv3Intfs() {
+		if intf.AreaId != areaId || intf.State == config.Down {
+			continue
+		}
+		if isPointToPointV3(intf) {
+			for _, nbr := range sortedOspfv3Nbrs(intf) {
+				if nbr.State != config.NbrFull {
+					continue
+				}
+				rtrLsa.Links = append(rtrLsa.Links, Ospfv3RouterLink{
+					Type:           P2PLinkV3,
+					Metric:         intf.Cost,
+					InterfaceId:    intf.InterfaceId,
+					NbrInterfaceId: nbr.InterfaceId,
+					NbrRouterId:    nbr.RouterId,
+				})
+			}
+			continue
+		}
+		nbrIfId, drRtrId, ok := inst.ospfv3TransitLink(intf)
+		if ok {
+			rtrLsa.Links = append(rtrLsa.Links, Ospfv3RouterLink{
+				Type:           TransitLinkV3,
+				Metric:         intf.Cost,
+				InterfaceId:    intf.InterfaceId,
+				NbrInterfaceId: nbrIfId,
+				NbrRouterId:    drRtrId,
+			})
+		}
+	}
+	inst.originateOspfv3Lsa(db, areaId, nil, RouterLSAV3, 0, encodeOspfv3RouterLsa(rtrLsa), want)
+}
+
+/*
+   As DR, a Network-LSA for the link and an Intra-Area-Prefix-LSA
+   carrying the prefixes from the Link-LSAs of the attached routers.
+*/
+func (inst *Ospfv3Instance) originateOspfv3NetworkLsas(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, want map[Ospfv3LsaKey]bool) {
+	for _, intf := range inst.sortedOspfv3Intfs() {
+		if intf.AreaId != areaId || intf.DRtrId != inst.RouterId {
+			continue
+		}
+		if _, _, ok := inst.ospfv3TransitLink(intf); !ok {
+			continue
+		}
+		netLsa := Ospfv3NetworkLsa{
+			Options:     V6Option | EV3Option | RV3Option,
+			AttachedRtr: []uint32{inst.RouterId},
+		}
+		prefixes := make(map[string]Ospfv3Prefix)
+		for _, prefix := range intf.Prefixes {
+			prefixes[prefix.String()] = prefix
+		}
+		for _, nbr := range sortedOspfv3Nbrs(intf) {
+			if nbr.State != config.NbrFull {
+				continue
+			}
+			netLsa.AttachedRtr = append(netLsa.AttachedRtr, nbr.RouterId)
+			for key, lsa := range intf.LinkLsdb {
+				if key.LSType != LinkLSAV3 || key.AdvRouter != nbr.RouterId || lsa.Hdr.LSAge == LSA_MAX_AGE_V3 {
+					continue
+				}
+				var linkLsa Ospfv3LinkLsa
+				if decodeOspfv3LinkLsa(lsa.Data[OSPFV3_LSA_HEADER_SIZE:], &linkLsa) != nil {
+					continue
+				}
+				for _, prefix := range linkLsa.Prefixes {
+					if prefix.PrefixOptions&(PrefixNUBit|PrefixLABit) == 0 {
+						prefixes[prefix.String()] = prefix
+					}
+				}
+			}
+		}
+		inst.originateOspfv3Lsa(db, areaId, nil, NetworkLSAV3, intf.InterfaceId, encodeOspfv3NetworkLsa(netLsa), want)
+
+		iapLsa := Ospfv3IntraAreaPrefixLsa{
+			RefLSType:    NetworkLSAV3,
+			RefLSId:      intf.InterfaceId,
+			RefAdvRouter: inst.RouterId,
+		}
+		for _, prefix := range prefixes {
+			prefix.Metric = 0
+			iapLsa.Prefixes = append(iapLsa.Prefixes, prefix)
+		}
+		if len(iapLsa.Prefixes) == 0 {
+			continue
+		}
+		sort.Sort(Ospfv3PrefixList(iapLsa.Prefixes))
+		inst.originateOspfv3Lsa(db, areaId, nil, IntraAreaPrefixLSAV3, intf.InterfaceId, encodeOspfv3IntraAreaPrefixLsa(iapLsa), want)
+	}
+}
+
+/* Prefixes of the interfaces which are not transit links, referencing the Router-LSA */
+func (inst *Ospfv3Instance) originateOspfv3IntraAreaPrefixLsa(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, want map[Ospfv3LsaKey]bool) {
+	iapLsa := Ospfv3IntraAreaPrefixLsa{
+		RefLSType:    RouterLSAV3,
+		RefLSId:      0,
+		RefAdvRouter: inst.RouterId,
+	}
+	for _, intf := range inst.sortedOspfv3Intfs() {
+		if intf.AreaId != areaId || intf.State == config.Down {
+			continue
+		}
+		if _, _, ok := inst.ospfv3TransitLink(intf); ok {
+			continue
+		}
+		for _, prefix := range intf.Prefixes {
+			prefix.Metric = intf.Cost
+			iapLsa.Prefixes = append(iapLsa.Prefixes, prefix)
+		}
+	}
+	if len(iapLsa.Prefixes) == 0 {
+		return
+	}
+	inst.originateOspfv3Lsa(db, areaId, nil, IntraAreaPrefixLSAV3, 0, encodeOspfv3IntraAreaPrefixLsa(iapLsa), want)
+}
+
+/*
+   As ABR, summarize the intra-area routes of every other area, and the
+   inter-area routes learnt over the backbone into the non backbone areas.
+*/
+func (inst *Ospfv3Instance) originateOspfv3InterAreaPrefixLsas(db map[Ospfv3LsaKey]Ospfv3Lsa, areaId uint32, want map[Ospfv3LsaKey]bool) {
+	for key, route := range inst.RouteTbl {
+		if route.AreaId == areaId || route.Cost >= OSPFV3_LS_INFINITY {
+			continue
+		}
+		if route.PathType == Ospfv3InterArea && (route.AreaId != 0 || areaId == 0) {
+			continue
+		}
+		lsId, exist := inst.interAreaLsId[key]
+		if !exist {
+			inst.nextInterAreaId++
+			lsId = inst.nextInterAreaId
+			inst.interAreaLsId[key] = lsId
+		}
+		iapLsa := Ospfv3InterAreaPrefixLsa{
+			Metric: route.Cost,
+			Prefix: route.Prefix,
+		}
+		inst.originateOspfv3Lsa(db, areaId, nil, InterAreaPrefixLSAV3, lsId, encodeOspfv3InterAreaPrefixLsa(iapLsa), want)
+	}
+}
+
+func (inst *Ospfv3Instance) originateOspfv3LinkLsa(intf *Ospfv3Intf, want map[Ospfv3LsaKey]bool) {
+	linkLsa := Ospfv3LinkLsa{
+		RtrPrio:   intf.RtrPrio,
+		Options:   V6Option | EV3Option | RV3Option,
+		LinkLocal: intf.LinkLocal,
+	}
+	linkLsa.Prefixes = append(linkLsa.Prefixes, intf.Prefixes...)
+	sort.Sort(Ospfv3PrefixList(linkLsa.Prefixes))
+	inst.originateOspfv3Lsa(intf.LinkLsdb, intf.AreaId, intf, LinkLSAV3, intf.InterfaceId, encodeOspfv3LinkLsa(linkLsa), want)
+}
+
+/*
+   Topology only graph of an area from the Router and Network LSAs,
+   RFC 5340 4.8.1. Links are only used when both ends advertise them.
+*/
+func (inst *Ospfv3Instance) buildOspfv3AreaGraph(areaId uint32, db map[Ospfv3LsaKey]Ospfv3Lsa) map[VertexKey]Vertex {
+	rtrLinks := make(map[uint32][]Ospfv3RouterLink)
+	nets := make(map[VertexKey][]uint32)
+	for key, lsa := range db {
+		if lsa.Hdr.LSAge == LSA_MAX_AGE_V3 {
+			continue
+		}
+		body := lsa.Data[OSPFV3_LSA_HEADER_SIZE:]
+		switch key.LSType {
+		case RouterLSAV3:
+			var rtrLsa Ospfv3RouterLsa
+			if decodeOspfv3RouterLsa(body, &rtrLsa) != nil {
+				continue
+			}
+			rtrLinks[key.AdvRouter] = append(rtrLinks[key.AdvRouter], rtrLsa.Links...)
+		case NetworkLSAV3:
+			var netLsa Ospfv3NetworkLsa
+			if decodeOspfv3NetworkLsa(body, &netLsa) != nil {
+				continue
+			}
+			nKey := VertexKey{
+				Type:   TNetworkVertex,
+				ID:     key.LSId,
+				AdvRtr: key.AdvRouter,
+			}
+			nets[nKey] = netLsa.AttachedRtr
+		}
+	}
+	hasLink := func(rtrId uint32, linkType uint8, nbrIfId uint32, nbrRtrId uint32) bool {
+		for _, link := range rtrLinks[rtrId] {
+			if link.Type == linkType && link.NbrRouterId == nbrRtrId &&
+				(linkType == P2PLinkV3 || link.NbrInterfaceId == nbrIfId) {
+				return true
+			}
+		}
+		return false
+	}
+	attached := func(nKey VertexKey, rtrId uint32) bool {
+		for _, rtr := range nets[nKey] {
+			if rtr == rtrId {
+				return true
+			}
+		}
+		return false
+	}
+
+	graph := make(map[VertexKey]Vertex)
+	for rtrId, links := range rtrLinks {
+		vKey := VertexKey{
+			Type:   RouterVertex,
+			ID:     rtrId,
+			AdvRtr: rtrId,
+		}
+		vertex := Vertex{
+			LinkData: make(map[VertexKey]uint32),
+			AreaId:   areaId,
+		}
+		for _, link := range links {
+			var nKey VertexKey
+			switch link.Type {
+			case P2PLinkV3:
+				if !hasLink(link.NbrRouterId, P2PLinkV3, 0, rtrId) {
+					continue
+				}
+				nKey = VertexKey{
+					Type:   RouterVertex,
+					ID:     link.NbrRouterId,
+					AdvRtr: link.NbrRouterId,
+				}
+			case TransitLinkV3:
+				nKey = VertexKey{
+					Type:   TNetworkVertex,
+					ID:     link.NbrInterfaceId,
+					AdvRtr: link.NbrRouterId,
+				}
+				if !attached(nKey, rtrId) {
+					continue
+				}
+			default:
+				continue
+			}
+			vertex.NbrVertexKey = append(vertex.NbrVertexKey, nKey)
+			vertex.NbrVertexCost = append(vertex.NbrVertexCost, link.Metric)
+			vertex.LinkData[nKey] = link.InterfaceId
+		}
+		graph[vKey] = vertex
+	}
+	for nKey, rtrs := range nets {
+		vertex := Vertex{
+			LinkData:    make(map[VertexKey]uint32),
+			AreaId:      areaId,
+			LinkStateId: nKey.ID,
+		}
+		for _, rtrId := range rtrs {
+			if !hasLink(rtrId, TransitLinkV3, nKey.ID, nKey.AdvRtr) {
+				continue
+			}
+			rKey := VertexKey{
+				Type:   RouterVertex,
+				ID:     rtrId,
+				AdvRtr: rtrId,
+			}
+			vertex.NbrVertexKey = append(vertex.NbrVertexKey, rKey)
+			vertex.NbrVertexCost = append(vertex.NbrVertexCost, 0)
+		}
+		graph[nKey] = vertex
+	}
+	return graph
+}
+
+func (inst *Ospfv3Instance) findOspfv3P2PIntf(rtrId uint32) *Ospfv3Intf {
+	for _, intf := range inst.sortedOspfv3Intfs() {
+		if !isPointToPointV3(intf) {
+			continue
+		}
+		if nbr, exist := intf.NbrMap[rtrId]; exist && nbr.State == config.NbrFull {
+			return intf
+		}
+	}
+	return nil
+}
+
+func (inst *Ospfv3Instance) findOspfv3TransitIntf(nKey VertexKey) *Ospfv3Intf {
+	for _, intf := range inst.sortedOspfv3Intfs() {
+		nbrIfId, drRtrId, ok := inst.ospfv3TransitLink(intf)
+		if ok && nbrIfId == nKey.ID && drRtrId == nKey.AdvRtr {
+			return intf
+		}
+	}
+	return nil
+}
+
+/* Link local address of a neighbor, as advertised in its Link-LSA */
+func (inst *Ospfv3Instance) ospfv3NbrLinkLocal(intf *Ospfv3Intf, rtrId uint32) net.IP {
+	for key, lsa := range intf.LinkLsdb {
+		if key.LSType != LinkLSAV3 || key.AdvRouter != rtrId || lsa.Hdr.LSAge == LSA_MAX_AGE_V3 {
+			continue
+		}
+		var linkLsa Ospfv3LinkLsa
+		if decodeOspfv3LinkLsa(lsa.Data[OSPFV3_LSA_HEADER_SIZE:], &linkLsa) == nil &&
+			linkLsa.LinkLocal.IsLinkLocalUnicast() {
+			return linkLsa.LinkLocal
+		}
+	}
+	if nbr, exist := intf.NbrMap[rtrId]; exist {
+		return nbr.Addr
+	}
+	return nil
+}
+
+/*
+   Next hops are derived from the first hop of each path: the neighbor
+   itself over a point to point link, or the router following the
+   directly attached transit network.
+*/
+func (inst *Ospfv3Instance) ospfv3NextHops(spfTree map[VertexKey]TreeVertex, vKey VertexKey) []Ospfv3NextHop {
+	var nextHops []Ospfv3NextHop
+	tEnt, exist := spfTree[vKey]
+	if !exist {
+		return nil
+	}
+	for _, path := range tEnt.Paths {
+		hops := append(append(Path{}, path...), vKey)
+		if len(hops) < 2 {
+			continue
+		}
+		var intf *Ospfv3Intf
+		var rtrId uint32
+		if hops[1].Type == RouterVertex {
+			rtrId = hops[1].ID
+			intf = inst.findOspfv3P2PIntf(rtrId)
+		} else {
+			if len(hops) == 2 {
+				// Destination is on the attached network
+				continue
+			}
+			rtrId = hops[2].ID
+			intf = inst.findOspfv3TransitIntf(hops[1])
+		}
+		if intf == nil {
+			continue
+		}
+		addr := inst.ospfv3NbrLinkLocal(intf, rtrId)
+		if addr == nil {
+			continue
+		}
+		nextHop := Ospfv3NextHop{
+			IfIndex:   intf.IfIndex,
+			NextHopIp: addr.String(),
+		}
+		nextHops = mergeOspfv3NextHops(nextHops, []Ospfv3NextHop{nextHop})
+	}
+	return nextHops
+}
+
+func mergeOspfv3NextHops(nextHops []Ospfv3NextHop, add []Ospfv3NextHop) []Ospfv3NextHop {
+	for _, nextHop := range add {
+		found := false
+		for _, ent := range nextHops {
+			if ent == nextHop {
+				found = true
+				break
+			}
+		}
+		if !found {
+			nextHops = append(nextHops, nextHop)
+		}
+	}
+	sort.Sort(Ospfv3NextHopList(nextHops))
+	return nextHops
+}
+
+/* Intra-area routes are preferred to inter-area ones, then the lowest cost */
+func addOspfv3Route(tbl map[string]Ospfv3Route, prefix Ospfv3Prefix, areaId uint32, pathType uint8, cost uint32, nextHops []Ospfv3NextHop) {
+	prefix.Metric = 0
+	prefix.PrefixOptions = 0
+	key := prefix.String()
+	cur, exist := tbl[key]
+	if exist {
+		if cur.PathType < pathType || (cur.PathType == pathType && cur.Cost < cost) {
+			return
+		}
+		if cur.PathType == pathType && cur.Cost == cost {
+			cur.NextHops = mergeOspfv3NextHops(cur.NextHops, nextHops)
+			tbl[key] = cur
+			return
+		}
+	}
+	tbl[key] = Ospfv3Route{
+		Prefix:   prefix,
+		AreaId:   areaId,
+		PathType: pathType,
+		Cost:     cost,
+		NextHops: mergeOspfv3NextHops(nil, nextHops),
+	}
+}
+
+func (inst *Ospfv3Instance) addOspfv3IntraAreaRoutes(tbl map[string]Ospfv3Route, areaId uint32, db map[Ospfv3LsaKey]Ospfv3Lsa, spfTree map[VertexKey]TreeVertex) {
+	for key, lsa := range db {
+		if key.LSType != IntraAreaPrefixLSAV3 || lsa.Hdr.LSAge == LSA_MAX_AGE_V3 {
+			continue
+		}
+		var iapLsa Ospfv3IntraAreaPrefixLsa
+		if decodeOspfv3IntraAreaPrefixLsa(lsa.Data[OSPFV3_LSA_HEADER_SIZE:], &iapLsa) != nil {
+			continue
+		}
+		var vKey VertexKey
+		switch iapLsa.RefLSType {
+		case RouterLSAV3:
+			if iapLsa.RefAdvRouter != key.AdvRouter {
+				continue
+			}
+			vKey = VertexKey{
+				Type:   RouterVertex,
+				ID:     iapLsa.RefAdvRouter,
+				AdvRtr: iapLsa.RefAdvRouter,
+			}
+		case NetworkLSAV3:
+			vKey = VertexKey{
+				Type:   TNetworkVertex,
+				ID:     iapLsa.RefLSId,
+				AdvRtr: iapLsa.RefAdvRouter,
+			}
+		default:
+			continue
+		}
+		tEnt, exist := spfTree[vKey]
+		if !exist {
+			continue
+		}
+		nextHops := inst.ospfv3NextHops(spfTree, vKey)
+		for _, prefix := range iapLsa.Prefixes {
+			if prefix.PrefixOptions&PrefixNUBit != 0 {
+				continue
+			}
+			addOspfv3Route(tbl, prefix, areaId, Ospfv3IntraArea, uint32(tEnt.Distance)+uint32(prefix.Metric), nextHops)
+		}
+	}
+}
+
+func (inst *Ospfv3Instance) addOspfv3InterAreaRoutes(tbl map[string]Ospfv3Route, areaId uint32, db map[Ospfv3LsaKey]Ospfv3Lsa, spfTree map[VertexKey]TreeVertex) {
+	for key, lsa := range db {
+		if key.LSType != InterAreaPrefixLSAV3 || key.AdvRouter == inst.RouterId ||
+			lsa.Hdr.LSAge == LSA_MAX_AGE_V3 {
+			continue
+		}
+		var iapLsa Ospfv3InterAreaPrefixLsa
+		if decodeOspfv3InterAreaPrefixLsa(lsa.Data[OSPFV3_LSA_HEADER_SIZE:], &iapLsa) != nil {
+			continue
+		}
+		if iapLsa.Metric >= OSPFV3_LS_INFINITY || iapLsa.Prefix.PrefixOptions&PrefixNUBit != 0 {
+			continue
+		}
+		abrKey := VertexKey{
+			Type:   RouterVertex,
+			ID:     key.AdvRouter,
+			AdvRtr: key.AdvRouter,
+		}
+		tEnt, exist := spfTree[abrKey]
+		if !exist {
+			continue
+		}
+		nextHops := inst.ospfv3NextHops(spfTree, abrKey)
+		addOspfv3Route(tbl, iapLsa.Prefix, areaId, Ospfv3InterArea, uint32(tEnt.Distance)+iapLsa.Metric, nextHops)
+	}
+}
+
+/*
+   Per area shortest path tree over the topology, then the prefixes of
+   the Intra-Area-Prefix LSAs and, from the backbone when we are an ABR,
+   the Inter-Area-Prefix LSAs are attached to its vertices.
+*/
+func (inst *Ospfv3Instance) computeOspfv3Routes() map[string]Ospfv3Route {
+	tbl := make(map[string]Ospfv3Route)
+	areas := inst.ospfv3AttachedAreas()
+	trees := make(map[uint32]map[VertexKey]TreeVertex)
+	root := VertexKey{
+		Type:   RouterVertex,
+		ID:     inst.RouterId,
+		AdvRtr: inst.RouterId,
+	}
+	for areaId, db := range inst.AreaLsdb {
+		if !areas[areaId] {
+			continue
+		}
+		graph := inst.buildOspfv3AreaGraph(areaId, db)
+		if _, exist := graph[root]; !exist {
+			continue
+		}
+		spfTree := make(map[VertexKey]TreeVertex)
+		err := inst.server.runDijkstra(graph, spfTree, root)
+		if err != nil {
+			inst.server.logger.Err(fmt.Sprintln("Ospfv3: SPF failed for area", convertUint32ToIPv4(areaId), err))
+			continue
+		}
+		trees[areaId] = spfTree
+		inst.addOspfv3IntraAreaRoutes(tbl, areaId, db, spfTree)
+	}
+	isABR := len(areas) > 1
+	for areaId, spfTree := range trees {
+		if isABR && areaId != 0 {
+			continue
+		}
+		inst.addOspfv3InterAreaRoutes(tbl, areaId, inst.AreaLsdb[areaId], spfTree)
+	}
+	return tbl
+}
+
+func ospfv3RouteEqual(a Ospfv3Route, b Ospfv3Route) bool {
+	if a.Cost != b.Cost || len(a.NextHops) != len(b.NextHops) {
+		return false
+	}
+	for idx, _ := range a.NextHops {
+		if a.NextHops[idx] != b.NextHops[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func convertOspfv3RouteToRibd(route Ospfv3Route) ribd.IPv6Route {
+	mask := net.CIDRMask(int(route.Prefix.PrefixLen), 128)
+	cfg := ribd.IPv6Route{
+		DestinationNw: route.Prefix.Prefix.Mask(mask).String(),
+		Protocol:      "OSPF",
+		Cost:          int32(route.Cost),
+		NetworkMask:   net.IP(mask).String(),
+	}
+	cfg.NextHop = make([]*ribd.NextHopInfo, 0)
+	for _, nextHop := range route.NextHops {
+		cfg.NextHop = append(cfg.NextHop, &ribd.NextHopInfo{
+			NextHopIp:     nextHop.NextHopIp,
+			NextHopIntRef: strconv.Itoa(int(nextHop.IfIndex)),
+		})
+	}
+	return cfg
+}
+
+/*
+   Routes without next hops are the directly attached prefixes, which
+   ribd already has as connected routes.
+*/
+func (inst *Ospfv3Instance) installOspfv3Routes(newTbl map[string]Ospfv3Route) {
+	server := inst.server
+	for key, oldRoute := range inst.RouteTbl {
+		newRoute, exist := newTbl[key]
+		if (exist && ospfv3RouteEqual(oldRoute, newRoute)) || len(oldRoute.NextHops) == 0 {
+			continue
+		}
+		cfg := convertOspfv3RouteToRibd(oldRoute)
+		server.logger.Info(fmt.Sprintln("Ospfv3: Deleting route", key, cfg.NextHop))
+		if server.ribdClient.ClientHdl == nil {
+			server.logger.Err("Nil ribd handle. Can not delete route.")
+			continue
+		}
+		_, err := server.ribdClient.ClientHdl.DeleteIPv6Route(&cfg)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("Ospfv3: Error deleting route", key, err))
+		}
+	}
+	for key, newRoute := range newTbl {
+		oldRoute, exist := inst.RouteTbl[key]
+		if (exist && ospfv3RouteEqual(oldRoute, newRoute)) || len(newRoute.NextHops) == 0 {
+			continue
+		}
+		cfg := convertOspfv3RouteToRibd(newRoute)
+		server.logger.Info(fmt.Sprintln("Ospfv3: Installing route", key, "cost", newRoute.Cost, cfg.NextHop))
+		if server.ribdClient.ClientHdl == nil {
+			server.logger.Err("Nil ribd handle. Can not install route.")
+			continue
+		}
+		_, err := server.ribdClient.ClientHdl.CreateIPv6Route(&cfg)
+		if err != nil {
+			server.logger.Err(fmt.Sprintln("Ospfv3: Error installing route", key, err))
+		}
+	}
+	inst.RouteTbl = newTbl
+}
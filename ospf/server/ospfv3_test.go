@@ -0,0 +1,251 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+/* ospfv3_test
+   This test covers
+   1) OSPFv3 packet and LSA encode and decode APIs.
+   2) DR and BDR selection.
+   3) LS update packing.
+   4) Intra area SPF and route calculation.
+*/
+package server
+
+import (
+	"fmt"
+	"l3/ospf/config"
+	"net"
+	"testing"
+)
+
+var v3Prefix = Ospfv3Prefix{
+	PrefixLen: 64,
+	Metric:    10,
+	Prefix:    net.ParseIP("2001:db8:2::"),
+}
+
+func TestOspfv3(t *testing.T) {
+	fmt.Println("\n**************** OSPFV3 ************\n")
+	ospf = getServerObject()
+	for index := 1; index < 6; index++ {
+		err := ospfv3TestLogic(index)
+		if err != SUCCESS {
+			fmt.Println("Failed test case for ospfv3")
+		}
+	}
+}
+
+func ospfv3TestLogic(tNum int) int {
+	switch tNum {
+	case 1:
+		fmt.Println(tNum, ": Running Ospfv3 packet codec tests")
+		checkOspfv3PktCodec()
+	case 2:
+		fmt.Println(tNum, ": Running Ospfv3 LSA codec tests")
+		checkOspfv3LsaCodec()
+	case 3:
+		fmt.Println(tNum, ": Running Ospfv3 DR selection")
+		checkOspfv3DRSelection()
+	case 4:
+		fmt.Println(tNum, ": Running Ospfv3 LS update packing")
+		checkOspfv3LsUpdSplit()
+	case 5:
+		fmt.Println(tNum, ": Running Ospfv3 SPF")
+		checkOspfv3SPF()
+	}
+	return SUCCESS
+}
+
+func checkOspfv3PktCodec() {
+	hello := Ospfv3HelloData{
+		interfaceId:   5,
+		rtrPrio:       1,
+		options:       V6Option | EV3Option | RV3Option,
+		helloInterval: 10,
+		deadInterval:  40,
+		dRtrId:        0x01010101,
+		neighbors:     []uint32{0x02020202, 0x03030303},
+	}
+	body := encodeOspfv3HelloData(hello)
+	var decHello Ospfv3HelloData
+	err := decodeOspfv3HelloData(body, &decHello)
+	fmt.Println("Hello decoded:", decHello, "err:", err)
+
+	hdr := Ospfv3Header{
+		ver:      OSPFV3_VERSION,
+		pktType:  uint8(HelloType),
+		pktlen:   uint16(OSPFV3_HEADER_SIZE + len(body)),
+		routerId: 0x01010101,
+	}
+	pkt := append(encodeOspfv3Hdr(hdr), body...)
+	srcIP := net.ParseIP("fe80::1")
+	dstIP := net.ParseIP(ALLSPFROUTERV6)
+	csum := computeOspfv3CheckSum(srcIP, dstIP, pkt)
+	pkt[12] = byte(csum >> 8)
+	pkt[13] = byte(csum)
+	fmt.Println("Checksum:", csum, "verify:", computeOspfv3CheckSum(srcIP, dstIP, pkt))
+	var decHdr Ospfv3Header
+	err = decodeOspfv3Hdr(pkt, &decHdr)
+	fmt.Println("Header decoded:", decHdr, "err:", err)
+	fmt.Println("Multicast MAC:", ipv6MulticastMAC(dstIP))
+
+	dd := Ospfv3DDData{
+		options:  V6Option | EV3Option | RV3Option,
+		mtu:      1500,
+		flags:    DD_I_BIT | DD_M_BIT | DD_MS_BIT,
+		ddSeqNum: 100,
+	}
+	var decDD Ospfv3DDData
+	err = decodeOspfv3DDData(encodeOspfv3DDData(dd), &decDD)
+	fmt.Println("DD decoded:", decDD, "err:", err)
+}
+
+func checkOspfv3LsaCodec() {
+	rtrLsa := Ospfv3RouterLsa{
+		BitB: true,
+		Links: []Ospfv3RouterLink{
+			{
+				Type:           P2PLinkV3,
+				Metric:         10,
+				InterfaceId:    1,
+				NbrInterfaceId: 2,
+				NbrRouterId:    0x02020202,
+			},
+		},
+	}
+	hdr := Ospfv3LsaHeader{
+		LSType:        RouterLSAV3,
+		AdvRouter:     0x01010101,
+		LSSequenceNum: OSPFV3_INITIAL_SEQ_NUM,
+	}
+	lsa := buildOspfv3Lsa(hdr, encodeOspfv3RouterLsa(rtrLsa))
+	decLsa, err := decodeOspfv3Lsa(lsa.Data)
+	fmt.Println("Router LSA header:", decLsa.Hdr, "err:", err)
+	var decRtrLsa Ospfv3RouterLsa
+	err = decodeOspfv3RouterLsa(decLsa.Data[OSPFV3_LSA_HEADER_SIZE:], &decRtrLsa)
+	fmt.Println("Router LSA body:", decRtrLsa, "err:", err)
+
+	corrupt := append([]byte{}, lsa.Data...)
+	corrupt[len(corrupt)-1] ^= 0xff
+	_, err = decodeOspfv3Lsa(corrupt)
+	fmt.Println("Corrupted LSA err:", err)
+
+	iapLsa := Ospfv3IntraAreaPrefixLsa{
+		RefLSType:    RouterLSAV3,
+		RefAdvRouter: 0x01010101,
+		Prefixes:     []Ospfv3Prefix{v3Prefix},
+	}
+	var decIapLsa Ospfv3IntraAreaPrefixLsa
+	err = decodeOspfv3IntraAreaPrefixLsa(encodeOspfv3IntraAreaPrefixLsa(iapLsa), &decIapLsa)
+	fmt.Println("Intra area prefix LSA:", decIapLsa.Prefixes, "err:", err)
+
+	newer := lsa.Hdr
+	newer.LSSequenceNum++
+	fmt.Println("Compare newer:", compareOspfv3Lsa(newer, lsa.Hdr), "same:", compareOspfv3Lsa(lsa.Hdr, lsa.Hdr))
+}
+
+func checkOspfv3DRSelection() {
+	candidates := []ospfv3DRCandidate{
+		{routerId: 1, prio: 1},
+		{routerId: 2, prio: 1},
+		{routerId: 3, prio: 0},
+	}
+	dr, bdr := selectOspfv3DR(candidates)
+	fmt.Println("Fresh election DR:", dr, "BDR:", bdr)
+	candidates[0].dRtrId = 1
+	candidates[0].routerId = 1
+	dr, bdr = selectOspfv3DR(candidates)
+	fmt.Println("Declared DR:", dr, "BDR:", bdr)
+}
+
+func checkOspfv3LsUpdSplit() {
+	var lsas [][]byte
+	for idx := 0; idx < 10; idx++ {
+		lsas = append(lsas, make([]byte, 200))
+	}
+	bodies := splitOspfv3LsUpd(lsas, 1000)
+	for idx, body := range bodies {
+		upd, err := decodeOspfv3LsaUpd(body)
+		fmt.Println("LS update", idx, "len:", len(body), "lsas:", len(upd), "err:", err)
+	}
+}
+
+func buildOspfv3TestLsdb() map[Ospfv3LsaKey]Ospfv3Lsa {
+	db := make(map[Ospfv3LsaKey]Ospfv3Lsa)
+	rtrIds := []uint32{0x01010101, 0x02020202}
+	for idx, rtrId := range rtrIds {
+		rtrLsa := Ospfv3RouterLsa{
+			Links: []Ospfv3RouterLink{
+				{
+					Type:           P2PLinkV3,
+					Metric:         10,
+					InterfaceId:    uint32(idx + 1),
+					NbrInterfaceId: uint32(2 - idx),
+					NbrRouterId:    rtrIds[1-idx],
+				},
+			},
+		}
+		hdr := Ospfv3LsaHeader{
+			LSType:        RouterLSAV3,
+			AdvRouter:     rtrId,
+			LSSequenceNum: OSPFV3_INITIAL_SEQ_NUM,
+		}
+		lsa := buildOspfv3Lsa(hdr, encodeOspfv3RouterLsa(rtrLsa))
+		db[lsa.Hdr.key()] = lsa
+	}
+	iapLsa := Ospfv3IntraAreaPrefixLsa{
+		RefLSType:    RouterLSAV3,
+		RefAdvRouter: rtrIds[1],
+		Prefixes:     []Ospfv3Prefix{v3Prefix},
+	}
+	hdr := Ospfv3LsaHeader{
+		LSType:        IntraAreaPrefixLSAV3,
+		AdvRouter:     rtrIds[1],
+		LSSequenceNum: OSPFV3_INITIAL_SEQ_NUM,
+	}
+	lsa := buildOspfv3Lsa(hdr, encodeOspfv3IntraAreaPrefixLsa(iapLsa))
+	db[lsa.Hdr.key()] = lsa
+	return db
+}
+
+func checkOspfv3SPF() {
+	inst := newOspfv3Instance(ospf, 0)
+	inst.RouterId = 0x01010101
+	intf := newOspfv3Intf(config.Ospfv3IntfConf{
+		IfIndex:  1,
+		IfAreaId: "0.0.0.0",
+		IfType:   config.NumberedP2P,
+	})
+	intf.State = config.P2P
+	intf.NbrMap[0x02020202] = &Ospfv3Nbr{
+		RouterId: 0x02020202,
+		Addr:     net.ParseIP("fe80::2"),
+		State:    config.NbrFull,
+	}
+	inst.IntfMap[intf.IfIndex] = intf
+	inst.AreaLsdb[0] = buildOspfv3TestLsdb()
+	tbl := inst.computeOspfv3Routes()
+	for prefix, route := range tbl {
+		fmt.Println("Route:", prefix, "cost:", route.Cost, "next hops:", route.NextHops)
+		fmt.Println("Ribd route:", convertOspfv3RouteToRibd(route))
+	}
+}
@@ -33,6 +33,7 @@ import (
 	nanomsg "github.com/op/go-nanomsg"
 	"io/ioutil"
 	"l3/ospf/config"
+	"path"
 	"ribd"
 	"strconv"
 	"sync"
@@ -72,6 +73,7 @@ type LsdbSliceEnt struct {
 type OSPFServer struct {
 	logger                 *logging.Writer
 	ribdClient             RibdClient
+	bfddClient             BfddClient
 	asicdClient            AsicdClient
 	portPropertyMap        map[int32]PortProperty
 	vlanPropertyMap        map[uint16]VlanProperty
@@ -82,6 +84,10 @@ type OSPFServer struct {
 	AreaConfigCh           chan config.AreaConf
 	IntfConfigCh           chan config.InterfaceConf
 	IfMetricConfCh         chan config.IfMetricConf
+	AuthConfigCh           chan AuthConfMsg
+	AggregateConfigCh      chan AggregateConfMsg
+	NbmaNbrConfigCh        chan NbmaNbrConfMsg
+	VirtIfConfigCh         chan VirtIfConfMsg
 	GlobalConfigRetCh      chan error
 	AreaConfigRetCh        chan error
 	IntfConfigRetCh        chan error
@@ -104,6 +110,10 @@ type OSPFServer struct {
 	ribSubSocketCh    chan []byte
 	ribSubSocketErrCh chan error
 
+	bfdSubSocket      *nanomsg.SubSocket
+	bfdSubSocketCh    chan []byte
+	bfdSubSocketErrCh chan error
+
 	asicdSubSocket        *nanomsg.SubSocket
 	asicdSubSocketCh      chan []byte
 	asicdSubSocketErrCh   chan error
@@ -156,17 +166,88 @@ type OSPFServer struct {
 
 	SummaryLsDb map[LsdbKey]SummaryLsaMap
 
-	StartCalcSPFCh chan bool
+	NssaTranslatedLsDb map[LsaKey]ASExternalLsa
+	NssaTranslatedLsa  map[LsaKey]bool
+
+	StartCalcSPFCh chan SpfRequest
 	DoneCalcSPFCh  chan bool
 	AreaGraph      map[VertexKey]Vertex
 	SPFTree        map[VertexKey]TreeVertex
 	AreaStubs      map[VertexKey]StubVertex
 
+	SpfThrottleConfigCh chan config.SpfThrottleConf
+	SpfThrottleMutex    sync.RWMutex
+	SpfThrottle         SpfThrottle
+	SpfAreaCache        map[uint32]SpfAreaCache
+
+	LfaConfigCh   chan LfaConfMsg
+	LfaMutex      sync.RWMutex
+	LfaConf       config.LfaConf
+	LfaExcludeMap map[IntfConfKey]bool
+	LfaBackups    map[VertexKey]LfaBackup
+
+	StubRouterConfigCh chan config.StubRouterConf
+	BgpConvergedCh     chan bool
+	StubRouterMutex    sync.RWMutex
+	StubRouter         StubRouter
+
+	BfdConfigCh   chan BfdConfMsg
+	BfdMutex      sync.RWMutex
+	IfBfdMap      map[IntfConfKey]config.IfBfdConf
+	BfdSessionMap map[NeighborConfKey]BfdSessionEnt
+
+	OpaqueConfigCh   chan OpaqueConfMsg
+	OpaqueConf       config.OpaqueConf
+	OpaqueLinkLsaMap map[IntfConfKey]map[LsaKey]OpaqueLsa
+	SelfOpaqueLsaMap map[SelfOpaqueKey][]byte
+	IfTeMap          map[IntfConfKey]config.IfTeConf
+	TeLinkIdMap      map[IntfConfKey]uint32
+
+	StatsMutex   sync.RWMutex
+	IntfStatsMap map[IntfConfKey]IntfStats
+	NbrStatsMap  map[NeighborConfKey]NbrStats
+	NbrEventLog  []NbrEventRecord
+	NbrEventIdx  int32
+
+	PolicyConfigCh      chan PolicyConfMsg
+	PolicyMutex         sync.RWMutex
+	PolicyConf          config.PolicyConf
+	RouteMapTbl         map[string][]config.RouteMapConf
+	IfPolicyMap         map[IntfConfKey]config.IfPolicyConf
+	RedistRouteMap      map[AggrPrefixKey]RedistRouteEnt
+	DistListFilteredMap map[RoutingTblEntryKey]bool
+
 	dbHdl        *dbutils.DBUtil
 	DbReadConfig chan bool
 	DbRouteOp    chan DbRouteMsg
 	DbLsdbOp     chan DbLsdbMsg
 	DbEventOp    chan DbEventMsg
+
+	AuthDB OspfAuthDB
+
+	AreaRangeMap   map[AreaRangeKey]AreaRangeEnt
+	SummaryAddrMap map[AggrPrefixKey]SummaryAddrEnt
+	ExtRouteMap    map[AggrPrefixKey]RouteMdata
+	AggrDiscardMap map[AggrPrefixKey]bool
+
+	NbmaNbrMutex sync.RWMutex
+	NbmaNbrMap   map[NeighborConfKey]NbmaNbrEnt
+
+	VirtLinkMutex sync.RWMutex
+	VirtLinkMap   map[VirtLinkKey]VirtLinkEnt
+
+	GracefulRestartConfigCh chan config.GracefulRestartConf
+	GraceRestartPrepareCh   chan uint8
+	GraceHelperExitCh       chan GraceHelperExitMsg
+	GraceRestartMutex       sync.RWMutex
+	GraceRestart            GraceRestarter
+	GraceHelperMap          map[NeighborConfKey]GraceHelperEnt
+	GraceHelperExitMap      map[NeighborConfKey]config.RestartExitReason
+
+	Ospfv3ConfigCh    chan Ospfv3ConfMsg
+	Ospfv3Mutex       sync.RWMutex
+	Ospfv3InstanceMap map[uint8]*Ospfv3Instance
+	ipv6PropertyMap   map[int32]IPv6IntfProperty
 }
 
 func NewOSPFServer(logger *logging.Writer) *OSPFServer {
@@ -176,6 +257,10 @@ func NewOSPFServer(logger *logging.Writer) *OSPFServer {
 	ospfServer.AreaConfigCh = make(chan config.AreaConf)
 	ospfServer.IntfConfigCh = make(chan config.InterfaceConf)
 	ospfServer.IfMetricConfCh = make(chan config.IfMetricConf)
+	ospfServer.AuthConfigCh = make(chan AuthConfMsg)
+	ospfServer.AggregateConfigCh = make(chan AggregateConfMsg)
+	ospfServer.NbmaNbrConfigCh = make(chan NbmaNbrConfMsg)
+	ospfServer.VirtIfConfigCh = make(chan VirtIfConfMsg)
 	ospfServer.GlobalConfigRetCh = make(chan error)
 	ospfServer.AreaConfigRetCh = make(chan error)
 	ospfServer.IntfConfigRetCh = make(chan error)
@@ -209,6 +294,7 @@ func NewOSPFServer(logger *logging.Writer) *OSPFServer {
 	ospfServer.neighborFSMCtrlCh = make(chan bool)
 	ospfServer.AreaStateMutex = sync.RWMutex{}
 	ospfServer.AreaStateMap = make(map[AreaConfKey]AreaState)
+	ospfServer.NssaTranslatedLsa = make(map[LsaKey]bool)
 	ospfServer.AreaStateSlice = []AreaConfKey{}
 	ospfServer.AreaConfKeyToSliceIdxMap = make(map[AreaConfKey]int)
 	ospfServer.IntfKeySlice = []IntfConfKey{}
@@ -242,8 +328,24 @@ func NewOSPFServer(logger *logging.Writer) *OSPFServer {
 	ospfServer.TempGlobalRoutingTbl = make(map[RoutingTblEntryKey]GlobalRoutingTblEntry)
 	//ospfServer.OldRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
 	ospfServer.TempAreaRoutingTbl = make(map[AreaIdKey]AreaRoutingTbl)
-	ospfServer.StartCalcSPFCh = make(chan bool)
+	ospfServer.StartCalcSPFCh = make(chan SpfRequest)
 	ospfServer.DoneCalcSPFCh = make(chan bool)
+	ospfServer.SpfThrottleConfigCh = make(chan config.SpfThrottleConf)
+	ospfServer.initSpfThrottle()
+	ospfServer.initLfa()
+	ospfServer.initStubRouter()
+	ospfServer.initBfd()
+	ospfServer.initOpaque()
+	ospfServer.initStats()
+	ospfServer.initPolicy()
+	ospfServer.initAuthDB()
+	ospfServer.initAggregateDB()
+	ospfServer.initNbmaNbrDB()
+	ospfServer.initVirtLinkDB()
+	ospfServer.initGracefulRestart()
+	ospfServer.Ospfv3ConfigCh = make(chan Ospfv3ConfMsg)
+	ospfServer.Ospfv3InstanceMap = make(map[uint8]*Ospfv3Instance)
+	ospfServer.ipv6PropertyMap = make(map[int32]IPv6IntfProperty)
 
 	return ospfServer
 }
@@ -311,6 +413,9 @@ func (server *OSPFServer) ConnectToClients(paramsFile string) {
 			server.logger.Info("Ospfd is connected to Ribd")
 			server.ribdClient.ClientHdl = ribd.NewRIBDServicesClientFactory(server.ribdClient.Transport, server.ribdClient.PtrProtocolFactory)
 			server.ribdClient.IsConnected = true
+		} else if client.Name == "bfdd" {
+			server.logger.Info(fmt.Sprintln("found bfdd at port", client.Port))
+			go server.connectToBfdd("localhost:" + strconv.Itoa(client.Port))
 		}
 	}
 }
@@ -322,6 +427,7 @@ func (server *OSPFServer) InitServer(paramFile string) {
 	server.initAreaConfDefault()
 	server.logger.Info(fmt.Sprintln("AreaConf:", server.AreaConfMap))
 	server.initIntfStateSlice()
+	server.loadAuthSeqNum(path.Join(path.Dir(paramFile), OSPF_AUTH_SEQ_NUM_FILE))
 	server.ConnectToClients(paramFile)
 	server.logger.Info("Listen for ASICd updates")
 	server.listenForASICdUpdates(asicdCommonDefs.PUB_SOCKET_ADDR)
@@ -382,12 +488,52 @@ func (server *OSPFServer) StartServer(paramFile string) {
 			if err == nil {
 
 			}
+		case authConf := <-server.AuthConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Auth Configuration", authConf))
+			err := server.processAuthConfig(authConf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Auth configuration failed", err))
+			}
+		case nbrConf := <-server.NbmaNbrConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Nbma Neighbor Configuration", nbrConf))
+			err := server.processNbmaNbrConfig(nbrConf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Nbma neighbor configuration failed", err))
+			}
+		case virtConf := <-server.VirtIfConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Virtual Link Configuration", virtConf))
+			err := server.processVirtIfConfig(virtConf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Virtual link configuration failed", err))
+			}
+		case msg := <-server.BfdConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing BFD Configuration", msg))
+			err := server.processIfBfdConfig(msg)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("BFD configuration failed", err))
+			}
+		case msg := <-server.PolicyConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Policy Configuration", msg))
+			err := server.processPolicyConfig(msg)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Policy configuration failed", err))
+			}
+		case v3Conf := <-server.Ospfv3ConfigCh:
+			server.logger.Info(fmt.Sprintln("Received call for performing Ospfv3 Configuration", v3Conf))
+			err := server.processOspfv3Config(v3Conf)
+			if err != nil {
+				server.logger.Err(fmt.Sprintln("Ospfv3 configuration failed", err))
+			}
 		case asicdrxBuf := <-server.asicdSubSocketCh:
 			server.processAsicdNotification(asicdrxBuf)
 		case <-server.asicdSubSocketErrCh:
 
 		case ribrxBuf := <-server.ribSubSocketCh:
 			server.processRibdNotification(ribrxBuf)
+		case bfdrxBuf := <-server.bfdSubSocketCh:
+			server.processBfdNotification(bfdrxBuf)
+		case <-server.bfdSubSocketErrCh:
+
 		/*
 		   case <-server.connRoutesTimer.C:
 		       routes, _ := server.ribdClient.ClientHdl.GetConnectedRoutesInfo()
@@ -0,0 +1,301 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// lsdbtool saves the LSDB of a running ospfd to a snapshot file and
+// runs the SPF on a snapshot offline.
+//
+//	lsdbtool export [-params dir | -addr host:port] -f file
+//	lsdbtool spf -f file -root rtrId [-fail-link rtrId,linkId] [-fail-router rtrId] [-diff file]
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"git.apache.org/thrift.git/lib/go/thrift"
+	"io/ioutil"
+	"l3/ospf/config"
+	"l3/ospf/server"
+	"os"
+	"ospfd"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	GETBULK_COUNT   = 100
+	GETBULK_RETRIES = 10
+)
+
+type ClientJson struct {
+	Name string `json:Name`
+	Port int    `json:Port`
+}
+
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *listFlag) Set(val string) error {
+	*l = append(*l, val)
+	return nil
+}
+
+func getOspfdAddr(paramsDir string) (string, error) {
+	var allClients []ClientJson
+	fileName := paramsDir
+	if fileName[len(fileName)-1] != '/' {
+		fileName = fileName + "/"
+	}
+	data, err := ioutil.ReadFile(fileName + "clients.json")
+	if err != nil {
+		return "", err
+	}
+	err = json.Unmarshal(data, &allClients)
+	if err != nil {
+		return "", err
+	}
+	for _, client := range allClients {
+		if client.Name == "ospfd" {
+			return "localhost:" + strconv.Itoa(client.Port), nil
+		}
+	}
+	return "", errors.New("ospfd is not in clients.json")
+}
+
+func getOspfdClient(addr string) (*ospfd.OSPFDServicesClient, error) {
+	protocolFactory := thrift.NewTBinaryProtocolFactoryDefault()
+	transportFactory := thrift.NewTBufferedTransportFactory(8192)
+	socket, err := thrift.NewTSocket(addr)
+	if err != nil {
+		return nil, err
+	}
+	transport := transportFactory.GetTransport(socket)
+	err = transport.Open()
+	if err != nil {
+		return nil, err
+	}
+	return ospfd.NewOSPFDServicesClientFactory(transport, protocolFactory), nil
+}
+
+func getLsdb(client *ospfd.OSPFDServicesClient) ([]config.LsdbState, error) {
+	lsdb := []config.LsdbState{}
+	fromIdx := ospfd.Int(0)
+	retries := 0
+	for {
+		getInfo, err := client.GetBulkOspfLsdbEntryState(fromIdx, GETBULK_COUNT)
+		if err != nil || getInfo == nil {
+			/* ospfd refreshes its cache, try again from the start */
+			retries++
+			if retries > GETBULK_RETRIES {
+				return nil, errors.New(fmt.Sprintln("Failed to read the LSDB", err))
+			}
+			lsdb = []config.LsdbState{}
+			fromIdx = 0
+			time.Sleep(time.Second)
+			continue
+		}
+		for _, ent := range getInfo.OspfLsdbEntryStateList {
+			lsdb = append(lsdb, config.LsdbState{
+				LsdbAreaId:        config.AreaId(ent.LsdbAreaId),
+				LsdbType:          config.LsaType(ent.LsdbType),
+				LsdbLsid:          config.IpAddress(ent.LsdbLsid),
+				LsdbRouterId:      config.RouterId(ent.LsdbRouterId),
+				LsdbSequence:      int(ent.LsdbSequence),
+				LsdbAge:           int(ent.LsdbAge),
+				LsdbCheckSum:      int(ent.LsdbChecksum),
+				LsdbAdvertisement: ent.LsdbAdvertisement,
+			})
+		}
+		if !getInfo.More {
+			return lsdb, nil
+		}
+		fromIdx = getInfo.EndIdx
+	}
+}
+
+func exportLsdb(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	paramsDir := flags.String("params", "./params", "Params directory")
+	addr := flags.String("addr", "", "ospfd address, host:port")
+	fileName := flags.String("f", "lsdb.json", "Snapshot file")
+	flags.Parse(args)
+
+	ospfdAddr := *addr
+	if ospfdAddr == "" {
+		var err error
+		ospfdAddr, err = getOspfdAddr(*paramsDir)
+		if err != nil {
+			return err
+		}
+	}
+	client, err := getOspfdClient(ospfdAddr)
+	if err != nil {
+		return err
+	}
+	lsdb, err := getLsdb(client)
+	if err != nil {
+		return err
+	}
+	var routerId config.RouterId
+	gInfo, err := client.GetBulkOspfGlobalState(0, 1)
+	if err == nil && gInfo != nil && len(gInfo.OspfGlobalStateList) != 0 {
+		routerId = config.RouterId(gInfo.OspfGlobalStateList[0].RouterId)
+	}
+	snapshot := server.NewLsdbSnapshot(routerId, lsdb)
+	err = server.WriteLsdbSnapshot(*fileName, snapshot)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Saved", len(snapshot.Lsdb), "LSAs of router", routerId, "to", *fileName)
+	return nil
+}
+
+func printRoutes(routes []server.OfflineRoute) {
+	fmt.Printf("%-16s %-16s %-16s %-12s %-10s %-6s %s\n",
+		"Destination", "Mask", "DestType", "Area", "PathType", "Cost", "NextHops")
+	for _, route := range routes {
+		cost := strconv.Itoa(int(route.Cost))
+		if route.PathType == "Type2Ext" {
+			cost = cost + "/" + strconv.Itoa(int(route.Type2Cost))
+		}
+		fmt.Printf("%-16s %-16s %-16s %-12s %-10s %-6s %s\n", route.DestId, route.AddrMask,
+			route.DestType, route.AreaId, route.PathType, cost, strings.Join(route.NextHops, ", "))
+	}
+}
+
+func printDiff(diffs []server.OfflineRouteDiff) {
+	if len(diffs) == 0 {
+		fmt.Println("No change")
+		return
+	}
+	for _, diff := range diffs {
+		switch diff.Op {
+		case "Added":
+			fmt.Println("+", diff.New.DestId, diff.New.AddrMask, diff.New.DestType,
+				diff.New.PathType, diff.New.Cost, diff.New.NextHops)
+		case "Removed":
+			fmt.Println("-", diff.Old.DestId, diff.Old.AddrMask, diff.Old.DestType,
+				diff.Old.PathType, diff.Old.Cost, diff.Old.NextHops)
+		case "Changed":
+			fmt.Println("~", diff.New.DestId, diff.New.AddrMask, diff.New.DestType,
+				diff.Old.PathType, diff.Old.Cost, diff.Old.NextHops, "->",
+				diff.New.PathType, diff.New.Cost, diff.New.NextHops)
+		}
+	}
+}
+
+func runSpf(fileName string, root string, failLinks []string, failRouters []string) ([]server.OfflineRoute, error) {
+	snapshot, err := server.ReadLsdbSnapshot(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if root == "" {
+		root = string(snapshot.RouterId)
+	}
+	ospfServer := server.NewOfflineServerObject()
+	err = ospfServer.ImportLsdbSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range failLinks {
+		ids := strings.Split(link, ",")
+		if len(ids) != 2 {
+			return nil, errors.New(fmt.Sprintln("Invalid link", link, "should be rtrId,linkId"))
+		}
+		err = ospfServer.FailOfflineLink(ids[0], ids[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, rtr := range failRouters {
+		err = ospfServer.FailOfflineRouter(rtr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = ospfServer.RunOfflineSpf(root)
+	if err != nil {
+		return nil, err
+	}
+	return ospfServer.GetOfflineRoutingTbl(), nil
+}
+
+func spf(args []string) error {
+	var failLinks listFlag
+	var failRouters listFlag
+	flags := flag.NewFlagSet("spf", flag.ExitOnError)
+	fileName := flags.String("f", "lsdb.json", "Snapshot file")
+	root := flags.String("root", "", "Router id of the SPF root, default is the router of the snapshot")
+	diffFile := flags.String("diff", "", "Snapshot file to compare the routing table with")
+	flags.Var(&failLinks, "fail-link", "Link to fail, rtrId,linkId")
+	flags.Var(&failRouters, "fail-router", "Router to fail")
+	flags.Parse(args)
+
+	routes, err := runSpf(*fileName, *root, failLinks, failRouters)
+	if err != nil {
+		return err
+	}
+	printRoutes(routes)
+	if len(failLinks) != 0 || len(failRouters) != 0 {
+		baseRoutes, err := runSpf(*fileName, *root, nil, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\nChanges after failure:")
+		printDiff(server.DiffOfflineRoutes(baseRoutes, routes))
+	}
+	if *diffFile != "" {
+		otherRoutes, err := runSpf(*diffFile, *root, failLinks, failRouters)
+		if err != nil {
+			return err
+		}
+		fmt.Println("\nChanges in", *diffFile, ":")
+		printDiff(server.DiffOfflineRoutes(routes, otherRoutes))
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("Usage: lsdbtool export|spf [options]")
+		os.Exit(1)
+	}
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = exportLsdb(os.Args[2:])
+	case "spf":
+		err = spf(os.Args[2:])
+	default:
+		err = errors.New(fmt.Sprintln("Unknown command", os.Args[1]))
+	}
+	if err != nil {
+		fmt.Println("lsdbtool:", err)
+		os.Exit(1)
+	}
+}
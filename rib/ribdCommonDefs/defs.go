@@ -48,6 +48,7 @@ const (
 	PUB_SOCKET_BFDD_ADDR                    = "ipc:///tmp/ribd_bfdd.ipc"
 	PUB_SOCKET_VXLAND_ADDR                  = "ipc:///tmp/ribd_vxland.ipc"
 	PUB_SOCKET_POLICY_ADDR                  = "ipc:///tmp/ribd_policyd.ipc"
+	PUB_SOCKET_SUBSCRIPTION_ADDR            = "ipc:///tmp/ribd_subscription.ipc"
 	NOTIFY_ROUTE_CREATED                    = 1
 	NOTIFY_ROUTE_DELETED                    = 2
 	NOTIFY_ROUTE_INVALIDATED                = 3
@@ -64,6 +65,9 @@ const (
 	NOTIFY_POLICY_PREFIX_SET_CREATED        = 14
 	NOTIFY_POLICY_PREFIX_SET_DELETED        = 15
 	NOTIFY_POLICY_PREFIX_SET_UPDATED        = 15
+	NOTIFY_VRF_ROUTE_INSTALLED              = 16
+	NOTIFY_VRF_ROUTE_UNINSTALLED            = 17
+	NOTIFY_ROUTE_SUBSCRIPTION_EVENT         = 18
 	DEFAULT_NOTIFICATION_SIZE               = 128
 	RoutePolicyStateChangetoValid           = 1
 	RoutePolicyStateChangetoInValid         = 2
@@ -82,6 +86,42 @@ type RouteReachabilityStatusMsgInfo struct {
 	Network     string
 	IsReachable bool
 	NextHopIntf ribdInt.NextHopInfo
+	Vrf         string
+}
+
+/*
+   Route event types a subscription can filter on
+*/
+const (
+	ROUTE_EVENT_INSTALLED    = "RouteInstalled"
+	ROUTE_EVENT_UNINSTALLED  = "RouteUninstalled"
+	ROUTE_EVENT_NOTIFICATION = "Notification"
+)
+
+/*
+   Event published to a route subscription on PUB_SOCKET_SUBSCRIPTION_ADDR. Events delivered to a
+   subscription are chained with PrevSeqNum, a PrevSeqNum different from the SeqNum of the last event
+   received means events were lost and the client should resync using getRouteSubscriptionEvents.
+*/
+type RouteSubscriptionEventMsg struct {
+	Subscription string
+	SeqNum       int64
+	PrevSeqNum   int64
+	TimeStamp    string
+	EventType    string
+	Vrf          string
+	Protocol     string
+	Network      string
+	NextHopIp    string
+	EventInfo    string
+}
+
+/*
+   Messages of a subscription are prefixed with its topic, clients subscribe to the topic
+   on their sub socket and strip it before unmarshalling the RibdNotifyMsg
+*/
+func GetRouteSubscriptionTopic(name string) []byte {
+	return []byte(name + "|")
 }
 
 func GetNextHopIfTypeStr(nextHopIfType ribdInt.Int) (nextHopIfTypeStr string, err error) {
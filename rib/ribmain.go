@@ -76,6 +76,8 @@ func main() {
 	}*/
 	var err error
 	paramsDir := flag.String("params", "./params", "Params directory")
+	fibType := flag.String("fib", server.FibProgrammerAsicd, "Forwarding plane the routes are programmed into: asicd or netlink")
+	fibTable := flag.Int("fibtable", server.DefaultNetlinkRouteTable, "Kernel route table used by the netlink fib programmer")
 	flag.Parse()
 	fileName := *paramsDir
 	if fileName[len(fileName)-1] != '/' {
@@ -100,6 +102,11 @@ func main() {
 		logger.Println("routeServer nil")
 		return
 	}
+	err = routeServer.SetFibProgrammer(*fibType, *fibTable)
+	if err != nil {
+		logger.Err(fmt.Sprintln("Failed to setup the fib programmer ", *fibType, " err:", err))
+		return
+	}
 	go routeServer.StartServer(*paramsDir)
 	up := <-routeServer.ServerUpCh
 	//dbHdl.Close()
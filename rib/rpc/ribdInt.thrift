@@ -51,7 +51,9 @@ struct Routes {
 	17: bool NetworkStatement,
 	18: string RouteOrigin,
 	19: int Weight,
-	20: int IPAddrType
+	20: int IPAddrType,
+	21: string Vrf,
+	22: i64 RouteTag
 }
 struct RoutesGetInfo {
 	1: int StartIdx,
@@ -100,6 +102,8 @@ struct IPv4RouteConfig {
 	4 : i32 Cost
 	5 : bool NullRoute
 	6 : list<RouteNextHopInfo> NextHop
+	7 : string Vrf
+	8 : i64 RouteTag
 }
 struct IPv4Route {
 	1 : string DestinationNw
@@ -126,6 +130,7 @@ struct RouteNextHopInfo {
 	1 : string NextHopIp
 	2 : string NextHopIntRef
 	3 : i32 Weight
+	4 : bool Backup
 }
 struct IPv4RouteState {
 	1 : string DestinationNw
@@ -153,6 +158,243 @@ struct ApplyPolicyInfo {
 	3: string Action     
 	4: list<ConditionInfo>Conditions 
 }
+struct VrfAdminDistance {
+	1 : string Protocol
+	2 : i32 Distance
+}
+struct Vrf {
+	1 : string Name
+	2 : list<string> IntfList
+	3 : list<VrfAdminDistance> AdminDistance
+}
+struct VrfState {
+	1 : string Name
+	2 : list<string> IntfList
+	3 : i32 NumV4Routes
+	4 : i32 NumV6Routes
+}
+struct VrfStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<VrfState> VrfStateList
+}
+struct NextHopGroupMemberState {
+	1 : string NextHopIp
+	2 : i32 Weight
+	3 : bool IsUp
+	4 : i32 NumBuckets
+	5 : bool Backup
+}
+struct NextHopGroupState {
+	1 : i32 GroupId
+	2 : string IpType
+	3 : list<NextHopGroupMemberState> Members
+	4 : i32 RefCount
+	5 : bool Resilient
+	6 : list<string> Prefixes
+}
+struct NextHopGroupStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<NextHopGroupState> NextHopGroupStateList
+}
+struct StaticRouteNextHop {
+	1 : string NextHopIp
+	2 : string NextHopIntRef
+	3 : i32 Weight
+	4 : bool Bfd
+	5 : string BfdSessionParam
+	6 : string TrackObject
+}
+struct StaticRoute {
+	1 : string DestinationNw
+	2 : string NetworkMask
+	3 : string Vrf
+	4 : i32 Cost
+	5 : i32 Distance
+	6 : list<StaticRouteNextHop> NextHopList
+	7 : i64 RouteTag
+}
+struct StaticRouteNextHopState {
+	1 : string NextHopIp
+	2 : string ResolvedNextHopIp
+	3 : bool IsResolved
+	4 : string BfdState
+	5 : string TrackState
+	6 : bool Installed
+}
+struct StaticRouteState {
+	1 : string DestinationNw
+	2 : string NetworkMask
+	3 : string Vrf
+	4 : i32 Distance
+	5 : bool Active
+	6 : list<StaticRouteNextHopState> NextHopList
+	7 : i64 RouteTag
+}
+struct StaticRouteStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<StaticRouteState> StaticRouteStateList
+}
+struct TrackObject {
+	1 : string Name
+	2 : string Type
+	3 : string Prefix
+	4 : string Vrf
+	5 : string IntfRef
+}
+struct TrackObjectState {
+	1 : string Name
+	2 : string Type
+	3 : bool IsUp
+	4 : i32 NumNextHops
+}
+struct TrackObjectStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<TrackObjectState> TrackObjectStateList
+}
+struct RouteSubscription {
+	1 : string Name
+	2 : list<string> PrefixList
+	3 : list<string> ProtocolList
+	4 : list<string> VrfList
+	5 : list<string> EventTypeList
+}
+struct RouteSubscriptionEvent {
+	1 : i64 SeqNum
+	2 : i64 PrevSeqNum
+	3 : string TimeStamp
+	4 : string EventType
+	5 : string Vrf
+	6 : string Protocol
+	7 : string Network
+	8 : string NextHopIp
+	9 : string EventInfo
+}
+struct RouteSubscriptionEventGetInfo {
+	1: i64 FromSeqNum
+	2: i64 LastSeqNum
+	3: int Count
+	4: bool More
+	5: bool Truncated
+	6: list<RouteSubscriptionEvent> RouteSubscriptionEventList
+}
+struct RouteSubscriptionState {
+	1 : string Name
+	2 : list<string> PrefixList
+	3 : list<string> ProtocolList
+	4 : list<string> VrfList
+	5 : list<string> EventTypeList
+	6 : i64 LastSeqNum
+	7 : i64 NumEvents
+	8 : i64 NumDropped
+}
+struct RouteSubscriptionStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<RouteSubscriptionState> RouteSubscriptionStateList
+}
+struct FibAudit {
+	1 : i32 Interval
+	2 : bool Repair
+	3 : i32 RepairBatchSize
+	4 : i32 RepairBatchInterval
+}
+struct FibAuditState {
+	1 : string FibProgrammer
+	2 : i32 Interval
+	3 : bool Repair
+	4 : i64 NumAudits
+	5 : string LastAuditTime
+	6 : i32 NumRibRoutes
+	7 : i32 NumFibRoutes
+	8 : i32 NumMissing
+	9 : i32 NumExtra
+	10 : i32 NumMismatched
+	11 : i32 NumRepaired
+	12 : string LastError
+}
+struct FibAuditEntryState {
+	1 : string Network
+	2 : string Vrf
+	3 : string Type
+	4 : list<string> RibNextHopList
+	5 : list<string> FibNextHopList
+	6 : bool Repaired
+	7 : string AuditTime
+}
+struct FibAuditEntryStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<FibAuditEntryState> FibAuditEntryStateList
+}
+struct PbrRule {
+	1 : i32 Sequence
+	2 : string SrcPrefix
+	3 : string DstPrefix
+	4 : string Protocol
+	5 : string SrcPort
+	6 : string DstPort
+	7 : string Dscp
+	8 : string Action
+	9 : list<string> NextHopList
+	10 : string Vrf
+}
+struct PbrPolicy {
+	1 : string Name
+	2 : list<PbrRule> RuleList
+}
+struct PbrPolicyBinding {
+	1 : string IntfRef
+	2 : string Policy
+}
+struct PbrRuleState {
+	1 : i32 Sequence
+	2 : string Action
+	3 : bool Active
+	4 : string ActiveNextHop
+}
+struct PbrPolicyState {
+	1 : string Name
+	2 : list<PbrRuleState> RuleList
+	3 : list<string> IntfList
+	4 : i32 NumFibRules
+	5 : string ProgramError
+}
+struct PbrPolicyStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<PbrPolicyState> PbrPolicyStateList
+}
+struct PolicyStmtRouteTag {
+	1 : string Statement
+	2 : list<i64> MatchRouteTagList
+	3 : list<i64> ExcludeRouteTagList
+	4 : i64 SetRouteTag
+}
+struct PolicyStmtRouteTagStateGetInfo {
+	1: int StartIdx
+	2: int EndIdx
+	3: int Count
+	4: bool More
+	5: list<PolicyStmtRouteTag> PolicyStmtRouteTagList
+}
 service RIBDINTServices 
 {
     NextHopInfo getRouteReachabilityInfo(1: string desIPv4MasktNet,2: int ifIndex);
@@ -174,4 +416,35 @@ service RIBDINTServices
     void ApplyPolicy(1:list<ApplyPolicyInfo> applyList, 2: list<ApplyPolicyInfo> undoApplyList)
 //  void UpdateApplyPolicy(1: string source, 2: string policy, 3: string action, 4: list<ConditionInfo>conditions)
     void UpdateApplyPolicy(1:list<ApplyPolicyInfo> applyList, 2: list<ApplyPolicyInfo> undoApplyList)	
+	bool CreateVrf(1: Vrf config);
+	bool UpdateVrf(1: Vrf origconfig, 2: Vrf newconfig);
+	bool DeleteVrf(1: Vrf config);
+	VrfStateGetInfo getBulkVrfState(1: int fromIndex, 2: int rcount);
+	RoutesGetInfo getBulkRoutesForProtocolInVrf(1: string vrf, 2: string srcProtocol, 3: int fromIndex ,4: int rcount)
+    NextHopInfo getRouteReachabilityInfoInVrf(1: string vrf, 2: string desIPv4MasktNet,3: int ifIndex);
+	NextHopGroupStateGetInfo getBulkNextHopGroupState(1: int fromIndex, 2: int rcount);
+	bool SetNextHopGroupResilientHashing(1: bool enable);
+	bool CreateStaticRoute(1: StaticRoute config);
+	bool DeleteStaticRoute(1: StaticRoute config);
+	StaticRouteStateGetInfo getBulkStaticRouteState(1: int fromIndex, 2: int rcount);
+	bool CreateTrackObject(1: TrackObject config);
+	bool DeleteTrackObject(1: TrackObject config);
+	TrackObjectStateGetInfo getBulkTrackObjectState(1: int fromIndex, 2: int rcount);
+	bool CreateRouteSubscription(1: RouteSubscription config);
+	bool DeleteRouteSubscription(1: RouteSubscription config);
+	RouteSubscriptionEventGetInfo getRouteSubscriptionEvents(1: string name, 2: i64 fromSeqNum, 3: int count);
+	RouteSubscriptionStateGetInfo getBulkRouteSubscriptionState(1: int fromIndex, 2: int rcount);
+	bool UpdateFibAudit(1: FibAudit config);
+	bool ExecuteFibAudit(1: bool repair);
+	FibAuditState getFibAuditState();
+	FibAuditEntryStateGetInfo getBulkFibAuditEntryState(1: int fromIndex, 2: int rcount);
+	bool CreatePbrPolicy(1: PbrPolicy config);
+	bool DeletePbrPolicy(1: PbrPolicy config);
+	bool CreatePbrPolicyBinding(1: PbrPolicyBinding config);
+	bool DeletePbrPolicyBinding(1: PbrPolicyBinding config);
+	PbrPolicyStateGetInfo getBulkPbrPolicyState(1: int fromIndex, 2: int rcount);
+	bool CreateIPv4RouteConfig(1: IPv4RouteConfig config);
+	bool CreatePolicyStmtRouteTag(1: PolicyStmtRouteTag config);
+	bool DeletePolicyStmtRouteTag(1: PolicyStmtRouteTag config);
+	PolicyStmtRouteTagStateGetInfo getBulkPolicyStmtRouteTagState(1: int fromIndex, 2: int rcount);
 }
@@ -51,6 +51,24 @@ func (m RIBDServicesHandler) CreateIPv4Route(cfg *ribd.IPv4Route) (val bool, err
 	return true, nil
 }
 
+/*
+   Create API for a route config carrying a vrf, a route tag and backup next hops, unlike the bulk create the
+   route is validated and the error returned to the caller
+*/
+func (m RIBDServicesHandler) CreateIPv4RouteConfig(cfg *ribdInt.IPv4RouteConfig) (val bool, err error) {
+	logger.Info("Received create route config request for ip", cfg.DestinationNw, " mask ", cfg.NetworkMask, " tag ", cfg.RouteTag)
+	err = m.server.IPv4RouteConfigValidationCheck(cfg)
+	if err != nil {
+		logger.Err("validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addRouteConfig",
+	}
+	return true, nil
+}
+
 /*
    OnewayCreate API for route
 */
@@ -486,3 +504,290 @@ func (m RIBDServicesHandler) Getv4RouteCreatedTime(number ribdInt.Int) (time str
 	time, err = m.server.Getv4RouteCreatedTime(int(number))
 	return time, err
 }
+
+/*
+   Vrf APIs
+*/
+func (m RIBDServicesHandler) CreateVrf(cfg *ribdInt.Vrf) (val bool, err error) {
+	logger.Info("Received create vrf request for ", cfg.Name)
+	err = m.server.VrfConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("vrf validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addVrf",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) UpdateVrf(origconfig *ribdInt.Vrf, newconfig *ribdInt.Vrf) (val bool, err error) {
+	logger.Info("Received update vrf request for ", origconfig.Name)
+	err = m.server.VrfConfigValidationCheck(newconfig, "update")
+	if err != nil {
+		logger.Err("vrf validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: origconfig,
+		NewConfigObject:  newconfig,
+		Op:               "updateVrf",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) DeleteVrf(cfg *ribdInt.Vrf) (val bool, err error) {
+	logger.Info("Received delete vrf request for ", cfg.Name)
+	err = m.server.VrfConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("vrf validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delVrf",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) GetBulkVrfState(fromIndex ribdInt.Int, rcount ribdInt.Int) (vrfs *ribdInt.VrfStateGetInfo, err error) {
+	ret, err := m.server.GetBulkVrfState(fromIndex, rcount)
+	return ret, err
+}
+func (m RIBDServicesHandler) GetBulkRoutesForProtocolInVrf(vrf string, srcProtocol string, fromIndex ribdInt.Int, rcount ribdInt.Int) (routes *ribdInt.RoutesGetInfo, err error) {
+	ret, err := m.server.GetBulkRoutesForProtocolInVrf(vrf, srcProtocol, fromIndex, rcount)
+	return ret, err
+}
+func (m RIBDServicesHandler) GetRouteReachabilityInfoInVrf(vrf string, destNet string, ifIndex ribdInt.Int) (nextHopIntf *ribdInt.NextHopInfo, err error) {
+	nh, err := m.server.GetVrfRouteReachabilityInfo(vrf, destNet, ifIndex)
+	return nh, err
+}
+
+/*
+   Next hop group APIs
+*/
+func (m RIBDServicesHandler) GetBulkNextHopGroupState(fromIndex ribdInt.Int, rcount ribdInt.Int) (groups *ribdInt.NextHopGroupStateGetInfo, err error) {
+	ret, err := m.server.GetBulkNextHopGroupState(fromIndex, rcount)
+	return ret, err
+}
+func (m RIBDServicesHandler) SetNextHopGroupResilientHashing(enable bool) (val bool, err error) {
+	logger.Info("Received resilient hashing request for next hop groups, enable:", enable)
+	m.server.AsicdRouteCh <- server.RIBdServerConfig{
+		OrigConfigObject: enable,
+		Op:               "resilientHashing",
+	}
+	return true, nil
+}
+
+/*
+   Static route and object tracking APIs
+*/
+func (m RIBDServicesHandler) CreateStaticRoute(cfg *ribdInt.StaticRoute) (val bool, err error) {
+	logger.Info("Received create static route request for ", cfg.DestinationNw, ":", cfg.NetworkMask, " distance:", cfg.Distance)
+	err = m.server.StaticRouteConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("static route validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addStaticRoute",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) DeleteStaticRoute(cfg *ribdInt.StaticRoute) (val bool, err error) {
+	logger.Info("Received delete static route request for ", cfg.DestinationNw, ":", cfg.NetworkMask, " distance:", cfg.Distance)
+	err = m.server.StaticRouteConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("static route validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delStaticRoute",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) GetBulkStaticRouteState(fromIndex ribdInt.Int, rcount ribdInt.Int) (routes *ribdInt.StaticRouteStateGetInfo, err error) {
+	ret, err := m.server.GetBulkStaticRouteState(fromIndex, rcount)
+	return ret, err
+}
+func (m RIBDServicesHandler) CreateTrackObject(cfg *ribdInt.TrackObject) (val bool, err error) {
+	logger.Info("Received create track object request for ", cfg.Name)
+	err = m.server.TrackObjectConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("track object validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addTrackObject",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) DeleteTrackObject(cfg *ribdInt.TrackObject) (val bool, err error) {
+	logger.Info("Received delete track object request for ", cfg.Name)
+	err = m.server.TrackObjectConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("track object validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delTrackObject",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) GetBulkTrackObjectState(fromIndex ribdInt.Int, rcount ribdInt.Int) (objs *ribdInt.TrackObjectStateGetInfo, err error) {
+	ret, err := m.server.GetBulkTrackObjectState(fromIndex, rcount)
+	return ret, err
+}
+
+/*
+   Route change subscription APIs, subscriptions are kept along with the route event journal
+   and take effect right away
+*/
+func (m RIBDServicesHandler) CreateRouteSubscription(cfg *ribdInt.RouteSubscription) (val bool, err error) {
+	logger.Info("Received create route subscription request for ", cfg.Name)
+	err = m.server.RouteSubscriptionConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("route subscription validation check failed with error ", err)
+		return false, err
+	}
+	return m.server.ProcessRouteSubscriptionCreateConfig(cfg)
+}
+func (m RIBDServicesHandler) DeleteRouteSubscription(cfg *ribdInt.RouteSubscription) (val bool, err error) {
+	logger.Info("Received delete route subscription request for ", cfg.Name)
+	err = m.server.RouteSubscriptionConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("route subscription validation check failed with error ", err)
+		return false, err
+	}
+	return m.server.ProcessRouteSubscriptionDeleteConfig(cfg)
+}
+func (m RIBDServicesHandler) GetRouteSubscriptionEvents(name string, fromSeqNum int64, count ribdInt.Int) (events *ribdInt.RouteSubscriptionEventGetInfo, err error) {
+	ret, err := m.server.GetRouteSubscriptionEvents(name, fromSeqNum, count)
+	return ret, err
+}
+func (m RIBDServicesHandler) GetBulkRouteSubscriptionState(fromIndex ribdInt.Int, rcount ribdInt.Int) (subs *ribdInt.RouteSubscriptionStateGetInfo, err error) {
+	ret, err := m.server.GetBulkRouteSubscriptionState(fromIndex, rcount)
+	return ret, err
+}
+
+/*
+   FIB audit APIs, the audit compares the routes installed by the fib programmer with the
+   routes selected in the RIB
+*/
+func (m RIBDServicesHandler) UpdateFibAudit(cfg *ribdInt.FibAudit) (val bool, err error) {
+	logger.Info("Received update fib audit request interval:", cfg.Interval, " repair:", cfg.Repair)
+	err = m.server.FibAuditConfigValidationCheck(cfg)
+	if err != nil {
+		logger.Err("fib audit validation check failed with error ", err)
+		return false, err
+	}
+	return m.server.ProcessFibAuditUpdateConfig(cfg)
+}
+func (m RIBDServicesHandler) ExecuteFibAudit(repair bool) (val bool, err error) {
+	logger.Info("Received execute fib audit request repair:", repair)
+	return m.server.ExecuteFibAudit(repair)
+}
+func (m RIBDServicesHandler) GetFibAuditState() (state *ribdInt.FibAuditState, err error) {
+	ret, err := m.server.GetFibAuditState()
+	return ret, err
+}
+func (m RIBDServicesHandler) GetBulkFibAuditEntryState(fromIndex ribdInt.Int, rcount ribdInt.Int) (entries *ribdInt.FibAuditEntryStateGetInfo, err error) {
+	ret, err := m.server.GetBulkFibAuditEntryState(fromIndex, rcount)
+	return ret, err
+}
+
+/*
+   Policy based routing APIs, the policies are compiled into rules for the interfaces they are bound to
+*/
+func (m RIBDServicesHandler) CreatePbrPolicy(cfg *ribdInt.PbrPolicy) (val bool, err error) {
+	logger.Info("Received create pbr policy request for ", cfg.Name)
+	err = m.server.PbrPolicyConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("pbr policy validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addPbrPolicy",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) DeletePbrPolicy(cfg *ribdInt.PbrPolicy) (val bool, err error) {
+	logger.Info("Received delete pbr policy request for ", cfg.Name)
+	err = m.server.PbrPolicyConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("pbr policy validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delPbrPolicy",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) CreatePbrPolicyBinding(cfg *ribdInt.PbrPolicyBinding) (val bool, err error) {
+	logger.Info("Received create pbr policy binding request for ", cfg.IntfRef, " policy ", cfg.Policy)
+	err = m.server.PbrPolicyBindingConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("pbr policy binding validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addPbrPolicyBinding",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) DeletePbrPolicyBinding(cfg *ribdInt.PbrPolicyBinding) (val bool, err error) {
+	logger.Info("Received delete pbr policy binding request for ", cfg.IntfRef, " policy ", cfg.Policy)
+	err = m.server.PbrPolicyBindingConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("pbr policy binding validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delPbrPolicyBinding",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) GetBulkPbrPolicyState(fromIndex ribdInt.Int, rcount ribdInt.Int) (policies *ribdInt.PbrPolicyStateGetInfo, err error) {
+	ret, err := m.server.GetBulkPbrPolicyState(fromIndex, rcount)
+	return ret, err
+}
+
+/*
+   Route tag conditions and set tag action of a policy statement, evaluated when the statement redistributes a route
+*/
+func (m RIBDServicesHandler) CreatePolicyStmtRouteTag(cfg *ribdInt.PolicyStmtRouteTag) (val bool, err error) {
+	logger.Info("Received create route tag request for policy statement ", cfg.Statement)
+	err = m.server.PolicyStmtRouteTagConfigValidationCheck(cfg, "add")
+	if err != nil {
+		logger.Err("policy statement route tag validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "addPolicyStmtRouteTag",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) DeletePolicyStmtRouteTag(cfg *ribdInt.PolicyStmtRouteTag) (val bool, err error) {
+	logger.Info("Received delete route tag request for policy statement ", cfg.Statement)
+	err = m.server.PolicyStmtRouteTagConfigValidationCheck(cfg, "del")
+	if err != nil {
+		logger.Err("policy statement route tag validation check failed with error ", err)
+		return false, err
+	}
+	m.server.RouteConfCh <- server.RIBdServerConfig{
+		OrigConfigObject: cfg,
+		Op:               "delPolicyStmtRouteTag",
+	}
+	return true, nil
+}
+func (m RIBDServicesHandler) GetBulkPolicyStmtRouteTagState(fromIndex ribdInt.Int, rcount ribdInt.Int) (stmts *ribdInt.PolicyStmtRouteTagStateGetInfo, err error) {
+	ret, err := m.server.GetBulkPolicyStmtRouteTagState(fromIndex, rcount)
+	return ret, err
+}
@@ -437,6 +437,9 @@ func (ribdServiceHandler *RIBDServer) StartDBServer() {
 				dbInfo := info.OrigConfigObject.(RouteDBInfo)
 				logger.Debug("DBServer add for route:", dbInfo.entry)
 				entry := dbInfo.entry
+				if !isDefaultVrfRoute(entry) {
+					continue
+				}
 				if entry.ipType == ribdCommonDefs.IPv6 {
 					info.Op = "addv6"
 				}
@@ -455,6 +458,9 @@ func (ribdServiceHandler *RIBDServer) StartDBServer() {
 				//logger.Debug("del case")
 				dbInfo := info.OrigConfigObject.(RouteDBInfo)
 				entry := dbInfo.entry
+				if !isDefaultVrfRoute(entry) {
+					continue
+				}
 				//logger.Debug("del case iptype = ", entry.ipType)
 				if entry.ipType == ribdCommonDefs.IPv6 {
 					info.Op = "delv6"
@@ -29,6 +29,7 @@ import (
 	"asicdServices"
 	//"fmt"
 	"l3/rib/ribdCommonDefs"
+	"strings"
 )
 
 var asicdBulkCount = 30000
@@ -43,7 +44,7 @@ var V6linklocalIPMap = make(map[string]Linklocaldata)
 
 func addAsicdRouteBulk(routeInfoRecord RouteInfoRecord, bulkEnd bool) {
 	logger.Info("addAsicdRouteBulk, bulkEnd:", bulkEnd)
-	if asicdclnt.IsConnected == false {
+	if !fibProgrammer.IsReady() {
 		return
 	}
 	ipType := ""
@@ -70,15 +71,23 @@ func addAsicdRouteBulk(routeInfoRecord RouteInfoRecord, bulkEnd bool) {
 			})
 		asicdv4RouteCount++
 		if asicdv4RouteCount == asicdBulkCount || bulkEnd {
-			asicdclnt.ClientHdl.OnewayCreateIPv4Route(asicdv4Routes)
+			fibProgrammer.CreateIPv4Routes(asicdv4Routes)
 			asicdv4Routes = nil
 			asicdv4RouteCount = 0
 		}
 	} else if routeInfoRecord.ipType == ribdCommonDefs.IPv6 {
 	}
 }
+func flushAsicdRouteBulk() {
+	if !fibProgrammer.IsReady() || asicdv4RouteCount == 0 {
+		return
+	}
+	fibProgrammer.CreateIPv4Routes(asicdv4Routes)
+	asicdv4Routes = nil
+	asicdv4RouteCount = 0
+}
 func addAsicdRoute(routeInfoRecord RouteInfoRecord) {
-	if asicdclnt.IsConnected == false {
+	if !fibProgrammer.IsReady() {
 		return
 	}
 	/*	ipType := ""
@@ -111,7 +120,7 @@ func addAsicdRoute(routeInfoRecord RouteInfoRecord) {
 				asicdRoutes = nil
 				asicdRouteCount = 0
 			}*/
-		asicdclnt.ClientHdl.OnewayCreateIPv4Route([]*asicdInt.IPv4Route{
+		fibProgrammer.CreateIPv4Routes([]*asicdInt.IPv4Route{
 			&asicdInt.IPv4Route{
 				routeInfoRecord.destNetIp.String(),
 				routeInfoRecord.networkMask.String(),
@@ -137,7 +146,7 @@ func addAsicdRoute(routeInfoRecord RouteInfoRecord) {
 		}
 		if add {
 			//fmt.Println("RIBD addAsicdRoute: add set to true, send add address to asicd for ", routeInfoRecord.destNetIp.String())
-			asicdclnt.ClientHdl.OnewayCreateIPv6Route([]*asicdInt.IPv6Route{
+			fibProgrammer.CreateIPv6Routes([]*asicdInt.IPv6Route{
 				&asicdInt.IPv6Route{
 					routeInfoRecord.destNetIp.String(),
 					routeInfoRecord.networkMask.String(),
@@ -153,12 +162,12 @@ func addAsicdRoute(routeInfoRecord RouteInfoRecord) {
 	}
 }
 func delAsicdRoute(routeInfoRecord RouteInfoRecord) {
-	if asicdclnt.IsConnected == false {
+	if !fibProgrammer.IsReady() {
 		return
 	}
 	logger.Info("delAsicdRoute with ipType ", routeInfoRecord.ipType)
 	if routeInfoRecord.ipType == ribdCommonDefs.IPv4 {
-		asicdclnt.ClientHdl.OnewayDeleteIPv4Route([]*asicdInt.IPv4Route{
+		fibProgrammer.DeleteIPv4Routes([]*asicdInt.IPv4Route{
 			&asicdInt.IPv4Route{
 				routeInfoRecord.destNetIp.String(),
 				routeInfoRecord.networkMask.String(),
@@ -185,7 +194,7 @@ func delAsicdRoute(routeInfoRecord RouteInfoRecord) {
 		}
 		if del {
 			//fmt.Sprintln("RIBD delAsicdRoute:del is true, calling delasicdroute for ", routeInfoRecord.destNetIp.String())
-			asicdclnt.ClientHdl.OnewayDeleteIPv6Route([]*asicdInt.IPv6Route{
+			fibProgrammer.DeleteIPv6Routes([]*asicdInt.IPv6Route{
 				&asicdInt.IPv6Route{
 					routeInfoRecord.destNetIp.String(),
 					routeInfoRecord.networkMask.String(),
@@ -202,6 +211,10 @@ func delAsicdRoute(routeInfoRecord RouteInfoRecord) {
 	}
 }
 func (m RIBDServer) GetV4ConnectedRoutes() {
+	logger.Info("Getting v4 Intfs from fib programmer ", fibProgrammer.Name())
+	m.V4IntfsGetDone <- fibProgrammer.GetV4Intfs()
+}
+func getAsicdV4Intfs() V4IntfGetInfo {
 	logger.Info("Getting v4 Intfs from asicd")
 	var currMarker asicdServices.Int
 	var count asicdServices.Int
@@ -227,10 +240,14 @@ func (m RIBDServer) GetV4ConnectedRoutes() {
 		}
 		currMarker = asicdServices.Int(IPIntfBulk.EndIdx)
 	}
-	m.V4IntfsGetDone <- V4IntfGetInfo{ret_count, ipv4IntfList}
+	return V4IntfGetInfo{ret_count, ipv4IntfList}
 }
 
 func (m RIBDServer) GetV6ConnectedRoutes() {
+	logger.Info("Getting v6 Intfs from fib programmer ", fibProgrammer.Name())
+	m.V6IntfsGetDone <- fibProgrammer.GetV6Intfs()
+}
+func getAsicdV6Intfs() V6IntfGetInfo {
 	logger.Info("Getting v6  intfs from asicd")
 	var currMarker asicdServices.Int
 	var count asicdServices.Int
@@ -256,7 +273,55 @@ func (m RIBDServer) GetV6ConnectedRoutes() {
 		}
 		currMarker = asicdServices.Int(IPIntfBulk.EndIdx)
 	}
-	m.V6IntfsGetDone <- V6IntfGetInfo{ret_count, ipv6IntfList}
+	return V6IntfGetInfo{ret_count, ipv6IntfList}
+}
+
+/*
+   Reads back the routes programmed in the hardware, used by the fib audit
+*/
+func getAsicdInstalledRoutes() (routes []FibRouteInfo, err error) {
+	routes = make([]FibRouteInfo, 0)
+	var currMarker asicdServices.Int
+	var count asicdServices.Int
+	count = 100
+	for {
+		bulkInfo, err := asicdclnt.ClientHdl.GetBulkIPv4RouteHwState(currMarker, count)
+		if err != nil {
+			logger.Err("GetBulkIPv4RouteHwState with err ", err)
+			return routes, err
+		}
+		for i := 0; i < int(bulkInfo.Count); i++ {
+			routes = append(routes, FibRouteInfo{
+				ipType:   ribdCommonDefs.IPv4,
+				network:  bulkInfo.IPv4RouteHwStateList[i].DestinationNw,
+				nextHops: strings.Split(bulkInfo.IPv4RouteHwStateList[i].NextHopIps, ","),
+			})
+		}
+		if bulkInfo.More == false {
+			break
+		}
+		currMarker = asicdServices.Int(bulkInfo.EndIdx)
+	}
+	currMarker = 0
+	for {
+		bulkInfo, err := asicdclnt.ClientHdl.GetBulkIPv6RouteHwState(currMarker, count)
+		if err != nil {
+			logger.Err("GetBulkIPv6RouteHwState with err ", err)
+			return routes, err
+		}
+		for i := 0; i < int(bulkInfo.Count); i++ {
+			routes = append(routes, FibRouteInfo{
+				ipType:   ribdCommonDefs.IPv6,
+				network:  bulkInfo.IPv6RouteHwStateList[i].DestinationNw,
+				nextHops: strings.Split(bulkInfo.IPv6RouteHwStateList[i].NextHopIps, ","),
+			})
+		}
+		if bulkInfo.More == false {
+			break
+		}
+		currMarker = asicdServices.Int(bulkInfo.EndIdx)
+	}
+	return routes, err
 }
 
 func (ribdServiceHandler *RIBDServer) StartAsicdServer() {
@@ -267,14 +332,57 @@ func (ribdServiceHandler *RIBDServer) StartAsicdServer() {
 		select {
 		case route := <-ribdServiceHandler.AsicdRouteCh:
 			logger.Info(" received message on AsicdRouteCh, op:", route.Op)
-			if route.Op == "add" {
-				if route.Bulk {
-					addAsicdRouteBulk(route.OrigConfigObject.(RouteInfoRecord), route.BulkEnd)
+			if (route.Op == "add" || route.Op == "del") && !isDefaultVrfRoute(route.OrigConfigObject.(RouteInfoRecord)) {
+				/*
+				   routes in non default vrfs are not programmed in the default table, they go to
+				   the table of the vrf and are published for the vrf aware consumers
+				*/
+				programVrfRoute(route.OrigConfigObject.(RouteInfoRecord), route.Op)
+				vrfRouteNotificationSend(route.OrigConfigObject.(RouteInfoRecord), route.Op)
+				if route.Bulk && route.BulkEnd {
+					flushNextHopGroupUpdates()
+					flushAsicdRouteBulk()
+				}
+			} else if route.Op == "add" {
+				routeInfoRecord := route.OrigConfigObject.(RouteInfoRecord)
+				if isNextHopGroupRoute(routeInfoRecord) {
+					nextHopGroupRouteAdd(routeInfoRecord)
+					if !route.Bulk || route.BulkEnd || nextHopGroupPendingUpdate.count() >= asicdBulkCount {
+						flushNextHopGroupUpdates()
+					}
+					if route.Bulk && route.BulkEnd {
+						flushAsicdRouteBulk()
+					}
+				} else if route.Bulk {
+					if route.BulkEnd {
+						flushNextHopGroupUpdates()
+					}
+					addAsicdRouteBulk(routeInfoRecord, route.BulkEnd)
 				} else {
-					addAsicdRoute(route.OrigConfigObject.(RouteInfoRecord))
+					addAsicdRoute(routeInfoRecord)
 				}
 			} else if route.Op == "del" {
-				delAsicdRoute(route.OrigConfigObject.(RouteInfoRecord))
+				routeInfoRecord := route.OrigConfigObject.(RouteInfoRecord)
+				if isNextHopGroupRoute(routeInfoRecord) {
+					nextHopGroupRouteDel(routeInfoRecord)
+					flushNextHopGroupUpdates()
+				} else {
+					delAsicdRoute(routeInfoRecord)
+				}
+			} else if route.Op == "nhReachability" {
+				/*
+				   update the members of the next hop groups instead of deleting every route through the next hop,
+				   the prefixes of the changed groups only get the changed next hops
+				*/
+				nextHopGroupNetworkStateUpdate(route.OrigConfigObject.(RouteReachabilityStatusInfo))
+				flushNextHopGroupUpdates()
+			} else if route.Op == "resilientHashing" {
+				nextHopGroupResilientHashingUpdate(route.OrigConfigObject.(bool))
+				flushNextHopGroupUpdates()
+			} else if route.Op == "fibAudit" {
+				ribdServiceHandler.ProcessFibAudit(route.OrigConfigObject.(FibAuditRequest))
+			} else if route.Op == "fibAuditRepair" {
+				ribdServiceHandler.ProcessFibAuditRepairBatch(route.OrigConfigObject.(FibAuditRepairBatch))
 			} else if route.Op == "fetchv4" {
 				logger.Info("AsicdServer loop fetchv4, call getv4connectedroutes")
 				ribdServiceHandler.GetV4ConnectedRoutes()
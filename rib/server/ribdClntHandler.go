@@ -26,6 +26,7 @@ package server
 import (
 	"arpd"
 	"asicdServices"
+	"bfdd"
 	"encoding/json"
 	"git.apache.org/thrift.git/lib/go/thrift"
 	"infra/sysd/sysdCommonDefs"
@@ -56,6 +57,11 @@ type ArpdClient struct {
 	RIBClientBase
 	ClientHdl *arpd.ARPDServicesClient
 }
+type BfddClient struct {
+	baseClient
+	RIBClientBase
+	ClientHdl *bfdd.BFDDServicesClient
+}
 type BGPdClient struct {
 	baseClient
 }
@@ -78,6 +84,7 @@ type ClientJson struct {
 
 var asicdclnt AsicdClient
 var arpdclnt ArpdClient
+var bfddclnt BfddClient
 var bgpdclnt BGPdClient
 var ospfdclnt OSPFdClient
 
@@ -104,7 +111,7 @@ func deleteV4RoutesOfType(protocol string, destNet string) {
 	for _, protoroute := range testroutes { //protocolRouteList {
 		//logger.Info(len(testroutes), " number of ", protocol, " routes in routemap:", testroutes, " remaining")
 		//logger.Info("protoroute:", protoroute, " nexthop:", protoroute.nextHopIp.String())
-		_, err := deleteIPRoute(protoroute.destNetIp.String(), ribdCommonDefs.IPv4, protoroute.networkMask.String(), protocol, protoroute.nextHopIp.String(), protoroute.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
+		_, err := deleteIPRoute(protoroute.vrf, protoroute.destNetIp.String(), ribdCommonDefs.IPv4, protoroute.networkMask.String(), protocol, protoroute.nextHopIp.String(), protoroute.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
 		logger.Info("err :", err, " while deleting ", protocol, " route with destNet:", protoroute.destNetIp.String(), " nexthopIP:", protoroute.nextHopIp.String())
 	}
 }
@@ -131,7 +138,7 @@ func deleteV6RoutesOfType(protocol string, destNet string) {
 	for _, protoroute := range testroutes { //protocolRouteList {
 		//logger.Info(len(testroutes), " number of ", protocol, " routes in routemap:", testroutes, " remaining")
 		//logger.Info("protoroute:", protoroute, " nexthop:", protoroute.nextHopIp.String())
-		_, err := deleteIPRoute(protoroute.destNetIp.String(), ribdCommonDefs.IPv6, protoroute.networkMask.String(), protocol, protoroute.nextHopIp.String(), protoroute.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
+		_, err := deleteIPRoute(protoroute.vrf, protoroute.destNetIp.String(), ribdCommonDefs.IPv6, protoroute.networkMask.String(), protocol, protoroute.nextHopIp.String(), protoroute.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
 		logger.Info("err :", err, " while deleting ", protocol, " route with destNet:", protoroute.destNetIp.String(), " nexthopIP:", protoroute.nextHopIp.String())
 	}
 }
@@ -189,6 +196,10 @@ func (clnt *AsicdClient) DmnDownHandler() {
 	logger.Info("DmnDownHandler for AsicdClient")
 	clnt.IsConnected = false
 }
+func (clnt *BfddClient) DmnDownHandler() {
+	logger.Info("DmnDownHandler for BfddClient")
+	clnt.IsConnected = false
+}
 func (clnt *baseClient) DmnDownHandler() {
 	logger.Info("DmnDownHandler for baseClient")
 }
@@ -228,6 +239,14 @@ func (clnt *AsicdClient) DmnUpHandler() {
 	}
 	go clnt.ConnectToClient()
 }
+func (clnt *BfddClient) DmnUpHandler() {
+	logger.Info("DmnUpHandler for BfddClient")
+	if bfddclnt.IsConnected {
+		logger.Info("RIBD already connected to bfdd")
+		return
+	}
+	go clnt.ConnectToClient()
+}
 func (clnt *BGPdClient) DmnUpHandler() {
 	logger.Info("DmnUpHandler for BGPd")
 	//no op here since BGP calls GetBulkRoutesForProtocol
@@ -313,6 +332,25 @@ func (clnt *AsicdClient) ConnectToClient() {
 		}
 	}
 }
+func (clnt *BfddClient) ConnectToClient() {
+	var timer *time.Timer
+	logger.Info("in go routine ConnectToClient for connecting to BFDd")
+	for {
+		timer = time.NewTimer(time.Second * 1)
+		<-timer.C
+		logger.Info("Connecting to bfdd at address ", bfddclnt.Address)
+		bfddclnt.Transport, bfddclnt.PtrProtocolFactory, _ = ipcutils.CreateIPCHandles(bfddclnt.Address)
+		if bfddclnt.Transport != nil && bfddclnt.PtrProtocolFactory != nil {
+			bfddclnt.ClientHdl = bfdd.NewBFDDServicesClientFactory(bfddclnt.Transport, bfddclnt.PtrProtocolFactory)
+			bfddclnt.IsConnected = true
+			RouteServiceHandler.Clients["bfdd"] = &bfddclnt
+			//sessions of bfd enabled static routes configured while bfdd was down
+			RouteServiceHandler.RouteConfCh <- RIBdServerConfig{Op: "bfdSessionRestore"}
+			timer.Stop()
+			return
+		}
+	}
+}
 func (clnt *baseClient) ConnectToClient() {
 }
 
@@ -377,6 +415,11 @@ func (ribdServiceHandler *RIBDServer) ConnectToClients(paramsFile string) {
 
 	for _, client := range clientsList {
 		logger.Info("#### Client name is ", client.Name)
+		if (client.Name == "asicd" || client.Name == "arpd") && !usingAsicdFib() {
+			//the kernel does the forwarding and neighbor resolution
+			logger.Info("Not connecting to ", client.Name, " with fib programmer ", fibProgrammer.Name())
+			continue
+		}
 		if client.Name == "bgpd" {
 			ribdServiceHandler.Clients["bgpd"] = &bgpdclnt
 		}
@@ -418,6 +461,13 @@ func (ribdServiceHandler *RIBDServer) ConnectToClients(paramsFile string) {
 				//go asicdclnt.ConnectToClient()
 			}
 		}
+		if client.Name == "bfdd" {
+			//bfd is only needed for static routes, do not hold up ribd startup for it
+			logger.Info("found bfdd at port ", client.Port)
+			bfddclnt.Address = "localhost:" + strconv.Itoa(client.Port)
+			ribdServiceHandler.Clients["bfdd"] = &bfddclnt
+			go bfddclnt.ConnectToClient()
+		}
 		if client.Name == "arpd" {
 			logger.Info("RIBD: found arpd at port ", client.Port)
 			arpdclnt.Address = "localhost:" + strconv.Itoa(client.Port)
@@ -456,4 +506,8 @@ func (ribdServiceHandler *RIBDServer) ConnectToClients(paramsFile string) {
 			}
 		}
 	}
+	if !usingAsicdFib() {
+		logger.Info("Using fib programmer ", fibProgrammer.Name(), ": call AcceptConfigActions")
+		ribdServiceHandler.AcceptConfigActions()
+	}
 }
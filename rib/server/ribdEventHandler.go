@@ -29,6 +29,7 @@ import (
 	"encoding/json"
 	//"fmt"
 	"github.com/op/go-nanomsg"
+	"l3/bfd/bfddCommonDefs"
 	"net"
 	"ribd"
 	"strconv"
@@ -47,7 +48,7 @@ func (ribdServiceHandler *RIBDServer) ProcessLogicalIntfCreateEvent(logicalIntfN
 		IfNameToIfIndex = make(map[string]int32)
 	}
 	IfNameToIfIndex[logicalIntfNotifyMsg.LogicalIntfName] = ifId
-
+	ribdServiceHandler.IntfVrfBindingUpdate(ifId)
 }
 func (ribdServiceHandler *RIBDServer) ProcessVlanCreateEvent(vlanNotifyMsg asicdCommonDefs.VlanNotifyMsg) {
 	ifId := asicdCommonDefs.GetIfIndexFromIntfIdAndIntfType(int(vlanNotifyMsg.VlanId), commonDefs.IfTypeVlan)
@@ -61,6 +62,7 @@ func (ribdServiceHandler *RIBDServer) ProcessVlanCreateEvent(vlanNotifyMsg asicd
 		IfNameToIfIndex = make(map[string]int32)
 	}
 	IfNameToIfIndex[vlanNotifyMsg.VlanName] = ifId
+	ribdServiceHandler.IntfVrfBindingUpdate(ifId)
 }
 func (ribdServiceHandler *RIBDServer) ProcessIPv4IntfCreateEvent(msg asicdCommonDefs.IPv4IntfNotifyMsg) {
 
@@ -92,6 +94,7 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv4IntfCreateEvent(msg asicdCommon
 		OrigConfigObject: &cfg,
 		Op:               "add",
 	}
+	ribdServiceHandler.PbrIntfChange()
 }
 func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfCreateEvent(msg asicdCommonDefs.IPv6IntfNotifyMsg) {
 	var ipMask net.IP
@@ -121,6 +124,7 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfCreateEvent(msg asicdCommon
 		OrigConfigObject: &cfg,
 		Op:               "addv6",
 	}
+	ribdServiceHandler.PbrIntfChange()
 }
 func (ribdServiceHandler *RIBDServer) ProcessIPv4IntfDeleteEvent(msg asicdCommonDefs.IPv4IntfNotifyMsg) {
 	var ipMask net.IP
@@ -149,6 +153,7 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv4IntfDeleteEvent(msg asicdCommon
 		OrigConfigObject: &cfg,
 		Op:               "del",
 	}
+	ribdServiceHandler.PbrIntfChange()
 
 }
 func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfDeleteEvent(msg asicdCommonDefs.IPv6IntfNotifyMsg) {
@@ -178,6 +183,7 @@ func (ribdServiceHandler *RIBDServer) ProcessIPv6IntfDeleteEvent(msg asicdCommon
 		OrigConfigObject: &cfg,
 		Op:               "delv6",
 	}
+	ribdServiceHandler.PbrIntfChange()
 }
 func (ribdServiceHandler *RIBDServer) ProcessAsicdEvents(sub *nanomsg.SubSocket) {
 
@@ -233,6 +239,7 @@ func (ribdServiceHandler *RIBDServer) ProcessAsicdEvents(sub *nanomsg.SubSocket)
 				//processLinkUpEvent(ribd.Int(msg.IfType), ribd.Int(msg.IfId))
 				ribdServiceHandler.ProcessIPv4IntfUpEvent(msg.IpAddr, msg.IfIndex)
 			}
+			ribdServiceHandler.TrackIntfStateUpdate(msg.IfIndex, msg.IfState != asicdCommonDefs.INTF_STATE_DOWN)
 			break
 		case asicdCommonDefs.NOTIFY_IPV6_L3INTF_STATE_CHANGE:
 			ribdServiceHandler.Logger.Info("NOTIFY_IPV6_L3INTF_STATE_CHANGE event")
@@ -250,6 +257,7 @@ func (ribdServiceHandler *RIBDServer) ProcessAsicdEvents(sub *nanomsg.SubSocket)
 				//processLinkUpEvent(ribd.Int(msg.IfType), ribd.Int(msg.IfId))
 				ribdServiceHandler.ProcessIPv6IntfUpEvent(msg.IpAddr, msg.IfIndex)
 			}
+			ribdServiceHandler.TrackIntfStateUpdate(msg.IfIndex, msg.IfState != asicdCommonDefs.INTF_STATE_DOWN)
 			break
 		case asicdCommonDefs.NOTIFY_IPV4INTF_CREATE:
 			ribdServiceHandler.Logger.Info("NOTIFY_IPV4INTF_CREATE event")
@@ -300,11 +308,35 @@ func (ribdServiceHandler *RIBDServer) ProcessAsicdEvents(sub *nanomsg.SubSocket)
 		}
 	}
 }
+func (ribdServiceHandler *RIBDServer) ProcessBfddEvents(sub *nanomsg.SubSocket) {
+	for {
+		ribdServiceHandler.Logger.Debug("Read on Bfdd subscriber socket...")
+		rxBuf, err := sub.Recv(0)
+		if err != nil {
+			ribdServiceHandler.Logger.Info("Recv on Bfdd subscriber socket failed with error:", err)
+			continue
+		}
+		var msg bfddCommonDefs.BfddNotifyMsg
+		err = json.Unmarshal(rxBuf, &msg)
+		if err != nil {
+			ribdServiceHandler.Logger.Info("Error in reading bfd msg ", err)
+			continue
+		}
+		ribdServiceHandler.Logger.Debug("Bfd state of ", msg.DestIp, " up:", msg.State)
+		ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+			OrigConfigObject: msg,
+			Op:               "bfdStateChange",
+		}
+	}
+}
 func (ribdServiceHandler *RIBDServer) ProcessEvents(sub *nanomsg.SubSocket, subType ribd.Int) {
 	ribdServiceHandler.Logger.Info("in process events for sub ", subType)
 	if subType == SUB_ASICD {
 		ribdServiceHandler.Logger.Info("process Asicd events")
 		ribdServiceHandler.ProcessAsicdEvents(sub)
+	} else if subType == SUB_BFDD {
+		ribdServiceHandler.Logger.Info("process Bfdd events")
+		ribdServiceHandler.ProcessBfddEvents(sub)
 	}
 }
 func (ribdServiceHandler *RIBDServer) SetupEventHandler(sub *nanomsg.SubSocket, address string, subtype ribd.Int) {
@@ -0,0 +1,606 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdFibAudit.go
+package server
+
+import (
+	"asicdInt"
+	"errors"
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"ribdInt"
+	"sort"
+	"sync"
+	"time"
+	"utils/patriciaDB"
+)
+
+const (
+	FibAuditTypeMissing                = "Missing"
+	FibAuditTypeExtra                  = "Extra"
+	FibAuditTypeMismatch               = "Mismatch"
+	DefaultFibAuditRepairBatchSize     = 100
+	DefaultFibAuditRepairBatchInterval = 100 //milliseconds
+	FibAuditRestartDelay               = 30 * time.Second
+)
+
+/*
+   Fib entry expected for a network, built from the selected routes of the RIB
+*/
+type FibAuditRibRoute struct {
+	vrf              string
+	ipType           ribdCommonDefs.IPType
+	network          string
+	routeInfoRecords []RouteInfoRecord
+}
+
+/*
+   Discrepancy found by an audit
+*/
+type FibAuditEntry struct {
+	network          string
+	vrf              string
+	ipType           ribdCommonDefs.IPType
+	auditType        string
+	ribNextHops      []string
+	fibNextHops      []string
+	routeInfoRecords []RouteInfoRecord
+	repaired         bool
+	auditTime        string
+}
+
+/*
+   Audit request handed from the route server to the asicd server along with the
+   RIB snapshot, so that the fib is read after every queued route update is programmed
+*/
+type FibAuditRequest struct {
+	repair    bool
+	ribRoutes map[string]*FibAuditRibRoute
+}
+type FibAuditRepairBatch struct {
+	entries []*FibAuditEntry
+	last    bool
+}
+
+type FibAuditInfo struct {
+	sync.RWMutex
+	interval            int32 //seconds between periodic audits, 0 disables them
+	repair              bool
+	repairBatchSize     int32
+	repairBatchInterval int32 //milliseconds between two repair batches
+	lastAuditTime       time.Time
+	numAudits           int64
+	numRibRoutes        int32
+	numFibRoutes        int32
+	numRepaired         int32
+	lastError           string
+	repairInProgress    bool
+	entries             []*FibAuditEntry
+}
+
+var FibAuditDB = FibAuditInfo{
+	repairBatchSize:     DefaultFibAuditRepairBatchSize,
+	repairBatchInterval: DefaultFibAuditRepairBatchInterval,
+}
+
+func (m RIBDServer) FibAuditConfigValidationCheck(cfg *ribdInt.FibAudit) (err error) {
+	if cfg.Interval < 0 {
+		logger.Err("FibAuditConfigValidationCheck: invalid interval ", cfg.Interval)
+		return errors.New(fmt.Sprintln("Invalid fib audit interval ", cfg.Interval))
+	}
+	if cfg.RepairBatchSize < 0 || cfg.RepairBatchInterval < 0 {
+		logger.Err("FibAuditConfigValidationCheck: invalid repair batch size ", cfg.RepairBatchSize, " interval ", cfg.RepairBatchInterval)
+		return errors.New(fmt.Sprintln("Invalid fib audit repair batch size ", cfg.RepairBatchSize, " interval ", cfg.RepairBatchInterval))
+	}
+	return err
+}
+func (m RIBDServer) ProcessFibAuditUpdateConfig(cfg *ribdInt.FibAudit) (val bool, err error) {
+	logger.Info("ProcessFibAuditUpdateConfig interval:", cfg.Interval, " repair:", cfg.Repair, " batch size:", cfg.RepairBatchSize, " batch interval:", cfg.RepairBatchInterval)
+	FibAuditDB.Lock()
+	defer FibAuditDB.Unlock()
+	FibAuditDB.interval = cfg.Interval
+	FibAuditDB.repair = cfg.Repair
+	FibAuditDB.repairBatchSize = cfg.RepairBatchSize
+	if FibAuditDB.repairBatchSize == 0 {
+		FibAuditDB.repairBatchSize = DefaultFibAuditRepairBatchSize
+	}
+	FibAuditDB.repairBatchInterval = cfg.RepairBatchInterval
+	if FibAuditDB.repairBatchInterval == 0 {
+		FibAuditDB.repairBatchInterval = DefaultFibAuditRepairBatchInterval
+	}
+	return true, err
+}
+
+/*
+   On demand audit, repair overrides the configured repair setting
+*/
+func (m RIBDServer) ExecuteFibAudit(repair bool) (val bool, err error) {
+	if !m.AcceptConfig {
+		return false, errors.New("RIB not ready")
+	}
+	m.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: repair,
+		Op:               "fibAudit",
+	}
+	return true, err
+}
+
+/*
+   Runs the periodic audits and the one that follows an asicd/ribd restart
+*/
+func (ribdServiceHandler *RIBDServer) FibAuditServer() {
+	logger.Info("Starting the fib audit server loop")
+	ticker := time.NewTicker(time.Second)
+	restartAuditTime := time.Time{}
+	lastTriggerTime := time.Now()
+	for {
+		select {
+		case <-ticker.C:
+			FibAuditDB.RLock()
+			interval := FibAuditDB.interval
+			repair := FibAuditDB.repair
+			FibAuditDB.RUnlock()
+			if !RouteServiceHandler.AcceptConfig || !fibProgrammer.IsReady() {
+				continue
+			}
+			if !restartAuditTime.IsZero() && time.Now().After(restartAuditTime) {
+				restartAuditTime = time.Time{}
+				lastTriggerTime = time.Now()
+				ribdServiceHandler.ExecuteFibAudit(repair)
+				continue
+			}
+			if interval > 0 && time.Since(lastTriggerTime) >= time.Duration(interval)*time.Second {
+				lastTriggerTime = time.Now()
+				ribdServiceHandler.ExecuteFibAudit(repair)
+			}
+		case <-ribdServiceHandler.FibAuditRestartCh:
+			logger.Info("FibAuditServer: audit the fib in ", FibAuditRestartDelay)
+			restartAuditTime = time.Now().Add(FibAuditRestartDelay)
+		}
+	}
+}
+
+func fibAuditNetwork(destNetIp net.IP, networkMask net.IP) string {
+	if ip := destNetIp.To4(); ip != nil {
+		mask := net.IPMask(networkMask.To4())
+		return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+	}
+	mask := net.IPMask(networkMask.To16())
+	return (&net.IPNet{IP: destNetIp.Mask(mask), Mask: mask}).String()
+}
+
+/*
+   Routes the fib programmer is expected to have installed, connected routes are owned by
+   the kernel with the netlink programmer and ipv6 link local routes are never audited
+*/
+func fibAuditRouteProgrammed(routeInfoRecord RouteInfoRecord) bool {
+	if routeInfoRecord.ipType == ribdCommonDefs.IPv6 && routeInfoRecord.destNetIp.IsLinkLocalUnicast() {
+		return false
+	}
+	if routeInfoRecord.protocol == ribdCommonDefs.CONNECTED && !usingAsicdFib() {
+		return false
+	}
+	return true
+}
+
+/*
+   Snapshot of the selected routes of the default vrf, called in the route server context.
+   The routes of the other vrfs are programmed in their own tables, which the installed
+   routes read back from the fib programmer do not cover.
+*/
+func (m RIBDServer) ProcessFibAuditRequest(repair bool) {
+	logger.Info("ProcessFibAuditRequest repair:", repair)
+	ribRoutes := make(map[string]*FibAuditRibRoute)
+	collect := func(prefix patriciaDB.Prefix, item patriciaDB.Item, handle patriciaDB.Item) (err error) {
+		routeInfoRecordList := item.(RouteInfoRecordList)
+		if routeInfoRecordList.selectedRouteProtocol == "" {
+			return err
+		}
+		routeInfoList := routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol]
+		for _, routeInfoRecord := range routeInfoList {
+			if !fibAuditRouteProgrammed(routeInfoRecord) {
+				continue
+			}
+			network := fibAuditNetwork(routeInfoRecord.destNetIp, routeInfoRecord.networkMask)
+			ribRoute, ok := ribRoutes[network]
+			if !ok {
+				ribRoute = &FibAuditRibRoute{
+					vrf:              getVrfName(routeInfoRecord.vrf),
+					ipType:           routeInfoRecord.ipType,
+					network:          network,
+					routeInfoRecords: make([]RouteInfoRecord, 0),
+				}
+				ribRoutes[network] = ribRoute
+			}
+			ribRoute.routeInfoRecords = append(ribRoute.routeInfoRecords, routeInfoRecord)
+		}
+		return err
+	}
+	if vrfInfo, ok := VrfInfoMap[DefaultVrf]; ok {
+		vrfInfo.v4RouteInfoMap.VisitAndUpdate(collect, nil)
+		vrfInfo.v6RouteInfoMap.VisitAndUpdate(collect, nil)
+	}
+	m.AsicdRouteCh <- RIBdServerConfig{
+		OrigConfigObject: FibAuditRequest{repair: repair, ribRoutes: ribRoutes},
+		Op:               "fibAudit",
+	}
+}
+
+/*
+   Next hops expected in the fib for the records, the unreachable next hops of a group
+   are not programmed. The unspecified next hop of the connected routes is left out on
+   both sides.
+*/
+func fibAuditRibNextHops(routeInfoRecords []RouteInfoRecord) []string {
+	nextHopMap := make(map[string]bool)
+	for _, routeInfoRecord := range routeInfoRecords {
+		ip := net.ParseIP(routeInfoRecord.resolvedNextHopIpIntf.NextHopIp)
+		if ip == nil || ip.IsUnspecified() {
+			continue
+		}
+		if isNextHopGroupRoute(routeInfoRecord) && NextHopGroupMemberDownMap[ip.String()] {
+			continue
+		}
+		nextHopMap[ip.String()] = true
+	}
+	return fibAuditNextHopList(nextHopMap)
+}
+func fibAuditFibNextHops(nextHops []string) []string {
+	nextHopMap := make(map[string]bool)
+	for _, nextHop := range nextHops {
+		ip := net.ParseIP(nextHop)
+		if ip == nil || ip.IsUnspecified() {
+			continue
+		}
+		nextHopMap[ip.String()] = true
+	}
+	return fibAuditNextHopList(nextHopMap)
+}
+func fibAuditNextHopList(nextHopMap map[string]bool) []string {
+	nextHops := make([]string, 0)
+	for nextHop, _ := range nextHopMap {
+		nextHops = append(nextHops, nextHop)
+	}
+	sort.Strings(nextHops)
+	return nextHops
+}
+func fibAuditNextHopsEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+   Diffs the routes installed by the fib programmer against the RIB snapshot, called in
+   the asicd server context
+*/
+func (m RIBDServer) ProcessFibAudit(req FibAuditRequest) {
+	auditTime := time.Now()
+	fibRoutes, err := fibProgrammer.GetInstalledRoutes()
+	FibAuditDB.Lock()
+	FibAuditDB.lastAuditTime = auditTime
+	FibAuditDB.numAudits++
+	if err != nil {
+		logger.Err("ProcessFibAudit: reading the routes from ", fibProgrammer.Name(), " failed with err:", err)
+		FibAuditDB.lastError = err.Error()
+		FibAuditDB.Unlock()
+		return
+	}
+	FibAuditDB.Unlock()
+	entries := make([]*FibAuditEntry, 0)
+	seen := make(map[string]bool)
+	for _, fibRoute := range fibRoutes {
+		ip, ipNet, err := net.ParseCIDR(fibRoute.network)
+		if err != nil {
+			logger.Err("ProcessFibAudit: invalid network ", fibRoute.network, " read from ", fibProgrammer.Name())
+			continue
+		}
+		if fibRoute.ipType == ribdCommonDefs.IPv6 && ip.IsLinkLocalUnicast() {
+			continue
+		}
+		network := ipNet.String()
+		seen[network] = true
+		fibNextHops := fibAuditFibNextHops(fibRoute.nextHops)
+		ribRoute, ok := req.ribRoutes[network]
+		if !ok {
+			//the installed routes are those of the default table, the only vrf audited
+			entries = append(entries, &FibAuditEntry{
+				network:     network,
+				vrf:         DefaultVrf,
+				ipType:      fibRoute.ipType,
+				auditType:   FibAuditTypeExtra,
+				ribNextHops: make([]string, 0),
+				fibNextHops: fibNextHops,
+				auditTime:   auditTime.String(),
+			})
+			continue
+		}
+		ribNextHops := fibAuditRibNextHops(ribRoute.routeInfoRecords)
+		if fibAuditNextHopsEqual(ribNextHops, fibNextHops) {
+			continue
+		}
+		entries = append(entries, &FibAuditEntry{
+			network:          network,
+			vrf:              ribRoute.vrf,
+			ipType:           ribRoute.ipType,
+			auditType:        FibAuditTypeMismatch,
+			ribNextHops:      ribNextHops,
+			fibNextHops:      fibNextHops,
+			routeInfoRecords: ribRoute.routeInfoRecords,
+			auditTime:        auditTime.String(),
+		})
+	}
+	for network, ribRoute := range req.ribRoutes {
+		if seen[network] {
+			continue
+		}
+		ribNextHops := fibAuditRibNextHops(ribRoute.routeInfoRecords)
+		if len(ribNextHops) == 0 && ribRoute.routeInfoRecords[0].protocol != ribdCommonDefs.CONNECTED {
+			//every next hop of the route is unreachable, nothing to program
+			continue
+		}
+		entries = append(entries, &FibAuditEntry{
+			network:          network,
+			vrf:              ribRoute.vrf,
+			ipType:           ribRoute.ipType,
+			auditType:        FibAuditTypeMissing,
+			ribNextHops:      ribNextHops,
+			fibNextHops:      make([]string, 0),
+			routeInfoRecords: ribRoute.routeInfoRecords,
+			auditTime:        auditTime.String(),
+		})
+	}
+	sort.Sort(fibAuditEntryList(entries))
+	logger.Info("ProcessFibAudit: rib routes:", len(req.ribRoutes), " fib routes:", len(fibRoutes), " discrepancies:", len(entries))
+	FibAuditDB.Lock()
+	FibAuditDB.numRibRoutes = int32(len(req.ribRoutes))
+	FibAuditDB.numFibRoutes = int32(len(fibRoutes))
+	FibAuditDB.numRepaired = 0
+	FibAuditDB.lastError = ""
+	FibAuditDB.entries = entries
+	startRepair := req.repair && len(entries) > 0 && !FibAuditDB.repairInProgress
+	if startRepair {
+		FibAuditDB.repairInProgress = true
+	}
+	batchSize := int(FibAuditDB.repairBatchSize)
+	batchInterval := time.Duration(FibAuditDB.repairBatchInterval) * time.Millisecond
+	FibAuditDB.Unlock()
+	if startRepair {
+		go m.fibAuditRepair(entries, batchSize, batchInterval)
+	}
+}
+
+type fibAuditEntryList []*FibAuditEntry
+
+func (l fibAuditEntryList) Len() int           { return len(l) }
+func (l fibAuditEntryList) Swap(i, j int)      { l[i], l[j] = l[j], l[i] }
+func (l fibAuditEntryList) Less(i, j int) bool { return l[i].network < l[j].network }
+
+/*
+   Feeds the repairs to the route server in batches, paced so that a large drift does
+   not starve the regular route updates
+*/
+func (m RIBDServer) fibAuditRepair(entries []*FibAuditEntry, batchSize int, batchInterval time.Duration) {
+	logger.Info("fibAuditRepair: ", len(entries), " entries, batch size:", batchSize, " interval:", batchInterval)
+	for start := 0; start < len(entries); start += batchSize {
+		end := start + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		m.RouteConfCh <- RIBdServerConfig{
+			OrigConfigObject: FibAuditRepairBatch{entries: entries[start:end], last: end == len(entries)},
+			Op:               "fibAuditRepair",
+		}
+		if end < len(entries) {
+			time.Sleep(batchInterval)
+		}
+	}
+}
+
+/*
+   Drops the entries whose RIB routes changed since the audit, the next audit looks at them
+   again. The extra next hops are removed by the asicd server, the missing ones are queued
+   behind as regular route adds. Called in the route server context.
+*/
+func (m RIBDServer) ProcessFibAuditRepair(batch FibAuditRepairBatch) {
+	entries := make([]*FibAuditEntry, 0)
+	for _, entry := range batch.entries {
+		current := make([]RouteInfoRecord, 0)
+		_, ipNet, _ := net.ParseCIDR(entry.network)
+		destNet, _, err := getNetworkPrefix(ipNet.IP, net.IP(ipNet.Mask))
+		if err == nil {
+			routeInfoRecordListItem := RouteInfoMapGet(entry.vrf, entry.ipType, destNet)
+			if routeInfoRecordListItem != nil {
+				routeInfoRecordList := routeInfoRecordListItem.(RouteInfoRecordList)
+				for _, routeInfoRecord := range routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol] {
+					if fibAuditRouteProgrammed(routeInfoRecord) {
+						current = append(current, routeInfoRecord)
+					}
+				}
+			}
+		}
+		if len(current) != len(entry.routeInfoRecords) {
+			logger.Info("ProcessFibAuditRepair: rib route for ", entry.network, " changed since the audit, skip the repair")
+			continue
+		}
+		entry.routeInfoRecords = current
+		entries = append(entries, entry)
+	}
+	m.AsicdRouteCh <- RIBdServerConfig{
+		OrigConfigObject: FibAuditRepairBatch{entries: entries, last: batch.last},
+		Op:               "fibAuditRepair",
+	}
+	for _, route := range fibAuditRepairRoutes(entries) {
+		m.AsicdRouteCh <- route
+	}
+}
+
+/*
+   Route adds for the next hops missing from the fib, sent on the AsicdRouteCh like every
+   other route update so that the vrf and next hop group handling apply to them
+*/
+func fibAuditRepairRoutes(entries []*FibAuditEntry) []RIBdServerConfig {
+	routes := make([]RIBdServerConfig, 0)
+	for _, entry := range entries {
+		ribNextHops := make(map[string]bool)
+		for _, nextHop := range entry.ribNextHops {
+			ribNextHops[nextHop] = true
+		}
+		fibNextHops := make(map[string]bool)
+		for _, nextHop := range entry.fibNextHops {
+			fibNextHops[nextHop] = true
+		}
+		for _, routeInfoRecord := range entry.routeInfoRecords {
+			ip := net.ParseIP(routeInfoRecord.resolvedNextHopIpIntf.NextHopIp)
+			if ip != nil && !ip.IsUnspecified() {
+				if !ribNextHops[ip.String()] || fibNextHops[ip.String()] {
+					//unreachable group member or already installed
+					continue
+				}
+			}
+			routes = append(routes, RIBdServerConfig{OrigConfigObject: routeInfoRecord, Op: "add", Bulk: true})
+		}
+	}
+	if len(routes) > 0 {
+		routes[len(routes)-1].BulkEnd = true
+	}
+	return routes
+}
+
+func fibAuditDeleteNextHops(entry *FibAuditEntry, nextHops []string) {
+	if len(nextHops) == 0 {
+		return
+	}
+	ip, ipNet, _ := net.ParseCIDR(entry.network)
+	if entry.ipType == ribdCommonDefs.IPv4 {
+		route := &asicdInt.IPv4Route{ip.Mask(ipNet.Mask).String(), net.IP(ipNet.Mask).String(), make([]*asicdInt.IPv4NextHop, 0)}
+		for _, nextHop := range nextHops {
+			route.NextHopList = append(route.NextHopList, &asicdInt.IPv4NextHop{NextHopIp: nextHop})
+		}
+		fibProgrammer.DeleteIPv4Routes([]*asicdInt.IPv4Route{route})
+	} else {
+		route := &asicdInt.IPv6Route{ip.Mask(ipNet.Mask).String(), net.IP(ipNet.Mask).String(), make([]*asicdInt.IPv6NextHop, 0)}
+		for _, nextHop := range nextHops {
+			route.NextHopList = append(route.NextHopList, &asicdInt.IPv6NextHop{NextHopIp: nextHop})
+		}
+		fibProgrammer.DeleteIPv6Routes([]*asicdInt.IPv6Route{route})
+	}
+}
+
+/*
+   Removes the extra next hops of the batch, called in the asicd server context
+*/
+func (m RIBDServer) ProcessFibAuditRepairBatch(batch FibAuditRepairBatch) {
+	logger.Info("ProcessFibAuditRepairBatch: ", len(batch.entries), " entries, last:", batch.last)
+	if !fibProgrammer.IsReady() {
+		FibAuditDB.Lock()
+		FibAuditDB.repairInProgress = !batch.last
+		FibAuditDB.Unlock()
+		return
+	}
+	for _, entry := range batch.entries {
+		ribNextHops := make(map[string]bool)
+		for _, nextHop := range entry.ribNextHops {
+			ribNextHops[nextHop] = true
+		}
+		extraNextHops := make([]string, 0)
+		for _, nextHop := range entry.fibNextHops {
+			if !ribNextHops[nextHop] {
+				extraNextHops = append(extraNextHops, nextHop)
+			}
+		}
+		fibAuditDeleteNextHops(entry, extraNextHops)
+	}
+	FibAuditDB.Lock()
+	for _, entry := range batch.entries {
+		entry.repaired = true
+	}
+	FibAuditDB.numRepaired += int32(len(batch.entries))
+	if batch.last {
+		FibAuditDB.repairInProgress = false
+	}
+	FibAuditDB.Unlock()
+}
+
+func (m RIBDServer) GetFibAuditState() (state *ribdInt.FibAuditState, err error) {
+	FibAuditDB.RLock()
+	defer FibAuditDB.RUnlock()
+	state = &ribdInt.FibAuditState{
+		FibProgrammer: fibProgrammer.Name(),
+		Interval:      FibAuditDB.interval,
+		Repair:        FibAuditDB.repair,
+		NumAudits:     FibAuditDB.numAudits,
+		NumRibRoutes:  FibAuditDB.numRibRoutes,
+		NumFibRoutes:  FibAuditDB.numFibRoutes,
+		NumRepaired:   FibAuditDB.numRepaired,
+		LastError:     FibAuditDB.lastError,
+	}
+	if !FibAuditDB.lastAuditTime.IsZero() {
+		state.LastAuditTime = FibAuditDB.lastAuditTime.String()
+	}
+	for _, entry := range FibAuditDB.entries {
+		switch entry.auditType {
+		case FibAuditTypeMissing:
+			state.NumMissing++
+		case FibAuditTypeExtra:
+			state.NumExtra++
+		case FibAuditTypeMismatch:
+			state.NumMismatched++
+		}
+	}
+	return state, err
+}
+func (m RIBDServer) GetBulkFibAuditEntryState(fromIndex ribdInt.Int, rcount ribdInt.Int) (entries *ribdInt.FibAuditEntryStateGetInfo, err error) {
+	var returnFibAuditEntryGetInfo ribdInt.FibAuditEntryStateGetInfo
+	entries = &returnFibAuditEntryGetInfo
+	FibAuditDB.RLock()
+	defer FibAuditDB.RUnlock()
+	entryStates := make([]*ribdInt.FibAuditEntryState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(FibAuditDB.entries)) && ribdInt.Int(len(entryStates)) < rcount; i++ {
+		entry := FibAuditDB.entries[i]
+		entryStates = append(entryStates, &ribdInt.FibAuditEntryState{
+			Network:        entry.network,
+			Vrf:            entry.vrf,
+			Type:           entry.auditType,
+			RibNextHopList: entry.ribNextHops,
+			FibNextHopList: entry.fibNextHops,
+			Repaired:       entry.repaired,
+			AuditTime:      entry.auditTime,
+		})
+	}
+	entries.FibAuditEntryStateList = entryStates
+	entries.StartIdx = fromIndex
+	entries.EndIdx = i
+	entries.More = i < ribdInt.Int(len(FibAuditDB.entries))
+	entries.Count = ribdInt.Int(len(entryStates))
+	return entries, err
+}
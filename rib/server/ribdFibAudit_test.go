@@ -0,0 +1,166 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"asicdInt"
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"ribdInt"
+	"testing"
+)
+
+/*
+   Fib programmer keeping the installed routes in memory, network -> next hops
+*/
+type fakeFibProgrammer struct {
+	AsicdFibProgrammer
+	routes map[string]map[string]bool
+}
+
+func (fib *fakeFibProgrammer) Name() string {
+	return "fake"
+}
+func (fib *fakeFibProgrammer) IsReady() bool {
+	return true
+}
+func (fib *fakeFibProgrammer) update(network string, nextHops []string, add bool) {
+	if fib.routes[network] == nil {
+		fib.routes[network] = make(map[string]bool)
+	}
+	for _, nextHop := range nextHops {
+		if add {
+			fib.routes[network][nextHop] = true
+		} else {
+			delete(fib.routes[network], nextHop)
+		}
+	}
+	if len(fib.routes[network]) == 0 {
+		delete(fib.routes, network)
+	}
+}
+func (fib *fakeFibProgrammer) CreateIPv4Routes(routes []*asicdInt.IPv4Route) {
+	for _, route := range routes {
+		nextHops := make([]string, 0)
+		for _, nextHop := range route.NextHopList {
+			nextHops = append(nextHops, nextHop.NextHopIp)
+		}
+		fib.update(fibAuditNetwork(net.ParseIP(route.DestinationNw), net.ParseIP(route.NetworkMask)), nextHops, true)
+	}
+}
+func (fib *fakeFibProgrammer) DeleteIPv4Routes(routes []*asicdInt.IPv4Route) {
+	for _, route := range routes {
+		nextHops := make([]string, 0)
+		for _, nextHop := range route.NextHopList {
+			nextHops = append(nextHops, nextHop.NextHopIp)
+		}
+		fib.update(fibAuditNetwork(net.ParseIP(route.DestinationNw), net.ParseIP(route.NetworkMask)), nextHops, false)
+	}
+}
+func (fib *fakeFibProgrammer) GetInstalledRoutes() ([]FibRouteInfo, error) {
+	routes := make([]FibRouteInfo, 0)
+	for network, nextHopMap := range fib.routes {
+		routes = append(routes, FibRouteInfo{ipType: ribdCommonDefs.IPv4, network: network, nextHops: fibAuditNextHopList(nextHopMap)})
+	}
+	return routes, nil
+}
+
+var fakeFib *fakeFibProgrammer
+
+func fibAuditRouteInfoRecord(destNet string, nextHopIp string) RouteInfoRecord {
+	return RouteInfoRecord{
+		ipType:                ribdCommonDefs.IPv4,
+		destNetIp:             net.ParseIP(destNet).To4(),
+		networkMask:           net.ParseIP("255.255.255.0").To4(),
+		nextHopIp:             net.ParseIP(nextHopIp),
+		resolvedNextHopIpIntf: ribdInt.NextHopInfo{NextHopIp: nextHopIp},
+		protocol:              ribdCommonDefs.STATIC,
+		vrf:                   DefaultVrf,
+	}
+}
+func fibAuditTestRequest() FibAuditRequest {
+	ribRoutes := make(map[string]*FibAuditRibRoute)
+	for _, routeInfoRecord := range []RouteInfoRecord{
+		fibAuditRouteInfoRecord("80.1.1.0", "11.1.10.2"),
+		fibAuditRouteInfoRecord("80.1.2.0", "11.1.10.2"),
+		fibAuditRouteInfoRecord("80.1.2.0", "11.1.10.3"),
+		fibAuditRouteInfoRecord("80.1.3.0", "11.1.10.2"),
+	} {
+		network := fibAuditNetwork(routeInfoRecord.destNetIp, routeInfoRecord.networkMask)
+		if ribRoutes[network] == nil {
+			ribRoutes[network] = &FibAuditRibRoute{vrf: DefaultVrf, ipType: ribdCommonDefs.IPv4, network: network}
+		}
+		ribRoutes[network].routeInfoRecords = append(ribRoutes[network].routeInfoRecords, routeInfoRecord)
+	}
+	return FibAuditRequest{ribRoutes: ribRoutes}
+}
+func printFibAuditState() {
+	state, _ := server.GetFibAuditState()
+	fmt.Println("fib audit: programmer:", state.FibProgrammer, " rib routes:", state.NumRibRoutes, " fib routes:", state.NumFibRoutes, " missing:", state.NumMissing, " extra:", state.NumExtra, " mismatched:", state.NumMismatched, " repaired:", state.NumRepaired)
+	entries, err := server.GetBulkFibAuditEntryState(0, 10)
+	if err != nil {
+		fmt.Println("GetBulkFibAuditEntryState returned err:", err)
+		return
+	}
+	for _, entry := range entries.FibAuditEntryStateList {
+		fmt.Println("    ", entry.Type, " network:", entry.Network, " rib next hops:", entry.RibNextHopList, " fib next hops:", entry.FibNextHopList, " repaired:", entry.Repaired)
+	}
+}
+func TestInitFibAuditTestServer(t *testing.T) {
+	fmt.Println("****Init FibAudit Test Server****")
+	StartTestServer()
+	fakeFib = &fakeFibProgrammer{routes: make(map[string]map[string]bool)}
+	fakeFib.update("80.1.1.0/24", []string{"11.1.10.2"}, true)
+	fakeFib.update("80.1.2.0/24", []string{"11.1.10.2"}, true)
+	fakeFib.update("80.1.4.0/24", []string{"11.1.10.4"}, true)
+	fmt.Println("****************")
+}
+func TestFibAudit(t *testing.T) {
+	fmt.Println("****TestFibAudit****")
+	savedFibProgrammer := fibProgrammer
+	fibProgrammer = fakeFib
+	server.ProcessFibAudit(fibAuditTestRequest())
+	fibProgrammer = savedFibProgrammer
+	printFibAuditState()
+	fmt.Println("****************")
+}
+func TestFibAuditRepair(t *testing.T) {
+	fmt.Println("****TestFibAuditRepair****")
+	savedFibProgrammer := fibProgrammer
+	fibProgrammer = fakeFib
+	FibAuditDB.RLock()
+	entries := FibAuditDB.entries
+	FibAuditDB.RUnlock()
+	server.ProcessFibAuditRepairBatch(FibAuditRepairBatch{entries: entries, last: true})
+	for _, route := range fibAuditRepairRoutes(entries) {
+		fmt.Println("repair ", route.Op, " route:", route.OrigConfigObject.(RouteInfoRecord).destNetIp, " next hop:", route.OrigConfigObject.(RouteInfoRecord).resolvedNextHopIpIntf.NextHopIp, " bulk end:", route.BulkEnd)
+		addAsicdRouteBulk(route.OrigConfigObject.(RouteInfoRecord), route.BulkEnd)
+	}
+	fmt.Println("fib after repair:", fakeFib.routes)
+	server.ProcessFibAudit(fibAuditTestRequest())
+	fibProgrammer = savedFibProgrammer
+	printFibAuditState()
+	fmt.Println("****************")
+}
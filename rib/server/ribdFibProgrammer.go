@@ -0,0 +1,413 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdFibProgrammer.go
+package server
+
+import (
+	"asicd/asicdCommonDefs"
+	"asicdInt"
+	"errors"
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"sort"
+)
+
+const (
+	FibProgrammerAsicd   = "asicd"
+	FibProgrammerNetlink = "netlink"
+)
+
+/*
+   Forwarding plane the routes selected by the RIB are programmed into. The programmer
+   is also the source of the L3 interfaces the connected routes are built from.
+   Routes use the asicd route objects, each call adds or removes the listed next hops
+   of the destination. Neither forwarding plane has next hop group objects, the groups are
+   expanded into the next hops of every prefix pointing to them, so a member change
+   rewrites the next hops of each prefix of the group.
+*/
+type FibProgrammer interface {
+	Name() string
+	Init() error
+	IsReady() bool
+	CreateIPv4Routes(routes []*asicdInt.IPv4Route)
+	DeleteIPv4Routes(routes []*asicdInt.IPv4Route)
+	CreateIPv6Routes(routes []*asicdInt.IPv6Route)
+	DeleteIPv6Routes(routes []*asicdInt.IPv6Route)
+	GetIntfInfo()
+	GetV4Intfs() V4IntfGetInfo
+	GetV6Intfs() V6IntfGetInfo
+	StartIntfEventListener(ribdServiceHandler *RIBDServer)
+	InitialRouteSyncDone()
+	GetInstalledRoutes() ([]FibRouteInfo, error)
+	SupportsPbr() bool
+	CreatePbrRules(rules []*PbrFibRule) error
+	DeletePbrRules(rules []*PbrFibRule) error
+	SupportsVrf() bool
+	CreateVrfRoutes(vrf string, routes []*FibVrfRoute) error
+	DeleteVrfRoutes(vrf string, routes []*FibVrfRoute) error
+	BindVrfIntf(vrf string, ifIndex int32) error
+	DeleteVrf(vrf string) error
+	SetNextHopGroup(group *FibNextHopGroup)
+	DeleteNextHopGroup(groupId int)
+	SetNextHopGroupRoutes(routes []*FibGroupRoute)
+}
+
+/*
+   Route as read back from the forwarding plane, network is in cidr form
+*/
+type FibRouteInfo struct {
+	ipType   ribdCommonDefs.IPType
+	network  string
+	nextHops []string
+}
+
+/*
+   Next hop group with the next hops and weights it forwards on
+*/
+type FibNextHopGroup struct {
+	id      int
+	ipType  ribdCommonDefs.IPType
+	members map[string]int32 //next hop ip -> weight
+}
+
+/*
+   Prefix pointing to a next hop group, group id 0 removes the prefix
+*/
+type FibGroupRoute struct {
+	ipType      ribdCommonDefs.IPType
+	destNw      string
+	networkMask string
+	groupId     int
+}
+
+func (route *FibGroupRoute) Key() string {
+	return route.destNw + "/" + route.networkMask
+}
+
+/*
+   Next hop of a route in a non default vrf. The next hop interface is always set since
+   the next hop of a leaked route is resolved in the vrf the route was leaked from.
+*/
+type FibVrfRoute struct {
+	ipType      ribdCommonDefs.IPType
+	destNw      string
+	networkMask string
+	nextHopIp   string
+	ifIndex     int32
+	weight      int32
+}
+
+/*
+   Policy based routing rule of one ingress interface, the prefixes are nil and the
+   protocol, ports and dscp are -1 when they match any packet
+*/
+type PbrFibRule struct {
+	priority   int
+	ifIndex    int32
+	intfName   string
+	ipType     ribdCommonDefs.IPType
+	srcPrefix  *net.IPNet
+	dstPrefix  *net.IPNet
+	protocol   int
+	srcPortMin int
+	srcPortMax int
+	dstPortMin int
+	dstPortMax int
+	dscp       int
+	action     string
+	nextHop    string
+	vrf        string
+}
+
+func (rule *PbrFibRule) Key() string {
+	return fmt.Sprint(rule.ifIndex, ":", rule.priority, ":", rule.ipType, ":", rule.srcPrefix, ":", rule.dstPrefix, ":", rule.protocol, ":",
+		rule.srcPortMin, "-", rule.srcPortMax, ":", rule.dstPortMin, "-", rule.dstPortMax, ":", rule.dscp, ":", rule.action, ":", rule.nextHop, ":", rule.vrf)
+}
+
+var fibProgrammer FibProgrammer = &AsicdFibProgrammer{}
+
+/*
+   Selects the fib programmer, has to be called before the server is started
+*/
+func (ribdServiceHandler *RIBDServer) SetFibProgrammer(fibType string, table int) (err error) {
+	logger.Info("SetFibProgrammer type:", fibType, " table:", table)
+	switch fibType {
+	case FibProgrammerAsicd:
+		fibProgrammer = &AsicdFibProgrammer{}
+	case FibProgrammerNetlink:
+		programmer, err := NewNetlinkFibProgrammer(table)
+		if err != nil {
+			return err
+		}
+		fibProgrammer = programmer
+	default:
+		return errors.New(fmt.Sprintln("Invalid fib programmer ", fibType))
+	}
+	return fibProgrammer.Init()
+}
+func usingAsicdFib() bool {
+	return fibProgrammer.Name() == FibProgrammerAsicd
+}
+
+/*
+   Next hop groups of the forwarding planes without next hop group objects, the group is
+   expanded into the next hops of the prefixes pointing to it. Only the next hops that
+   changed are sent, and only the prefixes of the changed group are touched.
+*/
+type fibNextHopGroupTable struct {
+	groups      map[int]*FibNextHopGroup
+	routes      map[string]*FibGroupRoute         //prefix -> route
+	groupRoutes map[int]map[string]*FibGroupRoute //group id -> prefix -> route
+}
+
+/*
+   Route updates of the expanded groups, deletes are sent first so that the weight change
+   of a next hop is not undone by the delete of its old weight
+*/
+type fibGroupRouteUpdate struct {
+	v4CreateList []*asicdInt.IPv4Route
+	v4DeleteList []*asicdInt.IPv4Route
+	v6CreateList []*asicdInt.IPv6Route
+	v6DeleteList []*asicdInt.IPv6Route
+}
+
+func newFibNextHopGroupTable() *fibNextHopGroupTable {
+	return &fibNextHopGroupTable{
+		groups:      make(map[int]*FibNextHopGroup),
+		routes:      make(map[string]*FibGroupRoute),
+		groupRoutes: make(map[int]map[string]*FibGroupRoute),
+	}
+}
+func (table *fibNextHopGroupTable) groupMembers(groupId int) map[string]int32 {
+	if group, ok := table.groups[groupId]; ok {
+		return group.members
+	}
+	return nil
+}
+func (table *fibNextHopGroupTable) setGroup(group *FibNextHopGroup) *fibGroupRouteUpdate {
+	update := &fibGroupRouteUpdate{}
+	oldMembers := table.groupMembers(group.id)
+	table.groups[group.id] = group
+	for _, route := range table.groupRoutes[group.id] {
+		update.queue(route, oldMembers, group.members)
+	}
+	return update
+}
+func (table *fibNextHopGroupTable) deleteGroup(groupId int) *fibGroupRouteUpdate {
+	update := &fibGroupRouteUpdate{}
+	oldMembers := table.groupMembers(groupId)
+	for prefix, route := range table.groupRoutes[groupId] {
+		update.queue(route, oldMembers, nil)
+		delete(table.routes, prefix)
+	}
+	delete(table.groupRoutes, groupId)
+	delete(table.groups, groupId)
+	return update
+}
+func (table *fibNextHopGroupTable) setRoutes(routes []*FibGroupRoute) *fibGroupRouteUpdate {
+	update := &fibGroupRouteUpdate{}
+	for _, route := range routes {
+		prefix := route.Key()
+		var oldMembers map[string]int32
+		if oldRoute, ok := table.routes[prefix]; ok {
+			oldMembers = table.groupMembers(oldRoute.groupId)
+			delete(table.groupRoutes[oldRoute.groupId], prefix)
+			delete(table.routes, prefix)
+		}
+		var newMembers map[string]int32
+		if group, ok := table.groups[route.groupId]; ok {
+			newMembers = group.members
+			table.routes[prefix] = route
+			if table.groupRoutes[route.groupId] == nil {
+				table.groupRoutes[route.groupId] = make(map[string]*FibGroupRoute)
+			}
+			table.groupRoutes[route.groupId][prefix] = route
+		}
+		update.queue(route, oldMembers, newMembers)
+	}
+	return update
+}
+func (update *fibGroupRouteUpdate) queueNextHops(route *FibGroupRoute, nextHops map[string]int32, create bool) {
+	if len(nextHops) == 0 {
+		return
+	}
+	nextHopIps := make([]string, 0)
+	for nextHopIp, _ := range nextHops {
+		nextHopIps = append(nextHopIps, nextHopIp)
+	}
+	sort.Strings(nextHopIps)
+	if route.ipType == ribdCommonDefs.IPv6 {
+		v6NextHops := make([]*asicdInt.IPv6NextHop, 0)
+		for _, nextHopIp := range nextHopIps {
+			v6NextHops = append(v6NextHops, &asicdInt.IPv6NextHop{NextHopIp: nextHopIp, Weight: nextHops[nextHopIp]})
+		}
+		v6Route := &asicdInt.IPv6Route{route.destNw, route.networkMask, v6NextHops}
+		if create {
+			update.v6CreateList = append(update.v6CreateList, v6Route)
+		} else {
+			update.v6DeleteList = append(update.v6DeleteList, v6Route)
+		}
+		return
+	}
+	v4NextHops := make([]*asicdInt.IPv4NextHop, 0)
+	for _, nextHopIp := range nextHopIps {
+		v4NextHops = append(v4NextHops, &asicdInt.IPv4NextHop{NextHopIp: nextHopIp, Weight: nextHops[nextHopIp]})
+	}
+	v4Route := &asicdInt.IPv4Route{route.destNw, route.networkMask, v4NextHops}
+	if create {
+		update.v4CreateList = append(update.v4CreateList, v4Route)
+	} else {
+		update.v4DeleteList = append(update.v4DeleteList, v4Route)
+	}
+}
+
+/*
+   Queue the next hop updates moving the prefix from the old set of next hops to the new one
+*/
+func (update *fibGroupRouteUpdate) queue(route *FibGroupRoute, oldMembers map[string]int32, newMembers map[string]int32) {
+	delList := make(map[string]int32)
+	addList := make(map[string]int32)
+	for nextHopIp, weight := range oldMembers {
+		if newWeight, ok := newMembers[nextHopIp]; !ok || newWeight != weight {
+			delList[nextHopIp] = weight
+		}
+	}
+	for nextHopIp, weight := range newMembers {
+		if oldWeight, ok := oldMembers[nextHopIp]; !ok || oldWeight != weight {
+			addList[nextHopIp] = weight
+		}
+	}
+	update.queueNextHops(route, delList, false)
+	update.queueNextHops(route, addList, true)
+}
+func (update *fibGroupRouteUpdate) send(fib FibProgrammer) {
+	if len(update.v4DeleteList) > 0 {
+		fib.DeleteIPv4Routes(update.v4DeleteList)
+	}
+	if len(update.v6DeleteList) > 0 {
+		fib.DeleteIPv6Routes(update.v6DeleteList)
+	}
+	if len(update.v4CreateList) > 0 {
+		fib.CreateIPv4Routes(update.v4CreateList)
+	}
+	if len(update.v6CreateList) > 0 {
+		fib.CreateIPv6Routes(update.v6CreateList)
+	}
+}
+
+/*
+   asicd programs the hardware tables
+*/
+type AsicdFibProgrammer struct {
+	groupTable *fibNextHopGroupTable
+}
+
+func (fib *AsicdFibProgrammer) Name() string {
+	return FibProgrammerAsicd
+}
+func (fib *AsicdFibProgrammer) Init() error {
+	//the asicd client is connected along with the other clients
+	return nil
+}
+func (fib *AsicdFibProgrammer) IsReady() bool {
+	return asicdclnt.IsConnected
+}
+func (fib *AsicdFibProgrammer) CreateIPv4Routes(routes []*asicdInt.IPv4Route) {
+	asicdclnt.ClientHdl.OnewayCreateIPv4Route(routes)
+}
+func (fib *AsicdFibProgrammer) DeleteIPv4Routes(routes []*asicdInt.IPv4Route) {
+	asicdclnt.ClientHdl.OnewayDeleteIPv4Route(routes)
+}
+func (fib *AsicdFibProgrammer) CreateIPv6Routes(routes []*asicdInt.IPv6Route) {
+	asicdclnt.ClientHdl.OnewayCreateIPv6Route(routes)
+}
+func (fib *AsicdFibProgrammer) DeleteIPv6Routes(routes []*asicdInt.IPv6Route) {
+	asicdclnt.ClientHdl.OnewayDeleteIPv6Route(routes)
+}
+func (fib *AsicdFibProgrammer) GetIntfInfo() {
+	getIntfInfo()
+}
+func (fib *AsicdFibProgrammer) GetV4Intfs() V4IntfGetInfo {
+	return getAsicdV4Intfs()
+}
+func (fib *AsicdFibProgrammer) GetV6Intfs() V6IntfGetInfo {
+	return getAsicdV6Intfs()
+}
+func (fib *AsicdFibProgrammer) StartIntfEventListener(ribdServiceHandler *RIBDServer) {
+	go ribdServiceHandler.SetupEventHandler(AsicdSub, asicdCommonDefs.PUB_SOCKET_ADDR, SUB_ASICD)
+}
+func (fib *AsicdFibProgrammer) InitialRouteSyncDone() {
+}
+func (fib *AsicdFibProgrammer) GetInstalledRoutes() ([]FibRouteInfo, error) {
+	return getAsicdInstalledRoutes()
+}
+
+/*
+   asicd has no policy rules and no per vrf route tables, the pbr bindings and the non default
+   vrfs are rejected when they are configured
+*/
+func (fib *AsicdFibProgrammer) SupportsPbr() bool {
+	return false
+}
+func (fib *AsicdFibProgrammer) SupportsVrf() bool {
+	return false
+}
+func (fib *AsicdFibProgrammer) CreatePbrRules(rules []*PbrFibRule) error {
+	return errors.New("policy based routing is not supported by asicd")
+}
+func (fib *AsicdFibProgrammer) DeletePbrRules(rules []*PbrFibRule) error {
+	return errors.New("policy based routing is not supported by asicd")
+}
+func (fib *AsicdFibProgrammer) CreateVrfRoutes(vrf string, routes []*FibVrfRoute) error {
+	return errors.New("vrf route tables are not supported by asicd")
+}
+func (fib *AsicdFibProgrammer) DeleteVrfRoutes(vrf string, routes []*FibVrfRoute) error {
+	return errors.New("vrf route tables are not supported by asicd")
+}
+func (fib *AsicdFibProgrammer) BindVrfIntf(vrf string, ifIndex int32) error {
+	return errors.New("vrf route tables are not supported by asicd")
+}
+func (fib *AsicdFibProgrammer) DeleteVrf(vrf string) error {
+	return errors.New("vrf route tables are not supported by asicd")
+}
+
+/*
+   asicd has no next hop group objects, the groups are expanded into the prefix next hops
+*/
+func (fib *AsicdFibProgrammer) nextHopGroups() *fibNextHopGroupTable {
+	if fib.groupTable == nil {
+		fib.groupTable = newFibNextHopGroupTable()
+	}
+	return fib.groupTable
+}
+func (fib *AsicdFibProgrammer) SetNextHopGroup(group *FibNextHopGroup) {
+	fib.nextHopGroups().setGroup(group).send(fib)
+}
+func (fib *AsicdFibProgrammer) DeleteNextHopGroup(groupId int) {
+	fib.nextHopGroups().deleteGroup(groupId).send(fib)
+}
+func (fib *AsicdFibProgrammer) SetNextHopGroupRoutes(routes []*FibGroupRoute) {
+	fib.nextHopGroups().setRoutes(routes).send(fib)
+}
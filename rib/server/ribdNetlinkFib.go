@@ -0,0 +1,1106 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdNetlinkFib.go
+package server
+
+import (
+	"asicd/asicdCommonDefs"
+	"asicdInt"
+	"asicdServices"
+	"errors"
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultNetlinkRouteTable    = syscall.RT_TABLE_MAIN
+	DefaultNetlinkRouteProtocol = 195 //rtnetlink protocol id the ribd routes are tagged with
+	NetlinkStaleRouteHoldTime   = 90 * time.Second
+	netlinkMaxRouteWeight       = 256
+	PbrNetlinkTableBase         = 10000 //route tables of the pbr next hops are allocated from here
+	PbrNetlinkMaxTables         = 1024
+	VrfNetlinkTableBase         = 20000 //route tables of the non default vrfs are allocated from here
+	VrfNetlinkMaxTables         = 1024
+	VrfNetlinkRulePriority      = 32000 //after the pbr rules, before the main table rule
+)
+
+/*
+   The netlink calls used by the programmer, the kernel handle does the actual
+   rtnetlink requests and the tests plug in a fake one
+*/
+type netlinkHandle interface {
+	RouteReplace(route *netlink.Route) error
+	RouteDel(route *netlink.Route) error
+	RouteList(family int, table int) ([]netlink.Route, error)
+	LinkList() ([]netlink.Link, error)
+	AddrList(family int) ([]netlink.Addr, error)
+	Subscribe(linkCh chan netlink.LinkUpdate, addrCh chan netlink.AddrUpdate, done chan struct{}) error
+	RuleAdd(rule *netlink.Rule) error
+	RuleDel(rule *netlink.Rule) error
+	RuleList(family int) ([]netlink.Rule, error)
+}
+
+type kernelNetlinkHandle struct {
+}
+
+func (handle kernelNetlinkHandle) RouteReplace(route *netlink.Route) error {
+	return netlink.RouteReplace(route)
+}
+func (handle kernelNetlinkHandle) RouteDel(route *netlink.Route) error {
+	return netlink.RouteDel(route)
+}
+func (handle kernelNetlinkHandle) RouteList(family int, table int) ([]netlink.Route, error) {
+	return netlink.RouteListFiltered(family, &netlink.Route{Table: table}, netlink.RT_FILTER_TABLE)
+}
+func (handle kernelNetlinkHandle) LinkList() ([]netlink.Link, error) {
+	return netlink.LinkList()
+}
+func (handle kernelNetlinkHandle) AddrList(family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(nil, family)
+}
+func (handle kernelNetlinkHandle) Subscribe(linkCh chan netlink.LinkUpdate, addrCh chan netlink.AddrUpdate, done chan struct{}) error {
+	err := netlink.LinkSubscribe(linkCh, done)
+	if err != nil {
+		return err
+	}
+	return netlink.AddrSubscribe(addrCh, done)
+}
+func (handle kernelNetlinkHandle) RuleAdd(rule *netlink.Rule) error {
+	return netlink.RuleAdd(rule)
+}
+func (handle kernelNetlinkHandle) RuleDel(rule *netlink.Rule) error {
+	return netlink.RuleDel(rule)
+}
+func (handle kernelNetlinkHandle) RuleList(family int) ([]netlink.Rule, error) {
+	return netlink.RuleList(family)
+}
+
+/*
+   Route programmed into the kernel, next hop ip -> asicd weight
+*/
+type NetlinkRouteInfo struct {
+	family   int
+	dst      *net.IPNet
+	nextHops map[string]int32
+}
+
+/*
+   Interface learnt from the kernel, addrs is the set of the interface addresses in cidr form
+*/
+type NetlinkIntfInfo struct {
+	name  string
+	isUp  bool
+	addrs map[string]int
+}
+
+/*
+   Route table holding the default route the pbr rules of a next hop, or the drop rules of
+   a family, point to
+*/
+type NetlinkPbrTable struct {
+	id       int
+	route    *netlink.Route
+	refCount int
+}
+type NetlinkPbrRule struct {
+	rule     *netlink.Rule
+	tableKey string //empty when the rule points to the ribd table or a vrf table
+}
+
+/*
+   Route table of a non default vrf, the packets received on the interfaces bound to the
+   vrf are looked up in it through an iif rule per family
+*/
+type NetlinkVrfTable struct {
+	id        int
+	routes    map[string]*NetlinkVrfRouteInfo //prefix -> route
+	intfRules map[int32][]*netlink.Rule       //ifIndex -> iif rules
+}
+type NetlinkVrfRouteInfo struct {
+	dst      *net.IPNet
+	nextHops map[string]*FibVrfRoute //next hop ip@ifIndex -> next hop
+}
+
+/*
+   Programs the routes into a linux kernel route table over rtnetlink. The kernel does
+   the forwarding and the neighbor resolution, so the connected routes are left to it
+   and only the routes with gateways are installed.
+*/
+type NetlinkFibProgrammer struct {
+	sync.Mutex
+	handle      netlinkHandle
+	table       int
+	protocol    int
+	ready       bool
+	routes      map[string]*NetlinkRouteInfo //prefix -> route installed by ribd
+	staleRoutes map[string]netlink.Route     //prefix -> route left behind by the previous ribd instance
+	intfs       map[int32]*NetlinkIntfInfo
+	pbrTables   map[string]*NetlinkPbrTable //next hop or drop family -> table
+	pbrRules    map[string]*NetlinkPbrRule  //pbr rule key -> kernel rule
+	vrfTables   map[string]*NetlinkVrfTable //vrf -> route table of the vrf
+	groupTable  *fibNextHopGroupTable
+	linkCh      chan netlink.LinkUpdate
+	addrCh      chan netlink.AddrUpdate
+	done        chan struct{}
+}
+
+func NewNetlinkFibProgrammer(table int) (*NetlinkFibProgrammer, error) {
+	if table <= syscall.RT_TABLE_UNSPEC || table == syscall.RT_TABLE_LOCAL {
+		return nil, errors.New(fmt.Sprintln("Invalid kernel route table ", table))
+	}
+	return newNetlinkFibProgrammer(kernelNetlinkHandle{}, table), nil
+}
+func newNetlinkFibProgrammer(handle netlinkHandle, table int) *NetlinkFibProgrammer {
+	return &NetlinkFibProgrammer{
+		handle:      handle,
+		table:       table,
+		protocol:    DefaultNetlinkRouteProtocol,
+		routes:      make(map[string]*NetlinkRouteInfo),
+		staleRoutes: make(map[string]netlink.Route),
+		intfs:       make(map[int32]*NetlinkIntfInfo),
+		pbrTables:   make(map[string]*NetlinkPbrTable),
+		pbrRules:    make(map[string]*NetlinkPbrRule),
+		vrfTables:   make(map[string]*NetlinkVrfTable),
+		groupTable:  newFibNextHopGroupTable(),
+	}
+}
+func (fib *NetlinkFibProgrammer) Name() string {
+	return FibProgrammerNetlink
+}
+
+/*
+   Subscribes to the link and address events before anything is read from the kernel so
+   that no change is lost, and records the routes a previous instance left in the table
+*/
+func (fib *NetlinkFibProgrammer) Init() error {
+	fib.Lock()
+	defer fib.Unlock()
+	fib.linkCh = make(chan netlink.LinkUpdate, 100)
+	fib.addrCh = make(chan netlink.AddrUpdate, 100)
+	fib.done = make(chan struct{})
+	err := fib.handle.Subscribe(fib.linkCh, fib.addrCh, fib.done)
+	if err != nil {
+		logger.Err("NetlinkFibProgrammer: subscribe to link/addr events failed with err:", err)
+		return err
+	}
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		routes, err := fib.handle.RouteList(family, fib.table)
+		if err != nil {
+			logger.Err("NetlinkFibProgrammer: route list for family ", family, " failed with err:", err)
+			return err
+		}
+		for _, route := range routes {
+			if route.Protocol != fib.protocol || route.Dst == nil {
+				continue
+			}
+			fib.staleRoutes[route.Dst.String()] = route
+		}
+	}
+	logger.Info("NetlinkFibProgrammer: table ", fib.table, " protocol ", fib.protocol, " stale routes:", len(fib.staleRoutes))
+	fib.flushStalePbrRules()
+	fib.ready = true
+	return nil
+}
+func (fib *NetlinkFibProgrammer) IsReady() bool {
+	fib.Lock()
+	defer fib.Unlock()
+	return fib.ready
+}
+
+/*
+   Builds the destination prefix of an asicd route object, returns nil for the routes
+   the kernel owns
+*/
+func netlinkRouteDst(destNw string, networkMask string) *net.IPNet {
+	ip := net.ParseIP(destNw)
+	mask := net.ParseIP(networkMask)
+	if ip == nil || mask == nil {
+		return nil
+	}
+	if ip.To4() != nil {
+		ip = ip.To4()
+		mask = mask.To4()
+	}
+	if mask == nil || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return nil
+	}
+	return &net.IPNet{IP: ip.Mask(net.IPMask(mask)), Mask: net.IPMask(mask)}
+}
+func netlinkRouteFamily(dst *net.IPNet) int {
+	if dst.IP.To4() != nil {
+		return netlink.FAMILY_V4
+	}
+	return netlink.FAMILY_V6
+}
+
+/*
+   Kernel route for the current next hop set of the prefix, rtnexthop hops is the weight - 1
+*/
+func (fib *NetlinkFibProgrammer) kernelRoute(route *NetlinkRouteInfo) *netlink.Route {
+	kRoute := &netlink.Route{
+		Dst:      route.dst,
+		Protocol: fib.protocol,
+		Table:    fib.table,
+	}
+	nextHops := make([]string, 0, len(route.nextHops))
+	for nextHopIp := range route.nextHops {
+		nextHops = append(nextHops, nextHopIp)
+	}
+	sort.Strings(nextHops)
+	if len(nextHops) == 1 {
+		kRoute.Gw = net.ParseIP(nextHops[0])
+		return kRoute
+	}
+	for _, nextHopIp := range nextHops {
+		kRoute.MultiPath = append(kRoute.MultiPath, &netlink.NexthopInfo{Gw: net.ParseIP(nextHopIp), Hops: netlinkNextHopHops(route.nextHops[nextHopIp])})
+	}
+	return kRoute
+}
+func netlinkNextHopHops(weight int32) int {
+	hops := int(weight) - 1
+	if hops < 0 {
+		hops = 0
+	} else if hops >= netlinkMaxRouteWeight {
+		hops = netlinkMaxRouteWeight - 1
+	}
+	return hops
+}
+
+/*
+   Adds/removes the next hops of a prefix and pushes the resulting next hop set to the
+   kernel, the route is deleted from the table when its last next hop goes away
+*/
+func (fib *NetlinkFibProgrammer) updateRoute(destNw string, networkMask string, nextHops map[string]int32, add bool) {
+	dst := netlinkRouteDst(destNw, networkMask)
+	if dst == nil {
+		logger.Debug("NetlinkFibProgrammer: skip route ", destNw, ":", networkMask)
+		return
+	}
+	prefix := dst.String()
+	route, ok := fib.routes[prefix]
+	if !ok {
+		if !add {
+			return
+		}
+		route = &NetlinkRouteInfo{family: netlinkRouteFamily(dst), dst: dst, nextHops: make(map[string]int32)}
+	}
+	changed := false
+	for nextHopIp, weight := range nextHops {
+		ip := net.ParseIP(nextHopIp)
+		if ip == nil || ip.IsUnspecified() {
+			//connected route, installed by the kernel with the interface address
+			continue
+		}
+		if add {
+			//always pushed, the kernel flushes the routes of a link that goes down
+			route.nextHops[ip.String()] = weight
+			changed = true
+		} else if _, exists := route.nextHops[ip.String()]; exists {
+			delete(route.nextHops, ip.String())
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	delete(fib.staleRoutes, prefix)
+	if len(route.nextHops) == 0 {
+		delete(fib.routes, prefix)
+		err := fib.handle.RouteDel(fib.kernelRoute(route))
+		if err != nil && err != syscall.ESRCH {
+			logger.Err("NetlinkFibProgrammer: route delete for ", prefix, " failed with err:", err)
+		}
+		return
+	}
+	fib.routes[prefix] = route
+	err := fib.handle.RouteReplace(fib.kernelRoute(route))
+	if err != nil {
+		logger.Err("NetlinkFibProgrammer: route replace for ", prefix, " next hops:", route.nextHops, " failed with err:", err)
+	}
+}
+func (fib *NetlinkFibProgrammer) CreateIPv4Routes(routes []*asicdInt.IPv4Route) {
+	fib.Lock()
+	defer fib.Unlock()
+	for _, route := range routes {
+		nextHops := make(map[string]int32)
+		for _, nextHop := range route.NextHopList {
+			nextHops[nextHop.NextHopIp] = nextHop.Weight
+		}
+		fib.updateRoute(route.DestinationNw, route.NetworkMask, nextHops, true)
+	}
+}
+func (fib *NetlinkFibProgrammer) DeleteIPv4Routes(routes []*asicdInt.IPv4Route) {
+	fib.Lock()
+	defer fib.Unlock()
+	for _, route := range routes {
+		nextHops := make(map[string]int32)
+		for _, nextHop := range route.NextHopList {
+			nextHops[nextHop.NextHopIp] = nextHop.Weight
+		}
+		fib.updateRoute(route.DestinationNw, route.NetworkMask, nextHops, false)
+	}
+}
+func (fib *NetlinkFibProgrammer) CreateIPv6Routes(routes []*asicdInt.IPv6Route) {
+	fib.Lock()
+	defer fib.Unlock()
+	for _, route := range routes {
+		nextHops := make(map[string]int32)
+		for _, nextHop := range route.NextHopList {
+			nextHops[nextHop.NextHopIp] = nextHop.Weight
+		}
+		fib.updateRoute(route.DestinationNw, route.NetworkMask, nextHops, true)
+	}
+}
+func (fib *NetlinkFibProgrammer) DeleteIPv6Routes(routes []*asicdInt.IPv6Route) {
+	fib.Lock()
+	defer fib.Unlock()
+	for _, route := range routes {
+		nextHops := make(map[string]int32)
+		for _, nextHop := range route.NextHopList {
+			nextHops[nextHop.NextHopIp] = nextHop.Weight
+		}
+		fib.updateRoute(route.DestinationNw, route.NetworkMask, nextHops, false)
+	}
+}
+
+/*
+   netlink has no next hop group objects, the kernel route of each prefix carries the next
+   hops of its group. The group table is only used from the route programming loop and the
+   route updates take the lock
+*/
+func (fib *NetlinkFibProgrammer) SetNextHopGroup(group *FibNextHopGroup) {
+	fib.groupTable.setGroup(group).send(fib)
+}
+func (fib *NetlinkFibProgrammer) DeleteNextHopGroup(groupId int) {
+	fib.groupTable.deleteGroup(groupId).send(fib)
+}
+func (fib *NetlinkFibProgrammer) SetNextHopGroupRoutes(routes []*FibGroupRoute) {
+	fib.groupTable.setRoutes(routes).send(fib)
+}
+
+/*
+   The routes of the previous instance that ribd did not reprogram by the end of the hold
+   time are removed from the table
+*/
+func (fib *NetlinkFibProgrammer) InitialRouteSyncDone() {
+	fib.Lock()
+	defer fib.Unlock()
+	logger.Info("NetlinkFibProgrammer: ", len(fib.staleRoutes), " stale routes, sweep in ", NetlinkStaleRouteHoldTime)
+	time.AfterFunc(NetlinkStaleRouteHoldTime, fib.sweepStaleRoutes)
+}
+func (fib *NetlinkFibProgrammer) sweepStaleRoutes() {
+	fib.Lock()
+	defer fib.Unlock()
+	for prefix, route := range fib.staleRoutes {
+		logger.Info("NetlinkFibProgrammer: remove stale route ", prefix)
+		err := fib.handle.RouteDel(&route)
+		if err != nil && err != syscall.ESRCH {
+			logger.Err("NetlinkFibProgrammer: stale route delete for ", prefix, " failed with err:", err)
+		}
+		delete(fib.staleRoutes, prefix)
+	}
+}
+
+/*
+   Routes of the ribd protocol in the table, the connected routes are not read back since
+   the kernel owns them
+*/
+func (fib *NetlinkFibProgrammer) GetInstalledRoutes() ([]FibRouteInfo, error) {
+	fib.Lock()
+	defer fib.Unlock()
+	routes := make([]FibRouteInfo, 0)
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		kRoutes, err := fib.handle.RouteList(family, fib.table)
+		if err != nil {
+			logger.Err("NetlinkFibProgrammer: route list for family ", family, " failed with err:", err)
+			return routes, err
+		}
+		ipType := ribdCommonDefs.IPv4
+		if family == netlink.FAMILY_V6 {
+			ipType = ribdCommonDefs.IPv6
+		}
+		for _, kRoute := range kRoutes {
+			if kRoute.Protocol != fib.protocol || kRoute.Dst == nil {
+				continue
+			}
+			route := FibRouteInfo{ipType: ipType, network: kRoute.Dst.String(), nextHops: make([]string, 0)}
+			if kRoute.Gw != nil {
+				route.nextHops = append(route.nextHops, kRoute.Gw.String())
+			}
+			for _, nextHop := range kRoute.MultiPath {
+				route.nextHops = append(route.nextHops, nextHop.Gw.String())
+			}
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+
+func netlinkRibdRuleTable(table int) bool {
+	return (table >= PbrNetlinkTableBase && table < PbrNetlinkTableBase+PbrNetlinkMaxTables) ||
+		(table >= VrfNetlinkTableBase && table < VrfNetlinkTableBase+VrfNetlinkMaxTables)
+}
+
+/*
+   Removes the pbr and vrf rules and tables left behind by the previous instance, the policies
+   and the vrf routes are programmed again once they are configured
+*/
+func (fib *NetlinkFibProgrammer) flushStalePbrRules() {
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		rules, err := fib.handle.RuleList(family)
+		if err != nil {
+			logger.Err("NetlinkFibProgrammer: rule list for family ", family, " failed with err:", err)
+			continue
+		}
+		tables := make(map[int]bool)
+		for _, rule := range rules {
+			if !netlinkRibdRuleTable(rule.Table) {
+				continue
+			}
+			tables[rule.Table] = true
+			rule.Family = family
+			if err = fib.handle.RuleDel(&rule); err != nil && err != syscall.ENOENT {
+				logger.Err("NetlinkFibProgrammer: stale pbr rule delete ", rule, " failed with err:", err)
+			}
+		}
+		for table, _ := range tables {
+			routes, err := fib.handle.RouteList(family, table)
+			if err != nil {
+				continue
+			}
+			for _, route := range routes {
+				if route.Protocol != fib.protocol {
+					continue
+				}
+				if err = fib.handle.RouteDel(&route); err != nil && err != syscall.ESRCH {
+					logger.Err("NetlinkFibProgrammer: stale pbr route delete in table ", table, " failed with err:", err)
+				}
+			}
+		}
+	}
+}
+func netlinkPbrDefaultDst(ipType ribdCommonDefs.IPType) *net.IPNet {
+	if ipType == ribdCommonDefs.IPv4 {
+		return &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+	}
+	return &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+}
+
+/*
+   Takes a reference on the table of the next hop, or the drop table of the family, the
+   table is created with its default route on first use
+*/
+func (fib *NetlinkFibProgrammer) pbrTableGet(rule *PbrFibRule) (key string, table *NetlinkPbrTable, err error) {
+	key = "nh:" + rule.nextHop
+	if rule.action == PbrActionDrop {
+		key = fmt.Sprint("drop:", rule.ipType)
+	}
+	table, ok := fib.pbrTables[key]
+	if ok {
+		table.refCount++
+		return key, table, nil
+	}
+	ids := make(map[int]bool)
+	for _, table := range fib.pbrTables {
+		ids[table.id] = true
+	}
+	id := PbrNetlinkTableBase
+	for ; id < PbrNetlinkTableBase+PbrNetlinkMaxTables && ids[id]; id++ {
+	}
+	if id == PbrNetlinkTableBase+PbrNetlinkMaxTables {
+		return key, nil, errors.New("Out of pbr route tables")
+	}
+	route := &netlink.Route{Dst: netlinkPbrDefaultDst(rule.ipType), Protocol: fib.protocol, Table: id}
+	if rule.action == PbrActionDrop {
+		route.Type = syscall.RTN_BLACKHOLE
+	} else {
+		route.Gw = net.ParseIP(rule.nextHop)
+	}
+	if err = fib.handle.RouteReplace(route); err != nil {
+		return key, nil, err
+	}
+	table = &NetlinkPbrTable{id: id, route: route, refCount: 1}
+	fib.pbrTables[key] = table
+	return key, table, nil
+}
+func (fib *NetlinkFibProgrammer) pbrTablePut(key string) {
+	table, ok := fib.pbrTables[key]
+	if !ok {
+		return
+	}
+	table.refCount--
+	if table.refCount > 0 {
+		return
+	}
+	delete(fib.pbrTables, key)
+	err := fib.handle.RouteDel(table.route)
+	if err != nil && err != syscall.ESRCH {
+		logger.Err("NetlinkFibProgrammer: pbr table ", table.id, " route delete failed with err:", err)
+	}
+}
+
+/*
+   Kernel rules match on the addresses, the ingress interface, the ip protocol, the port ranges
+   and the dscp, which is the upper six bits of the tos match. SetVrf points the lookup to the
+   ribd table for the default vrf and to the table of the vrf otherwise.
+*/
+func (fib *NetlinkFibProgrammer) SupportsPbr() bool {
+	return true
+}
+func (fib *NetlinkFibProgrammer) CreatePbrRules(rules []*PbrFibRule) error {
+	fib.Lock()
+	defer fib.Unlock()
+	var retErr error
+	for _, rule := range rules {
+		if err := fib.createPbrRule(rule); err != nil {
+			logger.Err("NetlinkFibProgrammer: pbr rule ", rule.Key(), " create failed with err:", err)
+			retErr = err
+		}
+	}
+	return retErr
+}
+func (fib *NetlinkFibProgrammer) createPbrRule(rule *PbrFibRule) error {
+	if rule.intfName == "" {
+		return errors.New(fmt.Sprintln("No kernel interface for ifIndex ", rule.ifIndex))
+	}
+	key := rule.Key()
+	if _, ok := fib.pbrRules[key]; ok {
+		return nil
+	}
+	kRule := netlink.NewRule()
+	kRule.Priority = rule.priority
+	kRule.Family = netlink.FAMILY_V4
+	if rule.ipType == ribdCommonDefs.IPv6 {
+		kRule.Family = netlink.FAMILY_V6
+	}
+	kRule.Src = rule.srcPrefix
+	kRule.Dst = rule.dstPrefix
+	kRule.IifName = rule.intfName
+	if rule.protocol != pbrMatchAny {
+		kRule.IPProto = rule.protocol
+	}
+	if rule.srcPortMin != pbrMatchAny {
+		kRule.Sport = netlink.NewRulePortRange(uint16(rule.srcPortMin), uint16(rule.srcPortMax))
+	}
+	if rule.dstPortMin != pbrMatchAny {
+		kRule.Dport = netlink.NewRulePortRange(uint16(rule.dstPortMin), uint16(rule.dstPortMax))
+	}
+	if rule.dscp != pbrMatchAny {
+		kRule.Tos = uint(rule.dscp << 2)
+	}
+	tableKey := ""
+	switch rule.action {
+	case PbrActionSetVrf:
+		kRule.Table = fib.table
+		if rule.vrf != DefaultVrf {
+			table, err := fib.vrfTableGet(rule.vrf, true)
+			if err != nil {
+				return err
+			}
+			kRule.Table = table.id
+		}
+	default:
+		var table *NetlinkPbrTable
+		var err error
+		tableKey, table, err = fib.pbrTableGet(rule)
+		if err != nil {
+			return err
+		}
+		kRule.Table = table.id
+	}
+	err := fib.handle.RuleAdd(kRule)
+	if err != nil && err != syscall.EEXIST {
+		if tableKey != "" {
+			fib.pbrTablePut(tableKey)
+		}
+		return err
+	}
+	fib.pbrRules[key] = &NetlinkPbrRule{rule: kRule, tableKey: tableKey}
+	return nil
+}
+func (fib *NetlinkFibProgrammer) DeletePbrRules(rules []*PbrFibRule) error {
+	fib.Lock()
+	defer fib.Unlock()
+	var retErr error
+	for _, rule := range rules {
+		key := rule.Key()
+		pbrRule, ok := fib.pbrRules[key]
+		if !ok {
+			continue
+		}
+		delete(fib.pbrRules, key)
+		err := fib.handle.RuleDel(pbrRule.rule)
+		if err != nil && err != syscall.ENOENT {
+			logger.Err("NetlinkFibProgrammer: pbr rule ", key, " delete failed with err:", err)
+			retErr = err
+		}
+		if pbrRule.tableKey != "" {
+			fib.pbrTablePut(pbrRule.tableKey)
+		}
+	}
+	return retErr
+}
+
+/*
+   Returns the route table of the vrf, the table is allocated on first use when create is set
+*/
+func (fib *NetlinkFibProgrammer) vrfTableGet(vrf string, create bool) (*NetlinkVrfTable, error) {
+	table, ok := fib.vrfTables[vrf]
+	if ok || !create {
+		return table, nil
+	}
+	ids := make(map[int]bool)
+	for _, table := range fib.vrfTables {
+		ids[table.id] = true
+	}
+	id := VrfNetlinkTableBase
+	for ; id < VrfNetlinkTableBase+VrfNetlinkMaxTables && ids[id]; id++ {
+	}
+	if id == VrfNetlinkTableBase+VrfNetlinkMaxTables {
+		return nil, errors.New("Out of vrf route tables")
+	}
+	logger.Info("NetlinkFibProgrammer: vrf ", vrf, " uses table ", id)
+	table = &NetlinkVrfTable{id: id, routes: make(map[string]*NetlinkVrfRouteInfo), intfRules: make(map[int32][]*netlink.Rule)}
+	fib.vrfTables[vrf] = table
+	return table, nil
+}
+func netlinkVrfGw(nextHopIp string) net.IP {
+	ip := net.ParseIP(nextHopIp)
+	if ip == nil || ip.IsUnspecified() {
+		return nil
+	}
+	return ip
+}
+
+/*
+   The kernel only installs the connected routes in the main table, so unlike the ribd table
+   the vrf tables carry the connected routes as well. Every next hop names its interface since
+   the interface of a leaked route is bound to another vrf.
+*/
+func (fib *NetlinkFibProgrammer) kernelVrfRoute(table *NetlinkVrfTable, route *NetlinkVrfRouteInfo) *netlink.Route {
+	kRoute := &netlink.Route{
+		Dst:      route.dst,
+		Protocol: fib.protocol,
+		Table:    table.id,
+	}
+	keys := make([]string, 0, len(route.nextHops))
+	for key, _ := range route.nextHops {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 1 {
+		nextHop := route.nextHops[keys[0]]
+		kRoute.LinkIndex = int(nextHop.ifIndex)
+		kRoute.Gw = netlinkVrfGw(nextHop.nextHopIp)
+		if kRoute.Gw == nil {
+			kRoute.Scope = netlink.SCOPE_LINK
+		}
+		return kRoute
+	}
+	for _, key := range keys {
+		nextHop := route.nextHops[key]
+		kRoute.MultiPath = append(kRoute.MultiPath, &netlink.NexthopInfo{LinkIndex: int(nextHop.ifIndex), Gw: netlinkVrfGw(nextHop.nextHopIp), Hops: netlinkNextHopHops(nextHop.weight)})
+	}
+	return kRoute
+}
+func (fib *NetlinkFibProgrammer) updateVrfRoute(table *NetlinkVrfTable, nextHop *FibVrfRoute, add bool) error {
+	dst := netlinkRouteDst(nextHop.destNw, nextHop.networkMask)
+	if dst == nil {
+		logger.Debug("NetlinkFibProgrammer: skip vrf route ", nextHop.destNw, ":", nextHop.networkMask)
+		return nil
+	}
+	prefix := dst.String()
+	route, ok := table.routes[prefix]
+	if !ok {
+		if !add {
+			return nil
+		}
+		route = &NetlinkVrfRouteInfo{dst: dst, nextHops: make(map[string]*FibVrfRoute)}
+	}
+	key := fmt.Sprint(nextHop.nextHopIp, "@", nextHop.ifIndex)
+	if add {
+		route.nextHops[key] = nextHop
+	} else if _, exists := route.nextHops[key]; exists {
+		delete(route.nextHops, key)
+	} else {
+		return nil
+	}
+	if len(route.nextHops) == 0 {
+		delete(table.routes, prefix)
+		err := fib.handle.RouteDel(&netlink.Route{Dst: dst, Protocol: fib.protocol, Table: table.id})
+		if err != nil && err != syscall.ESRCH {
+			return err
+		}
+		return nil
+	}
+	table.routes[prefix] = route
+	return fib.handle.RouteReplace(fib.kernelVrfRoute(table, route))
+}
+func (fib *NetlinkFibProgrammer) SupportsVrf() bool {
+	return true
+}
+func (fib *NetlinkFibProgrammer) CreateVrfRoutes(vrf string, routes []*FibVrfRoute) error {
+	fib.Lock()
+	defer fib.Unlock()
+	table, err := fib.vrfTableGet(vrf, true)
+	if err != nil {
+		return err
+	}
+	var retErr error
+	for _, route := range routes {
+		if err = fib.updateVrfRoute(table, route, true); err != nil {
+			logger.Err("NetlinkFibProgrammer: vrf ", vrf, " route ", route.destNw, ":", route.networkMask, " create failed with err:", err)
+			retErr = err
+		}
+	}
+	return retErr
+}
+func (fib *NetlinkFibProgrammer) DeleteVrfRoutes(vrf string, routes []*FibVrfRoute) error {
+	fib.Lock()
+	defer fib.Unlock()
+	table, _ := fib.vrfTableGet(vrf, false)
+	if table == nil {
+		return nil
+	}
+	var retErr error
+	for _, route := range routes {
+		if err := fib.updateVrfRoute(table, route, false); err != nil {
+			logger.Err("NetlinkFibProgrammer: vrf ", vrf, " route ", route.destNw, ":", route.networkMask, " delete failed with err:", err)
+			retErr = err
+		}
+	}
+	return retErr
+}
+func (fib *NetlinkFibProgrammer) vrfIntfRulesDel(table *NetlinkVrfTable, ifIndex int32) {
+	for _, kRule := range table.intfRules[ifIndex] {
+		err := fib.handle.RuleDel(kRule)
+		if err != nil && err != syscall.ENOENT {
+			logger.Err("NetlinkFibProgrammer: vrf rule ", kRule, " delete failed with err:", err)
+		}
+	}
+	delete(table.intfRules, ifIndex)
+}
+
+/*
+   Points the lookups of the packets received on the interface to the table of the vrf, the
+   binding is retried by ribd when the kernel link shows up later
+*/
+func (fib *NetlinkFibProgrammer) BindVrfIntf(vrf string, ifIndex int32) error {
+	fib.Lock()
+	defer fib.Unlock()
+	for name, table := range fib.vrfTables {
+		if name != vrf {
+			fib.vrfIntfRulesDel(table, ifIndex)
+		}
+	}
+	if vrf == DefaultVrf {
+		return nil
+	}
+	table, err := fib.vrfTableGet(vrf, true)
+	if err != nil {
+		return err
+	}
+	if _, ok := table.intfRules[ifIndex]; ok {
+		return nil
+	}
+	intf, ok := fib.intfs[ifIndex]
+	if !ok {
+		return errors.New(fmt.Sprintln("No kernel interface for ifIndex ", ifIndex))
+	}
+	rules := make([]*netlink.Rule, 0)
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		kRule := netlink.NewRule()
+		kRule.Priority = VrfNetlinkRulePriority
+		kRule.Family = family
+		kRule.IifName = intf.name
+		kRule.Table = table.id
+		if err = fib.handle.RuleAdd(kRule); err != nil && err != syscall.EEXIST {
+			table.intfRules[ifIndex] = rules
+			fib.vrfIntfRulesDel(table, ifIndex)
+			return err
+		}
+		rules = append(rules, kRule)
+	}
+	table.intfRules[ifIndex] = rules
+	return nil
+}
+func (fib *NetlinkFibProgrammer) DeleteVrf(vrf string) error {
+	fib.Lock()
+	defer fib.Unlock()
+	table, _ := fib.vrfTableGet(vrf, false)
+	if table == nil {
+		return nil
+	}
+	for ifIndex, _ := range table.intfRules {
+		fib.vrfIntfRulesDel(table, ifIndex)
+	}
+	for prefix, route := range table.routes {
+		err := fib.handle.RouteDel(&netlink.Route{Dst: route.dst, Protocol: fib.protocol, Table: table.id})
+		if err != nil && err != syscall.ESRCH {
+			logger.Err("NetlinkFibProgrammer: vrf ", vrf, " route ", prefix, " delete failed with err:", err)
+		}
+	}
+	//the table id may be handed to another vrf, so no pbr rule is left pointing to it
+	for key, pbrRule := range fib.pbrRules {
+		if pbrRule.tableKey != "" || pbrRule.rule.Table != table.id {
+			continue
+		}
+		err := fib.handle.RuleDel(pbrRule.rule)
+		if err != nil && err != syscall.ENOENT {
+			logger.Err("NetlinkFibProgrammer: pbr rule ", key, " delete failed with err:", err)
+		}
+		delete(fib.pbrRules, key)
+	}
+	delete(fib.vrfTables, vrf)
+	return nil
+}
+
+func netlinkLinkIsUp(attrs *netlink.LinkAttrs) bool {
+	if attrs.Flags&net.FlagUp == 0 {
+		return false
+	}
+	//loopback and tunnel devices report unknown
+	return attrs.OperState == netlink.OperUp || attrs.OperState == netlink.OperUnknown
+}
+func netlinkIntfOperState(isUp bool) string {
+	if isUp {
+		return "UP"
+	}
+	return "DOWN"
+}
+
+/*
+   Records the kernel link, returns whether it is new and whether its oper state changed
+*/
+func (fib *NetlinkFibProgrammer) linkUpdate(attrs *netlink.LinkAttrs) (created bool, stateChanged bool) {
+	ifIndex := int32(attrs.Index)
+	isUp := netlinkLinkIsUp(attrs)
+	intf, ok := fib.intfs[ifIndex]
+	if !ok {
+		intf = &NetlinkIntfInfo{addrs: make(map[string]int)}
+		fib.intfs[ifIndex] = intf
+	}
+	created = !ok || intf.name != attrs.Name
+	stateChanged = ok && intf.isUp != isUp
+	intf.name = attrs.Name
+	intf.isUp = isUp
+	return created, stateChanged
+}
+
+/*
+   Records the address of a kernel link, only the global unicast addresses are used for
+   connected routes. Returns whether the set of addresses of the link changed.
+*/
+func (fib *NetlinkFibProgrammer) addrUpdate(ifIndex int32, ipNet *net.IPNet, scope int, add bool) (family int, changed bool) {
+	if ipNet == nil || scope != int(netlink.SCOPE_UNIVERSE) || ipNet.IP.IsLinkLocalUnicast() {
+		return family, false
+	}
+	family = netlinkRouteFamily(ipNet)
+	intf, ok := fib.intfs[ifIndex]
+	if !ok {
+		intf = &NetlinkIntfInfo{isUp: true, addrs: make(map[string]int)}
+		fib.intfs[ifIndex] = intf
+	}
+	cidr := ipNet.String()
+	_, exists := intf.addrs[cidr]
+	if add == exists {
+		return family, false
+	}
+	if add {
+		intf.addrs[cidr] = family
+	} else {
+		delete(intf.addrs, cidr)
+	}
+	return family, true
+}
+func (fib *NetlinkFibProgrammer) GetIntfInfo() {
+	fib.Lock()
+	links, err := fib.handle.LinkList()
+	msgs := make([]asicdCommonDefs.LogicalIntfNotifyMsg, 0)
+	for _, link := range links {
+		attrs := link.Attrs()
+		fib.linkUpdate(attrs)
+		msgs = append(msgs, asicdCommonDefs.LogicalIntfNotifyMsg{IfIndex: int32(attrs.Index), LogicalIntfName: attrs.Name})
+	}
+	fib.Unlock()
+	if err != nil {
+		logger.Err("NetlinkFibProgrammer: link list failed with err:", err)
+		return
+	}
+	for _, msg := range msgs {
+		RouteServiceHandler.ProcessLogicalIntfCreateEvent(msg)
+	}
+}
+
+/*
+   Interface addresses of the family, with the name and oper state of their link
+*/
+func (fib *NetlinkFibProgrammer) getIntfAddrs(family int) (ipAddrList []string, ifIndexList []int32, err error) {
+	fib.Lock()
+	defer fib.Unlock()
+	addrs, err := fib.handle.AddrList(family)
+	if err != nil {
+		logger.Err("NetlinkFibProgrammer: addr list for family ", family, " failed with err:", err)
+		return ipAddrList, ifIndexList, err
+	}
+	for _, addr := range addrs {
+		if addr.IPNet == nil {
+			continue
+		}
+		ifIndex := int32(addr.LinkIndex)
+		if _, changed := fib.addrUpdate(ifIndex, addr.IPNet, addr.Scope, true); !changed {
+			continue
+		}
+		ipAddrList = append(ipAddrList, addr.IPNet.String())
+		ifIndexList = append(ifIndexList, ifIndex)
+	}
+	return ipAddrList, ifIndexList, err
+}
+func (fib *NetlinkFibProgrammer) intfNameAndState(ifIndex int32) (string, string) {
+	fib.Lock()
+	defer fib.Unlock()
+	intf, ok := fib.intfs[ifIndex]
+	if !ok {
+		return "", netlinkIntfOperState(false)
+	}
+	return intf.name, netlinkIntfOperState(intf.isUp)
+}
+func (fib *NetlinkFibProgrammer) GetV4Intfs() V4IntfGetInfo {
+	ipv4IntfList := make([]*asicdServices.IPv4IntfState, 0)
+	ipAddrList, ifIndexList, _ := fib.getIntfAddrs(netlink.FAMILY_V4)
+	for i := 0; i < len(ipAddrList); i++ {
+		name, operState := fib.intfNameAndState(ifIndexList[i])
+		ipv4IntfList = append(ipv4IntfList, &asicdServices.IPv4IntfState{
+			IntfRef:   name,
+			IfIndex:   ifIndexList[i],
+			IpAddr:    ipAddrList[i],
+			OperState: operState,
+		})
+	}
+	return V4IntfGetInfo{len(ipv4IntfList), ipv4IntfList}
+}
+func (fib *NetlinkFibProgrammer) GetV6Intfs() V6IntfGetInfo {
+	ipv6IntfList := make([]*asicdServices.IPv6IntfState, 0)
+	ipAddrList, ifIndexList, _ := fib.getIntfAddrs(netlink.FAMILY_V6)
+	for i := 0; i < len(ipAddrList); i++ {
+		name, operState := fib.intfNameAndState(ifIndexList[i])
+		ipv6IntfList = append(ipv6IntfList, &asicdServices.IPv6IntfState{
+			IntfRef:   name,
+			IfIndex:   ifIndexList[i],
+			IpAddr:    ipAddrList[i],
+			OperState: operState,
+		})
+	}
+	return V6IntfGetInfo{len(ipv6IntfList), ipv6IntfList}
+}
+
+func (fib *NetlinkFibProgrammer) StartIntfEventListener(ribdServiceHandler *RIBDServer) {
+	go fib.ProcessNetlinkEvents(ribdServiceHandler)
+}
+
+/*
+   Kernel link/address events, mapped onto the asicd interface event handlers
+*/
+func (fib *NetlinkFibProgrammer) ProcessNetlinkEvents(ribdServiceHandler *RIBDServer) {
+	logger.Info("NetlinkFibProgrammer: processing link/addr events")
+	for {
+		select {
+		case update, ok := <-fib.linkCh:
+			if !ok {
+				logger.Err("NetlinkFibProgrammer: link event channel closed")
+				return
+			}
+			fib.processLinkEvent(ribdServiceHandler, update)
+		case update, ok := <-fib.addrCh:
+			if !ok {
+				logger.Err("NetlinkFibProgrammer: addr event channel closed")
+				return
+			}
+			fib.processAddrEvent(ribdServiceHandler, update)
+		}
+	}
+}
+func (fib *NetlinkFibProgrammer) processLinkEvent(ribdServiceHandler *RIBDServer, update netlink.LinkUpdate) {
+	if update.Link == nil || update.Header.Type != syscall.RTM_NEWLINK {
+		return
+	}
+	attrs := update.Link.Attrs()
+	ifIndex := int32(attrs.Index)
+	fib.Lock()
+	created, stateChanged := fib.linkUpdate(attrs)
+	isUp := fib.intfs[ifIndex].isUp
+	addrs := make(map[string]int)
+	for cidr, family := range fib.intfs[ifIndex].addrs {
+		addrs[cidr] = family
+	}
+	fib.Unlock()
+	if created {
+		logger.Info("NetlinkFibProgrammer: link ", attrs.Name, " ifIndex ", ifIndex, " created")
+		ribdServiceHandler.ProcessLogicalIntfCreateEvent(asicdCommonDefs.LogicalIntfNotifyMsg{IfIndex: ifIndex, LogicalIntfName: attrs.Name})
+	}
+	if !stateChanged {
+		return
+	}
+	logger.Info("NetlinkFibProgrammer: link ", attrs.Name, " ifIndex ", ifIndex, " up:", isUp)
+	for cidr, family := range addrs {
+		switch {
+		case family == netlink.FAMILY_V4 && isUp:
+			ribdServiceHandler.ProcessIPv4IntfUpEvent(cidr, ifIndex)
+		case family == netlink.FAMILY_V4:
+			ribdServiceHandler.ProcessIPv4IntfDownEvent(cidr, ifIndex)
+		case isUp:
+			ribdServiceHandler.ProcessIPv6IntfUpEvent(cidr, ifIndex)
+		default:
+			ribdServiceHandler.ProcessIPv6IntfDownEvent(cidr, ifIndex)
+		}
+	}
+	ribdServiceHandler.TrackIntfStateUpdate(ifIndex, isUp)
+}
+func (fib *NetlinkFibProgrammer) processAddrEvent(ribdServiceHandler *RIBDServer, update netlink.AddrUpdate) {
+	ifIndex := int32(update.LinkIndex)
+	ipNet := update.LinkAddress
+	fib.Lock()
+	family, changed := fib.addrUpdate(ifIndex, &ipNet, update.Scope, update.NewAddr)
+	fib.Unlock()
+	if !changed {
+		return
+	}
+	logger.Info("NetlinkFibProgrammer: addr ", ipNet.String(), " ifIndex ", ifIndex, " added:", update.NewAddr)
+	switch {
+	case family == netlink.FAMILY_V4 && update.NewAddr:
+		ribdServiceHandler.ProcessIPv4IntfCreateEvent(asicdCommonDefs.IPv4IntfNotifyMsg{IpAddr: ipNet.String(), IfIndex: ifIndex})
+	case family == netlink.FAMILY_V4:
+		ribdServiceHandler.ProcessIPv4IntfDeleteEvent(asicdCommonDefs.IPv4IntfNotifyMsg{IpAddr: ipNet.String(), IfIndex: ifIndex})
+	case update.NewAddr:
+		ribdServiceHandler.ProcessIPv6IntfCreateEvent(asicdCommonDefs.IPv6IntfNotifyMsg{IpAddr: ipNet.String(), IfIndex: ifIndex})
+	default:
+		ribdServiceHandler.ProcessIPv6IntfDeleteEvent(asicdCommonDefs.IPv6IntfNotifyMsg{IpAddr: ipNet.String(), IfIndex: ifIndex})
+	}
+}
@@ -0,0 +1,295 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"asicdInt"
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"syscall"
+	"testing"
+)
+
+/*
+   In memory stand in for the kernel route tables and rules
+*/
+type fakeNetlinkHandle struct {
+	routes map[string]netlink.Route //table:prefix -> route
+	rules  []netlink.Rule
+	links  []netlink.Link
+	addrs  []netlink.Addr
+}
+
+func fakeNetlinkRouteKey(route *netlink.Route) string {
+	return fmt.Sprint(route.Table, ":", route.Dst)
+}
+func (handle *fakeNetlinkHandle) RouteReplace(route *netlink.Route) error {
+	handle.routes[fakeNetlinkRouteKey(route)] = *route
+	return nil
+}
+func (handle *fakeNetlinkHandle) RouteDel(route *netlink.Route) error {
+	if _, ok := handle.routes[fakeNetlinkRouteKey(route)]; !ok {
+		return syscall.ESRCH
+	}
+	delete(handle.routes, fakeNetlinkRouteKey(route))
+	return nil
+}
+func (handle *fakeNetlinkHandle) RouteList(family int, table int) ([]netlink.Route, error) {
+	routes := make([]netlink.Route, 0)
+	for _, route := range handle.routes {
+		if route.Table == table && netlinkRouteFamily(route.Dst) == family {
+			routes = append(routes, route)
+		}
+	}
+	return routes, nil
+}
+func (handle *fakeNetlinkHandle) LinkList() ([]netlink.Link, error) {
+	return handle.links, nil
+}
+func (handle *fakeNetlinkHandle) AddrList(family int) ([]netlink.Addr, error) {
+	addrs := make([]netlink.Addr, 0)
+	for _, addr := range handle.addrs {
+		if netlinkRouteFamily(addr.IPNet) == family {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+func (handle *fakeNetlinkHandle) Subscribe(linkCh chan netlink.LinkUpdate, addrCh chan netlink.AddrUpdate, done chan struct{}) error {
+	return nil
+}
+func (handle *fakeNetlinkHandle) RuleAdd(rule *netlink.Rule) error {
+	for _, kRule := range handle.rules {
+		if kRule.Priority == rule.Priority && kRule.Family == rule.Family && kRule.IifName == rule.IifName && kRule.Table == rule.Table &&
+			fmt.Sprint(kRule.Src, kRule.Dst) == fmt.Sprint(rule.Src, rule.Dst) {
+			return syscall.EEXIST
+		}
+	}
+	handle.rules = append(handle.rules, *rule)
+	return nil
+}
+func (handle *fakeNetlinkHandle) RuleDel(rule *netlink.Rule) error {
+	for idx, kRule := range handle.rules {
+		if kRule.Priority == rule.Priority && kRule.Family == rule.Family && kRule.IifName == rule.IifName && kRule.Table == rule.Table &&
+			fmt.Sprint(kRule.Src, kRule.Dst) == fmt.Sprint(rule.Src, rule.Dst) {
+			handle.rules = append(handle.rules[:idx], handle.rules[idx+1:]...)
+			return nil
+		}
+	}
+	return syscall.ENOENT
+}
+func (handle *fakeNetlinkHandle) RuleList(family int) ([]netlink.Rule, error) {
+	rules := make([]netlink.Rule, 0)
+	for _, rule := range handle.rules {
+		if rule.Family == family {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+var fakeNetlink *fakeNetlinkHandle
+var netlinkFib *NetlinkFibProgrammer
+
+func printNetlinkRoutes() {
+	for prefix, route := range fakeNetlink.routes {
+		fmt.Println("kernel route:", prefix, " table:", route.Table, " protocol:", route.Protocol, " gw:", route.Gw)
+		for _, nextHop := range route.MultiPath {
+			fmt.Println("    next hop:", nextHop.Gw, " hops:", nextHop.Hops)
+		}
+	}
+}
+func TestInitNetlinkFibTestServer(t *testing.T) {
+	fmt.Println("****Init NetlinkFib Test Server****")
+	StartTestServer()
+	_, staleDst, _ := net.ParseCIDR("70.1.1.0/24")
+	_, otherDst, _ := net.ParseCIDR("70.1.2.0/24")
+	fakeNetlink = &fakeNetlinkHandle{routes: make(map[string]netlink.Route)}
+	//left behind by a previous ribd instance
+	fakeNetlink.RouteReplace(&netlink.Route{Dst: staleDst, Gw: net.ParseIP("11.1.10.2"), Protocol: DefaultNetlinkRouteProtocol, Table: DefaultNetlinkRouteTable})
+	//installed by some other routing daemon
+	fakeNetlink.RouteReplace(&netlink.Route{Dst: otherDst, Gw: net.ParseIP("11.1.10.2"), Protocol: syscall.RTPROT_STATIC, Table: DefaultNetlinkRouteTable})
+	//pbr rule and table of a previous ribd instance
+	_, defaultDst, _ := net.ParseCIDR("0.0.0.0/0")
+	fakeNetlink.RouteReplace(&netlink.Route{Dst: defaultDst, Gw: net.ParseIP("11.1.10.5"), Protocol: DefaultNetlinkRouteProtocol, Table: PbrNetlinkTableBase})
+	fakeNetlink.rules = []netlink.Rule{netlink.Rule{Priority: PbrRulePriorityBase + 10, Family: netlink.FAMILY_V4, IifName: "dummy10", Table: PbrNetlinkTableBase}}
+	fakeNetlink.links = []netlink.Link{&netlink.Dummy{LinkAttrs: netlink.LinkAttrs{Index: 10, Name: "dummy10", Flags: net.FlagUp, OperState: netlink.OperUnknown}}}
+	ip, ipNet, _ := net.ParseCIDR("11.1.10.1/24")
+	ipNet.IP = ip
+	fakeNetlink.addrs = []netlink.Addr{netlink.Addr{IPNet: ipNet, LinkIndex: 10}}
+	netlinkFib = newNetlinkFibProgrammer(fakeNetlink, DefaultNetlinkRouteTable)
+	err := netlinkFib.Init()
+	fmt.Println("Init returned err:", err, " stale routes:", len(netlinkFib.staleRoutes), " kernel rules:", len(fakeNetlink.rules))
+	fmt.Println("****************")
+}
+func TestNetlinkFibGetV4Intfs(t *testing.T) {
+	fmt.Println("****TestNetlinkFibGetV4Intfs****")
+	netlinkFib.GetIntfInfo()
+	intfs := netlinkFib.GetV4Intfs()
+	for _, intf := range intfs.IPv4IntfList {
+		fmt.Println("intf:", intf.IntfRef, " ifIndex:", intf.IfIndex, " ipAddr:", intf.IpAddr, " operState:", intf.OperState)
+	}
+	fmt.Println("****************")
+}
+func TestNetlinkFibRouteAdd(t *testing.T) {
+	fmt.Println("****TestNetlinkFibRouteAdd****")
+	netlinkFib.CreateIPv4Routes([]*asicdInt.IPv4Route{
+		&asicdInt.IPv4Route{"11.1.10.1", "255.255.255.0", []*asicdInt.IPv4NextHop{&asicdInt.IPv4NextHop{NextHopIp: "0.0.0.0", Weight: 1}}},
+		&asicdInt.IPv4Route{"70.1.3.0", "255.255.255.0", []*asicdInt.IPv4NextHop{&asicdInt.IPv4NextHop{NextHopIp: "11.1.10.2", Weight: 1}}},
+		&asicdInt.IPv4Route{"70.1.3.0", "255.255.255.0", []*asicdInt.IPv4NextHop{&asicdInt.IPv4NextHop{NextHopIp: "11.1.10.3", Weight: 3}}},
+	})
+	netlinkFib.CreateIPv6Routes([]*asicdInt.IPv6Route{
+		&asicdInt.IPv6Route{"2001:db8:1::", "ffff:ffff:ffff:ffff::", []*asicdInt.IPv6NextHop{&asicdInt.IPv6NextHop{NextHopIp: "2001:db8::2", Weight: 1}}},
+		&asicdInt.IPv6Route{"fe80::", "ffff:ffff:ffff:ffff::", []*asicdInt.IPv6NextHop{&asicdInt.IPv6NextHop{NextHopIp: "::", Weight: 1}}},
+	})
+	printNetlinkRoutes()
+	fmt.Println("****************")
+}
+func TestNetlinkFibRouteDel(t *testing.T) {
+	fmt.Println("****TestNetlinkFibRouteDel****")
+	netlinkFib.DeleteIPv4Routes([]*asicdInt.IPv4Route{
+		&asicdInt.IPv4Route{"70.1.3.0", "255.255.255.0", []*asicdInt.IPv4NextHop{&asicdInt.IPv4NextHop{NextHopIp: "11.1.10.3", Weight: 3}}},
+	})
+	fmt.Println("after deleting one of the next hops of 70.1.3.0/24")
+	printNetlinkRoutes()
+	netlinkFib.DeleteIPv6Routes([]*asicdInt.IPv6Route{
+		&asicdInt.IPv6Route{"2001:db8:1::", "ffff:ffff:ffff:ffff::", []*asicdInt.IPv6NextHop{&asicdInt.IPv6NextHop{NextHopIp: "2001:db8::2", Weight: 1}}},
+	})
+	fmt.Println("after deleting 2001:db8:1::/64")
+	printNetlinkRoutes()
+	fmt.Println("****************")
+}
+func TestNetlinkFibStaleRouteSweep(t *testing.T) {
+	fmt.Println("****TestNetlinkFibStaleRouteSweep****")
+	netlinkFib.sweepStaleRoutes()
+	fmt.Println("stale routes after sweep:", len(netlinkFib.staleRoutes))
+	printNetlinkRoutes()
+	fmt.Println("****************")
+}
+func TestNetlinkFibAddrUpdate(t *testing.T) {
+	fmt.Println("****TestNetlinkFibAddrUpdate****")
+	ip, ipNet, _ := net.ParseCIDR("12.1.10.1/24")
+	ipNet.IP = ip
+	family, changed := netlinkFib.addrUpdate(10, ipNet, int(netlink.SCOPE_UNIVERSE), true)
+	fmt.Println("add 12.1.10.1/24 family:", family, " changed:", changed)
+	family, changed = netlinkFib.addrUpdate(10, ipNet, int(netlink.SCOPE_UNIVERSE), true)
+	fmt.Println("add 12.1.10.1/24 again family:", family, " changed:", changed)
+	ip, ipNet, _ = net.ParseCIDR("127.0.0.1/8")
+	ipNet.IP = ip
+	family, changed = netlinkFib.addrUpdate(1, ipNet, int(netlink.SCOPE_HOST), true)
+	fmt.Println("add 127.0.0.1/8 family:", family, " changed:", changed)
+	created, stateChanged := netlinkFib.linkUpdate(&netlink.LinkAttrs{Index: 10, Name: "dummy10", OperState: netlink.OperDown})
+	fmt.Println("dummy10 down created:", created, " stateChanged:", stateChanged, " addrs:", netlinkFib.intfs[10].addrs)
+	fmt.Println("****************")
+}
+func printNetlinkRules() {
+	for _, rule := range fakeNetlink.rules {
+		fmt.Println("kernel rule:", rule.Priority, " family:", rule.Family, " iif:", rule.IifName, " from:", rule.Src, " to:", rule.Dst, " table:", rule.Table)
+	}
+}
+func TestNetlinkFibPbrRules(t *testing.T) {
+	fmt.Println("****TestNetlinkFibPbrRules****")
+	_, srcPrefix, _ := net.ParseCIDR("80.1.0.0/16")
+	rules := []*PbrFibRule{
+		&PbrFibRule{priority: PbrRulePriorityBase + 10, ifIndex: 10, intfName: "dummy10", ipType: ribdCommonDefs.IPv4, srcPrefix: srcPrefix,
+			protocol: pbrMatchAny, srcPortMin: pbrMatchAny, dstPortMin: pbrMatchAny, dscp: pbrMatchAny, action: PbrActionSetNextHop, nextHop: "11.1.10.5"},
+		&PbrFibRule{priority: PbrRulePriorityBase + 20, ifIndex: 10, intfName: "dummy10", ipType: ribdCommonDefs.IPv4,
+			protocol: pbrMatchAny, srcPortMin: pbrMatchAny, dstPortMin: pbrMatchAny, dscp: pbrMatchAny, action: PbrActionDrop},
+		&PbrFibRule{priority: PbrRulePriorityBase + 30, ifIndex: 10, intfName: "dummy10", ipType: ribdCommonDefs.IPv4,
+			protocol: pbrMatchAny, srcPortMin: pbrMatchAny, dstPortMin: pbrMatchAny, dscp: pbrMatchAny, action: PbrActionSetVrf, vrf: DefaultVrf},
+		&PbrFibRule{priority: PbrRulePriorityBase + 35, ifIndex: 10, intfName: "dummy10", ipType: ribdCommonDefs.IPv4,
+			protocol: 6, srcPortMin: pbrMatchAny, dstPortMin: 80, dstPortMax: 88, dscp: 46, action: PbrActionDrop},
+		&PbrFibRule{priority: PbrRulePriorityBase + 40, ifIndex: 10, intfName: "dummy10", ipType: ribdCommonDefs.IPv4,
+			protocol: pbrMatchAny, srcPortMin: pbrMatchAny, dstPortMin: pbrMatchAny, dscp: pbrMatchAny, action: PbrActionSetVrf, vrf: "blue"},
+	}
+	err := netlinkFib.CreatePbrRules(rules)
+	fmt.Println("CreatePbrRules returned err:", err)
+	printNetlinkRules()
+	printNetlinkRoutes()
+	for _, kRule := range fakeNetlink.rules {
+		if kRule.Priority != PbrRulePriorityBase+35 {
+			continue
+		}
+		if kRule.IPProto != 6 || kRule.Sport != nil || kRule.Dport == nil || kRule.Dport.Start != 80 || kRule.Dport.End != 88 || kRule.Tos != 46<<2 {
+			t.Fatal("protocol, port and dscp matches not programmed:", kRule.IPProto, kRule.Sport, kRule.Dport, kRule.Tos)
+		}
+	}
+	err = netlinkFib.DeletePbrRules(rules[:4])
+	fmt.Println("DeletePbrRules returned err:", err, " pbr tables:", len(netlinkFib.pbrTables))
+	printNetlinkRules()
+	err = netlinkFib.DeleteVrf("blue")
+	fmt.Println("DeleteVrf of blue returned err:", err, " pbr rules:", len(netlinkFib.pbrRules), " vrf tables:", len(netlinkFib.vrfTables))
+	printNetlinkRules()
+	fmt.Println("****************")
+}
+func TestNetlinkFibVrfRoutes(t *testing.T) {
+	fmt.Println("****TestNetlinkFibVrfRoutes****")
+	err := netlinkFib.BindVrfIntf("red", 11)
+	fmt.Println("BindVrfIntf of unknown ifIndex 11 returned err:", err)
+	err = netlinkFib.BindVrfIntf("red", 10)
+	fmt.Println("BindVrfIntf of dummy10 to red returned err:", err)
+	printNetlinkRules()
+	routes := []*FibVrfRoute{
+		&FibVrfRoute{ipType: ribdCommonDefs.IPv4, destNw: "11.1.10.0", networkMask: "255.255.255.0", nextHopIp: "0.0.0.0", ifIndex: 10, weight: 1},
+		&FibVrfRoute{ipType: ribdCommonDefs.IPv4, destNw: "90.1.1.0", networkMask: "255.255.255.0", nextHopIp: "11.1.10.2", ifIndex: 10, weight: 1},
+		&FibVrfRoute{ipType: ribdCommonDefs.IPv4, destNw: "90.1.1.0", networkMask: "255.255.255.0", nextHopIp: "11.1.10.3", ifIndex: 10, weight: 2},
+	}
+	err = netlinkFib.CreateVrfRoutes("red", routes)
+	fmt.Println("CreateVrfRoutes returned err:", err)
+	printNetlinkRoutes()
+	err = netlinkFib.DeleteVrfRoutes("red", routes[2:])
+	fmt.Println("DeleteVrfRoutes of one next hop of 90.1.1.0/24 returned err:", err)
+	printNetlinkRoutes()
+	err = netlinkFib.BindVrfIntf(DefaultVrf, 10)
+	fmt.Println("BindVrfIntf of dummy10 back to the default vrf returned err:", err)
+	printNetlinkRules()
+	err = netlinkFib.DeleteVrf("red")
+	fmt.Println("DeleteVrf returned err:", err, " vrf tables:", len(netlinkFib.vrfTables))
+	printNetlinkRoutes()
+	fmt.Println("****************")
+}
+func TestNetlinkFibNextHopGroups(t *testing.T) {
+	fmt.Println("****TestNetlinkFibNextHopGroups****")
+	netlinkFib.SetNextHopGroup(&FibNextHopGroup{id: 1, ipType: ribdCommonDefs.IPv4, members: map[string]int32{"11.1.10.2": 1, "11.1.10.3": 2}})
+	netlinkFib.SetNextHopGroupRoutes([]*FibGroupRoute{
+		&FibGroupRoute{ipType: ribdCommonDefs.IPv4, destNw: "60.1.1.0", networkMask: "255.255.255.0", groupId: 1},
+		&FibGroupRoute{ipType: ribdCommonDefs.IPv4, destNw: "60.1.2.0", networkMask: "255.255.255.0", groupId: 1},
+	})
+	fmt.Println("after pointing 60.1.1.0/24 and 60.1.2.0/24 to group 1")
+	printNetlinkRoutes()
+	netlinkFib.SetNextHopGroup(&FibNextHopGroup{id: 1, ipType: ribdCommonDefs.IPv4, members: map[string]int32{"11.1.10.2": 1}})
+	fmt.Println("after 11.1.10.3 went down in group 1")
+	printNetlinkRoutes()
+	netlinkFib.SetNextHopGroup(&FibNextHopGroup{id: 2, ipType: ribdCommonDefs.IPv4, members: map[string]int32{"11.1.10.3": 1}})
+	netlinkFib.SetNextHopGroupRoutes([]*FibGroupRoute{&FibGroupRoute{ipType: ribdCommonDefs.IPv4, destNw: "60.1.2.0", networkMask: "255.255.255.0", groupId: 2}})
+	netlinkFib.DeleteNextHopGroup(1)
+	fmt.Println("after moving 60.1.2.0/24 to group 2 and deleting group 1")
+	printNetlinkRoutes()
+	netlinkFib.SetNextHopGroupRoutes([]*FibGroupRoute{&FibGroupRoute{ipType: ribdCommonDefs.IPv4, destNw: "60.1.2.0", networkMask: "255.255.255.0"}})
+	netlinkFib.DeleteNextHopGroup(2)
+	fmt.Println("group routes:", len(netlinkFib.groupTable.routes), " groups:", len(netlinkFib.groupTable.groups))
+	fmt.Println("****************")
+}
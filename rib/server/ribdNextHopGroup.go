@@ -0,0 +1,529 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdNextHopGroup.go
+package server
+
+import (
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"ribdInt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+   Number of hash buckets spread across the members of a group when resilient hashing is enabled
+*/
+const NextHopGroupBucketCount = 64
+
+type NextHopGroupMember struct {
+	nextHopIp string
+	weight    int32
+	backup    bool //repair path, programmed only when none of the primary members are reachable
+}
+
+/*
+   Set of weighted next hops shared by all the prefixes resolving to them
+*/
+type NextHopGroup struct {
+	id        int
+	key       string
+	ipType    ribdCommonDefs.IPType
+	members   []NextHopGroupMember
+	buckets   []string //next hop ip owning each hash bucket, only used with resilient hashing
+	resilient bool
+	refCount  int
+	prefixes  map[string]RouteInfoRecord
+}
+
+/*
+   Fib updates collected while processing group changes, sent out by flushNextHopGroupUpdates.
+   The fib programmer expands the groups into the next hops of the prefixes pointing to them.
+*/
+type nextHopGroupFibUpdate struct {
+	groups        map[int]*FibNextHopGroup //groups created or whose programmed members changed
+	routes        map[string]*FibGroupRoute
+	deletedGroups map[int]bool
+}
+
+var NextHopGroupMap = make(map[string]*NextHopGroup)       //group key -> group
+var NextHopGroupPrefixMap = make(map[string]*NextHopGroup) //prefix -> group
+var NextHopGroupMemberDownMap = make(map[string]bool)      //next hop ips that are currently unreachable
+var NextHopGroupResilientHashing = false
+var nextHopGroupId = 0
+var nextHopGroupPendingUpdate = newNextHopGroupFibUpdate()
+
+/*
+   Routes in the connected table and ipv6 link local routes are programmed directly
+*/
+func isNextHopGroupRoute(routeInfoRecord RouteInfoRecord) bool {
+	if routeInfoRecord.protocol == ribdCommonDefs.CONNECTED {
+		return false
+	}
+	if routeInfoRecord.ipType == ribdCommonDefs.IPv6 && routeInfoRecord.destNetIp.IsLinkLocalUnicast() {
+		return false
+	}
+	return true
+}
+func nextHopGroupPrefixKey(routeInfoRecord RouteInfoRecord) string {
+	return routeInfoRecord.destNetIp.String() + "/" + routeInfoRecord.networkMask.String()
+}
+
+type nextHopGroupMemberList []NextHopGroupMember
+
+func (members nextHopGroupMemberList) Len() int           { return len(members) }
+func (members nextHopGroupMemberList) Swap(i, j int)      { members[i], members[j] = members[j], members[i] }
+func (members nextHopGroupMemberList) Less(i, j int) bool { return members[i].nextHopIp < members[j].nextHopIp }
+
+func nextHopGroupKey(members []NextHopGroupMember) string {
+	keys := make([]string, 0)
+	for _, member := range members {
+		key := member.nextHopIp + "*" + strconv.Itoa(int(member.weight))
+		if member.backup {
+			key += "*backup"
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+func newNextHopGroup(key string, ipType ribdCommonDefs.IPType, members []NextHopGroupMember, seed *NextHopGroup) *NextHopGroup {
+	nextHopGroupId++
+	group := &NextHopGroup{
+		id:        nextHopGroupId,
+		key:       key,
+		ipType:    ipType,
+		members:   members,
+		resilient: NextHopGroupResilientHashing,
+		prefixes:  make(map[string]RouteInfoRecord),
+	}
+	if group.resilient {
+		/*
+		   start from the bucket layout of the group the prefix is moving out of so that
+		   only the buckets of the changed members get rehashed
+		*/
+		if seed != nil && len(seed.buckets) == NextHopGroupBucketCount {
+			group.buckets = append([]string(nil), seed.buckets...)
+		}
+		group.rebalanceBuckets()
+	}
+	logger.Info("Created next hop group ", group.id, " with members ", key)
+	NextHopGroupMap[key] = group
+	return group
+}
+func (group *NextHopGroup) hasMember(nextHopIp string) bool {
+	for _, member := range group.members {
+		if member.nextHopIp == nextHopIp {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+   Members the prefixes of this group forward on, the backup members take
+   over only when every primary member is unreachable
+*/
+func (group *NextHopGroup) activeMembers() []NextHopGroupMember {
+	primaryMembers := make([]NextHopGroupMember, 0)
+	backupMembers := make([]NextHopGroupMember, 0)
+	for _, member := range group.members {
+		if NextHopGroupMemberDownMap[member.nextHopIp] {
+			continue
+		}
+		if member.backup {
+			backupMembers = append(backupMembers, member)
+		} else {
+			primaryMembers = append(primaryMembers, member)
+		}
+	}
+	if len(primaryMembers) > 0 {
+		return primaryMembers
+	}
+	return backupMembers
+}
+
+/*
+   Spread the hash buckets across the active members in proportion to their weights.
+   Only the buckets owned by unreachable or over subscribed members are moved.
+*/
+func (group *NextHopGroup) rebalanceBuckets() {
+	if len(group.buckets) != NextHopGroupBucketCount {
+		group.buckets = make([]string, NextHopGroupBucketCount)
+	}
+	upMembers := group.activeMembers()
+	totalWeight := 0
+	for _, member := range upMembers {
+		totalWeight += int(member.weight)
+	}
+	if totalWeight == 0 {
+		for i := 0; i < len(group.buckets); i++ {
+			group.buckets[i] = ""
+		}
+		return
+	}
+	target := make(map[string]int)
+	assigned := 0
+	for _, member := range upMembers {
+		target[member.nextHopIp] = NextHopGroupBucketCount * int(member.weight) / totalWeight
+		assigned += target[member.nextHopIp]
+	}
+	for i := 0; assigned < NextHopGroupBucketCount; i = (i + 1) % len(upMembers) {
+		target[upMembers[i].nextHopIp]++
+		assigned++
+	}
+	owned := make(map[string]int)
+	freeBuckets := make([]int, 0)
+	for i, nextHopIp := range group.buckets {
+		if nextHopIp == "" || owned[nextHopIp] >= target[nextHopIp] {
+			freeBuckets = append(freeBuckets, i)
+			continue
+		}
+		owned[nextHopIp]++
+	}
+	for _, member := range upMembers {
+		for owned[member.nextHopIp] < target[member.nextHopIp] && len(freeBuckets) > 0 {
+			group.buckets[freeBuckets[0]] = member.nextHopIp
+			freeBuckets = freeBuckets[1:]
+			owned[member.nextHopIp]++
+		}
+	}
+}
+
+/*
+   Next hops and weights currently programmed in the fib for this group.
+   With resilient hashing the weight of a member is the number of buckets it owns.
+*/
+func (group *NextHopGroup) programmedMembers() map[string]int32 {
+	programmed := make(map[string]int32)
+	if group.resilient {
+		for _, nextHopIp := range group.buckets {
+			if nextHopIp != "" {
+				programmed[nextHopIp]++
+			}
+		}
+		return programmed
+	}
+	for _, member := range group.activeMembers() {
+		programmed[member.nextHopIp] = member.weight
+	}
+	return programmed
+}
+func newNextHopGroupFibUpdate() *nextHopGroupFibUpdate {
+	return &nextHopGroupFibUpdate{
+		groups:        make(map[int]*FibNextHopGroup),
+		routes:        make(map[string]*FibGroupRoute),
+		deletedGroups: make(map[int]bool),
+	}
+}
+func (update *nextHopGroupFibUpdate) setGroup(group *NextHopGroup) {
+	update.groups[group.id] = &FibNextHopGroup{id: group.id, ipType: group.ipType, members: group.programmedMembers()}
+}
+func (update *nextHopGroupFibUpdate) deleteGroup(group *NextHopGroup) {
+	delete(update.groups, group.id)
+	update.deletedGroups[group.id] = true
+}
+
+/*
+   Points the prefix to the group, group id 0 removes the prefix from the fib
+*/
+func (update *nextHopGroupFibUpdate) setRoute(routeInfoRecord RouteInfoRecord, prefix string, groupId int) {
+	update.routes[prefix] = &FibGroupRoute{
+		ipType:      routeInfoRecord.ipType,
+		destNw:      routeInfoRecord.destNetIp.String(),
+		networkMask: routeInfoRecord.networkMask.String(),
+		groupId:     groupId,
+	}
+}
+func (update *nextHopGroupFibUpdate) count() int {
+	return len(update.groups) + len(update.routes) + len(update.deletedGroups)
+}
+
+/*
+   Send the queued group updates to the fib, the groups are programmed before the
+   prefixes pointing to them and deleted once no prefix points to them anymore
+*/
+func flushNextHopGroupUpdates() {
+	update := nextHopGroupPendingUpdate
+	nextHopGroupPendingUpdate = newNextHopGroupFibUpdate()
+	if !fibProgrammer.IsReady() || update.count() == 0 {
+		return
+	}
+	logger.Info("flushNextHopGroupUpdates: groups:", len(update.groups), " routes:", len(update.routes), " deleted groups:", len(update.deletedGroups))
+	groupIds := make([]int, 0)
+	for id, _ := range update.groups {
+		groupIds = append(groupIds, id)
+	}
+	sort.Ints(groupIds)
+	for _, id := range groupIds {
+		fibProgrammer.SetNextHopGroup(update.groups[id])
+	}
+	if len(update.routes) > 0 {
+		prefixes := make([]string, 0)
+		for prefix, _ := range update.routes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		routes := make([]*FibGroupRoute, 0)
+		for _, prefix := range prefixes {
+			routes = append(routes, update.routes[prefix])
+		}
+		fibProgrammer.SetNextHopGroupRoutes(routes)
+	}
+	groupIds = make([]int, 0)
+	for id, _ := range update.deletedGroups {
+		groupIds = append(groupIds, id)
+	}
+	sort.Ints(groupIds)
+	for _, id := range groupIds {
+		fibProgrammer.DeleteNextHopGroup(id)
+	}
+}
+func nextHopGroupRelease(group *NextHopGroup, prefix string) {
+	delete(group.prefixes, prefix)
+	group.refCount--
+	if group.refCount <= 0 {
+		logger.Info("Deleting next hop group ", group.id, " since no prefix uses it")
+		delete(NextHopGroupMap, group.key)
+		nextHopGroupPendingUpdate.deleteGroup(group)
+	}
+}
+
+/*
+   Move the prefix to the group with the given members, creating the group when
+   no other prefix uses the same set of next hops
+*/
+func nextHopGroupPrefixMove(routeInfoRecord RouteInfoRecord, prefix string, oldGroup *NextHopGroup, members []NextHopGroupMember) {
+	groupId := 0
+	if len(members) > 0 {
+		sort.Sort(nextHopGroupMemberList(members))
+		key := nextHopGroupKey(members)
+		group, ok := NextHopGroupMap[key]
+		if !ok {
+			group = newNextHopGroup(key, routeInfoRecord.ipType, members, oldGroup)
+			nextHopGroupPendingUpdate.setGroup(group)
+		}
+		if group != oldGroup {
+			group.refCount++
+		}
+		group.prefixes[prefix] = routeInfoRecord
+		NextHopGroupPrefixMap[prefix] = group
+		groupId = group.id
+	} else {
+		delete(NextHopGroupPrefixMap, prefix)
+	}
+	if oldGroup != nil && NextHopGroupPrefixMap[prefix] != oldGroup {
+		nextHopGroupRelease(oldGroup, prefix)
+	}
+	if oldGroup == nil || oldGroup.id != groupId {
+		nextHopGroupPendingUpdate.setRoute(routeInfoRecord, prefix, groupId)
+	}
+}
+func nextHopGroupProgrammedChanged(oldProgrammed map[string]int32, newProgrammed map[string]int32) bool {
+	if len(oldProgrammed) != len(newProgrammed) {
+		return true
+	}
+	for nextHopIp, weight := range newProgrammed {
+		if oldWeight, ok := oldProgrammed[nextHopIp]; !ok || oldWeight != weight {
+			return true
+		}
+	}
+	return false
+}
+func nextHopGroupRouteAdd(routeInfoRecord RouteInfoRecord) {
+	nextHopIp := routeInfoRecord.resolvedNextHopIpIntf.NextHopIp
+	if NextHopGroupMemberDownMap[nextHopIp] {
+		//a route is being installed through this next hop, so it is reachable again
+		nextHopGroupMemberStateUpdate(nextHopIp, true)
+	}
+	prefix := nextHopGroupPrefixKey(routeInfoRecord)
+	oldGroup := NextHopGroupPrefixMap[prefix]
+	members := make([]NextHopGroupMember, 0)
+	if oldGroup != nil {
+		for _, member := range oldGroup.members {
+			if member.nextHopIp != nextHopIp {
+				members = append(members, member)
+			}
+		}
+	}
+	members = append(members, NextHopGroupMember{nextHopIp, int32(routeInfoRecord.weight + 1), routeInfoRecord.backup})
+	nextHopGroupPrefixMove(routeInfoRecord, prefix, oldGroup, members)
+}
+func nextHopGroupRouteDel(routeInfoRecord RouteInfoRecord) {
+	nextHopIp := routeInfoRecord.resolvedNextHopIpIntf.NextHopIp
+	prefix := nextHopGroupPrefixKey(routeInfoRecord)
+	oldGroup := NextHopGroupPrefixMap[prefix]
+	if oldGroup == nil || !oldGroup.hasMember(nextHopIp) {
+		logger.Info("nextHopGroupRouteDel: next hop ", nextHopIp, " not part of the group for prefix ", prefix)
+		return
+	}
+	members := make([]NextHopGroupMember, 0)
+	for _, member := range oldGroup.members {
+		if member.nextHopIp != nextHopIp {
+			members = append(members, member)
+		}
+	}
+	nextHopGroupPrefixMove(routeInfoRecord, prefix, oldGroup, members)
+}
+
+/*
+   Mark the next hop up or down and update the members of every group using it, the
+   prefixes of these groups keep their group and only get the changed next hops
+*/
+func nextHopGroupMemberStateUpdate(nextHopIp string, isUp bool) {
+	if isUp != NextHopGroupMemberDownMap[nextHopIp] {
+		return
+	}
+	groups := make([]*NextHopGroup, 0)
+	oldProgrammedList := make([]map[string]int32, 0)
+	for _, group := range NextHopGroupMap {
+		if group.hasMember(nextHopIp) {
+			groups = append(groups, group)
+			oldProgrammedList = append(oldProgrammedList, group.programmedMembers())
+		}
+	}
+	if isUp {
+		delete(NextHopGroupMemberDownMap, nextHopIp)
+	} else {
+		NextHopGroupMemberDownMap[nextHopIp] = true
+	}
+	logger.Info("Next hop ", nextHopIp, " isUp:", isUp, " updating ", len(groups), " next hop groups")
+	for i, group := range groups {
+		if group.resilient {
+			group.rebalanceBuckets()
+		}
+		if nextHopGroupProgrammedChanged(oldProgrammedList[i], group.programmedMembers()) {
+			nextHopGroupPendingUpdate.setGroup(group)
+		}
+	}
+}
+
+/*
+   Reachability of a network changed, update the group members whose next hop lies in it
+*/
+func nextHopGroupNetworkStateUpdate(routeReachabilityStatusInfo RouteReachabilityStatusInfo) {
+	_, ipNet, err := net.ParseCIDR(routeReachabilityStatusInfo.destNet)
+	if err != nil {
+		logger.Err("nextHopGroupNetworkStateUpdate: invalid network ", routeReachabilityStatusInfo.destNet)
+		return
+	}
+	isUp := routeReachabilityStatusInfo.status == "Up"
+	nextHopIpMap := make(map[string]bool)
+	for _, group := range NextHopGroupMap {
+		for _, member := range group.members {
+			if NextHopGroupMemberDownMap[member.nextHopIp] != isUp {
+				continue
+			}
+			ip := net.ParseIP(member.nextHopIp)
+			if ip != nil && ipNet.Contains(ip) {
+				nextHopIpMap[member.nextHopIp] = true
+			}
+		}
+	}
+	nextHopIps := make([]string, 0)
+	for nextHopIp, _ := range nextHopIpMap {
+		nextHopIps = append(nextHopIps, nextHopIp)
+	}
+	sort.Strings(nextHopIps)
+	for _, nextHopIp := range nextHopIps {
+		nextHopGroupMemberStateUpdate(nextHopIp, isUp)
+	}
+}
+func nextHopGroupResilientHashingUpdate(enable bool) {
+	logger.Info("Setting resilient hashing for next hop groups to ", enable)
+	NextHopGroupResilientHashing = enable
+	for _, group := range NextHopGroupMap {
+		if group.resilient == enable {
+			continue
+		}
+		oldProgrammed := group.programmedMembers()
+		group.resilient = enable
+		if enable {
+			group.rebalanceBuckets()
+		} else {
+			group.buckets = nil
+		}
+		if nextHopGroupProgrammedChanged(oldProgrammed, group.programmedMembers()) {
+			nextHopGroupPendingUpdate.setGroup(group)
+		}
+	}
+}
+
+type nextHopGroupList []*NextHopGroup
+
+func (groups nextHopGroupList) Len() int           { return len(groups) }
+func (groups nextHopGroupList) Swap(i, j int)      { groups[i], groups[j] = groups[j], groups[i] }
+func (groups nextHopGroupList) Less(i, j int) bool { return groups[i].id < groups[j].id }
+
+func (m RIBDServer) GetBulkNextHopGroupState(fromIndex ribdInt.Int, rcount ribdInt.Int) (groups *ribdInt.NextHopGroupStateGetInfo, err error) {
+	var returnNextHopGroupGetInfo ribdInt.NextHopGroupStateGetInfo
+	groups = &returnNextHopGroupGetInfo
+	groupList := make([]*NextHopGroup, 0)
+	for _, group := range NextHopGroupMap {
+		groupList = append(groupList, group)
+	}
+	sort.Sort(nextHopGroupList(groupList))
+	groupStates := make([]*ribdInt.NextHopGroupState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(groupList)) && ribdInt.Int(len(groupStates)) < rcount; i++ {
+		group := groupList[i]
+		ipType := "IPv4"
+		if group.ipType == ribdCommonDefs.IPv6 {
+			ipType = "IPv6"
+		}
+		buckets := make(map[string]int32)
+		for _, nextHopIp := range group.buckets {
+			buckets[nextHopIp]++
+		}
+		groupState := &ribdInt.NextHopGroupState{
+			GroupId:   int32(group.id),
+			IpType:    ipType,
+			Members:   make([]*ribdInt.NextHopGroupMemberState, 0),
+			RefCount:  int32(group.refCount),
+			Resilient: group.resilient,
+			Prefixes:  make([]string, 0),
+		}
+		for _, member := range group.members {
+			groupState.Members = append(groupState.Members, &ribdInt.NextHopGroupMemberState{
+				NextHopIp:  member.nextHopIp,
+				Weight:     member.weight,
+				IsUp:       !NextHopGroupMemberDownMap[member.nextHopIp],
+				NumBuckets: buckets[member.nextHopIp],
+				Backup:     member.backup,
+			})
+		}
+		for prefix, _ := range group.prefixes {
+			groupState.Prefixes = append(groupState.Prefixes, prefix)
+		}
+		sort.Strings(groupState.Prefixes)
+		groupStates = append(groupStates, groupState)
+	}
+	groups.NextHopGroupStateList = groupStates
+	groups.StartIdx = fromIndex
+	groups.EndIdx = i
+	groups.More = i < ribdInt.Int(len(groupList))
+	groups.Count = ribdInt.Int(len(groupStates))
+	return groups, err
+}
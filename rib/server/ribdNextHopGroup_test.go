@@ -0,0 +1,196 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"reflect"
+	"ribd"
+	"ribdInt"
+	"testing"
+	"time"
+)
+
+var nextHopGroupRouteList []RouteInfoRecord
+
+func InitNextHopGroupRouteList() {
+	nextHopGroupRouteList = make([]RouteInfoRecord, 0)
+	for _, destNet := range []string{"60.1.1.0", "60.1.2.0", "60.1.3.0"} {
+		for idx, nextHopIp := range []string{"11.1.10.2", "11.1.10.3"} {
+			nextHopGroupRouteList = append(nextHopGroupRouteList, RouteInfoRecord{
+				ipType:                ribdCommonDefs.IPv4,
+				destNetIp:             net.ParseIP(destNet).To4(),
+				networkMask:           net.ParseIP("255.255.255.0").To4(),
+				nextHopIp:             net.ParseIP(nextHopIp),
+				resolvedNextHopIpIntf: ribdInt.NextHopInfo{NextHopIp: nextHopIp},
+				weight:                ribd.Int(idx),
+				protocol:              ribdCommonDefs.STATIC,
+				vrf:                   DefaultVrf,
+			})
+		}
+	}
+}
+func printNextHopGroupState() {
+	groups, err := server.GetBulkNextHopGroupState(0, 10)
+	if err != nil {
+		fmt.Println("GetBulkNextHopGroupState returned err:", err)
+		return
+	}
+	for _, group := range groups.NextHopGroupStateList {
+		fmt.Println("group:", group.GroupId, " refCount:", group.RefCount, " resilient:", group.Resilient, " prefixes:", group.Prefixes)
+		for _, member := range group.Members {
+			fmt.Println("    member:", member.NextHopIp, " weight:", member.Weight, " isUp:", member.IsUp, " backup:", member.Backup, " buckets:", member.NumBuckets)
+		}
+	}
+}
+func TestInitNextHopGroupTestServer(t *testing.T) {
+	fmt.Println("****Init NextHopGroup Test Server****")
+	StartTestServer()
+	InitNextHopGroupRouteList()
+	fmt.Println("****************")
+}
+func nextHopGroupTestGroup(t *testing.T, prefix string) *NextHopGroup {
+	group := NextHopGroupPrefixMap[prefix]
+	if group == nil {
+		t.Fatal("No next hop group for prefix", prefix)
+	}
+	return group
+}
+func nextHopGroupTestCheckProgrammed(t *testing.T, group *NextHopGroup, expected map[string]int32) {
+	programmed := group.programmedMembers()
+	if !reflect.DeepEqual(programmed, expected) {
+		t.Fatal("Group", group.id, "programmed with", programmed, "expected", expected)
+	}
+}
+func TestNextHopGroupRouteAdd(t *testing.T) {
+	fmt.Println("****TestNextHopGroupRouteAdd****")
+	for _, routeInfoRecord := range nextHopGroupRouteList {
+		server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: routeInfoRecord, Op: "add"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	printNextHopGroupState()
+	group := nextHopGroupTestGroup(t, "60.1.1.0/255.255.255.0")
+	for _, prefix := range []string{"60.1.2.0/255.255.255.0", "60.1.3.0/255.255.255.0"} {
+		if nextHopGroupTestGroup(t, prefix) != group {
+			t.Fatal("Prefix", prefix, "not sharing next hop group", group.id)
+		}
+	}
+	if group.refCount != 3 || len(group.members) != 2 {
+		t.Fatal("Group", group.id, "refCount", group.refCount, "members", group.members, "expected 3 prefixes and 2 members")
+	}
+	nextHopGroupTestCheckProgrammed(t, group, map[string]int32{"11.1.10.2": 1, "11.1.10.3": 2})
+	fmt.Println("****************")
+}
+
+var nextHopGroupTestBuckets []string
+
+func TestNextHopGroupResilientHashing(t *testing.T) {
+	fmt.Println("****TestNextHopGroupResilientHashing****")
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: true, Op: "resilientHashing"}
+	time.Sleep(100 * time.Millisecond)
+	printNextHopGroupState()
+	group := nextHopGroupTestGroup(t, "60.1.1.0/255.255.255.0")
+	if len(group.buckets) != NextHopGroupBucketCount {
+		t.Fatal("Group", group.id, "has", len(group.buckets), "buckets, expected", NextHopGroupBucketCount)
+	}
+	//weights 1 and 2 split the 64 buckets 21/42, the remaining bucket goes to the first member
+	nextHopGroupTestCheckProgrammed(t, group, map[string]int32{"11.1.10.2": 22, "11.1.10.3": 42})
+	nextHopGroupTestBuckets = append([]string(nil), group.buckets...)
+	fmt.Println("****************")
+}
+func TestNextHopGroupMemberDown(t *testing.T) {
+	fmt.Println("****TestNextHopGroupMemberDown****")
+	group := nextHopGroupTestGroup(t, "60.1.1.0/255.255.255.0")
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: RouteReachabilityStatusInfo{destNet: "11.1.10.3/32", ipType: ribdCommonDefs.IPv4, status: "Down"}, Op: "nhReachability"}
+	time.Sleep(100 * time.Millisecond)
+	printNextHopGroupState()
+	if nextHopGroupTestGroup(t, "60.1.1.0/255.255.255.0") != group {
+		t.Fatal("Prefix moved out of next hop group", group.id, "on member down")
+	}
+	nextHopGroupTestCheckProgrammed(t, group, map[string]int32{"11.1.10.2": 64})
+	for i, nextHopIp := range nextHopGroupTestBuckets {
+		if nextHopIp == "11.1.10.2" && group.buckets[i] != nextHopIp {
+			t.Fatal("Bucket", i, "of the reachable member 11.1.10.2 moved to", group.buckets[i])
+		}
+	}
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: RouteReachabilityStatusInfo{destNet: "11.1.10.3/32", ipType: ribdCommonDefs.IPv4, status: "Up"}, Op: "nhReachability"}
+	time.Sleep(100 * time.Millisecond)
+	printNextHopGroupState()
+	for i, nextHopIp := range nextHopGroupTestBuckets {
+		if group.buckets[i] != nextHopIp {
+			t.Fatal("Bucket", i, "owned by", group.buckets[i], "after member up, expected", nextHopIp)
+		}
+	}
+	fmt.Println("****************")
+}
+func TestNextHopGroupBackupMember(t *testing.T) {
+	fmt.Println("****TestNextHopGroupBackupMember****")
+	backupRoute := RouteInfoRecord{
+		ipType:                ribdCommonDefs.IPv4,
+		destNetIp:             net.ParseIP("60.1.4.0").To4(),
+		networkMask:           net.ParseIP("255.255.255.0").To4(),
+		nextHopIp:             net.ParseIP("11.1.10.3"),
+		resolvedNextHopIpIntf: ribdInt.NextHopInfo{NextHopIp: "11.1.10.3"},
+		protocol:              ribdCommonDefs.OSPF,
+		vrf:                   DefaultVrf,
+		backup:                true,
+	}
+	primaryRoute := backupRoute
+	primaryRoute.nextHopIp = net.ParseIP("11.1.10.2")
+	primaryRoute.resolvedNextHopIpIntf = ribdInt.NextHopInfo{NextHopIp: "11.1.10.2"}
+	primaryRoute.backup = false
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: primaryRoute, Op: "add"}
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: backupRoute, Op: "add"}
+	time.Sleep(100 * time.Millisecond)
+	group := nextHopGroupTestGroup(t, "60.1.4.0/255.255.255.0")
+	nextHopGroupTestCheckProgrammed(t, group, map[string]int32{"11.1.10.2": NextHopGroupBucketCount})
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: RouteReachabilityStatusInfo{destNet: "11.1.10.2/32", ipType: ribdCommonDefs.IPv4, status: "Down"}, Op: "nhReachability"}
+	time.Sleep(100 * time.Millisecond)
+	nextHopGroupTestCheckProgrammed(t, group, map[string]int32{"11.1.10.3": NextHopGroupBucketCount})
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: RouteReachabilityStatusInfo{destNet: "11.1.10.2/32", ipType: ribdCommonDefs.IPv4, status: "Up"}, Op: "nhReachability"}
+	time.Sleep(100 * time.Millisecond)
+	printNextHopGroupState()
+	nextHopGroupTestCheckProgrammed(t, group, map[string]int32{"11.1.10.2": NextHopGroupBucketCount})
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: backupRoute, Op: "del"}
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: primaryRoute, Op: "del"}
+	time.Sleep(100 * time.Millisecond)
+	fmt.Println("****************")
+}
+func TestNextHopGroupRouteDel(t *testing.T) {
+	fmt.Println("****TestNextHopGroupRouteDel****")
+	for _, routeInfoRecord := range nextHopGroupRouteList {
+		server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: routeInfoRecord, Op: "del"}
+		time.Sleep(10 * time.Millisecond)
+		printNextHopGroupState()
+	}
+	for _, routeInfoRecord := range nextHopGroupRouteList {
+		if group, ok := NextHopGroupPrefixMap[nextHopGroupPrefixKey(routeInfoRecord)]; ok {
+			t.Fatal("Prefix", nextHopGroupPrefixKey(routeInfoRecord), "still in next hop group", group.id, "after its routes were deleted")
+		}
+	}
+	server.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: false, Op: "resilientHashing"}
+	fmt.Println("****************")
+}
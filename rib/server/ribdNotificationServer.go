@@ -26,6 +26,7 @@ package server
 
 import (
 	"github.com/op/go-nanomsg"
+	"l3/rib/ribdCommonDefs"
 	"time"
 )
 
@@ -40,8 +41,7 @@ func (ribdServiceHandler *RIBDServer) NotificationServer() {
 	for {
 		notificationMsg := <-ribdServiceHandler.NotificationChannel
 		logger.Info("Event received with eventInfo: ", notificationMsg.eventInfo)
-		eventInfo := RouteEventInfo{timeStamp: time.Now().String(), eventInfo: notificationMsg.eventInfo}
-		localRouteEventsDB = append(localRouteEventsDB, eventInfo)
+		routeEventAdd(ribdCommonDefs.ROUTE_EVENT_NOTIFICATION, "", "", "", "", notificationMsg.eventInfo, time.Now().String())
 		notificationMsg.pub_socket.Send(notificationMsg.msg, nanomsg.DontWait)
 	}
 }
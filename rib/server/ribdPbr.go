@@ -0,0 +1,622 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdPbr.go
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"ribdInt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	PbrActionSetNextHop = "SetNextHop"
+	PbrActionSetVrf     = "SetVrf"
+	PbrActionDrop       = "Drop"
+	PbrMaxSequence      = 10000
+	PbrRulePriorityBase = 1000  //fib rule priority of sequence 0
+	PbrTrackProtocol    = "PBR" //protocol the pbr next hops are tracked as
+	pbrMatchAny         = -1
+)
+
+var pbrProtocolMap = map[string]int{
+	"icmp":   1,
+	"tcp":    6,
+	"udp":    17,
+	"icmpv6": 58,
+}
+
+/*
+   Rule of a pbr policy, the unset match fields match any packet. The next hops of the
+   SetNextHop action are tried in order and the first reachable one is used.
+*/
+type PbrRuleInfo struct {
+	sequence      int32
+	ipType        ribdCommonDefs.IPType
+	anyFamily     bool //no prefix or next hop pins the rule to an address family
+	srcPrefix     *net.IPNet
+	dstPrefix     *net.IPNet
+	protocol      int
+	srcPortMin    int
+	srcPortMax    int
+	dstPortMin    int
+	dstPortMax    int
+	dscp          int
+	action        string
+	nextHops      []string
+	vrf           string
+	active        bool
+	activeNextHop string
+}
+type PbrPolicyInfo struct {
+	name         string
+	rules        []*PbrRuleInfo         //sorted by sequence
+	fibRules     map[string]*PbrFibRule //key -> rule programmed into the fib
+	programError string
+}
+type PbrBindingInfo struct {
+	intfRef string
+	policy  string
+}
+
+var PbrPolicyMap = make(map[string]*PbrPolicyInfo)   //name -> policy
+var PbrBindingMap = make(map[string]*PbrBindingInfo) //intfRef -> binding
+var PbrNextHopRefMap = make(map[string]int)          //next hop ip -> number of rules tracking it
+var pbrEvalQueued = false
+
+type pbrRuleList []*PbrRuleInfo
+
+func (rules pbrRuleList) Len() int           { return len(rules) }
+func (rules pbrRuleList) Swap(i, j int)      { rules[i], rules[j] = rules[j], rules[i] }
+func (rules pbrRuleList) Less(i, j int) bool { return rules[i].sequence < rules[j].sequence }
+
+/*
+   Parses a port match of the form "N" or "N-M", an empty string matches any port
+*/
+func parsePbrPortRange(portRange string) (min int, max int, err error) {
+	if portRange == "" {
+		return pbrMatchAny, pbrMatchAny, nil
+	}
+	ports := strings.SplitN(portRange, "-", 2)
+	min, err = strconv.Atoi(strings.TrimSpace(ports[0]))
+	if err != nil {
+		return min, max, errors.New(fmt.Sprintln("Invalid port ", portRange))
+	}
+	max = min
+	if len(ports) == 2 {
+		max, err = strconv.Atoi(strings.TrimSpace(ports[1]))
+		if err != nil {
+			return min, max, errors.New(fmt.Sprintln("Invalid port ", portRange))
+		}
+	}
+	if min < 0 || max > 65535 || min > max {
+		return min, max, errors.New(fmt.Sprintln("Invalid port range ", portRange))
+	}
+	return min, max, nil
+}
+func parsePbrProtocol(protocol string) (int, error) {
+	if protocol == "" {
+		return pbrMatchAny, nil
+	}
+	if val, ok := pbrProtocolMap[strings.ToLower(protocol)]; ok {
+		return val, nil
+	}
+	val, err := strconv.Atoi(protocol)
+	if err != nil || val < 0 || val > 255 {
+		return pbrMatchAny, errors.New(fmt.Sprintln("Invalid protocol ", protocol))
+	}
+	return val, nil
+}
+func parsePbrPrefix(prefix string) (*net.IPNet, error) {
+	if prefix == "" {
+		return nil, nil
+	}
+	if !strings.Contains(prefix, "/") {
+		ip := net.ParseIP(prefix)
+		if ip == nil {
+			return nil, errors.New(fmt.Sprintln("Invalid prefix ", prefix))
+		}
+		if ip.To4() != nil {
+			prefix = prefix + "/32"
+		} else {
+			prefix = prefix + "/128"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintln("Invalid prefix ", prefix))
+	}
+	return ipNet, nil
+}
+func pbrIPType(ip net.IP) ribdCommonDefs.IPType {
+	if ip.To4() != nil {
+		return ribdCommonDefs.IPv4
+	}
+	return ribdCommonDefs.IPv6
+}
+
+/*
+   Builds the rule from its config, all the addresses of a rule have to be of the same family
+*/
+func newPbrRuleInfo(cfg *ribdInt.PbrRule) (rule *PbrRuleInfo, err error) {
+	if cfg.Sequence < 0 || cfg.Sequence > PbrMaxSequence {
+		return nil, errors.New(fmt.Sprintln("Invalid sequence ", cfg.Sequence, " expected 0-", PbrMaxSequence))
+	}
+	rule = &PbrRuleInfo{sequence: cfg.Sequence, anyFamily: true, action: cfg.Action, dscp: pbrMatchAny}
+	setFamily := func(ip net.IP, what string) error {
+		ipType := pbrIPType(ip)
+		if !rule.anyFamily && rule.ipType != ipType {
+			return errors.New(fmt.Sprintln("Address family of ", what, " does not match the rest of rule ", cfg.Sequence))
+		}
+		rule.ipType = ipType
+		rule.anyFamily = false
+		return nil
+	}
+	if rule.srcPrefix, err = parsePbrPrefix(cfg.SrcPrefix); err != nil {
+		return nil, err
+	}
+	if rule.srcPrefix != nil {
+		if err = setFamily(rule.srcPrefix.IP, "source prefix"); err != nil {
+			return nil, err
+		}
+	}
+	if rule.dstPrefix, err = parsePbrPrefix(cfg.DstPrefix); err != nil {
+		return nil, err
+	}
+	if rule.dstPrefix != nil {
+		if err = setFamily(rule.dstPrefix.IP, "destination prefix"); err != nil {
+			return nil, err
+		}
+	}
+	if rule.protocol, err = parsePbrProtocol(cfg.Protocol); err != nil {
+		return nil, err
+	}
+	if rule.srcPortMin, rule.srcPortMax, err = parsePbrPortRange(cfg.SrcPort); err != nil {
+		return nil, err
+	}
+	if rule.dstPortMin, rule.dstPortMax, err = parsePbrPortRange(cfg.DstPort); err != nil {
+		return nil, err
+	}
+	if (rule.srcPortMin != pbrMatchAny || rule.dstPortMin != pbrMatchAny) && rule.protocol != pbrProtocolMap["tcp"] && rule.protocol != pbrProtocolMap["udp"] {
+		return nil, errors.New(fmt.Sprintln("Port match of rule ", cfg.Sequence, " needs protocol tcp or udp"))
+	}
+	if cfg.Dscp != "" {
+		rule.dscp, err = strconv.Atoi(cfg.Dscp)
+		if err != nil || rule.dscp < 0 || rule.dscp > 63 {
+			return nil, errors.New(fmt.Sprintln("Invalid dscp ", cfg.Dscp))
+		}
+	}
+	switch cfg.Action {
+	case PbrActionSetNextHop:
+		if len(cfg.NextHopList) == 0 {
+			return nil, errors.New(fmt.Sprintln("No next hop specified for rule ", cfg.Sequence))
+		}
+		for _, nextHop := range cfg.NextHopList {
+			ip := net.ParseIP(nextHop)
+			if ip == nil || ip.IsUnspecified() {
+				return nil, errors.New(fmt.Sprintln("Invalid next hop ", nextHop))
+			}
+			if err = setFamily(ip, "next hop "+nextHop); err != nil {
+				return nil, err
+			}
+			rule.nextHops = append(rule.nextHops, ip.String())
+		}
+		//vrf the next hops are resolved in
+		rule.vrf = getVrfName(cfg.Vrf)
+	case PbrActionSetVrf:
+		if cfg.Vrf == "" {
+			return nil, errors.New(fmt.Sprintln("No vrf specified for rule ", cfg.Sequence))
+		}
+		rule.vrf = getVrfName(cfg.Vrf)
+	case PbrActionDrop:
+	default:
+		return nil, errors.New(fmt.Sprintln("Invalid action ", cfg.Action, " for rule ", cfg.Sequence))
+	}
+	return rule, nil
+}
+func newPbrPolicyInfo(cfg *ribdInt.PbrPolicy) (policy *PbrPolicyInfo, err error) {
+	policy = &PbrPolicyInfo{name: cfg.Name, fibRules: make(map[string]*PbrFibRule)}
+	sequences := make(map[int32]bool)
+	for _, ruleCfg := range cfg.RuleList {
+		if sequences[ruleCfg.Sequence] {
+			return nil, errors.New(fmt.Sprintln("Duplicate sequence ", ruleCfg.Sequence, " in policy ", cfg.Name))
+		}
+		sequences[ruleCfg.Sequence] = true
+		rule, err := newPbrRuleInfo(ruleCfg)
+		if err != nil {
+			return nil, err
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	sort.Sort(pbrRuleList(policy.rules))
+	return policy, nil
+}
+
+/*
+   Pbr policies and their bindings to the L3 interfaces
+*/
+func (m RIBDServer) PbrPolicyConfigValidationCheck(cfg *ribdInt.PbrPolicy, op string) (err error) {
+	if cfg.Name == "" {
+		return errors.New("Pbr policy name not specified")
+	}
+	_, exists := PbrPolicyMap[cfg.Name]
+	if op == "del" {
+		if !exists {
+			return errors.New(fmt.Sprintln("pbr policy ", cfg.Name, " not configured"))
+		}
+		for _, binding := range PbrBindingMap {
+			if binding.policy == cfg.Name {
+				return errors.New(fmt.Sprintln("pbr policy ", cfg.Name, " bound to interface ", binding.intfRef))
+			}
+		}
+		return nil
+	}
+	if exists {
+		return errors.New(fmt.Sprintln("Duplicate pbr policy ", cfg.Name))
+	}
+	_, err = newPbrPolicyInfo(cfg)
+	return err
+}
+func (m RIBDServer) PbrPolicyBindingConfigValidationCheck(cfg *ribdInt.PbrPolicyBinding, op string) (err error) {
+	if cfg.IntfRef == "" {
+		return errors.New("Interface not specified for pbr policy binding")
+	}
+	binding, exists := PbrBindingMap[cfg.IntfRef]
+	if op == "del" {
+		if !exists {
+			return errors.New(fmt.Sprintln("No pbr policy bound to interface ", cfg.IntfRef))
+		}
+		if cfg.Policy != "" && cfg.Policy != binding.policy {
+			return errors.New(fmt.Sprintln("pbr policy ", cfg.Policy, " not bound to interface ", cfg.IntfRef))
+		}
+		return nil
+	}
+	if exists {
+		return errors.New(fmt.Sprintln("pbr policy ", binding.policy, " already bound to interface ", cfg.IntfRef))
+	}
+	if _, ok := PbrPolicyMap[cfg.Policy]; !ok {
+		return errors.New(fmt.Sprintln("pbr policy ", cfg.Policy, " not configured"))
+	}
+	if !fibProgrammer.SupportsPbr() {
+		return errors.New(fmt.Sprintln("Policy based routing is not supported by the ", fibProgrammer.Name(), " fib"))
+	}
+	//the interface may not be known yet, the binding takes effect once it comes up
+	return nil
+}
+func (m RIBDServer) ProcessPbrPolicyCreateConfig(cfg *ribdInt.PbrPolicy) (val bool, err error) {
+	logger.Info("ProcessPbrPolicyCreateConfig for ", cfg.Name, " rules:", len(cfg.RuleList))
+	policy, err := newPbrPolicyInfo(cfg)
+	if err != nil {
+		logger.Err("ProcessPbrPolicyCreateConfig for ", cfg.Name, " failed with err:", err)
+		return false, err
+	}
+	PbrPolicyMap[cfg.Name] = policy
+	for _, rule := range policy.rules {
+		pbrNextHopTrack(rule, "add")
+	}
+	pbrPolicyEvaluate(policy)
+	return true, nil
+}
+func (m RIBDServer) ProcessPbrPolicyDeleteConfig(cfg *ribdInt.PbrPolicy) (val bool, err error) {
+	logger.Info("ProcessPbrPolicyDeleteConfig for ", cfg.Name)
+	policy, ok := PbrPolicyMap[cfg.Name]
+	if !ok {
+		return false, errors.New(fmt.Sprintln("pbr policy ", cfg.Name, " not configured"))
+	}
+	pbrPolicyProgram(policy, make(map[string]*PbrFibRule))
+	for _, rule := range policy.rules {
+		pbrNextHopTrack(rule, "del")
+	}
+	delete(PbrPolicyMap, cfg.Name)
+	return true, nil
+}
+func (m RIBDServer) ProcessPbrPolicyBindingCreateConfig(cfg *ribdInt.PbrPolicyBinding) (val bool, err error) {
+	logger.Info("ProcessPbrPolicyBindingCreateConfig for ", cfg.IntfRef, " policy ", cfg.Policy)
+	policy, ok := PbrPolicyMap[cfg.Policy]
+	if !ok {
+		return false, errors.New(fmt.Sprintln("pbr policy ", cfg.Policy, " not configured"))
+	}
+	PbrBindingMap[cfg.IntfRef] = &PbrBindingInfo{intfRef: cfg.IntfRef, policy: cfg.Policy}
+	pbrPolicyEvaluate(policy)
+	return true, nil
+}
+func (m RIBDServer) ProcessPbrPolicyBindingDeleteConfig(cfg *ribdInt.PbrPolicyBinding) (val bool, err error) {
+	logger.Info("ProcessPbrPolicyBindingDeleteConfig for ", cfg.IntfRef)
+	binding, ok := PbrBindingMap[cfg.IntfRef]
+	if !ok {
+		return false, errors.New(fmt.Sprintln("No pbr policy bound to interface ", cfg.IntfRef))
+	}
+	delete(PbrBindingMap, cfg.IntfRef)
+	if policy, ok := PbrPolicyMap[binding.policy]; ok {
+		pbrPolicyEvaluate(policy)
+	}
+	return true, nil
+}
+
+/*
+   The next hops of the SetNextHop rules are tracked like the ones of the client protocols,
+   a reachability change of any of them re-evaluates the policies
+*/
+func pbrNextHopTrack(rule *PbrRuleInfo, op string) {
+	for _, nextHop := range rule.nextHops {
+		if op == "add" {
+			PbrNextHopRefMap[nextHop]++
+			if PbrNextHopRefMap[nextHop] > 1 {
+				continue
+			}
+		} else {
+			PbrNextHopRefMap[nextHop]--
+			if PbrNextHopRefMap[nextHop] > 0 {
+				continue
+			}
+			delete(PbrNextHopRefMap, nextHop)
+		}
+		RouteServiceHandler.TrackReachabilityCh <- TrackReachabilityInfo{nextHop, PbrTrackProtocol, op}
+	}
+}
+
+/*
+   Called with every reachability change of the RIB, queues a policy evaluation when the
+   changed network covers one of the pbr next hops
+*/
+func pbrReachabilityChange(destNet string) {
+	if len(PbrNextHopRefMap) == 0 {
+		return
+	}
+	_, ipNet, err := net.ParseCIDR(destNet)
+	if err != nil {
+		return
+	}
+	for nextHop, _ := range PbrNextHopRefMap {
+		if ipNet.Contains(net.ParseIP(nextHop)) {
+			queuePbrEval()
+			return
+		}
+	}
+}
+func queuePbrEval() {
+	if pbrEvalQueued {
+		return
+	}
+	pbrEvalQueued = true
+	RouteServiceHandler.RouteConfCh <- RIBdServerConfig{Op: "evalPbrPolicies"}
+}
+
+/*
+   Interface events may resolve or withdraw bindings, the evaluation runs in the route server
+*/
+func (ribdServiceHandler *RIBDServer) PbrIntfChange() {
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{Op: "evalPbrPolicies"}
+}
+func (m RIBDServer) ProcessPbrPolicyEval() {
+	pbrEvalQueued = false
+	if len(PbrPolicyMap) == 0 {
+		return
+	}
+	names := make([]string, 0)
+	for name, _ := range PbrPolicyMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pbrPolicyEvaluate(PbrPolicyMap[name])
+	}
+}
+
+/*
+   Picks the action the rule is programmed with, a SetNextHop rule without a reachable next hop
+   and a SetVrf rule to a missing vrf are left out of the fib
+*/
+func (rule *PbrRuleInfo) evaluate() {
+	rule.active = false
+	rule.activeNextHop = ""
+	switch rule.action {
+	case PbrActionSetNextHop:
+		for _, nextHop := range rule.nextHops {
+			nhIntf, err := RouteServiceHandler.GetVrfRouteReachabilityInfo(rule.vrf, nextHop, -1)
+			if err == nil && nhIntf != nil && nhIntf.IsReachable {
+				rule.active = true
+				rule.activeNextHop = nextHop
+				return
+			}
+		}
+	case PbrActionSetVrf:
+		rule.active = getVrfInfo(rule.vrf) != nil
+	case PbrActionDrop:
+		rule.active = true
+	}
+}
+
+/*
+   Resolves the interface of a binding, only operationally up interfaces get rules
+*/
+func (binding *PbrBindingInfo) resolve() (ifIndex int32, intfName string, ok bool) {
+	ifIndexStr, err := RouteServiceHandler.ConvertIntfStrToIfIndexStr(binding.intfRef)
+	if err != nil {
+		return ifIndex, intfName, false
+	}
+	val, err := strconv.Atoi(ifIndexStr)
+	if err != nil {
+		return ifIndex, intfName, false
+	}
+	ifIndex = int32(val)
+	if intfEntry, found := IntfIdNameMap[ifIndex]; found {
+		intfName = intfEntry.name
+	}
+	return ifIndex, intfName, getIntfOperState(ifIndex)
+}
+
+/*
+   Compiles the active rules of the policy for all the interfaces it is bound to, the fib
+   rule priority follows the sequence so lower sequences are matched first
+*/
+func (policy *PbrPolicyInfo) compile() map[string]*PbrFibRule {
+	fibRules := make(map[string]*PbrFibRule)
+	intfRefs := make([]string, 0)
+	for intfRef, binding := range PbrBindingMap {
+		if binding.policy == policy.name {
+			intfRefs = append(intfRefs, intfRef)
+		}
+	}
+	sort.Strings(intfRefs)
+	for _, intfRef := range intfRefs {
+		ifIndex, intfName, ok := PbrBindingMap[intfRef].resolve()
+		if !ok {
+			logger.Debug("pbr policy ", policy.name, " binding to ", intfRef, " not resolved")
+			continue
+		}
+		for _, rule := range policy.rules {
+			if !rule.active {
+				continue
+			}
+			ipTypes := []ribdCommonDefs.IPType{rule.ipType}
+			if rule.anyFamily {
+				ipTypes = []ribdCommonDefs.IPType{ribdCommonDefs.IPv4, ribdCommonDefs.IPv6}
+			}
+			for _, ipType := range ipTypes {
+				fibRule := &PbrFibRule{
+					priority:   PbrRulePriorityBase + int(rule.sequence),
+					ifIndex:    ifIndex,
+					intfName:   intfName,
+					ipType:     ipType,
+					srcPrefix:  rule.srcPrefix,
+					dstPrefix:  rule.dstPrefix,
+					protocol:   rule.protocol,
+					srcPortMin: rule.srcPortMin,
+					srcPortMax: rule.srcPortMax,
+					dstPortMin: rule.dstPortMin,
+					dstPortMax: rule.dstPortMax,
+					dscp:       rule.dscp,
+					action:     rule.action,
+					nextHop:    rule.activeNextHop,
+					vrf:        rule.vrf,
+				}
+				key := fibRule.Key()
+				if _, exists := fibRules[key]; exists {
+					//another reference to the same interface
+					continue
+				}
+				fibRules[key] = fibRule
+			}
+		}
+	}
+	return fibRules
+}
+func pbrPolicyEvaluate(policy *PbrPolicyInfo) {
+	for _, rule := range policy.rules {
+		rule.evaluate()
+	}
+	pbrPolicyProgram(policy, policy.compile())
+}
+
+/*
+   Hands the difference between the programmed and the compiled rules to the fib programmer,
+   rules that failed to program are retried with the next evaluation
+*/
+func pbrPolicyProgram(policy *PbrPolicyInfo, fibRules map[string]*PbrFibRule) {
+	delRules := make([]*PbrFibRule, 0)
+	for key, fibRule := range policy.fibRules {
+		if _, ok := fibRules[key]; !ok {
+			delRules = append(delRules, fibRule)
+		}
+	}
+	addRules := make([]*PbrFibRule, 0)
+	for key, fibRule := range fibRules {
+		if _, ok := policy.fibRules[key]; !ok {
+			addRules = append(addRules, fibRule)
+		}
+	}
+	if len(delRules) == 0 && len(addRules) == 0 {
+		return
+	}
+	policy.programError = ""
+	if len(delRules) > 0 {
+		logger.Info("pbr policy ", policy.name, " deleting ", len(delRules), " fib rules")
+		if err := fibProgrammer.DeletePbrRules(delRules); err != nil {
+			logger.Err("pbr policy ", policy.name, " fib rule delete failed with err:", err)
+			policy.programError = err.Error()
+		}
+		for _, fibRule := range delRules {
+			delete(policy.fibRules, fibRule.Key())
+		}
+	}
+	if len(addRules) > 0 {
+		logger.Info("pbr policy ", policy.name, " adding ", len(addRules), " fib rules")
+		if err := fibProgrammer.CreatePbrRules(addRules); err != nil {
+			logger.Err("pbr policy ", policy.name, " fib rule create failed with err:", err)
+			policy.programError = err.Error()
+			return
+		}
+		for _, fibRule := range addRules {
+			policy.fibRules[fibRule.Key()] = fibRule
+		}
+	}
+}
+func (m RIBDServer) GetBulkPbrPolicyState(fromIndex ribdInt.Int, rcount ribdInt.Int) (policies *ribdInt.PbrPolicyStateGetInfo, err error) {
+	var returnPbrPolicyGetInfo ribdInt.PbrPolicyStateGetInfo
+	policies = &returnPbrPolicyGetInfo
+	names := make([]string, 0)
+	for name, _ := range PbrPolicyMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	policyStates := make([]*ribdInt.PbrPolicyState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(names)) && ribdInt.Int(len(policyStates)) < rcount; i++ {
+		policy := PbrPolicyMap[names[i]]
+		policyState := &ribdInt.PbrPolicyState{
+			Name:         policy.name,
+			RuleList:     make([]*ribdInt.PbrRuleState, 0),
+			IntfList:     make([]string, 0),
+			NumFibRules:  int32(len(policy.fibRules)),
+			ProgramError: policy.programError,
+		}
+		for _, rule := range policy.rules {
+			policyState.RuleList = append(policyState.RuleList, &ribdInt.PbrRuleState{
+				Sequence:      rule.sequence,
+				Action:        rule.action,
+				Active:        rule.active,
+				ActiveNextHop: rule.activeNextHop,
+			})
+		}
+		for intfRef, binding := range PbrBindingMap {
+			if binding.policy == policy.name {
+				policyState.IntfList = append(policyState.IntfList, intfRef)
+			}
+		}
+		sort.Strings(policyState.IntfList)
+		policyStates = append(policyStates, policyState)
+	}
+	policies.PbrPolicyStateList = policyStates
+	policies.StartIdx = fromIndex
+	policies.EndIdx = i
+	policies.More = i < ribdInt.Int(len(names))
+	policies.Count = ribdInt.Int(len(policyStates))
+	return policies, err
+}
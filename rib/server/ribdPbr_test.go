@@ -0,0 +1,170 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"ribdInt"
+	"testing"
+	"time"
+)
+
+/*
+   Fib programmer keeping the pbr rules in memory
+*/
+type fakePbrFibProgrammer struct {
+	AsicdFibProgrammer
+	rules map[string]*PbrFibRule
+}
+
+func (fib *fakePbrFibProgrammer) SupportsPbr() bool {
+	return true
+}
+func (fib *fakePbrFibProgrammer) CreatePbrRules(rules []*PbrFibRule) error {
+	for _, rule := range rules {
+		fib.rules[rule.Key()] = rule
+	}
+	return nil
+}
+func (fib *fakePbrFibProgrammer) DeletePbrRules(rules []*PbrFibRule) error {
+	for _, rule := range rules {
+		delete(fib.rules, rule.Key())
+	}
+	return nil
+}
+
+var fakePbrFib *fakePbrFibProgrammer
+var pbrPolicyList []*ribdInt.PbrPolicy
+
+func InitPbrPolicyList() {
+	pbrPolicyList = make([]*ribdInt.PbrPolicy, 0)
+	pbrPolicyList = append(pbrPolicyList, &ribdInt.PbrPolicy{
+		Name: "web",
+		RuleList: []*ribdInt.PbrRule{
+			&ribdInt.PbrRule{Sequence: 10, SrcPrefix: "80.1.0.0/16", Protocol: "tcp", DstPort: "80", Action: PbrActionSetNextHop,
+				NextHopList: []string{"90.1.1.1", "11.1.10.2"}},
+			&ribdInt.PbrRule{Sequence: 20, DstPrefix: "81.1.1.0/24", Dscp: "46", Action: PbrActionSetVrf, Vrf: DefaultVrf},
+			&ribdInt.PbrRule{Sequence: 30, Action: PbrActionDrop},
+		},
+	})
+}
+func printPbrPolicyState() {
+	policies, _ := server.GetBulkPbrPolicyState(0, 100)
+	for _, policy := range policies.PbrPolicyStateList {
+		fmt.Println("pbr policy:", policy.Name, " interfaces:", policy.IntfList, " fib rules:", policy.NumFibRules, " error:", policy.ProgramError)
+		for _, rule := range policy.RuleList {
+			fmt.Println("    rule:", rule.Sequence, " action:", rule.Action, " active:", rule.Active, " next hop:", rule.ActiveNextHop)
+		}
+	}
+	fmt.Println("fib rules:")
+	for key, _ := range fakePbrFib.rules {
+		fmt.Println("    ", key)
+	}
+}
+func TestInitPbrTestServer(t *testing.T) {
+	fmt.Println("****Init Pbr Test Server****")
+	StartTestServer()
+	TestProcessLogicalIntfCreateEvent(t)
+	TestIPv4IntfCreateEvent(t)
+	InitPbrPolicyList()
+	fakePbrFib = &fakePbrFibProgrammer{rules: make(map[string]*PbrFibRule)}
+	fmt.Println("****************")
+}
+func TestPbrPolicyConfigValidationCheck(t *testing.T) {
+	fmt.Println("****TestPbrPolicyConfigValidationCheck****")
+	err := server.PbrPolicyConfigValidationCheck(pbrPolicyList[0], "add")
+	fmt.Println("err:", err, " for add of pbr policy:", pbrPolicyList[0].Name)
+	for _, rule := range []*ribdInt.PbrRule{
+		&ribdInt.PbrRule{Sequence: 10, DstPort: "80", Action: PbrActionDrop},
+		&ribdInt.PbrRule{Sequence: 10, Protocol: "udp", DstPort: "90-80", Action: PbrActionDrop},
+		&ribdInt.PbrRule{Sequence: 10, Dscp: "64", Action: PbrActionDrop},
+		&ribdInt.PbrRule{Sequence: 10, SrcPrefix: "80.1.0.300/16", Action: PbrActionDrop},
+		&ribdInt.PbrRule{Sequence: 10, SrcPrefix: "80.1.0.0/16", DstPrefix: "2002::/64", Action: PbrActionDrop},
+		&ribdInt.PbrRule{Sequence: 10, SrcPrefix: "80.1.0.0/16", Action: PbrActionSetNextHop, NextHopList: []string{"2002::2"}},
+		&ribdInt.PbrRule{Sequence: 10, Action: PbrActionSetVrf},
+		&ribdInt.PbrRule{Sequence: 10, Action: "Redirect"},
+	} {
+		err = server.PbrPolicyConfigValidationCheck(&ribdInt.PbrPolicy{Name: "bad", RuleList: []*ribdInt.PbrRule{rule}}, "add")
+		fmt.Println("err:", err, " for rule:", rule)
+	}
+	err = server.PbrPolicyConfigValidationCheck(&ribdInt.PbrPolicy{Name: "bad", RuleList: []*ribdInt.PbrRule{
+		&ribdInt.PbrRule{Sequence: 10, Action: PbrActionDrop}, &ribdInt.PbrRule{Sequence: 10, Action: PbrActionDrop}}}, "add")
+	fmt.Println("err:", err, " for duplicate sequence")
+	err = server.PbrPolicyBindingConfigValidationCheck(&ribdInt.PbrPolicyBinding{IntfRef: "lo1", Policy: "web"}, "add")
+	fmt.Println("err:", err, " for binding of a policy not configured")
+	fmt.Println("************************************")
+}
+func TestProcessPbrPolicyCreateConfig(t *testing.T) {
+	fmt.Println("****TestProcessPbrPolicyCreateConfig****")
+	savedFibProgrammer := fibProgrammer
+	fibProgrammer = fakePbrFib
+	for _, policy := range pbrPolicyList {
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: policy, Op: "addPbrPolicy"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	binding := &ribdInt.PbrPolicyBinding{IntfRef: "lo1", Policy: "web"}
+	err := server.PbrPolicyBindingConfigValidationCheck(binding, "add")
+	fmt.Println("err:", err, " for binding of policy web to lo1")
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: binding, Op: "addPbrPolicyBinding"}
+	//bound before the interface exists
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: &ribdInt.PbrPolicyBinding{IntfRef: "lo9", Policy: "web"}, Op: "addPbrPolicyBinding"}
+	time.Sleep(100 * time.Millisecond)
+	printPbrPolicyState()
+	fibProgrammer = savedFibProgrammer
+	fmt.Println("************************************")
+}
+func TestPbrIntfStateChange(t *testing.T) {
+	fmt.Println("****TestPbrIntfStateChange****")
+	savedFibProgrammer := fibProgrammer
+	fibProgrammer = fakePbrFib
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: TrackIntfStateInfo{1, false}, Op: "trackIntfState"}
+	time.Sleep(100 * time.Millisecond)
+	fmt.Println("after lo1 down")
+	printPbrPolicyState()
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: TrackIntfStateInfo{1, true}, Op: "trackIntfState"}
+	time.Sleep(100 * time.Millisecond)
+	fmt.Println("after lo1 up")
+	printPbrPolicyState()
+	fibProgrammer = savedFibProgrammer
+	fmt.Println("************************************")
+}
+func TestProcessPbrPolicyDeleteConfig(t *testing.T) {
+	fmt.Println("****TestProcessPbrPolicyDeleteConfig****")
+	savedFibProgrammer := fibProgrammer
+	fibProgrammer = fakePbrFib
+	err := server.PbrPolicyConfigValidationCheck(pbrPolicyList[0], "del")
+	fmt.Println("err:", err, " for delete of bound pbr policy")
+	for _, intfRef := range []string{"lo1", "lo9"} {
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: &ribdInt.PbrPolicyBinding{IntfRef: intfRef}, Op: "delPbrPolicyBinding"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	printPbrPolicyState()
+	for _, policy := range pbrPolicyList {
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: policy, Op: "delPbrPolicy"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	printPbrPolicyState()
+	fibProgrammer = savedFibProgrammer
+	fmt.Println("************************************")
+}
@@ -61,6 +61,10 @@ type RouteParams struct {
 	weight         ribd.Int
 	bulk           bool
 	bulkEnd        bool
+	vrf            string
+	leakSrcVrf     string
+	routeTag       uint32
+	backup         bool
 }
 
 type TraverseAndApplyPolicyData struct {
@@ -161,85 +165,97 @@ func policyEngineActionRejectRoute(params interface{}) {
 			}
 		}
 	} else if routeInfo.routeType == ribdCommonDefs.CONNECTED {
-		logger.Info(("this is a connected route, fetch it from ASICD"))
-		if !asicdclnt.IsConnected {
-			logger.Info(("Not connected to ASICD"))
+		logger.Info("this is a connected route, fetch it from the fib programmer ", fibProgrammer.Name())
+		if !fibProgrammer.IsReady() {
+			logger.Info("fib programmer ", fibProgrammer.Name(), " not ready")
 			return
 		}
-		var currMarker asicdServices.Int
-		var count asicdServices.Int
-		count = 100
-		for {
-			logger.Info(fmt.Sprintf("Getting %d objects from currMarker %d\n", count, currMarker))
-			IPIntfBulk, err := asicdclnt.ClientHdl.GetBulkIPv4IntfState(currMarker, count)
+		v4Intfs := fibProgrammer.GetV4Intfs()
+		for i := 0; i < v4Intfs.Count; i++ {
+			ip, ipNet, err := net.ParseCIDR(v4Intfs.IPv4IntfList[i].IpAddr)
 			if err != nil {
-				logger.Info(("GetBulkIPv4IntfState with err ", err))
-				return
+				continue
 			}
-			if IPIntfBulk.Count == 0 {
-				logger.Info(("0 objects returned from GetBulkIPv4IntfState"))
-				return
+			cfg, ok := policyConnectedRouteCfg(&tempRoute, ribdCommonDefs.IPv4, ip.Mask(ipNet.Mask), net.IP(ipNet.Mask), v4Intfs.IPv4IntfList[i].IfIndex, conditionsList, params, policyStmt)
+			if !ok {
+				continue
 			}
-			logger.Info(fmt.Sprintf("len(IPIntfBulk.IPv4IntfStateList)  = %d, num objects returned = %d\n", len(IPIntfBulk.IPv4IntfStateList), IPIntfBulk.Count))
-			for i := 0; i < int(IPIntfBulk.Count); i++ {
-				var ipMask net.IP
-				ip, ipNet, err := net.ParseCIDR(IPIntfBulk.IPv4IntfStateList[i].IpAddr)
-				if err != nil {
-					return
-				}
-				ipMask = make(net.IP, 4)
-				copy(ipMask, ipNet.Mask)
-				ipAddrStr := ip.String()
-				ipMaskStr := net.IP(ipMask).String()
-				tempRoute.Ipaddr = ipAddrStr
-				tempRoute.Mask = ipMaskStr
-				tempRoute.NextHopIp = "0.0.0.0"
-				tempRoute.NextHopIfType = ribdInt.Int(asicdCommonDefs.GetIntfTypeFromIfIndex(IPIntfBulk.IPv4IntfStateList[i].IfIndex))
-				nextHopIfTypeStr := ""
-				switch tempRoute.NextHopIfType {
-				case commonDefs.IfTypePort:
-					nextHopIfTypeStr = "PHY"
-					break
-				case commonDefs.IfTypeVlan:
-					nextHopIfTypeStr = "VLAN"
-					break
-				case commonDefs.IfTypeNull:
-					nextHopIfTypeStr = "NULL"
-					break
-				}
-				tempRoute.IfIndex = ribdInt.Int(asicdCommonDefs.GetIntfIdFromIfIndex(IPIntfBulk.IPv4IntfStateList[i].IfIndex))
-				tempRoute.Prototype = ribdCommonDefs.CONNECTED
-				tempRoute.Metric = 0
-				entity, err := buildPolicyEntityFromRoute(tempRoute, params)
-				if err != nil {
-					logger.Err(("Error builiding policy entity params"))
-					return
-				}
-				if !PolicyEngineDB.ConditionCheckValid(entity, conditionsList, policyStmt) {
-					logger.Info(("This route does not qualify for reversing reject route"))
-					continue
-				}
-				logger.Info(fmt.Sprintf("Calling createv4Route with ipaddr %s mask %s\n", ipAddrStr, ipMaskStr))
-				cfg := ribd.IPv4Route{
-					DestinationNw:     tempRoute.Ipaddr,
-					Protocol:          "CONNECTED",
-					OutgoingInterface: strconv.Itoa(int(tempRoute.IfIndex)),
-					OutgoingIntfType:  nextHopIfTypeStr,
-					Cost:              0,
-					NetworkMask:       tempRoute.Mask,
-					NextHopIp:         "0.0.0.0"}
-				_, err = RouteServiceHandler.ProcessRouteCreateConfig(&cfg) //ipAddrStr, ipMaskStr, 0, "0.0.0.0", ribd.Int(asicdCommonDefs.GetIntfTypeFromIfIndex(IPIntfBulk.IPv4IntfList[i].IfIndex)), ribd.Int(asicdCommonDefs.GetIntfIdFromIfIndex(IPIntfBulk.IPv4IntfList[i].IfIndex)), "CONNECTED") // FIBAndRIB, ribd.Int(len(destNetSlice)))
-				if err != nil {
-					logger.Info(fmt.Sprintf("Failed to create connected route for ip Addr %s/%s intfType %d intfId %d\n", ipAddrStr, ipMaskStr, ribd.Int(asicdCommonDefs.GetIntfTypeFromIfIndex(IPIntfBulk.IPv4IntfStateList[i].IfIndex)), ribd.Int(asicdCommonDefs.GetIntfIdFromIfIndex(IPIntfBulk.IPv4IntfStateList[i].IfIndex))))
-				}
+			_, err = RouteServiceHandler.ProcessV4RouteCreateConfig(&ribd.IPv4Route{
+				DestinationNw:     cfg.DestinationNw,
+				Protocol:          cfg.Protocol,
+				OutgoingInterface: cfg.OutgoingInterface,
+				OutgoingIntfType:  cfg.OutgoingIntfType,
+				Cost:              cfg.Cost,
+				NetworkMask:       cfg.NetworkMask,
+				NextHopIp:         cfg.NextHopIp}, FIBAndRIB, ribd.Int(len(destNetSlice)))
+			if err != nil {
+				logger.Info("Failed to create connected route for ", cfg.DestinationNw, "/", cfg.NetworkMask, " err ", err)
 			}
-			if IPIntfBulk.More == false {
-				logger.Info(("more returned as false, so no more get bulks"))
-				return
+		}
+		v6Intfs := fibProgrammer.GetV6Intfs()
+		for i := 0; i < v6Intfs.Count; i++ {
+			ip, ipNet, err := net.ParseCIDR(v6Intfs.IPv6IntfList[i].IpAddr)
+			if err != nil || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			cfg, ok := policyConnectedRouteCfg(&tempRoute, ribdCommonDefs.IPv6, ip.Mask(ipNet.Mask), net.IP(ipNet.Mask), v6Intfs.IPv6IntfList[i].IfIndex, conditionsList, params, policyStmt)
+			if !ok {
+				continue
+			}
+			_, err = RouteServiceHandler.ProcessV6RouteCreateConfig(&ribd.IPv6Route{
+				DestinationNw:     cfg.DestinationNw,
+				Protocol:          cfg.Protocol,
+				OutgoingInterface: cfg.OutgoingInterface,
+				OutgoingIntfType:  cfg.OutgoingIntfType,
+				Cost:              cfg.Cost,
+				NetworkMask:       cfg.NetworkMask,
+				NextHopIp:         cfg.NextHopIp}, FIBAndRIB, ribd.Int(len(destNetSlice)))
+			if err != nil {
+				logger.Info("Failed to create connected route for ", cfg.DestinationNw, "/", cfg.NetworkMask, " err ", err)
 			}
-			currMarker = asicdServices.Int(IPIntfBulk.EndIdx)
 		}
 	}
+}
+func policyConnectedRouteCfg(tempRoute *ribdInt.Routes, ipType ribdCommonDefs.IPType, ipAddr net.IP, ipMask net.IP, ifIndex int32, conditionsList []string, params interface{}, policyStmt policy.PolicyStmt) (cfg ribd.IPv4Route, ok bool) {
+	nextHopIp := "0.0.0.0"
+	if ipType == ribdCommonDefs.IPv6 {
+		nextHopIp = "::"
+	}
+	tempRoute.Ipaddr = ipAddr.String()
+	tempRoute.Mask = ipMask.String()
+	tempRoute.NextHopIp = nextHopIp
+	tempRoute.IPAddrType = ribdInt.Int(ipType)
+	tempRoute.NextHopIfType = ribdInt.Int(asicdCommonDefs.GetIntfTypeFromIfIndex(ifIndex))
+	nextHopIfTypeStr := ""
+	switch tempRoute.NextHopIfType {
+	case commonDefs.IfTypePort:
+		nextHopIfTypeStr = "PHY"
+	case commonDefs.IfTypeVlan:
+		nextHopIfTypeStr = "VLAN"
+	case commonDefs.IfTypeNull:
+		nextHopIfTypeStr = "NULL"
+	}
+	tempRoute.IfIndex = ribdInt.Int(asicdCommonDefs.GetIntfIdFromIfIndex(ifIndex))
+	tempRoute.Prototype = ribdCommonDefs.CONNECTED
+	tempRoute.Metric = 0
+	entity, err := buildPolicyEntityFromRoute(*tempRoute, params)
+	if err != nil {
+		logger.Err("Error builiding policy entity params")
+		return cfg, false
+	}
+	if !PolicyEngineDB.ConditionCheckValid(entity, conditionsList, policyStmt) {
+		logger.Info("This route does not qualify for reversing reject route")
+		return cfg, false
+	}
+	cfg = ribd.IPv4Route{
+		DestinationNw:     tempRoute.Ipaddr,
+		Protocol:          "CONNECTED",
+		OutgoingInterface: strconv.Itoa(int(tempRoute.IfIndex)),
+		OutgoingIntfType:  nextHopIfTypeStr,
+		Cost:              0,
+		NetworkMask:       tempRoute.Mask,
+		NextHopIp:         nextHopIp}
+	return cfg, true
 }*/
 func policyEngineUndoRouteDispositionAction(action interface{}, conditionList []interface{}, params interface{}, policyStmt policy.PolicyStmt) {
 	logger.Info("policyEngineUndoRouteDispositionAction")
@@ -293,8 +309,17 @@ func policyEngineActionUndoRedistribute(actionItem interface{}, conditionsList [
 		logger.Info("evt = NOTIFY_ROUTE_CREATED")
 		evt = ribdCommonDefs.NOTIFY_ROUTE_CREATED
 	}
-	route = ribdInt.Routes{Ipaddr: RouteInfo.destNetIp, Mask: RouteInfo.networkMask, NextHopIp: RouteInfo.nextHopIp, IPAddrType: ribdInt.Int(RouteInfo.ipType), IfIndex: ribdInt.Int(RouteInfo.nextHopIfIndex), Metric: ribdInt.Int(RouteInfo.metric), Prototype: ribdInt.Int(RouteInfo.routeType)}
+	if !policyStmtRouteTagApply(policyStmt.Name, evt, &RouteInfo) {
+		logger.Info("Route tag ", RouteInfo.routeTag, " not matched by policy statement ", policyStmt.Name)
+		return
+	}
+	route = ribdInt.Routes{Ipaddr: RouteInfo.destNetIp, Mask: RouteInfo.networkMask, NextHopIp: RouteInfo.nextHopIp, IPAddrType: ribdInt.Int(RouteInfo.ipType), IfIndex: ribdInt.Int(RouteInfo.nextHopIfIndex), Metric: ribdInt.Int(RouteInfo.metric), Prototype: ribdInt.Int(RouteInfo.routeType), Vrf: getVrfName(RouteInfo.vrf), RouteTag: int64(RouteInfo.routeTag)}
 	route.RouteOrigin = ReverseRouteProtoTypeMapDB[int(RouteInfo.routeType)]
+	if targetVrf, isLeak := getLeakTargetVrf(redistributeActionInfo.RedistributeTargetProtocol); isLeak {
+		leakRouteToVrf(RouteInfo, targetVrf, evt)
+		UpdateRedistributeTargetMap(evt, redistributeActionInfo.RedistributeTargetProtocol, route)
+		return
+	}
 	publisherInfo, ok := PublisherInfoMap[redistributeActionInfo.RedistributeTargetProtocol]
 	if ok {
 		logger.Info("ReditributeNotificationSend event called for target protocol - ", redistributeActionInfo.RedistributeTargetProtocol)
@@ -358,6 +383,11 @@ func policyEngineTraverseAndUpdate() {
 	logger.Info("policyEngineTraverseAndUpdate")
 	V4RouteInfoMap.VisitAndUpdate(policyEngineUpdateRoute, nil)
 	V6RouteInfoMap.VisitAndUpdate(policyEngineUpdateRoute, nil)
+	for vrf, _ := range VrfInfoMap {
+		if vrf != DefaultVrf {
+			updateVrfBestRoutes(vrf)
+		}
+	}
 }
 func policyEngineActionAcceptRoute(params interface{}) {
 	routeInfo := params.(RouteParams)
@@ -520,8 +550,17 @@ func policyEngineActionRedistribute(actionInfo interface{}, conditionInfo []inte
 			return
 		}
 	}
-	route = ribdInt.Routes{Ipaddr: RouteInfo.destNetIp, Mask: RouteInfo.networkMask, NextHopIp: RouteInfo.nextHopIp, IPAddrType: ribdInt.Int(RouteInfo.ipType), IfIndex: ribdInt.Int(RouteInfo.nextHopIfIndex), Metric: ribdInt.Int(RouteInfo.metric), Prototype: ribdInt.Int(RouteInfo.routeType)}
+	if !policyStmtRouteTagApply(policyStmt.Name, evt, &RouteInfo) {
+		logger.Info("Route tag ", RouteInfo.routeTag, " not matched by policy statement ", policyStmt.Name)
+		return
+	}
+	route = ribdInt.Routes{Ipaddr: RouteInfo.destNetIp, Mask: RouteInfo.networkMask, NextHopIp: RouteInfo.nextHopIp, IPAddrType: ribdInt.Int(RouteInfo.ipType), IfIndex: ribdInt.Int(RouteInfo.nextHopIfIndex), Metric: ribdInt.Int(RouteInfo.metric), Prototype: ribdInt.Int(RouteInfo.routeType), Vrf: getVrfName(RouteInfo.vrf), RouteTag: int64(RouteInfo.routeTag)}
 	route.RouteOrigin = ReverseRouteProtoTypeMapDB[int(RouteInfo.routeType)]
+	if targetVrf, isLeak := getLeakTargetVrf(redistributeActionInfo.RedistributeTargetProtocol); isLeak {
+		leakRouteToVrf(RouteInfo, targetVrf, evt)
+		UpdateRedistributeTargetMap(evt, redistributeActionInfo.RedistributeTargetProtocol, route)
+		return
+	}
 	publisherInfo, ok := PublisherInfoMap[redistributeActionInfo.RedistributeTargetProtocol]
 	if ok {
 		logger.Info("ReditributeNotificationSend event called for target protocol - ", redistributeActionInfo.RedistributeTargetProtocol)
@@ -534,7 +573,7 @@ func policyEngineActionRedistribute(actionInfo interface{}, conditionInfo []inte
 
 func UpdateRouteAndPolicyDB(policyDetails policy.PolicyDetails, params interface{}) {
 	routeInfo := params.(RouteParams)
-	route := ribdInt.Routes{Ipaddr: routeInfo.destNetIp, Mask: routeInfo.networkMask, IPAddrType: ribdInt.Int(routeInfo.ipType), NextHopIp: routeInfo.nextHopIp, IfIndex: ribdInt.Int(routeInfo.nextHopIfIndex), Metric: ribdInt.Int(routeInfo.metric), Prototype: ribdInt.Int(routeInfo.routeType)}
+	route := ribdInt.Routes{Ipaddr: routeInfo.destNetIp, Mask: routeInfo.networkMask, IPAddrType: ribdInt.Int(routeInfo.ipType), NextHopIp: routeInfo.nextHopIp, IfIndex: ribdInt.Int(routeInfo.nextHopIfIndex), Metric: ribdInt.Int(routeInfo.metric), Prototype: ribdInt.Int(routeInfo.routeType), Vrf: getVrfName(routeInfo.vrf)}
 	var op int
 	if routeInfo.deleteType != Invalid {
 		op = del
@@ -558,7 +597,7 @@ func DoesRouteExist(params interface{}) (exists bool) {
 		logger.Info("Error when getting ipPrefix, err= ", err)
 		return
 	}
-	routeInfoRecordList := RouteInfoMapGet(routeInfo.ipType, ipPrefix)
+	routeInfoRecordList := RouteInfoMapGet(routeInfo.vrf, routeInfo.ipType, ipPrefix)
 	if routeInfoRecordList == nil {
 		logger.Info("Route for type ", routeInfo.ipType, " and prefix", ipPrefix, " no longer exists")
 		routeDeleted = true
@@ -656,8 +695,8 @@ func policyEngineApplyForRoute(prefix patriciaDB.Prefix, item patriciaDB.Item, t
 			logger.Info("route ", selectedRouteInfoRecord, " not valid, continue, sliceIdx:", selectedRouteInfoRecord.sliceIdx, " len(destNetSlice):", len(destNetSlice))
 			continue
 		}
-		policyRoute := ribdInt.Routes{Ipaddr: selectedRouteInfoRecord.destNetIp.String(), Mask: selectedRouteInfoRecord.networkMask.String(), NextHopIp: selectedRouteInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(selectedRouteInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(selectedRouteInfoRecord.metric), Prototype: ribdInt.Int(selectedRouteInfoRecord.protocol), IsPolicyBasedStateValid: rmapInfoRecordList.isPolicyBasedStateValid}
-		params := RouteParams{destNetIp: policyRoute.Ipaddr, networkMask: policyRoute.Mask, routeType: ribd.Int(policyRoute.Prototype), nextHopIp: selectedRouteInfoRecord.nextHopIp.String(), sliceIdx: ribd.Int(policyRoute.SliceIdx), createType: Invalid, deleteType: Invalid}
+		policyRoute := ribdInt.Routes{Ipaddr: selectedRouteInfoRecord.destNetIp.String(), Mask: selectedRouteInfoRecord.networkMask.String(), NextHopIp: selectedRouteInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(selectedRouteInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(selectedRouteInfoRecord.metric), Prototype: ribdInt.Int(selectedRouteInfoRecord.protocol), IsPolicyBasedStateValid: rmapInfoRecordList.isPolicyBasedStateValid, Vrf: selectedRouteInfoRecord.vrf, RouteTag: int64(selectedRouteInfoRecord.routeTag)}
+		params := RouteParams{vrf: selectedRouteInfoRecord.vrf, leakSrcVrf: selectedRouteInfoRecord.leakSrcVrf, routeTag: selectedRouteInfoRecord.routeTag, ipType: selectedRouteInfoRecord.ipType, metric: selectedRouteInfoRecord.metric, nextHopIfIndex: selectedRouteInfoRecord.nextHopIfIndex, destNetIp: policyRoute.Ipaddr, networkMask: policyRoute.Mask, routeType: ribd.Int(policyRoute.Prototype), nextHopIp: selectedRouteInfoRecord.nextHopIp.String(), sliceIdx: ribd.Int(policyRoute.SliceIdx), createType: Invalid, deleteType: Invalid}
 		entity, err := buildPolicyEntityFromRoute(policyRoute, params)
 		if err != nil {
 			logger.Err("Error builiding policy entity params")
@@ -674,8 +713,10 @@ func policyEngineApplyForRoute(prefix patriciaDB.Prefix, item patriciaDB.Item, t
 func policyEngineTraverseAndApply(data interface{}, updatefunc policy.PolicyApplyfunc) {
 	logger.Info("PolicyEngineTraverseAndApply - traverse routing table and apply policy ")
 	traverseAndApplyPolicyData := TraverseAndApplyPolicyData{data: data, updatefunc: updatefunc}
-	V4RouteInfoMap.VisitAndUpdate(policyEngineApplyForRoute, traverseAndApplyPolicyData)
-	V6RouteInfoMap.VisitAndUpdate(policyEngineApplyForRoute, traverseAndApplyPolicyData)
+	for _, vrfInfo := range VrfInfoMap {
+		vrfInfo.v4RouteInfoMap.VisitAndUpdate(policyEngineApplyForRoute, traverseAndApplyPolicyData)
+		vrfInfo.v6RouteInfoMap.VisitAndUpdate(policyEngineApplyForRoute, traverseAndApplyPolicyData)
+	}
 }
 func policyEngineTraverseAndReverse(applyPolicyItem interface{}) {
 	updateInfo := applyPolicyItem.(policy.PolicyEngineApplyInfo)
@@ -691,7 +732,7 @@ func policyEngineTraverseAndReverse(applyPolicyItem interface{}) {
 	var params RouteParams
 	for idx := 0; idx < len(ext.routeInfoList); idx++ {
 		policyRoute = ext.routeInfoList[idx]
-		params = RouteParams{destNetIp: policyRoute.Ipaddr, networkMask: policyRoute.Mask, routeType: ribd.Int(policyRoute.Prototype), sliceIdx: ribd.Int(policyRoute.SliceIdx), createType: Invalid, deleteType: Invalid}
+		params = RouteParams{vrf: policyRoute.Vrf, destNetIp: policyRoute.Ipaddr, networkMask: policyRoute.Mask, routeType: ribd.Int(policyRoute.Prototype), sliceIdx: ribd.Int(policyRoute.SliceIdx), createType: Invalid, deleteType: Invalid}
 		ipPrefix, err := getNetowrkPrefixFromStrings(ext.routeInfoList[idx].Ipaddr, ext.routeInfoList[idx].Mask)
 		if err != nil {
 			logger.Info("Invalid route ", ext.routeList[idx])
@@ -705,7 +746,7 @@ func policyEngineTraverseAndReverse(applyPolicyItem interface{}) {
 		//PolicyEngineDB.PolicyEngineUndoPolicyForEntity(entity, policy, params)
 		success := PolicyEngineDB.PolicyEngineUndoApplyPolicyForEntity(entity, updateInfo, params)
 		if success {
-			deleteRoutePolicyState(params.ipType, ipPrefix, policy.Name)
+			deleteRoutePolicyState(params.vrf, params.ipType, ipPrefix, policy.Name)
 			PolicyEngineDB.DeletePolicyEntityMapEntry(entity, policy.Name)
 		}
 	}
@@ -0,0 +1,407 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdRouteEventJournal.go
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/op/go-nanomsg"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"ribd"
+	"ribdInt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultRouteEventJournalSize = 10000
+	RouteSubscriptionChSize      = 5000
+)
+
+/*
+   Subscriber interest, an empty filter list matches everything
+*/
+type RouteSubscriptionInfo struct {
+	name       string
+	prefixes   []*net.IPNet
+	protocols  map[string]bool
+	vrfs       map[string]bool
+	eventTypes map[string]bool
+	cfg        ribdInt.RouteSubscription
+	lastSeqNum int64 //seq num of the last event published to the subscriber
+	numEvents  int64
+	numDropped int64
+}
+
+/*
+   Bounded ring of route events, every event gets the next sequence number. Events are
+   matched against the subscriptions as they are added so the stream of each subscription
+   follows the journal order.
+*/
+type RouteEventJournal struct {
+	sync.RWMutex
+	events         []RouteEventInfo
+	start          int //index of the oldest event
+	count          int
+	lastSeqNum     int64
+	subscriptions  map[string]*RouteSubscriptionInfo
+	subscriptionCh chan []byte
+}
+
+var RIBD_SUBSCRIPTION_PUB *nanomsg.PubSocket
+
+func newRouteEventJournal(size int, subscriptionCh chan []byte) *RouteEventJournal {
+	return &RouteEventJournal{
+		events:         make([]RouteEventInfo, size),
+		subscriptions:  make(map[string]*RouteSubscriptionInfo),
+		subscriptionCh: subscriptionCh,
+	}
+}
+
+/*
+   Returns the event at idx, idx 0 being the oldest event still in the journal
+*/
+func (journal *RouteEventJournal) get(idx int) RouteEventInfo {
+	return journal.events[(journal.start+idx)%len(journal.events)]
+}
+func (journal *RouteEventJournal) oldestSeqNum() int64 {
+	return journal.lastSeqNum - int64(journal.count) + 1
+}
+func (journal *RouteEventJournal) add(event RouteEventInfo) {
+	journal.Lock()
+	defer journal.Unlock()
+	journal.lastSeqNum++
+	event.seqNum = journal.lastSeqNum
+	if journal.count < len(journal.events) {
+		journal.events[(journal.start+journal.count)%len(journal.events)] = event
+		journal.count++
+	} else {
+		//overwrite the oldest event
+		journal.events[journal.start] = event
+		journal.start = (journal.start + 1) % len(journal.events)
+	}
+	for _, sub := range journal.subscriptions {
+		if sub.match(event) {
+			journal.publish(sub, event)
+		}
+	}
+}
+func (journal *RouteEventJournal) publish(sub *RouteSubscriptionInfo, event RouteEventInfo) {
+	msgInfo := event.toSubscriptionEventMsg(sub.name, sub.lastSeqNum)
+	sub.lastSeqNum = event.seqNum
+	sub.numEvents++
+	msgbufbytes, err := json.Marshal(msgInfo)
+	if err != nil {
+		logger.Err("Error in marshalling Json")
+		return
+	}
+	msg := ribdCommonDefs.RibdNotifyMsg{MsgType: uint16(ribdCommonDefs.NOTIFY_ROUTE_SUBSCRIPTION_EVENT), MsgBuf: msgbufbytes}
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		logger.Err("Error in marshalling Json")
+		return
+	}
+	select {
+	case journal.subscriptionCh <- append(ribdCommonDefs.GetRouteSubscriptionTopic(sub.name), buf...):
+	default:
+		//the subscriber sees the gap in the PrevSeqNum chain and resyncs from the journal
+		sub.numDropped++
+	}
+}
+func (event RouteEventInfo) toSubscriptionEventMsg(name string, prevSeqNum int64) ribdCommonDefs.RouteSubscriptionEventMsg {
+	return ribdCommonDefs.RouteSubscriptionEventMsg{
+		Subscription: name,
+		SeqNum:       event.seqNum,
+		PrevSeqNum:   prevSeqNum,
+		TimeStamp:    event.timeStamp,
+		EventType:    event.eventType,
+		Vrf:          event.vrf,
+		Protocol:     event.protocol,
+		Network:      event.network,
+		NextHopIp:    event.nextHopIp,
+		EventInfo:    event.eventInfo,
+	}
+}
+func (sub *RouteSubscriptionInfo) match(event RouteEventInfo) bool {
+	if len(sub.eventTypes) > 0 && !sub.eventTypes[event.eventType] {
+		return false
+	}
+	if len(sub.protocols) > 0 && !sub.protocols[event.protocol] {
+		return false
+	}
+	if len(sub.vrfs) > 0 && !sub.vrfs[getVrfName(event.vrf)] {
+		return false
+	}
+	if len(sub.prefixes) == 0 {
+		return true
+	}
+	ip, ipNet, err := net.ParseCIDR(event.network)
+	if err != nil {
+		return false
+	}
+	eventLen, eventBits := ipNet.Mask.Size()
+	for _, prefix := range sub.prefixes {
+		//the event network has to be within the range and at least as specific
+		prefixLen, prefixBits := prefix.Mask.Size()
+		if prefixBits == eventBits && eventLen >= prefixLen && prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+   Record an event in the journal
+*/
+func routeEventAdd(eventType string, vrf string, protocol string, network string, nextHopIp string, eventInfo string, timeStamp string) {
+	if localRouteEventsDB == nil {
+		return
+	}
+	localRouteEventsDB.add(RouteEventInfo{
+		timeStamp: timeStamp,
+		eventType: eventType,
+		vrf:       getVrfName(vrf),
+		protocol:  protocol,
+		network:   network,
+		nextHopIp: nextHopIp,
+		eventInfo: eventInfo,
+	})
+}
+
+/*
+   Publishes the subscription events on the subscription socket
+*/
+func (ribdServiceHandler *RIBDServer) RouteSubscriptionServer() {
+	logger.Info("Starting route subscription server loop")
+	for {
+		buf := <-ribdServiceHandler.RouteSubscriptionCh
+		if RIBD_SUBSCRIPTION_PUB == nil {
+			continue
+		}
+		RIBD_SUBSCRIPTION_PUB.Send(buf, nanomsg.DontWait)
+	}
+}
+
+func (m RIBDServer) RouteSubscriptionConfigValidationCheck(cfg *ribdInt.RouteSubscription, op string) (err error) {
+	if cfg.Name == "" || strings.Contains(cfg.Name, "|") {
+		return errors.New(fmt.Sprintln("Invalid subscription name ", cfg.Name))
+	}
+	localRouteEventsDB.RLock()
+	_, exists := localRouteEventsDB.subscriptions[cfg.Name]
+	localRouteEventsDB.RUnlock()
+	if op == "del" {
+		if !exists {
+			return errors.New(fmt.Sprintln("route subscription ", cfg.Name, " not configured"))
+		}
+		return nil
+	}
+	if exists {
+		return errors.New(fmt.Sprintln("Duplicate route subscription ", cfg.Name))
+	}
+	for _, prefix := range cfg.PrefixList {
+		if _, _, err = net.ParseCIDR(prefix); err != nil {
+			return errors.New(fmt.Sprintln("Invalid prefix ", prefix))
+		}
+	}
+	for _, protocol := range cfg.ProtocolList {
+		if _, ok := RouteProtocolTypeMapDB[strings.ToUpper(protocol)]; !ok {
+			return errors.New(fmt.Sprintln("Invalid protocol ", protocol))
+		}
+	}
+	for _, eventType := range cfg.EventTypeList {
+		switch eventType {
+		case ribdCommonDefs.ROUTE_EVENT_INSTALLED, ribdCommonDefs.ROUTE_EVENT_UNINSTALLED, ribdCommonDefs.ROUTE_EVENT_NOTIFICATION:
+		default:
+			return errors.New(fmt.Sprintln("Invalid event type ", eventType))
+		}
+	}
+	return nil
+}
+func (m RIBDServer) ProcessRouteSubscriptionCreateConfig(cfg *ribdInt.RouteSubscription) (val bool, err error) {
+	logger.Info("ProcessRouteSubscriptionCreateConfig for ", cfg.Name)
+	sub := &RouteSubscriptionInfo{
+		name:       cfg.Name,
+		prefixes:   make([]*net.IPNet, 0),
+		protocols:  make(map[string]bool),
+		vrfs:       make(map[string]bool),
+		eventTypes: make(map[string]bool),
+		cfg:        *cfg,
+	}
+	for _, prefix := range cfg.PrefixList {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return false, err
+		}
+		sub.prefixes = append(sub.prefixes, ipNet)
+	}
+	for _, protocol := range cfg.ProtocolList {
+		sub.protocols[strings.ToUpper(protocol)] = true
+	}
+	for _, vrf := range cfg.VrfList {
+		sub.vrfs[getVrfName(vrf)] = true
+	}
+	for _, eventType := range cfg.EventTypeList {
+		sub.eventTypes[eventType] = true
+	}
+	localRouteEventsDB.Lock()
+	defer localRouteEventsDB.Unlock()
+	if _, ok := localRouteEventsDB.subscriptions[cfg.Name]; ok {
+		return false, errors.New(fmt.Sprintln("Duplicate route subscription ", cfg.Name))
+	}
+	//the stream starts after the current end of the journal
+	sub.lastSeqNum = localRouteEventsDB.lastSeqNum
+	localRouteEventsDB.subscriptions[cfg.Name] = sub
+	return true, nil
+}
+func (m RIBDServer) ProcessRouteSubscriptionDeleteConfig(cfg *ribdInt.RouteSubscription) (val bool, err error) {
+	logger.Info("ProcessRouteSubscriptionDeleteConfig for ", cfg.Name)
+	localRouteEventsDB.Lock()
+	defer localRouteEventsDB.Unlock()
+	delete(localRouteEventsDB.subscriptions, cfg.Name)
+	return true, nil
+}
+
+/*
+   Events of the subscription after fromSeqNum, used by subscribers to resync after a reconnect or a
+   gap in the stream. Truncated is set when events after fromSeqNum are no longer in the journal and
+   the subscriber has to refetch the route table.
+*/
+func (m RIBDServer) GetRouteSubscriptionEvents(name string, fromSeqNum int64, count ribdInt.Int) (events *ribdInt.RouteSubscriptionEventGetInfo, err error) {
+	var returnGetInfo ribdInt.RouteSubscriptionEventGetInfo
+	events = &returnGetInfo
+	localRouteEventsDB.RLock()
+	defer localRouteEventsDB.RUnlock()
+	sub, ok := localRouteEventsDB.subscriptions[name]
+	if !ok {
+		return events, errors.New(fmt.Sprintln("route subscription ", name, " not configured"))
+	}
+	journal := localRouteEventsDB
+	events.FromSeqNum = fromSeqNum
+	events.LastSeqNum = journal.lastSeqNum
+	events.RouteSubscriptionEventList = make([]*ribdInt.RouteSubscriptionEvent, 0)
+	prevSeqNum := fromSeqNum
+	if fromSeqNum+1 < journal.oldestSeqNum() {
+		events.Truncated = true
+		prevSeqNum = 0
+	}
+	idx := int(fromSeqNum + 1 - journal.oldestSeqNum())
+	if idx < 0 {
+		idx = 0
+	}
+	for ; idx < journal.count; idx++ {
+		if ribdInt.Int(len(events.RouteSubscriptionEventList)) == count {
+			events.More = true
+			break
+		}
+		event := journal.get(idx)
+		if !sub.match(event) {
+			continue
+		}
+		msgInfo := event.toSubscriptionEventMsg(name, prevSeqNum)
+		prevSeqNum = event.seqNum
+		events.RouteSubscriptionEventList = append(events.RouteSubscriptionEventList, &ribdInt.RouteSubscriptionEvent{
+			SeqNum:     msgInfo.SeqNum,
+			PrevSeqNum: msgInfo.PrevSeqNum,
+			TimeStamp:  msgInfo.TimeStamp,
+			EventType:  msgInfo.EventType,
+			Vrf:        msgInfo.Vrf,
+			Protocol:   msgInfo.Protocol,
+			Network:    msgInfo.Network,
+			NextHopIp:  msgInfo.NextHopIp,
+			EventInfo:  msgInfo.EventInfo,
+		})
+	}
+	events.Count = ribdInt.Int(len(events.RouteSubscriptionEventList))
+	return events, err
+}
+func (m RIBDServer) GetBulkRouteSubscriptionState(fromIndex ribdInt.Int, rcount ribdInt.Int) (subs *ribdInt.RouteSubscriptionStateGetInfo, err error) {
+	var returnGetInfo ribdInt.RouteSubscriptionStateGetInfo
+	subs = &returnGetInfo
+	localRouteEventsDB.RLock()
+	defer localRouteEventsDB.RUnlock()
+	names := make([]string, 0)
+	for name, _ := range localRouteEventsDB.subscriptions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	subStates := make([]*ribdInt.RouteSubscriptionState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(names)) && ribdInt.Int(len(subStates)) < rcount; i++ {
+		sub := localRouteEventsDB.subscriptions[names[i]]
+		subStates = append(subStates, &ribdInt.RouteSubscriptionState{
+			Name:          sub.name,
+			PrefixList:    sub.cfg.PrefixList,
+			ProtocolList:  sub.cfg.ProtocolList,
+			VrfList:       sub.cfg.VrfList,
+			EventTypeList: sub.cfg.EventTypeList,
+			LastSeqNum:    sub.lastSeqNum,
+			NumEvents:     sub.numEvents,
+			NumDropped:    sub.numDropped,
+		})
+	}
+	subs.RouteSubscriptionStateList = subStates
+	subs.StartIdx = fromIndex
+	subs.EndIdx = i
+	subs.More = i < ribdInt.Int(len(names))
+	subs.Count = ribdInt.Int(len(subStates))
+	return subs, err
+}
+
+/*
+   Pages through the journal by sequence number, returns the events after the seq num
+   fromIndex and EndIdx is the seq num to continue from. A fromIndex older than the
+   journal starts from the oldest event still in it.
+*/
+func (m RIBDServer) GetBulkRIBEventState(fromIndex ribd.Int, rcount ribd.Int) (events *ribd.RIBEventStateGetInfo, err error) {
+	var returnGetInfo ribd.RIBEventStateGetInfo
+	events = &returnGetInfo
+	events.StartIdx = fromIndex
+	events.EndIdx = fromIndex
+	if localRouteEventsDB == nil {
+		return events, err
+	}
+	localRouteEventsDB.RLock()
+	defer localRouteEventsDB.RUnlock()
+	returnNodes := make([]*ribd.RIBEventState, 0)
+	idx := int(int64(fromIndex) + 1 - localRouteEventsDB.oldestSeqNum())
+	if idx < 0 {
+		idx = 0
+	}
+	for ; idx < localRouteEventsDB.count && ribd.Int(len(returnNodes)) < rcount; idx++ {
+		event := localRouteEventsDB.get(idx)
+		returnNodes = append(returnNodes, &ribd.RIBEventState{
+			TimeStamp: event.timeStamp,
+			EventInfo: event.eventInfo,
+		})
+		events.EndIdx = ribd.Int(event.seqNum)
+	}
+	events.RIBEventStateList = returnNodes
+	events.More = idx < localRouteEventsDB.count
+	events.Count = ribd.Int(len(returnNodes))
+	return events, err
+}
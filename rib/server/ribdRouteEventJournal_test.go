@@ -0,0 +1,126 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"ribdInt"
+	"testing"
+)
+
+var routeSubscriptionList []*ribdInt.RouteSubscription
+
+func InitRouteSubscriptionList() {
+	routeSubscriptionList = make([]*ribdInt.RouteSubscription, 0)
+	routeSubscriptionList = append(routeSubscriptionList, &ribdInt.RouteSubscription{
+		Name: "all",
+	})
+	routeSubscriptionList = append(routeSubscriptionList, &ribdInt.RouteSubscription{
+		Name:          "monitor",
+		PrefixList:    []string{"40.0.0.0/8"},
+		ProtocolList:  []string{"static"},
+		EventTypeList: []string{ribdCommonDefs.ROUTE_EVENT_INSTALLED, ribdCommonDefs.ROUTE_EVENT_UNINSTALLED},
+	})
+}
+func addJournalTestEvents(start int, count int) {
+	for i := start; i < start+count; i++ {
+		network := fmt.Sprintf("40.%d.0.0/16", i)
+		if i%2 == 1 {
+			network = fmt.Sprintf("50.%d.0.0/16", i)
+		}
+		routeEventAdd(ribdCommonDefs.ROUTE_EVENT_INSTALLED, "", "STATIC", network, "11.1.10.2", "test event "+network, "")
+	}
+}
+func TestInitRouteEventJournalTestServer(t *testing.T) {
+	fmt.Println("****Init RouteEventJournal Test Server****")
+	StartTestServer()
+	InitRouteSubscriptionList()
+	fmt.Println("****************")
+}
+func TestRouteSubscriptionConfigValidationCheck(t *testing.T) {
+	fmt.Println("****TestRouteSubscriptionConfigValidationCheck****")
+	for _, sub := range routeSubscriptionList {
+		err := server.RouteSubscriptionConfigValidationCheck(sub, "add")
+		fmt.Println("err:", err, " for add of subscription:", sub.Name)
+	}
+	err := server.RouteSubscriptionConfigValidationCheck(&ribdInt.RouteSubscription{Name: "a|b"}, "add")
+	fmt.Println("err:", err, " for subscription with invalid name")
+	err = server.RouteSubscriptionConfigValidationCheck(&ribdInt.RouteSubscription{Name: "bad", PrefixList: []string{"40.0.0.0"}}, "add")
+	fmt.Println("err:", err, " for subscription with invalid prefix")
+	err = server.RouteSubscriptionConfigValidationCheck(&ribdInt.RouteSubscription{Name: "bad", ProtocolList: []string{"RIP"}}, "add")
+	fmt.Println("err:", err, " for subscription with invalid protocol")
+	err = server.RouteSubscriptionConfigValidationCheck(&ribdInt.RouteSubscription{Name: "bad", EventTypeList: []string{"RouteFlap"}}, "add")
+	fmt.Println("err:", err, " for subscription with invalid event type")
+	fmt.Println("************************************")
+}
+func TestRouteEventJournalWrap(t *testing.T) {
+	fmt.Println("****TestRouteEventJournalWrap****")
+	journal := newRouteEventJournal(4, make(chan []byte, 10))
+	for i := 0; i < 6; i++ {
+		journal.add(RouteEventInfo{eventType: ribdCommonDefs.ROUTE_EVENT_NOTIFICATION, eventInfo: fmt.Sprintf("event %d", i)})
+	}
+	fmt.Println("count:", journal.count, " lastSeqNum:", journal.lastSeqNum, " oldestSeqNum:", journal.oldestSeqNum())
+	for i := 0; i < journal.count; i++ {
+		event := journal.get(i)
+		fmt.Println("idx:", i, " seqNum:", event.seqNum, " eventInfo:", event.eventInfo)
+	}
+	fmt.Println("************************************")
+}
+func TestRouteSubscriptionEvents(t *testing.T) {
+	fmt.Println("****TestRouteSubscriptionEvents****")
+	for _, sub := range routeSubscriptionList {
+		val, err := server.ProcessRouteSubscriptionCreateConfig(sub)
+		fmt.Println("val:", val, " err:", err, " for subscription:", sub.Name)
+	}
+	startSeqNum := localRouteEventsDB.lastSeqNum
+	addJournalTestEvents(0, 6)
+	events, err := server.GetRouteSubscriptionEvents("monitor", startSeqNum, 2)
+	for err == nil && events.Count > 0 {
+		fmt.Println("from:", events.FromSeqNum, " last:", events.LastSeqNum, " count:", events.Count, " more:", events.More, " truncated:", events.Truncated)
+		for _, event := range events.RouteSubscriptionEventList {
+			fmt.Println("    seqNum:", event.SeqNum, " prevSeqNum:", event.PrevSeqNum, " network:", event.Network, " protocol:", event.Protocol)
+		}
+		events, err = server.GetRouteSubscriptionEvents("monitor", events.RouteSubscriptionEventList[events.Count-1].SeqNum, 2)
+	}
+	//resync from a seq num no longer in the journal
+	addJournalTestEvents(0, DefaultRouteEventJournalSize)
+	events, err = server.GetRouteSubscriptionEvents("monitor", startSeqNum, 2)
+	fmt.Println("err:", err, " truncated:", events.Truncated, " for resync from seqNum:", startSeqNum)
+	subs, _ := server.GetBulkRouteSubscriptionState(0, 10)
+	for _, sub := range subs.RouteSubscriptionStateList {
+		fmt.Println("subscription:", sub.Name, " lastSeqNum:", sub.LastSeqNum, " numEvents:", sub.NumEvents, " numDropped:", sub.NumDropped)
+	}
+	ribEvents, _ := server.GetBulkRIBEventState(0, 2)
+	fmt.Println("RIBEventState count:", ribEvents.Count, " more:", ribEvents.More, " endIdx:", ribEvents.EndIdx)
+	//the next page continues after the last seq num even when new events pushed older ones out
+	addJournalTestEvents(0, 2)
+	ribEvents, _ = server.GetBulkRIBEventState(ribEvents.EndIdx, 2)
+	fmt.Println("RIBEventState count:", ribEvents.Count, " more:", ribEvents.More, " startIdx:", ribEvents.StartIdx, " endIdx:", ribEvents.EndIdx)
+	for _, sub := range routeSubscriptionList {
+		val, err := server.ProcessRouteSubscriptionDeleteConfig(sub)
+		fmt.Println("val:", val, " err:", err, " for delete of subscription:", sub.Name)
+	}
+	fmt.Println("************************************")
+}
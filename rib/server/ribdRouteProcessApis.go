@@ -58,6 +58,10 @@ type RouteInfoRecord struct {
 	isPolicyBasedStateValid bool
 	routeCreatedTime        string
 	routeUpdatedTime        string
+	vrf                     string
+	leakSrcVrf              string //vrf the route was leaked from, empty for native routes
+	routeTag                uint32 //administrative tag, 0 when untagged
+	backup                  bool   //repair next hop, only forwarded on when no primary next hop is reachable
 }
 
 /*
@@ -69,6 +73,7 @@ type RouteInfoRecordList struct {
 	policyHitCounter        ribd.Int
 	policyList              []string
 	isPolicyBasedStateValid bool
+	vrf                     string
 }
 
 /*
@@ -83,7 +88,13 @@ type RouteOpInfoRecord struct {
    event notification data-structure
 */
 type RouteEventInfo struct {
+	seqNum    int64
 	timeStamp string
+	eventType string
+	vrf       string
+	protocol  string
+	network   string //cidr
+	nextHopIp string
 	eventInfo string
 }
 
@@ -96,55 +107,70 @@ type RouteReachabilityStatusInfo struct {
 	status      string
 	protocol    string
 	nextHopIntf ribdInt.NextHopInfo
+	vrf         string
 }
 
 var DummyRouteInfoRecord RouteInfoRecord
 var destNetSlice []localDB
-var localRouteEventsDB []RouteEventInfo
+var localRouteEventsDB *RouteEventJournal
 
 /*
    RoutInfoMap operations functions
 */
-func RouteInfoMapInsert(ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix, routeInfoRecordList interface{}) (ok bool) {
-	logger.Debug("RouteInfoMapInsert prefix: %v", prefix, "ipType:", ipType)
-	if ipType == ribdCommonDefs.IPv4 {
-		ok = V4RouteInfoMap.Insert(prefix, routeInfoRecordList)
-	} else {
-		ok = V6RouteInfoMap.Insert(prefix, routeInfoRecordList)
-	}
+func RouteInfoMapInsert(vrf string, ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix, routeInfoRecordList interface{}) (ok bool) {
+	logger.Debug("RouteInfoMapInsert prefix: %v", prefix, "ipType:", ipType, " vrf:", vrf)
+	routeInfoMap := getVrfRouteInfoMap(vrf, ipType)
+	if routeInfoMap == nil {
+		logger.Err("RouteInfoMapInsert: vrf ", vrf, " not found")
+		return false
+	}
+	ok = routeInfoMap.Insert(prefix, routeInfoRecordList)
+	staticRouteRibChange(vrf, ipType, prefix)
 	return ok
 }
-func RouteInfoMapSet(ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix, routeInfoRecordList interface{}) {
-	logger.Debug("RouteInfoMapSet prefix: %v", prefix, "ipType:", ipType)
-	if ipType == ribdCommonDefs.IPv4 {
-		V4RouteInfoMap.Set(prefix, routeInfoRecordList)
-	} else {
-		V6RouteInfoMap.Set(prefix, routeInfoRecordList)
+func RouteInfoMapSet(vrf string, ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix, routeInfoRecordList interface{}) {
+	logger.Debug("RouteInfoMapSet prefix: %v", prefix, "ipType:", ipType, " vrf:", vrf)
+	routeInfoMap := getVrfRouteInfoMap(vrf, ipType)
+	if routeInfoMap == nil {
+		logger.Err("RouteInfoMapSet: vrf ", vrf, " not found")
+		return
 	}
+	routeInfoMap.Set(prefix, routeInfoRecordList)
+	staticRouteRibChange(vrf, ipType, prefix)
 }
-func RouteInfoMapDelete(ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix) {
-	logger.Debug("RouteInfoMapDelete prefix: %v", prefix, "ipType:", ipType)
-	if ipType == ribdCommonDefs.IPv4 {
-		V4RouteInfoMap.Delete(prefix)
-	} else {
-		V6RouteInfoMap.Delete(prefix)
+func RouteInfoMapDelete(vrf string, ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix) {
+	logger.Debug("RouteInfoMapDelete prefix: %v", prefix, "ipType:", ipType, " vrf:", vrf)
+	routeInfoMap := getVrfRouteInfoMap(vrf, ipType)
+	if routeInfoMap == nil {
+		logger.Err("RouteInfoMapDelete: vrf ", vrf, " not found")
+		return
 	}
+	routeInfoMap.Delete(prefix)
+	staticRouteRibChange(vrf, ipType, prefix)
 }
-func RouteInfoMapGet(ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix) (item interface{}) {
-	logger.Debug("RouteInfoMapGet prefix: %v", prefix, "ipType:", ipType)
-	if ipType == ribdCommonDefs.IPv4 {
-		item = V4RouteInfoMap.Get(prefix)
-	} else {
-		item = V6RouteInfoMap.Get(prefix)
+func RouteInfoMapGet(vrf string, ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix) (item interface{}) {
+	logger.Debug("RouteInfoMapGet prefix: %v", prefix, "ipType:", ipType, " vrf:", vrf)
+	routeInfoMap := getVrfRouteInfoMap(vrf, ipType)
+	if routeInfoMap == nil {
+		return nil
 	}
+	item = routeInfoMap.Get(prefix)
 	return item
 }
-func RouteInfoMapVisitAndUpdate(ipType ribdCommonDefs.IPType, routeReachabilityStatusInfo RouteReachabilityStatusInfo) {
-	logger.Debug("RouteInfoMapVisitAndUpdate() routeReachabilityStatusInfo", routeReachabilityStatusInfo, "ipType:", ipType)
+func RouteInfoMapVisitAndUpdate(vrf string, ipType ribdCommonDefs.IPType, routeReachabilityStatusInfo RouteReachabilityStatusInfo) {
+	logger.Debug("RouteInfoMapVisitAndUpdate() routeReachabilityStatusInfo", routeReachabilityStatusInfo, "ipType:", ipType, " vrf:", vrf)
+	routeInfoMap := getVrfRouteInfoMap(vrf, ipType)
+	if routeInfoMap == nil {
+		return
+	}
+	if getVrfName(vrf) == DefaultVrf {
+		//let the next hop groups with members in this network update their members
+		RouteServiceHandler.AsicdRouteCh <- RIBdServerConfig{OrigConfigObject: routeReachabilityStatusInfo, Op: "nhReachability"}
+	}
 	if ipType == ribdCommonDefs.IPv4 {
-		V4RouteInfoMap.VisitAndUpdate(UpdateV4RouteReachabilityStatus, routeReachabilityStatusInfo)
+		routeInfoMap.VisitAndUpdate(UpdateV4RouteReachabilityStatus, routeReachabilityStatusInfo)
 	} else {
-		V6RouteInfoMap.VisitAndUpdate(UpdateV6RouteReachabilityStatus, routeReachabilityStatusInfo)
+		routeInfoMap.VisitAndUpdate(UpdateV6RouteReachabilityStatus, routeReachabilityStatusInfo)
 	}
 }
 
@@ -352,6 +378,9 @@ func (m RIBDServer) GetPerProtocolRouteCountList() (retList []*ribd.PerProtocolR
 */
 func (m RIBDServer) GetBulkRoutesForProtocol(srcProtocol string, fromIndex ribdInt.Int, rcount ribdInt.Int) (routes *ribdInt.RoutesGetInfo, err error) {
 	//logger.Debug("GetBulkRoutesForProtocol")
+	return getBulkRedistributeRoutes(RedistributeRouteMap[srcProtocol], fromIndex, rcount)
+}
+func getBulkRedistributeRoutes(redistributeRouteMap []RedistributeRouteInfo, fromIndex ribdInt.Int, rcount ribdInt.Int) (routes *ribdInt.RoutesGetInfo, err error) {
 	var i, validCount, toIndex ribdInt.Int
 	var nextRoute *ribdInt.Routes
 	var returnRoutes []*ribdInt.Routes
@@ -359,7 +388,6 @@ func (m RIBDServer) GetBulkRoutesForProtocol(srcProtocol string, fromIndex ribdI
 	i = 0
 	routes = &returnRouteGetInfo
 	moreRoutes := true
-	redistributeRouteMap := RedistributeRouteMap[srcProtocol]
 	if redistributeRouteMap == nil {
 		//logger.Debug("no routes to be advertised for this protocol ", srcProtocol)
 		return routes, err
@@ -419,7 +447,7 @@ func (m RIBDServer) TrackReachabilityStatus(ipAddr string, protocol string, op s
 	index = findElement(protocolList, protocol)
 	if index != -1 {
 		if op == "del" {
-			protocolList = append(protocolList[:index], protocolList[index:]...)
+			protocolList = append(protocolList[:index], protocolList[index+1:]...)
 		} else if op == "add" {
 			logger.Debug(protocol, " already tracking ip ", ipAddr)
 			return nil
@@ -505,49 +533,6 @@ func (m RIBDServer) GetRouteStatsPerInterfaceState(intfref string) (stats *ribd.
 	return stats, err
 }
 
-func (m RIBDServer) GetBulkRIBEventState(fromIndex ribd.Int, rcount ribd.Int) (events *ribd.RIBEventStateGetInfo, err error) {
-	//logger.Debug("GetBulkRIBEventState")
-	var i, validCount, toIndex ribd.Int
-	var tempNode []ribd.RIBEventState = make([]ribd.RIBEventState, rcount)
-	var nextNode *ribd.RIBEventState
-	var returnNodes []*ribd.RIBEventState
-	var returnGetInfo ribd.RIBEventStateGetInfo
-	i = 0
-	events = &returnGetInfo
-	more := true
-	if localRouteEventsDB == nil {
-		//logger.Debug("localRouteEventsDB not initialized")
-		return events, err
-	}
-	for ; ; i++ {
-		if i+fromIndex >= ribd.Int(len(localRouteEventsDB)) {
-			//logger.Debug("All the events fetched")
-			more = false
-			break
-		}
-		if validCount == rcount {
-			//logger.Debug("Enough events fetched")
-			break
-		}
-		//logger.Debug("Fetching event record for index ", i+fromIndex)
-		nextNode = &tempNode[validCount]
-		nextNode.TimeStamp = localRouteEventsDB[i+fromIndex].timeStamp
-		nextNode.EventInfo = localRouteEventsDB[i+fromIndex].eventInfo
-		toIndex = ribd.Int(i + fromIndex)
-		if len(returnNodes) == 0 {
-			returnNodes = make([]*ribd.RIBEventState, 0)
-		}
-		returnNodes = append(returnNodes, nextNode)
-		validCount++
-	}
-	events.RIBEventStateList = returnNodes
-	events.StartIdx = fromIndex
-	events.EndIdx = toIndex + 1
-	events.More = more
-	events.Count = validCount
-	return events, err
-}
-
 /*
    Returns the longest prefix match route to reach the destination network destNet
 */
@@ -713,8 +698,17 @@ func UpdateRouteReachabilityStatus(prefix patriciaDB.Prefix, //prefix of the nod
    Resolve and determine the immediate next hop info for a given ipAddr
 */
 func ResolveNextHop(ipAddr string) (nextHopIntf ribdInt.NextHopInfo, resolvedNextHopIntf ribdInt.NextHopInfo, err error) {
-	func_mesg := "ResolveNextHop() for " + ipAddr
-	logger.Debug("ResolveNextHop for ", ipAddr)
+	return ResolveVrfNextHop(DefaultVrf, ipAddr)
+}
+
+const MaxNextHopResolveDepth = 32
+
+/*
+   Resolve and determine the immediate next hop info for a given ipAddr in the route table of vrf
+*/
+func ResolveVrfNextHop(vrf string, ipAddr string) (nextHopIntf ribdInt.NextHopInfo, resolvedNextHopIntf ribdInt.NextHopInfo, err error) {
+	func_mesg := "ResolveVrfNextHop() for " + ipAddr + " in vrf " + vrf
+	logger.Debug("ResolveVrfNextHop for ", ipAddr, " vrf ", vrf)
 	var prev_intf ribdInt.NextHopInfo
 	nextHopIntf.NextHopIp = ipAddr
 	prev_intf.NextHopIp = ipAddr
@@ -724,8 +718,13 @@ func ResolveNextHop(ipAddr string) (nextHopIntf ribdInt.NextHopInfo, resolvedNex
 		return nextHopIntf, nextHopIntf, err
 	}
 	ip := ipAddr
-	for {
-		intf, err := RouteServiceHandler.GetRouteReachabilityInfo(ip, -1)
+	for depth := 0; ; depth++ {
+		if depth == MaxNextHopResolveDepth {
+			//recursive next hops resolving through each other
+			logger.Err(func_mesg, "next hop resolution exceeded depth ", MaxNextHopResolveDepth)
+			return nextHopIntf, nextHopIntf, errors.New("next hop resolution loop")
+		}
+		intf, err := RouteServiceHandler.GetVrfRouteReachabilityInfo(vrf, ip, -1)
 		if err != nil {
 			logger.Err(func_mesg, "next hop ", ip, " not reachable")
 			return nextHopIntf, nextHopIntf, err
@@ -764,9 +763,9 @@ func SelectNextBestRoute(routeInfoRecordList RouteInfoRecordList, protocol strin
 	/*
 	   Build protocol admin distance slice based on the current admin distance values
 	*/
-	BuildProtocolAdminDistanceSlice(false)
-	for i := 0; i < len(ProtocolAdminDistanceSlice); i++ {
-		tempSelectedProtocol = ProtocolAdminDistanceSlice[i].Protocol
+	adminDistanceSlice := getVrfAdminDistanceSlice(routeInfoRecordList.vrf)
+	for i := 0; i < len(adminDistanceSlice); i++ {
+		tempSelectedProtocol = adminDistanceSlice[i].Protocol
 		if tempSelectedProtocol == protocol {
 			continue
 		}
@@ -798,8 +797,8 @@ func SelectBestRoute(routeInfoRecordList RouteInfoRecordList) (addRouteList []Ro
 	/*
 	   Build protocol admin distance slice based on the current admin distance values
 	*/
-	BuildProtocolAdminDistanceSlice(false)
-	logger.Info("len(protocolAdminDistanceSlice):", len(ProtocolAdminDistanceSlice))
+	adminDistanceSlice := getVrfAdminDistanceSlice(routeInfoRecordList.vrf)
+	logger.Info("len(protocolAdminDistanceSlice):", len(adminDistanceSlice))
 	/*
 	   go over the protocol admin distance slice, select the protocols from best to worst
 	   and check if there are any routes configured with that protocol type
@@ -808,8 +807,8 @@ func SelectBestRoute(routeInfoRecordList RouteInfoRecordList) (addRouteList []Ro
 	   If not, then delete all the routes configured with the old selected protocol in FIB
 	   and configure the routes of the new selected type
 	*/
-	for i := 0; i < len(ProtocolAdminDistanceSlice); i++ {
-		tempSelectedProtocol = ProtocolAdminDistanceSlice[i].Protocol
+	for i := 0; i < len(adminDistanceSlice); i++ {
+		tempSelectedProtocol = adminDistanceSlice[i].Protocol
 		logger.Info("Best preferred protocol ", tempSelectedProtocol, " at i= ", i)
 		routeInfoList := routeInfoRecordList.routeInfoProtocolMap[tempSelectedProtocol]
 		if routeInfoList == nil || len(routeInfoList) == 0 {
@@ -820,12 +819,12 @@ func SelectBestRoute(routeInfoRecordList RouteInfoRecordList) (addRouteList []Ro
 		tempSelectedProtocol = "INVALID"
 		for j := 0; j < len(routeInfoList); j++ {
 			routeInfoRecord := routeInfoList[j]
-			policyRoute := ribdInt.Routes{Ipaddr: routeInfoRecord.destNetIp.String(), Mask: routeInfoRecord.networkMask.String(), NextHopIp: routeInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(routeInfoRecord.metric), Prototype: ribdInt.Int(routeInfoRecord.protocol), IsPolicyBasedStateValid: routeInfoRecordList.isPolicyBasedStateValid}
+			policyRoute := ribdInt.Routes{Ipaddr: routeInfoRecord.destNetIp.String(), Mask: routeInfoRecord.networkMask.String(), NextHopIp: routeInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(routeInfoRecord.metric), Prototype: ribdInt.Int(routeInfoRecord.protocol), IsPolicyBasedStateValid: routeInfoRecordList.isPolicyBasedStateValid, Vrf: routeInfoRecord.vrf}
 			entity, _ := buildPolicyEntityFromRoute(policyRoute, RouteParams{})
 			actionList := PolicyEngineDB.PolicyEngineCheckActionsForEntity(entity, policyCommonDefs.PolicyConditionTypeProtocolMatch)
 			if !PolicyEngineDB.ActionNameListHasAction(actionList, policyCommonDefs.PolicyActionTypeRouteDisposition, "Reject") {
 				logger.Info("atleast one of the routes of this protocol will not be rejected by the policy engine -protocol at index i:", i)
-				tempSelectedProtocol = ProtocolAdminDistanceSlice[i].Protocol
+				tempSelectedProtocol = adminDistanceSlice[i].Protocol
 				break
 			}
 		}
@@ -866,6 +865,7 @@ func selectBestRouteOnAdd(routeInfoRecordList RouteInfoRecordList, routeInfoReco
 	addRouteList = make([]RouteOpInfoRecord, 0)
 	newSelectedProtocol = routeInfoRecordList.selectedRouteProtocol
 	newRouteProtocol := ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)]
+	adminDistanceMapDB := getVrfAdminDistanceMapDB(routeInfoRecord.vrf)
 	add := false
 	del := false
 	var addrouteOpInfoRecord RouteOpInfoRecord
@@ -881,20 +881,20 @@ func selectBestRouteOnAdd(routeInfoRecordList RouteInfoRecordList, routeInfoReco
 			addrouteOpInfoRecord.opType = FIBAndRIB
 			newSelectedProtocol = newRouteProtocol
 		}
-	} else if ProtocolAdminDistanceMapDB[newRouteProtocol].configuredDistance > ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance {
+	} else if adminDistanceMapDB[newRouteProtocol].configuredDistance > adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance {
 		/*
 		   If the configured admin distance is more than the incoming route, add the route in RIB
 		*/
 		add = true
 		addrouteOpInfoRecord.opType = RIBOnly
-	} else if ProtocolAdminDistanceMapDB[newRouteProtocol].configuredDistance < ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance {
-		logger.Debug(" Selecting the new route because the admin distance of the new routetype ", newRouteProtocol, ":", ProtocolAdminDistanceMapDB[ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)]].configuredDistance, "is better than the selected route protocol ", routeInfoRecordList.selectedRouteProtocol, "'s admin distance ", ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol])
+	} else if adminDistanceMapDB[newRouteProtocol].configuredDistance < adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance {
+		logger.Debug(" Selecting the new route because the admin distance of the new routetype ", newRouteProtocol, ":", adminDistanceMapDB[ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)]].configuredDistance, "is better than the selected route protocol ", routeInfoRecordList.selectedRouteProtocol, "'s admin distance ", adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol])
 		del = true
 		add = true
 		addrouteOpInfoRecord.opType = FIBAndRIB
 		delrouteOpInfoRecord.opType = FIBOnly
 		newSelectedProtocol = newRouteProtocol
-	} else if ProtocolAdminDistanceMapDB[ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)]].configuredDistance == ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance {
+	} else if adminDistanceMapDB[ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)]].configuredDistance == adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance {
 		logger.Debug("Same admin distance ")
 		if newRouteProtocol == routeInfoRecordList.selectedRouteProtocol {
 			logger.Debug("Same protocol as the selected route")
@@ -915,16 +915,16 @@ func selectBestRouteOnAdd(routeInfoRecordList RouteInfoRecordList, routeInfoReco
 				addrouteOpInfoRecord.opType = FIBAndRIB
 			}
 		} else {
-			logger.Debug("Protocol ", newRouteProtocol, " has the same admin distance ", ProtocolAdminDistanceMapDB[newRouteProtocol].configuredDistance, " as the protocol", routeInfoRecordList.selectedRouteProtocol, "'s configured admin distance ", ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance)
-			if ProtocolAdminDistanceMapDB[newRouteProtocol].defaultDistance < ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].defaultDistance {
-				logger.Debug("Protocol ", newRouteProtocol, " has lower default admin distance ", ProtocolAdminDistanceMapDB[newRouteProtocol].defaultDistance, " than the protocol", routeInfoRecordList.selectedRouteProtocol, "'s default admin distance ", ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].defaultDistance)
+			logger.Debug("Protocol ", newRouteProtocol, " has the same admin distance ", adminDistanceMapDB[newRouteProtocol].configuredDistance, " as the protocol", routeInfoRecordList.selectedRouteProtocol, "'s configured admin distance ", adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance)
+			if adminDistanceMapDB[newRouteProtocol].defaultDistance < adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].defaultDistance {
+				logger.Debug("Protocol ", newRouteProtocol, " has lower default admin distance ", adminDistanceMapDB[newRouteProtocol].defaultDistance, " than the protocol", routeInfoRecordList.selectedRouteProtocol, "'s default admin distance ", adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].defaultDistance)
 				del = true
 				delrouteOpInfoRecord.opType = FIBOnly
 				add = true
 				addrouteOpInfoRecord.opType = FIBAndRIB
 				newSelectedProtocol = newRouteProtocol
 			} else {
-				logger.Debug("Protocol ", newRouteProtocol, " has higher default admin distance ", ProtocolAdminDistanceMapDB[newRouteProtocol].configuredDistance, " than the protocol", routeInfoRecordList.selectedRouteProtocol, "'s default admin distance ", ProtocolAdminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance)
+				logger.Debug("Protocol ", newRouteProtocol, " has higher default admin distance ", adminDistanceMapDB[newRouteProtocol].configuredDistance, " than the protocol", routeInfoRecordList.selectedRouteProtocol, "'s default admin distance ", adminDistanceMapDB[routeInfoRecordList.selectedRouteProtocol].configuredDistance)
 				add = true
 				addrouteOpInfoRecord.opType = RIBOnly
 			}
@@ -977,7 +977,7 @@ func addNewRoute(destNetPrefix patriciaDB.Prefix,
 	} else {
 		logger.Debug("This is a new route for selectedProtocolType being added, create destNetSlice entry at index ", len(destNetSlice))
 		routeInfoRecord.sliceIdx = len(destNetSlice)
-		localDBRecord := localDB{prefix: destNetPrefix, isValid: true, nextHopIp: routeInfoRecord.nextHopIp.String()}
+		localDBRecord := localDB{prefix: destNetPrefix, isValid: true, nextHopIp: routeInfoRecord.nextHopIp.String(), vrf: routeInfoRecord.vrf}
 		if destNetSlice == nil {
 			destNetSlice = make([]localDB, 0)
 		}
@@ -1005,7 +1005,7 @@ func addNewRoute(destNetPrefix patriciaDB.Prefix,
 	/*
 	   Update route info in RouteMap
 	*/
-	RouteInfoMapSet(routeInfoRecord.ipType, patriciaDB.Prefix(destNetPrefix), routeInfoRecordList)
+	RouteInfoMapSet(routeInfoRecord.vrf, routeInfoRecord.ipType, patriciaDB.Prefix(destNetPrefix), routeInfoRecordList)
 	if routeInfoRecord.ipType == ribdCommonDefs.IPv4 {
 		v4rtCount++
 		v4routeCreatedTimeMap[v4rtCount] = routeInfoRecord.routeCreatedTime
@@ -1032,7 +1032,7 @@ func addNewRoute(destNetPrefix patriciaDB.Prefix,
 		Op:               "add",
 	}
 
-	policyRoute := ribdInt.Routes{Ipaddr: routeInfoRecord.destNetIp.String(), Mask: routeInfoRecord.networkMask.String(), IPAddrType: ribdInt.Int(routeInfoRecord.ipType), NextHopIp: routeInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(routeInfoRecord.metric), Prototype: ribdInt.Int(routeInfoRecord.protocol), IsPolicyBasedStateValid: routeInfoRecordList.isPolicyBasedStateValid}
+	policyRoute := ribdInt.Routes{Ipaddr: routeInfoRecord.destNetIp.String(), Mask: routeInfoRecord.networkMask.String(), IPAddrType: ribdInt.Int(routeInfoRecord.ipType), NextHopIp: routeInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(routeInfoRecord.metric), Prototype: ribdInt.Int(routeInfoRecord.protocol), IsPolicyBasedStateValid: routeInfoRecordList.isPolicyBasedStateValid, Vrf: routeInfoRecord.vrf, RouteTag: int64(routeInfoRecord.routeTag)}
 	var params RouteParams
 	params = BuildRouteParamsFromRouteInoRecord(routeInfoRecord)
 	if policyPath == policyCommonDefs.PolicyPath_Export {
@@ -1041,7 +1041,7 @@ func addNewRoute(destNetPrefix patriciaDB.Prefix,
 		/*
 		   Find resolved next hop
 		*/
-		nhIntf, resolvedNextHopIntf, res_err := ResolveNextHop(routeInfoRecord.nextHopIp.String())
+		nhIntf, resolvedNextHopIntf, res_err := ResolveVrfNextHop(getNextHopVrf(routeInfoRecord), routeInfoRecord.nextHopIp.String())
 		//logger.Debug("nhIntf:ipAddr:mask = ", nhIntf.Ipaddr, ":", nhIntf.Mask, " nexthop ip :", routeInfoRecord.nextHopIp.String())
 		routeInfoRecord.resolvedNextHopIpIntf = resolvedNextHopIntf
 		//call asicd to add
@@ -1070,8 +1070,7 @@ func addNewRoute(destNetPrefix patriciaDB.Prefix,
 		//update in the event log
 		eventInfo := "Installed " + ReverseRouteProtoTypeMapDB[int(policyRoute.Prototype)] + " route " + policyRoute.Ipaddr + ":" + policyRoute.Mask + " nextHopIp :" + routeInfoRecord.nextHopIp.String() + " in Hardware and RIB "
 		t1 := time.Now()
-		routeEventInfo := RouteEventInfo{timeStamp: t1.String(), eventInfo: eventInfo}
-		localRouteEventsDB = append(localRouteEventsDB, routeEventInfo)
+		routeEventAdd(ribdCommonDefs.ROUTE_EVENT_INSTALLED, routeInfoRecord.vrf, ReverseRouteProtoTypeMapDB[int(policyRoute.Prototype)], routeInfoRecord.networkAddr, routeInfoRecord.nextHopIp.String(), eventInfo, t1.String())
 
 		//get the network address associated with the nexthop and update its refcount
 		if res_err == nil {
@@ -1088,9 +1087,9 @@ func addNewRoute(destNetPrefix patriciaDB.Prefix,
 			}
 			//check if there are routes depending on this network as next hop
 			if RouteServiceHandler.NextHopInfoMap[NextHopInfoKey{string(destNetPrefix)}].refCount > 0 {
-				routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Up", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf}
+				routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Up", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf, routeInfoRecord.vrf}
 				RouteReachabilityStatusUpdate(routeReachabilityStatusInfo.protocol, routeReachabilityStatusInfo)
-				RouteInfoMapVisitAndUpdate(routeInfoRecord.ipType, routeReachabilityStatusInfo)
+				RouteInfoMapVisitAndUpdate(routeInfoRecord.vrf, routeInfoRecord.ipType, routeReachabilityStatusInfo)
 			}
 		}
 	}
@@ -1161,12 +1160,12 @@ func deleteRoute(destNetPrefix patriciaDB.Prefix, //route prefix of the route be
 				//check if there are routes dependent on this network
 				if RouteServiceHandler.NextHopInfoMap[NextHopInfoKey{string(destNetPrefix)}].refCount > 0 {
 					nextHopIntf := ribdInt.NextHopInfo{}
-					routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Down", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf}
+					routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Down", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf, routeInfoRecord.vrf}
 					RouteReachabilityStatusUpdate(routeReachabilityStatusInfo.protocol, routeReachabilityStatusInfo)
-					RouteInfoMapVisitAndUpdate(routeInfoRecord.ipType, routeReachabilityStatusInfo)
+					RouteInfoMapVisitAndUpdate(routeInfoRecord.vrf, routeInfoRecord.ipType, routeReachabilityStatusInfo)
 				}
 				//get the network address associated with the nexthop and update its refcount
-				nhIntf, err := RouteServiceHandler.GetRouteReachabilityInfo(routeInfoRecord.nextHopIp.String(), -1)
+				nhIntf, err := RouteServiceHandler.GetVrfRouteReachabilityInfo(getNextHopVrf(routeInfoRecord), routeInfoRecord.nextHopIp.String(), -1)
 				if err == nil {
 					nhPrefix, err := getNetowrkPrefixFromStrings(nhIntf.Ipaddr, nhIntf.Mask)
 					if err == nil {
@@ -1180,7 +1179,7 @@ func deleteRoute(destNetPrefix patriciaDB.Prefix, //route prefix of the route be
 					OrigConfigObject: RouteDBInfo{routeInfoRecord, routeInfoRecordList},
 					Op:               "del",
 				}
-				RouteInfoMapDelete(routeInfoRecord.ipType, destNetPrefix)
+				RouteInfoMapDelete(routeInfoRecord.vrf, routeInfoRecord.ipType, destNetPrefix)
 				UpdateProtocolRouteMap(ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], "del", routeInfoRecord.ipType, string(destNetPrefix), false)
 				UpdateInterfaceRouteMap(int(routeInfoRecord.nextHopIfIndex), "del", routeInfoRecord.ipType, string(destNetPrefix), false)
 				nodeDeleted = true
@@ -1191,7 +1190,7 @@ func deleteRoute(destNetPrefix patriciaDB.Prefix, //route prefix of the route be
 				OrigConfigObject: RouteDBInfo{routeInfoRecord, routeInfoRecordList},
 				Op:               "add",
 			}
-			RouteInfoMapSet(routeInfoRecord.ipType, destNetPrefix, routeInfoRecordList)
+			RouteInfoMapSet(routeInfoRecord.vrf, routeInfoRecord.ipType, destNetPrefix, routeInfoRecordList)
 			UpdateProtocolRouteMap(ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], "del", routeInfoRecord.ipType, string(destNetPrefix), true)
 			UpdateInterfaceRouteMap(int(routeInfoRecord.nextHopIfIndex), "del", routeInfoRecord.ipType, string(destNetPrefix), true)
 		}
@@ -1213,12 +1212,12 @@ func deleteRoute(destNetPrefix patriciaDB.Prefix, //route prefix of the route be
 		//check if there are routes dependent on this network
 		if RouteServiceHandler.NextHopInfoMap[NextHopInfoKey{string(destNetPrefix)}].refCount > 0 {
 			nextHopIntf := ribdInt.NextHopInfo{}
-			routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Down", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf}
+			routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Down", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf, routeInfoRecord.vrf}
 			RouteReachabilityStatusUpdate(routeReachabilityStatusInfo.protocol, routeReachabilityStatusInfo)
-			RouteInfoMapVisitAndUpdate(routeInfoRecord.ipType, routeReachabilityStatusInfo)
+			RouteInfoMapVisitAndUpdate(routeInfoRecord.vrf, routeInfoRecord.ipType, routeReachabilityStatusInfo)
 		}
 		//get the network address associated with the nexthop and update its refcount
-		nhIntf, err := RouteServiceHandler.GetRouteReachabilityInfo(routeInfoRecord.nextHopIp.String(), -1)
+		nhIntf, err := RouteServiceHandler.GetVrfRouteReachabilityInfo(getNextHopVrf(routeInfoRecord), routeInfoRecord.nextHopIp.String(), -1)
 		if err == nil {
 			nhPrefix, err := getNetowrkPrefixFromStrings(nhIntf.Ipaddr, nhIntf.Mask)
 			if err == nil {
@@ -1230,13 +1229,13 @@ func deleteRoute(destNetPrefix patriciaDB.Prefix, //route prefix of the route be
 			OrigConfigObject: RouteDBInfo{routeInfoRecord, routeInfoRecordList},
 			Op:               "add",
 		}
-		RouteInfoMapSet(routeInfoRecord.ipType, destNetPrefix, routeInfoRecordList)
+		RouteInfoMapSet(routeInfoRecord.vrf, routeInfoRecord.ipType, destNetPrefix, routeInfoRecordList)
 	}
 	if routeInfoRecordList.selectedRouteProtocol != ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)] {
 		logger.Debug("This is not the selected protocol, nothing more to do here")
 		return
 	}
-	policyRoute := ribdInt.Routes{Ipaddr: routeInfoRecord.destNetIp.String(), Mask: routeInfoRecord.networkMask.String(), IPAddrType: ribdInt.Int(routeInfoRecord.ipType), NextHopIp: routeInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(routeInfoRecord.metric), Prototype: ribdInt.Int(routeInfoRecord.protocol), IsPolicyBasedStateValid: routeInfoRecordList.isPolicyBasedStateValid}
+	policyRoute := ribdInt.Routes{Ipaddr: routeInfoRecord.destNetIp.String(), Mask: routeInfoRecord.networkMask.String(), IPAddrType: ribdInt.Int(routeInfoRecord.ipType), NextHopIp: routeInfoRecord.nextHopIp.String(), IfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex), Metric: ribdInt.Int(routeInfoRecord.metric), Prototype: ribdInt.Int(routeInfoRecord.protocol), IsPolicyBasedStateValid: routeInfoRecordList.isPolicyBasedStateValid, Vrf: routeInfoRecord.vrf, RouteTag: int64(routeInfoRecord.routeTag)}
 	if policyPath != policyCommonDefs.PolicyPath_Export {
 		//logger.Debug("Expected export path for delete op")
 		return
@@ -1265,8 +1264,7 @@ func deleteRoute(destNetPrefix patriciaDB.Prefix, //route prefix of the route be
 	}
 	eventInfo := delStr + ":" + ReverseRouteProtoTypeMapDB[int(policyRoute.Prototype)] + " " + policyRoute.Ipaddr + ":" + policyRoute.Mask + " nextHopIp :" + routeInfoRecord.nextHopIp.String()
 	t1 := time.Now()
-	routeEventInfo := RouteEventInfo{timeStamp: t1.String(), eventInfo: eventInfo}
-	localRouteEventsDB = append(localRouteEventsDB, routeEventInfo)
+	routeEventAdd(ribdCommonDefs.ROUTE_EVENT_UNINSTALLED, routeInfoRecord.vrf, ReverseRouteProtoTypeMapDB[int(policyRoute.Prototype)], routeInfoRecord.networkAddr, routeInfoRecord.nextHopIp.String(), eventInfo, t1.String())
 
 	var params RouteParams
 	params = BuildRouteParamsFromRouteInoRecord(routeInfoRecord)
@@ -1359,7 +1357,12 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
 	addType := routeInfo.createType
 	policyStateChange := ribdCommonDefs.RoutePolicyStateChangetoValid
 	sliceIdx := routeInfo.sliceIdx
+	vrf := getVrfName(routeInfo.vrf)
 	callSelectRoute := false
+	if getVrfInfo(vrf) == nil {
+		logger.Err("vrf ", vrf, " not found")
+		return 0, errors.New(fmt.Sprintln("vrf ", vrf, " not found"))
+	}
 	destNetIpAddr, err := getIP(destNetIp)
 	if err != nil {
 		logger.Err("destNetIpAddr invalid")
@@ -1404,20 +1407,24 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
 		metric:         metric,
 		sliceIdx:       int(sliceIdx),
 		weight:         weight,
+		vrf:            vrf,
+		leakSrcVrf:     routeInfo.leakSrcVrf,
+		routeTag:       routeInfo.routeTag,
+		backup:         routeInfo.backup,
 	}
 
-	policyRoute := ribdInt.Routes{Ipaddr: destNetIp, IPAddrType: ribdInt.Int(ipType), Mask: networkMask, NextHopIp: nextHopIp, IfIndex: ribdInt.Int(nextHopIfIndex), Metric: ribdInt.Int(metric), Prototype: ribdInt.Int(routeType), Weight: ribdInt.Int(weight)}
+	policyRoute := ribdInt.Routes{Ipaddr: destNetIp, IPAddrType: ribdInt.Int(ipType), Mask: networkMask, NextHopIp: nextHopIp, IfIndex: ribdInt.Int(nextHopIfIndex), Metric: ribdInt.Int(metric), Prototype: ribdInt.Int(routeType), Weight: ribdInt.Int(weight), Vrf: vrf, RouteTag: int64(routeInfo.routeTag)}
 	//logger.Info("createroute:,setting ipaddrtype to :", policyRoute.IPAddrType, " from iptype:", ipType)
 	routeInfoRecord.resolvedNextHopIpIntf.NextHopIp = routeInfoRecord.nextHopIp.String()
 	routeInfoRecord.resolvedNextHopIpIntf.NextHopIfIndex = ribdInt.Int(routeInfoRecord.nextHopIfIndex)
 
-	nhIntf, resolvedNextHopIntf, res_err := ResolveNextHop(routeInfoRecord.nextHopIp.String())
+	nhIntf, resolvedNextHopIntf, res_err := ResolveVrfNextHop(getNextHopVrf(routeInfoRecord), routeInfoRecord.nextHopIp.String())
 	//_, resolvedNextHopIntf, _ := ResolveNextHop(routeInfoRecord.nextHopIp.String())
 	routeInfoRecord.resolvedNextHopIpIntf = resolvedNextHopIntf
 	logger.Info("nhIntf ipaddr/mask: ", nhIntf.Ipaddr, ":", nhIntf.Mask, " resolvedNex ", resolvedNextHopIntf.NextHopIp, " nexthop ", nextHopIp, "Is reachable:", resolvedNextHopIntf.IsReachable)
 
 	routeInfoRecord.routeCreatedTime = time.Now().String()
-	routeInfoRecordListItem := RouteInfoMapGet(ipType, destNet)
+	routeInfoRecordListItem := RouteInfoMapGet(vrf, ipType, destNet)
 	if routeInfoRecordListItem == nil {
 		/*
 		   no routes for this destination are currently configured
@@ -1433,13 +1440,14 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
 		newRouteInfoRecordList.routeInfoProtocolMap[ReverseRouteProtoTypeMapDB[int(routeType)]] = make([]RouteInfoRecord, 0)
 		newRouteInfoRecordList.routeInfoProtocolMap[ReverseRouteProtoTypeMapDB[int(routeType)]] = append(newRouteInfoRecordList.routeInfoProtocolMap[ReverseRouteProtoTypeMapDB[int(routeType)]], routeInfoRecord)
 		newRouteInfoRecordList.selectedRouteProtocol = ReverseRouteProtoTypeMapDB[int(routeType)]
+		newRouteInfoRecordList.vrf = vrf
 
 		if policyStateChange == ribdCommonDefs.RoutePolicyStateChangetoInValid {
 			newRouteInfoRecordList.isPolicyBasedStateValid = false
 		} else if policyStateChange == ribdCommonDefs.RoutePolicyStateChangetoValid {
 			newRouteInfoRecordList.isPolicyBasedStateValid = true
 		}
-		if ok := RouteInfoMapInsert(ipType, destNet, newRouteInfoRecordList); ok != true {
+		if ok := RouteInfoMapInsert(vrf, ipType, destNet, newRouteInfoRecordList); ok != true {
 			logger.Err("Route map insert return value not ok")
 			return 0, err
 		}
@@ -1452,7 +1460,7 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
 		}
 		UpdateProtocolRouteMap(ReverseRouteProtoTypeMapDB[int(routeType)], "add", ipType, string(destNet), false)
 		UpdateInterfaceRouteMap(int(routeInfoRecord.nextHopIfIndex), "add", routeInfoRecord.ipType, string(destNet), false)
-		localDBRecord := localDB{prefix: destNet, isValid: true, nextHopIp: nextHopIp}
+		localDBRecord := localDB{prefix: destNet, isValid: true, nextHopIp: nextHopIp, vrf: vrf}
 		if destNetSlice == nil {
 			destNetSlice = make([]localDB, 0)
 		}
@@ -1479,8 +1487,7 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
 		}
 		//update in the event log
 		eventInfo := "Installed " + ReverseRouteProtoTypeMapDB[int(policyRoute.Prototype)] + " route " + policyRoute.Ipaddr + ":" + policyRoute.Mask + " nextHopIp :" + routeInfoRecord.nextHopIp.String() + " in Hardware and RIB "
-		routeEventInfo := RouteEventInfo{timeStamp: routeInfoRecord.routeCreatedTime, eventInfo: eventInfo}
-		localRouteEventsDB = append(localRouteEventsDB, routeEventInfo)
+		routeEventAdd(ribdCommonDefs.ROUTE_EVENT_INSTALLED, routeInfoRecord.vrf, ReverseRouteProtoTypeMapDB[int(policyRoute.Prototype)], routeInfoRecord.networkAddr, routeInfoRecord.nextHopIp.String(), eventInfo, routeInfoRecord.routeCreatedTime)
 
 		//update the ref count for the next hop ip
 		if res_err == nil {
@@ -1497,10 +1504,10 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
 				NextHopIfIndex: ribdInt.Int(routeInfoRecord.nextHopIfIndex),
 			}
 			if RouteServiceHandler.NextHopInfoMap[NextHopInfoKey{string(destNet)}].refCount > 0 {
-				routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Up", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf}
+				routeReachabilityStatusInfo := RouteReachabilityStatusInfo{routeInfoRecord.networkAddr, routeInfoRecord.ipType, "Up", ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], nextHopIntf, routeInfoRecord.vrf}
 				RouteReachabilityStatusUpdate(routeReachabilityStatusInfo.protocol, routeReachabilityStatusInfo)
 				//If there are dependent routes for this ip, then bring them up
-				RouteInfoMapVisitAndUpdate(ipType, routeReachabilityStatusInfo)
+				RouteInfoMapVisitAndUpdate(vrf, ipType, routeReachabilityStatusInfo)
 			}
 		}
 		var params RouteParams
@@ -1567,7 +1574,8 @@ func createRoute(routeInfo RouteParams) (rc ribd.Int, err error) {
    -  a user/protocol deletes a route - delType = FIBAndRIB
    - when a link goes down and we have connected routes on that link - delType = FIBOnly
 **/
-func deleteIPRoute(destNetIp string,
+func deleteIPRoute(vrf string,
+	destNetIp string,
 	ipType ribdCommonDefs.IPType,
 	networkMask string,
 	routeType string,
@@ -1575,7 +1583,8 @@ func deleteIPRoute(destNetIp string,
 	nextHopIfIndex ribd.Int,
 	delType ribd.Int,
 	policyStateChange int) (rc ribd.Int, err error) {
-	logger.Debug("deleteIPRoute for destNetIp:", destNetIp, " networkMask:", networkMask, " with routeType:", routeType, " nextHopIP", nextHopIP, " del type ", delType)
+	logger.Debug("deleteIPRoute for destNetIp:", destNetIp, " networkMask:", networkMask, " with routeType:", routeType, " nextHopIP", nextHopIP, " del type ", delType, " vrf ", vrf)
+	vrf = getVrfName(vrf)
 
 	destNetIpAddr, err := getIP(destNetIp)
 	if err != nil {
@@ -1605,7 +1614,7 @@ func deleteIPRoute(destNetIp string,
 		}
 	}
 	//logger.Debug("destNet = ", destNet)
-	routeInfoRecordListItem := RouteInfoMapGet(ipType, destNet)
+	routeInfoRecordListItem := RouteInfoMapGet(vrf, ipType, destNet)
 	if routeInfoRecordListItem == nil {
 		logger.Err("Destnet ", destNet, " not found")
 		return 0, errors.New("No match found ")
@@ -25,8 +25,10 @@
 package server
 
 import (
+	"l3/bfd/bfddCommonDefs"
 	"l3/rib/ribdCommonDefs"
 	"ribd"
+	"ribdInt"
 	"strconv"
 )
 
@@ -325,6 +327,8 @@ func (ribdServiceHandler *RIBDServer) StartRouteProcessServer() {
 			//logger.Debug(fmt.Sprintln("received message on RouteConfCh channel, op: ", routeConf.Op)
 			if routeConf.Op == "add" {
 				ribdServiceHandler.ProcessV4RouteCreateConfig(routeConf.OrigConfigObject.(*ribd.IPv4Route), FIBAndRIB, ribd.Int(len(destNetSlice)))
+			} else if routeConf.Op == "addRouteConfig" {
+				ribdServiceHandler.ProcessV4RouteConfigCreate(routeConf.OrigConfigObject.(*ribdInt.IPv4RouteConfig), ribd.Int(len(destNetSlice)))
 			} else if routeConf.Op == "addFIBOnly" {
 				ribdServiceHandler.ProcessV4RouteCreateConfig(routeConf.OrigConfigObject.(*ribd.IPv4Route), FIBOnly, routeConf.AdditionalParams.(ribd.Int))
 			} else if routeConf.Op == "addBulk" {
@@ -358,6 +362,48 @@ func (ribdServiceHandler *RIBDServer) StartRouteProcessServer() {
 				} else {
 					ribdServiceHandler.Processv6RoutePatchUpdateConfig(routeConf.OrigConfigObject.(*ribd.IPv6Route), routeConf.NewConfigObject.(*ribd.IPv6Route), routeConf.PatchOp)
 				}
+			} else if routeConf.Op == "addVrf" {
+				ribdServiceHandler.ProcessVrfCreateConfig(routeConf.OrigConfigObject.(*ribdInt.Vrf))
+			} else if routeConf.Op == "updateVrf" {
+				ribdServiceHandler.ProcessVrfUpdateConfig(routeConf.OrigConfigObject.(*ribdInt.Vrf), routeConf.NewConfigObject.(*ribdInt.Vrf))
+			} else if routeConf.Op == "delVrf" {
+				ribdServiceHandler.ProcessVrfDeleteConfig(routeConf.OrigConfigObject.(*ribdInt.Vrf))
+			} else if routeConf.Op == "vrfIntfBind" {
+				ribdServiceHandler.ProcessIntfVrfBinding(routeConf.OrigConfigObject.(int32))
+			} else if routeConf.Op == "addStaticRoute" {
+				ribdServiceHandler.ProcessStaticRouteCreateConfig(routeConf.OrigConfigObject.(*ribdInt.StaticRoute))
+			} else if routeConf.Op == "delStaticRoute" {
+				ribdServiceHandler.ProcessStaticRouteDeleteConfig(routeConf.OrigConfigObject.(*ribdInt.StaticRoute))
+			} else if routeConf.Op == "addTrackObject" {
+				ribdServiceHandler.ProcessTrackObjectCreateConfig(routeConf.OrigConfigObject.(*ribdInt.TrackObject))
+			} else if routeConf.Op == "delTrackObject" {
+				ribdServiceHandler.ProcessTrackObjectDeleteConfig(routeConf.OrigConfigObject.(*ribdInt.TrackObject))
+			} else if routeConf.Op == "evalStaticRoutes" {
+				ribdServiceHandler.ProcessStaticRouteEval()
+			} else if routeConf.Op == "trackIntfState" {
+				ribdServiceHandler.ProcessTrackIntfStateChange(routeConf.OrigConfigObject.(TrackIntfStateInfo))
+			} else if routeConf.Op == "bfdStateChange" {
+				ribdServiceHandler.ProcessStaticBfdStateChange(routeConf.OrigConfigObject.(bfddCommonDefs.BfddNotifyMsg))
+			} else if routeConf.Op == "bfdSessionRestore" {
+				ribdServiceHandler.ProcessStaticBfdSessionRestore()
+			} else if routeConf.Op == "fibAudit" {
+				ribdServiceHandler.ProcessFibAuditRequest(routeConf.OrigConfigObject.(bool))
+			} else if routeConf.Op == "fibAuditRepair" {
+				ribdServiceHandler.ProcessFibAuditRepair(routeConf.OrigConfigObject.(FibAuditRepairBatch))
+			} else if routeConf.Op == "addPbrPolicy" {
+				ribdServiceHandler.ProcessPbrPolicyCreateConfig(routeConf.OrigConfigObject.(*ribdInt.PbrPolicy))
+			} else if routeConf.Op == "delPbrPolicy" {
+				ribdServiceHandler.ProcessPbrPolicyDeleteConfig(routeConf.OrigConfigObject.(*ribdInt.PbrPolicy))
+			} else if routeConf.Op == "addPbrPolicyBinding" {
+				ribdServiceHandler.ProcessPbrPolicyBindingCreateConfig(routeConf.OrigConfigObject.(*ribdInt.PbrPolicyBinding))
+			} else if routeConf.Op == "delPbrPolicyBinding" {
+				ribdServiceHandler.ProcessPbrPolicyBindingDeleteConfig(routeConf.OrigConfigObject.(*ribdInt.PbrPolicyBinding))
+			} else if routeConf.Op == "addPolicyStmtRouteTag" {
+				ribdServiceHandler.ProcessPolicyStmtRouteTagCreateConfig(routeConf.OrigConfigObject.(*ribdInt.PolicyStmtRouteTag))
+			} else if routeConf.Op == "delPolicyStmtRouteTag" {
+				ribdServiceHandler.ProcessPolicyStmtRouteTagDeleteConfig(routeConf.OrigConfigObject.(*ribdInt.PolicyStmtRouteTag))
+			} else if routeConf.Op == "evalPbrPolicies" {
+				ribdServiceHandler.ProcessPbrPolicyEval()
 			}
 		}
 	}
@@ -0,0 +1,152 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdRouteTag.go
+package server
+
+import (
+	"errors"
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"ribdInt"
+	"sort"
+	"utils/patriciaDB"
+)
+
+const (
+	MaxRouteTag = 0xFFFFFFFF
+)
+
+/*
+   Route tag match/exclude conditions and set tag action of a policy statement. They are
+   evaluated by the redistribution action of the statement, after the policy engine matched
+   the other conditions of the statement, so each statement of a policy carries its own tags.
+*/
+type PolicyStmtRouteTagInfo struct {
+	cfg         ribdInt.PolicyStmtRouteTag
+	matchTags   map[uint32]bool //empty when any tag matches
+	excludeTags map[uint32]bool
+	setTag      uint32 //0 keeps the tag of the route
+}
+
+var PolicyStmtRouteTagMap map[string]*PolicyStmtRouteTagInfo = make(map[string]*PolicyStmtRouteTagInfo) //statement name -> tag info
+
+func validateRouteTag(tag int64) error {
+	if tag < 0 || tag > MaxRouteTag {
+		return errors.New(fmt.Sprintln("Invalid route tag ", tag))
+	}
+	return nil
+}
+func (m RIBDServer) PolicyStmtRouteTagConfigValidationCheck(cfg *ribdInt.PolicyStmtRouteTag, op string) (err error) {
+	if op == "del" {
+		if _, ok := PolicyStmtRouteTagMap[cfg.Statement]; !ok {
+			logger.Err("Route tags not configured for policy statement ", cfg.Statement)
+			return errors.New(fmt.Sprintln("Route tags not configured for policy statement ", cfg.Statement))
+		}
+		return nil
+	}
+	if m.GlobalPolicyEngineDB.PolicyStmtDB.Get(patriciaDB.Prefix(cfg.Statement)) == nil {
+		logger.Err("Policy statement ", cfg.Statement, " not defined")
+		return errors.New(fmt.Sprintln("Policy statement ", cfg.Statement, " not defined"))
+	}
+	for _, tag := range append(append([]int64{cfg.SetRouteTag}, cfg.MatchRouteTagList...), cfg.ExcludeRouteTagList...) {
+		if err = validateRouteTag(tag); err != nil {
+			return err
+		}
+	}
+	for _, matchTag := range cfg.MatchRouteTagList {
+		for _, excludeTag := range cfg.ExcludeRouteTagList {
+			if matchTag == excludeTag {
+				return errors.New(fmt.Sprintln("Route tag ", matchTag, " both matched and excluded by policy statement ", cfg.Statement))
+			}
+		}
+	}
+	return nil
+}
+func (m RIBDServer) ProcessPolicyStmtRouteTagCreateConfig(cfg *ribdInt.PolicyStmtRouteTag) {
+	info := &PolicyStmtRouteTagInfo{
+		cfg:         *cfg,
+		matchTags:   make(map[uint32]bool),
+		excludeTags: make(map[uint32]bool),
+		setTag:      uint32(cfg.SetRouteTag),
+	}
+	for _, tag := range cfg.MatchRouteTagList {
+		info.matchTags[uint32(tag)] = true
+	}
+	for _, tag := range cfg.ExcludeRouteTagList {
+		info.excludeTags[uint32(tag)] = true
+	}
+	logger.Info("Route tags for policy statement ", cfg.Statement, " match:", info.matchTags, " exclude:", info.excludeTags, " set tag:", info.setTag)
+	PolicyStmtRouteTagMap[cfg.Statement] = info
+}
+func (m RIBDServer) ProcessPolicyStmtRouteTagDeleteConfig(cfg *ribdInt.PolicyStmtRouteTag) {
+	logger.Info("Removing route tags of policy statement ", cfg.Statement)
+	delete(PolicyStmtRouteTagMap, cfg.Statement)
+}
+func (info *PolicyStmtRouteTagInfo) permit(tag uint32) bool {
+	if info.excludeTags[tag] {
+		return false
+	}
+	return len(info.matchTags) == 0 || info.matchTags[tag]
+}
+
+/*
+   Called by the redistribution actions of policy statement stmtName before a route is sent to
+   the target protocol. Returns false if the route tag does not match the tag conditions of the
+   statement, otherwise sets the tag of the statement on routeInfo. Withdrawals are never
+   filtered so that a route redistributed before the statement tags changed is taken back.
+*/
+func policyStmtRouteTagApply(stmtName string, evt int, routeInfo *RouteParams) bool {
+	info, ok := PolicyStmtRouteTagMap[stmtName]
+	if !ok {
+		return true
+	}
+	if evt == ribdCommonDefs.NOTIFY_ROUTE_CREATED && !info.permit(routeInfo.routeTag) {
+		return false
+	}
+	if info.setTag != 0 {
+		routeInfo.routeTag = info.setTag
+	}
+	return true
+}
+func (m RIBDServer) GetBulkPolicyStmtRouteTagState(fromIndex ribdInt.Int, rcount ribdInt.Int) (stmts *ribdInt.PolicyStmtRouteTagStateGetInfo, err error) {
+	var returnPolicyStmtRouteTagGetInfo ribdInt.PolicyStmtRouteTagStateGetInfo
+	stmts = &returnPolicyStmtRouteTagGetInfo
+	names := make([]string, 0)
+	for name, _ := range PolicyStmtRouteTagMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	stmtStates := make([]*ribdInt.PolicyStmtRouteTag, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(names)) && ribdInt.Int(len(stmtStates)) < rcount; i++ {
+		stmtState := PolicyStmtRouteTagMap[names[i]].cfg
+		stmtStates = append(stmtStates, &stmtState)
+	}
+	stmts.PolicyStmtRouteTagList = stmtStates
+	stmts.StartIdx = fromIndex
+	stmts.EndIdx = i
+	stmts.More = i < ribdInt.Int(len(names))
+	stmts.Count = ribdInt.Int(len(stmtStates))
+	return stmts, err
+}
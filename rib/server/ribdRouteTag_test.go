@@ -0,0 +1,125 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"ribd"
+	"ribdInt"
+	"testing"
+)
+
+var routeTagStmtList []*ribdInt.PolicyStmtRouteTag
+
+func InitRouteTagStmtList() {
+	routeTagStmtList = make([]*ribdInt.PolicyStmtRouteTag, 0)
+	//ospf externals are tagged 200 on their way into bgp, bgp does not send them back
+	routeTagStmtList = append(routeTagStmtList, &ribdInt.PolicyStmtRouteTag{
+		Statement:           "RouteTagOspfToBgpStmt",
+		ExcludeRouteTagList: []int64{100},
+		SetRouteTag:         200,
+	})
+	routeTagStmtList = append(routeTagStmtList, &ribdInt.PolicyStmtRouteTag{
+		Statement:         "RouteTagBgpToOspfStmt",
+		MatchRouteTagList: []int64{0, 300},
+	})
+	//invalid tag
+	routeTagStmtList = append(routeTagStmtList, &ribdInt.PolicyStmtRouteTag{
+		Statement:   "RouteTagBgpToOspfStmt",
+		SetRouteTag: MaxRouteTag + 1,
+	})
+	//tag both matched and excluded
+	routeTagStmtList = append(routeTagStmtList, &ribdInt.PolicyStmtRouteTag{
+		Statement:           "RouteTagBgpToOspfStmt",
+		MatchRouteTagList:   []int64{300},
+		ExcludeRouteTagList: []int64{300},
+	})
+	//statement not defined
+	routeTagStmtList = append(routeTagStmtList, &ribdInt.PolicyStmtRouteTag{
+		Statement:   "RouteTagUndefinedStmt",
+		SetRouteTag: 400,
+	})
+}
+func printPolicyStmtRouteTagApply(stmtName string, evt int, routeTag uint32) {
+	routeInfo := RouteParams{destNetIp: "80.1.1.0", networkMask: "255.255.255.0", routeTag: routeTag}
+	permit := policyStmtRouteTagApply(stmtName, evt, &routeInfo)
+	fmt.Println("stmt:", stmtName, " evt:", evt, " route tag:", routeTag, " permit:", permit, " redistributed tag:", routeInfo.routeTag)
+}
+func TestInitRouteTagTestServer(t *testing.T) {
+	fmt.Println("****Init RouteTag Test Server****")
+	StartTestServer()
+	InitRouteTagStmtList()
+	for _, stmt := range []string{"RouteTagOspfToBgpStmt", "RouteTagBgpToOspfStmt"} {
+		err := server.ProcessPolicyStmtConfigCreate(&ribd.PolicyStmt{Name: stmt, MatchConditions: "all"}, server.GlobalPolicyEngineDB)
+		fmt.Println("err:", err, " for stmt:", stmt)
+	}
+	fmt.Println("****************")
+}
+func TestRouteTagValidation(t *testing.T) {
+	fmt.Println("****TestRouteTagValidation****")
+	for _, tag := range []int64{0, 100, MaxRouteTag, MaxRouteTag + 1, -1} {
+		fmt.Println("tag:", tag, " err:", validateRouteTag(tag))
+	}
+	fmt.Println("****************")
+}
+func TestPolicyStmtRouteTagCreate(t *testing.T) {
+	fmt.Println("****TestPolicyStmtRouteTagCreate****")
+	for _, cfg := range routeTagStmtList {
+		err := server.PolicyStmtRouteTagConfigValidationCheck(cfg, "add")
+		fmt.Println("stmt:", cfg.Statement, " validation err:", err)
+		if err == nil {
+			server.ProcessPolicyStmtRouteTagCreateConfig(cfg)
+		}
+	}
+	stmts, _ := server.GetBulkPolicyStmtRouteTagState(0, 10)
+	for _, stmt := range stmts.PolicyStmtRouteTagList {
+		fmt.Println("stmt:", stmt.Statement, " match:", stmt.MatchRouteTagList, " exclude:", stmt.ExcludeRouteTagList, " set tag:", stmt.SetRouteTag)
+	}
+	fmt.Println("****************")
+}
+func TestPolicyStmtRouteTagApply(t *testing.T) {
+	fmt.Println("****TestPolicyStmtRouteTagApply****")
+	printPolicyStmtRouteTagApply("RouteTagOspfToBgpStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 0)
+	printPolicyStmtRouteTagApply("RouteTagOspfToBgpStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 100)
+	printPolicyStmtRouteTagApply("RouteTagOspfToBgpStmt", ribdCommonDefs.NOTIFY_ROUTE_DELETED, 100)
+	printPolicyStmtRouteTagApply("RouteTagBgpToOspfStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 0)
+	printPolicyStmtRouteTagApply("RouteTagBgpToOspfStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 200)
+	printPolicyStmtRouteTagApply("RouteTagBgpToOspfStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 300)
+	printPolicyStmtRouteTagApply("redistStaticStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 200)
+	fmt.Println("****************")
+}
+func TestPolicyStmtRouteTagDelete(t *testing.T) {
+	fmt.Println("****TestPolicyStmtRouteTagDelete****")
+	for _, cfg := range routeTagStmtList {
+		err := server.PolicyStmtRouteTagConfigValidationCheck(cfg, "del")
+		fmt.Println("stmt:", cfg.Statement, " validation err:", err)
+		if err == nil {
+			server.ProcessPolicyStmtRouteTagDeleteConfig(cfg)
+		}
+	}
+	fmt.Println("statements with route tags left:", len(PolicyStmtRouteTagMap))
+	printPolicyStmtRouteTagApply("RouteTagOspfToBgpStmt", ribdCommonDefs.NOTIFY_ROUTE_CREATED, 100)
+	fmt.Println("****************")
+}
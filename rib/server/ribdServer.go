@@ -24,11 +24,11 @@
 package server
 
 import (
-	"asicd/asicdCommonDefs"
 	"asicdServices"
 	//	"database/sql"
 	"fmt"
 	"github.com/op/go-nanomsg"
+	"l3/bfd/bfddCommonDefs"
 	//"l3/rib/ribdCommonDefs"
 	"net"
 	//	"os"
@@ -80,6 +80,8 @@ type RIBDServer struct {
 	AsicdRouteCh         chan RIBdServerConfig
 	ArpdRouteCh          chan RIBdServerConfig
 	NotificationChannel  chan NotificationMsg
+	RouteSubscriptionCh  chan []byte
+	FibAuditRestartCh    chan bool
 	NextHopInfoMap       map[NextHopInfoKey]NextHopInfo
 	/*PolicyConditionConfCh  chan RIBdServerConfig
 	PolicyActionConfCh     chan RIBdServerConfig
@@ -122,6 +124,7 @@ const (
 )
 const (
 	SUB_ASICD = 0
+	SUB_BFDD  = 1
 )
 
 type localDB struct {
@@ -129,6 +132,7 @@ type localDB struct {
 	isValid    bool
 	precedence int
 	nextHopIp  string
+	vrf        string
 }
 type IntfEntry struct {
 	name string
@@ -138,6 +142,7 @@ var count int
 var ConnectedRoutes []*ribdInt.Routes
 var logger *logging.Writer
 var AsicdSub *nanomsg.SubSocket
+var BfddSub *nanomsg.SubSocket
 var RouteServiceHandler *RIBDServer
 var IntfIdNameMap map[int32]IntfEntry
 var IfNameToIfIndex map[string]int32
@@ -480,7 +485,7 @@ func getIntfInfo() {
 func (ribdServiceHandler *RIBDServer) AcceptConfigActions() {
 	logger.Info("AcceptConfigActions: Setting AcceptConfig to true")
 	RouteServiceHandler.AcceptConfig = true
-	getIntfInfo()
+	fibProgrammer.GetIntfInfo()
 	logger.Info("adding fetchv4 to asicdroutech")
 	ribdServiceHandler.AsicdRouteCh <- RIBdServerConfig{Op: "fetchv4"}
 	v4IntfsGetDone := <-ribdServiceHandler.V4IntfsGetDone
@@ -499,7 +504,14 @@ func (ribdServiceHandler *RIBDServer) AcceptConfigActions() {
 	if dbRead != true {
 		logger.Err("DB read failed")
 	}
-	go ribdServiceHandler.SetupEventHandler(AsicdSub, asicdCommonDefs.PUB_SOCKET_ADDR, SUB_ASICD)
+	fibProgrammer.InitialRouteSyncDone()
+	fibProgrammer.StartIntfEventListener(ribdServiceHandler)
+	go ribdServiceHandler.SetupEventHandler(BfddSub, bfddCommonDefs.PUB_SOCKET_ADDR, SUB_BFDD)
+	//the fib may have drifted while ribd or asicd was down
+	select {
+	case ribdServiceHandler.FibAuditRestartCh <- true:
+	default:
+	}
 	logger.Info("All set to signal start the RIBd server")
 	ribdServiceHandler.ServerUpCh <- true
 }
@@ -550,7 +562,9 @@ func NewRIBDServicesHandler(dbHdl *dbutils.DBUtil, loggerC *logging.Writer) *RIB
 	ribdServicesHandler := &RIBDServer{}
 	ribdServicesHandler.Logger = loggerC
 	logger = loggerC
-	localRouteEventsDB = make([]RouteEventInfo, 0)
+	ribdServicesHandler.RouteSubscriptionCh = make(chan []byte, RouteSubscriptionChSize)
+	localRouteEventsDB = newRouteEventJournal(DefaultRouteEventJournalSize, ribdServicesHandler.RouteSubscriptionCh)
+	ribdServicesHandler.FibAuditRestartCh = make(chan bool, 1)
 	RedistributeRouteMap = make(map[string][]RedistributeRouteInfo)
 	ribdServicesHandler.Clients = make(map[string]ClientIf)
 	TrackReachabilityMap = make(map[string][]string)
@@ -583,6 +597,7 @@ func NewRIBDServicesHandler(dbHdl *dbutils.DBUtil, loggerC *logging.Writer) *RIB
 	//ribdServicesHandler.RouteInstallCh = make(chan RouteParams)
 	BuildRouteProtocolTypeMapDB()
 	BuildProtocolAdminDistanceMapDB()
+	InitVrfDB()
 	BuildPublisherMap()
 	PolicyEngineDB = ribdServicesHandler.InitializePolicyDB()
 	GlobalPolicyEngineDB = ribdServicesHandler.InitializeGlobalPolicyDB()
@@ -598,6 +613,8 @@ func (s *RIBDServer) InitServer() {
 	go s.StartDBServer()
 	go s.StartPolicyServer()
 	go s.NotificationServer()
+	go s.RouteSubscriptionServer()
+	go s.FibAuditServer()
 	go s.StartAsicdServer()
 	go s.StartArpdServer()
 
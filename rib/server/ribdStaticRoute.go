@@ -0,0 +1,939 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdStaticRoute.go
+package server
+
+import (
+	"bfdd"
+	"errors"
+	"fmt"
+	"l3/bfd/bfddCommonDefs"
+	"l3/rib/ribdCommonDefs"
+	"net"
+	"ribd"
+	"ribdInt"
+	"sort"
+	"strconv"
+	"strings"
+	"utils/patriciaDB"
+)
+
+const (
+	TrackObjectTypePrefix    = "prefix"
+	TrackObjectTypeInterface = "interface"
+	MaxStaticRouteDistance   = 255
+)
+
+/*
+   Next hop of a static route along with the liveness state used to decide if it can be installed
+*/
+type StaticRouteNextHopInfo struct {
+	nextHopIp        string
+	nextHopIntRef    string
+	nextHopIfIndex   int32 //-1 when the next hop is resolved recursively
+	weight           int32
+	bfd              bool
+	bfdSessionParam  string
+	trackObject      string
+	isResolved       bool
+	resolvedIntf     ribdInt.NextHopInfo
+	resolvedIfIndex  int32
+	installed        bool
+	installedIfIndex int32
+}
+
+/*
+   Static routes to the same prefix are kept in the order of their distance, the routes
+   with higher distance are floating statics and only installed when all the better ones fail
+*/
+type StaticRouteInfo struct {
+	key         string
+	prefixKey   string
+	prefix      patriciaDB.Prefix
+	destNetIp   string
+	networkMask string
+	ipType      ribdCommonDefs.IPType
+	vrf         string
+	cost        int32
+	distance    int32
+	routeTag    uint32
+	nextHops    []*StaticRouteNextHopInfo
+}
+type TrackObjectInfo struct {
+	name    string
+	objType string
+	vrf     string
+	prefix  string //cidr, for prefix track objects
+	ifIndex int32  //for interface track objects
+	isUp    bool
+}
+type TrackIntfStateInfo struct {
+	ifIndex int32
+	isUp    bool
+}
+
+var StaticRouteMap = make(map[string]*StaticRouteInfo)         //route key -> static route
+var StaticRoutePrefixMap = make(map[string][]*StaticRouteInfo) //prefix key -> static routes sorted by distance
+var TrackObjectMap = make(map[string]*TrackObjectInfo)         //name -> track object
+var TrackIntfStateMap = make(map[int32]bool)                   //ifIndex -> oper state
+var StaticBfdSessionMap = make(map[string]int)                 //next hop ip -> number of static next hops using the session
+var StaticBfdStateMap = make(map[string]bool)                  //next hop ip -> bfd session state
+var StaticRouteResolveMap = make(map[string]map[string]int)    //resolve key -> prefix keys of the static routes with a next hop in it
+var TrackObjectResolveMap = make(map[string]map[string]bool)   //resolve key -> names of the prefix track objects on it
+var TrackObjectRouteMap = make(map[string]map[string]int)      //track object name -> prefix keys of the static routes tracking it
+var staticRouteEvalPending = make(map[string]bool)
+var trackObjectEvalPending = make(map[string]bool)
+var staticRouteEvalQueued = false
+
+type staticRouteDistanceList []*StaticRouteInfo
+
+func (routes staticRouteDistanceList) Len() int      { return len(routes) }
+func (routes staticRouteDistanceList) Swap(i, j int) { routes[i], routes[j] = routes[j], routes[i] }
+func (routes staticRouteDistanceList) Less(i, j int) bool {
+	return routes[i].distance < routes[j].distance
+}
+
+func getStaticRouteDistance(distance int32) int32 {
+	if distance != 0 {
+		return distance
+	}
+	if routeDistanceConfig, ok := ProtocolAdminDistanceMapDB["STATIC"]; ok {
+		if routeDistanceConfig.configuredDistance > 0 {
+			return int32(routeDistanceConfig.configuredDistance)
+		}
+		return int32(routeDistanceConfig.defaultDistance)
+	}
+	return 1
+}
+
+/*
+   Converts the destination in CIDR notation to ip and mask strings and returns the prefix key
+*/
+func getStaticRouteDest(cfg *ribdInt.StaticRoute) (ipType ribdCommonDefs.IPType, prefixKey string, err error) {
+	if strings.Contains(cfg.DestinationNw, "/") {
+		ip, ipNet, err := net.ParseCIDR(cfg.DestinationNw)
+		if err != nil {
+			return ipType, prefixKey, errors.New("Invalid destination ip address")
+		}
+		cfg.DestinationNw = ip.String()
+		cfg.NetworkMask = net.IP(ipNet.Mask).String()
+	}
+	ipType = ribdCommonDefs.IPv4
+	destNetIp := net.ParseIP(cfg.DestinationNw)
+	if destNetIp == nil {
+		return ipType, prefixKey, errors.New(fmt.Sprintln("Invalid destination ip address ", cfg.DestinationNw))
+	}
+	if destNetIp.To4() == nil {
+		ipType = ribdCommonDefs.IPv6
+	}
+	_, err = validateNetworkPrefix(cfg.DestinationNw, cfg.NetworkMask)
+	if err != nil {
+		return ipType, prefixKey, err
+	}
+	destNetIpAddr, _ := getIP(cfg.DestinationNw)
+	networkMaskAddr, _ := getIP(cfg.NetworkMask)
+	_, nwAddr, err := getNetworkPrefix(destNetIpAddr, networkMaskAddr)
+	if err != nil {
+		return ipType, prefixKey, err
+	}
+	prefixKey = getVrfName(cfg.Vrf) + "#" + nwAddr
+	return ipType, prefixKey, nil
+}
+func getStaticRouteKey(prefixKey string, distance int32) string {
+	return prefixKey + "#" + strconv.Itoa(int(distance))
+}
+
+/*
+   This function performs config parameters validation for static route create/delete operation.
+	Key validations performed by this function include:
+	   - Validate destinationNw, if provided in CIDR notation convert to ip addr and mask values
+	   - Next hops need not be reachable now, they are resolved as routes come and go
+	   - Referenced interfaces and track objects must exist
+*/
+func (m RIBDServer) StaticRouteConfigValidationCheck(cfg *ribdInt.StaticRoute, op string) (err error) {
+	ipType, prefixKey, err := getStaticRouteDest(cfg)
+	if err != nil {
+		logger.Err("StaticRouteConfigValidationCheck for route:", cfg, " invalid destination, err:", err)
+		return err
+	}
+	if getVrfInfo(getVrfName(cfg.Vrf)) == nil {
+		return errors.New(fmt.Sprintln("vrf ", cfg.Vrf, " not found"))
+	}
+	if cfg.Distance < 0 || cfg.Distance > MaxStaticRouteDistance {
+		return errors.New(fmt.Sprintln("Invalid distance ", cfg.Distance))
+	}
+	if err = validateRouteTag(cfg.RouteTag); err != nil {
+		return err
+	}
+	key := getStaticRouteKey(prefixKey, getStaticRouteDistance(cfg.Distance))
+	_, exists := StaticRouteMap[key]
+	if op == "del" {
+		if !exists {
+			return errors.New(fmt.Sprintln("static route ", key, " not configured"))
+		}
+		return nil
+	}
+	if exists {
+		return errors.New(fmt.Sprintln("Duplicate static route ", key))
+	}
+	if len(cfg.NextHopList) == 0 {
+		return errors.New("Next hop ip not specified")
+	}
+	_, destNet, _ := net.ParseCIDR(strings.Split(prefixKey, "#")[1])
+	for _, nextHop := range cfg.NextHopList {
+		nextHopIp := net.ParseIP(nextHop.NextHopIp)
+		if nextHopIp == nil {
+			return errors.New(fmt.Sprintln("Invalid next hop ip address ", nextHop.NextHopIp))
+		}
+		if (nextHopIp.To4() == nil) != (ipType == ribdCommonDefs.IPv6) {
+			return errors.New(fmt.Sprintln("Next hop ", nextHop.NextHopIp, " address family does not match the destination"))
+		}
+		if destNet != nil && destNet.IP.Equal(nextHopIp) {
+			return errors.New(fmt.Sprintln("Next hop ", nextHop.NextHopIp, " is the destination network address"))
+		}
+		if nextHop.NextHopIntRef != "" {
+			_, err = m.ConvertIntfStrToIfIndexStr(nextHop.NextHopIntRef)
+			if err != nil {
+				return errors.New(fmt.Sprintln("Invalid next hop interface ", nextHop.NextHopIntRef))
+			}
+		}
+		if nextHop.TrackObject != "" {
+			if _, ok := TrackObjectMap[nextHop.TrackObject]; !ok {
+				return errors.New(fmt.Sprintln("track object ", nextHop.TrackObject, " not configured"))
+			}
+		}
+		if nextHop.Bfd && ipType == ribdCommonDefs.IPv6 {
+			return errors.New("bfd is only supported for ipv4 static next hops")
+		}
+	}
+	return nil
+}
+func (m RIBDServer) ProcessStaticRouteCreateConfig(cfg *ribdInt.StaticRoute) (val bool, err error) {
+	logger.Info("ProcessStaticRouteCreateConfig for ", cfg.DestinationNw, ":", cfg.NetworkMask, " vrf:", cfg.Vrf, " distance:", cfg.Distance)
+	ipType, prefixKey, err := getStaticRouteDest(cfg)
+	if err != nil {
+		return false, err
+	}
+	distance := getStaticRouteDistance(cfg.Distance)
+	prefix, err := getNetowrkPrefixFromStrings(cfg.DestinationNw, cfg.NetworkMask)
+	if err != nil {
+		return false, err
+	}
+	route := &StaticRouteInfo{
+		key:         getStaticRouteKey(prefixKey, distance),
+		prefixKey:   prefixKey,
+		prefix:      prefix,
+		destNetIp:   cfg.DestinationNw,
+		networkMask: cfg.NetworkMask,
+		ipType:      ipType,
+		vrf:         getVrfName(cfg.Vrf),
+		cost:        cfg.Cost,
+		distance:    distance,
+		routeTag:    uint32(cfg.RouteTag),
+		nextHops:    make([]*StaticRouteNextHopInfo, 0),
+	}
+	if _, ok := StaticRouteMap[route.key]; ok {
+		return false, errors.New(fmt.Sprintln("Duplicate static route ", route.key))
+	}
+	for _, nextHop := range cfg.NextHopList {
+		nextHopInfo := &StaticRouteNextHopInfo{
+			nextHopIp:       nextHop.NextHopIp,
+			nextHopIntRef:   nextHop.NextHopIntRef,
+			nextHopIfIndex:  -1,
+			weight:          nextHop.Weight,
+			bfd:             nextHop.Bfd,
+			bfdSessionParam: nextHop.BfdSessionParam,
+			trackObject:     nextHop.TrackObject,
+		}
+		if nextHop.NextHopIntRef != "" {
+			ifIndexStr, err := m.ConvertIntfStrToIfIndexStr(nextHop.NextHopIntRef)
+			if err != nil {
+				return false, err
+			}
+			ifIndex, _ := strconv.Atoi(ifIndexStr)
+			nextHopInfo.nextHopIfIndex = int32(ifIndex)
+		}
+		if nextHopInfo.bfd {
+			staticBfdSessionCreate(nextHopInfo)
+		}
+		route.nextHops = append(route.nextHops, nextHopInfo)
+	}
+	StaticRouteMap[route.key] = route
+	staticRouteIndexUpdate(route, true)
+	routes := append(StaticRoutePrefixMap[prefixKey], route)
+	sort.Sort(staticRouteDistanceList(routes))
+	StaticRoutePrefixMap[prefixKey] = routes
+	evaluateStaticRoutePrefix(prefixKey)
+	return true, nil
+}
+func (m RIBDServer) ProcessStaticRouteDeleteConfig(cfg *ribdInt.StaticRoute) (val bool, err error) {
+	logger.Info("ProcessStaticRouteDeleteConfig for ", cfg.DestinationNw, ":", cfg.NetworkMask, " vrf:", cfg.Vrf, " distance:", cfg.Distance)
+	_, prefixKey, err := getStaticRouteDest(cfg)
+	if err != nil {
+		return false, err
+	}
+	key := getStaticRouteKey(prefixKey, getStaticRouteDistance(cfg.Distance))
+	route, ok := StaticRouteMap[key]
+	if !ok {
+		return false, errors.New(fmt.Sprintln("static route ", key, " not configured"))
+	}
+	for _, nextHop := range route.nextHops {
+		if nextHop.installed {
+			staticRouteNextHopWithdraw(route, nextHop)
+		}
+		if nextHop.bfd {
+			staticBfdSessionDelete(nextHop)
+		}
+	}
+	delete(StaticRouteMap, key)
+	staticRouteIndexUpdate(route, false)
+	routes := StaticRoutePrefixMap[prefixKey]
+	for idx, r := range routes {
+		if r == route {
+			routes = append(routes[:idx], routes[idx+1:]...)
+			break
+		}
+	}
+	if len(routes) == 0 {
+		delete(StaticRoutePrefixMap, prefixKey)
+	} else {
+		StaticRoutePrefixMap[prefixKey] = routes
+		//a floating static may take over now
+		evaluateStaticRoutePrefix(prefixKey)
+	}
+	return true, nil
+}
+
+/*
+   Checks if the network the next hop of route was resolved through is reachable. A static route
+   is never resolved through itself.
+*/
+func staticNextHopNetworkReachable(route *StaticRouteInfo, nhIntf ribdInt.NextHopInfo) bool {
+	vrf, ipType := route.vrf, route.ipType
+	prefix, err := getNetowrkPrefixFromStrings(nhIntf.Ipaddr, nhIntf.Mask)
+	if err != nil || (string(prefix) == string(route.prefix) && nhIntf.Mask == route.networkMask) {
+		return false
+	}
+	item := RouteInfoMapGet(vrf, ipType, prefix)
+	if item == nil {
+		return false
+	}
+	routeInfoRecordList := item.(RouteInfoRecordList)
+	for _, routeInfoRecord := range routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol] {
+		if routeInfoRecord.resolvedNextHopIpIntf.IsReachable {
+			return true
+		}
+	}
+	return false
+}
+func (nextHop *StaticRouteNextHopInfo) resolve(route *StaticRouteInfo) {
+	nextHop.isResolved = false
+	if nextHop.nextHopIfIndex != -1 {
+		//next hop configured with an interface has to be directly reachable over it
+		nhIntf, err := RouteServiceHandler.GetVrfRouteReachabilityInfo(route.vrf, nextHop.nextHopIp, ribdInt.Int(nextHop.nextHopIfIndex))
+		if err != nil || nhIntf == nil {
+			return
+		}
+		nextHop.resolvedIntf = *nhIntf
+		nextHop.resolvedIfIndex = nextHop.nextHopIfIndex
+		nextHop.isResolved = staticNextHopNetworkReachable(route, *nhIntf)
+		return
+	}
+	nhIntf, resolvedIntf, err := ResolveVrfNextHop(route.vrf, nextHop.nextHopIp)
+	if err != nil {
+		return
+	}
+	nextHop.resolvedIntf = resolvedIntf
+	nextHop.resolvedIfIndex = int32(nhIntf.NextHopIfIndex)
+	nextHop.isResolved = staticNextHopNetworkReachable(route, nhIntf)
+}
+func (nextHop *StaticRouteNextHopInfo) isUsable() bool {
+	if !nextHop.isResolved {
+		return false
+	}
+	if nextHop.bfd && !StaticBfdStateMap[nextHop.nextHopIp] {
+		return false
+	}
+	if nextHop.trackObject != "" {
+		trackObject, ok := TrackObjectMap[nextHop.trackObject]
+		if !ok || !trackObject.isUp {
+			return false
+		}
+	}
+	return true
+}
+func staticRouteNextHopInstall(route *StaticRouteInfo, nextHop *StaticRouteNextHopInfo) {
+	logger.Info("Installing static route ", route.key, " via ", nextHop.nextHopIp)
+	params := RouteParams{
+		ipType:         route.ipType,
+		destNetIp:      route.destNetIp,
+		networkMask:    route.networkMask,
+		nextHopIp:      nextHop.nextHopIp,
+		nextHopIfIndex: ribd.Int(nextHop.resolvedIfIndex),
+		weight:         ribd.Int(nextHop.weight),
+		metric:         ribd.Int(route.cost),
+		routeType:      ribd.Int(ribdCommonDefs.STATIC),
+		sliceIdx:       ribd.Int(len(destNetSlice)),
+		createType:     FIBAndRIB,
+		deleteType:     Invalid,
+		vrf:            route.vrf,
+		routeTag:       route.routeTag,
+	}
+	_, err := createRoute(params)
+	if err != nil {
+		logger.Err("Failed to install static route ", route.key, " via ", nextHop.nextHopIp, " err:", err)
+		return
+	}
+	nextHop.installed = true
+	nextHop.installedIfIndex = nextHop.resolvedIfIndex
+}
+func staticRouteNextHopWithdraw(route *StaticRouteInfo, nextHop *StaticRouteNextHopInfo) {
+	logger.Info("Withdrawing static route ", route.key, " via ", nextHop.nextHopIp)
+	_, err := deleteIPRoute(route.vrf, route.destNetIp, route.ipType, route.networkMask, "STATIC", nextHop.nextHopIp, ribd.Int(nextHop.installedIfIndex), FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
+	if err != nil {
+		logger.Err("Failed to withdraw static route ", route.key, " via ", nextHop.nextHopIp, " err:", err)
+	}
+	nextHop.installed = false
+}
+
+/*
+   Install the usable next hops of the best static route to the prefix and withdraw
+   the rest, this is where a floating static takes over or gives way
+*/
+func evaluateStaticRoutePrefix(prefixKey string) {
+	routes := StaticRoutePrefixMap[prefixKey]
+	var activeRoute *StaticRouteInfo
+	for _, route := range routes {
+		for _, nextHop := range route.nextHops {
+			nextHop.resolve(route)
+			if activeRoute == nil && nextHop.isUsable() {
+				activeRoute = route
+			}
+		}
+	}
+	for _, route := range routes {
+		for _, nextHop := range route.nextHops {
+			if nextHop.installed && (route != activeRoute || !nextHop.isUsable()) {
+				staticRouteNextHopWithdraw(route, nextHop)
+			}
+		}
+	}
+	if activeRoute == nil {
+		logger.Debug("No usable static route to ", prefixKey)
+		return
+	}
+	for _, nextHop := range activeRoute.nextHops {
+		if !nextHop.installed && nextHop.isUsable() {
+			staticRouteNextHopInstall(activeRoute, nextHop)
+		}
+	}
+}
+
+/*
+   Coalesce evaluations requested while processing a route change, they are run
+   once the current config message is done
+*/
+func queueStaticRouteEval(prefixKey string, trackObject string) {
+	if prefixKey != "" {
+		staticRouteEvalPending[prefixKey] = true
+	}
+	if trackObject != "" {
+		trackObjectEvalPending[trackObject] = true
+	}
+	if staticRouteEvalQueued {
+		return
+	}
+	staticRouteEvalQueued = true
+	RouteServiceHandler.RouteConfCh <- RIBdServerConfig{Op: "evalStaticRoutes"}
+}
+
+/*
+   The static routes are indexed by every prefix their next hops may resolve through, so a
+   route table change only looks up the routes that depend on the changed prefix. Prefixes
+   only carry the bytes covering the mask, the resolve key of a prefix is the one it is
+   stored under in the route table.
+*/
+func staticRouteResolveKey(vrf string, ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix) string {
+	return fmt.Sprint(getVrfName(vrf), "#", ipType, "#", string(prefix))
+}
+func staticRouteResolveKeys(vrf string, ipType ribdCommonDefs.IPType, ipAddr string) []string {
+	keys := make([]string, 0)
+	ip := net.ParseIP(ipAddr)
+	if ip == nil {
+		return keys
+	}
+	bits := 128
+	if ipType == ribdCommonDefs.IPv4 {
+		ip = ip.To4()
+		bits = 32
+	}
+	if ip == nil {
+		return keys
+	}
+	keyMap := make(map[string]bool)
+	for maskLen := 0; maskLen <= bits; maskLen++ {
+		netIp := ip.Mask(net.CIDRMask(maskLen, bits))
+		key := staticRouteResolveKey(vrf, ipType, patriciaDB.Prefix(netIp[:(maskLen+7)/8]))
+		if !keyMap[key] {
+			keyMap[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+func staticRouteIndexUpdate(route *StaticRouteInfo, add bool) {
+	for _, nextHop := range route.nextHops {
+		for _, key := range staticRouteResolveKeys(route.vrf, route.ipType, nextHop.nextHopIp) {
+			StaticRouteResolveMap[key] = staticRouteIndexRef(StaticRouteResolveMap[key], route.prefixKey, add)
+			if len(StaticRouteResolveMap[key]) == 0 {
+				delete(StaticRouteResolveMap, key)
+			}
+		}
+		if nextHop.trackObject != "" {
+			TrackObjectRouteMap[nextHop.trackObject] = staticRouteIndexRef(TrackObjectRouteMap[nextHop.trackObject], route.prefixKey, add)
+			if len(TrackObjectRouteMap[nextHop.trackObject]) == 0 {
+				delete(TrackObjectRouteMap, nextHop.trackObject)
+			}
+		}
+	}
+}
+func staticRouteIndexRef(prefixKeys map[string]int, prefixKey string, add bool) map[string]int {
+	if prefixKeys == nil {
+		prefixKeys = make(map[string]int)
+	}
+	if add {
+		prefixKeys[prefixKey]++
+		return prefixKeys
+	}
+	prefixKeys[prefixKey]--
+	if prefixKeys[prefixKey] <= 0 {
+		delete(prefixKeys, prefixKey)
+	}
+	return prefixKeys
+}
+func trackObjectResolveKey(trackObject *TrackObjectInfo) (key string, err error) {
+	ip, ipNet, err := net.ParseCIDR(trackObject.prefix)
+	if err != nil {
+		return key, err
+	}
+	ipType := ribdCommonDefs.IPv4
+	if ip.To4() == nil {
+		ipType = ribdCommonDefs.IPv6
+	}
+	prefix, err := getNetowrkPrefixFromStrings(ip.String(), net.IP(ipNet.Mask).String())
+	if err != nil {
+		return key, err
+	}
+	return staticRouteResolveKey(trackObject.vrf, ipType, prefix), nil
+}
+
+/*
+   Route table of vrf changed at prefix, queue the static routes and track objects that depend on it
+*/
+func staticRouteRibChange(vrf string, ipType ribdCommonDefs.IPType, prefix patriciaDB.Prefix) {
+	if len(StaticRouteResolveMap) == 0 && len(TrackObjectResolveMap) == 0 {
+		return
+	}
+	key := staticRouteResolveKey(vrf, ipType, prefix)
+	for prefixKey, _ := range StaticRouteResolveMap[key] {
+		if staticRouteEvalPending[prefixKey] {
+			continue
+		}
+		if routes := StaticRoutePrefixMap[prefixKey]; len(routes) > 0 && string(routes[0].prefix) == string(prefix) {
+			//install or withdraw of the static route itself
+			continue
+		}
+		queueStaticRouteEval(prefixKey, "")
+	}
+	for name, _ := range TrackObjectResolveMap[key] {
+		queueStaticRouteEval("", name)
+	}
+}
+func (m RIBDServer) ProcessStaticRouteEval() {
+	staticRouteEvalQueued = false
+	for name, _ := range trackObjectEvalPending {
+		delete(trackObjectEvalPending, name)
+		trackObjectEvaluate(name)
+	}
+	for prefixKey, _ := range staticRouteEvalPending {
+		delete(staticRouteEvalPending, prefixKey)
+		evaluateStaticRoutePrefix(prefixKey)
+	}
+}
+
+/*
+   Track objects
+*/
+func (m RIBDServer) TrackObjectConfigValidationCheck(cfg *ribdInt.TrackObject, op string) (err error) {
+	if cfg.Name == "" {
+		return errors.New("Track object name not specified")
+	}
+	_, exists := TrackObjectMap[cfg.Name]
+	if op == "del" {
+		if !exists {
+			return errors.New(fmt.Sprintln("track object ", cfg.Name, " not configured"))
+		}
+		if len(TrackObjectRouteMap[cfg.Name]) > 0 {
+			return errors.New(fmt.Sprintln("track object ", cfg.Name, " in use by static routes"))
+		}
+		return nil
+	}
+	if exists {
+		return errors.New(fmt.Sprintln("Duplicate track object ", cfg.Name))
+	}
+	switch cfg.Type {
+	case TrackObjectTypePrefix:
+		if _, _, err = net.ParseCIDR(cfg.Prefix); err != nil {
+			return errors.New(fmt.Sprintln("Invalid prefix ", cfg.Prefix))
+		}
+		if getVrfInfo(getVrfName(cfg.Vrf)) == nil {
+			return errors.New(fmt.Sprintln("vrf ", cfg.Vrf, " not found"))
+		}
+	case TrackObjectTypeInterface:
+		if _, err = m.ConvertIntfStrToIfIndexStr(cfg.IntfRef); err != nil {
+			return errors.New(fmt.Sprintln("Invalid interface ", cfg.IntfRef))
+		}
+	default:
+		return errors.New(fmt.Sprintln("Invalid track object type ", cfg.Type))
+	}
+	return nil
+}
+func (m RIBDServer) ProcessTrackObjectCreateConfig(cfg *ribdInt.TrackObject) (val bool, err error) {
+	logger.Info("ProcessTrackObjectCreateConfig for ", cfg.Name, " type:", cfg.Type)
+	trackObject := &TrackObjectInfo{
+		name:    cfg.Name,
+		objType: cfg.Type,
+		vrf:     getVrfName(cfg.Vrf),
+		ifIndex: -1,
+	}
+	if cfg.Type == TrackObjectTypePrefix {
+		_, ipNet, err := net.ParseCIDR(cfg.Prefix)
+		if err != nil {
+			return false, err
+		}
+		trackObject.prefix = ipNet.String()
+	} else {
+		ifIndexStr, err := m.ConvertIntfStrToIfIndexStr(cfg.IntfRef)
+		if err != nil {
+			return false, err
+		}
+		ifIndex, _ := strconv.Atoi(ifIndexStr)
+		trackObject.ifIndex = int32(ifIndex)
+	}
+	TrackObjectMap[cfg.Name] = trackObject
+	if trackObject.objType == TrackObjectTypePrefix {
+		if key, err := trackObjectResolveKey(trackObject); err == nil {
+			if TrackObjectResolveMap[key] == nil {
+				TrackObjectResolveMap[key] = make(map[string]bool)
+			}
+			TrackObjectResolveMap[key][trackObject.name] = true
+		}
+	}
+	trackObject.isUp = trackObject.getState()
+	return true, nil
+}
+func (m RIBDServer) ProcessTrackObjectDeleteConfig(cfg *ribdInt.TrackObject) (val bool, err error) {
+	logger.Info("ProcessTrackObjectDeleteConfig for ", cfg.Name)
+	if trackObject, ok := TrackObjectMap[cfg.Name]; ok && trackObject.objType == TrackObjectTypePrefix {
+		if key, err := trackObjectResolveKey(trackObject); err == nil {
+			delete(TrackObjectResolveMap[key], cfg.Name)
+			if len(TrackObjectResolveMap[key]) == 0 {
+				delete(TrackObjectResolveMap, key)
+			}
+		}
+	}
+	delete(TrackObjectMap, cfg.Name)
+	delete(trackObjectEvalPending, cfg.Name)
+	return true, nil
+}
+func getIntfOperState(ifIndex int32) bool {
+	if isUp, ok := TrackIntfStateMap[ifIndex]; ok {
+		return isUp
+	}
+	//no state change seen for the interface yet, it is up if a connected route exists on it
+	for _, connectedRoute := range ConnectedRoutes {
+		if int32(connectedRoute.IfIndex) == ifIndex && connectedRoute.IsValid {
+			return true
+		}
+	}
+	return false
+}
+func (trackObject *TrackObjectInfo) getState() bool {
+	if trackObject.objType == TrackObjectTypeInterface {
+		return getIntfOperState(trackObject.ifIndex)
+	}
+	ip, ipNet, err := net.ParseCIDR(trackObject.prefix)
+	if err != nil {
+		return false
+	}
+	ipType := ribdCommonDefs.IPv4
+	if ip.To4() == nil {
+		ipType = ribdCommonDefs.IPv6
+	}
+	prefix, err := getNetowrkPrefixFromStrings(ip.String(), net.IP(ipNet.Mask).String())
+	if err != nil {
+		return false
+	}
+	item := RouteInfoMapGet(trackObject.vrf, ipType, prefix)
+	if item == nil {
+		return false
+	}
+	routeInfoRecordList := item.(RouteInfoRecordList)
+	for _, routeInfoRecord := range routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol] {
+		if routeInfoRecord.networkAddr == trackObject.prefix && routeInfoRecord.resolvedNextHopIpIntf.IsReachable {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+   Update the state of the track object and re-evaluate the static routes tracking it on a change
+*/
+func trackObjectEvaluate(name string) {
+	trackObject, ok := TrackObjectMap[name]
+	if !ok {
+		return
+	}
+	isUp := trackObject.getState()
+	if isUp == trackObject.isUp {
+		return
+	}
+	logger.Info("Track object ", name, " state changed to up:", isUp)
+	trackObject.isUp = isUp
+	for prefixKey, _ := range TrackObjectRouteMap[name] {
+		staticRouteEvalPending[prefixKey] = true
+	}
+}
+func (m RIBDServer) ProcessTrackIntfStateChange(info TrackIntfStateInfo) {
+	TrackIntfStateMap[info.ifIndex] = info.isUp
+	for _, trackObject := range TrackObjectMap {
+		if trackObject.objType == TrackObjectTypeInterface && trackObject.ifIndex == info.ifIndex {
+			trackObjectEvaluate(trackObject.name)
+		}
+	}
+	for prefixKey, _ := range staticRouteEvalPending {
+		delete(staticRouteEvalPending, prefixKey)
+		evaluateStaticRoutePrefix(prefixKey)
+	}
+	queuePbrEval()
+}
+func (ribdServiceHandler *RIBDServer) TrackIntfStateUpdate(ifIndex int32, isUp bool) {
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: TrackIntfStateInfo{ifIndex, isUp},
+		Op:               "trackIntfState",
+	}
+}
+
+/*
+   BFD sessions for static next hops, registered with bfdd as the ribd owner
+*/
+func staticBfdSessionCreate(nextHop *StaticRouteNextHopInfo) {
+	StaticBfdSessionMap[nextHop.nextHopIp]++
+	if StaticBfdSessionMap[nextHop.nextHopIp] > 1 {
+		return
+	}
+	bfddCreateSession(nextHop.nextHopIp, nextHop.nextHopIntRef, nextHop.bfdSessionParam)
+}
+func staticBfdSessionDelete(nextHop *StaticRouteNextHopInfo) {
+	StaticBfdSessionMap[nextHop.nextHopIp]--
+	if StaticBfdSessionMap[nextHop.nextHopIp] > 0 {
+		return
+	}
+	delete(StaticBfdSessionMap, nextHop.nextHopIp)
+	delete(StaticBfdStateMap, nextHop.nextHopIp)
+	if !bfddclnt.IsConnected {
+		return
+	}
+	bfdSession := bfdd.NewBfdSession()
+	bfdSession.IpAddr = nextHop.nextHopIp
+	bfdSession.Interface = nextHop.nextHopIntRef
+	bfdSession.Owner = bfddCommonDefs.ConvertBfdSessionOwnerValToStr(bfddCommonDefs.RIBD)
+	logger.Info("Deleting BFD Session: ", bfdSession)
+	ret, err := bfddclnt.ClientHdl.DeleteBfdSession(bfdSession)
+	if !ret || err != nil {
+		logger.Err("DeleteBfdSession for ", nextHop.nextHopIp, " failed, err:", err)
+	}
+}
+func bfddCreateSession(ipAddr string, iface string, sessionParam string) {
+	if !bfddclnt.IsConnected {
+		logger.Info("Not connected to bfdd, bfd session for ", ipAddr, " will be created on connect")
+		return
+	}
+	bfdSession := bfdd.NewBfdSession()
+	bfdSession.IpAddr = ipAddr
+	bfdSession.ParamName = sessionParam
+	bfdSession.Interface = iface
+	bfdSession.Owner = bfddCommonDefs.ConvertBfdSessionOwnerValToStr(bfddCommonDefs.RIBD)
+	logger.Info("Creating BFD Session: ", bfdSession)
+	ret, err := bfddclnt.ClientHdl.CreateBfdSession(bfdSession)
+	if !ret || err != nil {
+		logger.Err("CreateBfdSession for ", ipAddr, " failed, err:", err)
+	}
+}
+
+/*
+   bfdd came up, create the sessions for all the bfd enabled static next hops
+*/
+func (m RIBDServer) ProcessStaticBfdSessionRestore() {
+	for _, route := range StaticRouteMap {
+		for _, nextHop := range route.nextHops {
+			if nextHop.bfd {
+				sessionCreated := false
+				for _, r := range StaticRouteMap {
+					if r == route {
+						break
+					}
+					for _, nh := range r.nextHops {
+						if nh.bfd && nh.nextHopIp == nextHop.nextHopIp {
+							sessionCreated = true
+						}
+					}
+				}
+				if !sessionCreated {
+					bfddCreateSession(nextHop.nextHopIp, nextHop.nextHopIntRef, nextHop.bfdSessionParam)
+				}
+			}
+		}
+	}
+}
+func (m RIBDServer) ProcessStaticBfdStateChange(msg bfddCommonDefs.BfddNotifyMsg) {
+	if _, ok := StaticBfdSessionMap[msg.DestIp]; !ok {
+		return
+	}
+	if StaticBfdStateMap[msg.DestIp] == msg.State {
+		return
+	}
+	logger.Info("BFD session to static next hop ", msg.DestIp, " state up:", msg.State)
+	StaticBfdStateMap[msg.DestIp] = msg.State
+	for _, route := range StaticRouteMap {
+		for _, nextHop := range route.nextHops {
+			if nextHop.bfd && nextHop.nextHopIp == msg.DestIp {
+				staticRouteEvalPending[route.prefixKey] = true
+				break
+			}
+		}
+	}
+	for prefixKey, _ := range staticRouteEvalPending {
+		delete(staticRouteEvalPending, prefixKey)
+		evaluateStaticRoutePrefix(prefixKey)
+	}
+}
+
+type staticRouteKeyList []*StaticRouteInfo
+
+func (routes staticRouteKeyList) Len() int           { return len(routes) }
+func (routes staticRouteKeyList) Swap(i, j int)      { routes[i], routes[j] = routes[j], routes[i] }
+func (routes staticRouteKeyList) Less(i, j int) bool { return routes[i].key < routes[j].key }
+
+func (m RIBDServer) GetBulkStaticRouteState(fromIndex ribdInt.Int, rcount ribdInt.Int) (routes *ribdInt.StaticRouteStateGetInfo, err error) {
+	var returnStaticRouteGetInfo ribdInt.StaticRouteStateGetInfo
+	routes = &returnStaticRouteGetInfo
+	routeList := make([]*StaticRouteInfo, 0)
+	for _, route := range StaticRouteMap {
+		routeList = append(routeList, route)
+	}
+	sort.Sort(staticRouteKeyList(routeList))
+	routeStates := make([]*ribdInt.StaticRouteState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(routeList)) && ribdInt.Int(len(routeStates)) < rcount; i++ {
+		route := routeList[i]
+		routeState := &ribdInt.StaticRouteState{
+			DestinationNw: route.destNetIp,
+			NetworkMask:   route.networkMask,
+			Vrf:           route.vrf,
+			Distance:      route.distance,
+			RouteTag:      int64(route.routeTag),
+			NextHopList:   make([]*ribdInt.StaticRouteNextHopState, 0),
+		}
+		for _, nextHop := range route.nextHops {
+			bfdState := "disabled"
+			if nextHop.bfd {
+				bfdState = "down"
+				if StaticBfdStateMap[nextHop.nextHopIp] {
+					bfdState = "up"
+				}
+			}
+			trackState := "none"
+			if trackObject, ok := TrackObjectMap[nextHop.trackObject]; ok {
+				trackState = "down"
+				if trackObject.isUp {
+					trackState = "up"
+				}
+			}
+			if nextHop.installed {
+				routeState.Active = true
+			}
+			routeState.NextHopList = append(routeState.NextHopList, &ribdInt.StaticRouteNextHopState{
+				NextHopIp:         nextHop.nextHopIp,
+				ResolvedNextHopIp: nextHop.resolvedIntf.NextHopIp,
+				IsResolved:        nextHop.isResolved,
+				BfdState:          bfdState,
+				TrackState:        trackState,
+				Installed:         nextHop.installed,
+			})
+		}
+		routeStates = append(routeStates, routeState)
+	}
+	routes.StaticRouteStateList = routeStates
+	routes.StartIdx = fromIndex
+	routes.EndIdx = i
+	routes.More = i < ribdInt.Int(len(routeList))
+	routes.Count = ribdInt.Int(len(routeStates))
+	return routes, err
+}
+func (m RIBDServer) GetBulkTrackObjectState(fromIndex ribdInt.Int, rcount ribdInt.Int) (objs *ribdInt.TrackObjectStateGetInfo, err error) {
+	var returnTrackObjectGetInfo ribdInt.TrackObjectStateGetInfo
+	objs = &returnTrackObjectGetInfo
+	names := make([]string, 0)
+	for name, _ := range TrackObjectMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	trackObjectStates := make([]*ribdInt.TrackObjectState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(names)) && ribdInt.Int(len(trackObjectStates)) < rcount; i++ {
+		trackObject := TrackObjectMap[names[i]]
+		numNextHops := 0
+		for _, route := range StaticRouteMap {
+			for _, nextHop := range route.nextHops {
+				if nextHop.trackObject == trackObject.name {
+					numNextHops++
+				}
+			}
+		}
+		trackObjectStates = append(trackObjectStates, &ribdInt.TrackObjectState{
+			Name:        trackObject.name,
+			Type:        trackObject.objType,
+			IsUp:        trackObject.isUp,
+			NumNextHops: int32(numNextHops),
+		})
+	}
+	objs.TrackObjectStateList = trackObjectStates
+	objs.StartIdx = fromIndex
+	objs.EndIdx = i
+	objs.More = i < ribdInt.Int(len(names))
+	objs.Count = ribdInt.Int(len(trackObjectStates))
+	return objs, err
+}
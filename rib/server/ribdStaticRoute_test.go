@@ -0,0 +1,192 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"l3/bfd/bfddCommonDefs"
+	"l3/rib/ribdCommonDefs"
+	"ribdInt"
+	"testing"
+	"time"
+)
+
+var staticRouteList []*ribdInt.StaticRoute
+var trackObjectList []*ribdInt.TrackObject
+
+func InitStaticRouteList() {
+	trackObjectList = make([]*ribdInt.TrackObject, 0)
+	trackObjectList = append(trackObjectList, &ribdInt.TrackObject{
+		Name:   "uplink",
+		Type:   TrackObjectTypePrefix,
+		Prefix: "11.1.10.0/24",
+	})
+	trackObjectList = append(trackObjectList, &ribdInt.TrackObject{
+		Name:    "lo2",
+		Type:    TrackObjectTypeInterface,
+		IntfRef: "lo2",
+	})
+	staticRouteList = make([]*ribdInt.StaticRoute, 0)
+	staticRouteList = append(staticRouteList, &ribdInt.StaticRoute{
+		DestinationNw: "40.1.0.0/16",
+		NextHopList: []*ribdInt.StaticRouteNextHop{
+			&ribdInt.StaticRouteNextHop{NextHopIp: "11.1.10.2", TrackObject: "uplink"},
+		},
+	})
+	//floating static, only used when the one above is not usable
+	staticRouteList = append(staticRouteList, &ribdInt.StaticRoute{
+		DestinationNw: "40.1.0.0/16",
+		Distance:      200,
+		NextHopList: []*ribdInt.StaticRouteNextHop{
+			&ribdInt.StaticRouteNextHop{NextHopIp: "21.1.10.2", NextHopIntRef: "lo2", TrackObject: "lo2"},
+		},
+	})
+	//recursive static resolved through 40.1.0.0/16
+	staticRouteList = append(staticRouteList, &ribdInt.StaticRoute{
+		DestinationNw: "50.1.0.0",
+		NetworkMask:   "255.255.0.0",
+		Cost:          10,
+		NextHopList: []*ribdInt.StaticRouteNextHop{
+			&ribdInt.StaticRouteNextHop{NextHopIp: "40.1.1.1"},
+		},
+	})
+	staticRouteList = append(staticRouteList, &ribdInt.StaticRoute{
+		DestinationNw: "60.1.0.0/16",
+		RouteTag:      100,
+		NextHopList: []*ribdInt.StaticRouteNextHop{
+			&ribdInt.StaticRouteNextHop{NextHopIp: "11.1.10.3", Bfd: true},
+		},
+	})
+}
+func printStaticRouteState() {
+	routes, _ := server.GetBulkStaticRouteState(0, 100)
+	for _, route := range routes.StaticRouteStateList {
+		fmt.Println("route:", route.DestinationNw, "/", route.NetworkMask, " distance:", route.Distance, " tag:", route.RouteTag, " active:", route.Active)
+		for _, nextHop := range route.NextHopList {
+			fmt.Println("    next hop:", nextHop.NextHopIp, " resolved via:", nextHop.ResolvedNextHopIp, " isResolved:", nextHop.IsResolved,
+				" bfd:", nextHop.BfdState, " track:", nextHop.TrackState, " installed:", nextHop.Installed)
+		}
+	}
+	objs, _ := server.GetBulkTrackObjectState(0, 100)
+	for _, obj := range objs.TrackObjectStateList {
+		fmt.Println("track object:", obj.Name, " type:", obj.Type, " isUp:", obj.IsUp, " next hops:", obj.NumNextHops)
+	}
+}
+func TestInitStaticRouteTestServer(t *testing.T) {
+	fmt.Println("****Init StaticRoute Test Server****")
+	StartTestServer()
+	TestProcessLogicalIntfCreateEvent(t)
+	TestIPv4IntfCreateEvent(t)
+	InitStaticRouteList()
+	fmt.Println("****************")
+}
+func TestStaticRouteConfigValidationCheck(t *testing.T) {
+	fmt.Println("****TestStaticRouteConfigValidationCheck****")
+	err := server.StaticRouteConfigValidationCheck(staticRouteList[0], "add")
+	fmt.Println("err:", err, " for static route with unknown track object")
+	for _, obj := range trackObjectList {
+		err = server.TrackObjectConfigValidationCheck(obj, "add")
+		fmt.Println("err:", err, " for add of track object:", obj.Name)
+	}
+	err = server.TrackObjectConfigValidationCheck(&ribdInt.TrackObject{Name: "bad", Type: "route"}, "add")
+	fmt.Println("err:", err, " for track object with invalid type")
+	err = server.StaticRouteConfigValidationCheck(&ribdInt.StaticRoute{DestinationNw: "70.1.0.0/16"}, "add")
+	fmt.Println("err:", err, " for static route without next hops")
+	err = server.StaticRouteConfigValidationCheck(&ribdInt.StaticRoute{DestinationNw: "70.1.0.0/16",
+		NextHopList: []*ribdInt.StaticRouteNextHop{&ribdInt.StaticRouteNextHop{NextHopIp: "2002::2"}}}, "add")
+	fmt.Println("err:", err, " for static route with ipv6 next hop")
+	err = server.StaticRouteConfigValidationCheck(&ribdInt.StaticRoute{DestinationNw: "70.1.0.0/16", Distance: 300,
+		NextHopList: []*ribdInt.StaticRouteNextHop{&ribdInt.StaticRouteNextHop{NextHopIp: "11.1.10.2"}}}, "add")
+	fmt.Println("err:", err, " for static route with invalid distance")
+	err = server.StaticRouteConfigValidationCheck(&ribdInt.StaticRoute{DestinationNw: "70.1.0.0/16"}, "del")
+	fmt.Println("err:", err, " for delete of static route not configured")
+	fmt.Println("************************************")
+}
+func TestProcessStaticRouteCreateConfig(t *testing.T) {
+	fmt.Println("****TestProcessStaticRouteCreateConfig****")
+	for _, obj := range trackObjectList {
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: obj, Op: "addTrackObject"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, route := range staticRouteList {
+		err := server.StaticRouteConfigValidationCheck(route, "add")
+		fmt.Println("err:", err, " for add of static route:", route.DestinationNw, " distance:", route.Distance)
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: route, Op: "addStaticRoute"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	err := server.StaticRouteConfigValidationCheck(staticRouteList[0], "add")
+	fmt.Println("err:", err, " for duplicate static route")
+	printStaticRouteState()
+	fmt.Println("************************************")
+}
+func TestStaticRouteBfdStateChange(t *testing.T) {
+	fmt.Println("****TestStaticRouteBfdStateChange****")
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: bfddCommonDefs.BfddNotifyMsg{DestIp: "11.1.10.3", State: true}, Op: "bfdStateChange"}
+	time.Sleep(100 * time.Millisecond)
+	printStaticRouteState()
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: bfddCommonDefs.BfddNotifyMsg{DestIp: "11.1.10.3", State: false}, Op: "bfdStateChange"}
+	time.Sleep(100 * time.Millisecond)
+	printStaticRouteState()
+	fmt.Println("************************************")
+}
+func TestStaticRouteTrackObjectChange(t *testing.T) {
+	fmt.Println("****TestStaticRouteTrackObjectChange****")
+	ifIndex := TrackObjectMap["lo2"].ifIndex
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: TrackIntfStateInfo{ifIndex, false}, Op: "trackIntfState"}
+	time.Sleep(100 * time.Millisecond)
+	printStaticRouteState()
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: TrackIntfStateInfo{ifIndex, true}, Op: "trackIntfState"}
+	time.Sleep(100 * time.Millisecond)
+	printStaticRouteState()
+	fmt.Println("************************************")
+}
+func TestStaticRouteResolveIndex(t *testing.T) {
+	fmt.Println("****TestStaticRouteResolveIndex****")
+	for _, cidr := range []string{"11.1.10.0/24", "11.1.0.0/16", "0.0.0.0/0", "12.1.1.0/24"} {
+		prefix, _ := getNetworkPrefixFromCIDR(cidr)
+		key := staticRouteResolveKey(DefaultVrf, ribdCommonDefs.IPv4, prefix)
+		fmt.Println("static routes resolving through ", cidr, ":", StaticRouteResolveMap[key])
+	}
+	fmt.Println("resolve keys of 11.1.10.2:", len(staticRouteResolveKeys(DefaultVrf, ribdCommonDefs.IPv4, "11.1.10.2")))
+	fmt.Println("static routes tracking lo2:", TrackObjectRouteMap["lo2"])
+	fmt.Println("************************************")
+}
+func TestProcessStaticRouteDeleteConfig(t *testing.T) {
+	fmt.Println("****TestProcessStaticRouteDeleteConfig****")
+	err := server.TrackObjectConfigValidationCheck(trackObjectList[0], "del")
+	fmt.Println("err:", err, " for delete of track object in use")
+	//deleting the preferred route lets the floating static take over
+	server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: staticRouteList[0], Op: "delStaticRoute"}
+	time.Sleep(100 * time.Millisecond)
+	printStaticRouteState()
+	for _, route := range staticRouteList[1:] {
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: route, Op: "delStaticRoute"}
+	}
+	for _, obj := range trackObjectList {
+		server.RouteConfCh <- RIBdServerConfig{OrigConfigObject: obj, Op: "delTrackObject"}
+	}
+	time.Sleep(100 * time.Millisecond)
+	printStaticRouteState()
+	fmt.Println("************************************")
+}
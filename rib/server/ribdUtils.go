@@ -93,6 +93,7 @@ func InitPublisher(pub_str string) (pub *nanomsg.PubSocket) {
 func BuildPublisherMap() {
 	RIBD_PUB = InitPublisher(ribdCommonDefs.PUB_SOCKET_ADDR)
 	RIBD_POLICY_PUB = InitPublisher(ribdCommonDefs.PUB_SOCKET_POLICY_ADDR)
+	RIBD_SUBSCRIPTION_PUB = InitPublisher(ribdCommonDefs.PUB_SOCKET_SUBSCRIPTION_ADDR)
 	for k, _ := range RouteProtocolTypeMapDB {
 		logger.Info("Building publisher map for protocol ", k)
 		if k == "CONNECTED" || k == "STATIC" {
@@ -269,6 +270,10 @@ func BuildRouteParamsFromRouteInoRecord(routeInfoRecord RouteInfoRecord) RoutePa
 	params.metric = routeInfoRecord.metric
 	params.nextHopIp = routeInfoRecord.nextHopIp.String()
 	params.nextHopIfIndex = routeInfoRecord.nextHopIfIndex
+	params.vrf = routeInfoRecord.vrf
+	params.leakSrcVrf = routeInfoRecord.leakSrcVrf
+	params.routeTag = routeInfoRecord.routeTag
+	params.backup = routeInfoRecord.backup
 	return params
 }
 func BuildRouteParamsFromribdIPv4Route(cfg *ribd.IPv4Route, createType int, deleteType int, sliceIdx ribd.Int) RouteParams {
@@ -289,6 +294,7 @@ func BuildRouteParamsFromribdIPv4Route(cfg *ribd.IPv4Route, createType int, dele
 		sliceIdx:       ribd.Int(sliceIdx),
 		createType:     ribd.Int(createType),
 		deleteType:     ribd.Int(deleteType),
+		vrf:            GetIntfVrf(int32(nextHopIntRef)),
 	}
 	return params
 }
@@ -310,6 +316,7 @@ func BuildRouteParamsFromribdIPv6Route(cfg *ribd.IPv6Route, createType int, dele
 		sliceIdx:       ribd.Int(sliceIdx),
 		createType:     ribd.Int(createType),
 		deleteType:     ribd.Int(deleteType),
+		vrf:            GetIntfVrf(int32(nextHopIntRef)),
 	}
 	return params
 }
@@ -387,7 +394,7 @@ func newNextHop(ipType ribdCommonDefs.IPType, ip string, nextHopIfIndex ribd.Int
 }
 func isSameRoute(selectedRoute ribdInt.Routes, route ribdInt.Routes) (same bool) {
 	//logger.Info("isSameRoute")
-	if selectedRoute.IPAddrType == route.IPAddrType && selectedRoute.Ipaddr == route.Ipaddr && selectedRoute.Mask == route.Mask && selectedRoute.Prototype == route.Prototype {
+	if selectedRoute.IPAddrType == route.IPAddrType && selectedRoute.Ipaddr == route.Ipaddr && selectedRoute.Mask == route.Mask && selectedRoute.Prototype == route.Prototype && selectedRoute.Vrf == route.Vrf {
 		same = true
 	}
 	return same
@@ -449,7 +456,7 @@ func addPolicyRouteMap(route ribdInt.Routes, policyName string) {
 	//logger.Info("routeInfoList details")
 	for i := 0; i < len(tempPolicy.routeInfoList); i++ {
 		//logger.Info("IP: ", tempPolicy.routeInfoList[i].Ipaddr, ":", tempPolicy.routeInfoList[i].Mask, " protocolType: ", ReverseRouteProtoTypeMapDB[int(tempPolicy.routeInfoList[i].Prototype)])
-		if tempPolicy.routeInfoList[i].Ipaddr == route.Ipaddr && tempPolicy.routeInfoList[i].Mask == route.Mask && tempPolicy.routeInfoList[i].Prototype == route.Prototype {
+		if tempPolicy.routeInfoList[i].Ipaddr == route.Ipaddr && tempPolicy.routeInfoList[i].Mask == route.Mask && tempPolicy.routeInfoList[i].Prototype == route.Prototype && tempPolicy.routeInfoList[i].Vrf == route.Vrf {
 			//		logger.Info("route already is a part of ", policyName, "'s routeInfolist")
 			found = true
 		}
@@ -483,7 +490,7 @@ func deleteRoutePolicyStateAll(route ribdInt.Routes) {
 		return
 	}
 
-	routeInfoRecordListItem := RouteInfoMapGet(ribdCommonDefs.IPType(route.IPAddrType), destNet)
+	routeInfoRecordListItem := RouteInfoMapGet(route.Vrf, ribdCommonDefs.IPType(route.IPAddrType), destNet)
 	if routeInfoRecordListItem == nil {
 		logger.Info(" entry not found for prefix %v", destNet)
 		return
@@ -491,7 +498,7 @@ func deleteRoutePolicyStateAll(route ribdInt.Routes) {
 	routeInfoRecordList := routeInfoRecordListItem.(RouteInfoRecordList)
 	routeInfoRecordList.policyHitCounter = ribd.Int(route.PolicyHitCounter)
 	routeInfoRecordList.policyList = nil //append(routeInfoRecordList.policyList[:0])
-	RouteInfoMapSet(ribdCommonDefs.IPType(route.IPAddrType), destNet, routeInfoRecordList)
+	RouteInfoMapSet(route.Vrf, ribdCommonDefs.IPType(route.IPAddrType), destNet, routeInfoRecordList)
 	return
 }
 func addRoutePolicyState(route ribdInt.Routes, policy string, policyStmt string) {
@@ -501,7 +508,7 @@ func addRoutePolicyState(route ribdInt.Routes, policy string, policyStmt string)
 		return
 	}
 
-	routeInfoRecordListItem := RouteInfoMapGet(ribdCommonDefs.IPType(route.IPAddrType), destNet)
+	routeInfoRecordListItem := RouteInfoMapGet(route.Vrf, ribdCommonDefs.IPType(route.IPAddrType), destNet)
 	if routeInfoRecordListItem == nil {
 		logger.Info("Unexpected - entry not found for prefix ", destNet)
 		return
@@ -531,7 +538,7 @@ func addRoutePolicyState(route ribdInt.Routes, policy string, policyStmt string)
 		policyStmtList = append(policyStmtList,policyStmt)
 	    routeInfoRecordList.policyList[policy] = policyStmtList*/
 	routeInfoRecordList.policyList = append(routeInfoRecordList.policyList, policy)
-	RouteInfoMapSet(ribdCommonDefs.IPType(route.IPAddrType), destNet, routeInfoRecordList)
+	RouteInfoMapSet(route.Vrf, ribdCommonDefs.IPType(route.IPAddrType), destNet, routeInfoRecordList)
 	//logger.Debug("Adding to DBRouteCh from addRoutePolicyState")
 	RouteServiceHandler.DBRouteCh <- RIBdServerConfig{
 		OrigConfigObject: RouteDBInfo{routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol][0], routeInfoRecordList},
@@ -540,11 +547,11 @@ func addRoutePolicyState(route ribdInt.Routes, policy string, policyStmt string)
 	//RouteServiceHandler.WriteIPv4RouteStateEntryToDB(RouteDBInfo{routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol][0], routeInfoRecordList})
 	return
 }
-func deleteRoutePolicyState(ipType ribdCommonDefs.IPType, ipPrefix patriciaDB.Prefix, policyName string) {
+func deleteRoutePolicyState(vrf string, ipType ribdCommonDefs.IPType, ipPrefix patriciaDB.Prefix, policyName string) {
 	//logger.Info("deleteRoutePolicyState")
 	found := false
 	idx := 0
-	routeInfoRecordListItem := RouteInfoMapGet(ipType, ipPrefix)
+	routeInfoRecordListItem := RouteInfoMapGet(vrf, ipType, ipPrefix)
 	if routeInfoRecordListItem == nil {
 		logger.Info("routeInfoRecordListItem nil for prefix ", ipPrefix)
 		return
@@ -570,7 +577,7 @@ func deleteRoutePolicyState(ipType ribdCommonDefs.IPType, ipPrefix patriciaDB.Pr
 	} else {
 		routeInfoRecordList.policyList = append(routeInfoRecordList.policyList[:idx], routeInfoRecordList.policyList[idx+1:]...)
 	}
-	RouteInfoMapSet(ipType, ipPrefix, routeInfoRecordList)
+	RouteInfoMapSet(vrf, ipType, ipPrefix, routeInfoRecordList)
 	//logger.Debug("Adding to DBRouteCh from deleteRoutePolicyState")
 	RouteServiceHandler.DBRouteCh <- RIBdServerConfig{
 		OrigConfigObject: RouteDBInfo{routeInfoRecordList.routeInfoProtocolMap[routeInfoRecordList.selectedRouteProtocol][0], routeInfoRecordList},
@@ -588,16 +595,20 @@ func updateRoutePolicyState(route ribdInt.Routes, op int, policy string, policyS
 }
 func UpdateRedistributeTargetMap(evt int, protocol string, route ribdInt.Routes) {
 	//logger.Info("UpdateRedistributeTargetMap")
+	redistributeRouteMap := RedistributeRouteMap
+	if vrfInfo := getVrfInfo(route.Vrf); vrfInfo != nil {
+		redistributeRouteMap = vrfInfo.redistributeRouteMap
+	}
 	if evt == ribdCommonDefs.NOTIFY_ROUTE_CREATED {
-		redistributeMapInfo := RedistributeRouteMap[protocol]
+		redistributeMapInfo := redistributeRouteMap[protocol]
 		if redistributeMapInfo == nil {
 			redistributeMapInfo = make([]RedistributeRouteInfo, 0)
 		}
 		redistributeRouteInfo := RedistributeRouteInfo{route: route}
 		redistributeMapInfo = append(redistributeMapInfo, redistributeRouteInfo)
-		RedistributeRouteMap[protocol] = redistributeMapInfo
+		redistributeRouteMap[protocol] = redistributeMapInfo
 	} else if evt == ribdCommonDefs.NOTIFY_ROUTE_DELETED {
-		redistributeMapInfo := RedistributeRouteMap[protocol]
+		redistributeMapInfo := redistributeRouteMap[protocol]
 		if redistributeMapInfo != nil {
 			found := false
 			i := 0
@@ -615,7 +626,7 @@ func UpdateRedistributeTargetMap(evt int, protocol string, route ribdInt.Routes)
 					redistributeMapInfo = append(redistributeMapInfo[:i], redistributeMapInfo[i+1:]...)
 				}
 			}
-			RedistributeRouteMap[protocol] = redistributeMapInfo
+			redistributeRouteMap[protocol] = redistributeMapInfo
 		}
 	}
 }
@@ -640,6 +651,9 @@ func RedistributionNotificationSend(PUB *nanomsg.PubSocket, route ribdInt.Routes
 		eventInfo = " Advertise Network Statement "
 	}
 	eventInfo = eventInfo + evtStr + " for route " + route.Ipaddr + " " + route.Mask + " type " + ReverseRouteProtoTypeMapDB[int(route.Prototype)] + " to " + targetProtocol
+	if route.Vrf != "" && route.Vrf != DefaultVrf {
+		eventInfo = eventInfo + " in vrf " + route.Vrf
+	}
 	//logger.Info("Adding ", evtStr, " for route ", route.Ipaddr, " ", route.Mask, " to notification channel")
 	RouteServiceHandler.NotificationChannel <- NotificationMsg{PUB, buf, eventInfo}
 }
@@ -658,6 +672,7 @@ func RouteReachabilityStatusNotificationSend(targetProtocol string, info RouteRe
 		msgInfo.IsReachable = true
 	}
 	msgInfo.NextHopIntf = info.nextHopIntf
+	msgInfo.Vrf = info.vrf
 	msgBuf := msgInfo
 	msgbufbytes, err := json.Marshal(msgBuf)
 	msg := ribdCommonDefs.RibdNotifyMsg{MsgType: uint16(evt), MsgBuf: msgbufbytes}
@@ -678,6 +693,7 @@ func RouteReachabilityStatusUpdate(targetProtocol string, info RouteReachability
 	if targetProtocol != "NONE" {
 		RouteReachabilityStatusNotificationSend(targetProtocol, info)
 	}
+	pbrReachabilityChange(info.destNet)
 	var ipMask net.IP
 	ip, ipNet, err := net.ParseCIDR(info.destNet)
 	if err != nil {
@@ -702,6 +718,10 @@ func RouteReachabilityStatusUpdate(targetProtocol string, info RouteReachability
 		}
 		if bytes.Equal(destIpPrefix, prefix) {
 			for idx := 0; idx < len(list); idx++ {
+				if list[idx] == PbrTrackProtocol {
+					//handled by pbrReachabilityChange
+					continue
+				}
 				//logger.Info(" protocol ", list[idx], " interested in receving reachability updates for ipAddr ", info.destNet)
 				info.destNet = k
 				RouteReachabilityStatusNotificationSend(list[idx], info)
@@ -0,0 +1,643 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// ribdVrf.go
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"l3/rib/ribdCommonDefs"
+	"ribd"
+	"ribdInt"
+	"sort"
+	"strconv"
+	"strings"
+	"utils/patriciaDB"
+)
+
+const (
+	DefaultVrf = "default"
+	/*
+	   redistribute target protocol used to leak routes into a vrf, for ex:"VRF:red"
+	*/
+	VrfLeakTargetPrefix = "VRF:"
+)
+
+/*
+   Per vrf routing table and config
+*/
+type VrfInfo struct {
+	name                 string
+	v4RouteInfoMap       *patriciaDB.Trie
+	v6RouteInfoMap       *patriciaDB.Trie
+	intfList             []string
+	adminDistanceMap     map[string]int //per protocol admin distance overrides
+	redistributeRouteMap map[string][]RedistributeRouteInfo
+}
+
+var VrfInfoMap map[string]*VrfInfo
+var IntfNameVrfMap map[string]string //map[intfName]vrf, interfaces not in this map belong to the default vrf
+
+/*
+   The default vrf wraps the global route tables so that the existing apis continue to work on it
+*/
+func InitVrfDB() {
+	VrfInfoMap = make(map[string]*VrfInfo)
+	IntfNameVrfMap = make(map[string]string)
+	VrfInfoMap[DefaultVrf] = &VrfInfo{
+		name:                 DefaultVrf,
+		v4RouteInfoMap:       V4RouteInfoMap,
+		v6RouteInfoMap:       V6RouteInfoMap,
+		intfList:             make([]string, 0),
+		adminDistanceMap:     make(map[string]int),
+		redistributeRouteMap: RedistributeRouteMap,
+	}
+}
+func getVrfName(vrf string) string {
+	if vrf == "" {
+		return DefaultVrf
+	}
+	return vrf
+}
+func getVrfInfo(vrf string) *VrfInfo {
+	if VrfInfoMap == nil {
+		return nil
+	}
+	vrfInfo, ok := VrfInfoMap[getVrfName(vrf)]
+	if !ok {
+		return nil
+	}
+	return vrfInfo
+}
+func getVrfRouteInfoMap(vrf string, ipType ribdCommonDefs.IPType) *patriciaDB.Trie {
+	vrfInfo := getVrfInfo(vrf)
+	if vrfInfo == nil {
+		return nil
+	}
+	if ipType == ribdCommonDefs.IPv6 {
+		return vrfInfo.v6RouteInfoMap
+	}
+	return vrfInfo.v4RouteInfoMap
+}
+func isDefaultVrfRoute(routeInfoRecord RouteInfoRecord) bool {
+	return getVrfName(routeInfoRecord.vrf) == DefaultVrf
+}
+
+/*
+   Returns the vrf the interface with ifIndex is bound to
+*/
+func GetIntfVrf(ifIndex int32) string {
+	if IntfNameVrfMap == nil {
+		return DefaultVrf
+	}
+	if intfEntry, ok := IntfIdNameMap[ifIndex]; ok {
+		if vrf, ok := IntfNameVrfMap[intfEntry.name]; ok {
+			return vrf
+		}
+	}
+	if vrf, ok := IntfNameVrfMap[strconv.Itoa(int(ifIndex))]; ok {
+		return vrf
+	}
+	return DefaultVrf
+}
+
+/*
+   Next hops of leaked routes are resolved in the vrf the route was leaked from
+*/
+func getNextHopVrf(routeInfoRecord RouteInfoRecord) string {
+	if routeInfoRecord.leakSrcVrf != "" {
+		return routeInfoRecord.leakSrcVrf
+	}
+	return getVrfName(routeInfoRecord.vrf)
+}
+
+/*
+   Admin distance of a vrf is the global admin distance with the vrf overrides applied
+*/
+func getVrfAdminDistanceMapDB(vrf string) map[string]RouteDistanceConfig {
+	vrfInfo := getVrfInfo(vrf)
+	if vrfInfo == nil || len(vrfInfo.adminDistanceMap) == 0 {
+		return ProtocolAdminDistanceMapDB
+	}
+	adminDistanceMapDB := make(map[string]RouteDistanceConfig)
+	for protocol, routeDistanceConfig := range ProtocolAdminDistanceMapDB {
+		if distance, ok := vrfInfo.adminDistanceMap[protocol]; ok {
+			routeDistanceConfig.configuredDistance = distance
+		}
+		adminDistanceMapDB[protocol] = routeDistanceConfig
+	}
+	return adminDistanceMapDB
+}
+func getVrfAdminDistanceSlice(vrf string) AdminDistanceSlice {
+	vrfInfo := getVrfInfo(vrf)
+	if vrfInfo == nil || len(vrfInfo.adminDistanceMap) == 0 {
+		BuildProtocolAdminDistanceSlice(false)
+		return ProtocolAdminDistanceSlice
+	}
+	adminDistanceSlice := make(AdminDistanceSlice, 0)
+	for protocol, v := range getVrfAdminDistanceMapDB(vrf) {
+		distance := v.defaultDistance
+		if v.configuredDistance != -1 {
+			distance = v.configuredDistance
+		}
+		adminDistanceSlice = append(adminDistanceSlice, ribd.RouteDistanceState{Protocol: protocol, Distance: int32(distance)})
+	}
+	sort.Sort(adminDistanceSlice)
+	return adminDistanceSlice
+}
+
+/*
+   Returns the longest prefix match route to reach the destination network destNet in vrf
+*/
+func (m RIBDServer) GetVrfRouteReachabilityInfo(vrf string, destNet string, ifIndex ribdInt.Int) (nextHopIntf *ribdInt.NextHopInfo, err error) {
+	vrf = getVrfName(vrf)
+	if vrf == DefaultVrf {
+		return m.GetRouteReachabilityInfo(destNet, ifIndex)
+	}
+	logger.Debug("GetVrfRouteReachabilityInfo of ", destNet, " ifIndex:", ifIndex, " vrf:", vrf)
+	var retnextHopIntf ribdInt.NextHopInfo
+	nextHopIntf = &retnextHopIntf
+	destNetIp, err := getIP(destNet)
+	if err != nil {
+		logger.Err("getIP returned Invalid dest ip address for ", destNet)
+		return nextHopIntf, errors.New("Invalid dest ip address")
+	}
+	ipType := ribdCommonDefs.IPv4
+	lookupIp := destNetIp.To4()
+	if lookupIp == nil {
+		ipType = ribdCommonDefs.IPv6
+		lookupIp = destNetIp.To16()
+	}
+	routeInfoMap := getVrfRouteInfoMap(vrf, ipType)
+	if routeInfoMap == nil {
+		return nextHopIntf, errors.New(fmt.Sprintln("vrf ", vrf, " not found"))
+	}
+	rmapInfoListItem := routeInfoMap.GetLongestPrefixNode(patriciaDB.Prefix(lookupIp))
+	if rmapInfoListItem == nil {
+		logger.Err("dest IP", destNetIp, " not reachable in vrf ", vrf)
+		return nextHopIntf, errors.New("dest ip address not reachable")
+	}
+	rmapInfoList := rmapInfoListItem.(RouteInfoRecordList)
+	if rmapInfoList.selectedRouteProtocol == "INVALID" {
+		logger.Err("dest IP", destNetIp, " not reachable in vrf ", vrf)
+		return nextHopIntf, errors.New("dest ip address not reachable")
+	}
+	routeInfoList, ok := rmapInfoList.routeInfoProtocolMap[rmapInfoList.selectedRouteProtocol]
+	if !ok || len(routeInfoList) == 0 {
+		logger.Err("Selected route not found because len(routeInfoList) = 0")
+		return nil, errors.New("dest ip address not reachable")
+	}
+	nhFound, v, _ := findRouteWithNextHop(routeInfoList, ipType, "", ribd.Int(ifIndex))
+	if !nhFound {
+		logger.Err("Next hop for ifIndex:", ifIndex, " not found")
+		return nil, errors.New(fmt.Sprintln("dest ip address not reachable via ifIndex", ifIndex))
+	}
+	nextHopIntf.NextHopIp = v.nextHopIp.String()
+	nextHopIntf.NextHopIfIndex = ribdInt.Int(v.nextHopIfIndex)
+	nextHopIntf.Metric = ribdInt.Int(v.metric)
+	nextHopIntf.Ipaddr = v.destNetIp.String()
+	nextHopIntf.Mask = v.networkMask.String()
+	nextHopIntf.IsReachable = true
+	return nextHopIntf, err
+}
+
+/*
+   Vrf config processing
+*/
+func (m RIBDServer) VrfConfigValidationCheck(cfg *ribdInt.Vrf, op string) (err error) {
+	if cfg.Name == "" {
+		return errors.New("Vrf name not provided")
+	}
+	vrfInfo := getVrfInfo(cfg.Name)
+	switch op {
+	case "add":
+		if vrfInfo != nil {
+			return errors.New(fmt.Sprintln("Vrf ", cfg.Name, " already exists"))
+		}
+		//the routes of the vrf would be accepted but never forwarded on
+		if !fibProgrammer.SupportsVrf() {
+			return errors.New(fmt.Sprintln("Vrf ", cfg.Name, " can not be programmed, vrf route tables are not supported by the ", fibProgrammer.Name(), " fib"))
+		}
+	case "update":
+		if vrfInfo == nil {
+			return errors.New(fmt.Sprintln("Vrf ", cfg.Name, " not found"))
+		}
+	case "del":
+		if cfg.Name == DefaultVrf {
+			return errors.New("Cannot delete the default vrf")
+		}
+		if vrfInfo == nil {
+			return errors.New(fmt.Sprintln("Vrf ", cfg.Name, " not found"))
+		}
+		return nil
+	}
+	if cfg.Name == DefaultVrf && len(cfg.IntfList) > 0 {
+		return errors.New("Interfaces not bound to any vrf belong to the default vrf")
+	}
+	for _, intf := range cfg.IntfList {
+		if vrf, ok := IntfNameVrfMap[intf]; ok && vrf != cfg.Name {
+			return errors.New(fmt.Sprintln("Interface ", intf, " already bound to vrf ", vrf))
+		}
+	}
+	for _, adminDistance := range cfg.AdminDistance {
+		if _, ok := ProtocolAdminDistanceMapDB[adminDistance.Protocol]; !ok {
+			return errors.New(fmt.Sprintln("Invalid protocol ", adminDistance.Protocol, " for vrf admin distance"))
+		}
+		if adminDistance.Distance < 0 || adminDistance.Distance > 255 {
+			return errors.New(fmt.Sprintln("Invalid admin distance ", adminDistance.Distance, " for protocol ", adminDistance.Protocol))
+		}
+	}
+	return nil
+}
+func buildVrfAdminDistanceMap(cfg *ribdInt.Vrf) map[string]int {
+	adminDistanceMap := make(map[string]int)
+	for _, adminDistance := range cfg.AdminDistance {
+		adminDistanceMap[adminDistance.Protocol] = int(adminDistance.Distance)
+	}
+	return adminDistanceMap
+}
+func (m RIBDServer) ProcessVrfCreateConfig(cfg *ribdInt.Vrf) (val bool, err error) {
+	logger.Info("ProcessVrfCreateConfig for vrf ", cfg.Name)
+	if getVrfInfo(cfg.Name) != nil {
+		logger.Err("Vrf ", cfg.Name, " already exists")
+		return false, errors.New(fmt.Sprintln("Vrf ", cfg.Name, " already exists"))
+	}
+	VrfInfoMap[cfg.Name] = &VrfInfo{
+		name:                 cfg.Name,
+		v4RouteInfoMap:       patriciaDB.NewTrie(),
+		v6RouteInfoMap:       patriciaDB.NewTrie(),
+		intfList:             make([]string, 0),
+		adminDistanceMap:     buildVrfAdminDistanceMap(cfg),
+		redistributeRouteMap: make(map[string][]RedistributeRouteInfo),
+	}
+	for _, intf := range cfg.IntfList {
+		bindIntfToVrf(intf, cfg.Name)
+	}
+	//pbr rules setting the vrf become active
+	m.ProcessPbrPolicyEval()
+	return true, nil
+}
+func (m RIBDServer) ProcessVrfUpdateConfig(origCfg *ribdInt.Vrf, newCfg *ribdInt.Vrf) (val bool, err error) {
+	logger.Info("ProcessVrfUpdateConfig for vrf ", origCfg.Name)
+	vrfInfo := getVrfInfo(origCfg.Name)
+	if vrfInfo == nil {
+		logger.Err("Vrf ", origCfg.Name, " not found")
+		return false, errors.New(fmt.Sprintln("Vrf ", origCfg.Name, " not found"))
+	}
+	newIntfs := make(map[string]bool)
+	for _, intf := range newCfg.IntfList {
+		newIntfs[intf] = true
+	}
+	for _, intf := range append([]string(nil), vrfInfo.intfList...) {
+		if !newIntfs[intf] {
+			bindIntfToVrf(intf, DefaultVrf)
+		}
+	}
+	for _, intf := range newCfg.IntfList {
+		bindIntfToVrf(intf, vrfInfo.name)
+	}
+	vrfInfo.adminDistanceMap = buildVrfAdminDistanceMap(newCfg)
+	updateVrfBestRoutes(vrfInfo.name)
+	return true, nil
+}
+func (m RIBDServer) ProcessVrfDeleteConfig(cfg *ribdInt.Vrf) (val bool, err error) {
+	logger.Info("ProcessVrfDeleteConfig for vrf ", cfg.Name)
+	if getVrfName(cfg.Name) == DefaultVrf {
+		logger.Err("Cannot delete the default vrf")
+		return false, errors.New("Cannot delete the default vrf")
+	}
+	vrfInfo := getVrfInfo(cfg.Name)
+	if vrfInfo == nil {
+		logger.Err("Vrf ", cfg.Name, " not found")
+		return false, errors.New(fmt.Sprintln("Vrf ", cfg.Name, " not found"))
+	}
+	for _, intf := range append([]string(nil), vrfInfo.intfList...) {
+		bindIntfToVrf(intf, DefaultVrf)
+	}
+	for _, routeInfoRecord := range getVrfRouteRecords(vrfInfo.name, func(RouteInfoRecord) bool { return true }) {
+		deleteIPRoute(vrfInfo.name, routeInfoRecord.destNetIp.String(), routeInfoRecord.ipType, routeInfoRecord.networkMask.String(), ReverseRouteProtoTypeMapDB[int(routeInfoRecord.protocol)], routeInfoRecord.nextHopIp.String(), routeInfoRecord.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
+	}
+	if fibProgrammer.IsReady() {
+		if err = fibProgrammer.DeleteVrf(vrfInfo.name); err != nil {
+			logger.Err("Deleting the fib table of vrf ", vrfInfo.name, " failed with err ", err)
+		}
+	}
+	delete(VrfInfoMap, vrfInfo.name)
+	m.ProcessPbrPolicyEval()
+	return true, nil
+}
+
+/*
+   Returns the route records in vrf that match the filter
+*/
+func getVrfRouteRecords(vrf string, filter func(RouteInfoRecord) bool) []RouteInfoRecord {
+	routeInfoRecords := make([]RouteInfoRecord, 0)
+	vrfInfo := getVrfInfo(vrf)
+	if vrfInfo == nil {
+		return routeInfoRecords
+	}
+	collect := func(prefix patriciaDB.Prefix, item patriciaDB.Item, handle patriciaDB.Item) (err error) {
+		for _, routeInfoList := range item.(RouteInfoRecordList).routeInfoProtocolMap {
+			for _, routeInfoRecord := range routeInfoList {
+				if filter(routeInfoRecord) {
+					routeInfoRecords = append(routeInfoRecords, routeInfoRecord)
+				}
+			}
+		}
+		return err
+	}
+	vrfInfo.v4RouteInfoMap.VisitAndUpdate(collect, nil)
+	vrfInfo.v6RouteInfoMap.VisitAndUpdate(collect, nil)
+	return routeInfoRecords
+}
+
+/*
+   Binds intf to vrf and moves the connected routes of the interface into the route table of vrf
+*/
+func bindIntfToVrf(intf string, vrf string) {
+	oldVrf, ok := IntfNameVrfMap[intf]
+	if !ok {
+		oldVrf = DefaultVrf
+	}
+	if oldVrf == vrf {
+		return
+	}
+	logger.Info("Binding interface ", intf, " to vrf ", vrf, " from vrf ", oldVrf)
+	if oldVrfInfo := getVrfInfo(oldVrf); oldVrfInfo != nil {
+		for i, name := range oldVrfInfo.intfList {
+			if name == intf {
+				oldVrfInfo.intfList = append(oldVrfInfo.intfList[:i], oldVrfInfo.intfList[i+1:]...)
+				break
+			}
+		}
+	}
+	if vrf == DefaultVrf {
+		delete(IntfNameVrfMap, intf)
+	} else {
+		IntfNameVrfMap[intf] = vrf
+		vrfInfo := getVrfInfo(vrf)
+		vrfInfo.intfList = append(vrfInfo.intfList, intf)
+	}
+	ifIndex, ok := IfNameToIfIndex[intf]
+	if !ok {
+		val, err := strconv.Atoi(intf)
+		if err != nil {
+			logger.Info("Interface ", intf, " not created yet, binding applied on its create event")
+			return
+		}
+		ifIndex = int32(val)
+	}
+	moveIntfConnectedRoutes(ifIndex, oldVrf, vrf)
+	bindFibVrfIntf(vrf, ifIndex)
+}
+
+/*
+   Interface create events apply the binding of the interfaces configured in a vrf before
+   they existed, the connected routes learnt before the interface name was known are moved
+   from the default vrf
+*/
+func (ribdServiceHandler *RIBDServer) IntfVrfBindingUpdate(ifIndex int32) {
+	ribdServiceHandler.RouteConfCh <- RIBdServerConfig{
+		OrigConfigObject: ifIndex,
+		Op:               "vrfIntfBind",
+	}
+}
+func (m RIBDServer) ProcessIntfVrfBinding(ifIndex int32) {
+	if len(VrfInfoMap) <= 1 {
+		return
+	}
+	vrf := GetIntfVrf(ifIndex)
+	logger.Info("ProcessIntfVrfBinding ifIndex ", ifIndex, " vrf ", vrf)
+	for name, _ := range VrfInfoMap {
+		if name != vrf {
+			moveIntfConnectedRoutes(ifIndex, name, vrf)
+		}
+	}
+	bindFibVrfIntf(vrf, ifIndex)
+}
+func bindFibVrfIntf(vrf string, ifIndex int32) {
+	if !fibProgrammer.IsReady() {
+		return
+	}
+	if err := fibProgrammer.BindVrfIntf(vrf, ifIndex); err != nil {
+		logger.Err("Binding ifIndex ", ifIndex, " to vrf ", vrf, " in the fib failed with err ", err)
+	}
+}
+func moveIntfConnectedRoutes(ifIndex int32, oldVrf string, vrf string) {
+	connectedRoutes := getVrfRouteRecords(oldVrf, func(routeInfoRecord RouteInfoRecord) bool {
+		return routeInfoRecord.protocol == ribdCommonDefs.CONNECTED && int32(routeInfoRecord.nextHopIfIndex) == ifIndex
+	})
+	for _, routeInfoRecord := range connectedRoutes {
+		deleteIPRoute(oldVrf, routeInfoRecord.destNetIp.String(), routeInfoRecord.ipType, routeInfoRecord.networkMask.String(), "CONNECTED", routeInfoRecord.nextHopIp.String(), routeInfoRecord.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
+		params := BuildRouteParamsFromRouteInoRecord(routeInfoRecord)
+		params.vrf = vrf
+		params.leakSrcVrf = ""
+		params.weight = routeInfoRecord.weight
+		params.createType = FIBAndRIB
+		params.deleteType = Invalid
+		params.sliceIdx = ribd.Int(len(destNetSlice))
+		createRoute(params)
+	}
+}
+
+/*
+   Reruns best route selection for all the prefixes in the vrf, called when the vrf admin distance changes
+*/
+func updateVrfBestRoutes(vrf string) {
+	vrfInfo := getVrfInfo(vrf)
+	if vrfInfo == nil {
+		return
+	}
+	logger.Info("updateVrfBestRoutes for vrf ", vrf)
+	update := func(prefix patriciaDB.Prefix, item patriciaDB.Item, handle patriciaDB.Item) (err error) {
+		updateBestRoute(prefix, item.(RouteInfoRecordList))
+		return err
+	}
+	vrfInfo.v4RouteInfoMap.VisitAndUpdate(update, nil)
+	vrfInfo.v6RouteInfoMap.VisitAndUpdate(update, nil)
+}
+
+/*
+   Route leaking between vrfs
+*/
+func getLeakTargetVrf(targetProtocol string) (vrf string, isLeak bool) {
+	if !strings.HasPrefix(targetProtocol, VrfLeakTargetPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(targetProtocol, VrfLeakTargetPrefix), true
+}
+func leakRouteToVrf(routeInfo RouteParams, targetVrf string, evt int) {
+	srcVrf := getVrfName(routeInfo.vrf)
+	logger.Info("leakRouteToVrf route ", routeInfo.destNetIp, ":", routeInfo.networkMask, " from vrf ", srcVrf, " to vrf ", targetVrf, " evt:", evt)
+	if routeInfo.leakSrcVrf != "" {
+		logger.Info("Route already leaked from vrf ", routeInfo.leakSrcVrf, ", not leaking it again")
+		return
+	}
+	if targetVrf == srcVrf {
+		logger.Info("Leak target vrf same as the route vrf, do nothing")
+		return
+	}
+	if getVrfInfo(targetVrf) == nil {
+		logger.Err("Leak target vrf ", targetVrf, " not found")
+		return
+	}
+	if evt == ribdCommonDefs.NOTIFY_ROUTE_CREATED {
+		params := routeInfo
+		params.vrf = targetVrf
+		params.leakSrcVrf = srcVrf
+		params.createType = FIBAndRIB
+		params.deleteType = Invalid
+		params.bulk = false
+		params.bulkEnd = false
+		params.sliceIdx = ribd.Int(len(destNetSlice))
+		_, err := createRoute(params)
+		if err != nil {
+			logger.Err("Leaking route ", routeInfo.destNetIp, " to vrf ", targetVrf, " failed with err ", err)
+		}
+	} else if evt == ribdCommonDefs.NOTIFY_ROUTE_DELETED {
+		_, err := deleteIPRoute(targetVrf, routeInfo.destNetIp, routeInfo.ipType, routeInfo.networkMask, ReverseRouteProtoTypeMapDB[int(routeInfo.routeType)], routeInfo.nextHopIp, routeInfo.nextHopIfIndex, FIBAndRIB, ribdCommonDefs.RoutePolicyStateChangetoInValid)
+		if err != nil {
+			logger.Err("Deleting leaked route ", routeInfo.destNetIp, " from vrf ", targetVrf, " failed with err ", err)
+		}
+	}
+}
+
+/*
+   Routes in non default vrfs are programmed in the route table of the vrf, the fib programmers
+   without vrf tables leave them to the vrf aware applications they are published to
+*/
+func fibVrfRoute(routeInfoRecord RouteInfoRecord) *FibVrfRoute {
+	nextHopIp := routeInfoRecord.resolvedNextHopIpIntf.NextHopIp
+	ifIndex := int32(routeInfoRecord.resolvedNextHopIpIntf.NextHopIfIndex)
+	if routeInfoRecord.protocol == ribdCommonDefs.CONNECTED || nextHopIp == "" {
+		nextHopIp = routeInfoRecord.nextHopIp.String()
+		ifIndex = int32(routeInfoRecord.nextHopIfIndex)
+	}
+	return &FibVrfRoute{
+		ipType:      routeInfoRecord.ipType,
+		destNw:      routeInfoRecord.destNetIp.String(),
+		networkMask: routeInfoRecord.networkMask.String(),
+		nextHopIp:   nextHopIp,
+		ifIndex:     ifIndex,
+		weight:      int32(routeInfoRecord.weight + 1),
+	}
+}
+func programVrfRoute(routeInfoRecord RouteInfoRecord, op string) {
+	if !fibProgrammer.IsReady() {
+		return
+	}
+	vrf := getVrfName(routeInfoRecord.vrf)
+	routes := []*FibVrfRoute{fibVrfRoute(routeInfoRecord)}
+	var err error
+	if op == "add" {
+		err = fibProgrammer.CreateVrfRoutes(vrf, routes)
+	} else {
+		err = fibProgrammer.DeleteVrfRoutes(vrf, routes)
+	}
+	if err != nil {
+		logger.Err("Programming route ", routes[0].destNw, ":", routes[0].networkMask, " op ", op, " in vrf ", vrf, " failed with err ", err)
+	}
+}
+func vrfRouteNotificationSend(routeInfoRecord RouteInfoRecord, op string) {
+	evt := ribdCommonDefs.NOTIFY_VRF_ROUTE_INSTALLED
+	evtStr := " NOTIFY_VRF_ROUTE_INSTALLED "
+	if op == "del" {
+		evt = ribdCommonDefs.NOTIFY_VRF_ROUTE_UNINSTALLED
+		evtStr = " NOTIFY_VRF_ROUTE_UNINSTALLED "
+	}
+	route := ribdInt.Routes{
+		Ipaddr:     routeInfoRecord.destNetIp.String(),
+		Mask:       routeInfoRecord.networkMask.String(),
+		NextHopIp:  routeInfoRecord.resolvedNextHopIpIntf.NextHopIp,
+		IfIndex:    ribdInt.Int(routeInfoRecord.nextHopIfIndex),
+		Metric:     ribdInt.Int(routeInfoRecord.metric),
+		Prototype:  ribdInt.Int(routeInfoRecord.protocol),
+		Weight:     ribdInt.Int(routeInfoRecord.weight),
+		IPAddrType: ribdInt.Int(routeInfoRecord.ipType),
+		Vrf:        routeInfoRecord.vrf,
+		RouteTag:   int64(routeInfoRecord.routeTag),
+	}
+	msgBuf := ribdCommonDefs.RoutelistInfo{RouteInfo: route}
+	msgbufbytes, err := json.Marshal(msgBuf)
+	if err != nil {
+		logger.Err("Error in marshalling Json for vrf route ", route.Ipaddr, ":", route.Mask, " err ", err)
+		return
+	}
+	msg := ribdCommonDefs.RibdNotifyMsg{MsgType: uint16(evt), MsgBuf: msgbufbytes}
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		logger.Err("Error in marshalling Json")
+		return
+	}
+	eventInfo := evtStr + " for route " + route.Ipaddr + " " + route.Mask + " nexthop " + route.NextHopIp + " in vrf " + route.Vrf
+	RouteServiceHandler.NotificationChannel <- NotificationMsg{RIBD_PUB, buf, eventInfo}
+}
+
+/*
+   Vrf state
+*/
+func getVrfRouteCount(routeInfoMap *patriciaDB.Trie) (count int32) {
+	routeInfoMap.VisitAndUpdate(func(prefix patriciaDB.Prefix, item patriciaDB.Item, handle patriciaDB.Item) (err error) {
+		if item.(RouteInfoRecordList).selectedRouteProtocol != "INVALID" {
+			count++
+		}
+		return err
+	}, nil)
+	return count
+}
+func (m RIBDServer) GetBulkVrfState(fromIndex ribdInt.Int, rcount ribdInt.Int) (vrfs *ribdInt.VrfStateGetInfo, err error) {
+	var returnVrfGetInfo ribdInt.VrfStateGetInfo
+	vrfs = &returnVrfGetInfo
+	names := make([]string, 0)
+	for name, _ := range VrfInfoMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vrfStates := make([]*ribdInt.VrfState, 0)
+	i := fromIndex
+	for ; i < ribdInt.Int(len(names)) && ribdInt.Int(len(vrfStates)) < rcount; i++ {
+		vrfInfo := VrfInfoMap[names[i]]
+		vrfState := &ribdInt.VrfState{
+			Name:        vrfInfo.name,
+			IntfList:    append([]string(nil), vrfInfo.intfList...),
+			NumV4Routes: getVrfRouteCount(vrfInfo.v4RouteInfoMap),
+			NumV6Routes: getVrfRouteCount(vrfInfo.v6RouteInfoMap),
+		}
+		vrfStates = append(vrfStates, vrfState)
+	}
+	vrfs.VrfStateList = vrfStates
+	vrfs.StartIdx = fromIndex
+	vrfs.EndIdx = i
+	vrfs.More = i < ribdInt.Int(len(names))
+	vrfs.Count = ribdInt.Int(len(vrfStates))
+	return vrfs, err
+}
+func (m RIBDServer) GetBulkRoutesForProtocolInVrf(vrf string, srcProtocol string, fromIndex ribdInt.Int, rcount ribdInt.Int) (routes *ribdInt.RoutesGetInfo, err error) {
+	vrfInfo := getVrfInfo(vrf)
+	if vrfInfo == nil {
+		return &ribdInt.RoutesGetInfo{}, errors.New(fmt.Sprintln("vrf ", vrf, " not found"))
+	}
+	return getBulkRedistributeRoutes(vrfInfo.redistributeRouteMap[srcProtocol], fromIndex, rcount)
+}
@@ -0,0 +1,135 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"fmt"
+	"ribdInt"
+	"testing"
+)
+
+var vrfList []*ribdInt.Vrf
+
+func InitVrfList() {
+	vrfList = make([]*ribdInt.Vrf, 0)
+	vrfList = append(vrfList, &ribdInt.Vrf{
+		Name:     "red",
+		IntfList: []string{"lo1"},
+	})
+	vrfList = append(vrfList, &ribdInt.Vrf{
+		Name:     "blue",
+		IntfList: []string{"lo2"},
+		AdminDistance: []*ribdInt.VrfAdminDistance{
+			&ribdInt.VrfAdminDistance{Protocol: "STATIC", Distance: 250},
+		},
+	})
+}
+func TestInitVrfTestServer(t *testing.T) {
+	fmt.Println("****Init Vrf Test Server****")
+	StartTestServer()
+	TestProcessLogicalIntfCreateEvent(t)
+	TestIPv4IntfCreateEvent(t)
+	InitVrfList()
+	fmt.Println("****************")
+}
+func TestVrfConfigValidationCheck(t *testing.T) {
+	fmt.Println("****TestVrfConfigValidationCheck****")
+	for _, vrf := range vrfList {
+		err := server.VrfConfigValidationCheck(vrf, "add")
+		fmt.Println("err:", err, " for add of vrf:", vrf)
+	}
+	err := server.VrfConfigValidationCheck(&ribdInt.Vrf{Name: DefaultVrf}, "del")
+	fmt.Println("err:", err, " for delete of the default vrf")
+	err = server.VrfConfigValidationCheck(&ribdInt.Vrf{Name: "green", AdminDistance: []*ribdInt.VrfAdminDistance{&ribdInt.VrfAdminDistance{Protocol: "RIP", Distance: 120}}}, "add")
+	fmt.Println("err:", err, " for vrf with unknown admin distance protocol")
+	fmt.Println("************************************")
+}
+func TestProcessVrfCreateConfig(t *testing.T) {
+	fmt.Println("****TestProcessVrfCreateConfig****")
+	for _, vrf := range vrfList {
+		val, err := server.ProcessVrfCreateConfig(vrf)
+		fmt.Println("val:", val, " err:", err, " for vrf:", vrf.Name)
+	}
+	val, err := server.ProcessVrfCreateConfig(vrfList[0])
+	fmt.Println("val:", val, " err:", err, " for duplicate vrf:", vrfList[0].Name)
+	for _, vrf := range vrfList {
+		for _, intf := range vrf.IntfList {
+			fmt.Println("intf:", intf, " bound to vrf:", IntfNameVrfMap[intf])
+		}
+	}
+	vrfState, err := server.GetBulkVrfState(0, 10)
+	fmt.Println("vrfState:", vrfState, " err:", err)
+	fmt.Println("**********************************")
+}
+func TestGetVrfRouteReachabilityInfo(t *testing.T) {
+	fmt.Println("****TestGetVrfRouteReachabilityInfo****")
+	for _, vrf := range []string{DefaultVrf, "red", "blue", "green"} {
+		for _, ipAddr := range ipv4AddrList {
+			nh, err := server.GetVrfRouteReachabilityInfo(vrf, ipAddr.ipAddr, -1)
+			fmt.Println("nh:", nh, " err:", err, " for ip:", ipAddr.ipAddr, " in vrf:", vrf)
+		}
+	}
+	nh, rnh, err := ResolveVrfNextHop("red", "0.0.0.0")
+	fmt.Println("nh:", nh, " rnh:", rnh, " err:", err, " for 0.0.0.0 in vrf red")
+	fmt.Println("***************************************")
+}
+func TestVrfAdminDistance(t *testing.T) {
+	fmt.Println("****TestVrfAdminDistance****")
+	for _, vrf := range []string{DefaultVrf, "red", "blue"} {
+		fmt.Println("vrf:", vrf, " admin distance:", getVrfAdminDistanceSlice(vrf))
+		fmt.Println("vrf:", vrf, " STATIC admin distance config:", getVrfAdminDistanceMapDB(vrf)["STATIC"])
+	}
+	fmt.Println("****************************")
+}
+func TestGetLeakTargetVrf(t *testing.T) {
+	fmt.Println("****TestGetLeakTargetVrf****")
+	for _, target := range []string{"VRF:red", "VRF:", "BGP", "OSPF"} {
+		vrf, isLeak := getLeakTargetVrf(target)
+		fmt.Println("vrf:", vrf, " isLeak:", isLeak, " for target protocol:", target)
+	}
+	fmt.Println("****************************")
+}
+func TestProcessVrfUpdateConfig(t *testing.T) {
+	fmt.Println("****TestProcessVrfUpdateConfig****")
+	newCfg := &ribdInt.Vrf{Name: "red", IntfList: []string{"lo1", "lo3"}}
+	val, err := server.ProcessVrfUpdateConfig(vrfList[0], newCfg)
+	fmt.Println("val:", val, " err:", err, " for vrf update:", newCfg)
+	val, err = server.ProcessVrfUpdateConfig(&ribdInt.Vrf{Name: "green"}, &ribdInt.Vrf{Name: "green"})
+	fmt.Println("val:", val, " err:", err, " for update of unknown vrf green")
+	vrfState, err := server.GetBulkVrfState(0, 10)
+	fmt.Println("vrfState:", vrfState, " err:", err)
+	fmt.Println("**********************************")
+}
+func TestProcessVrfDeleteConfig(t *testing.T) {
+	fmt.Println("****TestProcessVrfDeleteConfig****")
+	val, err := server.ProcessVrfDeleteConfig(&ribdInt.Vrf{Name: DefaultVrf})
+	fmt.Println("val:", val, " err:", err, " for delete of the default vrf")
+	for _, vrf := range vrfList {
+		val, err = server.ProcessVrfDeleteConfig(vrf)
+		fmt.Println("val:", val, " err:", err, " for vrf:", vrf.Name)
+	}
+	vrfState, err := server.GetBulkVrfState(0, 10)
+	fmt.Println("vrfState:", vrfState, " err:", err)
+	fmt.Println("**********************************")
+}
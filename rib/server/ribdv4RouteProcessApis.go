@@ -158,7 +158,7 @@ func UpdateV4RouteReachabilityStatus(prefix patriciaDB.Prefix, //prefix of the n
 				if routeReachabilityStatusInfo.status == "Down" && v[i].resolvedNextHopIpIntf.IsReachable == true {
 					v[i].resolvedNextHopIpIntf.IsReachable = false
 					rmapInfoRecordList.routeInfoProtocolMap[k] = v
-					V4RouteInfoMap.Set(prefix, rmapInfoRecordList)
+					RouteInfoMapSet(v[i].vrf, ribdCommonDefs.IPv4, prefix, rmapInfoRecordList)
 					//logger.Debug("Adding to DBRouteCh from updateRouteReachability case 1")
 					RouteServiceHandler.DBRouteCh <- RIBdServerConfig{
 						OrigConfigObject: RouteDBInfo{v[i], rmapInfoRecordList},
@@ -166,34 +166,34 @@ func UpdateV4RouteReachabilityStatus(prefix patriciaDB.Prefix, //prefix of the n
 					}
 					//RouteServiceHandler.WriteIPv4RouteStateEntryToDB(RouteDBInfo{v[i], rmapInfoRecordList})
 					//logger.Debug("Bringing down route : ip: ", v[i].networkAddr)
-					RouteReachabilityStatusUpdate(k, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Down", k, nextHopIntf})
+					RouteReachabilityStatusUpdate(k, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Down", k, nextHopIntf, v[i].vrf})
 					/*
 					   The reachability status for this network has been updated, now check if there are routes dependent on
 					   this prefix and call reachability status
 					*/
 					if RouteServiceHandler.NextHopInfoMap[NextHopInfoKey{string(prefix)}].refCount > 0 {
 						//logger.Debug("There are dependent routes for this ip ", v[i].networkAddr)
-						V4RouteInfoMap.VisitAndUpdate(UpdateV4RouteReachabilityStatus, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Down", k, nextHopIntf})
+						RouteInfoMapVisitAndUpdate(v[i].vrf, ribdCommonDefs.IPv4, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Down", k, nextHopIntf, v[i].vrf})
 					}
 				} else if routeReachabilityStatusInfo.status == "Up" && v[i].resolvedNextHopIpIntf.IsReachable == false {
 					//logger.Debug("Bringing up route : ip: ", v[i].networkAddr)
 					v[i].resolvedNextHopIpIntf.IsReachable = true
 					rmapInfoRecordList.routeInfoProtocolMap[k] = v
-					V4RouteInfoMap.Set(prefix, rmapInfoRecordList)
+					RouteInfoMapSet(v[i].vrf, ribdCommonDefs.IPv4, prefix, rmapInfoRecordList)
 					//logger.Debug("Adding to DBRouteCh from updateRouteReachability case 2")
 					RouteServiceHandler.DBRouteCh <- RIBdServerConfig{
 						OrigConfigObject: RouteDBInfo{v[i], rmapInfoRecordList},
 						Op:               "add",
 					}
 					//RouteServiceHandler.WriteIPv4RouteStateEntryToDB(RouteDBInfo{v[i], rmapInfoRecordList})
-					RouteReachabilityStatusUpdate(k, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Up", k, nextHopIntf})
+					RouteReachabilityStatusUpdate(k, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Up", k, nextHopIntf, v[i].vrf})
 					/*
 					   The reachability status for this network has been updated, now check if there are routes dependent on
 					   this prefix and call reachability status
 					*/
 					if RouteServiceHandler.NextHopInfoMap[NextHopInfoKey{string(prefix)}].refCount > 0 {
 						//logger.Debug("There are dependent routes for this ip ", v[i].networkAddr)
-						V4RouteInfoMap.VisitAndUpdate(UpdateV4RouteReachabilityStatus, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Up", k, nextHopIntf})
+						RouteInfoMapVisitAndUpdate(v[i].vrf, ribdCommonDefs.IPv4, RouteReachabilityStatusInfo{v[i].networkAddr, v[i].ipType, "Up", k, nextHopIntf, v[i].vrf})
 					}
 				}
 			}
@@ -674,6 +674,10 @@ func (m RIBDServer) GetBulkIPv4RouteState(fromIndex ribd.Int, rcount ribd.Int) (
 			//logger.Debug("Enough routes fetched")
 			break
 		}
+		if destNetSlice[i+fromIndex].vrf != DefaultVrf {
+			//routes in other vrfs are reported through the vrf state
+			continue
+		}
 		prefixNode := V4RouteInfoMap.Get(destNetSlice[i+fromIndex].prefix)
 		if prefixNode != nil {
 			prefixNodeRouteList = prefixNode.(RouteInfoRecordList)
@@ -851,6 +855,54 @@ func (m RIBDServer) ProcessV4RouteCreateConfig(cfg *ribd.IPv4Route, addType int,
 	return true, err
 }
 
+/*
+   Synchronous create of a single route config, carrying the vrf, route tag and backup next hops
+   which the ribd.IPv4Route object does not have
+*/
+func ipv4RouteConfigToIPv4Route(cfg *ribdInt.IPv4RouteConfig) *ribd.IPv4Route {
+	route := &ribd.IPv4Route{
+		DestinationNw: cfg.DestinationNw,
+		NetworkMask:   cfg.NetworkMask,
+		Protocol:      cfg.Protocol,
+		Cost:          cfg.Cost,
+		NullRoute:     cfg.NullRoute,
+		NextHop:       make([]*ribd.NextHopInfo, 0),
+	}
+	for _, nextHop := range cfg.NextHop {
+		route.NextHop = append(route.NextHop, &ribd.NextHopInfo{
+			NextHopIp:     nextHop.NextHopIp,
+			NextHopIntRef: nextHop.NextHopIntRef,
+			Weight:        nextHop.Weight,
+		})
+	}
+	return route
+}
+func (m RIBDServer) IPv4RouteConfigValidationCheck(cfg *ribdInt.IPv4RouteConfig) (err error) {
+	if getVrfInfo(getVrfName(cfg.Vrf)) == nil {
+		return errors.New(fmt.Sprintln("vrf ", cfg.Vrf, " not found"))
+	}
+	if err = validateRouteTag(cfg.RouteTag); err != nil {
+		return err
+	}
+	return m.RouteConfigValidationCheck(ipv4RouteConfigToIPv4Route(cfg), "add")
+}
+func (m RIBDServer) ProcessV4RouteConfigCreate(cfg *ribdInt.IPv4RouteConfig, sliceIdx ribd.Int) (val bool, err error) {
+	logger.Debug("ProcessV4RouteConfigCreate: Received create route request for ip ", cfg.DestinationNw, " mask ", cfg.NetworkMask, " vrf ", cfg.Vrf, " tag ", cfg.RouteTag, " number of next hops: ", len(cfg.NextHop))
+	route := ipv4RouteConfigToIPv4Route(cfg)
+	for i, nextHop := range route.NextHop {
+		newCfg := *route
+		newCfg.NextHop = []*ribd.NextHopInfo{nextHop}
+		params := BuildRouteParamsFromribdIPv4Route(&newCfg, FIBAndRIB, Invalid, sliceIdx)
+		if cfg.Vrf != "" {
+			params.vrf = cfg.Vrf
+		}
+		params.routeTag = uint32(cfg.RouteTag)
+		params.backup = cfg.NextHop[i].Backup
+		_, err = createRoute(params)
+	}
+	return true, err
+}
+
 func (m RIBDServer) ProcessBulkRouteCreateConfig(bulkCfg []*ribdInt.IPv4RouteConfig) (val bool, err error) {
 	logger.Debug("ProcessBulkRouteCreateConfig: Received create route request for  ", len(bulkCfg), " number of routes")
 	index := 0
@@ -863,8 +915,10 @@ func (m RIBDServer) ProcessBulkRouteCreateConfig(bulkCfg []*ribdInt.IPv4RouteCon
 			Cost:          cfg.Cost,
 			NullRoute:     cfg.NullRoute,
 		}
+		backup := false
 		for i := 0; i < len(cfg.NextHop); i++ {
-			logger.Debug("nexthop info: ip: ", cfg.NextHop[i].NextHopIp, " intref: ", cfg.NextHop[i].NextHopIntRef)
+			logger.Debug("nexthop info: ip: ", cfg.NextHop[i].NextHopIp, " intref: ", cfg.NextHop[i].NextHopIntRef, " backup: ", cfg.NextHop[i].Backup)
+			backup = cfg.NextHop[i].Backup
 			nh := ribd.NextHopInfo{
 				NextHopIp:     cfg.NextHop[i].NextHopIp,
 				NextHopIntRef: cfg.NextHop[i].NextHopIntRef,
@@ -876,6 +930,11 @@ func (m RIBDServer) ProcessBulkRouteCreateConfig(bulkCfg []*ribdInt.IPv4RouteCon
 
 		//policyRoute := BuildPolicyRouteFromribdIPv4Route(&newCfg)
 		params := BuildRouteParamsFromribdIPv4Route(&newCfg, FIBAndRIB, Invalid, ribd.Int(len(destNetSlice)))
+		if cfg.Vrf != "" {
+			params.vrf = cfg.Vrf
+		}
+		params.routeTag = uint32(cfg.RouteTag)
+		params.backup = backup
 		params.bulk = true
 		index++
 		if index == len(bulkCfg) {
@@ -912,7 +971,7 @@ func (m RIBDServer) ProcessV4RouteDeleteConfig(cfg *ribd.IPv4Route, delType int)
 			nextHopIntRef, _ := strconv.Atoi(cfg.NextHop[i].NextHopIntRef)
 			nextHopIfIndex = ribd.Int(nextHopIntRef)
 		}
-		_, err = deleteIPRoute(cfg.DestinationNw, ribdCommonDefs.IPv4, cfg.NetworkMask, cfg.Protocol, cfg.NextHop[i].NextHopIp, nextHopIfIndex, ribd.Int(delType), ribdCommonDefs.RoutePolicyStateChangetoInValid)
+		_, err = deleteIPRoute(GetIntfVrf(int32(nextHopIfIndex)), cfg.DestinationNw, ribdCommonDefs.IPv4, cfg.NetworkMask, cfg.Protocol, cfg.NextHop[i].NextHopIp, nextHopIfIndex, ribd.Int(delType), ribdCommonDefs.RoutePolicyStateChangetoInValid)
 	}
 	return true, err
 }